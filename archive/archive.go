@@ -76,7 +76,9 @@ func TarFileWriter(ctx context.Context, src, dst string, tw *tar.Writer, opts ..
 	header.Gid = 0
 	header.Uname = ""
 	header.Gname = ""
-	header.Size = fi.Size()
+	if mode.IsRegular() {
+		header.Size = fi.Size()
+	}
 
 	if aopts.stripTimes {
 		header.ModTime = time.Time{}
@@ -201,18 +203,36 @@ func TarDir(ctx context.Context, root, prefix, out string, opts ...ArchiveOption
 // TarDirWriter makes a tarball of a given `root` directory into the provided
 // tarball writer `tw`.
 func TarDirWriter(ctx context.Context, root, prefix string, tw *tar.Writer, opts ...ArchiveOption) error {
-	return filepath.Walk(root, func(path string, _ os.FileInfo, err error) (returnErr error) {
+	aopts := ArchiveOptions{}
+	for _, opt := range opts {
+		if err := opt(&aopts); err != nil {
+			return err
+		}
+	}
+
+	return filepath.Walk(root, func(path string, fi os.FileInfo, err error) (returnErr error) {
 		if err != nil {
 			return err
 		}
 
+		if aopts.filter != nil && path != root && !aopts.filter(path, fi) {
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
 		dst, err := filepath.Rel(root, path)
 		if err != nil {
 			return err
 		}
 
 		dst = filepath.ToSlash(filepath.Join(prefix, dst))
+		if dst == "." {
+			return nil
+		}
 
-		return TarFileWriter(ctx, filepath.Join(root, path), dst, tw, opts...)
+		return TarFileWriter(ctx, path, dst, tw, opts...)
 	})
 }
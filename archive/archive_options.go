@@ -4,9 +4,16 @@
 // You may not use this file except in compliance with the License.
 package archive
 
+import "os"
+
+// FilterFunc decides whether the file at the provided path is included in an
+// archive.  Excluding a directory excludes all of its contents.
+type FilterFunc func(path string, fi os.FileInfo) bool
+
 type ArchiveOptions struct {
 	stripTimes bool
 	gzip       bool
+	filter     FilterFunc
 }
 
 type ArchiveOption func(*ArchiveOptions) error
@@ -28,3 +35,12 @@ func WithGzip(gzip bool) ArchiveOption {
 		return nil
 	}
 }
+
+// WithFilter indicates that when archiving a directory only the files which
+// are accepted by the provided filter should be included.
+func WithFilter(filter FilterFunc) ArchiveOption {
+	return func(ao *ArchiveOptions) error {
+		ao.filter = filter
+		return nil
+	}
+}
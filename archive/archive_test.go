@@ -0,0 +1,83 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTarDirWriterWithFilter(t *testing.T) {
+	root := t.TempDir()
+
+	for path, body := range map[string]string{
+		"libfoo.o":         "foo",
+		"libfoo/bar.o":     "bar",
+		"libfoo/bar.c":     "int bar;",
+		".git/HEAD":        "ref: refs/heads/main",
+		"libfoo/.git/HEAD": "ref: refs/heads/main",
+	} {
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+
+	// Rejected directories are skipped entirely and only object files are
+	// included otherwise.
+	if err := TarDirWriter(context.Background(), root, "/objs", tw,
+		WithFilter(func(path string, fi os.FileInfo) bool {
+			if fi.IsDir() {
+				return fi.Name() != ".git"
+			}
+
+			return filepath.Ext(path) == ".o"
+		}),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	tr := tar.NewReader(&buf)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+
+		names = append(names, hdr.Name)
+	}
+
+	expected := []string{
+		"objs",
+		"objs/libfoo",
+		"objs/libfoo/bar.o",
+		"objs/libfoo.o",
+	}
+
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected entries %v, got %v", expected, names)
+	}
+}
@@ -0,0 +1,22 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package main
+
+import (
+	"os"
+
+	_ "github.com/opencontainers/runc/libcontainer/nsenter"
+
+	libcontainer "kraftkit.sh/libmocktainer"
+)
+
+func init() {
+	if len(os.Args) > 1 && os.Args[1] == "init" {
+		// The shim creates containers in-process, which re-executes it as the
+		// init process of each container.  This is the golang entry point for
+		// it, executed before main() but after libcontainer/nsenter's nsexec().
+		libcontainer.Init()
+	}
+}
@@ -0,0 +1,44 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/containerd/containerd/runtime/v2/shim"
+
+	"kraftkit.sh/config"
+	"kraftkit.sh/internal/shim/manager"
+
+	_ "kraftkit.sh/internal/shim/task/plugin"
+)
+
+func main() {
+	ctx := context.Background()
+
+	// Use the configuration of kraft, such as the path of the QEMU binary, if
+	// there is one.
+	cfg, err := config.NewDefaultKraftKitConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if cfgm, err := config.NewConfigManager(
+		cfg,
+		config.WithFile[config.KraftKit](config.DefaultConfigFile(), false),
+	); err == nil {
+		ctx = config.WithConfigManager(ctx, cfgm)
+	}
+
+	// The shim waits for the processes of its containers itself, which means
+	// that they must not be reaped on its behalf.
+	shim.RunManager(ctx, manager.NewShimManager("io.containerd.runu.v2"), func(c *shim.Config) {
+		c.NoReaper = true
+	})
+}
@@ -0,0 +1,273 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package compose
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/types"
+
+	machineapi "kraftkit.sh/api/machine/v1alpha1"
+	"kraftkit.sh/machine/network"
+	"kraftkit.sh/machine/volume"
+	"kraftkit.sh/unikraft/app"
+)
+
+// KraftConfigExtension is the name of the extension of the project which holds
+// its KraftConfig when the project is rendered.
+const KraftConfigExtension = "x-kraft"
+
+// KraftConfig holds the decisions taken by kraft when creating the machines,
+// networks and volumes of a project.
+type KraftConfig struct {
+	Services map[string]KraftServiceConfig `yaml:"services,omitempty" json:"services,omitempty"`
+	Networks map[string]KraftNetworkConfig `yaml:"networks,omitempty" json:"networks,omitempty"`
+	Volumes  map[string]KraftVolumeConfig  `yaml:"volumes,omitempty" json:"volumes,omitempty"`
+}
+
+// KraftServiceConfig describes how a service is mapped onto machines.
+type KraftServiceConfig struct {
+	Platform     string               `yaml:"platform" json:"platform"`
+	Architecture string               `yaml:"architecture" json:"architecture"`
+	Package      string               `yaml:"package,omitempty" json:"package,omitempty"`
+	Build        string               `yaml:"build,omitempty" json:"build,omitempty"`
+	Runtime      string               `yaml:"runtime,omitempty" json:"runtime,omitempty"`
+	Args         []string             `yaml:"args,omitempty" json:"args,omitempty"`
+	CPUs         uint                 `yaml:"cpus,omitempty" json:"cpus,omitempty"`
+	Memory       int64                `yaml:"memory,omitempty" json:"memory,omitempty"`
+	Restart      string               `yaml:"restart" json:"restart"`
+	Proxied      bool                 `yaml:"proxied,omitempty" json:"proxied,omitempty"`
+	Volumes      []KraftVolumeConfig  `yaml:"volumes,omitempty" json:"volumes,omitempty"`
+	Machines     []KraftMachineConfig `yaml:"machines" json:"machines"`
+}
+
+// KraftMachineConfig describes the machine of a replica of a service.
+type KraftMachineConfig struct {
+	Name     string            `yaml:"name" json:"name"`
+	State    string            `yaml:"state,omitempty" json:"state,omitempty"`
+	Networks map[string]string `yaml:"networks,omitempty" json:"networks,omitempty"`
+}
+
+// KraftNetworkConfig describes a network of a project.
+type KraftNetworkConfig struct {
+	Name     string `yaml:"name" json:"name"`
+	Driver   string `yaml:"driver,omitempty" json:"driver,omitempty"`
+	Subnet   string `yaml:"subnet,omitempty" json:"subnet,omitempty"`
+	Gateway  string `yaml:"gateway,omitempty" json:"gateway,omitempty"`
+	External bool   `yaml:"external,omitempty" json:"external,omitempty"`
+}
+
+// KraftVolumeConfig describes a volume of a project or a volume mounted into
+// the machines of a service.
+type KraftVolumeConfig struct {
+	Name     string `yaml:"name,omitempty" json:"name,omitempty"`
+	Source   string `yaml:"source,omitempty" json:"source,omitempty"`
+	Target   string `yaml:"target,omitempty" json:"target,omitempty"`
+	Driver   string `yaml:"driver,omitempty" json:"driver,omitempty"`
+	External bool   `yaml:"external,omitempty" json:"external,omitempty"`
+}
+
+// KraftConfig returns the decisions taken by kraft when creating the project,
+// which must have been validated and have been assigned IPs.  The provided
+// machines are the existing machines, whose state and addresses are reported
+// instead of the ones they would be created with.
+func (project *Project) KraftConfig(ctx context.Context, machines []machineapi.Machine) (*KraftConfig, error) {
+	config := &KraftConfig{
+		Services: map[string]KraftServiceConfig{},
+		Networks: map[string]KraftNetworkConfig{},
+		Volumes:  map[string]KraftVolumeConfig{},
+	}
+
+	for name, net := range project.Networks {
+		config.Networks[name] = projectNetworkConfig(net)
+	}
+
+	for name, vol := range project.Volumes {
+		config.Volumes[name] = projectVolumeConfig(vol)
+	}
+
+	existing := map[string]machineapi.Machine{}
+	for _, machine := range machines {
+		existing[machine.Name] = machine
+	}
+
+	for _, name := range project.ServiceNames() {
+		service := project.Services[name]
+
+		plat, arch, ok := strings.Cut(service.Platform, "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid platform: %s for service %s", service.Platform, service.Name)
+		}
+
+		policy, err := ServiceRestartPolicy(service)
+		if err != nil {
+			return nil, err
+		}
+
+		serviceConfig := KraftServiceConfig{
+			Platform:     plat,
+			Architecture: arch,
+			Args:         ServiceArgs(service),
+			CPUs:         ServiceCPUs(service),
+			Memory:       ServiceMemory(service),
+			Restart:      policy.Condition,
+			Proxied:      NeedsProxy(service),
+			Machines:     []KraftMachineConfig{},
+		}
+
+		// A service with an image is run from its package, which is only built
+		// from its build context if it can neither be found locally nor remotely.
+		if service.Image != "" {
+			serviceConfig.Package = service.Image
+			if !strings.Contains(service.Image, ":") {
+				serviceConfig.Package += ":latest"
+			}
+		}
+
+		if service.Build != nil {
+			serviceConfig.Build = service.Build.Context
+
+			serviceConfig.Runtime, err = buildContextRuntime(ctx, service.Build.Context)
+			if err != nil {
+				return nil, fmt.Errorf("could not load the Kraftfile of service %s: %w", service.Name, err)
+			}
+		}
+
+		for _, vol := range service.Volumes {
+			serviceConfig.Volumes = append(serviceConfig.Volumes, project.serviceVolumeConfig(vol))
+		}
+
+		for _, path := range ServiceTmpfs(ctx, service) {
+			serviceConfig.Volumes = append(serviceConfig.Volumes, KraftVolumeConfig{
+				Target: path,
+				Driver: "ramfs",
+			})
+		}
+
+		for replica := 1; replica <= service.GetScale(); replica++ {
+			machineConfig := KraftMachineConfig{
+				Name:     ReplicaName(service, replica),
+				Networks: map[string]string{},
+			}
+
+			for net := range service.Networks {
+				machineConfig.Networks[project.Networks[net].Name] = project.ReplicaAddress(service, net, replica)
+			}
+
+			if machine, ok := existing[machineConfig.Name]; ok {
+				machineConfig.State = machine.Status.State.String()
+
+				for _, net := range machine.Spec.Networks {
+					for _, iface := range net.Interfaces {
+						address, _, _ := strings.Cut(iface.Spec.CIDR, "/")
+						machineConfig.Networks[net.IfName] = address
+					}
+				}
+			}
+
+			serviceConfig.Machines = append(serviceConfig.Machines, machineConfig)
+		}
+
+		config.Services[name] = serviceConfig
+	}
+
+	return config, nil
+}
+
+// projectNetworkConfig returns the configuration of the provided network of a
+// project.
+func projectNetworkConfig(net types.NetworkConfig) KraftNetworkConfig {
+	config := KraftNetworkConfig{
+		Name:     net.Name,
+		Driver:   net.Driver,
+		External: bool(net.External),
+	}
+
+	if len(config.Driver) == 0 {
+		config.Driver = network.DefaultStrategyName()
+	}
+
+	if len(net.Ipam.Config) > 0 {
+		config.Subnet = net.Ipam.Config[0].Subnet
+		config.Gateway = net.Ipam.Config[0].Gateway
+	}
+
+	return config
+}
+
+// projectVolumeConfig returns the configuration of the provided volume of a
+// project.
+func projectVolumeConfig(vol types.VolumeConfig) KraftVolumeConfig {
+	config := KraftVolumeConfig{
+		Name:     vol.Name,
+		Driver:   vol.Driver,
+		External: bool(vol.External),
+	}
+
+	if len(config.Driver) == 0 {
+		config.Driver = volume.DefaultStrategyName()
+	}
+
+	return config
+}
+
+// serviceVolumeConfig returns the configuration of the provided volume of a
+// service, which is either a volume of the project or a path on the host which
+// is mounted with the first compatible volume driver.
+func (project *Project) serviceVolumeConfig(vol types.ServiceVolumeConfig) KraftVolumeConfig {
+	if named, ok := project.Volumes[vol.Source]; ok {
+		config := projectVolumeConfig(named)
+		config.Target = vol.Target
+		return config
+	}
+
+	config := KraftVolumeConfig{
+		Source: vol.Source,
+		Target: vol.Target,
+	}
+
+	strategies := volume.Strategies()
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		if ok, err := strategies[name].IsCompatible(vol.Source, nil); ok && err == nil {
+			config.Driver = name
+			break
+		}
+	}
+
+	return config
+}
+
+// buildContextRuntime returns the runtime declared by the Kraftfile in the
+// provided build context, or an empty string if the application is built
+// from source.
+func buildContextRuntime(ctx context.Context, workdir string) (string, error) {
+	if !app.IsWorkdirInitialized(workdir) {
+		return "", nil
+	}
+
+	project, err := app.NewProjectFromOptions(ctx,
+		app.WithProjectWorkdir(workdir),
+		app.WithProjectDefaultKraftfiles(),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	if project.Runtime() == nil {
+		return "", nil
+	}
+
+	return project.Runtime().String(), nil
+}
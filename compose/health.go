@@ -0,0 +1,292 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package compose
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/compose-spec/compose-go/v2/types"
+
+	"kraftkit.sh/log"
+)
+
+const (
+	DefaultHealthCheckInterval      = 30 * time.Second
+	DefaultHealthCheckTimeout       = 30 * time.Second
+	DefaultHealthCheckStartInterval = 5 * time.Second
+	DefaultHealthCheckRetries       = 3
+)
+
+// HasHealthCheck returns whether the provided service has an enabled
+// healthcheck.
+func HasHealthCheck(service types.ServiceConfig) bool {
+	hc := service.HealthCheck
+	return hc != nil && !hc.Disable && len(hc.Test) > 0 && hc.Test[0] != "NONE"
+}
+
+// HealthCheckOnHostExtension is the extension of a healthcheck which opts in to
+// executing its test on the host, e.g.:
+//
+//	healthcheck:
+//	  test: ["CMD", "./check.sh"]
+//	  x-kraft-on-host: true
+const HealthCheckOnHostExtension = "x-kraft-on-host"
+
+// CheckHealth runs the healthcheck test of the provided service once.  Since a
+// unikernel cannot execute an additional process, the test is not executed.
+// Instead, if the test refers to an HTTP(S) URL, e.g. `curl -f
+// http://localhost:8080/health`, the URL is requested through the port which
+// publishes its port and the service is healthy if the response does not
+// indicate an error.  Otherwise the service is healthy if all of its published
+// TCP ports accept connections.  The test is only executed on the host if the
+// healthcheck explicitly opts in via HealthCheckOnHostExtension.
+func CheckHealth(ctx context.Context, service types.ServiceConfig) error {
+	if !HasHealthCheck(service) {
+		return nil
+	}
+
+	hc := service.HealthCheck
+
+	timeout := DefaultHealthCheckTimeout
+	if hc.Timeout != nil {
+		timeout = time.Duration(*hc.Timeout)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if onHost, _ := hc.Extensions[HealthCheckOnHostExtension].(bool); onHost {
+		return checkHealthOnHost(ctx, service)
+	}
+
+	if u := healthCheckURL(hc.Test); u != nil {
+		return checkHealthHTTP(ctx, service, u)
+	}
+
+	return checkHealthTCP(ctx, service)
+}
+
+// checkHealthOnHost executes the healthcheck test of the provided service on
+// the host, from where the published ports of the service are reachable.
+func checkHealthOnHost(ctx context.Context, service types.ServiceConfig) error {
+	hc := service.HealthCheck
+
+	var cmd *exec.Cmd
+
+	switch hc.Test[0] {
+	case "CMD":
+		if len(hc.Test) < 2 {
+			return fmt.Errorf("service %s has an empty healthcheck", service.Name)
+		}
+
+		cmd = exec.CommandContext(ctx, hc.Test[1], hc.Test[2:]...)
+	case "CMD-SHELL":
+		cmd = exec.CommandContext(ctx, "/bin/sh", "-c", strings.Join(hc.Test[1:], " "))
+	default:
+		cmd = exec.CommandContext(ctx, "/bin/sh", "-c", strings.Join(hc.Test, " "))
+	}
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if out := strings.TrimSpace(string(out)); len(out) > 0 {
+			return fmt.Errorf("healthcheck failed: %w: %s", err, out)
+		}
+
+		return fmt.Errorf("healthcheck failed: %w", err)
+	}
+
+	return nil
+}
+
+// healthCheckURL returns the first HTTP(S) URL referred to by the provided
+// healthcheck test, if any.
+func healthCheckURL(test []string) *url.URL {
+	for _, arg := range test {
+		for _, field := range strings.Fields(arg) {
+			field = strings.Trim(field, `"'`)
+			if !strings.HasPrefix(field, "http://") && !strings.HasPrefix(field, "https://") {
+				continue
+			}
+
+			if u, err := url.Parse(field); err == nil && len(u.Host) > 0 {
+				return u
+			}
+		}
+	}
+
+	return nil
+}
+
+// publishedAddress returns the address, in the format host:port, on the host
+// at which the provided TCP port of the service is published.
+func publishedAddress(service types.ServiceConfig, target uint32) (string, bool) {
+	for _, port := range service.Ports {
+		if port.Target != target || (len(port.Protocol) > 0 && port.Protocol != "tcp") {
+			continue
+		}
+
+		published, _, _ := strings.Cut(port.Published, "-")
+		if len(published) == 0 {
+			continue
+		}
+
+		host := port.HostIP
+		if len(host) == 0 || net.ParseIP(host).IsUnspecified() {
+			host = "127.0.0.1"
+		}
+
+		return net.JoinHostPort(host, published), true
+	}
+
+	return "", false
+}
+
+// checkHealthHTTP requests the provided URL, which refers to the service from
+// within, through the port on the host which publishes its port.
+func checkHealthHTTP(ctx context.Context, service types.ServiceConfig, u *url.URL) error {
+	port := u.Port()
+	if len(port) == 0 {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	target, err := strconv.ParseUint(port, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid port in healthcheck URL %s: %w", u, err)
+	}
+
+	address, ok := publishedAddress(service, uint32(target))
+	if !ok {
+		return fmt.Errorf("healthcheck URL %s refers to port %d which is not published", u, target)
+	}
+
+	probe := *u
+	probe.Host = address
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, probe.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	// Retain the host name the service expects to be addressed with.
+	req.Host = u.Host
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			// The certificate of the service is issued for its own host name rather
+			// than the address it is published at.
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("healthcheck failed: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("healthcheck failed: %s responded with %s", u, resp.Status)
+	}
+
+	return nil
+}
+
+// checkHealthTCP connects to all of the published TCP ports of the provided
+// service.
+func checkHealthTCP(ctx context.Context, service types.ServiceConfig) error {
+	var dialer net.Dialer
+	probed := 0
+
+	for _, port := range service.Ports {
+		address, ok := publishedAddress(service, port.Target)
+		if !ok {
+			continue
+		}
+
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return fmt.Errorf("healthcheck failed: %w", err)
+		}
+
+		conn.Close()
+		probed++
+	}
+
+	if probed == 0 {
+		return fmt.Errorf("service %s publishes no TCP port to probe its health through, see %s to execute its healthcheck on the host", service.Name, HealthCheckOnHostExtension)
+	}
+
+	return nil
+}
+
+// WaitHealthy blocks until the healthcheck of the provided service succeeds,
+// or fails once the start period of the service has elapsed as many times in
+// a row as it is retried.
+func WaitHealthy(ctx context.Context, service types.ServiceConfig) error {
+	if !HasHealthCheck(service) {
+		return nil
+	}
+
+	hc := service.HealthCheck
+
+	interval := DefaultHealthCheckInterval
+	if hc.Interval != nil {
+		interval = time.Duration(*hc.Interval)
+	}
+
+	startInterval := DefaultHealthCheckStartInterval
+	if hc.StartInterval != nil {
+		startInterval = time.Duration(*hc.StartInterval)
+	}
+
+	var startPeriod time.Duration
+	if hc.StartPeriod != nil {
+		startPeriod = time.Duration(*hc.StartPeriod)
+	}
+
+	retries := uint64(DefaultHealthCheckRetries)
+	if hc.Retries != nil {
+		retries = *hc.Retries
+	}
+
+	started := time.Now()
+	failures := uint64(0)
+
+	for {
+		err := CheckHealth(ctx, service)
+		if err == nil {
+			return nil
+		}
+
+		log.G(ctx).
+			WithField("service", service.Name).
+			Debugf("not yet healthy: %v", err)
+
+		wait := interval
+		if time.Since(started) < startPeriod {
+			wait = startInterval
+		} else if failures++; failures >= retries {
+			return fmt.Errorf("service %s is unhealthy: %w", service.Name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package compose
+
+import (
+	"testing"
+
+	"github.com/compose-spec/compose-go/v2/types"
+)
+
+func TestHealthCheckURL(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		test     []string
+		expected string
+	}{
+		{
+			name:     "cmd",
+			test:     []string{"CMD", "curl", "-f", "http://localhost:8080/health"},
+			expected: "http://localhost:8080/health",
+		},
+		{
+			name:     "cmd-shell",
+			test:     []string{"CMD-SHELL", "curl -f 'https://localhost/health' || exit 1"},
+			expected: "https://localhost/health",
+		},
+		{
+			name:     "first url",
+			test:     []string{"CMD", "check", "http://localhost:80/a", "http://localhost:81/b"},
+			expected: "http://localhost:80/a",
+		},
+		{
+			name:     "without host",
+			test:     []string{"CMD", "curl", "http:///health"},
+			expected: "",
+		},
+		{
+			name:     "other scheme",
+			test:     []string{"CMD", "nc", "-z", "tcp://localhost:5432"},
+			expected: "",
+		},
+		{
+			name:     "no url",
+			test:     []string{"CMD", "pg_isready"},
+			expected: "",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			u := healthCheckURL(tc.test)
+			if len(tc.expected) == 0 {
+				if u != nil {
+					t.Errorf("expected no URL, got %s", u)
+				}
+
+				return
+			}
+
+			if u == nil || u.String() != tc.expected {
+				t.Errorf("expected URL %s, got %v", tc.expected, u)
+			}
+		})
+	}
+}
+
+func TestPublishedAddress(t *testing.T) {
+	service := types.ServiceConfig{
+		Ports: []types.ServicePortConfig{
+			{Target: 53, Published: "5353", Protocol: "udp"},
+			{Target: 53, Published: "5354", Protocol: "tcp"},
+			{Target: 80, Published: "8080"},
+			{Target: 443, Published: "8443-8445", HostIP: "0.0.0.0"},
+			{Target: 5432, Published: "5432", HostIP: "192.168.1.10"},
+			{Target: 6379},
+			{Target: 8000, Published: "8000", HostIP: "::1"},
+		},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		target   uint32
+		expected string
+	}{
+		{
+			name:     "tcp is preferred over udp",
+			target:   53,
+			expected: "127.0.0.1:5354",
+		},
+		{
+			name:     "default host",
+			target:   80,
+			expected: "127.0.0.1:8080",
+		},
+		{
+			name:     "first port of range on unspecified host",
+			target:   443,
+			expected: "127.0.0.1:8443",
+		},
+		{
+			name:     "host",
+			target:   5432,
+			expected: "192.168.1.10:5432",
+		},
+		{
+			name:     "ipv6 host",
+			target:   8000,
+			expected: "[::1]:8000",
+		},
+		{
+			name:   "not published",
+			target: 6379,
+		},
+		{
+			name:   "not exposed",
+			target: 22,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			address, ok := publishedAddress(service, tc.target)
+			if ok != (len(tc.expected) > 0) || address != tc.expected {
+				t.Errorf("expected address %q, got %q (%t)", tc.expected, address, ok)
+			}
+		})
+	}
+}
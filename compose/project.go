@@ -27,6 +27,10 @@ import (
 
 type Project struct {
 	*types.Project `json:"project"` // The underlying compose-go project
+
+	// replicaAddresses are the IPv4 addresses of every replica but the first of
+	// each service on each network, as assigned by AssignIPs.
+	replicaAddresses map[string]map[string][]string
 }
 
 // DefaultFileNames is a list of default compose file names to look for
@@ -60,8 +64,14 @@ func NewProjectFromComposeFile(ctx context.Context, workdir, composefile string)
 
 	fullpath := filepath.Join(workdir, composefile)
 
+	// Services are enabled by the profiles listed in the COMPOSE_PROFILES
+	// environment variable, which may also be set in the .env file.
 	options, err := cli.NewProjectOptions(
 		[]string{fullpath},
+		cli.WithOsEnv,
+		cli.WithEnvFiles(),
+		cli.WithDotEnv,
+		cli.WithDefaultProfiles(),
 	)
 	if err != nil {
 		return nil, err
@@ -72,12 +82,18 @@ func NewProjectFromComposeFile(ctx context.Context, workdir, composefile string)
 		return nil, err
 	}
 
+	for name := range project.DisabledServices {
+		log.G(ctx).
+			WithField("service", name).
+			Debug("disabled by profiles")
+	}
+
 	project = project.WithoutUnnecessaryResources()
 
 	project.ComposeFiles = []string{composefile}
 	project.WorkingDir = workdir
 
-	return &Project{project}, err
+	return &Project{Project: project}, err
 }
 
 // Validate performs some early checks on the project to ensure it is valid,
@@ -91,6 +107,12 @@ func (project *Project) Validate(ctx context.Context) error {
 		}
 	}
 
+	for _, service := range project.Services {
+		if _, err := ServiceRestartPolicy(service); err != nil {
+			return err
+		}
+	}
+
 	// If the project has no name, use the directory name
 	if project.Name == "" {
 		// Take the last part of the working directory
@@ -127,7 +149,10 @@ func (project *Project) Validate(ctx context.Context) error {
 	return nil
 }
 
-func (project *Project) AssignIPs(ctx context.Context) error {
+// AssignIPs assigns an IPv4 address to each replica of each service on each
+// network with an IPAM configuration which has not been assigned one in the
+// Compose file, skipping the provided addresses which are already in use.
+func (project *Project) AssignIPs(ctx context.Context, reserved ...string) error {
 	var err error
 	usedAddresses := make(map[string]map[string]struct{})
 	for i, network := range project.Networks {
@@ -182,6 +207,9 @@ func (project *Project) AssignIPs(ctx context.Context) error {
 		usedAddresses[i] = make(map[string]struct{})
 		usedAddresses[i][ipamConfig.Gateway] = struct{}{}
 		usedAddresses[i][subnetMask.IP.String()] = struct{}{}
+		for _, address := range reserved {
+			usedAddresses[i][address] = struct{}{}
+		}
 
 		network.Ipam.Config[0] = ipamConfig
 		project.Networks[i] = network
@@ -262,6 +290,41 @@ func (project *Project) AssignIPs(ctx context.Context) error {
 		return err
 	}
 
+	// Every replica but the first is assigned its own address, in the order of
+	// the names of the services such that the assignment is reproducible.
+	project.replicaAddresses = make(map[string]map[string][]string)
+	for _, serviceName := range project.ServiceNames() {
+		service := project.Services[serviceName]
+		for name := range service.Networks {
+			if len(project.Networks[name].Ipam.Config) == 0 {
+				continue
+			}
+
+			_, subnet, err := net.ParseCIDR(project.Networks[name].Ipam.Config[0].Subnet)
+			if err != nil {
+				return err
+			}
+
+			ip := subnet.IP
+			for replica := 2; replica <= service.GetScale(); replica++ {
+				for _, exists := usedAddresses[name][ip.String()]; subnet.Contains(ip) && exists; _, exists = usedAddresses[name][ip.String()] {
+					ip = iputils.IncreaseIP(ip)
+				}
+
+				if !subnet.Contains(ip) {
+					return fmt.Errorf("not enough free IP addresses in network %s", name)
+				}
+
+				if project.replicaAddresses[service.Name] == nil {
+					project.replicaAddresses[service.Name] = make(map[string][]string)
+				}
+
+				project.replicaAddresses[service.Name][name] = append(project.replicaAddresses[service.Name][name], ip.String())
+				usedAddresses[name][ip.String()] = struct{}{}
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -0,0 +1,264 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package compose
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/compose-spec/compose-go/v2/types"
+
+	"kraftkit.sh/internal/netutil"
+	"kraftkit.sh/log"
+
+	machineapi "kraftkit.sh/api/machine/v1alpha1"
+	mplatform "kraftkit.sh/machine/platform"
+)
+
+// ProxyDialTimeout is the time after which connecting to a replica is given up
+// and the next one is tried.
+const ProxyDialTimeout = 5 * time.Second
+
+// ProxyBackends returns the addresses, in the format host:port, to which a
+// Proxy forwards connections.
+type ProxyBackends func(ctx context.Context) ([]string, error)
+
+// Proxy is a TCP proxy which forwards each connection it accepts to the next
+// of its backends in a round-robin fashion.
+type Proxy struct {
+	listener net.Listener
+	backends ProxyBackends
+	next     atomic.Uint64
+}
+
+// NewProxy listens for TCP connections at the provided address which are
+// forwarded to the provided backends once the proxy is served.
+func NewProxy(address string, backends ProxyBackends) (*Proxy, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("could not listen on %s: %w", address, err)
+	}
+
+	return &Proxy{
+		listener: listener,
+		backends: backends,
+	}, nil
+}
+
+// Addr returns the address the proxy listens on.
+func (proxy *Proxy) Addr() net.Addr {
+	return proxy.listener.Addr()
+}
+
+// Serve accepts connections until the context is cancelled.
+func (proxy *Proxy) Serve(ctx context.Context) error {
+	go func() {
+		<-ctx.Done()
+		proxy.listener.Close()
+	}()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		conn, err := proxy.listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+
+			return err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			proxy.handle(ctx, conn)
+		}()
+	}
+}
+
+// handle forwards the provided connection to the next backend which accepts
+// it.
+func (proxy *Proxy) handle(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+
+	backends, err := proxy.backends(ctx)
+	if err != nil {
+		log.G(ctx).Warnf("could not determine backends of %s: %v", proxy.Addr(), err)
+		return
+	}
+
+	if len(backends) == 0 {
+		log.G(ctx).Warnf("no backends available for %s", proxy.Addr())
+		return
+	}
+
+	dialer := net.Dialer{Timeout: ProxyDialTimeout}
+	next := proxy.next.Add(1) - 1
+
+	var backend net.Conn
+	for i := 0; i < len(backends); i++ {
+		address := backends[(next+uint64(i))%uint64(len(backends))]
+
+		backend, err = dialer.DialContext(ctx, "tcp", address)
+		if err == nil {
+			break
+		}
+
+		log.G(ctx).Debugf("could not connect to %s: %v", address, err)
+	}
+
+	if backend == nil {
+		log.G(ctx).Warnf("no backend of %s accepted the connection", proxy.Addr())
+		return
+	}
+
+	defer backend.Close()
+
+	netutil.Splice(conn, backend)
+}
+
+// Close stops the proxy from accepting connections.
+func (proxy *Proxy) Close() error {
+	return proxy.listener.Close()
+}
+
+// NeedsProxy returns whether the provided service has more than one replica
+// and publishes ports, which its replicas cannot bind on the host all at once
+// and are hence proxied.
+func NeedsProxy(service types.ServiceConfig) bool {
+	return service.GetScale() > 1 && len(service.Ports) > 0
+}
+
+// ServeReplicaProxies serves a proxy on the host for each port published by
+// each service of the project with more than one replica, which forwards
+// connections to the running replicas of the service in turn, until the
+// context is cancelled.
+func (project *Project) ServeReplicaProxies(ctx context.Context) error {
+	machineController, err := mplatform.NewMachineV1alpha1ServiceIterator(ctx)
+	if err != nil {
+		return err
+	}
+
+	var proxies []*Proxy
+
+	defer func() {
+		for _, proxy := range proxies {
+			proxy.Close()
+		}
+	}()
+
+	for _, name := range project.ServiceNames() {
+		service := project.Services[name]
+		if !NeedsProxy(service) {
+			continue
+		}
+
+		for _, port := range service.Ports {
+			if port.Protocol != "" && port.Protocol != "tcp" {
+				log.G(ctx).Warnf("cannot proxy %s port %d of service %s", port.Protocol, port.Target, service.Name)
+				continue
+			}
+
+			if len(port.Published) == 0 {
+				continue
+			}
+
+			proxy, err := NewProxy(
+				net.JoinHostPort(port.HostIP, port.Published),
+				project.replicaBackends(machineController, service, port.Target),
+			)
+			if err != nil {
+				return err
+			}
+
+			log.G(ctx).
+				WithField("service", service.Name).
+				WithField("target", port.Target).
+				Infof("proxying %s", proxy.Addr())
+
+			proxies = append(proxies, proxy)
+		}
+	}
+
+	errs := make(chan error, len(proxies))
+	for _, proxy := range proxies {
+		go func(proxy *Proxy) {
+			errs <- proxy.Serve(ctx)
+		}(proxy)
+	}
+
+	for range proxies {
+		if err := <-errs; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// replicaBackends returns the addresses of the provided port of the running
+// replicas of the provided service in the order of their index.
+func (project *Project) replicaBackends(machineController machineapi.MachineService, service types.ServiceConfig, port uint32) ProxyBackends {
+	return func(ctx context.Context) ([]string, error) {
+		machines, err := machineController.List(ctx, &machineapi.MachineList{})
+		if err != nil {
+			return nil, err
+		}
+
+		indexes := map[string]int{}
+		var backends []string
+
+		for _, machine := range machines.Items {
+			if machine.Status.State != machineapi.MachineStateRunning {
+				continue
+			}
+
+			owner, replica, ok := project.ServiceOfMachine(machine.Name)
+			if !ok || owner.Name != service.Name {
+				continue
+			}
+
+			address := MachineAddress(machine)
+			if len(address) == 0 {
+				continue
+			}
+
+			backend := net.JoinHostPort(address, strconv.FormatUint(uint64(port), 10))
+			indexes[backend] = replica
+			backends = append(backends, backend)
+		}
+
+		sort.Slice(backends, func(i, j int) bool {
+			return indexes[backends[i]] < indexes[backends[j]]
+		})
+
+		return backends, nil
+	}
+}
+
+// MachineAddress returns the first IPv4 address of the provided machine, or
+// an empty string if it has none.
+func MachineAddress(machine machineapi.Machine) string {
+	for _, network := range machine.Spec.Networks {
+		for _, iface := range network.Interfaces {
+			if ip, _, err := net.ParseCIDR(iface.Spec.CIDR); err == nil {
+				return ip.String()
+			} else if ip := net.ParseIP(iface.Spec.CIDR); ip != nil {
+				return ip.String()
+			}
+		}
+	}
+
+	return ""
+}
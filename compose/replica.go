@@ -0,0 +1,140 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package compose
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/types"
+
+	machineapi "kraftkit.sh/api/machine/v1alpha1"
+)
+
+// ReplicaName returns the name of the machine of the replica of the provided
+// service with the provided index, starting at 1.  The first replica is named
+// after the service such that scaling a service does not rename its existing
+// machine, and every other replica has its index appended to that name.
+func ReplicaName(service types.ServiceConfig, replica int) string {
+	if replica <= 1 {
+		return service.ContainerName
+	}
+
+	return fmt.Sprintf("%s-%d", service.ContainerName, replica)
+}
+
+// ReplicaIndex returns the index of the replica of the provided service which
+// has the machine with the provided name, and false if the machine is not a
+// replica of the service.
+func ReplicaIndex(service types.ServiceConfig, machine string) (int, bool) {
+	if machine == service.ContainerName {
+		return 1, true
+	}
+
+	suffix, ok := strings.CutPrefix(machine, service.ContainerName+"-")
+	if !ok {
+		return 0, false
+	}
+
+	replica, err := strconv.Atoi(suffix)
+	if err != nil || replica < 2 || strconv.Itoa(replica) != suffix {
+		return 0, false
+	}
+
+	return replica, true
+}
+
+// ServiceOfMachine returns the service of the project which has the machine
+// with the provided name as one of its replicas, together with the index of
+// the replica.  A service which is named exactly like the machine takes
+// precedence over a service of which it could be an indexed replica.
+func (project *Project) ServiceOfMachine(machine string) (types.ServiceConfig, int, bool) {
+	for _, service := range project.Services {
+		if service.ContainerName == machine {
+			return service, 1, true
+		}
+	}
+
+	for _, service := range project.Services {
+		if replica, ok := ReplicaIndex(service, machine); ok {
+			return service, replica, true
+		}
+	}
+
+	return types.ServiceConfig{}, 0, false
+}
+
+// IsServiceMachine returns whether the machine with the provided name is a
+// replica of the provided service.
+func (project *Project) IsServiceMachine(service types.ServiceConfig, machine string) bool {
+	owner, _, ok := project.ServiceOfMachine(machine)
+	return ok && owner.Name == service.Name
+}
+
+// ReservedAddresses returns the addresses of the provided machines which must
+// not be assigned to the replicas of the project by AssignIPs.  The addresses
+// of the replicas of the project itself are not reserved, such that a replica
+// which is recreated is assigned the same address again.
+func (project *Project) ReservedAddresses(machines []machineapi.Machine) []string {
+	reserved := []string{}
+	for _, machine := range machines {
+		if _, _, ok := project.ServiceOfMachine(machine.Name); ok {
+			continue
+		}
+
+		if address := MachineAddress(machine); len(address) > 0 {
+			reserved = append(reserved, address)
+		}
+	}
+
+	return reserved
+}
+
+// ApplyScale overrides the number of replicas of the services of the project
+// with the provided list in the format service=replicas.
+func (project *Project) ApplyScale(scale []string) error {
+	for _, entry := range scale {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return fmt.Errorf("invalid syntax for --scale=%s expected --scale=<service>=<replicas>", entry)
+		}
+
+		replicas, err := strconv.Atoi(value)
+		if err != nil || replicas < 0 {
+			return fmt.Errorf("invalid number of replicas for service %s: %s", name, value)
+		}
+
+		service, ok := project.Services[name]
+		if !ok {
+			return fmt.Errorf("no such service: %s", name)
+		}
+
+		service.SetScale(replicas)
+		project.Services[name] = service
+	}
+
+	return nil
+}
+
+// ReplicaAddress returns the IPv4 address which was assigned by AssignIPs to
+// the replica of the provided service with the provided index on the provided
+// network, or an empty string if the network assigns addresses itself.
+func (project *Project) ReplicaAddress(service types.ServiceConfig, network string, replica int) string {
+	if replica <= 1 {
+		if config := service.Networks[network]; config != nil {
+			return config.Ipv4Address
+		}
+
+		return ""
+	}
+
+	addresses := project.replicaAddresses[service.Name][network]
+	if replica-2 >= len(addresses) {
+		return ""
+	}
+
+	return addresses[replica-2]
+}
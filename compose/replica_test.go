@@ -0,0 +1,59 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package compose
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	machineapi "kraftkit.sh/api/machine/v1alpha1"
+	networkapi "kraftkit.sh/api/network/v1alpha1"
+)
+
+// newTestMachine returns a machine with the provided name which has the
+// provided address on its only interface.
+func newTestMachine(name, cidr string) machineapi.Machine {
+	return machineapi.Machine{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: machineapi.MachineSpec{
+			Networks: []networkapi.NetworkSpec{{
+				Interfaces: []networkapi.NetworkInterfaceTemplateSpec{{
+					Spec: networkapi.NetworkInterfaceSpec{CIDR: cidr},
+				}},
+			}},
+		},
+	}
+}
+
+func TestReservedAddresses(t *testing.T) {
+	project := &Project{
+		Project: &types.Project{
+			Name: "app",
+			Services: types.Services{
+				"web": {Name: "web", ContainerName: "app-web"},
+				"db":  {Name: "db", ContainerName: "app-db"},
+			},
+		},
+	}
+
+	reserved := project.ReservedAddresses([]machineapi.Machine{
+		newTestMachine("app-web", "172.23.0.2/24"),
+		newTestMachine("app-web-2", "172.23.0.3/24"),
+		newTestMachine("app-db", "172.23.0.4"),
+		newTestMachine("other-web", "172.23.0.5/24"),
+		newTestMachine("app-web-x", "172.23.0.6/24"),
+		newTestMachine("app-cache", ""),
+	})
+
+	// Only the machines which are not replicas of the project's services, and
+	// which have an address, reserve it.
+	expected := []string{"172.23.0.5", "172.23.0.6"}
+	if !slices.Equal(reserved, expected) {
+		t.Errorf("expected reserved addresses %v, got %v", expected, reserved)
+	}
+}
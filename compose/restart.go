@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package compose
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/compose-spec/compose-go/v2/types"
+)
+
+// RestartPolicy determines whether the machine of a service is restarted after
+// it has exited.
+type RestartPolicy struct {
+	// Condition is one of the compose-go RestartPolicy* values.
+	Condition string
+
+	// MaxAttempts is the number of restarts after which the machine is no
+	// longer restarted, or 0 for no limit.
+	MaxAttempts uint64
+
+	// Delay is the time to wait before restarting the machine.
+	Delay time.Duration
+}
+
+// ServiceRestartPolicy returns the restart policy of the provided service,
+// which is its deploy.restart_policy if set, or otherwise its restart key.
+func ServiceRestartPolicy(service types.ServiceConfig) (RestartPolicy, error) {
+	policy := RestartPolicy{
+		Condition: types.RestartPolicyNo,
+		Delay:     time.Second,
+	}
+
+	if service.Deploy != nil && service.Deploy.RestartPolicy != nil {
+		switch condition := service.Deploy.RestartPolicy.Condition; condition {
+		case "", "any":
+			policy.Condition = types.RestartPolicyAlways
+		case "none":
+			policy.Condition = types.RestartPolicyNo
+		case types.RestartPolicyOnFailure:
+			policy.Condition = types.RestartPolicyOnFailure
+		default:
+			return policy, fmt.Errorf("service %s has an unknown restart condition: %s", service.Name, condition)
+		}
+
+		if service.Deploy.RestartPolicy.MaxAttempts != nil {
+			policy.MaxAttempts = *service.Deploy.RestartPolicy.MaxAttempts
+		}
+
+		if service.Deploy.RestartPolicy.Delay != nil {
+			policy.Delay = time.Duration(*service.Deploy.RestartPolicy.Delay)
+		}
+
+		return policy, nil
+	}
+
+	condition, attempts, _ := strings.Cut(service.Restart, ":")
+
+	switch condition {
+	case "", types.RestartPolicyNo:
+	case types.RestartPolicyAlways, types.RestartPolicyUnlessStopped:
+		policy.Condition = condition
+	case types.RestartPolicyOnFailure:
+		policy.Condition = condition
+
+		if len(attempts) > 0 {
+			var err error
+			policy.MaxAttempts, err = strconv.ParseUint(attempts, 10, 64)
+			if err != nil {
+				return policy, fmt.Errorf("service %s has an invalid number of restart attempts: %w", service.Name, err)
+			}
+		}
+	default:
+		return policy, fmt.Errorf("service %s has an unknown restart policy: %s", service.Name, service.Restart)
+	}
+
+	return policy, nil
+}
+
+// ShouldRestart returns whether a machine which has exited with the provided
+// exit code after the provided number of restarts should be restarted.
+func (policy RestartPolicy) ShouldRestart(exitCode int, restarts uint64) bool {
+	if policy.MaxAttempts > 0 && restarts >= policy.MaxAttempts {
+		return false
+	}
+
+	switch policy.Condition {
+	case types.RestartPolicyAlways, types.RestartPolicyUnlessStopped:
+		return true
+	case types.RestartPolicyOnFailure:
+		return exitCode != 0
+	default:
+		return false
+	}
+}
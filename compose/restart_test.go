@@ -0,0 +1,187 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package compose
+
+import (
+	"testing"
+	"time"
+
+	"github.com/compose-spec/compose-go/v2/types"
+)
+
+func TestServiceRestartPolicy(t *testing.T) {
+	attempts := uint64(3)
+	delay := types.Duration(5 * time.Second)
+
+	for _, tc := range []struct {
+		name     string
+		service  types.ServiceConfig
+		expected RestartPolicy
+		err      bool
+	}{
+		{
+			name:     "default",
+			service:  types.ServiceConfig{},
+			expected: RestartPolicy{Condition: types.RestartPolicyNo, Delay: time.Second},
+		},
+		{
+			name:     "always",
+			service:  types.ServiceConfig{Restart: "always"},
+			expected: RestartPolicy{Condition: types.RestartPolicyAlways, Delay: time.Second},
+		},
+		{
+			name:     "unless-stopped",
+			service:  types.ServiceConfig{Restart: "unless-stopped"},
+			expected: RestartPolicy{Condition: types.RestartPolicyUnlessStopped, Delay: time.Second},
+		},
+		{
+			name:     "on-failure",
+			service:  types.ServiceConfig{Restart: "on-failure"},
+			expected: RestartPolicy{Condition: types.RestartPolicyOnFailure, Delay: time.Second},
+		},
+		{
+			name:     "on-failure with attempts",
+			service:  types.ServiceConfig{Restart: "on-failure:5"},
+			expected: RestartPolicy{Condition: types.RestartPolicyOnFailure, MaxAttempts: 5, Delay: time.Second},
+		},
+		{
+			name:    "on-failure with invalid attempts",
+			service: types.ServiceConfig{Restart: "on-failure:-1"},
+			err:     true,
+		},
+		{
+			name:    "unknown",
+			service: types.ServiceConfig{Restart: "sometimes"},
+			err:     true,
+		},
+		{
+			name: "deploy without condition",
+			service: types.ServiceConfig{
+				Deploy: &types.DeployConfig{
+					RestartPolicy: &types.RestartPolicy{},
+				},
+			},
+			expected: RestartPolicy{Condition: types.RestartPolicyAlways, Delay: time.Second},
+		},
+		{
+			name: "deploy takes precedence over restart",
+			service: types.ServiceConfig{
+				Restart: "always",
+				Deploy: &types.DeployConfig{
+					RestartPolicy: &types.RestartPolicy{Condition: "none"},
+				},
+			},
+			expected: RestartPolicy{Condition: types.RestartPolicyNo, Delay: time.Second},
+		},
+		{
+			name: "deploy on-failure",
+			service: types.ServiceConfig{
+				Deploy: &types.DeployConfig{
+					RestartPolicy: &types.RestartPolicy{
+						Condition:   "on-failure",
+						MaxAttempts: &attempts,
+						Delay:       &delay,
+					},
+				},
+			},
+			expected: RestartPolicy{Condition: types.RestartPolicyOnFailure, MaxAttempts: 3, Delay: 5 * time.Second},
+		},
+		{
+			name: "deploy unknown condition",
+			service: types.ServiceConfig{
+				Deploy: &types.DeployConfig{
+					RestartPolicy: &types.RestartPolicy{Condition: "always"},
+				},
+			},
+			err: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			policy, err := ServiceRestartPolicy(tc.service)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", policy)
+				}
+
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if policy != tc.expected {
+				t.Errorf("expected %+v, got %+v", tc.expected, policy)
+			}
+		})
+	}
+}
+
+func TestShouldRestart(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		policy   RestartPolicy
+		exitCode int
+		restarts uint64
+		expected bool
+	}{
+		{
+			name:     "no",
+			policy:   RestartPolicy{Condition: types.RestartPolicyNo},
+			exitCode: 1,
+			expected: false,
+		},
+		{
+			name:     "always after success",
+			policy:   RestartPolicy{Condition: types.RestartPolicyAlways},
+			exitCode: 0,
+			expected: true,
+		},
+		{
+			name:     "unless-stopped after failure",
+			policy:   RestartPolicy{Condition: types.RestartPolicyUnlessStopped},
+			exitCode: 1,
+			restarts: 100,
+			expected: true,
+		},
+		{
+			name:     "on-failure after success",
+			policy:   RestartPolicy{Condition: types.RestartPolicyOnFailure},
+			exitCode: 0,
+			expected: false,
+		},
+		{
+			name:     "on-failure after failure",
+			policy:   RestartPolicy{Condition: types.RestartPolicyOnFailure},
+			exitCode: 1,
+			expected: true,
+		},
+		{
+			name:     "on-failure below max attempts",
+			policy:   RestartPolicy{Condition: types.RestartPolicyOnFailure, MaxAttempts: 3},
+			exitCode: 1,
+			restarts: 2,
+			expected: true,
+		},
+		{
+			name:     "on-failure at max attempts",
+			policy:   RestartPolicy{Condition: types.RestartPolicyOnFailure, MaxAttempts: 3},
+			exitCode: 1,
+			restarts: 3,
+			expected: false,
+		},
+		{
+			name:     "always at max attempts",
+			policy:   RestartPolicy{Condition: types.RestartPolicyAlways, MaxAttempts: 1},
+			exitCode: 0,
+			restarts: 1,
+			expected: false,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if restart := tc.policy.ShouldRestart(tc.exitCode, tc.restarts); restart != tc.expected {
+				t.Errorf("expected restart to be %t, got %t", tc.expected, restart)
+			}
+		})
+	}
+}
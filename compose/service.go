@@ -0,0 +1,247 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package compose
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/types"
+
+	"kraftkit.sh/log"
+)
+
+// keySet is a set of keys of a Compose object, where the value of each key is
+// the set of its supported sub-keys or nil if all of them are supported.
+type keySet map[string]keySet
+
+// supportedResources are the resources of a service which are mapped onto a
+// machine.
+var supportedResources = keySet{
+	"cpus":   nil,
+	"memory": nil,
+}
+
+// supportedServiceKeys are the keys of a service which are mapped onto a
+// machine.
+var supportedServiceKeys = keySet{
+	"build":          nil,
+	"command":        nil,
+	"container_name": nil,
+	"cpus":           nil,
+	"depends_on":     nil,
+	"deploy": {
+		"replicas":       nil,
+		"restart_policy": nil,
+		"resources": {
+			"limits":       supportedResources,
+			"reservations": supportedResources,
+		},
+	},
+	"develop":         nil,
+	"dns":             nil,
+	"domainname":      nil,
+	"entrypoint":      nil,
+	"env_file":        nil,
+	"environment":     nil,
+	"extra_hosts":     nil,
+	"healthcheck":     nil,
+	"hostname":        nil,
+	"image":           nil,
+	"labels":          nil,
+	"mem_limit":       nil,
+	"mem_reservation": nil,
+	"networks":        nil,
+	"platform":        nil,
+	"ports":           nil,
+	"profiles":        nil,
+	"restart":         nil,
+	"scale":           nil,
+	"tmpfs":           nil,
+	"volumes":         nil,
+}
+
+// unsupportedServiceKeyReasons explains why some well-known keys of a service
+// cannot be mapped onto a machine.
+var unsupportedServiceKeyReasons = map[string]string{
+	"cap_add":     "unikernels have no capabilities",
+	"cap_drop":    "unikernels have no capabilities",
+	"privileged":  "unikernels have no privileges",
+	"user":        "unikernels have a single user",
+	"working_dir": "unikernels have no working directory",
+}
+
+// UnsupportedServiceKeys returns the keys, and sub-keys in dotted notation,
+// which are set on the provided service but are not mapped onto its machine.
+func UnsupportedServiceKeys(service types.ServiceConfig) ([]string, error) {
+	raw, err := json.Marshal(service)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal service: %w", err)
+	}
+
+	unsupported := unsupportedKeys("", raw, supportedServiceKeys)
+
+	sort.Strings(unsupported)
+
+	return unsupported, nil
+}
+
+// unsupportedKeys returns the keys of the provided JSON object which are set
+// but are not in the provided set of supported keys.
+func unsupportedKeys(prefix string, raw json.RawMessage, supported keySet) []string {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil
+	}
+
+	var unsupported []string
+
+	for key, value := range fields {
+		switch string(value) {
+		case "null", "{}", "[]", `""`, "0", "false":
+			continue
+		}
+
+		subkeys, ok := supported[key]
+		if !ok {
+			unsupported = append(unsupported, prefix+key)
+		} else if subkeys != nil {
+			unsupported = append(unsupported, unsupportedKeys(prefix+key+".", value, subkeys)...)
+		}
+	}
+
+	return unsupported
+}
+
+// WarnUnsupported logs a warning for each key of each service of the project
+// which is not mapped onto its machine and is hence ignored.
+func (project *Project) WarnUnsupported(ctx context.Context) error {
+	for _, service := range project.Services {
+		keys, err := UnsupportedServiceKeys(service)
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			entry := log.G(ctx).
+				WithField("service", service.Name).
+				WithField("key", key)
+
+			if reason, ok := unsupportedServiceKeyReasons[key]; ok {
+				entry.Warnf("ignoring unsupported key: %s", reason)
+			} else {
+				entry.Warn("ignoring unsupported key")
+			}
+		}
+	}
+
+	return nil
+}
+
+// ServiceArgs returns the application arguments of the provided service, which
+// are its entrypoint followed by its command, or nil to use the defaults of
+// its image.
+func ServiceArgs(service types.ServiceConfig) []string {
+	if len(service.Entrypoint) == 0 && len(service.Command) == 0 {
+		return nil
+	}
+
+	args := make([]string, 0, len(service.Entrypoint)+len(service.Command))
+	args = append(args, service.Entrypoint...)
+	args = append(args, service.Command...)
+
+	return args
+}
+
+// ServiceCPUs returns the number of vCPUs of the provided service, which is its
+// fractional CPU limit, or otherwise reservation, rounded up, or 0 to use the
+// default.
+func ServiceCPUs(service types.ServiceConfig) uint {
+	cpus := service.CPUS
+
+	if cpus == 0 && service.Deploy != nil {
+		if limits := service.Deploy.Resources.Limits; limits != nil && limits.NanoCPUs > 0 {
+			cpus = float32(limits.NanoCPUs)
+		} else if reservations := service.Deploy.Resources.Reservations; reservations != nil && reservations.NanoCPUs > 0 {
+			cpus = float32(reservations.NanoCPUs)
+		}
+	}
+
+	return uint(math.Ceil(float64(cpus)))
+}
+
+// ServiceMemory returns the memory of the provided service in bytes, which is
+// its memory limit, or otherwise reservation, or 0 to use the default.
+func ServiceMemory(service types.ServiceConfig) int64 {
+	if service.MemLimit > 0 {
+		return int64(service.MemLimit)
+	}
+
+	if service.Deploy != nil {
+		if limits := service.Deploy.Resources.Limits; limits != nil && limits.MemoryBytes > 0 {
+			return int64(limits.MemoryBytes)
+		}
+	}
+
+	if service.MemReservation > 0 {
+		return int64(service.MemReservation)
+	}
+
+	if service.Deploy != nil {
+		if reservations := service.Deploy.Resources.Reservations; reservations != nil && reservations.MemoryBytes > 0 {
+			return int64(reservations.MemoryBytes)
+		}
+	}
+
+	return 0
+}
+
+// ServiceLabels returns the labels of the provided service in the format
+// key=value.
+func ServiceLabels(service types.ServiceConfig) []string {
+	labels := make([]string, 0, len(service.Labels))
+	for k, v := range service.Labels {
+		labels = append(labels, k+"="+v)
+	}
+
+	sort.Strings(labels)
+
+	return labels
+}
+
+// ServiceExtraHosts returns the additional host entries of the provided
+// service in the format host=ip, which are written to the hosts file of its
+// root file system.
+func ServiceExtraHosts(service types.ServiceConfig) []string {
+	hosts := service.ExtraHosts.AsList("=")
+
+	sort.Strings(hosts)
+
+	return hosts
+}
+
+// ServiceTmpfs returns the paths at which the provided service mounts an
+// in-memory file system.  Mount options, such as the size, are not supported
+// and are discarded.
+func ServiceTmpfs(ctx context.Context, service types.ServiceConfig) []string {
+	paths := make([]string, 0, len(service.Tmpfs))
+	for _, tmpfs := range service.Tmpfs {
+		path, opts, _ := strings.Cut(tmpfs, ":")
+		if len(opts) > 0 {
+			log.G(ctx).
+				WithField("service", service.Name).
+				WithField("tmpfs", path).
+				Warnf("ignoring unsupported mount options: %s", opts)
+		}
+
+		paths = append(paths, path)
+	}
+
+	return paths
+}
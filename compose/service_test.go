@@ -0,0 +1,213 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package compose
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/compose-spec/compose-go/v2/types"
+)
+
+func TestUnsupportedServiceKeys(t *testing.T) {
+	replicas := 2
+
+	for _, tc := range []struct {
+		name     string
+		service  types.ServiceConfig
+		expected []string
+	}{
+		{
+			name: "supported keys",
+			service: types.ServiceConfig{
+				Name:     "web",
+				Image:    "nginx:latest",
+				Command:  types.ShellCommand{"-g", "daemon off;"},
+				CPUS:     2,
+				MemLimit: 256 << 20,
+				Deploy: &types.DeployConfig{
+					Replicas: &replicas,
+					Resources: types.Resources{
+						Limits: &types.Resource{NanoCPUs: 1, MemoryBytes: 64 << 20},
+					},
+				},
+			},
+			expected: nil,
+		},
+		{
+			name: "unset keys",
+			service: types.ServiceConfig{
+				Name:       "web",
+				Privileged: false,
+				User:       "",
+				CapAdd:     []string{},
+			},
+			expected: nil,
+		},
+		{
+			name: "unsupported keys",
+			service: types.ServiceConfig{
+				Name:       "web",
+				Privileged: true,
+				User:       "nobody",
+				WorkingDir: "/srv",
+				CapAdd:     []string{"NET_ADMIN"},
+			},
+			expected: []string{"cap_add", "privileged", "user", "working_dir"},
+		},
+		{
+			name: "unsupported sub-keys",
+			service: types.ServiceConfig{
+				Name: "web",
+				Deploy: &types.DeployConfig{
+					Mode: "global",
+					Resources: types.Resources{
+						Limits: &types.Resource{NanoCPUs: 1, Pids: 10},
+					},
+				},
+			},
+			expected: []string{"deploy.mode", "deploy.resources.limits.pids"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			keys, err := UnsupportedServiceKeys(tc.service)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Equal(keys, tc.expected) {
+				t.Errorf("expected unsupported keys %v, got %v", tc.expected, keys)
+			}
+		})
+	}
+}
+
+func TestServiceCPUs(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		service  types.ServiceConfig
+		expected uint
+	}{
+		{
+			name:     "default",
+			service:  types.ServiceConfig{},
+			expected: 0,
+		},
+		{
+			name:     "fractional cpus are rounded up",
+			service:  types.ServiceConfig{CPUS: 1.5},
+			expected: 2,
+		},
+		{
+			name: "cpus take precedence over deploy",
+			service: types.ServiceConfig{
+				CPUS: 1,
+				Deploy: &types.DeployConfig{
+					Resources: types.Resources{
+						Limits: &types.Resource{NanoCPUs: 4},
+					},
+				},
+			},
+			expected: 1,
+		},
+		{
+			name: "limit takes precedence over reservation",
+			service: types.ServiceConfig{
+				Deploy: &types.DeployConfig{
+					Resources: types.Resources{
+						Limits:       &types.Resource{NanoCPUs: 3},
+						Reservations: &types.Resource{NanoCPUs: 1},
+					},
+				},
+			},
+			expected: 3,
+		},
+		{
+			name: "reservation",
+			service: types.ServiceConfig{
+				Deploy: &types.DeployConfig{
+					Resources: types.Resources{
+						Limits:       &types.Resource{MemoryBytes: 64 << 20},
+						Reservations: &types.Resource{NanoCPUs: 0.25},
+					},
+				},
+			},
+			expected: 1,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if cpus := ServiceCPUs(tc.service); cpus != tc.expected {
+				t.Errorf("expected %d vCPUs, got %d", tc.expected, cpus)
+			}
+		})
+	}
+}
+
+func TestServiceMemory(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		service  types.ServiceConfig
+		expected int64
+	}{
+		{
+			name:     "default",
+			service:  types.ServiceConfig{},
+			expected: 0,
+		},
+		{
+			name: "mem_limit takes precedence over deploy",
+			service: types.ServiceConfig{
+				MemLimit: 128 << 20,
+				Deploy: &types.DeployConfig{
+					Resources: types.Resources{
+						Limits: &types.Resource{MemoryBytes: 256 << 20},
+					},
+				},
+			},
+			expected: 128 << 20,
+		},
+		{
+			name: "deploy limit takes precedence over mem_reservation",
+			service: types.ServiceConfig{
+				MemReservation: 32 << 20,
+				Deploy: &types.DeployConfig{
+					Resources: types.Resources{
+						Limits: &types.Resource{MemoryBytes: 256 << 20},
+					},
+				},
+			},
+			expected: 256 << 20,
+		},
+		{
+			name: "mem_reservation takes precedence over deploy reservation",
+			service: types.ServiceConfig{
+				MemReservation: 32 << 20,
+				Deploy: &types.DeployConfig{
+					Resources: types.Resources{
+						Reservations: &types.Resource{MemoryBytes: 64 << 20},
+					},
+				},
+			},
+			expected: 32 << 20,
+		},
+		{
+			name: "deploy reservation",
+			service: types.ServiceConfig{
+				Deploy: &types.DeployConfig{
+					Resources: types.Resources{
+						Reservations: &types.Resource{MemoryBytes: 64 << 20},
+					},
+				},
+			},
+			expected: 64 << 20,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if memory := ServiceMemory(tc.service); memory != tc.expected {
+				t.Errorf("expected %d bytes of memory, got %d", tc.expected, memory)
+			}
+		})
+	}
+}
@@ -82,13 +82,7 @@ func (v1 *v1Compose) refreshRunningServices(ctx context.Context, embeddedProject
 	// Orphaned machines
 	orphanMachines := []string{}
 	for _, machine := range embeddedProject.Status.Machines {
-		isService := false
-		for _, service := range project.Services {
-			if service.ContainerName == machine.Name {
-				isService = true
-				break
-			}
-		}
+		_, _, isService := project.ServiceOfMachine(machine.Name)
 
 		for _, m := range machines.Items {
 			if m.Name == machine.Name {
@@ -110,13 +104,7 @@ func (v1 *v1Compose) refreshRunningServices(ctx context.Context, embeddedProject
 		if m.Status.State != machineapi.MachineStateRunning {
 			continue
 		}
-		isService := false
-		for _, service := range project.Services {
-			if service.ContainerName == m.Name {
-				isService = true
-				break
-			}
-		}
+		_, _, isService := project.ServiceOfMachine(m.Name)
 
 		if !isService {
 			continue
@@ -0,0 +1,223 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package compose
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/types"
+)
+
+// watchIgnored are the paths relative to a watched path which never trigger a
+// rule, since they are written by building the service itself.
+var watchIgnored = []string{
+	".config",
+	".config.*",
+	".git",
+	".unikraft",
+}
+
+// WatchTrigger is a develop.watch rule of a service.
+type WatchTrigger struct {
+	types.Trigger
+
+	// Service is the name of the service the rule belongs to.
+	Service string
+}
+
+// ServiceWatchTriggers returns the develop.watch rules of the provided
+// service.  A service without rules which has a build context is rebuilt
+// whenever anything in its build context changes, which includes its Kraftfile,
+// its sources and its rootfs directory.
+func ServiceWatchTriggers(service types.ServiceConfig) []WatchTrigger {
+	triggers := []WatchTrigger{}
+
+	if service.Develop != nil && len(service.Develop.Watch) > 0 {
+		for _, trigger := range service.Develop.Watch {
+			triggers = append(triggers, WatchTrigger{
+				Trigger: trigger,
+				Service: service.Name,
+			})
+		}
+
+		return triggers
+	}
+
+	if service.Build != nil && len(service.Build.Context) > 0 {
+		triggers = append(triggers, WatchTrigger{
+			Trigger: types.Trigger{
+				Path:   service.Build.Context,
+				Action: types.WatchActionRebuild,
+			},
+			Service: service.Name,
+		})
+	}
+
+	return triggers
+}
+
+// Matches returns whether the provided path is within the path watched by the
+// trigger and not ignored by it.
+func (trigger WatchTrigger) Matches(path string) bool {
+	rel, err := filepath.Rel(trigger.Path, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+
+	return !trigger.Ignores(path)
+}
+
+// Ignores returns whether the provided path, or any of its parents within the
+// path watched by the trigger, matches one of the ignore patterns of the
+// trigger.
+func (trigger WatchTrigger) Ignores(path string) bool {
+	rel, err := filepath.Rel(trigger.Path, path)
+	if err != nil || rel == "." {
+		return false
+	}
+
+	patterns := append(append([]string{}, watchIgnored...), trigger.Ignore...)
+
+	for rel != "." {
+		for _, pattern := range patterns {
+			pattern = filepath.Clean(strings.TrimPrefix(pattern, "/"))
+
+			if ok, _ := filepath.Match(pattern, rel); ok {
+				return true
+			}
+
+			if ok, _ := filepath.Match(pattern, filepath.Base(rel)); ok {
+				return true
+			}
+		}
+
+		rel = filepath.Dir(rel)
+	}
+
+	return false
+}
+
+// SyncTarget returns the path within the machines of the service to which the
+// provided path is synchronized by the trigger.
+func (trigger WatchTrigger) SyncTarget(path string) (string, error) {
+	if len(trigger.Target) == 0 {
+		return "", fmt.Errorf("service %s has a %s rule for %s without a target", trigger.Service, trigger.Action, trigger.Path)
+	}
+
+	rel, err := filepath.Rel(trigger.Path, path)
+	if err != nil {
+		return "", err
+	}
+
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is not within %s watched by service %s", path, trigger.Path, trigger.Service)
+	}
+
+	return filepath.Join(trigger.Target, rel), nil
+}
+
+// ServiceHostPath returns the path on the host which is mounted at the
+// provided path within the machines of the provided service.  Only paths in
+// bind mounts can be changed without rebuilding the root filesystem of the
+// service.  The innermost bind mount applies if they are nested.
+func ServiceHostPath(service types.ServiceConfig, target string) (string, bool) {
+	var path, mountpoint string
+	var found bool
+
+	for _, volume := range service.Volumes {
+		if volume.Type != types.VolumeTypeBind || len(volume.Source) == 0 {
+			continue
+		}
+
+		rel, err := filepath.Rel(volume.Target, target)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+
+		if found && len(filepath.Clean(volume.Target)) <= len(mountpoint) {
+			continue
+		}
+
+		path = filepath.Join(volume.Source, rel)
+		mountpoint = filepath.Clean(volume.Target)
+		found = true
+	}
+
+	return path, found
+}
+
+// SyncPath makes the destination path a copy of the source path, which means
+// that it is removed if the source path no longer exists and that directories
+// are copied recursively.
+func SyncPath(src, dst string) error {
+	if filepath.Clean(src) == filepath.Clean(dst) {
+		return nil
+	}
+
+	if _, err := os.Lstat(src); errors.Is(err, fs.ErrNotExist) {
+		if err := os.RemoveAll(dst); err != nil {
+			return fmt.Errorf("could not remove %s: %w", dst, err)
+		}
+
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return os.MkdirAll(filepath.Join(dst, rel), info.Mode().Perm())
+		}
+
+		return copyFile(path, filepath.Join(dst, rel), info.Mode().Perm())
+	})
+}
+
+// copyFile copies the contents of the source file to the destination file,
+// which is created with the provided permissions if it does not exist.
+func copyFile(src, dst string, perm fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("could not copy %s to %s: %w", src, dst, err)
+	}
+
+	return out.Close()
+}
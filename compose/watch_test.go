@@ -0,0 +1,267 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package compose
+
+import (
+	"testing"
+
+	"github.com/compose-spec/compose-go/v2/types"
+)
+
+func TestWatchTriggerMatches(t *testing.T) {
+	trigger := WatchTrigger{
+		Trigger: types.Trigger{
+			Path:   "/src/app",
+			Action: types.WatchActionSync,
+			Target: "/app",
+			Ignore: []string{"node_modules", "/dist", "*.tmp"},
+		},
+		Service: "web",
+	}
+
+	for _, tc := range []struct {
+		name     string
+		path     string
+		expected bool
+	}{
+		{
+			name:     "watched path",
+			path:     "/src/app",
+			expected: true,
+		},
+		{
+			name:     "file",
+			path:     "/src/app/main.go",
+			expected: true,
+		},
+		{
+			name:     "nested file",
+			path:     "/src/app/pkg/http/server.go",
+			expected: true,
+		},
+		{
+			name:     "sibling with common prefix",
+			path:     "/src/application/main.go",
+			expected: false,
+		},
+		{
+			name:     "parent",
+			path:     "/src",
+			expected: false,
+		},
+		{
+			name:     "escape",
+			path:     "/src/app/../secret",
+			expected: false,
+		},
+		{
+			name:     "git directory",
+			path:     "/src/app/.git/objects/ab/cdef",
+			expected: false,
+		},
+		{
+			name:     "unikraft build directory",
+			path:     "/src/app/.unikraft/build/app_qemu-x86_64",
+			expected: false,
+		},
+		{
+			name:     "config",
+			path:     "/src/app/.config",
+			expected: false,
+		},
+		{
+			name:     "config backup",
+			path:     "/src/app/.config.old",
+			expected: false,
+		},
+		{
+			name:     "config prefix",
+			path:     "/src/app/.configure",
+			expected: true,
+		},
+		{
+			name:     "ignored directory",
+			path:     "/src/app/node_modules/lib/index.js",
+			expected: false,
+		},
+		{
+			name:     "nested ignored directory",
+			path:     "/src/app/web/node_modules/lib/index.js",
+			expected: false,
+		},
+		{
+			name:     "rooted ignore pattern",
+			path:     "/src/app/dist/bundle.js",
+			expected: false,
+		},
+		{
+			name:     "ignored extension",
+			path:     "/src/app/pkg/cache.tmp",
+			expected: false,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if matches := trigger.Matches(tc.path); matches != tc.expected {
+				t.Errorf("expected %s to match %t, got %t", tc.path, tc.expected, matches)
+			}
+		})
+	}
+}
+
+func TestWatchTriggerIgnores(t *testing.T) {
+	trigger := WatchTrigger{
+		Trigger: types.Trigger{
+			Path:   "/src",
+			Action: types.WatchActionRebuild,
+		},
+		Service: "web",
+	}
+
+	for _, tc := range []struct {
+		path     string
+		expected bool
+	}{
+		{path: "/src", expected: false},
+		{path: "/src/Kraftfile", expected: false},
+		{path: "/src/.git", expected: true},
+		{path: "/src/.git/HEAD", expected: true},
+		{path: "/src/lib/.git/HEAD", expected: true},
+		{path: "/src/.config.orig", expected: true},
+		{path: "/src/.unikraft/apps/app/.config", expected: true},
+		{path: "/src/config", expected: false},
+	} {
+		t.Run(tc.path, func(t *testing.T) {
+			if ignores := trigger.Ignores(tc.path); ignores != tc.expected {
+				t.Errorf("expected %s to be ignored %t, got %t", tc.path, tc.expected, ignores)
+			}
+		})
+	}
+}
+
+func TestWatchTriggerSyncTarget(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		trigger  types.Trigger
+		path     string
+		expected string
+		err      bool
+	}{
+		{
+			name:     "file",
+			trigger:  types.Trigger{Path: "/src/static", Target: "/srv/www"},
+			path:     "/src/static/css/main.css",
+			expected: "/srv/www/css/main.css",
+		},
+		{
+			name:     "watched path",
+			trigger:  types.Trigger{Path: "/src/static", Target: "/srv/www"},
+			path:     "/src/static",
+			expected: "/srv/www",
+		},
+		{
+			name:    "escape",
+			trigger: types.Trigger{Path: "/src/static", Target: "/srv/www"},
+			path:    "/src/static/../../etc/passwd",
+			err:     true,
+		},
+		{
+			name:    "outside",
+			trigger: types.Trigger{Path: "/src/static", Target: "/srv/www"},
+			path:    "/src/templates/index.html",
+			err:     true,
+		},
+		{
+			name:    "without target",
+			trigger: types.Trigger{Path: "/src/static"},
+			path:    "/src/static/index.html",
+			err:     true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.trigger.Action = types.WatchActionSync
+
+			target, err := WatchTrigger{Trigger: tc.trigger, Service: "web"}.SyncTarget(tc.path)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %s", target)
+				}
+
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if target != tc.expected {
+				t.Errorf("expected target %s, got %s", tc.expected, target)
+			}
+		})
+	}
+}
+
+func TestServiceHostPath(t *testing.T) {
+	service := types.ServiceConfig{
+		Name: "web",
+		Volumes: []types.ServiceVolumeConfig{
+			{Type: types.VolumeTypeBind, Source: "/home/user/site", Target: "/srv/www"},
+			{Type: types.VolumeTypeBind, Source: "/home/user/uploads", Target: "/srv/www/uploads"},
+			{Type: types.VolumeTypeVolume, Source: "data", Target: "/var/lib/data"},
+			{Type: types.VolumeTypeBind, Target: "/tmp"},
+		},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		target   string
+		expected string
+	}{
+		{
+			name:     "mountpoint",
+			target:   "/srv/www",
+			expected: "/home/user/site",
+		},
+		{
+			name:     "file",
+			target:   "/srv/www/css/main.css",
+			expected: "/home/user/site/css/main.css",
+		},
+		{
+			name:     "nested bind mount",
+			target:   "/srv/www/uploads/avatar.png",
+			expected: "/home/user/uploads/avatar.png",
+		},
+		{
+			name:     "sibling with common prefix",
+			target:   "/srv/www2/index.html",
+			expected: "",
+		},
+		{
+			name:     "escape",
+			target:   "/srv/www/../secret",
+			expected: "",
+		},
+		{
+			name:     "named volume",
+			target:   "/var/lib/data/db",
+			expected: "",
+		},
+		{
+			name:     "bind mount without source",
+			target:   "/tmp/file",
+			expected: "",
+		},
+		{
+			name:     "outside of bind mounts",
+			target:   "/etc/nginx/nginx.conf",
+			expected: "",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path, ok := ServiceHostPath(service, tc.target)
+			if ok != (len(tc.expected) > 0) || path != tc.expected {
+				t.Errorf("expected host path %q, got %q (%t)", tc.expected, path, ok)
+			}
+		})
+	}
+}
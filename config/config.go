@@ -14,24 +14,36 @@ type AuthConfig struct {
 	VerifySSL bool   `yaml:"verify_ssl" env:"KRAFTKIT_AUTH_%s_VERIFY_SSL" long:"auth-%s-verify-ssl" default:"true"`
 }
 
+// RegistryConfig represents how a remote OCI registry is accessed.  Mirrors
+// are tried in order before the registry itself when pulling and are each
+// either a host or a host followed by a path which is prefixed to the
+// repositories of the registry, e.g. "harbor.example.com/unikraft.org".
+type RegistryConfig struct {
+	Mirrors   []string `yaml:"mirrors,omitempty"`
+	CAFile    string   `yaml:"ca_file,omitempty"`
+	Insecure  bool     `yaml:"insecure,omitempty"`
+	PlainHTTP bool     `yaml:"plain_http,omitempty"`
+}
+
 type KraftKit struct {
-	NoPrompt       bool   `yaml:"no_prompt" env:"KRAFTKIT_NO_PROMPT" long:"no-prompt" usage:"Do not prompt for user interaction" default:"false"`
-	NoParallel     bool   `yaml:"no_parallel" env:"KRAFTKIT_NO_PARALLEL" long:"no-parallel" usage:"Do not run internal tasks in parallel" default:"false"`
-	NoEmojis       bool   `yaml:"no_emojis" env:"KRAFTKIT_NO_EMOJIS" long:"no-emojis" usage:"Do not use emojis in any console output" default:"true"`
-	NoCheckUpdates bool   `yaml:"no_check_updates" env:"KRAFTKIT_NO_CHECK_UPDATES" long:"no-check-updates" usage:"Do not check for updates" default:"false"`
-	NoColor        bool   `yaml:"no_color" env:"KRAFTKIT_NO_COLOR" long:"no-color" usage:"Disable color output"`
-	NoWarnSudo     bool   `yaml:"no_warn_sudo" env:"KRAFTKIT_NO_WARN_SUDO" long:"no-warn-sudo" usage:"Do not warn on running via sudo" default:"false"`
-	Editor         string `yaml:"editor" env:"KRAFTKIT_EDITOR" long:"editor" usage:"Set the text editor to open when prompt to edit a file"`
-	GitProtocol    string `yaml:"git_protocol" env:"KRAFTKIT_GIT_PROTOCOL" long:"git-protocol" usage:"Preferred Git protocol to use" default:"https"`
-	Pager          string `yaml:"pager,omitempty" env:"KRAFTKIT_PAGER" long:"pager" usage:"System pager to pipe output to" default:"cat"`
-	Qemu           string `yaml:"qemu,omitempty" env:"KRAFTKIT_QEMU" long:"qemu" usage:"Path to QEMU executable" default:""`
-	HTTPUnixSocket string `yaml:"http_unix_socket,omitempty" env:"KRAFTKIT_HTTP_UNIX_SOCKET" long:"http-unix-sock" usage:"When making HTTP(S) connections, pipe requests via this shared socket"`
-	RuntimeDir     string `yaml:"runtime_dir" env:"KRAFTKIT_RUNTIME_DIR" long:"runtime-dir" usage:"Directory for placing runtime files (e.g. pidfiles)"`
-	DefaultPlat    string `yaml:"default_plat" env:"KRAFTKIT_DEFAULT_PLAT" usage:"The default platform to use when invoking platform-specific code" noattribute:"true"`
-	DefaultArch    string `yaml:"default_arch" env:"KRAFTKIT_DEFAULT_ARCH" usage:"The default architecture to use when invoking architecture-specific code" noattribute:"true"`
-	ContainerdAddr string `yaml:"containerd_addr,omitempty" env:"KRAFTKIT_CONTAINERD_ADDR" long:"containerd-addr" usage:"Address of containerd daemon socket" default:""`
-	EventsPidFile  string `yaml:"events_pidfile" env:"KRAFTKIT_EVENTS_PIDFILE" long:"events-pid-file" usage:"Events process ID used when running multiple unikernels"`
-	BuildKitHost   string `yaml:"buildkit_host" env:"KRAFTKIT_BUILDKIT_HOST" long:"buildkit-host" usage:"Path to the buildkit host" default:""`
+	NoPrompt        bool   `yaml:"no_prompt" env:"KRAFTKIT_NO_PROMPT" long:"no-prompt" usage:"Do not prompt for user interaction" default:"false"`
+	NoParallel      bool   `yaml:"no_parallel" env:"KRAFTKIT_NO_PARALLEL" long:"no-parallel" usage:"Do not run internal tasks in parallel" default:"false"`
+	NoEmojis        bool   `yaml:"no_emojis" env:"KRAFTKIT_NO_EMOJIS" long:"no-emojis" usage:"Do not use emojis in any console output" default:"true"`
+	NoCheckUpdates  bool   `yaml:"no_check_updates" env:"KRAFTKIT_NO_CHECK_UPDATES" long:"no-check-updates" usage:"Do not check for updates" default:"false"`
+	NoColor         bool   `yaml:"no_color" env:"KRAFTKIT_NO_COLOR" long:"no-color" usage:"Disable color output"`
+	NoWarnSudo      bool   `yaml:"no_warn_sudo" env:"KRAFTKIT_NO_WARN_SUDO" long:"no-warn-sudo" usage:"Do not warn on running via sudo" default:"false"`
+	Editor          string `yaml:"editor" env:"KRAFTKIT_EDITOR" long:"editor" usage:"Set the text editor to open when prompt to edit a file"`
+	GitProtocol     string `yaml:"git_protocol" env:"KRAFTKIT_GIT_PROTOCOL" long:"git-protocol" usage:"Preferred Git protocol to use" default:"https"`
+	Pager           string `yaml:"pager,omitempty" env:"KRAFTKIT_PAGER" long:"pager" usage:"System pager to pipe output to" default:"cat"`
+	Qemu            string `yaml:"qemu,omitempty" env:"KRAFTKIT_QEMU" long:"qemu" usage:"Path to QEMU executable" default:""`
+	HTTPUnixSocket  string `yaml:"http_unix_socket,omitempty" env:"KRAFTKIT_HTTP_UNIX_SOCKET" long:"http-unix-sock" usage:"When making HTTP(S) connections, pipe requests via this shared socket"`
+	RuntimeDir      string `yaml:"runtime_dir" env:"KRAFTKIT_RUNTIME_DIR" long:"runtime-dir" usage:"Directory for placing runtime files (e.g. pidfiles)"`
+	DefaultPlat     string `yaml:"default_plat" env:"KRAFTKIT_DEFAULT_PLAT" usage:"The default platform to use when invoking platform-specific code" noattribute:"true"`
+	DefaultArch     string `yaml:"default_arch" env:"KRAFTKIT_DEFAULT_ARCH" usage:"The default architecture to use when invoking architecture-specific code" noattribute:"true"`
+	ContainerdAddr  string `yaml:"containerd_addr,omitempty" env:"KRAFTKIT_CONTAINERD_ADDR" long:"containerd-addr" usage:"Address of containerd daemon socket" default:""`
+	EventsPidFile   string `yaml:"events_pidfile" env:"KRAFTKIT_EVENTS_PIDFILE" long:"events-pid-file" usage:"Events process ID used when running multiple unikernels"`
+	BuildKitHost    string `yaml:"buildkit_host" env:"KRAFTKIT_BUILDKIT_HOST" long:"buildkit-host" usage:"Path to the buildkit host" default:""`
+	SignaturePolicy string `yaml:"signature_policy,omitempty" env:"KRAFTKIT_SIGNATURE_POLICY" long:"signature-policy" usage:"Path to the policy used to verify the signatures of packages"`
 
 	Paths struct {
 		Plugins   string `yaml:"plugins,omitempty" env:"KRAFTKIT_PATHS_PLUGINS" long:"plugins-dir" usage:"Path to KraftKit plugin directory"`
@@ -46,6 +58,11 @@ type KraftKit struct {
 		Type       string `yaml:"type" env:"KRAFTKIT_LOG_TYPE" long:"log-type" usage:"Log type. Choice of: [fancy, basic, json]" default:"fancy"`
 	} `yaml:"log"`
 
+	Ingress struct {
+		HTTPAddr  string `yaml:"http_addr,omitempty" env:"KRAFTKIT_INGRESS_HTTP_ADDR" long:"ingress-http-addr" usage:"Address on which the local ingress serves HTTP" default:"127.0.0.1:8000"`
+		HTTPSAddr string `yaml:"https_addr,omitempty" env:"KRAFTKIT_INGRESS_HTTPS_ADDR" long:"ingress-https-addr" usage:"Address on which the local ingress terminates TLS" default:"127.0.0.1:8443"`
+	} `yaml:"ingress,omitempty"`
+
 	Unikraft struct {
 		Mirrors   []string `yaml:"mirrors" env:"KRAFTKIT_UNIKRAFT_MIRRORS" long:"with-mirror" usage:"Paths to mirrors of Unikraft component artifacts"`
 		Manifests []string `yaml:"manifests" env:"KRAFTKIT_UNIKRAFT_MANIFESTS" long:"with-manifest" usage:"Paths to package or component manifests"`
@@ -53,6 +70,8 @@ type KraftKit struct {
 
 	Auth map[string]AuthConfig `yaml:"auth,omitempty" noattribute:"true"`
 
+	Registries map[string]RegistryConfig `yaml:"registries,omitempty" noattribute:"true"`
+
 	Aliases map[string]map[string]string `yaml:"aliases" noattribute:"true"`
 }
 
@@ -88,6 +107,10 @@ var configDetails = []ConfigDetail{
 		Key:         "pager",
 		Description: "the terminal pager program to send standard output to",
 	},
+	{
+		Key:         "signature_policy",
+		Description: "the policy which package signatures are verified against",
+	},
 	{
 		Key:         "log.level",
 		Description: "Set the logging verbosity",
@@ -114,6 +137,14 @@ var configDetails = []ConfigDetail{
 		Key:         "log.timestamps",
 		Description: "Show timestamps with log output",
 	},
+	{
+		Key:         "ingress.http_addr",
+		Description: "the address on which the local ingress serves HTTP",
+	},
+	{
+		Key:         "ingress.https_addr",
+		Description: "the address on which the local ingress terminates TLS",
+	},
 }
 
 func ConfigDetails() []ConfigDetail {
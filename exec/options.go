@@ -5,6 +5,7 @@
 package exec
 
 import (
+	"context"
 	"fmt"
 	"io"
 )
@@ -18,6 +19,14 @@ type ExecOptions struct {
 	env       []string
 	callbacks []func(int)
 	detach    bool
+	executor  Executor
+}
+
+// Executor is used to execute a process in an environment other than the host,
+// for example within a container.  It must block until the process has
+// exited and return its exit code.
+type Executor interface {
+	Execute(ctx context.Context, bin string, args, env []string, stdin io.Reader, stdout, stderr io.Writer) (int, error)
 }
 
 type ExecOption func(eo *ExecOptions) error
@@ -118,3 +127,12 @@ func WithDetach(detach bool) ExecOption {
 		return nil
 	}
 }
+
+// WithExecutor delegates the execution of the process to the provided
+// executor instead of starting it on the host.
+func WithExecutor(executor Executor) ExecOption {
+	return func(eo *ExecOptions) error {
+		eo.executor = executor
+		return nil
+	}
+}
@@ -20,6 +20,11 @@ type Process struct {
 	executable *Executable
 	opts       *ExecOptions
 	cmd        *exec.Cmd
+
+	// Set when the process is run via an Executor.
+	exited   chan struct{}
+	exitCode int
+	exitErr  error
 }
 
 // NewProcess prepares a process to be executed from a given binary name and
@@ -68,47 +73,74 @@ func (e *Process) Cmdline() string {
 
 // Start the process
 func (e *Process) Start(ctx context.Context) error {
-	e.cmd = exec.Command(
-		e.executable.bin,
-		e.executable.Args()...,
-	)
+	var stdout, stderr io.Writer
 
 	// Set the stdout
 	if e.opts.stdout != nil && len(e.opts.stdoutcbs) == 0 {
-		e.cmd.Stdout = e.opts.stdout
+		stdout = e.opts.stdout
 	} else if e.opts.stdout != nil && len(e.opts.stdoutcbs) > 0 {
-		e.cmd.Stdout = io.MultiWriter(
+		stdout = io.MultiWriter(
 			append([]io.Writer{e.opts.stdout}, e.opts.stdoutcbs...)...,
 		)
 	} else if len(e.opts.stdoutcbs) > 0 {
-		e.cmd.Stdout = io.MultiWriter(e.opts.stdoutcbs...)
+		stdout = io.MultiWriter(e.opts.stdoutcbs...)
 	}
 
 	// Set the stderr
 	if e.opts.stderr != nil && len(e.opts.stderrcbs) == 0 {
-		e.cmd.Stderr = e.opts.stderr
+		stderr = e.opts.stderr
 	} else if e.opts.stderr != nil && len(e.opts.stderrcbs) > 0 {
-		e.cmd.Stderr = io.MultiWriter(
+		stderr = io.MultiWriter(
 			append([]io.Writer{e.opts.stderr}, e.opts.stderrcbs...)...,
 		)
 	} else if e.opts.stdout != nil && len(e.opts.stderrcbs) == 0 {
-		e.cmd.Stderr = e.opts.stdout
+		stderr = e.opts.stdout
 	} else if e.opts.stdout != nil && len(e.opts.stderrcbs) > 0 {
-		e.cmd.Stderr = io.MultiWriter(
+		stderr = io.MultiWriter(
 			append([]io.Writer{e.opts.stdout}, e.opts.stderrcbs...)...,
 		)
 	} else if len(e.opts.stderrcbs) > 0 {
-		e.cmd.Stderr = io.MultiWriter(e.opts.stderrcbs...)
+		stderr = io.MultiWriter(e.opts.stderrcbs...)
 	}
 
+	log.G(ctx).Debug(e.Cmdline())
+
+	if e.opts.executor != nil {
+		e.exited = make(chan struct{})
+
+		go func() {
+			defer close(e.exited)
+
+			e.exitCode, e.exitErr = e.opts.executor.Execute(ctx,
+				e.executable.bin,
+				e.executable.Args(),
+				e.opts.env,
+				e.opts.stdin,
+				stdout,
+				stderr,
+			)
+			if e.exitErr == nil && e.exitCode != 0 {
+				e.exitErr = fmt.Errorf("exit status %d", e.exitCode)
+			}
+		}()
+
+		return nil
+	}
+
+	e.cmd = exec.Command(
+		e.executable.bin,
+		e.executable.Args()...,
+	)
+
+	e.cmd.Stdout = stdout
+	e.cmd.Stderr = stderr
+
 	// Set the stdin
 	e.cmd.Stdin = e.opts.stdin
 
 	// Add any set environmental variables including the host's
 	e.cmd.Env = append(os.Environ(), e.opts.env...)
 
-	log.G(ctx).Debug(e.Cmdline())
-
 	if e.opts.detach {
 		e.cmd.SysProcAttr = hostAttributes()
 		e.cmd.Stdin = nil
@@ -123,6 +155,16 @@ func (e *Process) Start(ctx context.Context) error {
 
 // Wait for the process to complete
 func (e *Process) Wait() error {
+	if e.exited != nil {
+		<-e.exited
+
+		for _, cb := range e.opts.callbacks {
+			cb(e.exitCode)
+		}
+
+		return e.exitErr
+	}
+
 	if e.cmd == nil {
 		return fmt.Errorf("process has not yet started cannot wait")
 	}
@@ -155,6 +197,10 @@ func (e *Process) StartAndWait(ctx context.Context) error {
 // Signal sends a signal to the running process.  If this fails, for example if
 // the process is not running, this will return an error.
 func (e *Process) Signal(signal syscall.Signal) error {
+	if e.cmd == nil || e.cmd.Process == nil {
+		return fmt.Errorf("process is not running on the host")
+	}
+
 	return e.cmd.Process.Signal(signal)
 }
 
@@ -0,0 +1,206 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package initrd
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"kraftkit.sh/cpio"
+)
+
+// HostsFile is the location of the static host name lookup table in the root
+// file system.
+const HostsFile = "/etc/hosts"
+
+// defaultHosts are the entries which are written to a newly created hosts
+// file.
+var defaultHosts = []string{
+	"127.0.0.1\tlocalhost",
+	"::1\tlocalhost",
+}
+
+// ParseHost parses an additional host entry in the format `<host>=<ip>` or
+// `<host>:<ip>` and returns the corresponding line of a hosts file.
+func ParseHost(entry string) (string, error) {
+	host, ip, ok := strings.Cut(entry, "=")
+	if !ok {
+		host, ip, ok = strings.Cut(entry, ":")
+	}
+	if !ok || len(host) == 0 {
+		return "", fmt.Errorf("invalid host entry '%s': expected <host>=<ip>", entry)
+	}
+
+	// IPv6 addresses may be enclosed in brackets, e.g. host=[::1].
+	ip = strings.TrimSuffix(strings.TrimPrefix(ip, "["), "]")
+	if net.ParseIP(ip) == nil {
+		return "", fmt.Errorf("invalid host entry '%s': '%s' is not an IP address", entry, ip)
+	}
+
+	return ip + "\t" + host, nil
+}
+
+// AddHosts reads the CPIO archive at the provided input path and writes it to
+// the provided output path with the provided host entries (see ParseHost)
+// appended to its hosts file.  The hosts file is created if the archive does
+// not contain one.  A gzip-compressed archive remains compressed.
+func AddHosts(input, output string, hosts []string) error {
+	lines := make([]string, 0, len(hosts))
+	for _, entry := range hosts {
+		line, err := ParseHost(entry)
+		if err != nil {
+			return err
+		}
+
+		lines = append(lines, line)
+	}
+
+	in, err := os.Open(input)
+	if err != nil {
+		return fmt.Errorf("could not open initramfs file: %w", err)
+	}
+
+	defer in.Close()
+
+	br := bufio.NewReader(in)
+	var src io.Reader = br
+
+	compressed := false
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return fmt.Errorf("could not decompress initramfs file: %w", err)
+		}
+
+		defer gr.Close()
+
+		src = gr
+		compressed = true
+	}
+
+	out, err := os.OpenFile(output, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("could not open initramfs file: %w", err)
+	}
+
+	defer out.Close()
+
+	var dst io.Writer = out
+	var gw *gzip.Writer
+	if compressed {
+		gw = gzip.NewWriter(out)
+		dst = gw
+	}
+
+	reader := cpio.NewReader(src)
+	writer := cpio.NewWriter(dst)
+
+	var existing bytes.Buffer
+	hasEtc := false
+
+	for {
+		hdr, _, err := reader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("could not read CPIO header: %w", err)
+		}
+
+		switch path.Join("/", hdr.Name) {
+		case path.Dir(HostsFile):
+			hasEtc = true
+
+		case HostsFile:
+			// Retain the entries of a regular hosts file and replace any other
+			// type of entry, e.g. a symbolic link, with a regular file.
+			if hdr.Mode.IsRegular() {
+				if _, err := io.Copy(&existing, reader); err != nil {
+					return fmt.Errorf("could not read hosts file: %w", err)
+				}
+			}
+
+			continue
+		}
+
+		header := &cpio.Header{
+			Name:     hdr.Name,
+			Linkname: hdr.Linkname,
+			Links:    hdr.Links,
+			Size:     hdr.Size,
+			Mode:     hdr.Mode,
+			Uid:      hdr.Uid,
+			Guid:     hdr.Guid,
+			ModTime:  hdr.ModTime,
+		}
+
+		// The reader consumes the target of a symbolic link as its name whereas
+		// the writer expects it as the contents of the entry.
+		var data io.Reader = reader
+		if hdr.Mode&^cpio.ModePerm == cpio.TypeSymlink {
+			header.Size = int64(len(hdr.Linkname))
+			data = strings.NewReader(hdr.Linkname)
+		}
+
+		if err := writer.WriteHeader(header); err != nil {
+			return fmt.Errorf("writing cpio header for %q: %w", hdr.Name, err)
+		}
+
+		if _, err := io.Copy(writer, data); err != nil {
+			return fmt.Errorf("could not write CPIO data for %s: %w", hdr.Name, err)
+		}
+	}
+
+	if !hasEtc {
+		if err := writer.WriteHeader(&cpio.Header{
+			Name:    "." + path.Dir(HostsFile),
+			Mode:    cpio.TypeDir | 0o755,
+			ModTime: time.Now(),
+		}); err != nil {
+			return fmt.Errorf("could not write CPIO header: %w", err)
+		}
+	}
+
+	content := existing.String()
+	if len(content) == 0 {
+		content = strings.Join(defaultHosts, "\n") + "\n"
+	} else if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+
+	content += strings.Join(lines, "\n") + "\n"
+
+	if err := writer.WriteHeader(&cpio.Header{
+		Name:    "." + HostsFile,
+		Mode:    cpio.TypeReg | 0o644,
+		ModTime: time.Now(),
+		Size:    int64(len(content)),
+	}); err != nil {
+		return fmt.Errorf("could not write CPIO header: %w", err)
+	}
+
+	if _, err := writer.Write([]byte(content)); err != nil {
+		return fmt.Errorf("could not write hosts file: %w", err)
+	}
+
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("could not close CPIO writer: %w", err)
+	}
+
+	if gw != nil {
+		if err := gw.Close(); err != nil {
+			return fmt.Errorf("could not close gzip writer: %w", err)
+		}
+	}
+
+	return out.Sync()
+}
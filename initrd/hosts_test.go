@@ -0,0 +1,126 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package initrd_test
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"kraftkit.sh/cpio"
+	"kraftkit.sh/initrd"
+)
+
+// readArchive returns the contents of the regular files and the names of all
+// other entries of the CPIO archive read from the provided reader.
+func readArchive(t *testing.T, r io.Reader) map[string]string {
+	t.Helper()
+
+	entries := map[string]string{}
+	reader := cpio.NewReader(r)
+
+	for {
+		hdr, _, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal("Failed to read next cpio header:", err)
+		}
+
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatal("Failed to read cpio data:", err)
+		}
+
+		entries[hdr.Name] = string(data)
+	}
+
+	return entries
+}
+
+func TestAddHosts(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	ird, err := initrd.NewFromDirectory(ctx, "testdata/rootfs",
+		initrd.WithOutput(filepath.Join(dir, "plain.cpio")),
+	)
+	if err != nil {
+		t.Fatal("NewFromDirectory:", err)
+	}
+
+	plain, err := ird.Build(ctx)
+	if err != nil {
+		t.Fatal("Build:", err)
+	}
+
+	withHosts := filepath.Join(dir, "hosts.cpio")
+	if err := initrd.AddHosts(plain, withHosts, []string{"db=10.0.0.2", "cache:[fd00::3]"}); err != nil {
+		t.Fatal("AddHosts:", err)
+	}
+
+	entries := readArchive(t, openFile(t, withHosts))
+
+	expected := "127.0.0.1\tlocalhost\n::1\tlocalhost\n10.0.0.2\tdb\nfd00::3\tcache\n"
+	if got := entries["./etc/hosts"]; got != expected {
+		t.Errorf("expected hosts file %q, got %q", expected, got)
+	}
+
+	if got := entries["./etc/app.conf"]; len(got) != 16 {
+		t.Errorf("expected existing files to be retained, got %q", got)
+	}
+
+	// Entries are appended to an existing hosts file.
+	again := filepath.Join(dir, "again.cpio")
+	if err := initrd.AddHosts(withHosts, again, []string{"web=10.0.0.4"}); err != nil {
+		t.Fatal("AddHosts:", err)
+	}
+
+	entries = readArchive(t, openFile(t, again))
+
+	expected += "10.0.0.4\tweb\n"
+	if got := entries["./etc/hosts"]; got != expected {
+		t.Errorf("expected hosts file %q, got %q", expected, got)
+	}
+
+	// Compressed archives remain compressed.
+	ird, err = initrd.NewFromDirectory(ctx, "testdata/rootfs",
+		initrd.WithOutput(filepath.Join(dir, "compressed.cpio")),
+		initrd.WithCompression(true),
+	)
+	if err != nil {
+		t.Fatal("NewFromDirectory:", err)
+	}
+
+	compressed, err := ird.Build(ctx)
+	if err != nil {
+		t.Fatal("Build:", err)
+	}
+
+	output := filepath.Join(dir, "compressed-hosts.cpio")
+	if err := initrd.AddHosts(compressed, output, []string{"db=10.0.0.2"}); err != nil {
+		t.Fatal("AddHosts:", err)
+	}
+
+	gr, err := gzip.NewReader(openFile(t, output))
+	if err != nil {
+		t.Fatal("Expected a compressed archive:", err)
+	}
+
+	entries = readArchive(t, gr)
+
+	if _, ok := entries["./etc/hosts"]; !ok {
+		t.Error("expected compressed archive to contain a hosts file")
+	}
+
+	for _, invalid := range []string{"db", "=10.0.0.2", "db=database"} {
+		if err := initrd.AddHosts(plain, output, []string{invalid}); err == nil {
+			t.Errorf("expected host entry '%s' to be rejected", invalid)
+		}
+	}
+}
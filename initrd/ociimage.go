@@ -14,6 +14,8 @@ import (
 
 	"kraftkit.sh/cpio"
 	"kraftkit.sh/log"
+	"kraftkit.sh/oci/cosign"
+	ociutils "kraftkit.sh/oci/utils"
 
 	"github.com/anchore/stereoscope"
 	scfile "github.com/anchore/stereoscope/pkg/file"
@@ -24,16 +26,17 @@ import (
 	"github.com/containers/image/v5/signature"
 	"github.com/containers/image/v5/transports/alltransports"
 	"github.com/containers/image/v5/types"
-	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 )
 
 type ociimage struct {
-	imageName string
-	opts      InitrdOptions
-	args      []string
-	ref       types.ImageReference
-	env       []string
+	imageName  string
+	opts       InitrdOptions
+	args       []string
+	ref        types.ImageReference
+	env        []string
+	domain     string
+	registries ociutils.Registries
 }
 
 // NewFromOCIImage creates a new initrd from a remote container image.
@@ -43,15 +46,41 @@ func NewFromOCIImage(ctx context.Context, path string, opts ...InitrdOption) (In
 		transport, path, _ = strings.Cut(path, "://")
 	}
 
-	nref, err := name.ParseReference(path)
+	registries := ociutils.RegistriesFromContext(ctx)
+
+	nref, err := registries.ParseReference(path)
 	if err != nil {
 		return nil, err
 	}
 
-	if desc, err := remote.Head(nref); err != nil || desc == nil {
+	sources, err := registries.Sources(nref, remote.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	// Retrieve the image from the first of the mirrors of its registry which
+	// serves it, falling back to the registry itself.
+	source, err := ociutils.Fetch(ctx, sources, func(src ociutils.Source) (ociutils.Source, error) {
+		if _, err := remote.Head(src.Ref, src.Options...); err != nil {
+			return src, err
+		}
+
+		return src, nil
+	})
+	if err != nil {
 		return nil, fmt.Errorf("could not find image: %w", err)
 	}
 
+	path = source.Ref.Name()
+
+	// Pin the image to the digest which has been verified such that the image
+	// cannot be altered between verifying and retrieving it.
+	if dgst, err := cosign.VerifyFromContext(ctx, nil, nref); err != nil {
+		return nil, fmt.Errorf("could not verify signature of image: %w", err)
+	} else if len(dgst) > 0 {
+		path = fmt.Sprintf("%s@%s", source.Ref.Context().Name(), dgst.String())
+	}
+
 	if !strings.Contains("://", path) {
 		path = fmt.Sprintf("docker://%s", path)
 	} else {
@@ -64,8 +93,10 @@ func NewFromOCIImage(ctx context.Context, path string, opts ...InitrdOption) (In
 	}
 
 	initrd := ociimage{
-		imageName: path,
-		ref:       ref,
+		imageName:  path,
+		ref:        ref,
+		domain:     source.Ref.Context().RegistryStr(),
+		registries: registries,
 	}
 
 	for _, opt := range opts {
@@ -84,8 +115,41 @@ func (initrd *ociimage) Name() string {
 
 // Build implements Initrd.
 func (initrd *ociimage) Build(ctx context.Context) (string, error) {
+	cfg := initrd.registries.Config(initrd.domain)
 	sysCtx := &types.SystemContext{
-		OSChoice: "linux",
+		OSChoice:                    "linux",
+		DockerInsecureSkipTLSVerify: types.NewOptionalBool(cfg.Insecure || cfg.PlainHTTP),
+	}
+
+	if auth, ok := initrd.registries.Auths[initrd.domain]; ok {
+		sysCtx.DockerAuthConfig = &types.DockerAuthConfig{
+			Username: auth.User,
+			Password: auth.Token,
+		}
+	}
+
+	// The CA bundle of the registry is only trusted from within a directory of
+	// certificates.
+	if len(cfg.CAFile) > 0 && !cfg.Insecure {
+		bundle, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return "", fmt.Errorf("could not read CA bundle of registry '%s': %w", initrd.domain, err)
+		}
+
+		certDir, err := os.MkdirTemp("", "")
+		if err != nil {
+			return "", fmt.Errorf("could not make certificate directory: %w", err)
+		}
+
+		defer func() {
+			_ = os.RemoveAll(certDir)
+		}()
+
+		if err := os.WriteFile(filepath.Join(certDir, "ca.crt"), bundle, 0o644); err != nil {
+			return "", fmt.Errorf("could not write CA bundle: %w", err)
+		}
+
+		sysCtx.DockerCertPath = certDir
 	}
 
 	if initrd.opts.arch == "x86_64" {
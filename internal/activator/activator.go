@@ -0,0 +1,378 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+// Package activator holds the listening sockets of a machine on its behalf,
+// such that the machine only needs to run whilst connections are being served.
+// The machine is woken upon the first connection, the traffic is spliced
+// through to it and it is put back to sleep once it has been idle for a
+// cooldown period.
+package activator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"kraftkit.sh/internal/netutil"
+	"kraftkit.sh/log"
+)
+
+const (
+	// DefaultCooldown is the period without any connection after which the
+	// machine is put to sleep.
+	DefaultCooldown = 30 * time.Second
+
+	// DefaultReadyTimeout is the time after which waiting for a woken machine
+	// to accept connections is given up.
+	DefaultReadyTimeout = 30 * time.Second
+)
+
+// probeInterval is the time between attempts of checking whether a woken
+// machine accepts connections.
+const probeInterval = 100 * time.Millisecond
+
+// Func is a transition of the machine, i.e. waking it or putting it to sleep.
+type Func func(ctx context.Context) error
+
+// binding is a listener of the activator along with the address of the
+// machine to which its connections are forwarded.
+type binding struct {
+	listener net.Listener
+	backend  string
+}
+
+// Activator wakes a machine upon connections to its listeners and puts it to
+// sleep once it is idle.
+type Activator struct {
+	bindings     []binding
+	wake         Func
+	sleep        Func
+	cooldown     time.Duration
+	readyTimeout time.Duration
+
+	mu         sync.Mutex
+	awake      bool
+	active     int
+	generation uint64
+	timer      *time.Timer
+}
+
+// ActivatorOption is a function which modifies the activator.
+type ActivatorOption func(*Activator) error
+
+// WithListener forwards the connections accepted by the provided listener to
+// the provided backend address, in the format host:port.  The listener is
+// closed once the activator stops serving.
+func WithListener(listener net.Listener, backend string) ActivatorOption {
+	return func(a *Activator) error {
+		if listener == nil {
+			return fmt.Errorf("listener cannot be nil")
+		}
+
+		a.bindings = append(a.bindings, binding{
+			listener: listener,
+			backend:  backend,
+		})
+
+		return nil
+	}
+}
+
+// WithWake sets the function which starts or resumes the machine.
+func WithWake(wake Func) ActivatorOption {
+	return func(a *Activator) error {
+		a.wake = wake
+		return nil
+	}
+}
+
+// WithSleep sets the function which pauses or stops the machine.
+func WithSleep(sleep Func) ActivatorOption {
+	return func(a *Activator) error {
+		a.sleep = sleep
+		return nil
+	}
+}
+
+// WithCooldown sets the period without any connection after which the machine
+// is put to sleep.
+func WithCooldown(cooldown time.Duration) ActivatorOption {
+	return func(a *Activator) error {
+		if cooldown <= 0 {
+			return fmt.Errorf("cooldown must be positive")
+		}
+
+		a.cooldown = cooldown
+		return nil
+	}
+}
+
+// WithReadyTimeout sets the time after which waiting for a woken machine to
+// accept connections is given up.
+func WithReadyTimeout(timeout time.Duration) ActivatorOption {
+	return func(a *Activator) error {
+		if timeout <= 0 {
+			return fmt.Errorf("ready timeout must be positive")
+		}
+
+		a.readyTimeout = timeout
+		return nil
+	}
+}
+
+// New returns an activator of a machine which is initially asleep.
+func New(opts ...ActivatorOption) (*Activator, error) {
+	a := Activator{
+		cooldown:     DefaultCooldown,
+		readyTimeout: DefaultReadyTimeout,
+	}
+
+	for _, opt := range opts {
+		if err := opt(&a); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(a.bindings) == 0 {
+		return nil, fmt.Errorf("no listeners provided")
+	}
+
+	if a.wake == nil || a.sleep == nil {
+		return nil, fmt.Errorf("both a wake and a sleep function must be provided")
+	}
+
+	return &a, nil
+}
+
+// Awake returns whether the machine is currently awake.
+func (a *Activator) Awake() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.awake
+}
+
+// Serve accepts connections on all listeners until the context is cancelled
+// and waits for the connections being served to end.  The machine is left in
+// whichever state it is in.
+func (a *Activator) Serve(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		<-ctx.Done()
+		for _, b := range a.bindings {
+			b.listener.Close()
+		}
+	}()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(a.bindings))
+
+	for _, b := range a.bindings {
+		wg.Add(1)
+		go func(b binding) {
+			defer wg.Done()
+
+			if err := a.accept(ctx, &wg, b); err != nil {
+				errs <- err
+				cancel()
+			}
+		}(b)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	a.mu.Lock()
+	if a.timer != nil {
+		a.timer.Stop()
+	}
+	a.mu.Unlock()
+
+	var ret []error
+	for err := range errs {
+		ret = append(ret, err)
+	}
+
+	return errors.Join(ret...)
+}
+
+// accept serves the connections of the provided binding until its listener is
+// closed.
+func (a *Activator) accept(ctx context.Context, wg *sync.WaitGroup, b binding) error {
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+
+			return err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.handle(ctx, conn, b.backend)
+		}()
+	}
+}
+
+// handle wakes the machine, if necessary, and forwards the provided
+// connection to the backend.
+func (a *Activator) handle(ctx context.Context, conn net.Conn, backend string) {
+	defer conn.Close()
+
+	if err := a.acquire(ctx, backend); err != nil {
+		log.G(ctx).Errorf("could not wake machine: %v", err)
+		return
+	}
+
+	defer a.release(ctx)
+
+	dialer := net.Dialer{Timeout: a.readyTimeout}
+	upstream, err := dialer.DialContext(ctx, "tcp", backend)
+	if err != nil {
+		log.G(ctx).Warnf("could not connect to %s: %v", backend, err)
+		return
+	}
+
+	defer upstream.Close()
+
+	netutil.Splice(conn, upstream)
+}
+
+// acquire registers a new connection and wakes the machine if it is asleep,
+// waiting until it accepts connections at the provided backend.
+func (a *Activator) acquire(ctx context.Context, backend string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.active++
+	a.generation++
+
+	if a.timer != nil {
+		a.timer.Stop()
+		a.timer = nil
+	}
+
+	if a.awake {
+		return nil
+	}
+
+	log.G(ctx).Info("waking machine")
+
+	start := time.Now()
+
+	if err := a.wake(ctx); err != nil {
+		a.active--
+		return err
+	}
+
+	a.awake = true
+
+	if err := waitReady(ctx, backend, a.readyTimeout); err != nil {
+		a.active--
+		a.schedule(ctx)
+		return err
+	}
+
+	log.G(ctx).
+		WithField("took", time.Since(start).Round(time.Millisecond)).
+		Info("machine is awake")
+
+	return nil
+}
+
+// release unregisters a connection and schedules the machine to be put to
+// sleep if it was the last one.
+func (a *Activator) release(ctx context.Context) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.active--
+	a.schedule(ctx)
+}
+
+// schedule puts the machine to sleep after the cooldown unless a connection
+// is made in the meantime.  It must be called with the lock held.
+func (a *Activator) schedule(ctx context.Context) {
+	if a.active > 0 || !a.awake {
+		return
+	}
+
+	generation := a.generation
+	a.timer = time.AfterFunc(a.cooldown, func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+
+		// A connection has been made since the timer was set.
+		if a.generation != generation || a.active > 0 || !a.awake {
+			return
+		}
+
+		log.G(ctx).
+			WithField("cooldown", a.cooldown).
+			Info("machine is idle, putting it to sleep")
+
+		if err := a.sleep(ctx); err != nil {
+			log.G(ctx).Errorf("could not put machine to sleep: %v", err)
+			return
+		}
+
+		a.awake = false
+	})
+}
+
+// waitReady waits until the provided backend accepts connections which are
+// not immediately closed.  Port forwarders, such as that of QEMU's user
+// networking, accept connections on behalf of the machine before it listens
+// and close them right away, which is why a successful connection alone does
+// not suffice.
+func waitReady(ctx context.Context, backend string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var err error
+
+	for {
+		if err = probe(ctx, backend); err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("machine did not become ready at %s: %w", backend, err)
+		case <-time.After(probeInterval):
+		}
+	}
+}
+
+// probe connects to the provided backend and checks that the connection
+// remains open or delivers data.
+func probe(ctx context.Context, backend string) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", backend)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	if err := conn.SetReadDeadline(time.Now().Add(probeInterval)); err != nil {
+		return err
+	}
+
+	if _, err = conn.Read(make([]byte, 1)); err == nil || errors.Is(err, os.ErrDeadlineExceeded) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,181 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+package activator
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// echo serves a TCP echo server which only starts listening once the returned
+// function is called, mimicking a machine which is started on demand.
+func echo(t *testing.T) (string, func(context.Context) error) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	address := l.Addr().String()
+	l.Close()
+
+	start := func(context.Context) error {
+		l, err := net.Listen("tcp", address)
+		if err != nil {
+			return err
+		}
+
+		t.Cleanup(func() { l.Close() })
+
+		go func() {
+			for {
+				conn, err := l.Accept()
+				if err != nil {
+					return
+				}
+
+				go func() {
+					defer conn.Close()
+					_, _ = io.Copy(conn, conn)
+				}()
+			}
+		}()
+
+		return nil
+	}
+
+	return address, start
+}
+
+func roundtrip(t *testing.T, address, msg string) {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(msg)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := conn.(*net.TCPConn).CloseWrite(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != msg {
+		t.Fatalf("expected %q, got %q", msg, got)
+	}
+}
+
+func TestActivatorWakesAndSleeps(t *testing.T) {
+	backend, start := echo(t)
+
+	var wakes, sleeps atomic.Int32
+	started := false
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := New(
+		WithListener(l, backend),
+		WithCooldown(200*time.Millisecond),
+		WithReadyTimeout(5*time.Second),
+		WithWake(func(ctx context.Context) error {
+			wakes.Add(1)
+			if started {
+				return nil
+			}
+
+			started = true
+			return start(ctx)
+		}),
+		WithSleep(func(context.Context) error {
+			sleeps.Add(1)
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error)
+	go func() { served <- a.Serve(ctx) }()
+
+	if a.Awake() {
+		t.Fatal("expected machine to be asleep before the first connection")
+	}
+
+	roundtrip(t, l.Addr().String(), "hello")
+	roundtrip(t, l.Addr().String(), "world")
+
+	if got := wakes.Load(); got != 1 {
+		t.Fatalf("expected 1 wake, got %d", got)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for a.Awake() {
+		if time.Now().After(deadline) {
+			t.Fatal("expected machine to be put to sleep after the cooldown")
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if got := sleeps.Load(); got != 1 {
+		t.Fatalf("expected 1 sleep, got %d", got)
+	}
+
+	roundtrip(t, l.Addr().String(), "again")
+
+	if got := wakes.Load(); got != 2 {
+		t.Fatalf("expected 2 wakes, got %d", got)
+	}
+
+	cancel()
+
+	if err := <-served; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewRequiresListenersAndTransitions(t *testing.T) {
+	noop := func(context.Context) error { return nil }
+
+	if _, err := New(WithWake(noop), WithSleep(noop)); err == nil {
+		t.Fatal("expected error without listeners")
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l.Close()
+
+	if _, err := New(WithListener(l, "127.0.0.1:1"), WithWake(noop)); err == nil {
+		t.Fatal("expected error without sleep function")
+	}
+
+	if _, err := New(WithListener(l, "127.0.0.1:1"), WithWake(noop), WithSleep(noop), WithCooldown(0)); err == nil {
+		t.Fatal("expected error with zero cooldown")
+	}
+}
@@ -0,0 +1,145 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+// Package chunker splits a stream into content-defined chunks, such that the
+// boundaries of chunks only depend on the surrounding content.  A change to
+// the stream therefore only affects the chunks around it whilst all others
+// remain identical, which allows them to be deduplicated.
+package chunker
+
+import (
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+const (
+	DefaultMinSize = 1 << 20
+	DefaultAvgSize = 4 << 20
+	DefaultMaxSize = 16 << 20
+)
+
+// gear is the table of random values of the rolling gear hash.  It must never
+// change as doing so changes the boundaries of all chunks and therefore
+// prevents any chunk from being reused.
+var gear [256]uint64
+
+func init() {
+	// SplitMix64 with a fixed seed.
+	seed := uint64(0x756e696b72616674) // "unikraft"
+	for i := range gear {
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		gear[i] = z ^ (z >> 31)
+	}
+}
+
+// Chunker splits the content of a reader into chunks.
+type Chunker struct {
+	r    io.Reader
+	min  int
+	max  int
+	mask uint64
+	buf  []byte
+	data []byte
+	eof  bool
+}
+
+// ChunkerOption is a function which modifies the chunker.
+type ChunkerOption func(*Chunker) error
+
+// WithSizes sets the minimum, average and maximum size of chunks.  The average
+// size must be a power of two.
+func WithSizes(min, avg, max int) ChunkerOption {
+	return func(c *Chunker) error {
+		if min <= 0 || min > avg || avg > max {
+			return fmt.Errorf("chunk sizes must satisfy 0 < min <= avg <= max")
+		}
+
+		if avg&(avg-1) != 0 {
+			return fmt.Errorf("average chunk size must be a power of two")
+		}
+
+		c.min = min
+		c.max = max
+		c.mask = uint64(1)<<bits.TrailingZeros(uint(avg)) - 1
+
+		return nil
+	}
+}
+
+// New returns a chunker of the content of the provided reader.
+func New(r io.Reader, opts ...ChunkerOption) (*Chunker, error) {
+	c := Chunker{r: r}
+
+	if err := WithSizes(DefaultMinSize, DefaultAvgSize, DefaultMaxSize)(&c); err != nil {
+		return nil, err
+	}
+
+	for _, opt := range opts {
+		if err := opt(&c); err != nil {
+			return nil, err
+		}
+	}
+
+	c.buf = make([]byte, c.max)
+
+	return &c, nil
+}
+
+// Next returns the next chunk, or io.EOF once the content has been consumed.
+// The chunk is only valid until the next call.
+func (c *Chunker) Next() ([]byte, error) {
+	if len(c.data) < c.max && !c.eof {
+		n := copy(c.buf, c.data)
+
+		for n < c.max && !c.eof {
+			m, err := c.r.Read(c.buf[n:])
+			n += m
+
+			if err == io.EOF {
+				c.eof = true
+			} else if err != nil {
+				return nil, err
+			}
+		}
+
+		c.data = c.buf[:n]
+	}
+
+	if len(c.data) == 0 {
+		return nil, io.EOF
+	}
+
+	cut := c.cut(c.data)
+	chunk := c.data[:cut]
+	c.data = c.data[cut:]
+
+	return chunk, nil
+}
+
+// cut returns the length of the chunk at the start of the provided data.
+func (c *Chunker) cut(data []byte) int {
+	if len(data) <= c.min {
+		return len(data)
+	}
+
+	n := len(data)
+	if n > c.max {
+		n = c.max
+	}
+
+	var hash uint64
+	for i := c.min; i < n; i++ {
+		hash = (hash << 1) + gear[data[i]]
+		if hash&c.mask == 0 {
+			return i + 1
+		}
+	}
+
+	return n
+}
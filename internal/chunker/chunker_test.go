@@ -0,0 +1,84 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+package chunker
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+// chunks returns the digests of the chunks of the provided data and fails if
+// they do not make up the data or violate the chunk sizes.
+func chunks(t *testing.T, data []byte) [][32]byte {
+	t.Helper()
+
+	c, err := New(bytes.NewReader(data), WithSizes(1<<10, 4<<10, 16<<10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ret [][32]byte
+	var joined []byte
+
+	for {
+		chunk, err := c.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(chunk) > 16<<10 {
+			t.Fatalf("chunk of %d bytes exceeds maximum size", len(chunk))
+		}
+
+		joined = append(joined, chunk...)
+		ret = append(ret, sha256.Sum256(chunk))
+	}
+
+	if !bytes.Equal(joined, data) {
+		t.Fatal("expected chunks to make up the data")
+	}
+
+	return ret
+}
+
+func TestChunkerResynchronizes(t *testing.T) {
+	data := make([]byte, 4<<20)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	// Insert a few bytes in the middle of the data.
+	modified := append(append(append([]byte{}, data[:len(data)/2]...), []byte("change")...), data[len(data)/2:]...)
+
+	before := chunks(t, data)
+	after := chunks(t, modified)
+
+	seen := make(map[[32]byte]bool, len(before))
+	for _, dgst := range before {
+		seen[dgst] = true
+	}
+
+	changed := 0
+	for _, dgst := range after {
+		if !seen[dgst] {
+			changed++
+		}
+	}
+
+	// Only the chunks surrounding the change may differ.
+	if changed > 2 {
+		t.Errorf("expected at most 2 of %d chunks to change, got %d", len(after), changed)
+	}
+}
+
+func TestChunkerEmpty(t *testing.T) {
+	if got := chunks(t, nil); len(got) != 0 {
+		t.Errorf("expected no chunks, got %d", len(got))
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/MakeNowJust/heredoc"
 	"github.com/dustin/go-humanize"
@@ -24,44 +25,57 @@ import (
 	"kraftkit.sh/tui"
 
 	"kraftkit.sh/log"
+	"kraftkit.sh/oci/buildcache"
 	"kraftkit.sh/packmanager"
 	"kraftkit.sh/unikraft/app"
+	"kraftkit.sh/unikraft/buildenv"
 	"kraftkit.sh/unikraft/target"
 )
 
 var ErrContextNotBuildable = fmt.Errorf("could not determine what or how to build from the given context")
 
 type BuildOptions struct {
-	All          bool            `long:"all" usage:"Build all targets"`
-	Architecture string          `long:"arch" short:"m" usage:"Filter the creation of the build by architecture of known targets (x86_64/arm64/arm)"`
-	DotConfig    string          `long:"config" short:"c" usage:"Override the path to the KConfig .config file"`
-	Env          []string        `long:"env" short:"e" usage:"Set environment variables to be built in the unikernel"`
-	ForcePull    bool            `long:"force-pull" usage:"Force pulling packages before building"`
-	Jobs         int             `long:"jobs" short:"j" usage:"Allow N jobs at once"`
-	KernelDbg    bool            `long:"dbg" usage:"Build the debuggable (symbolic) kernel image instead of the stripped image"`
-	Kraftfile    string          `long:"kraftfile" short:"K" usage:"Set an alternative path of the Kraftfile"`
-	NoCache      bool            `long:"no-cache" short:"F" usage:"Force a rebuild even if existing intermediate artifacts already exist"`
-	NoConfigure  bool            `long:"no-configure" usage:"Do not run Unikraft's configure step before building"`
-	NoFast       bool            `long:"no-fast" usage:"Do not use maximum parallelization when performing the build"`
-	NoFetch      bool            `long:"no-fetch" usage:"Do not run Unikraft's fetch step before building"`
-	NoRootfs     bool            `long:"no-rootfs" usage:"Do not build the root file system (initramfs)"`
-	NoUpdate     bool            `long:"no-update" usage:"Do not update package index before running the build"`
-	Platform     string          `long:"plat" short:"p" usage:"Filter the creation of the build by platform of known targets (fc/qemu/xen)"`
-	PrintStats   bool            `long:"print-stats" usage:"Print build statistics"`
-	Project      app.Application `noattribute:"true"`
-	Rootfs       string          `long:"rootfs" usage:"Specify a path to use as root file system (can be volume or initramfs)"`
-	SaveBuildLog string          `long:"build-log" usage:"Use the specified file to save the output from the build"`
-	Target       *target.Target  `noattribute:"true"`
-	TargetName   string          `long:"target" short:"t" usage:"Build a particular known target"`
-	Workdir      string          `noattribute:"true"`
-
+	All            bool            `long:"all" usage:"Build all targets"`
+	Architecture   string          `long:"arch" short:"m" usage:"Filter the creation of the build by architecture of known targets (x86_64/arm64/arm)"`
+	BuildCache     string          `long:"build-cache" usage:"Use the given OCI repository as a cache of prebuilt library objects"`
+	BuildCachePush bool            `long:"build-cache-push" usage:"Push newly built library objects to the build cache repository"`
+	BuildEnv       string          `long:"build-env" usage:"Set the environment to build in (host/container[:IMAGE]), where containers are run via containerd" default:"host"`
+	DotConfig      string          `long:"config" short:"c" usage:"Override the path to the KConfig .config file"`
+	Env            []string        `long:"env" short:"e" usage:"Set environment variables to be built in the unikernel"`
+	ForcePull      bool            `long:"force-pull" usage:"Force pulling packages before building"`
+	Jobs           int             `long:"jobs" short:"j" usage:"Allow N jobs at once"`
+	KernelDbg      bool            `long:"dbg" usage:"Build the debuggable (symbolic) kernel image instead of the stripped image"`
+	Kraftfile      string          `long:"kraftfile" short:"K" usage:"Set an alternative path of the Kraftfile"`
+	NoCache        bool            `long:"no-cache" short:"F" usage:"Force a rebuild even if existing intermediate artifacts already exist"`
+	NoConfigure    bool            `long:"no-configure" usage:"Do not run Unikraft's configure step before building"`
+	NoFast         bool            `long:"no-fast" usage:"Do not use maximum parallelization when performing the build"`
+	NoFetch        bool            `long:"no-fetch" usage:"Do not run Unikraft's fetch step before building"`
+	NoRootfs       bool            `long:"no-rootfs" usage:"Do not build the root file system (initramfs)"`
+	NoUpdate       bool            `long:"no-update" usage:"Do not update package index before running the build"`
+	Parallel       int             `long:"parallel" usage:"Build up to N targets concurrently when building all targets" default:"1"`
+	Platform       string          `long:"plat" short:"p" usage:"Filter the creation of the build by platform of known targets (fc/qemu/xen)"`
+	PrintStats     bool            `long:"print-stats" usage:"Print build statistics"`
+	Project        app.Application `noattribute:"true"`
+	Report         string          `long:"report" usage:"Emit a machine-readable build report (json/junit)"`
+	ReportFile     string          `long:"report-file" usage:"Write the build report to the given file instead of stdout"`
+	Rootfs         string          `long:"rootfs" usage:"Specify a path to use as root file system (can be volume or initramfs)"`
+	SaveBuildLog   string          `long:"build-log" usage:"Use the specified file to save the output from the build"`
+	Target         *target.Target  `noattribute:"true"`
+	TargetName     string          `long:"target" short:"t" usage:"Build a particular known target"`
+	Workdir        string          `noattribute:"true"`
+
+	buildCache *buildcache.BuildCache
+	buildEnv   *buildenv.Container
+	builtAll   bool
+	cached     []*cachedComponent
+	cacheHosts buildcache.HostPaths
+	report     *buildReport
 	statistics map[string]string
+	unattended bool
 }
 
 // Build a Unikraft unikernel.
-func Build(ctx context.Context, opts *BuildOptions, args ...string) error {
-	var err error
-
+func Build(ctx context.Context, opts *BuildOptions, args ...string) (err error) {
 	if opts == nil {
 		opts = &BuildOptions{}
 	}
@@ -79,6 +93,19 @@ func Build(ctx context.Context, opts *BuildOptions, args ...string) error {
 
 	opts.statistics = map[string]string{}
 
+	if len(opts.Report) > 0 {
+		opts.report, err = newBuildReport(opts.Report)
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if werr := opts.writeReport(ctx); werr != nil && err == nil {
+				err = werr
+			}
+		}()
+	}
+
 	var build builder
 	builders := builders()
 
@@ -103,11 +130,32 @@ func Build(ctx context.Context, opts *BuildOptions, args ...string) error {
 
 	log.G(ctx).WithField("builder", build.String()).Debug("using")
 
+	// Each target is built from a dedicated project when building all targets
+	// and the result of each is summarized by buildAll.
+	if opts.All && opts.Target == nil {
+		if ukbuild, ok := build.(*builderKraftfileUnikraft); ok && len(opts.Project.Targets()) > 1 {
+			opts.builtAll = true
+			return opts.buildAll(ctx, ukbuild, args...)
+		}
+	}
+
+	pullStart := time.Now()
 	if err := build.Prepare(ctx, opts, args...); err != nil {
+		var tc target.Target
+		if opts.Target != nil {
+			tc = *opts.Target
+		}
+		tr := opts.report.target(tc)
+		tr.addPhase(phasePull, pullStart, err)
+		tr.complete(tc, err)
 		return fmt.Errorf("could not complete build: %w", err)
 	}
 
+	tr := opts.report.target(*opts.Target)
+	tr.addPhase(phasePull, pullStart, nil)
+
 	if opts.Rootfs, _, _, err = utils.BuildRootfs(ctx, opts.Workdir, opts.Rootfs, false, (*opts.Target).Architecture().String()); err != nil {
+		tr.complete(*opts.Target, err)
 		return err
 	}
 
@@ -118,6 +166,7 @@ func Build(ctx context.Context, opts *BuildOptions, args ...string) error {
 	opts.Project.SetRootfs(opts.Rootfs)
 
 	err = build.Build(ctx, opts, args...)
+	tr.complete(*opts.Target, err)
 	if err != nil {
 		return fmt.Errorf("could not complete build: %w", err)
 	}
@@ -142,6 +191,31 @@ func Build(ctx context.Context, opts *BuildOptions, args ...string) error {
 	return nil
 }
 
+// writeReport serializes the build report, if one was requested, either to the
+// provided report file or to stdout.
+func (opts *BuildOptions) writeReport(ctx context.Context) error {
+	if opts.report == nil {
+		return nil
+	}
+
+	if len(opts.ReportFile) == 0 || opts.ReportFile == "-" {
+		return opts.report.Write(iostreams.G(ctx).Out, opts.Report)
+	}
+
+	f, err := os.Create(opts.ReportFile)
+	if err != nil {
+		return fmt.Errorf("creating build report: %w", err)
+	}
+
+	defer f.Close()
+
+	if err := opts.report.Write(f, opts.Report); err != nil {
+		return fmt.Errorf("writing build report: %w", err)
+	}
+
+	return nil
+}
+
 func NewCmd() *cobra.Command {
 	cmd, err := cmdfactory.New(&BuildOptions{}, cobra.Command{
 		Short:   "Configure and build Unikraft unikernels",
@@ -153,6 +227,12 @@ func NewCmd() *cobra.Command {
 
 			The default behaviour of %[1]skraft build%[1]s is to build a project.  Given no
 			arguments, you will be guided through interactive mode.
+
+			With %[1]s--build-env container%[1]s, the build runs within a container of a
+			toolchain image.  Containers are only run via containerd, whose socket is
+			set with the 'containerd_addr' configuration option; other container
+			runtimes, such as Docker or Podman, are not supported.  The default image
+			is the one tagged with the release of KraftKit.
 		`, "`"),
 		Example: heredoc.Doc(`
 			# Build the current project (cwd)
@@ -160,6 +240,22 @@ func NewCmd() *cobra.Command {
 
 			# Build path to a Unikraft project
 			$ kraft build path/to/app
+
+			# Build the current project and save a JUnit report of the build
+			$ kraft build --report junit --report-file build.xml
+
+			# Build the current project re-using library objects from a shared cache
+			# and upload any libraries which had to be compiled
+			$ kraft build --build-cache ghcr.io/acme/buildcache --build-cache-push
+
+			# Build all targets of the current project, up to 4 at a time
+			$ kraft build --all --parallel 4
+
+			# Build the current project within the default toolchain container
+			$ kraft build --build-env container
+
+			# Build the current project within a specific toolchain container
+			$ kraft build --build-env container:index.unikraft.io/kraftkit.sh/base:latest
 		`),
 		Annotations: map[string]string{
 			cmdfactory.AnnotationHelpGroup: "build",
@@ -173,6 +269,14 @@ func NewCmd() *cobra.Command {
 }
 
 func (opts *BuildOptions) Pre(cmd *cobra.Command, args []string) error {
+	if cmd.Flags().Changed("parallel") && !opts.All {
+		return fmt.Errorf("the --parallel flag can only be used with --all")
+	}
+
+	if opts.Parallel < 1 {
+		return fmt.Errorf("the --parallel flag must be at least 1")
+	}
+
 	ctx, err := packmanager.WithDefaultUmbrellaManagerInContext(cmd.Context())
 	if err != nil {
 		return err
@@ -188,6 +292,10 @@ func (opts *BuildOptions) Run(ctx context.Context, args []string) error {
 		return err
 	}
 
+	if opts.builtAll {
+		return nil
+	}
+
 	workdir, err := filepath.Abs(opts.Workdir)
 	if err != nil {
 		return fmt.Errorf("getting the work directory: %w", err)
@@ -249,6 +357,12 @@ func (opts *BuildOptions) Run(ctx context.Context, args []string) error {
 		}
 	}
 
+	// The build report has been written to stdout and should not be
+	// interleaved with any further output.
+	if opts.report != nil && (len(opts.ReportFile) == 0 || opts.ReportFile == "-") {
+		return nil
+	}
+
 	if !iostreams.G(ctx).IsStdoutTTY() {
 		fields := logrus.Fields{}
 		for _, entry := range entries {
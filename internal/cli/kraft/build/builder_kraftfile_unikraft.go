@@ -11,6 +11,7 @@ import (
 	plainexec "os/exec"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
@@ -20,6 +21,7 @@ import (
 	"kraftkit.sh/kconfig"
 	"kraftkit.sh/log"
 	"kraftkit.sh/make"
+	"kraftkit.sh/oci/buildcache"
 	"kraftkit.sh/pack"
 	"kraftkit.sh/packmanager"
 	"kraftkit.sh/tui/confirm"
@@ -28,6 +30,7 @@ import (
 	"kraftkit.sh/tui/selection"
 	"kraftkit.sh/unikraft"
 	"kraftkit.sh/unikraft/app"
+	"kraftkit.sh/unikraft/buildenv"
 	"kraftkit.sh/unikraft/export/v0/posixenviron"
 	"kraftkit.sh/unikraft/target"
 )
@@ -424,8 +427,35 @@ func (build *builderKraftfileUnikraft) Prepare(ctx context.Context, opts *BuildO
 	return build.pull(ctx, opts, norender, build.nameWidth)
 }
 
+// mounts returns the paths which must be available within a containerised
+// build environment, i.e. the project and the sources of all its components.
+func (build *builderKraftfileUnikraft) mounts(ctx context.Context, opts *BuildOptions) []string {
+	mounts := []string{
+		opts.Workdir,
+		opts.Project.WorkingDir(),
+		opts.Project.OutDir(),
+	}
+
+	if template := opts.Project.Template(); template != nil {
+		mounts = append(mounts, template.Path())
+	}
+
+	components, err := opts.Project.Components(ctx, *opts.Target)
+	if err != nil {
+		log.G(ctx).Warnf("could not determine components to mount: %v", err)
+		return mounts
+	}
+
+	for _, component := range components {
+		mounts = append(mounts, component.Path())
+	}
+
+	return mounts
+}
+
 func (build *builderKraftfileUnikraft) Build(ctx context.Context, opts *BuildOptions, args ...string) error {
 	var processes []*paraprogress.Process
+	tr := opts.report.target(*opts.Target)
 	var mopts []make.MakeOption
 	if opts.Jobs > 0 {
 		mopts = append(mopts, make.WithJobs(opts.Jobs))
@@ -433,6 +463,31 @@ func (build *builderKraftfileUnikraft) Build(ctx context.Context, opts *BuildOpt
 		mopts = append(mopts, make.WithMaxJobs(!opts.NoFast && !config.G[config.KraftKit](ctx).NoParallel))
 	}
 
+	// Options which determine where make is executed and which apply to every
+	// phase of the build, including configuration.
+	var envmopts []make.MakeOption
+
+	switch env, image, _ := strings.Cut(opts.BuildEnv, ":"); env {
+	case "", "host":
+	case "container":
+		container, err := buildenv.NewContainer(ctx, image, build.mounts(ctx, opts)...)
+		if err != nil {
+			return fmt.Errorf("could not prepare build environment: %w", err)
+		}
+
+		defer container.Close()
+
+		log.G(ctx).
+			WithField("image", container.String()).
+			Debug("building in container")
+
+		opts.buildEnv = container
+		envmopts = append(envmopts, make.WithExecOptions(exec.WithExecutor(container)))
+		mopts = append(mopts, envmopts...)
+	default:
+		return fmt.Errorf("unsupported build environment: %s", env)
+	}
+
 	allEnvs := map[string]string{}
 	for k, v := range opts.Project.Env() {
 		allEnvs[k] = v
@@ -477,7 +532,9 @@ func (build *builderKraftfileUnikraft) Build(ctx context.Context, opts *BuildOpt
 		var err error
 		configure := true
 
-		if opts.Project.IsConfigured(*opts.Target) {
+		// Targets which are built alongside others cannot be prompted for and
+		// are always re-configured.
+		if opts.Project.IsConfigured(*opts.Target) && !opts.unattended {
 			configure, err = confirm.NewConfirm("project already configured, are you sure you want to rerun the configure step:")
 			if err != nil {
 				return err
@@ -488,18 +545,22 @@ func (build *builderKraftfileUnikraft) Build(ctx context.Context, opts *BuildOpt
 			processes = append(processes, paraprogress.NewProcess(
 				fmt.Sprintf("configuring %s (%s)", (*opts.Target).Name(), target.TargetPlatArchName(*opts.Target)),
 				func(ctx context.Context, w func(progress float64)) error {
-					return opts.Project.Configure(
-						ctx,
-						*opts.Target, // Target-specific options
-						envKconfig,   // Extra Kconfigs for compiled in environment variables
-						make.WithProgressFunc(w),
-						make.WithSilent(true),
-						make.WithExecOptions(
-							exec.WithStdin(iostreams.G(ctx).In),
-							exec.WithStdout(log.G(ctx).Writer()),
-							exec.WithStderr(log.G(ctx).WriterLevel(logrus.WarnLevel)),
-						),
-					)
+					return tr.phase(phaseConfigure, func() error {
+						return opts.Project.Configure(
+							ctx,
+							*opts.Target, // Target-specific options
+							envKconfig,   // Extra Kconfigs for compiled in environment variables
+							append([]make.MakeOption{
+								make.WithProgressFunc(w),
+								make.WithSilent(true),
+								make.WithExecOptions(
+									exec.WithStdin(iostreams.G(ctx).In),
+									exec.WithStdout(log.G(ctx).Writer()),
+									exec.WithStderr(log.G(ctx).WriterLevel(logrus.WarnLevel)),
+								),
+							}, envmopts...)...,
+						)
+					})
 				},
 			))
 		} else {
@@ -511,23 +572,86 @@ func (build *builderKraftfileUnikraft) Build(ctx context.Context, opts *BuildOpt
 	processes = append(processes, paraprogress.NewProcess(
 		fmt.Sprintf("building %s (%s)", (*opts.Target).Name(), target.TargetPlatArchName(*opts.Target)),
 		func(ctx context.Context, w func(progress float64)) error {
+			eopts := []exec.ExecOption{
+				exec.WithStdout(log.G(ctx).Writer()),
+				exec.WithStderr(log.G(ctx).WriterLevel(logrus.WarnLevel)),
+				// exec.WithOSEnv(true),
+			}
+
+			if len(opts.BuildCache) > 0 {
+				var err error
+				ctx, opts.buildCache, err = buildcache.NewBuildCache(ctx,
+					opts.BuildCache,
+					buildcache.WithPush(opts.BuildCachePush),
+				)
+				if err != nil {
+					return fmt.Errorf("could not instantiate build cache: %w", err)
+				}
+			}
+
+			// When a report has been requested, the fetch and prepare steps are
+			// invoked individually such that each can be timed separately and the
+			// output of the main invocation is parsed for timings and diagnostics.
+			// Similarly, objects can only be restored from the build cache once the
+			// build directory has been prepared.
+			// The target-specific make options which Build would otherwise pass to
+			// prepare must be passed to the separate invocations too.
+			noPrepare := tr != nil || opts.buildCache != nil
+			var recorder *make.Recorder
+			if noPrepare {
+				prepareOpts := append(append(mopts, app.TargetMakeOptions(*opts.Target)...), make.WithExecOptions(eopts...))
+
+				if !opts.NoFetch {
+					if err := tr.phase(phaseFetch, func() error {
+						return opts.Project.Fetch(ctx, *opts.Target, prepareOpts...)
+					}); err != nil {
+						return fmt.Errorf("build failed: %w", err)
+					}
+				}
+
+				if err := tr.phase(phasePrepare, func() error {
+					return opts.Project.Prepare(ctx, *opts.Target, prepareOpts...)
+				}); err != nil {
+					return fmt.Errorf("build failed: %w", err)
+				}
+			}
+
+			if opts.buildCache != nil {
+				if err := opts.restoreBuildCache(ctx); err != nil {
+					return err
+				}
+			}
+
+			if tr != nil {
+				recorder = make.NewRecorder()
+				eopts = append(eopts,
+					exec.WithStdoutCallback(recorder),
+					exec.WithStderrCallback(recorder),
+				)
+			}
+
+			start := time.Now()
 			err := opts.Project.Build(
 				ctx,
 				*opts.Target, // Target-specific options
 				app.WithBuildProgressFunc(w),
 				app.WithBuildMakeOptions(append(mopts,
-					make.WithExecOptions(
-						exec.WithStdout(log.G(ctx).Writer()),
-						exec.WithStderr(log.G(ctx).WriterLevel(logrus.WarnLevel)),
-						// exec.WithOSEnv(true),
-					),
+					make.WithExecOptions(eopts...),
 				)...),
 				app.WithBuildLogFile(opts.SaveBuildLog),
+				app.WithBuildNoPrepare(noPrepare),
 			)
+			tr.addMake(recorder, start, err)
 			if err != nil {
 				return fmt.Errorf("build failed: %w", err)
 			}
 
+			if opts.buildCache != nil {
+				if err := opts.saveBuildCache(ctx); err != nil {
+					log.G(ctx).Warnf("could not update build cache: %v", err)
+				}
+			}
+
 			return nil
 		},
 	))
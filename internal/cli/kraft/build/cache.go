@@ -0,0 +1,268 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package build
+
+import (
+	"context"
+	"fmt"
+	"os"
+	plainexec "os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"kraftkit.sh/kconfig"
+	"kraftkit.sh/log"
+	"kraftkit.sh/oci/buildcache"
+	"kraftkit.sh/unikraft"
+	"kraftkit.sh/unikraft/component"
+)
+
+// cachedComponent represents a component of the target whose objects are
+// stored as a single entry in the build cache.  The objects of a component are
+// all entries of the build directory which start with the component's prefix,
+// e.g. `liblwip/` and `liblwip.o`.  Since the objects of the Unikraft core
+// cannot be enumerated ahead of the build, it claims all remaining `lib`
+// entries which have not been claimed by a longer prefix.  Local components
+// are tracked such that their entries are never claimed by another component,
+// but they are neither restored nor saved.
+type cachedComponent struct {
+	name   string
+	prefix string
+	key    digest.Digest
+	local  bool
+	hit    bool
+}
+
+// libraryBuildPrefix returns the prefix of the entries within the build
+// directory which belong to the library with the given name.
+func libraryBuildPrefix(name string) string {
+	name = strings.ReplaceAll(strings.ToLower(name), "-", "_")
+	if strings.HasPrefix(name, "lib") {
+		return name
+	}
+
+	return "lib" + name
+}
+
+// isLocalComponent returns whether the component is sourced from a local
+// directory.  Such components are likely being actively developed and their
+// version does not reflect their contents.
+func isLocalComponent(comp component.Component) bool {
+	return len(comp.Version()) == 0 || comp.Path() == comp.Source()
+}
+
+// toolchainVersion returns an identifier of the compiler which is used by
+// Unikraft's build system.
+func toolchainVersion(ctx context.Context, kvm kconfig.KeyValueMap) string {
+	cc := os.Getenv("CC")
+	if cc == "" {
+		cc = "gcc"
+		if cross, ok := kvm.Get("CROSS_COMPILE"); ok {
+			cc = cross.Value + cc
+		}
+	}
+
+	out, err := plainexec.CommandContext(ctx, cc, "--version").Output()
+	if err != nil {
+		log.G(ctx).
+			WithField("cc", cc).
+			Debugf("could not determine toolchain version: %v", err)
+		return cc
+	}
+
+	version, _, _ := strings.Cut(string(out), "\n")
+
+	return strings.TrimSpace(version)
+}
+
+// buildCachePlatform returns the OCI platform which is used to represent the
+// target's entries in the build cache.
+func (opts *BuildOptions) buildCachePlatform() *ocispec.Platform {
+	return &ocispec.Platform{
+		OS:           (*opts.Target).Platform().Name(),
+		Architecture: (*opts.Target).Architecture().Name(),
+	}
+}
+
+// restoreBuildCache computes the key of each cacheable component of the
+// target and restores the objects of every component found in the build
+// cache into the build directory.  This must be called after the project has
+// been configured and prepared.
+func (opts *BuildOptions) restoreBuildCache(ctx context.Context) error {
+	kvm, err := kconfig.NewKeyValueMapFromFile(
+		filepath.Join(opts.Project.WorkingDir(), (*opts.Target).ConfigFilename()),
+	)
+	if err != nil {
+		return fmt.Errorf("could not read resolved KConfig: %w", err)
+	}
+
+	// Builds performed within a container are identified by the digest of the
+	// toolchain image.
+	toolchain := toolchainVersion(ctx, kvm)
+	if opts.buildEnv != nil {
+		toolchain = opts.buildEnv.String()
+	}
+
+	inputs := buildcache.Inputs{
+		KConfig:      kvm,
+		Toolchain:    toolchain,
+		Platform:     (*opts.Target).Platform().Name(),
+		Architecture: (*opts.Target).Architecture().Name(),
+		Components:   map[string]string{},
+	}
+
+	components, err := opts.Project.Components(ctx, *opts.Target)
+	if err != nil {
+		return fmt.Errorf("could not get list of components: %w", err)
+	}
+
+	// Every component is built against the headers of the core and of the other
+	// libraries, so none can be cached if the core is sourced locally.
+	localCore := false
+
+	for _, component := range components {
+		switch component.Type() {
+		case unikraft.ComponentTypeCore:
+			localCore = isLocalComponent(component)
+		case unikraft.ComponentTypeLib:
+		default:
+			continue
+		}
+
+		inputs.Components[component.Name()] = fmt.Sprintf("%s@%s", component.Source(), component.Version())
+	}
+
+	opts.cached = nil
+
+	// The absolute paths which are recorded by the build system are relocated
+	// such that entries can be shared with hosts whose sources are located in
+	// different directories.
+	opts.cacheHosts = buildcache.HostPaths{
+		"O":      opts.Project.OutDir(),
+		"UK_APP": opts.Project.WorkingDir(),
+	}
+
+	for _, component := range components {
+		var prefix string
+
+		switch component.Type() {
+		case unikraft.ComponentTypeCore:
+			prefix = "lib"
+		case unikraft.ComponentTypeLib:
+			prefix = libraryBuildPrefix(component.Name())
+		default:
+			continue
+		}
+
+		if component.Type() == unikraft.ComponentTypeCore {
+			opts.cacheHosts["UK_BASE"] = component.Path()
+		} else {
+			opts.cacheHosts[strings.ToUpper(prefix)] = component.Path()
+		}
+
+		if localCore || isLocalComponent(component) {
+			log.G(ctx).
+				WithField("component", component.Name()).
+				Debug("not using build cache for local component")
+
+			opts.cached = append(opts.cached, &cachedComponent{
+				name:   component.Name(),
+				prefix: prefix,
+				local:  true,
+			})
+			continue
+		}
+
+		opts.cached = append(opts.cached, &cachedComponent{
+			name:   component.Name(),
+			prefix: prefix,
+			key: inputs.Key(
+				component.Name(),
+				fmt.Sprintf("%s@%s", component.Source(), component.Version()),
+			),
+		})
+	}
+
+	if opts.NoCache {
+		return nil
+	}
+
+	for _, cc := range opts.cached {
+		if cc.local {
+			continue
+		}
+
+		cc.hit, err = opts.buildCache.Restore(ctx,
+			cc.key,
+			opts.buildCachePlatform(),
+			opts.Project.OutDir(),
+			opts.cacheHosts,
+		)
+		if err != nil {
+			return fmt.Errorf("could not restore %s from build cache: %w", cc.name, err)
+		}
+
+		if cc.hit {
+			log.G(ctx).
+				WithField("component", cc.name).
+				Info("using build cache")
+		}
+	}
+
+	return nil
+}
+
+// saveBuildCache stores the objects of every cacheable component of the
+// target which were not restored from the build cache.
+func (opts *BuildOptions) saveBuildCache(ctx context.Context) error {
+	entries, err := os.ReadDir(opts.Project.OutDir())
+	if err != nil {
+		return fmt.Errorf("could not read build directory: %w", err)
+	}
+
+	paths := map[*cachedComponent][]string{}
+
+	for _, entry := range entries {
+		var owner *cachedComponent
+
+		for _, cc := range opts.cached {
+			if !strings.HasPrefix(entry.Name(), cc.prefix) {
+				continue
+			}
+
+			if owner == nil || len(cc.prefix) > len(owner.prefix) {
+				owner = cc
+			}
+		}
+
+		if owner == nil {
+			continue
+		}
+
+		paths[owner] = append(paths[owner], entry.Name())
+	}
+
+	for _, cc := range opts.cached {
+		if cc.local || cc.hit {
+			continue
+		}
+
+		if err := opts.buildCache.Save(ctx,
+			cc.key,
+			opts.buildCachePlatform(),
+			cc.name,
+			opts.Project.OutDir(),
+			paths[cc],
+			opts.cacheHosts,
+		); err != nil {
+			return fmt.Errorf("could not save %s to build cache: %w", cc.name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,389 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package build
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/LastPossum/kamino"
+	"github.com/sirupsen/logrus"
+
+	"kraftkit.sh/config"
+	"kraftkit.sh/internal/cli/kraft/utils"
+	"kraftkit.sh/internal/tableprinter"
+	"kraftkit.sh/iostreams"
+	"kraftkit.sh/log"
+	"kraftkit.sh/unikraft/app"
+	"kraftkit.sh/unikraft/target"
+)
+
+// targetBuild represents a single target which is built alongside the other
+// targets of the project when using `kraft build --all`.  Each target is built
+// from its own instance of the project whose output directory is unique to the
+// target, such that concurrent invocations of Unikraft's build system do not
+// collide.  Once built, the kernel images are installed at the location
+// which the target would have been built to otherwise.
+type targetBuild struct {
+	opts     *BuildOptions
+	target   target.Target
+	output   bytes.Buffer
+	duration time.Duration
+	err      error
+}
+
+// targetOutDir returns the output directory which is dedicated to the provided
+// target of the project.
+func (opts *BuildOptions) targetOutDir(tc target.Target) string {
+	return filepath.Join(opts.Project.OutDir(), "targets", filepath.Base(tc.Kernel()))
+}
+
+// buildAll builds every target of the project with up to opts.Parallel
+// targets being built concurrently.  Components are pulled and root file
+// systems are built sequentially beforehand since they are shared between
+// targets.  A failing target does not prevent the remaining targets from
+// being built.
+func (opts *BuildOptions) buildAll(ctx context.Context, build *builderKraftfileUnikraft, args ...string) error {
+	parallel := opts.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	var builds []*targetBuild
+
+	for i, tc := range opts.Project.Targets() {
+		tb := &targetBuild{target: tc}
+		builds = append(builds, tb)
+
+		pullStart := time.Now()
+		tb.opts, tb.err = opts.prepareTarget(ctx, build, i, args...)
+		tr := opts.report.target(tc)
+		tr.addPhase(phasePull, pullStart, tb.err)
+		if tb.err != nil {
+			tr.complete(tc, tb.err)
+			log.G(ctx).
+				WithField("target", target.TargetPlatArchName(tc)).
+				Errorf("could not prepare target: %v", tb.err)
+		}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, parallel)
+
+	for _, tb := range builds {
+		if tb.err != nil {
+			continue
+		}
+
+		wg.Add(1)
+
+		go func(tb *targetBuild) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			log.G(ctx).
+				WithField("target", target.TargetPlatArchName(tb.target)).
+				Info("building")
+
+			tb.build(ctx, build)
+
+			// Flush the output of each target at once such that the output of
+			// targets which are built concurrently is not interleaved.
+			mu.Lock()
+			defer mu.Unlock()
+
+			opts.printTargetOutput(ctx, tb)
+		}(tb)
+	}
+
+	wg.Wait()
+
+	// NOTE(craciunoiuc): See the equivalent workaround in Build.
+	make := filepath.Join(opts.Workdir, "Makefile.uk")
+	if finfo, err := os.Stat(make); err == nil && finfo.Size() == 0 {
+		if err := os.Remove(make); err != nil {
+			return fmt.Errorf("removing empty Makefile.uk: %w", err)
+		}
+	}
+
+	if err := opts.printBuildSummary(ctx, builds); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, tb := range builds {
+		if tb.err != nil {
+			errs = append(errs, fmt.Errorf("%s (%s): %w", tb.target.Name(), target.TargetPlatArchName(tb.target), tb.err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("could not build %d of %d targets: %w", len(errs), len(builds), errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// prepareTarget instantiates the options used to build the i-th target of the
+// project, pulls its components and builds its root file system.
+func (opts *BuildOptions) prepareTarget(ctx context.Context, build *builderKraftfileUnikraft, i int, args ...string) (*BuildOptions, error) {
+	tc := opts.Project.Targets()[i]
+
+	topts := *opts
+	topts.Project = nil
+	topts.Target = nil
+	topts.buildCache = nil
+	topts.buildEnv = nil
+	topts.cached = nil
+	topts.statistics = map[string]string{}
+	topts.unattended = true
+
+	// The package index only needs to be updated once and components which
+	// are shared between targets only need to be pulled once.
+	if i > 0 {
+		topts.NoUpdate = true
+		topts.ForcePull = false
+	}
+
+	if err := topts.initProject(ctx, app.WithProjectOutDir(opts.targetOutDir(tc))); err != nil {
+		return nil, err
+	}
+
+	// The project is loaded from the same Kraftfile and therefore its targets
+	// are in the same order.
+	targets := topts.Project.Targets()
+	if i >= len(targets) || targets[i].Name() != tc.Name() {
+		return nil, fmt.Errorf("could not determine target in dedicated project")
+	}
+
+	topts.Target = &targets[i]
+
+	if err := build.Prepare(ctx, &topts, args...); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if topts.Rootfs, _, _, err = utils.BuildRootfs(ctx, topts.Workdir, opts.Rootfs, false, tc.Architecture().String()); err != nil {
+		return nil, err
+	}
+
+	topts.Project.SetRootfs(topts.Rootfs)
+
+	return &topts, nil
+}
+
+// context returns a context whose logger and IO streams write to the output
+// buffer of the target and in which prompts and interactive rendering are
+// disabled.
+func (tb *targetBuild) context(ctx context.Context) (context.Context, error) {
+	cfg := *config.G[config.KraftKit](ctx)
+	cfg.NoPrompt = true
+	if log.LoggerTypeFromString(cfg.Log.Type) == log.FANCY {
+		cfg.Log.Type = log.LoggerTypeToString(log.BASIC)
+	}
+
+	cfgm := *config.M[config.KraftKit](ctx)
+	cfgm.Config = &cfg
+	ctx = config.WithConfigManager(ctx, &cfgm)
+
+	logger := logrus.New()
+	logger.SetOutput(&tb.output)
+	logger.SetLevel(log.G(ctx).Level)
+	logger.SetFormatter(log.G(ctx).Formatter)
+	ctx = log.WithLogger(ctx, logger)
+
+	ios, err := kamino.Clone(iostreams.G(ctx),
+		kamino.WithZeroUnexported(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	ios.Out = iostreams.NewNoTTYWriter(&tb.output, iostreams.G(ctx).Out.Fd())
+	ios.ErrOut = &tb.output
+	ios.In = iostreams.G(ctx).In
+
+	return iostreams.WithIOStreams(ctx, ios), nil
+}
+
+// build builds the target and installs its kernel images.
+func (tb *targetBuild) build(ctx context.Context, build *builderKraftfileUnikraft) {
+	tr := tb.opts.report.target(tb.target)
+	start := time.Now()
+
+	defer func() {
+		tb.duration = time.Since(start)
+		tr.complete(*tb.opts.Target, tb.err)
+	}()
+
+	ctx, tb.err = tb.context(ctx)
+	if tb.err != nil {
+		return
+	}
+
+	if tb.err = build.Build(ctx, tb.opts); tb.err != nil {
+		return
+	}
+
+	if tb.opts.PrintStats {
+		if err := build.Statistics(ctx, tb.opts); err != nil {
+			log.G(ctx).Warnf("could not compute statistics: %v", err)
+		}
+
+		keys := make([]string, 0, len(tb.opts.statistics))
+		for k := range tb.opts.statistics {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			log.G(ctx).
+				WithField(k, tb.opts.statistics[k]).
+				Info("statistics")
+		}
+	}
+
+	if tb.err = installFile((*tb.opts.Target).Kernel(), tb.target.Kernel()); tb.err != nil {
+		return
+	}
+
+	if tb.err = installFile((*tb.opts.Target).KernelDbg(), tb.target.KernelDbg()); tb.err != nil {
+		return
+	}
+}
+
+// installFile copies the file at src to dst, replacing dst atomically such
+// that it is never observed partially written.  Missing sources are ignored
+// since not every build produces every image.
+func installFile(src, dst string) error {
+	if len(src) == 0 || len(dst) == 0 || src == dst {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("could not open %s: %w", src, err)
+	}
+
+	defer in.Close()
+
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+
+	out, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".*")
+	if err != nil {
+		return fmt.Errorf("could not install %s: %w", dst, err)
+	}
+
+	defer os.Remove(out.Name())
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("could not install %s: %w", dst, err)
+	}
+
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(out.Name(), fi.Mode().Perm()); err != nil {
+		return err
+	}
+
+	return os.Rename(out.Name(), dst)
+}
+
+// summaryOut returns the writer to which the output of targets and the summary
+// are written.  When the build report is written to stdout, these are written
+// to stderr instead so as to not interleave with the report.
+func (opts *BuildOptions) summaryOut(ctx context.Context) io.Writer {
+	if opts.report != nil && (len(opts.ReportFile) == 0 || opts.ReportFile == "-") {
+		return iostreams.G(ctx).ErrOut
+	}
+
+	return iostreams.G(ctx).Out
+}
+
+// printTargetOutput writes the aggregated output of the provided target.
+func (opts *BuildOptions) printTargetOutput(ctx context.Context, tb *targetBuild) {
+	cs := iostreams.G(ctx).ColorScheme()
+	out := opts.summaryOut(ctx)
+
+	icon := cs.SuccessIcon()
+	if tb.err != nil {
+		icon = cs.FailureIcon()
+	}
+
+	fmt.Fprintf(out, "%s %s\n", icon, cs.Boldf("%s (%s)", tb.target.Name(), target.TargetPlatArchName(tb.target)))
+	_, _ = out.Write(tb.output.Bytes())
+}
+
+// printBuildSummary renders a table with the result of each target.
+func (opts *BuildOptions) printBuildSummary(ctx context.Context, builds []*targetBuild) error {
+	cs := iostreams.G(ctx).ColorScheme()
+
+	workdir, err := filepath.Abs(opts.Workdir)
+	if err != nil {
+		return fmt.Errorf("getting the work directory: %w", err)
+	}
+
+	table, err := tableprinter.NewTablePrinter(ctx,
+		tableprinter.WithMaxWidth(iostreams.G(ctx).TerminalWidth()),
+		tableprinter.WithOutputFormatFromString("table"),
+	)
+	if err != nil {
+		return err
+	}
+
+	table.AddField("TARGET", cs.Bold)
+	table.AddField("PLAT/ARCH", cs.Bold)
+	table.AddField("STATUS", cs.Bold)
+	table.AddField("DURATION", cs.Bold)
+	table.AddField("RESULT", cs.Bold)
+	table.EndRow()
+
+	for _, tb := range builds {
+		table.AddField(tb.target.Name(), nil)
+		table.AddField(target.TargetPlatArchName(tb.target), nil)
+
+		if tb.err != nil {
+			table.AddField("failed", cs.Red)
+			table.AddField(tb.duration.Round(time.Millisecond).String(), nil)
+			table.AddField(tb.err.Error(), nil)
+		} else {
+			kernel, err := filepath.Rel(workdir, tb.target.Kernel())
+			if err != nil {
+				kernel = tb.target.Kernel()
+			}
+
+			table.AddField("success", cs.Green)
+			table.AddField(tb.duration.Round(time.Millisecond).String(), nil)
+			table.AddField(kernel, nil)
+		}
+
+		table.EndRow()
+	}
+
+	return table.Render(opts.summaryOut(ctx))
+}
@@ -0,0 +1,460 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package build
+
+import (
+	"debug/elf"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"kraftkit.sh/make"
+	"kraftkit.sh/unikraft/target"
+)
+
+const (
+	reportFormatJSON  = "json"
+	reportFormatJUnit = "junit"
+)
+
+// The set of phases which are recorded for each target.
+const (
+	phasePull      = "pull"
+	phaseConfigure = "configure"
+	phaseFetch     = "fetch"
+	phasePrepare   = "prepare"
+	phaseCompile   = "compile"
+	phaseLink      = "link"
+)
+
+// buildReport is the machine-readable representation of a build which is
+// emitted via `--report`.
+type buildReport struct {
+	Start   time.Time       `json:"start"`
+	Targets []*targetReport `json:"targets"`
+
+	mu sync.Mutex
+}
+
+// targetReport contains the recorded phases, timings, diagnostics and the
+// resulting image of a single target.
+type targetReport struct {
+	Name         string               `json:"name"`
+	Platform     string               `json:"platform,omitempty"`
+	Architecture string               `json:"architecture,omitempty"`
+	Success      bool                 `json:"success"`
+	Error        string               `json:"error,omitempty"`
+	Phases       []*phaseReport       `json:"phases"`
+	Libraries    []make.LibraryTiming `json:"libraries,omitempty"`
+	Diagnostics  []make.Diagnostic    `json:"diagnostics,omitempty"`
+	Image        *imageReport         `json:"image,omitempty"`
+}
+
+// phaseReport represents a single phase of a target's build.
+type phaseReport struct {
+	Name     string        `json:"name"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// imageReport represents the final kernel image and its size, broken down by
+// allocated ELF section.
+type imageReport struct {
+	Path     string          `json:"path"`
+	Size     int64           `json:"size"`
+	Sections []sectionReport `json:"sections,omitempty"`
+}
+
+// sectionReport represents the size of a single ELF section.
+type sectionReport struct {
+	Name string `json:"name"`
+	Size uint64 `json:"size"`
+}
+
+// newBuildReport prepares a new build report for the given format.
+func newBuildReport(format string) (*buildReport, error) {
+	switch format {
+	case reportFormatJSON, reportFormatJUnit:
+	default:
+		return nil, fmt.Errorf("unsupported report format '%s': expected one of %s", format, strings.Join([]string{reportFormatJSON, reportFormatJUnit}, ", "))
+	}
+
+	return &buildReport{
+		Start: time.Now(),
+	}, nil
+}
+
+// target returns the report for the given target, creating it if it does not
+// exist yet.  A nil report always returns a nil target report, whose methods
+// are no-ops, such that call-sites do not need to check whether reporting is
+// enabled.
+func (report *buildReport) target(tc target.Target) *targetReport {
+	if report == nil {
+		return nil
+	}
+
+	report.mu.Lock()
+	defer report.mu.Unlock()
+
+	name := ""
+	if tc != nil {
+		name = tc.Name()
+	}
+
+	for _, tr := range report.Targets {
+		if tr.Name == name {
+			return tr
+		}
+	}
+
+	tr := &targetReport{
+		Name:   name,
+		Phases: []*phaseReport{},
+	}
+
+	if tc != nil {
+		tr.Platform = tc.Platform().Name()
+		tr.Architecture = tc.Architecture().Name()
+	}
+
+	report.Targets = append(report.Targets, tr)
+
+	return tr
+}
+
+// addPhase records a phase which started at the given time and has just
+// completed with the provided (possibly nil) error.
+func (tr *targetReport) addPhase(name string, start time.Time, err error) {
+	if tr == nil {
+		return
+	}
+
+	phase := &phaseReport{
+		Name:     name,
+		Start:    start,
+		Duration: time.Since(start),
+	}
+
+	if err != nil {
+		phase.Error = err.Error()
+	}
+
+	tr.Phases = append(tr.Phases, phase)
+}
+
+// phase records the execution of the provided function as a phase.
+func (tr *targetReport) phase(name string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	tr.addPhase(name, start, err)
+	return err
+}
+
+// addMake records the result of a make invocation which performed the
+// compilation and linking of the target.  The invocation is split into the
+// compile and link phases based on the first observed link step.
+func (tr *targetReport) addMake(recorder *make.Recorder, start time.Time, err error) {
+	if tr == nil {
+		return
+	}
+
+	recorder.Flush()
+
+	linkStart := recorder.LinkStart()
+	if linkStart.IsZero() {
+		tr.addPhase(phaseCompile, start, err)
+	} else {
+		tr.Phases = append(tr.Phases, &phaseReport{
+			Name:     phaseCompile,
+			Start:    start,
+			Duration: linkStart.Sub(start),
+		})
+		tr.addPhase(phaseLink, linkStart, err)
+	}
+
+	tr.Libraries = append(tr.Libraries, recorder.Libraries()...)
+	tr.Diagnostics = append(tr.Diagnostics, recorder.Diagnostics()...)
+}
+
+// complete marks the target as finished and, if successful, records the size
+// of the resulting kernel image.
+func (tr *targetReport) complete(tc target.Target, err error) {
+	if tr == nil {
+		return
+	}
+
+	if err != nil {
+		tr.Error = err.Error()
+		return
+	}
+
+	tr.Success = true
+
+	if tc == nil || len(tc.Kernel()) == 0 {
+		return
+	}
+
+	fi, err := os.Stat(tc.Kernel())
+	if err != nil {
+		return
+	}
+
+	tr.Image = &imageReport{
+		Path: tc.Kernel(),
+		Size: fi.Size(),
+	}
+
+	// Prefer the symbolic image when determining section sizes since the
+	// stripped image may no longer be a valid ELF (e.g. on Firecracker or when
+	// the image has been converted to a different format).
+	for _, path := range []string{tc.KernelDbg(), tc.Kernel()} {
+		if sections, err := elfSections(path); err == nil {
+			tr.Image.Sections = sections
+			break
+		}
+	}
+}
+
+// elfSections returns the sizes of all allocated sections of the ELF binary at
+// the provided path, sorted by size in descending order.
+func elfSections(path string) ([]sectionReport, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("no path provided")
+	}
+
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	var sections []sectionReport
+	for _, section := range f.Sections {
+		if section.Flags&elf.SHF_ALLOC == 0 || section.Size == 0 {
+			continue
+		}
+
+		sections = append(sections, sectionReport{
+			Name: section.Name,
+			Size: section.Size,
+		})
+	}
+
+	sort.SliceStable(sections, func(i, j int) bool {
+		return sections[i].Size > sections[j].Size
+	})
+
+	return sections, nil
+}
+
+// Write serializes the report in the given format to the provided writer.
+func (report *buildReport) Write(w io.Writer, format string) error {
+	report.mu.Lock()
+	defer report.mu.Unlock()
+
+	switch format {
+	case reportFormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+
+	case reportFormatJUnit:
+		if _, err := io.WriteString(w, xml.Header); err != nil {
+			return err
+		}
+
+		enc := xml.NewEncoder(w)
+		enc.Indent("", "  ")
+		if err := enc.Encode(report.junit()); err != nil {
+			return err
+		}
+
+		_, err := io.WriteString(w, "\n")
+		return err
+	}
+
+	return fmt.Errorf("unsupported report format '%s'", format)
+}
+
+// junitTestSuites is the root element of a JUnit XML report.
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite represents a single target within a JUnit XML report.
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr,omitempty"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Cases      []junitTestCase `xml:"testcase"`
+}
+
+// junitProperty is a key-value pair attached to a JUnit test suite.
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// junitTestCase represents a single phase of a target within a JUnit XML
+// report.
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemErr string        `xml:"system-err,omitempty"`
+}
+
+// junitFailure describes why a JUnit test case failed.
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Body    string `xml:",chardata"`
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// junit converts the report into the JUnit XML representation where each
+// target is a test suite and each phase is a test case.  Compiler warnings
+// and errors are attached to the phase during which they were emitted.
+func (report *buildReport) junit() junitTestSuites {
+	suites := junitTestSuites{
+		Name: "kraft build",
+		Time: junitSeconds(time.Since(report.Start)),
+	}
+
+	for _, tr := range report.Targets {
+		suite := junitTestSuite{
+			Name: tr.Name,
+		}
+
+		if len(tr.Platform) > 0 {
+			suite.Name = fmt.Sprintf("%s (%s/%s)", tr.Name, tr.Platform, tr.Architecture)
+		}
+
+		var total time.Duration
+		for _, phase := range tr.Phases {
+			total += phase.Duration
+		}
+
+		if len(tr.Phases) > 0 {
+			suite.Timestamp = tr.Phases[0].Start.Format(time.RFC3339)
+		}
+
+		suite.Time = junitSeconds(total)
+
+		if tr.Image != nil {
+			suite.Properties = append(suite.Properties, junitProperty{
+				Name:  "image.size",
+				Value: fmt.Sprintf("%d", tr.Image.Size),
+			})
+			for _, section := range tr.Image.Sections {
+				suite.Properties = append(suite.Properties, junitProperty{
+					Name:  "image.section" + section.Name,
+					Value: fmt.Sprintf("%d", section.Size),
+				})
+			}
+		}
+
+		for _, phase := range tr.Phases {
+			tc := junitTestCase{
+				Name:      phase.Name,
+				ClassName: suite.Name,
+				Time:      junitSeconds(phase.Duration),
+			}
+
+			var warnings, failures []string
+			if phase.Name == phaseCompile || phase.Name == phaseLink {
+				for _, diag := range tr.Diagnostics {
+					location := diag.File
+					if diag.Line > 0 {
+						location = fmt.Sprintf("%s:%d", diag.File, diag.Line)
+					}
+
+					line := diag.Message
+					if len(location) > 0 {
+						line = fmt.Sprintf("%s: %s", location, diag.Message)
+					}
+
+					if diag.Severity == make.DiagnosticSeverityError {
+						failures = append(failures, line)
+					} else {
+						warnings = append(warnings, line)
+					}
+				}
+			}
+
+			// Only attach the diagnostics to the last make-driven phase to avoid
+			// duplicating them across both the compile and link test cases.
+			if phase.Name == phaseCompile && tr.hasPhase(phaseLink) {
+				warnings, failures = nil, nil
+			}
+
+			if len(phase.Error) > 0 {
+				tc.Failure = &junitFailure{
+					Message: phase.Error,
+					Type:    phase.Name,
+					Body:    strings.Join(failures, "\n"),
+				}
+				suite.Failures++
+			}
+
+			if len(warnings) > 0 {
+				tc.SystemErr = strings.Join(warnings, "\n")
+			}
+
+			suite.Cases = append(suite.Cases, tc)
+		}
+
+		if !tr.Success && suite.Failures == 0 {
+			// The build failed outside of a recorded phase.
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      "build",
+				ClassName: suite.Name,
+				Time:      junitSeconds(0),
+				Failure: &junitFailure{
+					Message: tr.Error,
+				},
+			})
+			suite.Failures++
+		}
+
+		suite.Tests = len(suite.Cases)
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	return suites
+}
+
+// hasPhase returns whether the target report contains the named phase.
+func (tr *targetReport) hasPhase(name string) bool {
+	for _, phase := range tr.Phases {
+		if phase.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
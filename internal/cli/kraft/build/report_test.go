@@ -0,0 +1,149 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package build
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"kraftkit.sh/make"
+)
+
+func TestNewBuildReportUnsupportedFormat(t *testing.T) {
+	if _, err := newBuildReport("yaml"); err == nil {
+		t.Fatal("expected an error for an unsupported format")
+	}
+}
+
+func TestNilReportIsNoop(t *testing.T) {
+	var report *buildReport
+
+	tr := report.target(nil)
+	if tr != nil {
+		t.Fatalf("expected nil target report, got %v", tr)
+	}
+
+	// None of the following may panic.
+	tr.addPhase(phasePull, time.Now(), nil)
+	tr.addMake(nil, time.Now(), nil)
+	tr.complete(nil, nil)
+
+	called := false
+	if err := tr.phase(phaseFetch, func() error {
+		called = true
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !called {
+		t.Fatal("expected phase function to be called on a nil report")
+	}
+}
+
+func TestReportJUnit(t *testing.T) {
+	report, err := newBuildReport(reportFormatJUnit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok := report.target(nil)
+	ok.Name = "ok"
+	ok.addPhase(phaseFetch, time.Now(), nil)
+	ok.addPhase(phaseCompile, time.Now(), nil)
+	ok.addPhase(phaseLink, time.Now(), nil)
+	ok.Diagnostics = []make.Diagnostic{
+		{Severity: make.DiagnosticSeverityWarning, File: "main.c", Line: 3, Message: "unused variable 'x'"},
+	}
+	ok.complete(nil, nil)
+	ok.Image = &imageReport{
+		Size:     4096,
+		Sections: []sectionReport{{Name: ".text", Size: 1024}},
+	}
+
+	failed := &targetReport{Name: "failed"}
+	report.Targets = append(report.Targets, failed)
+	failed.addPhase(phaseCompile, time.Now(), errors.New("make failed"))
+	failed.Diagnostics = []make.Diagnostic{
+		{Severity: make.DiagnosticSeverityError, File: "main.c", Line: 7, Message: "expected ';'"},
+	}
+	failed.complete(nil, errors.New("build failed: make failed"))
+
+	outside := &targetReport{Name: "outside"}
+	report.Targets = append(report.Targets, outside)
+	outside.complete(nil, errors.New("could not pull"))
+
+	var buf bytes.Buffer
+	if err := report.Write(&buf, reportFormatJUnit); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(buf.String(), xml.Header) {
+		t.Errorf("expected report to start with the XML header, got %q", buf.String())
+	}
+
+	var suites junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &suites); err != nil {
+		t.Fatalf("could not parse report: %v", err)
+	}
+
+	if suites.Tests != 5 {
+		t.Errorf("expected 5 tests, got %d", suites.Tests)
+	}
+
+	if suites.Failures != 2 {
+		t.Errorf("expected 2 failures, got %d", suites.Failures)
+	}
+
+	if len(suites.Suites) != 3 {
+		t.Fatalf("expected 3 suites, got %d", len(suites.Suites))
+	}
+
+	okSuite := suites.Suites[0]
+	if okSuite.Failures != 0 || len(okSuite.Cases) != 3 {
+		t.Errorf("expected 3 passing cases in %s, got %+v", okSuite.Name, okSuite.Cases)
+	}
+
+	// Diagnostics are only attached to the link phase when it exists.
+	if okSuite.Cases[1].SystemErr != "" {
+		t.Errorf("expected no diagnostics on the compile phase, got %q", okSuite.Cases[1].SystemErr)
+	}
+
+	if expected := "main.c:3: unused variable 'x'"; okSuite.Cases[2].SystemErr != expected {
+		t.Errorf("expected link phase diagnostics %q, got %q", expected, okSuite.Cases[2].SystemErr)
+	}
+
+	properties := map[string]string{}
+	for _, property := range okSuite.Properties {
+		properties[property.Name] = property.Value
+	}
+
+	if properties["image.size"] != "4096" || properties["image.section.text"] != "1024" {
+		t.Errorf("unexpected image properties: %v", properties)
+	}
+
+	failedSuite := suites.Suites[1]
+	if failedSuite.Failures != 1 || len(failedSuite.Cases) != 1 {
+		t.Fatalf("expected a single failing case in %s, got %+v", failedSuite.Name, failedSuite.Cases)
+	}
+
+	failure := failedSuite.Cases[0].Failure
+	if failure == nil || failure.Message != "make failed" || failure.Type != phaseCompile {
+		t.Errorf("unexpected failure: %+v", failure)
+	} else if failure.Body != "main.c:7: expected ';'" {
+		t.Errorf("expected the compiler error in the failure body, got %q", failure.Body)
+	}
+
+	// A target which failed outside of a recorded phase is reported as a single
+	// failing build case.
+	outsideSuite := suites.Suites[2]
+	if outsideSuite.Failures != 1 || len(outsideSuite.Cases) != 1 || outsideSuite.Cases[0].Name != "build" {
+		t.Errorf("expected a single failing build case in %s, got %+v", outsideSuite.Name, outsideSuite.Cases)
+	}
+}
@@ -12,8 +12,9 @@ import (
 )
 
 // initProject sets up the project based on the provided context and
-// options.
-func (opts *BuildOptions) initProject(ctx context.Context) error {
+// options.  Additional project options may be provided to further customize
+// the project.
+func (opts *BuildOptions) initProject(ctx context.Context, extra ...app.ProjectOption) error {
 	var err error
 
 	popts := []app.ProjectOption{
@@ -26,6 +27,8 @@ func (opts *BuildOptions) initProject(ctx context.Context) error {
 		popts = append(popts, app.WithProjectDefaultKraftfiles())
 	}
 
+	popts = append(popts, extra...)
+
 	// Interpret the project directory
 	opts.Project, err = app.NewProjectFromOptions(ctx, popts...)
 	if err != nil {
@@ -52,6 +52,7 @@ func Create(ctx context.Context, opts *CreateOptions) (*kccertificates.CreateRes
 	if opts.Client == nil {
 		opts.Client = kraftcloud.NewCertificatesClient(
 			kraftcloud.WithToken(config.GetKraftCloudTokenAuthConfig(*opts.Auth)),
+			kraftcloud.WithHTTPClient(utils.HTTPClient()),
 		)
 	}
 
@@ -83,6 +83,7 @@ func (opts *GetOptions) Run(ctx context.Context, args []string) error {
 
 	client := kraftcloud.NewCertificatesClient(
 		kraftcloud.WithToken(config.GetKraftCloudTokenAuthConfig(*auth)),
+		kraftcloud.WithHTTPClient(utils.HTTPClient()),
 	)
 
 	certResp, err := client.WithMetro(opts.metro).Get(ctx, args[0])
@@ -92,6 +92,7 @@ func (opts *RemoveOptions) Run(ctx context.Context, args []string) error {
 
 	client := kraftcloud.NewCertificatesClient(
 		kraftcloud.WithToken(config.GetKraftCloudTokenAuthConfig(*auth)),
+		kraftcloud.WithHTTPClient(utils.HTTPClient()),
 	)
 
 	if opts.All {
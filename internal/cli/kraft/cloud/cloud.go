@@ -15,6 +15,7 @@ import (
 	"kraftkit.sh/internal/cli/kraft/cloud/certificate"
 	"kraftkit.sh/internal/cli/kraft/cloud/compose"
 	"kraftkit.sh/internal/cli/kraft/cloud/deploy"
+	"kraftkit.sh/internal/cli/kraft/cloud/emulator"
 	"kraftkit.sh/internal/cli/kraft/cloud/img"
 	"kraftkit.sh/internal/cli/kraft/cloud/instance"
 	"kraftkit.sh/internal/cli/kraft/cloud/metros"
@@ -86,6 +87,7 @@ func NewCmd() *cobra.Command {
 	}
 
 	cmd.AddCommand(deploy.NewCmd())
+	cmd.AddCommand(emulator.NewCmd())
 	cmd.AddCommand(quotas.NewCmd())
 	cmd.AddCommand(tunnel.NewCmd())
 
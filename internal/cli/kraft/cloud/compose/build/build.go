@@ -89,6 +89,7 @@ func Build(ctx context.Context, opts *BuildOptions, args ...string) error {
 	if opts.Client == nil {
 		opts.Client = kraftcloud.NewClient(
 			kraftcloud.WithToken(config.GetKraftCloudTokenAuthConfig(*opts.Auth)),
+			kraftcloud.WithHTTPClient(utils.HTTPClient()),
 		)
 	}
 
@@ -79,6 +79,7 @@ func (opts *DownOptions) Run(ctx context.Context, args []string) error {
 	if opts.Client == nil {
 		opts.Client = kraftcloud.NewClient(
 			kraftcloud.WithToken(config.GetKraftCloudTokenAuthConfig(*opts.Auth)),
+			kraftcloud.WithHTTPClient(utils.HTTPClient()),
 		)
 	}
 
@@ -90,6 +90,7 @@ func Logs(ctx context.Context, opts *LogsOptions, args ...string) error {
 	if opts.Client == nil {
 		opts.Client = kraftcloud.NewClient(
 			kraftcloud.WithToken(config.GetKraftCloudTokenAuthConfig(*opts.Auth)),
+			kraftcloud.WithHTTPClient(utils.HTTPClient()),
 		)
 	}
 
@@ -70,6 +70,7 @@ func Push(ctx context.Context, opts *PushOptions, args ...string) error {
 	if opts.Client == nil {
 		opts.Client = kraftcloud.NewInstancesClient(
 			kraftcloud.WithToken(config.GetKraftCloudTokenAuthConfig(*opts.Auth)),
+			kraftcloud.WithHTTPClient(utils.HTTPClient()),
 		)
 	}
 
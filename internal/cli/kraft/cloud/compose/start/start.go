@@ -84,6 +84,7 @@ func (opts *StartOptions) Run(ctx context.Context, args []string) error {
 	if opts.Client == nil {
 		opts.Client = kraftcloud.NewClient(
 			kraftcloud.WithToken(config.GetKraftCloudTokenAuthConfig(*opts.Auth)),
+			kraftcloud.WithHTTPClient(utils.HTTPClient()),
 		)
 	}
 
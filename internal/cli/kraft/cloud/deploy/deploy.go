@@ -210,6 +210,7 @@ func (opts *DeployOptions) Run(ctx context.Context, args []string) error {
 
 	opts.Client = kraftcloud.NewClient(
 		kraftcloud.WithToken(config.GetKraftCloudTokenAuthConfig(*opts.Auth)),
+		kraftcloud.WithHTTPClient(utils.HTTPClient()),
 	)
 
 	// TODO: Preflight check: check if `--subdomain` is already taken
@@ -0,0 +1,319 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package emulator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	machineapi "kraftkit.sh/api/machine/v1alpha1"
+	"kraftkit.sh/internal/cli/kraft/ingress"
+	"kraftkit.sh/internal/cli/kraft/remove"
+	"kraftkit.sh/internal/cli/kraft/run"
+	"kraftkit.sh/internal/cli/kraft/start"
+	"kraftkit.sh/internal/cli/kraft/stop"
+	"kraftkit.sh/internal/cloudemulator"
+	"kraftkit.sh/internal/netutil"
+	mplatform "kraftkit.sh/machine/platform"
+	"kraftkit.sh/packmanager"
+	"kraftkit.sh/unikraft"
+)
+
+// LabelInstance is the label of a machine which holds the UUID of the
+// instance of the emulator it runs.
+const LabelInstance = "kraftkit.sh/cloud-emulator.instance"
+
+// machineDriver runs the instances of the emulator as local machines and
+// stores its volumes as directories which are mounted into them.
+type machineDriver struct {
+	volumesDir        string
+	machineController machineapi.MachineService
+}
+
+var _ cloudemulator.Driver = (*machineDriver)(nil)
+
+// newMachineDriver returns a driver which stores volumes in the provided
+// directory.
+func newMachineDriver(ctx context.Context, volumesDir string) (*machineDriver, error) {
+	if err := os.MkdirAll(volumesDir, fs.ModeSetgid|0o775); err != nil {
+		return nil, fmt.Errorf("could not make volumes directory: %w", err)
+	}
+
+	machineController, err := mplatform.NewMachineV1alpha1ServiceIterator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &machineDriver{
+		volumesDir:        volumesDir,
+		machineController: machineController,
+	}, nil
+}
+
+// machineName returns the name of the machine running the provided instance.
+func machineName(instance *cloudemulator.Instance) string {
+	return fmt.Sprintf("ukc-%s", instance.Name)
+}
+
+// Images implements cloudemulator.Driver and returns the applications of the
+// local package catalog.
+func (d *machineDriver) Images(ctx context.Context) ([]cloudemulator.Image, error) {
+	packages, err := packmanager.G(ctx).Catalog(ctx,
+		packmanager.WithTypes(unikraft.ComponentTypeApp),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	images := make([]cloudemulator.Image, 0, len(packages))
+	for _, pkg := range packages {
+		tag := fmt.Sprintf("%s:%s", pkg.Name(), pkg.Version())
+
+		idx := slices.IndexFunc(images, func(image cloudemulator.Image) bool {
+			return image.Digest == pkg.ID()
+		})
+		if idx >= 0 {
+			if !slices.Contains(images[idx].Tags, tag) {
+				images[idx].Tags = append(images[idx].Tags, tag)
+			}
+			continue
+		}
+
+		images = append(images, cloudemulator.Image{
+			Digest:      pkg.ID(),
+			Tags:        []string{tag},
+			SizeInBytes: pkg.Size(),
+		})
+	}
+
+	return images, nil
+}
+
+// CreateInstance implements cloudemulator.Driver.  The machine of the
+// instance is only created once it is started, such that volumes attached in
+// the meantime are mounted.
+func (d *machineDriver) CreateInstance(ctx context.Context, instance *cloudemulator.Instance) error {
+	images, err := d.Images(ctx)
+	if err != nil {
+		return fmt.Errorf("could not list images: %w", err)
+	}
+
+	if !slices.ContainsFunc(images, func(image cloudemulator.Image) bool {
+		return cloudemulator.MatchesImage(image, instance.Image)
+	}) {
+		return fmt.Errorf("image %s not found locally: try 'kraft pkg pull %s'", instance.Image, instance.Image)
+	}
+
+	instance.Machine = machineName(instance)
+
+	return nil
+}
+
+// machine returns the machine of the provided instance or nil if it does not
+// exist.
+func (d *machineDriver) machine(ctx context.Context, instance *cloudemulator.Instance) (*machineapi.Machine, error) {
+	machines, err := d.machineController.List(ctx, &machineapi.MachineList{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, machine := range machines.Items {
+		if machine.Name == instance.Machine {
+			return &machine, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// StartInstance implements cloudemulator.Driver and (re)creates the machine of
+// the provided instance from its current configuration before starting it.
+func (d *machineDriver) StartInstance(ctx context.Context, instance *cloudemulator.Instance) error {
+	if err := d.DeleteInstance(ctx, instance); err != nil {
+		return err
+	}
+
+	env := make([]string, 0, len(instance.Env))
+	for k, v := range instance.Env {
+		env = append(env, fmt.Sprintf("%s=%s", k, v))
+	}
+
+	slices.Sort(env)
+
+	labels := []string{
+		fmt.Sprintf("%s=%s", LabelInstance, instance.UUID),
+	}
+
+	// Services are published on free ports of the loopback interface through
+	// which the local ingress reaches the domain names of the instance.
+	var ports []string
+	var published []int
+	for _, service := range instance.Services {
+		if slices.Contains(published, service.DestinationPort) {
+			continue
+		}
+
+		port, err := netutil.FreeLoopbackPort()
+		if err != nil {
+			return err
+		}
+
+		ports = append(ports, fmt.Sprintf("127.0.0.1:%d:%d/tcp", port, service.DestinationPort))
+		published = append(published, service.DestinationPort)
+	}
+
+	if instance.ServiceGroup != nil && len(instance.ServiceGroup.Domains) > 0 && len(published) > 0 {
+		fqdns := make([]string, len(instance.ServiceGroup.Domains))
+		for i, domain := range instance.ServiceGroup.Domains {
+			fqdns[i] = domain.FQDN
+		}
+
+		labels = append(labels,
+			fmt.Sprintf("%s=%s", ingress.LabelDomain, strings.Join(fqdns, ",")),
+			fmt.Sprintf("%s=%d", ingress.LabelPort, published[0]),
+		)
+	}
+
+	volumes := make([]string, len(instance.Volumes))
+	for i, volume := range instance.Volumes {
+		volumes[i] = fmt.Sprintf("%s:%s", volume.Path, volume.At)
+	}
+
+	runOptions := run.RunOptions{
+		CPUs:     uint(instance.Vcpus),
+		Detach:   true,
+		Env:      env,
+		Labels:   labels,
+		Memory:   fmt.Sprintf("%dMi", instance.MemoryMB),
+		Name:     instance.Machine,
+		NoStart:  true,
+		Platform: mplatform.PlatformQEMU.String(),
+		Ports:    ports,
+		Volumes:  volumes,
+	}
+
+	if err := runOptions.Run(ctx, append([]string{instance.Image}, instance.Args...)); err != nil {
+		return fmt.Errorf("could not create machine: %w", err)
+	}
+
+	startOptions := start.StartOptions{
+		Detach:   true,
+		Platform: "auto",
+	}
+
+	return startOptions.Run(ctx, []string{instance.Machine})
+}
+
+// StopInstance implements cloudemulator.Driver.
+func (d *machineDriver) StopInstance(ctx context.Context, instance *cloudemulator.Instance) error {
+	running, err := d.InstanceRunning(ctx, instance)
+	if err != nil || !running {
+		return err
+	}
+
+	stopOptions := stop.StopOptions{
+		Platform: "auto",
+	}
+
+	return stopOptions.Run(ctx, []string{instance.Machine})
+}
+
+// DeleteInstance implements cloudemulator.Driver and removes the machine of
+// the provided instance, if any.
+func (d *machineDriver) DeleteInstance(ctx context.Context, instance *cloudemulator.Instance) error {
+	machine, err := d.machine(ctx, instance)
+	if err != nil || machine == nil {
+		return err
+	}
+
+	removeOptions := remove.RemoveOptions{
+		Platform: "auto",
+	}
+
+	return removeOptions.Run(ctx, []string{machine.Name})
+}
+
+// InstanceRunning implements cloudemulator.Driver.
+func (d *machineDriver) InstanceRunning(ctx context.Context, instance *cloudemulator.Instance) (bool, error) {
+	machine, err := d.machine(ctx, instance)
+	if err != nil || machine == nil {
+		return false, err
+	}
+
+	return machine.Status.State == machineapi.MachineStateRunning, nil
+}
+
+// InstanceLogs implements cloudemulator.Driver and returns the console output
+// of the machine of the provided instance.
+func (d *machineDriver) InstanceLogs(ctx context.Context, instance *cloudemulator.Instance) ([]byte, error) {
+	machine, err := d.machine(ctx, instance)
+	if err != nil || machine == nil || len(machine.Status.LogFile) == 0 {
+		return nil, err
+	}
+
+	output, err := os.ReadFile(machine.Status.LogFile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("could not read logs: %w", err)
+	}
+
+	return output, nil
+}
+
+// CreateVolume implements cloudemulator.Driver.  Volumes are directories
+// which are shared with the machines mounting them, such that their size is
+// not enforced.
+func (d *machineDriver) CreateVolume(_ context.Context, volume *cloudemulator.Volume) error {
+	volume.Path = filepath.Join(d.volumesDir, volume.UUID)
+
+	return os.MkdirAll(volume.Path, 0o775)
+}
+
+// DeleteVolume implements cloudemulator.Driver.
+func (d *machineDriver) DeleteVolume(_ context.Context, volume *cloudemulator.Volume) error {
+	if len(volume.Path) == 0 {
+		return nil
+	}
+
+	return os.RemoveAll(volume.Path)
+}
+
+// removeStale removes the machines left behind by a previous emulator which
+// did not exit cleanly.
+func (d *machineDriver) removeStale(ctx context.Context) error {
+	machines, err := d.machineController.List(ctx, &machineapi.MachineList{})
+	if err != nil {
+		return err
+	}
+
+	var stale []string
+	for _, machine := range machines.Items {
+		if _, ok := machine.Labels[LabelInstance]; ok {
+			stale = append(stale, machine.Name)
+		}
+	}
+
+	if len(stale) == 0 {
+		return nil
+	}
+
+	removeOptions := remove.RemoveOptions{
+		Platform: "auto",
+	}
+
+	var errs []error
+	for _, name := range stale {
+		if err := removeOptions.Run(ctx, []string{name}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
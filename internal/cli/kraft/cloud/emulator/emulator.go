@@ -0,0 +1,157 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package emulator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"kraftkit.sh/cmdfactory"
+	"kraftkit.sh/config"
+	"kraftkit.sh/internal/cloudemulator"
+	"kraftkit.sh/iostreams"
+	"kraftkit.sh/log"
+)
+
+// shutdownTimeout is the time after which pending requests are abandoned
+// when the emulator exits.
+const shutdownTimeout = 10 * time.Second
+
+type EmulatorOptions struct {
+	Listen string `local:"true" long:"listen" short:"l" usage:"Address to serve the emulated API on" default:"127.0.0.1:8787"`
+
+	metro string
+}
+
+func NewCmd() *cobra.Command {
+	cmd, err := cmdfactory.New(&EmulatorOptions{}, cobra.Command{
+		Short: "Emulate Unikraft Cloud locally",
+		Use:   "emulator [FLAGS]",
+		Args:  cobra.NoArgs,
+		Long: heredoc.Docf(`
+			Emulate Unikraft Cloud locally.
+
+			Serve the REST API of Unikraft Cloud on the host such that deployment
+			scripts and the %[1]skraft cloud%[1]s subcommands can be used without an
+			account or network access.  Instances are run as local unikernels with
+			QEMU from the images of the local package catalog and volumes are
+			directories on the host which are shared with the instances mounting
+			them.  The domain names of service groups end in
+			%[1]s.<metro>.localhost%[1]s and are routed through the local ingress.
+
+			Point the %[1]skraft cloud%[1]s subcommands at the emulator by setting
+			the %[1]sUKC_EMULATOR%[1]s environmental variable to its address.  The
+			metro and token then default to the ones of the emulator.
+
+			All instances and volumes are removed when the emulator exits.  Only the
+			instance, volume, service group, autoscale, certificate, image and
+			quota endpoints of the API are emulated.  Certificates and autoscale
+			configurations are kept in memory: service groups are not scaled.
+		`, "`"),
+		Example: heredoc.Doc(`
+			# Serve the emulated API on the default address
+			$ kraft cloud emulator
+
+			# Use the emulator from another shell
+			$ export UKC_EMULATOR=http://127.0.0.1:8787
+			$ kraft cloud instance create -S -p 443:8080 unikraft.org/nginx:latest
+		`),
+		Annotations: map[string]string{
+			cmdfactory.AnnotationHelpGroup: "kraftcloud",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+func (opts *EmulatorOptions) Pre(cmd *cobra.Command, _ []string) error {
+	opts.metro = cmd.Flag("metro").Value.String()
+	if opts.metro == "" {
+		opts.metro = cloudemulator.DefaultMetro
+	}
+
+	return nil
+}
+
+func (opts *EmulatorOptions) Run(ctx context.Context, _ []string) error {
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	driver, err := newMachineDriver(ctx,
+		filepath.Join(config.G[config.KraftKit](ctx).RuntimeDir, "cloud-emulator", "volumes"),
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := driver.removeStale(ctx); err != nil {
+		log.G(ctx).Warnf("could not remove machines of a previous emulator: %v", err)
+	}
+
+	emulator, err := cloudemulator.New(
+		cloudemulator.WithDriver(driver),
+		cloudemulator.WithMetro(opts.metro),
+	)
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", opts.Listen)
+	if err != nil {
+		return fmt.Errorf("could not listen on %s: %w", opts.Listen, err)
+	}
+
+	server := http.Server{
+		Handler:           emulator,
+		ReadHeaderTimeout: shutdownTimeout,
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		sctx, scancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer scancel()
+
+		_ = server.Shutdown(sctx)
+	}()
+
+	fmt.Fprintf(iostreams.G(ctx).Out,
+		"serving metro %s, run:\n\n\texport UKC_EMULATOR=http://%s\n\n",
+		emulator.Metro(),
+		listener.Addr().String(),
+	)
+
+	serveErr := server.Serve(listener)
+	if errors.Is(serveErr, http.ErrServerClosed) {
+		serveErr = nil
+	}
+
+	log.G(ctx).Info("removing instances and volumes")
+
+	// The context is cancelled at this point, yet the resources of the
+	// emulator must still be released.
+	if err := emulator.Close(context.WithoutCancel(ctx)); err != nil {
+		return errors.Join(serveErr, err)
+	}
+
+	return serveErr
+}
@@ -85,6 +85,7 @@ func (opts *ListOptions) Run(ctx context.Context, args []string) error {
 
 	client := kraftcloud.NewImagesClient(
 		kraftcloud.WithToken(config.GetKraftCloudTokenAuthConfig(*auth)),
+		kraftcloud.WithHTTPClient(utils.HTTPClient()),
 	)
 
 	resp, err := client.WithMetro(opts.metro).List(ctx)
@@ -95,6 +95,7 @@ func (opts *RemoveOptions) Run(ctx context.Context, args []string) error {
 	if opts.Client == nil {
 		opts.Client = kraftcloud.NewImagesClient(
 			kraftcloud.WithToken(config.GetKraftCloudTokenAuthConfig(*opts.Auth)),
+			kraftcloud.WithHTTPClient(utils.HTTPClient()),
 		)
 	}
 
@@ -84,6 +84,7 @@ func (opts *GetOptions) Run(ctx context.Context, args []string) error {
 
 	client := kraftcloud.NewInstancesClient(
 		kraftcloud.WithToken(config.GetKraftCloudTokenAuthConfig(*auth)),
+		kraftcloud.WithHTTPClient(utils.HTTPClient()),
 	)
 
 	resp, err := client.WithMetro(opts.Metro).Get(ctx, args...)
@@ -70,6 +70,7 @@ func (opts *ListOptions) Run(ctx context.Context, args []string) error {
 
 	client := kraftcloud.NewInstancesClient(
 		kraftcloud.WithToken(config.GetKraftCloudTokenAuthConfig(*auth)),
+		kraftcloud.WithHTTPClient(utils.HTTPClient()),
 	)
 
 	resp, err := client.WithMetro(opts.metro).List(ctx)
@@ -73,6 +73,7 @@ func (opts *QuotasOptions) Run(ctx context.Context, _ []string) error {
 
 	client := kraftcloud.NewClient(
 		kraftcloud.WithToken(config.GetKraftCloudTokenAuthConfig(*auth)),
+		kraftcloud.WithHTTPClient(utils.HTTPClient()),
 	)
 
 	resp, err := client.Users().WithMetro(opts.metro).Quotas(ctx)
@@ -62,6 +62,7 @@ func Create(ctx context.Context, opts *CreateOptions, args ...string) (*kcservic
 	if opts.Client == nil {
 		opts.Client = kraftcloud.NewServicesClient(
 			kraftcloud.WithToken(config.GetKraftCloudTokenAuthConfig(*opts.Auth)),
+			kraftcloud.WithHTTPClient(utils.HTTPClient()),
 		)
 	}
 
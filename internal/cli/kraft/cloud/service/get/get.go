@@ -80,6 +80,7 @@ func (opts *GetOptions) Run(ctx context.Context, args []string) error {
 
 	client := kraftcloud.NewServicesClient(
 		kraftcloud.WithToken(config.GetKraftCloudTokenAuthConfig(*auth)),
+		kraftcloud.WithHTTPClient(utils.HTTPClient()),
 	)
 
 	resp, err := client.WithMetro(opts.metro).Get(ctx, args[0])
@@ -169,6 +169,7 @@ func (opts *TunnelOptions) Run(ctx context.Context, args []string) error {
 	var authStr string
 	cliInstance := kraftcloud.NewInstancesClient(
 		kraftcloud.WithToken(config.GetKraftCloudTokenAuthConfig(*auth)),
+		kraftcloud.WithHTTPClient(utils.HTTPClient()),
 	).WithMetro(opts.Metro)
 
 	rawInstances := opts.instances
@@ -0,0 +1,65 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package utils
+
+import (
+	"encoding/base64"
+	"net/http"
+	"os"
+
+	"kraftkit.sh/internal/cloudemulator"
+)
+
+// emulatorToken is the access token used with the emulator unless another one
+// is set.  The emulator accepts any token.
+var emulatorToken = base64.StdEncoding.EncodeToString([]byte("emulator:emulator"))
+
+// EmulatorAddress returns the URL of the emulator of Unikraft Cloud set in the
+// environment, e.g. as printed by 'kraft cloud emulator', or an empty string
+// if it is unset.
+func EmulatorAddress() string {
+	for _, env := range []string{
+		"UNIKRAFTCLOUD_EMULATOR",
+		"KRAFTCLOUD_EMULATOR",
+		"KC_EMULATOR",
+		"UKC_EMULATOR",
+	} {
+		if address := os.Getenv(env); address != "" {
+			return address
+		}
+	}
+
+	return ""
+}
+
+// HTTPClient returns the HTTP client of the clients of the Unikraft Cloud API.
+// The client sends the requests made to the API to the emulator set in the
+// environment, if any, whilst all other HTTP clients, e.g. those of OCI
+// registries, remain unaffected.
+func HTTPClient() *http.Client {
+	address := EmulatorAddress()
+	if address == "" {
+		return &http.Client{}
+	}
+
+	transport, err := cloudemulator.NewTransport(address, nil)
+	if err != nil {
+		// Never fall back to the API whilst an emulator is requested.
+		return &http.Client{Transport: errorTransport{err}}
+	}
+
+	return &http.Client{Transport: transport}
+}
+
+// errorTransport implements http.RoundTripper and fails every request with
+// the provided error.
+type errorTransport struct {
+	err error
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t errorTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, t.err
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/spf13/cobra"
 	"kraftkit.sh/config"
+	"kraftkit.sh/internal/cloudemulator"
 	"kraftkit.sh/log"
 	"kraftkit.sh/tui/selection"
 
@@ -24,6 +25,15 @@ func (m Metro) String() string {
 }
 
 func PopulateMetroToken(cmd *cobra.Command, metro, token *string) error {
+	emulator := EmulatorAddress()
+	if emulator != "" {
+		if _, err := cloudemulator.NewTransport(emulator, nil); err != nil {
+			return err
+		}
+
+		log.G(cmd.Context()).WithField("emulator", emulator).Debug("using")
+	}
+
 	*metro = cmd.Flag("metro").Value.String()
 	if *metro == "" {
 		*metro = os.Getenv("UNIKRAFTCLOUD_METRO")
@@ -40,6 +50,10 @@ func PopulateMetroToken(cmd *cobra.Command, metro, token *string) error {
 			*metro = os.Getenv("UKC_METRO")
 		}
 
+		if *metro == "" && emulator != "" {
+			*metro = cloudemulator.DefaultMetro
+		}
+
 		if *metro == "" && !config.G[config.KraftKit](cmd.Context()).NoPrompt {
 			client := unikraftcloud.NewMetrosClient()
 
@@ -89,6 +103,10 @@ func PopulateMetroToken(cmd *cobra.Command, metro, token *string) error {
 			*token = os.Getenv("UKC_TOKEN")
 		}
 
+		if *token == "" && emulator != "" {
+			*token = emulatorToken
+		}
+
 		if *token != "" {
 			log.G(cmd.Context()).WithField("token", *token).Debug("using")
 		}
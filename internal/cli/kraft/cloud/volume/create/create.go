@@ -50,6 +50,7 @@ func Create(ctx context.Context, opts *CreateOptions) (*kcvolumes.CreateResponse
 	if opts.Client == nil {
 		opts.Client = kraftcloud.NewVolumesClient(
 			kraftcloud.WithToken(config.GetKraftCloudTokenAuthConfig(*opts.Auth)),
+			kraftcloud.WithHTTPClient(utils.HTTPClient()),
 		)
 	}
 
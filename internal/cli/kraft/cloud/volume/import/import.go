@@ -112,6 +112,7 @@ func (opts *ImportOptions) Run(ctx context.Context, _ []string) error {
 func importVolumeData(ctx context.Context, opts *ImportOptions) (retErr error) {
 	cli := kraftcloud.NewClient(
 		kraftcloud.WithToken(config.GetKraftCloudTokenAuthConfig(*opts.Auth)),
+		kraftcloud.WithHTTPClient(utils.HTTPClient()),
 	)
 	icli := cli.Instances().WithMetro(opts.Metro)
 	vcli := cli.Volumes().WithMetro(opts.Metro)
@@ -14,11 +14,13 @@ import (
 
 	"kraftkit.sh/cmdfactory"
 	"kraftkit.sh/internal/cli/kraft/compose/build"
+	"kraftkit.sh/internal/cli/kraft/compose/config"
 	"kraftkit.sh/internal/cli/kraft/compose/create"
 	"kraftkit.sh/internal/cli/kraft/compose/down"
 	"kraftkit.sh/internal/cli/kraft/compose/logs"
 	"kraftkit.sh/internal/cli/kraft/compose/ls"
 	"kraftkit.sh/internal/cli/kraft/compose/pause"
+	"kraftkit.sh/internal/cli/kraft/compose/proxy"
 	"kraftkit.sh/internal/cli/kraft/compose/ps"
 	"kraftkit.sh/internal/cli/kraft/compose/pull"
 	"kraftkit.sh/internal/cli/kraft/compose/push"
@@ -26,6 +28,7 @@ import (
 	"kraftkit.sh/internal/cli/kraft/compose/stop"
 	"kraftkit.sh/internal/cli/kraft/compose/unpause"
 	"kraftkit.sh/internal/cli/kraft/compose/up"
+	"kraftkit.sh/internal/cli/kraft/compose/watch"
 )
 
 type ComposeOptions struct {
@@ -54,11 +57,13 @@ func NewCmd() *cobra.Command {
 	}
 
 	cmd.AddCommand(build.NewCmd())
+	cmd.AddCommand(config.NewCmd())
 	cmd.AddCommand(create.NewCmd())
 	cmd.AddCommand(down.NewCmd())
 	cmd.AddCommand(logs.NewCmd())
 	cmd.AddCommand(ls.NewCmd())
 	cmd.AddCommand(pause.NewCmd())
+	cmd.AddCommand(proxy.NewCmd())
 	cmd.AddCommand(ps.NewCmd())
 	cmd.AddCommand(pull.NewCmd())
 	cmd.AddCommand(push.NewCmd())
@@ -66,6 +71,7 @@ func NewCmd() *cobra.Command {
 	cmd.AddCommand(stop.NewCmd())
 	cmd.AddCommand(unpause.NewCmd())
 	cmd.AddCommand(up.NewCmd())
+	cmd.AddCommand(watch.NewCmd())
 
 	return cmd
 }
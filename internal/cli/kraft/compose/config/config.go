@@ -0,0 +1,147 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/spf13/cobra"
+
+	"kraftkit.sh/cmdfactory"
+	"kraftkit.sh/compose"
+	"kraftkit.sh/iostreams"
+	"kraftkit.sh/log"
+	"kraftkit.sh/packmanager"
+
+	machineapi "kraftkit.sh/api/machine/v1alpha1"
+	mplatform "kraftkit.sh/machine/platform"
+)
+
+type ConfigOptions struct {
+	Composefile string   `noattribute:"true"`
+	Output      string   `long:"output" short:"o" usage:"Set output format. Options: yaml,json" default:"yaml"`
+	Scale       []string `long:"scale" usage:"Scale a service to the provided number of replicas, in the format service=replicas"`
+}
+
+func NewCmd() *cobra.Command {
+	cmd, err := cmdfactory.New(&ConfigOptions{}, cobra.Command{
+		Short:   "Render the resolved compose project",
+		Use:     "config [FLAGS]",
+		Args:    cobra.NoArgs,
+		Aliases: []string{},
+		Long: heredoc.Doc(`
+			Render the compose project as it is interpreted by kraft.
+
+			The project is printed after the environment has been interpolated, the
+			services have been selected by their profiles and IP addresses have been
+			assigned to them.  The x-kraft extension of the project holds the
+			decisions taken when creating it, such as the package or runtime of each
+			service, the machines of its replicas and their addresses, and the drivers
+			of its networks and volumes.
+		`),
+		Example: heredoc.Doc(`
+			# Render the resolved compose project as YAML
+			$ kraft compose config
+
+			# Render the resolved compose project as JSON
+			$ kraft compose config --output json
+		`),
+		Annotations: map[string]string{
+			cmdfactory.AnnotationHelpGroup: "compose",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+func (opts *ConfigOptions) Pre(cmd *cobra.Command, _ []string) error {
+	ctx, err := packmanager.WithDefaultUmbrellaManagerInContext(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	cmd.SetContext(ctx)
+
+	if cmd.Flag("file").Changed {
+		opts.Composefile = cmd.Flag("file").Value.String()
+	}
+
+	log.G(cmd.Context()).WithField("composefile", opts.Composefile).Debug("using")
+	return nil
+}
+
+func (opts *ConfigOptions) Run(ctx context.Context, _ []string) error {
+	workdir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	project, err := compose.NewProjectFromComposeFile(ctx, workdir, opts.Composefile)
+	if err != nil {
+		return err
+	}
+
+	if err := project.Validate(ctx); err != nil {
+		return err
+	}
+
+	if err := project.ApplyScale(opts.Scale); err != nil {
+		return err
+	}
+
+	// Addresses are assigned as when creating the project, which means that the
+	// addresses of machines outside of the project are not assigned to its
+	// replicas.
+	machines := []machineapi.Machine{}
+
+	if machineController, err := mplatform.NewMachineV1alpha1ServiceIterator(ctx); err != nil {
+		log.G(ctx).Debugf("could not list machines: %v", err)
+	} else if list, err := machineController.List(ctx, &machineapi.MachineList{}); err != nil {
+		log.G(ctx).Debugf("could not list machines: %v", err)
+	} else {
+		machines = list.Items
+	}
+
+	if err := project.AssignIPs(ctx, project.ReservedAddresses(machines)...); err != nil {
+		return err
+	}
+
+	kraftConfig, err := project.KraftConfig(ctx, machines)
+	if err != nil {
+		return err
+	}
+
+	if project.Extensions == nil {
+		project.Extensions = types.Extensions{}
+	}
+
+	project.Extensions[compose.KraftConfigExtension] = kraftConfig
+
+	var out []byte
+
+	switch opts.Output {
+	case "yaml":
+		out, err = project.Project.MarshalYAML()
+	case "json":
+		out, err = json.MarshalIndent(project.Project, "", "  ")
+		out = append(out, '\n')
+	default:
+		return fmt.Errorf("unknown output format: %s", opts.Output)
+	}
+	if err != nil {
+		return fmt.Errorf("could not render project: %w", err)
+	}
+
+	_, err = iostreams.G(ctx).Out.Write(out)
+	return err
+}
@@ -9,6 +9,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/MakeNowJust/heredoc"
@@ -41,8 +42,12 @@ import (
 )
 
 type CreateOptions struct {
-	Composefile   string `noattribute:"true"`
-	RemoveOrphans bool   `long:"remove-orphans" usage:"Remove machines for services not defined in the Compose file"`
+	Build         bool     `long:"build" usage:"Build the services with a build context before creating their machines"`
+	Composefile   string   `noattribute:"true"`
+	ForceRecreate bool     `long:"force-recreate" usage:"Recreate the machines of the services even if they already exist"`
+	NoDeps        bool     `long:"no-deps" usage:"Do not create the services the provided services depend on"`
+	RemoveOrphans bool     `long:"remove-orphans" usage:"Remove machines for services not defined in the Compose file"`
+	Scale         []string `long:"scale" usage:"Scale a service to the provided number of replicas, in the format service=replicas"`
 }
 
 func NewCmd() *cobra.Command {
@@ -54,6 +59,12 @@ func NewCmd() *cobra.Command {
 		Example: heredoc.Doc(`
 			# Create the networks and services without running them
 			$ kraft compose create 
+
+			# Create three replicas of the web service
+			$ kraft compose create --scale web=3
+
+			# Rebuild the web service and recreate only its machines
+			$ kraft compose create --build --force-recreate --no-deps web
 		`),
 		Annotations: map[string]string{
 			cmdfactory.AnnotationHelpGroup: "compose",
@@ -97,7 +108,27 @@ func (opts *CreateOptions) Run(ctx context.Context, args []string) error {
 		return err
 	}
 
-	if err := project.AssignIPs(ctx); err != nil {
+	if err := project.ApplyScale(opts.Scale); err != nil {
+		return err
+	}
+
+	if err := project.WarnUnsupported(ctx); err != nil {
+		return err
+	}
+
+	machineController, err := mplatform.NewMachineV1alpha1ServiceIterator(ctx)
+	if err != nil {
+		return err
+	}
+
+	machines, err := machineController.List(ctx, &machineapi.MachineList{})
+	if err != nil {
+		return err
+	}
+
+	// Addresses of machines outside of the project are not assigned to its
+	// replicas.
+	if err := project.AssignIPs(ctx, project.ReservedAddresses(machines.Items)...); err != nil {
 		return err
 	}
 
@@ -271,15 +302,23 @@ func (opts *CreateOptions) Run(ctx context.Context, args []string) error {
 
 	}
 
-	// Check that none of the services are already running
-	machineController, err := mplatform.NewMachineV1alpha1ServiceIterator(ctx)
-	if err != nil {
-		return err
-	}
+	removeMachine := func(machine machineapi.Machine) error {
+		rmOpts := remove.RemoveOptions{
+			Platform: machine.Spec.Platform,
+		}
 
-	machines, err := machineController.List(ctx, &machineapi.MachineList{})
-	if err != nil {
-		return err
+		if err := rmOpts.Run(ctx, []string{machine.Name}); err != nil {
+			return err
+		}
+
+		for i, m := range projectMachines {
+			if m.Name == machine.Name {
+				projectMachines = append(projectMachines[:i], projectMachines[i+1:]...)
+				break
+			}
+		}
+
+		return nil
 	}
 
 	services, err := project.GetServices(args...)
@@ -287,57 +326,105 @@ func (opts *CreateOptions) Run(ctx context.Context, args []string) error {
 		return err
 	}
 
-	orderedServices := project.ServicesOrderedByDependencies(ctx, services, true)
+	orderedServices := project.ServicesOrderedByDependencies(ctx, services, !opts.NoDeps)
 	for _, service := range orderedServices {
-		log.G(ctx).Debugf("creating service %s...", service.Name)
-		alreadyCreated := false
+		// Scale the service down first, starting with the replica with the
+		// highest index.
+		surplus := []machineapi.Machine{}
 		for _, machine := range machines.Items {
-			if service.ContainerName != machine.Name {
-				continue
-			}
-			if machine.Status.State == machineapi.MachineStateRunning || machine.Status.State == machineapi.MachineStateCreated {
-				alreadyCreated = true
-				break
-			}
-			rmOpts := remove.RemoveOptions{
-				Platform: machine.Spec.Platform,
+			if replica, ok := compose.ReplicaIndex(service, machine.Name); ok && replica > service.GetScale() && project.IsServiceMachine(service, machine.Name) {
+				surplus = append(surplus, machine)
 			}
+		}
 
-			if err := rmOpts.Run(ctx, []string{service.ContainerName}); err != nil {
-				return err
-			}
+		sort.Slice(surplus, func(i, j int) bool {
+			a, _ := compose.ReplicaIndex(service, surplus[i].Name)
+			b, _ := compose.ReplicaIndex(service, surplus[j].Name)
+			return a > b
+		})
 
-			for i, m := range projectMachines {
-				if m.Name == machine.Name {
-					projectMachines = append(projectMachines[:i], projectMachines[i+1:]...)
-					break
-				}
+		for _, machine := range surplus {
+			log.G(ctx).Infof("removing replica %s...", machine.Name)
+
+			if err := removeMachine(machine); err != nil {
+				return err
 			}
-			break
 		}
-		if alreadyCreated {
+
+		if service.GetScale() == 0 {
 			continue
 		}
-		if service.Image == "" {
+
+		packaged := false
+
+		if opts.Build && service.Build != nil {
 			if err := buildService(ctx, service); err != nil {
 				return err
 			}
-		} else if err := ensureServiceIsPackaged(ctx, service); err != nil {
-			return err
-		}
 
-		if err := createService(ctx, project, service); err != nil {
-			log.G(ctx).WithError(err).Errorf("failed to create service %s", service.Name)
+			if service.Image != "" {
+				if err := pkgService(ctx, service); err != nil {
+					return err
+				}
+			}
+
+			packaged = true
 		}
 
-		if machine, err := machineController.Get(ctx, &machineapi.Machine{
-			ObjectMeta: metav1.ObjectMeta{
-				Name: service.ContainerName,
-			},
-		}); err == nil && machine.Status.State == machineapi.MachineStateCreated {
-			projectMachines = append(projectMachines, machine.ObjectMeta)
-		} else if err != nil {
-			return err
+		for replica := 1; replica <= service.GetScale(); replica++ {
+			name := compose.ReplicaName(service, replica)
+
+			log.G(ctx).Debugf("creating replica %s...", name)
+			alreadyCreated := false
+			for _, machine := range machines.Items {
+				if name != machine.Name {
+					continue
+				}
+				// Published ports are bound by the machine of a service with a single
+				// replica but by the proxy otherwise, so scaling the service from or
+				// to a single replica recreates its first replica.
+				proxied := compose.NeedsProxy(service)
+				if !opts.ForceRecreate &&
+					(machine.Status.State == machineapi.MachineStateRunning || machine.Status.State == machineapi.MachineStateCreated) &&
+					(len(service.Ports) == 0 || proxied == (len(machine.Spec.Ports) == 0)) {
+					alreadyCreated = true
+					break
+				}
+
+				if err := removeMachine(machine); err != nil {
+					return err
+				}
+				break
+			}
+			if alreadyCreated {
+				continue
+			}
+
+			if !packaged {
+				if service.Image == "" {
+					if err := buildService(ctx, service); err != nil {
+						return err
+					}
+				} else if err := ensureServiceIsPackaged(ctx, service); err != nil {
+					return err
+				}
+
+				packaged = true
+			}
+
+			if err := createService(ctx, project, service, replica); err != nil {
+				log.G(ctx).WithError(err).Errorf("failed to create service %s", service.Name)
+			}
+
+			if machine, err := machineController.Get(ctx, &machineapi.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: name,
+				},
+			}); err == nil && machine.Status.State == machineapi.MachineStateCreated {
+				projectMachines = append(projectMachines, machine.ObjectMeta)
+			} else if err != nil {
+				return err
+			}
 		}
 	}
 
@@ -459,14 +546,18 @@ func pkgService(ctx context.Context, service types.ServiceConfig) error {
 	return pkgOptions.Run(ctx, []string{service.Build.Context})
 }
 
-func createService(ctx context.Context, project *compose.Project, service types.ServiceConfig) error {
+// createService creates the machine of the replica of the provided service
+// with the provided index.
+func createService(ctx context.Context, project *compose.Project, service types.ServiceConfig, replica int) error {
 	// The service should be packaged at this point
 	plat, arch, err := utils.PlatArchFromService(service)
 	if err != nil {
 		return err
 	}
 
-	log.G(ctx).Infof("creating service %s...", service.Name)
+	name := compose.ReplicaName(service, replica)
+
+	log.G(ctx).Infof("creating service %s...", name)
 
 	networks := []string{}
 	if len(service.DNS) > 2 {
@@ -481,15 +572,15 @@ func createService(ctx context.Context, project *compose.Project, service types.
 	if len(service.DNS) > 1 {
 		dns1 = service.DNS[1]
 	}
-	for name, network := range service.Networks {
+	for network := range service.Networks {
 		arg := uknetdev.NetdevIp{
-			CIDR:     network.Ipv4Address,
+			CIDR:     project.ReplicaAddress(service, network, replica),
 			DNS0:     dns0,
 			DNS1:     dns1,
 			Hostname: service.Hostname,
 			Domain:   service.DomainName,
 		}
-		networks = append(networks, fmt.Sprintf("%s:%s", project.Networks[name].Name, arg.String()))
+		networks = append(networks, fmt.Sprintf("%s:%s", project.Networks[network].Name, arg.String()))
 	}
 
 	volumes := []string{}
@@ -511,34 +602,44 @@ func createService(ctx context.Context, project *compose.Project, service types.
 		environ = append(environ, fmt.Sprintf("%s=%s", k, *v))
 	}
 
+	// The published ports of a service with more than one replica are served by
+	// a proxy on the host instead.
 	ports := []string{}
-	for _, port := range service.Ports {
-		ports = append(ports, fmt.Sprintf("%s:%s:%d/%s", port.HostIP, port.Published, port.Target, port.Protocol))
+	if !compose.NeedsProxy(service) {
+		for _, port := range service.Ports {
+			ports = append(ports, fmt.Sprintf("%s:%s:%d/%s", port.HostIP, port.Published, port.Target, port.Protocol))
+		}
 	}
 
 	memory := ""
-	if service.MemLimit > 0 {
-		memory = fmt.Sprintf("%d", service.MemLimit)
-	} else if service.MemReservation > 0 {
-		memory = fmt.Sprintf("%d", service.MemReservation)
+	if bytes := compose.ServiceMemory(service); bytes > 0 {
+		memory = fmt.Sprintf("%d", bytes)
 	}
 
 	runOptions := run.RunOptions{
+		AddHosts:     compose.ServiceExtraHosts(service),
 		Architecture: arch,
+		CPUs:         compose.ServiceCPUs(service),
 		Detach:       true,
 		Env:          environ,
+		Labels:       compose.ServiceLabels(service),
 		Memory:       memory,
-		Name:         service.ContainerName,
+		Name:         name,
 		Networks:     networks,
 		NoStart:      true,
 		Platform:     plat,
 		Ports:        ports,
+		Tmpfs:        compose.ServiceTmpfs(ctx, service),
 		Volumes:      volumes,
 	}
 
+	// The entrypoint and command of the service replace the default
+	// application arguments of its unikernel.
+	args := compose.ServiceArgs(service)
+
 	if service.Image != "" {
-		return runOptions.Run(ctx, []string{service.Image})
+		return runOptions.Run(ctx, append([]string{service.Image}, args...))
 	}
 
-	return runOptions.Run(ctx, []string{service.Build.Context})
+	return runOptions.Run(ctx, append([]string{service.Build.Context}, args...))
 }
@@ -15,6 +15,7 @@ import (
 	"github.com/spf13/cobra"
 	"kraftkit.sh/cmdfactory"
 	"kraftkit.sh/compose"
+	"kraftkit.sh/internal/cli/kraft/compose/proxy"
 	"kraftkit.sh/internal/cli/kraft/compose/utils"
 	networkremove "kraftkit.sh/internal/cli/kraft/net/remove"
 	machineremove "kraftkit.sh/internal/cli/kraft/remove"
@@ -99,11 +100,15 @@ func (opts *DownOptions) Run(ctx context.Context, args []string) error {
 		return err
 	}
 
+	if err := proxy.Stop(ctx, project.Name); err != nil {
+		return err
+	}
+
 	orderedServices := project.ServicesReversedByDependencies(ctx, project.Services, false)
 	for _, service := range orderedServices {
 		for _, machine := range machines.Items {
-			if service.ContainerName == machine.Name {
-				if err := removeService(ctx, service); err != nil {
+			if project.IsServiceMachine(service, machine.Name) {
+				if err := removeMachine(ctx, machine.Name); err != nil {
 					return err
 				}
 			}
@@ -136,11 +141,11 @@ func (opts *DownOptions) Run(ctx context.Context, args []string) error {
 	return nil
 }
 
-func removeService(ctx context.Context, service types.ServiceConfig) error {
-	log.G(ctx).Infof("removing service %s...", service.Name)
+func removeMachine(ctx context.Context, name string) error {
+	log.G(ctx).Infof("removing service %s...", name)
 	removeOptions := machineremove.RemoveOptions{Platform: "auto"}
 
-	return removeOptions.Run(ctx, []string{service.ContainerName})
+	return removeOptions.Run(ctx, []string{name})
 }
 
 func removeNetwork(ctx context.Context, network types.NetworkConfig) error {
@@ -9,8 +9,6 @@ import (
 	"context"
 	"os"
 
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-
 	machineapi "kraftkit.sh/api/machine/v1alpha1"
 
 	"github.com/spf13/cobra"
@@ -88,18 +86,20 @@ func (opts *LogsOptions) Run(ctx context.Context, args []string) error {
 		return err
 	}
 
+	machines, err := controller.List(ctx, &machineapi.MachineList{})
+	if err != nil {
+		return err
+	}
+
 	machinesToLog := []string{}
 	for _, service := range services {
 		if len(args) == 0 && service.Attach != nil && !*service.Attach {
 			continue
 		}
-		machine, _ := controller.Get(ctx, &machineapi.Machine{
-			ObjectMeta: metav1.ObjectMeta{
-				Name: service.ContainerName,
-			},
-		})
-		if machine != nil {
-			machinesToLog = append(machinesToLog, machine.Name)
+		for _, machine := range machines.Items {
+			if project.IsServiceMachine(service, machine.Name) {
+				machinesToLog = append(machinesToLog, machine.Name)
+			}
 		}
 	}
 
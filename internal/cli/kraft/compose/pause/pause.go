@@ -95,7 +95,7 @@ func (opts *PauseOptions) Run(ctx context.Context, args []string) error {
 	machinesToPause := []string{}
 	for _, service := range orderedServices {
 		for _, machine := range machines.Items {
-			if service.ContainerName == machine.Name && machine.Status.State == machineapi.MachineStateRunning {
+			if project.IsServiceMachine(service, machine.Name) && machine.Status.State == machineapi.MachineStateRunning {
 				machinesToPause = append(machinesToPause, machine.Name)
 			}
 		}
@@ -0,0 +1,257 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/MakeNowJust/heredoc"
+	goprocess "github.com/shirou/gopsutil/v3/process"
+	"github.com/spf13/cobra"
+
+	"kraftkit.sh/cmdfactory"
+	"kraftkit.sh/compose"
+	"kraftkit.sh/config"
+	"kraftkit.sh/exec"
+	"kraftkit.sh/log"
+	"kraftkit.sh/packmanager"
+)
+
+type ProxyOptions struct {
+	Composefile string   `noattribute:"true"`
+	Scale       []string `long:"scale" usage:"Scale a service to the provided number of replicas, in the format service=replicas"`
+}
+
+func NewCmd() *cobra.Command {
+	cmd, err := cmdfactory.New(&ProxyOptions{}, cobra.Command{
+		Short:   "Proxy the published ports of scaled services",
+		Use:     "proxy [FLAGS]",
+		Args:    cobra.NoArgs,
+		Aliases: []string{},
+		Long: heredoc.Doc(`
+			Proxy the published ports of the services of a compose project which have
+			more than one replica.
+
+			Each connection to a published port is forwarded to the next running
+			replica of the service in turn.  The proxy runs in the foreground and is
+			started in the background by 'kraft compose up --detach'.
+		`),
+		Example: heredoc.Doc(`
+			# Proxy the published ports of the scaled services of a compose project
+			$ kraft compose proxy
+		`),
+		Annotations: map[string]string{
+			cmdfactory.AnnotationHelpGroup:  "compose",
+			cmdfactory.AnnotationHelpHidden: "true",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+func (opts *ProxyOptions) Pre(cmd *cobra.Command, _ []string) error {
+	ctx, err := packmanager.WithDefaultUmbrellaManagerInContext(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	cmd.SetContext(ctx)
+
+	if cmd.Flag("file").Changed {
+		opts.Composefile = cmd.Flag("file").Value.String()
+	}
+
+	log.G(cmd.Context()).WithField("composefile", opts.Composefile).Debug("using")
+	return nil
+}
+
+func (opts *ProxyOptions) Run(ctx context.Context, _ []string) error {
+	workdir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	project, err := compose.NewProjectFromComposeFile(ctx, workdir, opts.Composefile)
+	if err != nil {
+		return err
+	}
+
+	if err := project.Validate(ctx); err != nil {
+		return err
+	}
+
+	if err := project.ApplyScale(opts.Scale); err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	return project.ServeReplicaProxies(ctx)
+}
+
+// pidFile returns the path of the file holding the process ID of the proxy of
+// the provided project which runs in the background.
+func pidFile(ctx context.Context, project string) string {
+	return filepath.Join(config.G[config.KraftKit](ctx).RuntimeDir, "composeproxy", project+".pid")
+}
+
+// Start starts the proxy of the provided project in the background, replacing
+// the one which may already be running, if any of its services needs one.
+func Start(ctx context.Context, project *compose.Project, composefile string, scale []string) error {
+	if err := Stop(ctx, project.Name); err != nil {
+		return err
+	}
+
+	needed := false
+	for _, service := range project.Services {
+		if compose.NeedsProxy(service) {
+			needed = true
+			break
+		}
+	}
+
+	if !needed {
+		return nil
+	}
+
+	bin, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("could not determine executable: %w", err)
+	}
+
+	args := []string{"compose"}
+	if len(composefile) > 0 {
+		args = append(args, "--file", composefile)
+	}
+
+	args = append(args, "proxy")
+	for _, entry := range scale {
+		args = append(args, "--scale", entry)
+	}
+
+	path := pidFile(ctx, project.Name)
+	if err := os.MkdirAll(filepath.Dir(path), fs.ModeSetgid|0o775); err != nil {
+		return fmt.Errorf("could not make proxy directory: %w", err)
+	}
+
+	logFile, err := os.OpenFile(strings.TrimSuffix(path, ".pid")+".log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("could not open proxy log file: %w", err)
+	}
+
+	defer logFile.Close()
+
+	process, err := exec.NewProcess(bin, args,
+		exec.WithDetach(true),
+		exec.WithStdout(logFile),
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := process.Start(ctx); err != nil {
+		return fmt.Errorf("could not start proxy: %w", err)
+	}
+
+	pid, err := process.Pid()
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, []byte(strconv.Itoa(pid)), 0o644); err != nil {
+		return fmt.Errorf("could not save proxy process ID: %w", err)
+	}
+
+	log.G(ctx).WithField("pid", pid).Debug("started proxy")
+
+	return process.Release()
+}
+
+// Stop stops the proxy of the provided project which runs in the background,
+// if any.
+func Stop(ctx context.Context, project string) error {
+	path := pidFile(ctx, project)
+
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("could not read proxy process ID: %w", err)
+	}
+
+	defer os.Remove(path)
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(raw)))
+	if err != nil {
+		return fmt.Errorf("could not parse proxy process ID: %w", err)
+	}
+
+	// The process ID may have been reused by an unrelated process since the
+	// proxy has quit.
+	if !isProxy(ctx, pid) {
+		log.G(ctx).WithField("pid", pid).Debug("proxy is not running")
+		return nil
+	}
+
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return nil
+	}
+
+	if err := process.Signal(syscall.SIGTERM); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		log.G(ctx).Debugf("could not stop proxy: %v", err)
+	}
+
+	return nil
+}
+
+// isProxy returns whether the process with the provided ID runs the `compose
+// proxy` subcommand.
+func isProxy(ctx context.Context, pid int) bool {
+	process, err := goprocess.NewProcessWithContext(ctx, int32(pid))
+	if err != nil {
+		return false
+	}
+
+	args, err := process.CmdlineSliceWithContext(ctx)
+	if err != nil {
+		return false
+	}
+
+	return isProxyCmdline(args)
+}
+
+// isProxyCmdline returns whether the provided command line, including the
+// executable, invokes the `compose proxy` subcommand.
+func isProxyCmdline(args []string) bool {
+	if len(args) < 2 {
+		return false
+	}
+
+	subcommand := false
+	for _, arg := range args[1:] {
+		switch {
+		case arg == "compose":
+			subcommand = true
+		case arg == "proxy" && subcommand:
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,45 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package proxy
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestIsProxyCmdline(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected bool
+	}{
+		{[]string{"/usr/bin/kraft", "compose", "proxy"}, true},
+		{[]string{"/usr/bin/kraft", "compose", "--file", "compose.yaml", "proxy", "--scale", "web=2"}, true},
+		{[]string{"/usr/bin/kraft", "compose", "up"}, false},
+		{[]string{"/usr/bin/kraft", "proxy", "compose"}, false},
+		{[]string{"/usr/sbin/nginx", "-g", "daemon off;"}, false},
+		{[]string{"proxy"}, false},
+		{nil, false},
+	}
+
+	for _, test := range tests {
+		if got := isProxyCmdline(test.args); got != test.expected {
+			t.Errorf("isProxyCmdline(%q) = %t, expected %t", test.args, got, test.expected)
+		}
+	}
+}
+
+func TestIsProxyUnrelatedProcess(t *testing.T) {
+	ctx := context.Background()
+
+	// A running process which does not run the proxy, i.e. the test itself.
+	if isProxy(ctx, os.Getpid()) {
+		t.Error("expected the test process not to be identified as a proxy")
+	}
+
+	if isProxy(ctx, 1<<22+1) {
+		t.Error("expected a process which does not exist not to be identified as a proxy")
+	}
+}
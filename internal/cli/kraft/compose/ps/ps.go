@@ -8,6 +8,7 @@ package ps
 import (
 	"context"
 	"os"
+	"sort"
 
 	"github.com/MakeNowJust/heredoc"
 	"github.com/spf13/cobra"
@@ -110,25 +111,34 @@ func (opts *PsOptions) Run(ctx context.Context, args []string) error {
 	}
 
 	filteredPsTable := []pslist.PsEntry{}
+	replicas := map[string]int{}
 	for _, psEntry := range psTable {
 		for _, machine := range embeddedProject.Status.Machines {
-			orphaned := true
-			for _, service := range project.Services {
-				if service.ContainerName == machine.Name {
-					orphaned = false
-					break
-				}
+			if psEntry.Name != machine.Name {
+				continue
 			}
 
-			if orphaned && !opts.Orphans {
+			service, replica, ok := project.ServiceOfMachine(machine.Name)
+			if !ok && !opts.Orphans {
 				continue
 			}
 
-			if psEntry.Name == machine.Name {
-				filteredPsTable = append(filteredPsTable, psEntry)
-			}
+			psEntry.Service = service.Name
+			replicas[psEntry.Name] = replica
+			filteredPsTable = append(filteredPsTable, psEntry)
 		}
 	}
 
+	// Group the replicas of each service in the order of their index, followed
+	// by the orphaned machines.
+	sort.SliceStable(filteredPsTable, func(i, j int) bool {
+		a, b := filteredPsTable[i], filteredPsTable[j]
+		if a.Service != b.Service {
+			return len(b.Service) == 0 || (len(a.Service) > 0 && a.Service < b.Service)
+		}
+
+		return replicas[a.Name] < replicas[b.Name]
+	})
+
 	return pslistOptions.PrintPsTable(ctx, filteredPsTable)
 }
@@ -9,6 +9,7 @@ import (
 	"os"
 
 	"github.com/MakeNowJust/heredoc"
+	"github.com/compose-spec/compose-go/v2/types"
 	"github.com/spf13/cobra"
 
 	"kraftkit.sh/cmdfactory"
@@ -93,9 +94,53 @@ func (opts *StartOptions) Run(ctx context.Context, args []string) error {
 
 	orderedServices := project.ServicesOrderedByDependencies(ctx, services, true)
 	machinesToStart := []string{}
+
+	// Machines are started in batches such that the services which depend on
+	// another service being healthy are only started once it is.
+	startMachines := func() error {
+		if len(machinesToStart) == 0 {
+			return nil
+		}
+
+		kernelStartOptions := kernelstart.StartOptions{
+			Detach:   true,
+			Platform: "auto",
+		}
+
+		err := kernelStartOptions.Run(ctx, machinesToStart)
+		machinesToStart = []string{}
+
+		return err
+	}
+
+	healthy := map[string]bool{}
+
 	for _, service := range orderedServices {
+		for name, dependency := range service.DependsOn {
+			if dependency.Condition != types.ServiceConditionHealthy || healthy[name] {
+				continue
+			}
+
+			if !compose.HasHealthCheck(project.Services[name]) {
+				log.G(ctx).Warnf("service %s depends on service %s being healthy but it has no healthcheck", service.Name, name)
+				continue
+			}
+
+			if err := startMachines(); err != nil {
+				return err
+			}
+
+			log.G(ctx).Infof("waiting for service %s to be healthy...", name)
+
+			if err := compose.WaitHealthy(ctx, project.Services[name]); err != nil {
+				return err
+			}
+
+			healthy[name] = true
+		}
+
 		for _, machine := range machines.Items {
-			if service.ContainerName == machine.Name {
+			if project.IsServiceMachine(service, machine.Name) {
 				if machine.Status.State == machineapi.MachineStateCreated || machine.Status.State == machineapi.MachineStateExited {
 					machinesToStart = append(machinesToStart, machine.Name)
 				}
@@ -103,12 +148,7 @@ func (opts *StartOptions) Run(ctx context.Context, args []string) error {
 		}
 	}
 
-	kernelStartOptions := kernelstart.StartOptions{
-		Detach:   true,
-		Platform: "auto",
-	}
-
-	if err := kernelStartOptions.Run(ctx, machinesToStart); err != nil {
+	if err := startMachines(); err != nil {
 		return err
 	}
 
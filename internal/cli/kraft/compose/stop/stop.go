@@ -13,6 +13,7 @@ import (
 
 	"kraftkit.sh/cmdfactory"
 	"kraftkit.sh/compose"
+	"kraftkit.sh/internal/cli/kraft/compose/proxy"
 	"kraftkit.sh/log"
 	"kraftkit.sh/packmanager"
 
@@ -91,11 +92,17 @@ func (opts *StopOptions) Run(ctx context.Context, args []string) error {
 		return err
 	}
 
+	if len(args) == 0 {
+		if err := proxy.Stop(ctx, project.Name); err != nil {
+			return err
+		}
+	}
+
 	orderedServices := project.ServicesReversedByDependencies(ctx, services, false)
 	machinesToStop := []string{}
 	for _, service := range orderedServices {
 		for _, machine := range machines.Items {
-			if service.ContainerName == machine.Name &&
+			if project.IsServiceMachine(service, machine.Name) &&
 				(machine.Status.State == machineapi.MachineStateRunning ||
 					machine.Status.State == machineapi.MachineStatePaused) {
 				machinesToStop = append(machinesToStop, machine.Name)
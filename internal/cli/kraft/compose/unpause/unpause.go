@@ -95,7 +95,7 @@ func (opts *UnpauseOptions) Run(ctx context.Context, args []string) error {
 	machinesToUnpause := []string{}
 	for _, service := range orderedServices {
 		for _, machine := range machines.Items {
-			if service.ContainerName == machine.Name {
+			if project.IsServiceMachine(service, machine.Name) {
 				if machine.Status.State == machineapi.MachineStatePaused {
 					machinesToUnpause = append(machinesToUnpause, machine.Name)
 				}
@@ -0,0 +1,172 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package up
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"kraftkit.sh/compose"
+	"kraftkit.sh/config"
+	"kraftkit.sh/internal/cli/kraft/logs"
+	"kraftkit.sh/iostreams"
+	"kraftkit.sh/log"
+
+	machineapi "kraftkit.sh/api/machine/v1alpha1"
+	mplatform "kraftkit.sh/machine/platform"
+)
+
+// logFile tracks how much of the log file of a machine has been consumed, such
+// that its output is only consumed once across restarts of the machine.
+type logFile struct {
+	path   string
+	offset int64
+}
+
+// consume passes the complete lines which have been appended to the log file
+// since the last call to the provided function.  An incomplete last line is
+// only passed once the machine has exited and flush is set.  The log file is
+// read again from its start if it has been truncated.
+func (f *logFile) consume(flush bool, fn func(...string)) error {
+	fd, err := os.Open(f.path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	defer fd.Close()
+
+	fi, err := fd.Stat()
+	if err != nil {
+		return err
+	}
+
+	if fi.Size() < f.offset {
+		f.offset = 0
+	}
+
+	if _, err := fd.Seek(f.offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	b, err := io.ReadAll(fd)
+	if err != nil {
+		return err
+	}
+
+	end := bytes.LastIndexByte(b, '\n') + 1
+	if flush {
+		end = len(b)
+	}
+
+	if end == 0 {
+		return nil
+	}
+
+	f.offset += int64(end)
+
+	fn(strings.Split(strings.TrimSuffix(string(b[:end]), "\n"), "\n")...)
+
+	return nil
+}
+
+// followLogs prints the logs of the attached machines of the project as they
+// are written, until none of the machines of the project runs nor can be
+// restarted by the restart policy of its service, or until the context is
+// cancelled.
+func followLogs(ctx context.Context, project *compose.Project, restarts *restartCounter) error {
+	policies, err := restartPolicies(project)
+	if err != nil {
+		return err
+	}
+
+	controller, err := mplatform.NewMachineV1alpha1ServiceIterator(ctx)
+	if err != nil {
+		return err
+	}
+
+	files := map[string]*logFile{}
+	consumers := map[string]logs.LogConsumer{}
+	longestName := 0
+
+	ticker := time.NewTicker(supervisePollInterval)
+	defer ticker.Stop()
+
+	for {
+		// The machines are assumed to still be active if they cannot be listed.
+		machines, err := controller.List(ctx, &machineapi.MachineList{})
+		if err != nil {
+			log.G(ctx).Debugf("could not list machines: %v", err)
+			machines = &machineapi.MachineList{}
+		}
+
+		active := err != nil
+
+		for _, machine := range machines.Items {
+			service, _, ok := project.ServiceOfMachine(machine.Name)
+			if !ok {
+				continue
+			}
+
+			exited := false
+			switch machine.Status.State {
+			case machineapi.MachineStateExited,
+				machineapi.MachineStateFailed,
+				machineapi.MachineStateErrored:
+				policy, ok := policies[service.Name]
+				exited = !ok || !policy.ShouldRestart(machine.Status.ExitCode, restarts.get(machine.Name))
+			}
+
+			if !exited {
+				active = true
+			}
+
+			if service.Attach != nil && !*service.Attach {
+				continue
+			}
+
+			if len(machine.Name) > longestName {
+				longestName = len(machine.Name)
+			}
+
+			consumer, ok := consumers[machine.Name]
+			if !ok {
+				prefix := machine.Name + strings.Repeat(" ", longestName-len(machine.Name))
+
+				consumer, err = logs.NewColorfulConsumer(iostreams.G(ctx), !config.G[config.KraftKit](ctx).NoColor, prefix)
+				if err != nil {
+					return err
+				}
+
+				consumers[machine.Name] = consumer
+			}
+
+			file, ok := files[machine.Name]
+			if !ok || file.path != machine.Status.LogFile {
+				file = &logFile{path: machine.Status.LogFile}
+				files[machine.Name] = file
+			}
+
+			if err := file.consume(exited, consumer.Consume); err != nil {
+				log.G(ctx).Debugf("could not read logs of %s: %v", machine.Name, err)
+			}
+		}
+
+		if !active {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
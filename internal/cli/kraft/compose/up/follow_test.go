@@ -0,0 +1,102 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package up
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLogFileConsume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "machine.log")
+	file := &logFile{path: path}
+
+	var lines []string
+	consume := func(flush bool) []string {
+		t.Helper()
+
+		lines = nil
+		if err := file.consume(flush, func(l ...string) {
+			lines = append(lines, l...)
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		return lines
+	}
+
+	write := func(content string, flag int) {
+		t.Helper()
+
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|flag, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defer f.Close()
+
+		if _, err := f.WriteString(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, step := range []struct {
+		name     string
+		content  string
+		flag     int
+		flush    bool
+		expected []string
+	}{
+		{
+			name:     "missing file",
+			expected: nil,
+		},
+		{
+			name:     "complete and incomplete lines",
+			content:  "booting\nlistening on :80\nhello",
+			flag:     os.O_APPEND,
+			expected: []string{"booting", "listening on :80"},
+		},
+		{
+			name:     "nothing appended",
+			expected: nil,
+		},
+		{
+			name:     "completed line",
+			content:  " world\n",
+			flag:     os.O_APPEND,
+			expected: []string{"hello world"},
+		},
+		{
+			name:     "flushed incomplete line",
+			content:  "exiting",
+			flag:     os.O_APPEND,
+			flush:    true,
+			expected: []string{"exiting"},
+		},
+		{
+			name:     "appended after restart",
+			content:  "booting\n",
+			flag:     os.O_APPEND,
+			expected: []string{"booting"},
+		},
+		{
+			name:     "truncated after restart",
+			content:  "rebooting\n",
+			flag:     os.O_TRUNC,
+			expected: []string{"rebooting"},
+		},
+	} {
+		if step.content != "" {
+			write(step.content, step.flag)
+		}
+
+		if actual := consume(step.flush); !reflect.DeepEqual(actual, step.expected) {
+			t.Errorf("%s: expected %q, got %q", step.name, step.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package up
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/compose-spec/compose-go/v2/types"
+
+	"kraftkit.sh/compose"
+	"kraftkit.sh/log"
+
+	machineapi "kraftkit.sh/api/machine/v1alpha1"
+	mplatform "kraftkit.sh/machine/platform"
+)
+
+// supervisePollInterval is the interval at which the state of the machines of
+// a project is checked by supervise.
+const supervisePollInterval = time.Second
+
+// restartCounter counts the restarts of each machine of a project, which
+// supervise performs and followLogs awaits.
+type restartCounter struct {
+	mu     sync.Mutex
+	counts map[string]uint64
+}
+
+// newRestartCounter returns a restartCounter of machines which have not been
+// restarted yet.
+func newRestartCounter() *restartCounter {
+	return &restartCounter{counts: map[string]uint64{}}
+}
+
+// get returns the number of times the machine has been restarted.
+func (c *restartCounter) get(machine string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.counts[machine]
+}
+
+// inc records a restart of the machine.
+func (c *restartCounter) inc(machine string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.counts[machine]++
+}
+
+// restartPolicies returns the restart policies of the services of the project
+// which have one, by service name.
+func restartPolicies(project *compose.Project) (map[string]compose.RestartPolicy, error) {
+	policies := map[string]compose.RestartPolicy{}
+
+	for _, service := range project.Services {
+		policy, err := compose.ServiceRestartPolicy(service)
+		if err != nil {
+			return nil, err
+		}
+
+		if policy.Condition != types.RestartPolicyNo {
+			policies[service.Name] = policy
+		}
+	}
+
+	return policies, nil
+}
+
+// supervise restarts the machines of the services of the project which have
+// exited according to their restart policy, and reports changes to the health
+// of the services which have a healthcheck, until the context is cancelled.
+func supervise(ctx context.Context, project *compose.Project, restarts *restartCounter) error {
+	policies, err := restartPolicies(project)
+	if err != nil {
+		return err
+	}
+
+	for _, service := range project.Services {
+		if compose.HasHealthCheck(service) {
+			go monitorHealth(ctx, service)
+		}
+	}
+
+	if len(policies) == 0 {
+		return nil
+	}
+
+	machineController, err := mplatform.NewMachineV1alpha1ServiceIterator(ctx)
+	if err != nil {
+		return err
+	}
+
+	exitedAt := map[string]time.Time{}
+
+	ticker := time.NewTicker(supervisePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		machines, err := machineController.List(ctx, &machineapi.MachineList{})
+		if err != nil {
+			log.G(ctx).Debugf("could not list machines: %v", err)
+			continue
+		}
+
+		for _, machine := range machines.Items {
+			service, _, ok := project.ServiceOfMachine(machine.Name)
+			if !ok {
+				continue
+			}
+
+			policy, ok := policies[service.Name]
+			if !ok {
+				continue
+			}
+
+			switch machine.Status.State {
+			case machineapi.MachineStateExited,
+				machineapi.MachineStateFailed,
+				machineapi.MachineStateErrored:
+			default:
+				delete(exitedAt, machine.Name)
+				continue
+			}
+
+			if !policy.ShouldRestart(machine.Status.ExitCode, restarts.get(machine.Name)) {
+				continue
+			}
+
+			if _, ok := exitedAt[machine.Name]; !ok {
+				exitedAt[machine.Name] = time.Now()
+			}
+
+			if time.Since(exitedAt[machine.Name]) < policy.Delay {
+				continue
+			}
+
+			log.G(ctx).
+				WithField("exitcode", machine.Status.ExitCode).
+				Infof("restarting %s...", machine.Name)
+
+			restarts.inc(machine.Name)
+			delete(exitedAt, machine.Name)
+
+			if _, err := machineController.Start(ctx, &machine); err != nil {
+				log.G(ctx).Errorf("could not restart %s: %v", machine.Name, err)
+			}
+		}
+	}
+}
+
+// monitorHealth periodically runs the healthcheck of the provided service and
+// reports when its health changes, until the context is cancelled.
+func monitorHealth(ctx context.Context, service types.ServiceConfig) {
+	interval := compose.DefaultHealthCheckInterval
+	if service.HealthCheck.Interval != nil {
+		interval = time.Duration(*service.HealthCheck.Interval)
+	}
+
+	healthy := true
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		err := compose.CheckHealth(ctx, service)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil && healthy {
+			log.G(ctx).Warnf("service %s is unhealthy: %v", service.Name, err)
+		} else if err == nil && !healthy {
+			log.G(ctx).Infof("service %s is healthy", service.Name)
+		}
+
+		healthy = err == nil
+	}
+}
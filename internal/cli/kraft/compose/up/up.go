@@ -7,13 +7,15 @@ package up
 
 import (
 	"context"
+	"os"
 
 	"github.com/MakeNowJust/heredoc"
 	"github.com/spf13/cobra"
 
 	"kraftkit.sh/cmdfactory"
+	"kraftkit.sh/compose"
 	"kraftkit.sh/internal/cli/kraft/compose/create"
-	"kraftkit.sh/internal/cli/kraft/compose/logs"
+	"kraftkit.sh/internal/cli/kraft/compose/proxy"
 	"kraftkit.sh/internal/cli/kraft/compose/start"
 	"kraftkit.sh/internal/cli/kraft/compose/stop"
 	"kraftkit.sh/log"
@@ -21,8 +23,9 @@ import (
 )
 
 type UpOptions struct {
-	Detach        bool `long:"detach" short:"d" usage:"Run in background"`
-	RemoveOrphans bool `long:"remove-orphans" usage:"Remove machines for services not defined in the Compose file."`
+	Detach        bool     `long:"detach" short:"d" usage:"Run in background"`
+	RemoveOrphans bool     `long:"remove-orphans" usage:"Remove machines for services not defined in the Compose file."`
+	Scale         []string `long:"scale" usage:"Scale a service to the provided number of replicas, in the format service=replicas"`
 
 	composefile string
 }
@@ -36,6 +39,10 @@ func NewCmd() *cobra.Command {
 		Example: heredoc.Doc(`
 			# Run a compose project
 			$ kraft compose up
+
+			# Run a compose project with three replicas of the web service, whose
+			# published ports are proxied to each replica in turn
+			$ kraft compose up --scale web=3
 		`),
 		Annotations: map[string]string{
 			cmdfactory.AnnotationHelpGroup: "compose",
@@ -68,6 +75,7 @@ func (opts *UpOptions) Run(ctx context.Context, _ []string) error {
 	createOptions := create.CreateOptions{
 		Composefile:   opts.composefile,
 		RemoveOrphans: opts.RemoveOrphans,
+		Scale:         opts.Scale,
 	}
 
 	if err := createOptions.Run(ctx, []string{}); err != nil {
@@ -82,20 +90,66 @@ func (opts *UpOptions) Run(ctx context.Context, _ []string) error {
 		return err
 	}
 
+	workdir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	project, err := compose.NewProjectFromComposeFile(ctx, workdir, opts.composefile)
+	if err != nil {
+		return err
+	}
+
+	if err := project.Validate(ctx); err != nil {
+		return err
+	}
+
+	if err := project.ApplyScale(opts.Scale); err != nil {
+		return err
+	}
+
 	if opts.Detach {
-		return nil
+		policies, err := restartPolicies(project)
+		if err != nil {
+			return err
+		}
+
+		if len(policies) > 0 {
+			log.G(ctx).Warn("restart policies are only enforced when not detached")
+		}
+
+		return proxy.Start(ctx, project, opts.composefile, opts.Scale)
 	}
 
-	logsOptions := logs.LogsOptions{
-		Composefile: opts.composefile,
-		Follow:      true,
+	if err := proxy.Stop(ctx, project.Name); err != nil {
+		return err
 	}
 
-	if err := logsOptions.Run(ctx, []string{}); err != nil {
+	superviseCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	restarts := newRestartCounter()
+
+	go func() {
+		if err := supervise(superviseCtx, project, restarts); err != nil {
+			log.G(ctx).Errorf("could not supervise project: %v", err)
+		}
+	}()
+
+	go func() {
+		if err := project.ServeReplicaProxies(superviseCtx); err != nil {
+			log.G(ctx).Errorf("could not proxy published ports: %v", err)
+		}
+	}()
+
+	if err := followLogs(ctx, project, restarts); err != nil {
 		return err
 	}
 
-	// If we get here it means the context was cancelled, stop the machines
+	cancel()
+
+	// If we get here it means the context was cancelled or that no machine runs
+	// anymore, stop the machines
 	log.G(ctx).Infof("stopping machines...")
 	stopOptions := stop.StopOptions{
 		Composefile: opts.composefile,
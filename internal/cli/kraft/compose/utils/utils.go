@@ -48,13 +48,7 @@ func RemoveOrphans(ctx context.Context, project *compose.Project) error {
 
 	orphanMachines := []string{}
 	for _, machine := range embeddedProject.Status.Machines {
-		isService := false
-		for _, service := range project.Services {
-			if service.Name == machine.Name {
-				isService = true
-				break
-			}
-		}
+		_, _, isService := project.ServiceOfMachine(machine.Name)
 
 		for _, m := range machines.Items {
 			if m.Name == machine.Name {
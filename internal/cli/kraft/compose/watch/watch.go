@@ -0,0 +1,369 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package watch
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cobra"
+
+	"kraftkit.sh/cmdfactory"
+	"kraftkit.sh/compose"
+	"kraftkit.sh/internal/cli/kraft/compose/create"
+	"kraftkit.sh/internal/cli/kraft/compose/start"
+	"kraftkit.sh/internal/cli/kraft/compose/stop"
+	"kraftkit.sh/log"
+	"kraftkit.sh/packmanager"
+)
+
+// watchDebounce is the time to wait for further changes after a change before
+// the affected services are rebuilt, such that saving many files at once
+// results in a single rebuild.
+const watchDebounce = 500 * time.Millisecond
+
+type WatchOptions struct {
+	Composefile string `noattribute:"true"`
+	NoUp        bool   `long:"no-up" usage:"Do not create and start the services before watching them"`
+}
+
+func NewCmd() *cobra.Command {
+	cmd, err := cmdfactory.New(&WatchOptions{}, cobra.Command{
+		Short:   "Rebuild and restart services when their files change",
+		Use:     "watch [FLAGS] [SERVICE...]",
+		Aliases: []string{},
+		Long: heredoc.Doc(`
+			Watch the files of the services of a compose project and update their
+			machines when the files change.
+
+			A service with a build context is rebuilt and its machines are recreated
+			whenever anything in its build context changes, including its Kraftfile,
+			its sources and its rootfs directory.  The networks and volumes of the
+			project are kept.
+
+			The develop.watch rules of a service replace this behaviour.  A 'rebuild'
+			rule rebuilds the service and recreates its machines.  A 'sync' rule
+			copies the changed files to the host directory which is mounted at the
+			target of the rule, and a 'sync+restart' rule additionally restarts the
+			machines of the service.  Since the root filesystem of a unikernel is
+			built into it, a service is rebuilt if the target of a 'sync' rule is not
+			in a bind mount.
+		`),
+		Example: heredoc.Doc(`
+			# Create and start a compose project and update it on changes
+			$ kraft compose watch
+
+			# Only watch the web service of an already running project
+			$ kraft compose watch --no-up web
+		`),
+		Annotations: map[string]string{
+			cmdfactory.AnnotationHelpGroup: "compose",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+func (opts *WatchOptions) Pre(cmd *cobra.Command, _ []string) error {
+	ctx, err := packmanager.WithDefaultUmbrellaManagerInContext(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	cmd.SetContext(ctx)
+
+	if cmd.Flag("file").Changed {
+		opts.Composefile = cmd.Flag("file").Value.String()
+	}
+
+	log.G(cmd.Context()).WithField("composefile", opts.Composefile).Debug("using")
+	return nil
+}
+
+// change is a path which has changed and the rule it triggers.
+type change struct {
+	trigger compose.WatchTrigger
+	path    string
+}
+
+func (opts *WatchOptions) Run(ctx context.Context, args []string) error {
+	workdir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	project, err := compose.NewProjectFromComposeFile(ctx, workdir, opts.Composefile)
+	if err != nil {
+		return err
+	}
+
+	if err := project.Validate(ctx); err != nil {
+		return err
+	}
+
+	services, err := project.GetServices(args...)
+	if err != nil {
+		return err
+	}
+
+	orderedServices := project.ServicesOrderedByDependencies(ctx, services, false)
+	triggers := []compose.WatchTrigger{}
+
+	for _, service := range orderedServices {
+		for _, trigger := range compose.ServiceWatchTriggers(service) {
+			switch trigger.Action {
+			case types.WatchActionRebuild:
+				if service.Build == nil {
+					return fmt.Errorf("service %s cannot be rebuilt without a build context", service.Name)
+				}
+			case types.WatchActionSync, types.WatchActionSyncRestart:
+			default:
+				return fmt.Errorf("service %s has an unsupported watch action: %s", service.Name, trigger.Action)
+			}
+
+			triggers = append(triggers, trigger)
+		}
+	}
+
+	if len(triggers) == 0 {
+		return fmt.Errorf("none of the services have a build context or develop.watch rules")
+	}
+
+	if !opts.NoUp {
+		createOptions := create.CreateOptions{
+			Composefile: opts.Composefile,
+		}
+
+		if err := createOptions.Run(ctx, args); err != nil {
+			return err
+		}
+
+		startOptions := start.StartOptions{
+			Composefile: opts.Composefile,
+		}
+
+		if err := startOptions.Run(ctx, args); err != nil {
+			return err
+		}
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("setting up file watcher: %w", err)
+	}
+
+	defer watcher.Close()
+
+	for _, trigger := range triggers {
+		if err := watchTrigger(watcher, trigger); err != nil {
+			return err
+		}
+
+		log.G(ctx).
+			WithField("service", trigger.Service).
+			WithField("action", trigger.Action).
+			Infof("watching %s", trigger.Path)
+	}
+
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	changes := map[string][]change{}
+
+	debounce := time.NewTimer(watchDebounce)
+	debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+
+			log.G(ctx).Warnf("watching files: %v", err)
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+
+			for _, trigger := range triggers {
+				if !trigger.Matches(event.Name) {
+					continue
+				}
+
+				// Directories are not watched recursively, so each new directory is
+				// watched in turn.
+				if event.Has(fsnotify.Create) {
+					if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+						if err := watchDir(watcher, trigger, event.Name); err != nil {
+							log.G(ctx).Warnf("could not watch %s: %v", event.Name, err)
+						}
+					}
+				}
+
+				changes[trigger.Service] = append(changes[trigger.Service], change{
+					trigger: trigger,
+					path:    event.Name,
+				})
+			}
+
+			if len(changes) > 0 {
+				debounce.Reset(watchDebounce)
+			}
+
+		case <-debounce.C:
+			for _, service := range orderedServices {
+				if _, ok := changes[service.Name]; !ok {
+					continue
+				}
+
+				if err := opts.update(ctx, service, changes[service.Name]); err != nil {
+					log.G(ctx).Errorf("could not update service %s: %v", service.Name, err)
+				}
+			}
+
+			changes = map[string][]change{}
+		}
+	}
+}
+
+// update applies the rules triggered by the provided changes to the provided
+// service.
+func (opts *WatchOptions) update(ctx context.Context, service types.ServiceConfig, changes []change) error {
+	rebuild := false
+	restart := false
+
+	for _, change := range changes {
+		if change.trigger.Action == types.WatchActionRebuild {
+			rebuild = true
+			continue
+		}
+
+		target, err := change.trigger.SyncTarget(change.path)
+		if err != nil {
+			return err
+		}
+
+		path, ok := compose.ServiceHostPath(service, target)
+		if !ok {
+			if service.Build == nil {
+				log.G(ctx).Warnf("cannot sync %s since %s is not mounted from the host and service %s has no build context", change.path, target, service.Name)
+				continue
+			}
+
+			log.G(ctx).Debugf("%s is not mounted from the host, rebuilding service %s", target, service.Name)
+			rebuild = true
+			continue
+		}
+
+		if err := compose.SyncPath(change.path, path); err != nil {
+			return err
+		}
+
+		log.G(ctx).
+			WithField("service", service.Name).
+			Debugf("synced %s to %s", change.path, target)
+
+		if change.trigger.Action == types.WatchActionSyncRestart {
+			restart = true
+		}
+	}
+
+	startOptions := start.StartOptions{
+		Composefile: opts.Composefile,
+	}
+
+	if rebuild {
+		log.G(ctx).Infof("rebuilding service %s...", service.Name)
+
+		createOptions := create.CreateOptions{
+			Build:         true,
+			Composefile:   opts.Composefile,
+			ForceRecreate: true,
+			NoDeps:        true,
+		}
+
+		if err := createOptions.Run(ctx, []string{service.Name}); err != nil {
+			return err
+		}
+
+		return startOptions.Run(ctx, []string{service.Name})
+	}
+
+	if restart {
+		log.G(ctx).Infof("restarting service %s...", service.Name)
+
+		stopOptions := stop.StopOptions{
+			Composefile: opts.Composefile,
+		}
+
+		if err := stopOptions.Run(ctx, []string{service.Name}); err != nil {
+			return err
+		}
+
+		return startOptions.Run(ctx, []string{service.Name})
+	}
+
+	return nil
+}
+
+// watchTrigger watches the path of the provided rule.  A file is watched
+// through its directory such that it is still watched after it is replaced.
+func watchTrigger(watcher *fsnotify.Watcher, trigger compose.WatchTrigger) error {
+	info, err := os.Stat(trigger.Path)
+	if err != nil {
+		return fmt.Errorf("could not watch %s: %w", trigger.Path, err)
+	}
+
+	if !info.IsDir() {
+		return watcher.Add(filepath.Dir(trigger.Path))
+	}
+
+	return watchDir(watcher, trigger, trigger.Path)
+}
+
+// watchDir watches the provided directory and every directory within it which
+// is not ignored by the provided rule.
+func watchDir(watcher *fsnotify.Watcher, trigger compose.WatchTrigger, dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() {
+			return nil
+		}
+
+		if trigger.Ignores(path) {
+			return filepath.SkipDir
+		}
+
+		if err := watcher.Add(path); err != nil {
+			return fmt.Errorf("could not watch %s: %w", path, err)
+		}
+
+		return nil
+	})
+}
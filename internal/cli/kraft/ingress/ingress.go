@@ -0,0 +1,305 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package ingress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	machineapi "kraftkit.sh/api/machine/v1alpha1"
+	"kraftkit.sh/cmdfactory"
+	"kraftkit.sh/config"
+	"kraftkit.sh/exec"
+	localingress "kraftkit.sh/internal/ingress"
+	"kraftkit.sh/log"
+	mplatform "kraftkit.sh/machine/platform"
+)
+
+// pollInterval is how often the machines are checked for domain names when
+// the ingress quits together with them.
+const pollInterval = 5 * time.Second
+
+type IngressOptions struct {
+	QuitTogether bool `long:"quit-together" short:"q" usage:"Exit once no machine has a domain name"`
+}
+
+func NewCmd() *cobra.Command {
+	cmd, err := cmdfactory.New(&IngressOptions{}, cobra.Command{
+		Short:   "Route domain names to local unikernels",
+		Use:     "ingress [FLAGS]",
+		Args:    cobra.NoArgs,
+		Aliases: []string{},
+		Long: heredoc.Docf(`
+			Route domain names to local unikernels
+
+			Serve HTTP and terminate TLS on the host for the domain names of the
+			unikernels set with 'kraft run --domain' or the %[1]s label, e.g.
+			through the labels of a compose service.  HTTP requests are routed by
+			their Host header and TLS connections by their server name indication.
+
+			Domain names are served with the certificate set with
+			'kraft run --certificate' or the %[2]s label, or otherwise with one
+			issued by a local certificate authority.  The certificate of the
+			authority is stored as %[3]s in the runtime directory of the ingress,
+			such that it can be trusted by the host.
+
+			The ingress serves HTTP on port 8000 and TLS on port 8443 of the
+			loopback interface by default.  It runs in the foreground and is started in the
+			background by 'kraft run' when a unikernel has a domain name.
+		`, LabelDomain, LabelCertificate, localingress.AuthorityCertificateFile),
+		Example: heredoc.Doc(`
+			# Route domain names to local unikernels
+			$ kraft ingress
+
+			# Serve HTTP and HTTPS on the standard ports of all interfaces
+			$ kraft ingress --ingress-http-addr :80 --ingress-https-addr :443
+		`),
+		Annotations: map[string]string{
+			cmdfactory.AnnotationHelpGroup: "run",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+// runtimeDir returns the directory holding the process ID of the ingress and
+// its certificate authority.
+func runtimeDir(ctx context.Context) string {
+	return filepath.Join(config.G[config.KraftKit](ctx).RuntimeDir, "ingress")
+}
+
+// pidFile returns the path of the file holding the process ID of the ingress.
+func pidFile(ctx context.Context) string {
+	return filepath.Join(runtimeDir(ctx), "ingress.pid")
+}
+
+func (opts *IngressOptions) Run(ctx context.Context, _ []string) error {
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if pid, ok := running(ctx); ok {
+		return fmt.Errorf("ingress is already running with process ID %d", pid)
+	}
+
+	if err := os.MkdirAll(runtimeDir(ctx), fs.ModeSetgid|0o775); err != nil {
+		return fmt.Errorf("could not make ingress directory: %w", err)
+	}
+
+	if err := os.WriteFile(pidFile(ctx), []byte(strconv.Itoa(os.Getpid())), 0o644); err != nil {
+		return fmt.Errorf("could not save ingress process ID: %w", err)
+	}
+
+	defer os.Remove(pidFile(ctx))
+
+	authority, err := localingress.LoadOrCreateAuthority(runtimeDir(ctx))
+	if err != nil {
+		return err
+	}
+
+	machineController, err := mplatform.NewMachineV1alpha1ServiceIterator(ctx)
+	if err != nil {
+		return err
+	}
+
+	routes := func(ctx context.Context) ([]localingress.Route, error) {
+		machines, err := machineController.List(ctx, &machineapi.MachineList{})
+		if err != nil {
+			return nil, err
+		}
+
+		return RoutesOf(machines.Items), nil
+	}
+
+	in := localingress.New(routes, authority)
+
+	httpAddr := config.G[config.KraftKit](ctx).Ingress.HTTPAddr
+	httpsAddr := config.G[config.KraftKit](ctx).Ingress.HTTPSAddr
+
+	httpListener, err := net.Listen("tcp", httpAddr)
+	if err != nil {
+		return fmt.Errorf("could not listen on %s: %w", httpAddr, err)
+	}
+
+	httpsListener, err := net.Listen("tcp", httpsAddr)
+	if err != nil {
+		httpListener.Close()
+		return fmt.Errorf("could not listen on %s: %w", httpsAddr, err)
+	}
+
+	log.G(ctx).
+		WithField("http", httpListener.Addr().String()).
+		WithField("https", httpsListener.Addr().String()).
+		Info("serving ingress")
+
+	if opts.QuitTogether {
+		go func() {
+			defer cancel()
+
+			if err := waitForDomains(ctx, machineController); err != nil {
+				log.G(ctx).Errorf("could not list machines: %v", err)
+			}
+		}()
+	}
+
+	errs := make(chan error, 2)
+
+	go func() {
+		errs <- in.ServeHTTPListener(ctx, httpListener)
+	}()
+
+	go func() {
+		errs <- in.ServeTLSListener(ctx, httpsListener)
+	}()
+
+	var ret []error
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			ret = append(ret, err)
+			cancel()
+		}
+	}
+
+	return errors.Join(ret...)
+}
+
+// waitForDomains returns once no machine has a domain name or the context is
+// cancelled.
+func waitForDomains(ctx context.Context, machineController machineapi.MachineService) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(pollInterval):
+		}
+
+		machines, err := machineController.List(ctx, &machineapi.MachineList{})
+		if err != nil {
+			return err
+		}
+
+		if !HasDomains(machines.Items) {
+			log.G(ctx).Info("no machine has a domain name, quitting")
+			return nil
+		}
+	}
+}
+
+// HasDomains returns whether any of the provided machines has a domain name.
+func HasDomains(machines []machineapi.Machine) bool {
+	for _, machine := range machines {
+		if len(machine.Labels[LabelDomain]) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// running returns the process ID of the ingress if it is running.
+func running(ctx context.Context) (int, bool) {
+	raw, err := os.ReadFile(pidFile(ctx))
+	if err != nil {
+		return 0, false
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(raw)))
+	if err != nil || pid == os.Getpid() {
+		return 0, false
+	}
+
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return 0, false
+	}
+
+	if err := process.Signal(syscall.Signal(0)); err != nil {
+		return 0, false
+	}
+
+	return pid, true
+}
+
+// Start starts the ingress in the background unless it is already running.
+// The ingress quits once no machine has a domain name anymore.
+func Start(ctx context.Context) error {
+	if pid, ok := running(ctx); ok {
+		log.G(ctx).WithField("pid", pid).Debug("ingress already running")
+		return nil
+	}
+
+	bin, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("could not determine executable: %w", err)
+	}
+
+	httpAddr := config.G[config.KraftKit](ctx).Ingress.HTTPAddr
+	httpsAddr := config.G[config.KraftKit](ctx).Ingress.HTTPSAddr
+
+	// The ingress runs detached, so make sure that it can listen on its
+	// addresses beforehand rather than failing unnoticed.
+	for _, addr := range []string{httpAddr, httpsAddr} {
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return fmt.Errorf("could not listen on %s: %w", addr, err)
+		}
+
+		listener.Close()
+	}
+
+	args := []string{
+		"ingress",
+		"--quit-together",
+		"--ingress-http-addr", httpAddr,
+		"--ingress-https-addr", httpsAddr,
+	}
+
+	if err := os.MkdirAll(runtimeDir(ctx), fs.ModeSetgid|0o775); err != nil {
+		return fmt.Errorf("could not make ingress directory: %w", err)
+	}
+
+	logFile, err := os.OpenFile(filepath.Join(runtimeDir(ctx), "ingress.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("could not open ingress log file: %w", err)
+	}
+
+	defer logFile.Close()
+
+	process, err := exec.NewProcess(bin, args,
+		exec.WithDetach(true),
+		exec.WithStdout(logFile),
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := process.Start(ctx); err != nil {
+		return fmt.Errorf("could not start ingress: %w", err)
+	}
+
+	pid, err := process.Pid()
+	if err != nil {
+		return err
+	}
+
+	log.G(ctx).WithField("pid", pid).Debug("started ingress")
+
+	return process.Release()
+}
@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package ingress
+
+import (
+	"net"
+	"strconv"
+	"strings"
+
+	machineapi "kraftkit.sh/api/machine/v1alpha1"
+	localingress "kraftkit.sh/internal/ingress"
+)
+
+const (
+	// LabelDomain is the label of a machine which holds the comma-separated
+	// domain names routed to it.  A domain name starting with "*." matches all
+	// of its subdomains.
+	LabelDomain = "kraftkit.sh/ingress.domain"
+
+	// LabelPort is the label of a machine which holds the port of the machine
+	// to which connections are routed.  It defaults to the first published
+	// port of the machine, or 80 if it publishes none.
+	LabelPort = "kraftkit.sh/ingress.port"
+
+	// LabelCertificate is the label of a machine which holds the path to a PEM
+	// file with the certificate chain and private key of its domain names.  A
+	// certificate issued by the local authority is used otherwise.
+	LabelCertificate = "kraftkit.sh/ingress.certificate"
+)
+
+// DefaultPort is the port of a machine to which connections are routed if it
+// neither sets LabelPort nor publishes any port.
+const DefaultPort = 80
+
+// RoutesOf returns the routes of the provided running machines which have
+// domain names.  Machines are reached at their address on a network if they
+// are attached to one and through their published port on the host
+// otherwise.
+func RoutesOf(machines []machineapi.Machine) []localingress.Route {
+	var routes []localingress.Route
+
+	for _, machine := range machines {
+		if machine.Status.State != machineapi.MachineStateRunning {
+			continue
+		}
+
+		domains, ok := machine.Labels[LabelDomain]
+		if !ok || len(domains) == 0 {
+			continue
+		}
+
+		backend := machineBackend(machine)
+		if len(backend) == 0 {
+			continue
+		}
+
+		for _, domain := range strings.Split(domains, ",") {
+			domain = localingress.Normalize(domain)
+			if len(domain) == 0 {
+				continue
+			}
+
+			routes = append(routes, localingress.Route{
+				Domain:      domain,
+				Machine:     machine.Name,
+				Backend:     backend,
+				Certificate: machine.Labels[LabelCertificate],
+			})
+		}
+	}
+
+	return routes
+}
+
+// machineBackend returns the address of the provided machine to which
+// connections are routed, or an empty string if it cannot be reached.
+func machineBackend(machine machineapi.Machine) string {
+	port := DefaultPort
+	hostPort := 0
+	hostIP := ""
+
+	if len(machine.Spec.Ports) > 0 {
+		port = int(machine.Spec.Ports[0].MachinePort)
+		hostPort = int(machine.Spec.Ports[0].HostPort)
+		hostIP = machine.Spec.Ports[0].HostIP
+	}
+
+	if label, ok := machine.Labels[LabelPort]; ok {
+		parsed, err := strconv.Atoi(label)
+		if err != nil {
+			return ""
+		}
+
+		port = parsed
+		hostPort = 0
+
+		for _, published := range machine.Spec.Ports {
+			if int(published.MachinePort) == port {
+				hostPort = int(published.HostPort)
+				hostIP = published.HostIP
+				break
+			}
+		}
+	}
+
+	for _, network := range machine.Spec.Networks {
+		for _, iface := range network.Interfaces {
+			if ip, _, err := net.ParseCIDR(iface.Spec.CIDR); err == nil {
+				return net.JoinHostPort(ip.String(), strconv.Itoa(port))
+			} else if ip := net.ParseIP(iface.Spec.CIDR); ip != nil {
+				return net.JoinHostPort(ip.String(), strconv.Itoa(port))
+			}
+		}
+	}
+
+	if hostPort == 0 {
+		return ""
+	}
+
+	if ip := net.ParseIP(hostIP); ip == nil || ip.IsUnspecified() {
+		hostIP = "127.0.0.1"
+	}
+
+	return net.JoinHostPort(hostIP, strconv.Itoa(hostPort))
+}
@@ -31,6 +31,7 @@ import (
 	"kraftkit.sh/internal/cli/kraft/compose"
 	"kraftkit.sh/internal/cli/kraft/events"
 	"kraftkit.sh/internal/cli/kraft/fetch"
+	"kraftkit.sh/internal/cli/kraft/ingress"
 	"kraftkit.sh/internal/cli/kraft/lib"
 	"kraftkit.sh/internal/cli/kraft/login"
 	"kraftkit.sh/internal/cli/kraft/logs"
@@ -44,6 +45,7 @@ import (
 	"kraftkit.sh/internal/cli/kraft/set"
 	"kraftkit.sh/internal/cli/kraft/start"
 	"kraftkit.sh/internal/cli/kraft/stop"
+	"kraftkit.sh/internal/cli/kraft/system"
 	"kraftkit.sh/internal/cli/kraft/unset"
 	"kraftkit.sh/internal/cli/kraft/version"
 	"kraftkit.sh/internal/cli/kraft/volume"
@@ -93,6 +95,7 @@ func NewCmd() *cobra.Command {
 
 	cmd.AddGroup(&cobra.Group{ID: "run", Title: "LOCAL RUNTIME COMMANDS"})
 	cmd.AddCommand(events.NewCmd())
+	cmd.AddCommand(ingress.NewCmd())
 	cmd.AddCommand(logs.NewCmd())
 	cmd.AddCommand(ps.NewCmd())
 	cmd.AddCommand(remove.NewCmd())
@@ -123,6 +126,7 @@ func NewCmd() *cobra.Command {
 
 	cmd.AddGroup(&cobra.Group{ID: "misc", Title: "MISCELLANEOUS COMMANDS"})
 	cmd.AddCommand(login.NewCmd())
+	cmd.AddCommand(system.NewCmd())
 	cmd.AddCommand(version.NewCmd())
 	cmd.AddCommand(x.NewCmd())
 
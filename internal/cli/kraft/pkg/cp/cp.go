@@ -0,0 +1,150 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package cp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"kraftkit.sh/cmdfactory"
+	"kraftkit.sh/config"
+	"kraftkit.sh/log"
+	"kraftkit.sh/oci"
+	"kraftkit.sh/oci/handler"
+)
+
+type CpOptions struct{}
+
+// Cp copies a package between registries and local package stores.
+func Cp(ctx context.Context, opts *CpOptions, args ...string) error {
+	if opts == nil {
+		opts = &CpOptions{}
+	}
+
+	return opts.Run(ctx, args)
+}
+
+func NewCmd() *cobra.Command {
+	cmd, err := cmdfactory.New(&CpOptions{}, cobra.Command{
+		Short: "Copy a package between registries and package stores",
+		Use:   "cp [FLAGS] SOURCE DESTINATION",
+		Args:  cobra.ExactArgs(2),
+		Long: heredoc.Doc(`
+			Copy a package, including all of its targets, signatures and software
+			bills of materials, without unpacking it.
+
+			The source and destination are one of:
+
+			  REF, registry://REF        A package in a remote registry.
+			  local://REF                A package in the local package store.
+			  dir://PATH#REF             A package in the directory package store at PATH.
+			  containerd://ADDR#REF      A package in the containerd daemon at ADDR.
+
+			Packages are copied between registries directly and only the blobs
+			which are missing at the destination are transferred.
+		`),
+		Example: heredoc.Doc(`
+			# Mirror a package to another registry
+			$ kraft pkg cp unikraft.org/nginx:latest ghcr.io/acme/nginx:latest
+
+			# Copy a package from the local package store into containerd
+			$ kraft pkg cp local://unikraft.org/nginx:latest containerd:///run/containerd/containerd.sock#unikraft.org/nginx:latest
+
+			# Push a local package under another name
+			$ kraft pkg cp local://nginx:dev registry://ghcr.io/acme/nginx:latest
+		`),
+		Annotations: map[string]string{
+			cmdfactory.AnnotationHelpGroup: "pkg",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+// location parses the provided argument into a location of a package.
+func location(ctx context.Context, arg string) (context.Context, oci.Location, error) {
+	scheme, rest, ok := strings.Cut(arg, "://")
+	if !ok {
+		return ctx, oci.Location{Ref: arg}, nil
+	}
+
+	switch scheme {
+	case "registry":
+		return ctx, oci.Location{Ref: rest}, nil
+
+	case "local":
+		ctx, handle, err := oci.NewHandlerFromContext(ctx)
+		if err != nil {
+			return nil, oci.Location{}, err
+		}
+
+		return ctx, oci.Location{Handle: handle, Ref: rest}, nil
+
+	case "dir":
+		path, ref, ok := strings.Cut(rest, "#")
+		if !ok || len(path) == 0 {
+			return nil, oci.Location{}, fmt.Errorf("expected dir://PATH#REF but got '%s'", arg)
+		}
+
+		handle, err := handler.NewDirectoryHandler(path, config.G[config.KraftKit](ctx).Auth, config.G[config.KraftKit](ctx).Registries)
+		if err != nil {
+			return nil, oci.Location{}, err
+		}
+
+		return ctx, oci.Location{Handle: handle, Ref: ref}, nil
+
+	case "containerd":
+		addr, ref, ok := strings.Cut(rest, "#")
+		if !ok || len(addr) == 0 {
+			return nil, oci.Location{}, fmt.Errorf("expected containerd://ADDR#REF but got '%s'", arg)
+		}
+
+		namespace := oci.DefaultNamespace
+		if n := os.Getenv("CONTAINERD_NAMESPACE"); n != "" {
+			namespace = n
+		}
+
+		ctx, handle, err := handler.NewContainerdHandler(ctx, addr, namespace, config.G[config.KraftKit](ctx).Auth, config.G[config.KraftKit](ctx).Registries)
+		if err != nil {
+			return nil, oci.Location{}, err
+		}
+
+		return ctx, oci.Location{Handle: handle, Ref: ref}, nil
+
+	default:
+		return nil, oci.Location{}, fmt.Errorf("unsupported location '%s': expected one of registry, local, dir or containerd", scheme)
+	}
+}
+
+func (opts *CpOptions) Run(ctx context.Context, args []string) error {
+	ctx, src, err := location(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	ctx, dst, err := location(ctx, args[1])
+	if err != nil {
+		return err
+	}
+
+	if err := oci.Copy(ctx, src, dst); err != nil {
+		return fmt.Errorf("could not copy %s to %s: %w", src, dst, err)
+	}
+
+	log.G(ctx).
+		WithField("src", src.String()).
+		WithField("dst", dst.String()).
+		Info("copied")
+
+	return nil
+}
@@ -0,0 +1,111 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+package create
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"kraftkit.sh/cmdfactory"
+	"kraftkit.sh/iostreams"
+	"kraftkit.sh/oci"
+	"kraftkit.sh/packmanager"
+)
+
+type CreateOptions struct {
+	From     []string                  `long:"from" short:"f" usage:"Add the targets of the provided package to the index (can be repeated)"`
+	Push     bool                      `long:"push" short:"P" usage:"Push the index once it has been created"`
+	Strategy packmanager.MergeStrategy `noattribute:"true"`
+}
+
+// Create a package which combines the targets of other packages.
+func Create(ctx context.Context, opts *CreateOptions, args ...string) error {
+	if opts == nil {
+		opts = &CreateOptions{}
+	}
+
+	return opts.Run(ctx, args)
+}
+
+func NewCmd() *cobra.Command {
+	cmd, err := cmdfactory.New(&CreateOptions{}, cobra.Command{
+		Short: "Create a package which combines the targets of other packages",
+		Use:   "create [FLAGS] PACKAGE --from PACKAGE [--from PACKAGE...]",
+		Args:  cobra.ExactArgs(1),
+		Long: heredoc.Doc(`
+			Create a package in the local package store whose index lists the
+			targets of the provided packages, e.g. to assemble a multi-architecture
+			package from packages which have been built and packaged separately.
+
+			No two targets may share the same platform, architecture and KConfig.
+			If the package already exists, its targets are either retained (merge),
+			with the exception of those which are replaced by a target of the same
+			platform, or discarded (overwrite).
+		`),
+		Example: heredoc.Doc(`
+			# Combine separately built packages into a multi-architecture package
+			$ kraft pkg index create unikraft.org/nginx:latest --from nginx:x86_64 --from nginx:arm64
+
+			# Add a target to an existing package and push the result
+			$ kraft pkg index create unikraft.org/nginx:latest --from nginx:arm64 --strategy merge --push
+		`),
+		Annotations: map[string]string{
+			cmdfactory.AnnotationHelpGroup: "pkg",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	cmd.Flags().Var(
+		cmdfactory.NewEnumFlag[packmanager.MergeStrategy](
+			packmanager.MergeStrategies(),
+			packmanager.StrategyAbort,
+		),
+		"strategy",
+		"When a package of the same name exists, use this strategy when adding targets.",
+	)
+
+	return cmd
+}
+
+func (opts *CreateOptions) Pre(cmd *cobra.Command, _ []string) error {
+	if len(opts.From) == 0 {
+		return fmt.Errorf("at least one package must be provided via --from")
+	}
+
+	opts.Strategy = packmanager.MergeStrategy(cmd.Flag("strategy").Value.String())
+
+	return nil
+}
+
+func (opts *CreateOptions) Run(ctx context.Context, args []string) error {
+	ctx, handle, err := oci.NewHandlerFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	desc, err := oci.CreateIndex(ctx, handle, args[0], opts.From, opts.Strategy)
+	if err != nil {
+		return fmt.Errorf("could not create index: %w", err)
+	}
+
+	if opts.Push {
+		if err := oci.Copy(ctx,
+			oci.Location{Handle: handle, Ref: args[0]},
+			oci.Location{Ref: args[0]},
+		); err != nil {
+			return fmt.Errorf("could not push index: %w", err)
+		}
+	}
+
+	fmt.Fprintf(iostreams.G(ctx).Out, "%s\n", desc.Digest.String())
+
+	return nil
+}
@@ -0,0 +1,46 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+package index
+
+import (
+	"context"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"kraftkit.sh/internal/cli/kraft/pkg/index/create"
+
+	"kraftkit.sh/cmdfactory"
+)
+
+type IndexOptions struct{}
+
+func NewCmd() *cobra.Command {
+	cmd, err := cmdfactory.New(&IndexOptions{}, cobra.Command{
+		Short: "Manage multi-target package indexes",
+		Use:   "index SUBCOMMAND",
+		Long:  "Manage the indexes which list the targets of packages.",
+		Example: heredoc.Doc(`
+			# Combine separately built packages into a multi-architecture package
+			$ kraft pkg index create unikraft.org/nginx:latest --from nginx:x86_64 --from nginx:arm64
+		`),
+		Annotations: map[string]string{
+			cmdfactory.AnnotationHelpGroup: "pkg",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	cmd.AddCommand(create.NewCmd())
+
+	return cmd
+}
+
+func (opts *IndexOptions) Run(_ context.Context, _ []string) error {
+	return pflag.ErrHelp
+}
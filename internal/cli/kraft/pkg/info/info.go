@@ -24,9 +24,16 @@ import (
 
 type InfoOptions struct {
 	Output string `long:"output" short:"o" usage:"Set output format. Options: table,yaml,json,list" default:"table"`
+	SBOM   bool   `long:"sbom" usage:"Print the software bill of materials attached to the package"`
 	Update bool   `long:"update" short:"u" usage:"Get latest information about components before listing results"`
 }
 
+// sbomPackage is implemented by packages which can carry a software bill of
+// materials.
+type sbomPackage interface {
+	SBOM(context.Context) ([]byte, string, error)
+}
+
 // Info shows package information.
 func Info(ctx context.Context, opts *InfoOptions, args ...string) error {
 	if opts == nil {
@@ -48,6 +55,9 @@ func New() *cobra.Command {
 		Example: heredoc.Doc(`
 			# Shows details for the library nginx
 			$ kraft pkg info nginx
+
+			# Print the software bill of materials of a package
+			$ kraft pkg info --sbom unikraft.org/nginx:latest
 		`),
 		Annotations: map[string]string{
 			cmdfactory.AnnotationHelpGroup: "pkg",
@@ -127,5 +137,45 @@ func (opts *InfoOptions) Run(ctx context.Context, args []string) error {
 		return fmt.Errorf("could not find package(s): %v", args)
 	}
 
+	if opts.SBOM {
+		return opts.printSBOMs(ctx, packs)
+	}
+
 	return pkgutils.PrintPackages(ctx, iostreams.G(ctx).Out, opts.Output, packs...)
 }
+
+// printSBOMs writes the software bill of materials of each package.
+func (opts *InfoOptions) printSBOMs(ctx context.Context, packs []pack.Package) error {
+	printed := 0
+
+	for _, p := range packs {
+		sp, ok := p.(sbomPackage)
+		if !ok {
+			log.G(ctx).
+				WithField("package", p.Name()).
+				Debug("package format does not support sboms")
+			continue
+		}
+
+		raw, mediaType, err := sp.SBOM(ctx)
+		if err != nil {
+			return fmt.Errorf("could not retrieve sbom of %s: %w", p.Name(), err)
+		} else if len(raw) == 0 {
+			continue
+		}
+
+		log.G(ctx).
+			WithField("package", p.Name()).
+			WithField("mediaType", mediaType).
+			Debug("sbom")
+
+		fmt.Fprintf(iostreams.G(ctx).Out, "%s\n", raw)
+		printed++
+	}
+
+	if printed == 0 {
+		return fmt.Errorf("no software bill of materials attached to package(s)")
+	}
+
+	return nil
+}
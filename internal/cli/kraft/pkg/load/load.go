@@ -0,0 +1,109 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package load
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"kraftkit.sh/cmdfactory"
+	"kraftkit.sh/iostreams"
+	"kraftkit.sh/oci"
+	"kraftkit.sh/oci/layout"
+)
+
+type LoadOptions struct{}
+
+// Load packages from an OCI image-layout tarball.
+func Load(ctx context.Context, opts *LoadOptions, args ...string) error {
+	if opts == nil {
+		opts = &LoadOptions{}
+	}
+
+	return opts.Run(ctx, args)
+}
+
+func NewCmd() *cobra.Command {
+	cmd, err := cmdfactory.New(&LoadOptions{}, cobra.Command{
+		Short: "Load packages from a tarball",
+		Use:   "load [FLAGS] [FILE]",
+		Args:  cobra.MaximumNArgs(1),
+		Long: heredoc.Doc(`
+			Load packages from an OCI image-layout tarball, e.g. one which has been
+			created via 'kraft pkg save', into the local package store.
+
+			The tarball is read from stdin if no file is provided and may be gzip
+			compressed.  The name of each loaded package is printed.
+		`),
+		Example: heredoc.Doc(`
+			# Load the packages of a tarball
+			$ kraft pkg load nginx.tar
+
+			# Load the packages of a compressed tarball from stdin
+			$ kraft pkg load < apps.tar.gz
+		`),
+		Annotations: map[string]string{
+			cmdfactory.AnnotationHelpGroup: "pkg",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+func (opts *LoadOptions) Run(ctx context.Context, args []string) error {
+	var r io.Reader = iostreams.G(ctx).In
+
+	if len(args) > 0 && args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("could not open tarball: %w", err)
+		}
+
+		defer f.Close()
+
+		r = f
+	}
+
+	// Transparently decompress gzip-compressed tarballs.
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return fmt.Errorf("could not decompress tarball: %w", err)
+		}
+
+		defer gr.Close()
+
+		r = gr
+	} else {
+		r = br
+	}
+
+	ctx, handle, err := oci.NewHandlerFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	refs, err := layout.Load(ctx, handle, r)
+	if err != nil {
+		return err
+	}
+
+	for _, ref := range refs {
+		fmt.Fprintf(iostreams.G(ctx).Out, "%s\n", ref)
+	}
+
+	return nil
+}
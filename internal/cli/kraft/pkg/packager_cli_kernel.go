@@ -101,6 +101,20 @@ func (p *packagerCliKernel) Pack(ctx context.Context, opts *PkgOptions, args ...
 					popts = append(popts, packmanager.PackWithEnvs(opts.Env))
 				}
 
+				sbomopt, err := opts.packSBOM(ctx, opts.Rootfs)
+				if err != nil {
+					return err
+				} else if sbomopt != nil {
+					popts = append(popts, sbomopt)
+				}
+
+				srcopts, err := opts.packSources(ctx)
+				if err != nil {
+					return err
+				}
+
+				popts = append(popts, srcopts...)
+
 				more, err := opts.pm.Pack(ctx, targ, popts...)
 				if err != nil {
 					return err
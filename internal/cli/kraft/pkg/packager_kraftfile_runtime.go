@@ -393,6 +393,20 @@ func (p *packagerKraftfileRuntime) Pack(ctx context.Context, opts *PkgOptions, a
 					popts = append(popts, packmanager.PackWithEnvs(opts.Env))
 				}
 
+				sbomopt, err := opts.packSBOM(ctx, opts.Rootfs)
+				if err != nil {
+					return err
+				} else if sbomopt != nil {
+					popts = append(popts, sbomopt)
+				}
+
+				srcopts, err := opts.packSources(ctx)
+				if err != nil {
+					return err
+				}
+
+				popts = append(popts, srcopts...)
+
 				more, err := opts.pm.Pack(ctx, targ, popts...)
 				if err != nil {
 					return err
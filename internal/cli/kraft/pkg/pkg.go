@@ -17,6 +17,7 @@ import (
 	"kraftkit.sh/log"
 	"kraftkit.sh/machine/platform"
 	"kraftkit.sh/pack"
+	"kraftkit.sh/sbom"
 	"kraftkit.sh/tui/processtree"
 	"kraftkit.sh/tui/selection"
 	"kraftkit.sh/unikraft/app"
@@ -24,39 +25,53 @@ import (
 	"kraftkit.sh/cmdfactory"
 	"kraftkit.sh/packmanager"
 
+	"kraftkit.sh/internal/cli/kraft/pkg/cp"
+	"kraftkit.sh/internal/cli/kraft/pkg/index"
 	"kraftkit.sh/internal/cli/kraft/pkg/info"
 	"kraftkit.sh/internal/cli/kraft/pkg/list"
+	"kraftkit.sh/internal/cli/kraft/pkg/load"
 	"kraftkit.sh/internal/cli/kraft/pkg/pull"
 	"kraftkit.sh/internal/cli/kraft/pkg/push"
 	"kraftkit.sh/internal/cli/kraft/pkg/remove"
+	"kraftkit.sh/internal/cli/kraft/pkg/save"
+	"kraftkit.sh/internal/cli/kraft/pkg/serve"
+	"kraftkit.sh/internal/cli/kraft/pkg/sign"
 	"kraftkit.sh/internal/cli/kraft/pkg/source"
+	"kraftkit.sh/internal/cli/kraft/pkg/tag"
 	"kraftkit.sh/internal/cli/kraft/pkg/unsource"
 	"kraftkit.sh/internal/cli/kraft/pkg/update"
 )
 
 type PkgOptions struct {
-	Architecture string                    `local:"true" long:"arch" short:"m" usage:"Filter the creation of the package by architecture of known targets (x86_64/arm64/arm)"`
-	Args         []string                  `local:"true" long:"args" short:"a" usage:"Pass arguments that will be part of the running kernel's command line"`
-	Compress     bool                      `local:"true" long:"compress" short:"c" usage:"Compress the initrd package (experimental)"`
-	Dbg          bool                      `local:"true" long:"dbg" usage:"Package the debuggable (symbolic) kernel image instead of the stripped image"`
-	Env          []string                  `local:"true" long:"env" short:"e" usage:"Set environment variables to be packed into the package"`
-	Force        bool                      `local:"true" long:"force-format" usage:"Force the use of a packaging handler format"`
-	Format       string                    `local:"true" long:"as" short:"M" usage:"Force the packaging despite possible conflicts" default:"oci"`
-	Kernel       string                    `local:"true" long:"kernel" short:"k" usage:"Override the path to the unikernel image"`
-	Kraftfile    string                    `long:"kraftfile" short:"K" usage:"Set an alternative path of the Kraftfile"`
-	Labels       []string                  `local:"true" long:"label" short:"l" usage:"Set labels to be packed into the package (k=v)"`
-	Name         string                    `local:"true" long:"name" short:"n" usage:"Specify the name of the package"`
-	NoKConfig    bool                      `local:"true" long:"no-kconfig" usage:"Do not include target .config as metadata"`
-	NoPull       bool                      `local:"true" long:"no-pull" usage:"Do not pull package dependencies before packaging"`
-	Output       string                    `local:"true" long:"output" short:"o" usage:"Save the package at the following output"`
-	Platform     string                    `local:"true" long:"plat" short:"p" usage:"Filter the creation of the package by platform of known targets (fc/qemu/xen/kraftcloud)"`
-	Project      app.Application           `noattribute:"true"`
-	Push         bool                      `local:"true" long:"push" short:"P" usage:"Push the package on if successfully packaged"`
-	Rootfs       string                    `local:"true" long:"rootfs" usage:"Specify a path to use as root file system (can be volume or initramfs)"`
-	Runtime      string                    `local:"true" long:"runtime" short:"r" usage:"Set the runtime to use for the package"`
-	Strategy     packmanager.MergeStrategy `noattribute:"true"`
-	Target       string                    `local:"true" long:"target" short:"t" usage:"Package a particular known target"`
-	Workdir      string                    `local:"true" long:"workdir" short:"w" usage:"Set an alternative working directory (default is cwd)"`
+	Architecture            string                    `local:"true" long:"arch" short:"m" usage:"Filter the creation of the package by architecture of known targets (x86_64/arm64/arm)"`
+	Args                    []string                  `local:"true" long:"args" short:"a" usage:"Pass arguments that will be part of the running kernel's command line"`
+	ChunkInitrd             bool                      `local:"true" long:"chunk-initrd" usage:"Package the initrd as multiple content-defined chunks which are reused across versions"`
+	Compress                bool                      `local:"true" long:"compress" short:"c" usage:"Compress the initrd package (experimental)"`
+	Dbg                     bool                      `local:"true" long:"dbg" usage:"Package the debuggable (symbolic) kernel image instead of the stripped image"`
+	Env                     []string                  `local:"true" long:"env" short:"e" usage:"Set environment variables to be packed into the package"`
+	Force                   bool                      `local:"true" long:"force-format" usage:"Force the use of a packaging handler format"`
+	Format                  string                    `local:"true" long:"as" short:"M" usage:"Force the packaging despite possible conflicts" default:"oci"`
+	Kernel                  string                    `local:"true" long:"kernel" short:"k" usage:"Override the path to the unikernel image"`
+	Kraftfile               string                    `long:"kraftfile" short:"K" usage:"Set an alternative path of the Kraftfile"`
+	Labels                  []string                  `local:"true" long:"label" short:"l" usage:"Set labels to be packed into the package (k=v)"`
+	Name                    string                    `local:"true" long:"name" short:"n" usage:"Specify the name of the package"`
+	NoKConfig               bool                      `local:"true" long:"no-kconfig" usage:"Do not include target .config as metadata"`
+	NoPull                  bool                      `local:"true" long:"no-pull" usage:"Do not pull package dependencies before packaging"`
+	Output                  string                    `local:"true" long:"output" short:"o" usage:"Save the package at the following output"`
+	Platform                string                    `local:"true" long:"plat" short:"p" usage:"Filter the creation of the package by platform of known targets (fc/qemu/xen/kraftcloud)"`
+	Project                 app.Application           `noattribute:"true"`
+	Push                    bool                      `local:"true" long:"push" short:"P" usage:"Push the package on if successfully packaged"`
+	Rootfs                  string                    `local:"true" long:"rootfs" usage:"Specify a path to use as root file system (can be volume or initramfs)"`
+	Runtime                 string                    `local:"true" long:"runtime" short:"r" usage:"Set the runtime to use for the package"`
+	SBOM                    bool                      `local:"true" long:"sbom" usage:"Generate and attach a software bill of materials to the package"`
+	SBOMFormat              string                    `local:"true" long:"sbom-format" usage:"Set the format of the software bill of materials (spdx/cyclonedx)" default:"spdx"`
+	Strategy                packmanager.MergeStrategy `noattribute:"true"`
+	Target                  string                    `local:"true" long:"target" short:"t" usage:"Package a particular known target"`
+	WithAppSources          bool                      `local:"true" long:"with-app-sources" usage:"Include the source files of the application in the package"`
+	WithIntermediateObjects bool                      `local:"true" long:"with-intermediate-objects" usage:"Include the intermediate object files of the libraries in the package"`
+	WithKernelSources       bool                      `local:"true" long:"with-kernel-sources" usage:"Include the source files of the Unikraft core and libraries in the package"`
+	WithLibObjects          bool                      `local:"true" long:"with-lib-objects" usage:"Include the object files of the libraries in the package"`
+	Workdir                 string                    `local:"true" long:"workdir" short:"w" usage:"Set an alternative working directory (default is cwd)"`
 
 	packopts []packmanager.PackOption
 	pm       packmanager.PackageManager
@@ -91,6 +106,10 @@ func Pkg(ctx context.Context, opts *PkgOptions, args ...string) ([]pack.Package,
 		return nil, fmt.Errorf("the `--arch` and `--plat` options are not supported in addition to `--target`")
 	}
 
+	if opts.SBOM && len(sbom.Format(opts.SBOMFormat).MediaType()) == 0 {
+		return nil, fmt.Errorf("unsupported sbom format: %s: expected one of %v", opts.SBOMFormat, sbom.Formats())
+	}
+
 	if config.G[config.KraftKit](ctx).NoPrompt && opts.Strategy == packmanager.StrategyPrompt {
 		return nil, fmt.Errorf("cannot mix --strategy=prompt when --no-prompt is enabled in settings")
 	}
@@ -169,6 +188,10 @@ func Pkg(ctx context.Context, opts *PkgOptions, args ...string) ([]pack.Package,
 		)
 	}
 
+	opts.packopts = append(opts.packopts,
+		packmanager.PackInitrdChunks(opts.ChunkInitrd),
+	)
+
 	var pkgr packager
 
 	packagers := packagers()
@@ -258,6 +281,17 @@ func NewCmd() *cobra.Command {
 		Example: heredoc.Doc(`
 			# Package a project as an OCI archive and embed the target's KConfig.
 			$ kraft pkg --as oci --name unikraft.org/nginx:latest	
+
+			# Package a project and attach a CycloneDX software bill of materials.
+			$ kraft pkg --sbom --sbom-format cyclonedx --name unikraft.org/nginx:latest
+
+			# Package a project with its library objects and sources such that it can
+			# be relinked without the original source tree.
+			$ kraft pkg --with-lib-objects --with-kernel-sources --with-app-sources --name unikraft.org/nginx:latest
+
+			# Package a project with its initrd split into chunks such that pulling a
+			# new version only downloads the chunks which have changed.
+			$ kraft pkg --chunk-initrd --name unikraft.org/python:latest
 		`),
 		Annotations: map[string]string{
 			cmdfactory.AnnotationHelpGroup: "pkg",
@@ -267,12 +301,19 @@ func NewCmd() *cobra.Command {
 		panic(err)
 	}
 
+	cmd.AddCommand(cp.NewCmd())
+	cmd.AddCommand(index.NewCmd())
 	cmd.AddCommand(info.New())
 	cmd.AddCommand(list.NewCmd())
+	cmd.AddCommand(load.NewCmd())
 	cmd.AddCommand(pull.NewCmd())
 	cmd.AddCommand(push.NewCmd())
 	cmd.AddCommand(remove.NewCmd())
+	cmd.AddCommand(save.NewCmd())
+	cmd.AddCommand(serve.NewCmd())
+	cmd.AddCommand(sign.NewCmd())
 	cmd.AddCommand(source.NewCmd())
+	cmd.AddCommand(tag.NewCmd())
 	cmd.AddCommand(unsource.NewCmd())
 	cmd.AddCommand(update.NewCmd())
 
@@ -73,6 +73,10 @@ func NewCmd() *cobra.Command {
 
 			# Pull from a registry
 			$ kraft pkg pull unikraft.org/nginx:1.21.6
+
+			# Pull from a registry and unpack the package, including any library
+			# objects and source files, into a directory
+			$ kraft pkg pull --output ./nginx unikraft.org/nginx:1.21.6
 		`),
 		Annotations: map[string]string{
 			cmdfactory.AnnotationHelpGroup: "pkg",
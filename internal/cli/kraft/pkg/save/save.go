@@ -0,0 +1,126 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package save
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/spf13/cobra"
+
+	"kraftkit.sh/cmdfactory"
+	"kraftkit.sh/iostreams"
+	"kraftkit.sh/log"
+	"kraftkit.sh/oci"
+	"kraftkit.sh/oci/layout"
+)
+
+type SaveOptions struct {
+	Output string `long:"output" short:"o" usage:"Write the tarball to the provided path instead of stdout"`
+}
+
+// Save one or more packages to an OCI image-layout tarball.
+func Save(ctx context.Context, opts *SaveOptions, args ...string) error {
+	if opts == nil {
+		opts = &SaveOptions{}
+	}
+
+	return opts.Run(ctx, args)
+}
+
+func NewCmd() *cobra.Command {
+	cmd, err := cmdfactory.New(&SaveOptions{}, cobra.Command{
+		Short: "Save packages to a tarball",
+		Use:   "save [FLAGS] PACKAGE [PACKAGE...]",
+		Args:  cobra.MinimumNArgs(1),
+		Long: heredoc.Doc(`
+			Save one or more locally stored packages to an OCI image-layout tarball.
+
+			The tarball contains all targets of each package as well as any of
+			their locally stored signatures and software bills of materials, such
+			that it can be imported on another host via 'kraft pkg load' without
+			access to a registry.
+		`),
+		Example: heredoc.Doc(`
+			# Save a package to a tarball
+			$ kraft pkg save unikraft.org/nginx:latest -o nginx.tar
+
+			# Save multiple packages and compress the tarball
+			$ kraft pkg save unikraft.org/nginx:latest unikraft.org/redis:latest | gzip > apps.tar.gz
+		`),
+		Annotations: map[string]string{
+			cmdfactory.AnnotationHelpGroup: "pkg",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+func (opts *SaveOptions) Pre(cmd *cobra.Command, _ []string) error {
+	if len(opts.Output) == 0 && iostreams.G(cmd.Context()).IsStdoutTTY() {
+		return fmt.Errorf("refusing to write tarball to a terminal: use --output instead")
+	}
+
+	return nil
+}
+
+func (opts *SaveOptions) Run(ctx context.Context, args []string) error {
+	refs := make([]string, len(args))
+	for i, arg := range args {
+		ref, err := name.ParseReference(arg,
+			name.WithDefaultRegistry(oci.DefaultRegistry),
+			name.WithDefaultTag(oci.DefaultTag),
+		)
+		if err != nil {
+			return fmt.Errorf("could not parse reference: %w", err)
+		}
+
+		refs[i] = ref.Name()
+	}
+
+	ctx, handle, err := oci.NewHandlerFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(opts.Output) == 0 || opts.Output == "-" {
+		return layout.Save(ctx, handle, iostreams.G(ctx).Out, refs...)
+	}
+
+	// Write to a temporary file first such that an existing tarball is not left
+	// truncated should saving fail.
+	tmp, err := os.CreateTemp(filepath.Dir(opts.Output), ".kraftkit-save-*")
+	if err != nil {
+		return fmt.Errorf("could not create tarball: %w", err)
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if err := layout.Save(ctx, handle, tmp, refs...); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("could not write tarball: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), opts.Output); err != nil {
+		return fmt.Errorf("could not write tarball: %w", err)
+	}
+
+	log.G(ctx).
+		WithField("output", opts.Output).
+		Info("saved")
+
+	return nil
+}
@@ -0,0 +1,95 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"kraftkit.sh/packmanager"
+	"kraftkit.sh/sbom"
+)
+
+// packSBOM generates the software bill of materials of the project, which
+// lists its Unikraft core, libraries and the files of the provided initrd,
+// and returns the option which attaches it to the package.  No option is
+// returned if no SBOM has been requested.
+func (opts *PkgOptions) packSBOM(ctx context.Context, initrd string) (packmanager.PackOption, error) {
+	if !opts.SBOM {
+		return nil, nil
+	}
+
+	doc := sbom.Document{
+		Created: time.Now().UTC(),
+	}
+
+	if opts.Project == nil {
+		doc.Components = append(doc.Components, sbom.Component{
+			Type: sbom.ComponentTypeApplication,
+			Name: opts.Name,
+		})
+	} else {
+		doc.Components = append(doc.Components, sbom.Component{
+			Type:     sbom.ComponentTypeApplication,
+			Name:     opts.Project.Name(),
+			Version:  opts.Project.Version(),
+			Source:   opts.Project.Source(),
+			Revision: sbom.Revision(opts.Project.WorkingDir()),
+		})
+
+		if core := opts.Project.Unikraft(ctx); core != nil {
+			doc.Components = append(doc.Components, sbom.Component{
+				Type:     sbom.ComponentTypeCore,
+				Name:     core.Name(),
+				Version:  core.Version(),
+				License:  core.License(),
+				Source:   core.Source(),
+				Revision: sbom.Revision(core.Path()),
+			})
+		}
+
+		libraries, err := opts.Project.Libraries(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("could not resolve libraries: %w", err)
+		}
+
+		names := make([]string, 0, len(libraries))
+		for name := range libraries {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		for _, name := range names {
+			library := libraries[name]
+			doc.Components = append(doc.Components, sbom.Component{
+				Type:     sbom.ComponentTypeLibrary,
+				Name:     library.Name(),
+				Version:  library.Version(),
+				License:  library.License(),
+				Source:   library.Source(),
+				Revision: sbom.Revision(library.Path()),
+			})
+		}
+	}
+
+	if len(initrd) > 0 {
+		if err := doc.AddInitrd(ctx, initrd); err != nil {
+			return nil, fmt.Errorf("could not list files of initrd: %w", err)
+		}
+	}
+
+	format := sbom.Format(opts.SBOMFormat)
+
+	raw, err := doc.Encode(format)
+	if err != nil {
+		return nil, fmt.Errorf("could not generate sbom: %w", err)
+	}
+
+	return packmanager.PackSBOM(format.MediaType(), raw), nil
+}
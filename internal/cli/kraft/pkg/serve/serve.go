@@ -0,0 +1,140 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package serve
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"kraftkit.sh/cmdfactory"
+	"kraftkit.sh/log"
+	"kraftkit.sh/manifest"
+)
+
+type ServeOptions struct {
+	Dirs   []string `long:"dir" short:"d" usage:"Directory of a component, or of components, to publish (can be specified multiple times)"`
+	Listen string   `long:"listen" short:"l" usage:"Address to listen on" default:":8080"`
+	URL    string   `long:"url" usage:"Base URL at which the index is reachable by others (default derived from the listen address)"`
+}
+
+// Serve publishes local Unikraft components as a manifest index over HTTP.
+func Serve(ctx context.Context, opts *ServeOptions, args ...string) error {
+	if opts == nil {
+		opts = &ServeOptions{}
+	}
+
+	return opts.Run(ctx, args)
+}
+
+func NewCmd() *cobra.Command {
+	cmd, err := cmdfactory.New(&ServeOptions{}, cobra.Command{
+		Short: "Serve local Unikraft components as a manifest index",
+		Use:   "serve [FLAGS] --dir DIR",
+		Args:  cobra.NoArgs,
+		Long: heredoc.Doc(`
+			Serve local Unikraft components as a manifest index over HTTP.
+
+			Each directory is either a component, such as a library, or a directory
+			containing components.  Components which are Git repositories are
+			published with a channel for each of their branches and a version for each
+			of their tags, otherwise with a single default channel.  Each channel and
+			version is served as a tarball whose SHA-256 checksum is listed in the
+			manifest of the component, such that the index can be sourced by others.
+		`),
+		Example: heredoc.Doc(`
+			# Serve all libraries in the ./libs directory
+			$ kraft pkg serve --dir ./libs
+
+			# Serve libraries on a specific address and add the index elsewhere
+			$ kraft pkg serve --dir ./libs --listen 0.0.0.0:8080 --url http://libs.internal:8080
+			$ kraft pkg source http://libs.internal:8080/index.yaml
+		`),
+		Annotations: map[string]string{
+			cmdfactory.AnnotationHelpGroup: "pkg",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+func (opts *ServeOptions) Run(ctx context.Context, _ []string) error {
+	if len(opts.Dirs) == 0 {
+		return fmt.Errorf("must specify at least one directory with --dir")
+	}
+
+	baseURL := opts.URL
+	if len(baseURL) == 0 {
+		host, port, err := net.SplitHostPort(opts.Listen)
+		if err != nil {
+			return fmt.Errorf("could not parse listen address: %w", err)
+		}
+
+		if len(host) == 0 || host == "0.0.0.0" || host == "::" {
+			host = "localhost"
+		}
+
+		baseURL = "http://" + net.JoinHostPort(host, port)
+	}
+
+	workdir, err := os.MkdirTemp("", "kraft-pkg-serve-")
+	if err != nil {
+		return fmt.Errorf("could not make working directory: %w", err)
+	}
+
+	defer os.RemoveAll(workdir)
+
+	handler, err := manifest.NewIndexServer(ctx, baseURL, workdir, opts.Dirs)
+	if err != nil {
+		return fmt.Errorf("could not generate manifest index: %w", err)
+	}
+
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	server := &http.Server{
+		Addr:              opts.Listen,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+
+	log.G(ctx).
+		WithField("url", baseURL+"/"+manifest.IndexServerIndexFile).
+		Info("serving manifest index")
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("could not serve manifest index: %w", err)
+		}
+
+	case <-ctx.Done():
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer shutdownCancel()
+
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			return fmt.Errorf("could not shut down server: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,214 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package sign
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"kraftkit.sh/cmdfactory"
+	"kraftkit.sh/config"
+	"kraftkit.sh/iostreams"
+	"kraftkit.sh/log"
+	"kraftkit.sh/oci"
+	"kraftkit.sh/oci/cosign"
+)
+
+type SignOptions struct {
+	Annotations     []string `long:"annotation" short:"a" usage:"Set an optional key=value pair which is signed alongside the package"`
+	GenerateKeyPair bool     `long:"generate-key-pair" usage:"Generate a new key pair at the location of the key instead of signing"`
+	Key             string   `long:"key" short:"k" usage:"Path to the private key used to sign the package" default:"cosign.key"`
+	Push            bool     `long:"push" short:"P" usage:"Push the signature to the registry of the package"`
+}
+
+// Sign a package.
+func Sign(ctx context.Context, opts *SignOptions, args ...string) error {
+	if opts == nil {
+		opts = &SignOptions{}
+	}
+
+	return opts.Run(ctx, args)
+}
+
+func NewCmd() *cobra.Command {
+	cmd, err := cmdfactory.New(&SignOptions{}, cobra.Command{
+		Short: "Sign a package",
+		Use:   "sign [FLAGS] PACKAGE",
+		Args:  cobra.MaximumNArgs(1),
+		Long: heredoc.Doc(`
+			Sign a package with a private key.
+
+			Signatures are compatible with Sigstore's cosign and are stored as OCI
+			referrers of the package's index such that they accompany the package
+			when it is pushed.  The password of the private key is read from the
+			COSIGN_PASSWORD environmental variable or is otherwise prompted for.
+
+			Packages are verified when they are pulled if a signature policy is set
+			via the 'signature_policy' configuration option.  Only key pairs are
+			supported: keyless signing and verification with certificates issued by
+			Sigstore's Fulcio are not.
+		`),
+		Example: heredoc.Doc(`
+			# Generate a new key pair at cosign.key and cosign.pub
+			$ kraft pkg sign --generate-key-pair
+
+			# Sign a package with the key pair in the current directory
+			$ kraft pkg sign unikraft.org/helloworld:latest
+
+			# Sign a package with an alternative key and push the signature
+			$ kraft pkg sign --key /path/to/acme.key --push unikraft.org/helloworld:latest
+
+			# Sign a package alongside additional information
+			$ kraft pkg sign -a commit=8f3c2a1 unikraft.org/helloworld:latest
+		`),
+		Annotations: map[string]string{
+			cmdfactory.AnnotationHelpGroup: "pkg",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+func (opts *SignOptions) Pre(cmd *cobra.Command, args []string) error {
+	if !opts.GenerateKeyPair && len(args) == 0 {
+		return fmt.Errorf("no package provided")
+	}
+
+	return nil
+}
+
+// password returns the password of the private key, which is either read from
+// the environment or prompted for.
+func (opts *SignOptions) password(ctx context.Context) ([]byte, error) {
+	if password, ok := os.LookupEnv("COSIGN_PASSWORD"); ok {
+		return []byte(password), nil
+	}
+
+	if config.G[config.KraftKit](ctx).NoPrompt {
+		return nil, fmt.Errorf("cannot prompt for password: set COSIGN_PASSWORD instead")
+	}
+
+	fmt.Fprintf(iostreams.G(ctx).ErrOut, "Enter password for private key: ")
+
+	password, err := term.ReadPassword(int(iostreams.G(ctx).In.Fd()))
+	if err != nil {
+		return nil, fmt.Errorf("could not read password: %w", err)
+	}
+
+	fmt.Fprint(iostreams.G(ctx).ErrOut, "\n")
+
+	return password, nil
+}
+
+func (opts *SignOptions) Run(ctx context.Context, args []string) error {
+	password, err := opts.password(ctx)
+	if err != nil {
+		return err
+	}
+
+	if opts.GenerateKeyPair {
+		return opts.generateKeyPair(ctx, password)
+	}
+
+	ref, err := name.ParseReference(args[0],
+		name.WithDefaultRegistry(oci.DefaultRegistry),
+		name.WithDefaultTag(oci.DefaultTag),
+	)
+	if err != nil {
+		return fmt.Errorf("could not parse reference: %w", err)
+	}
+
+	signer, err := cosign.LoadPrivateKeyFromFile(opts.Key, password)
+	if err != nil {
+		return err
+	}
+
+	var optional map[string]interface{}
+	for _, annotation := range opts.Annotations {
+		k, v, ok := strings.Cut(annotation, "=")
+		if !ok {
+			return fmt.Errorf("invalid annotation '%s': expected key=value", annotation)
+		}
+
+		if optional == nil {
+			optional = make(map[string]interface{})
+		}
+
+		optional[k] = v
+	}
+
+	ctx, handle, err := oci.NewHandlerFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	subject, err := cosign.ResolveSubject(ctx, handle, ref)
+	if err != nil {
+		return err
+	}
+
+	desc, err := cosign.Sign(ctx, handle, ref.Context().Name(), subject, signer, optional)
+	if err != nil {
+		return err
+	}
+
+	log.G(ctx).
+		WithField("subject", subject.Digest.String()).
+		WithField("signature", desc.Digest.String()).
+		Info("signed")
+
+	if opts.Push {
+		fullref := fmt.Sprintf("%s@%s", ref.Context().Name(), desc.Digest.String())
+		if err := handle.PushDescriptor(ctx, fullref, desc); err != nil {
+			return fmt.Errorf("could not push signature: %w", err)
+		}
+	}
+
+	fmt.Fprintf(iostreams.G(ctx).Out, "%s\n", desc.Digest.String())
+
+	return nil
+}
+
+// generateKeyPair writes a new key pair to the location of the key, with the
+// public key placed alongside it.
+func (opts *SignOptions) generateKeyPair(ctx context.Context, password []byte) error {
+	pubPath := strings.TrimSuffix(opts.Key, ".key") + ".pub"
+
+	for _, path := range []string{opts.Key, pubPath} {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("refusing to overwrite existing key: %s", path)
+		}
+	}
+
+	privPEM, pubPEM, err := cosign.GenerateKeyPair(password)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(opts.Key, privPEM, 0o600); err != nil {
+		return fmt.Errorf("could not write private key: %w", err)
+	}
+
+	if err := os.WriteFile(pubPath, pubPEM, 0o644); err != nil {
+		return fmt.Errorf("could not write public key: %w", err)
+	}
+
+	log.G(ctx).
+		WithField("private", opts.Key).
+		WithField("public", pubPath).
+		Info("generated key pair")
+
+	return nil
+}
@@ -0,0 +1,67 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"kraftkit.sh/packmanager"
+)
+
+// packSources returns the options which include the library objects, the
+// intermediate objects and the source files of the project in the package.
+func (opts *PkgOptions) packSources(ctx context.Context) ([]packmanager.PackOption, error) {
+	popts := []packmanager.PackOption{
+		packmanager.PackKernelLibraryObjects(opts.WithLibObjects),
+		packmanager.PackKernelLibraryIntermediateObjects(opts.WithIntermediateObjects),
+		packmanager.PackKernelSourceFiles(opts.WithKernelSources),
+		packmanager.PackAppSourceFiles(opts.WithAppSources),
+	}
+
+	if !opts.WithKernelSources && !opts.WithAppSources {
+		return popts, nil
+	}
+
+	if opts.Project == nil {
+		return nil, fmt.Errorf("cannot include source files without a project")
+	}
+
+	if opts.WithAppSources {
+		popts = append(popts, packmanager.PackAppSourceDir(opts.Project.WorkingDir()))
+	}
+
+	if opts.WithKernelSources {
+		var dirs []string
+
+		if core := opts.Project.Unikraft(ctx); core != nil && len(core.Path()) > 0 {
+			dirs = append(dirs, core.Path())
+		}
+
+		libraries, err := opts.Project.Libraries(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("could not resolve libraries: %w", err)
+		}
+
+		names := make([]string, 0, len(libraries))
+		for name := range libraries {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		for _, name := range names {
+			if path := libraries[name].Path(); len(path) > 0 {
+				dirs = append(dirs, path)
+			}
+		}
+
+		popts = append(popts, packmanager.PackKernelSourceDirs(dirs...))
+	}
+
+	return popts, nil
+}
@@ -0,0 +1,73 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package tag
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"kraftkit.sh/cmdfactory"
+	"kraftkit.sh/iostreams"
+	"kraftkit.sh/oci"
+)
+
+type TagOptions struct{}
+
+// Tag a package with an additional name.
+func Tag(ctx context.Context, opts *TagOptions, args ...string) error {
+	if opts == nil {
+		opts = &TagOptions{}
+	}
+
+	return opts.Run(ctx, args)
+}
+
+func NewCmd() *cobra.Command {
+	cmd, err := cmdfactory.New(&TagOptions{}, cobra.Command{
+		Short: "Tag a package with an additional name",
+		Use:   "tag [FLAGS] SOURCE TARGET",
+		Args:  cobra.ExactArgs(2),
+		Long: heredoc.Doc(`
+			Create the package TARGET in the local package store which refers to the
+			same targets as the existing package SOURCE.
+
+			No targets are copied and the source package remains unchanged.
+		`),
+		Example: heredoc.Doc(`
+			# Tag the latest nginx package with a version
+			$ kraft pkg tag unikraft.org/nginx:latest unikraft.org/nginx:1.25
+
+			# Tag a package such that it can be pushed to another registry
+			$ kraft pkg tag unikraft.org/nginx:latest ghcr.io/acme/nginx:latest
+		`),
+		Annotations: map[string]string{
+			cmdfactory.AnnotationHelpGroup: "pkg",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+func (opts *TagOptions) Run(ctx context.Context, args []string) error {
+	ctx, handle, err := oci.NewHandlerFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	desc, err := oci.Tag(ctx, handle, args[0], args[1])
+	if err != nil {
+		return fmt.Errorf("could not tag %s: %w", args[0], err)
+	}
+
+	fmt.Fprintf(iostreams.G(ctx).Out, "%s\n", desc.Digest.String())
+
+	return nil
+}
@@ -97,6 +97,7 @@ type PsEntry struct {
 	Arch    string
 	Plat    string
 	IPs     []string
+	Service string
 }
 
 type colorFunc func(string) string
@@ -226,10 +227,22 @@ func (opts *PsOptions) PrintPsTable(ctx context.Context, items []PsEntry) error
 		return err
 	}
 
+	// Only entries of compose projects belong to a service.
+	services := false
+	for _, item := range items {
+		if len(item.Service) > 0 {
+			services = true
+			break
+		}
+	}
+
 	// Header row
 	if opts.Long {
 		table.AddField("MACHINE ID", cs.Bold)
 	}
+	if services {
+		table.AddField("SERVICE", cs.Bold)
+	}
 	table.AddField("NAME", cs.Bold)
 	table.AddField("KERNEL", cs.Bold)
 	table.AddField("ARGS", cs.Bold)
@@ -255,6 +268,9 @@ func (opts *PsOptions) PrintPsTable(ctx context.Context, items []PsEntry) error
 		if opts.Long {
 			table.AddField(item.ID, nil)
 		}
+		if services {
+			table.AddField(item.Service, nil)
+		}
 		table.AddField(item.Name, nil)
 		table.AddField(item.Kernel, nil)
 		table.AddField(item.Args, nil)
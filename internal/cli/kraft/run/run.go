@@ -6,8 +6,11 @@ package run
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"path/filepath"
+	"time"
 
 	"github.com/MakeNowJust/heredoc"
 	"github.com/sirupsen/logrus"
@@ -19,6 +22,8 @@ import (
 	machineapi "kraftkit.sh/api/machine/v1alpha1"
 	"kraftkit.sh/cmdfactory"
 	"kraftkit.sh/config"
+	"kraftkit.sh/internal/activator"
+	"kraftkit.sh/internal/cli/kraft/ingress"
 	"kraftkit.sh/internal/cli/kraft/start"
 	"kraftkit.sh/internal/set"
 	"kraftkit.sh/iostreams"
@@ -30,30 +35,39 @@ import (
 )
 
 type RunOptions struct {
-	Architecture  string   `long:"arch" short:"m" usage:"Set the architecture"`
-	Detach        bool     `long:"detach" short:"d" usage:"Run unikernel in background"`
-	DisableAccel  bool     `long:"disable-acceleration" short:"W" usage:"Disable acceleration of CPU (usually enables TCG)"`
-	Env           []string `long:"env" short:"e" usage:"Set environment variables, int the format key[=value]"`
-	InitRd        string   `long:"initrd" usage:"Use the specified initrd (readonly)" hidden:"true"`
-	IP            string   `long:"ip" usage:"Assign the provided IP address"`
-	KernelArgs    []string `long:"kernel-arg" short:"a" usage:"Set additional kernel arguments"`
-	Kraftfile     string   `long:"kraftfile" short:"K" usage:"Set an alternative path of the Kraftfile"`
-	MacAddress    string   `long:"mac" usage:"Assign the provided MAC address"`
-	Memory        string   `long:"memory" short:"M" usage:"Assign memory to the unikernel (K/Ki, M/Mi, G/Gi)" default:"64Mi"`
-	Name          string   `long:"name" short:"n" usage:"Name of the instance"`
-	Networks      []string `long:"network" usage:"Attach instance to the provided network, in the format <network>[:ip[/mask][:gw[:dns0[:dns1[:hostname[:domain]]]]]], e.g. kraft0:172.100.0.2"`
-	NoStart       bool     `long:"no-start" usage:"Do not start the machine"`
-	Platform      string   `noattribute:"true"`
-	Ports         []string `long:"port" short:"p" usage:"Publish a machine's port(s) to the host" split:"false"`
-	Prefix        string   `long:"prefix" usage:"Prefix each log line with the given string"`
-	PrefixName    bool     `long:"prefix-name" usage:"Prefix each log line with the machine name"`
-	Remove        bool     `long:"rm" usage:"Automatically remove the unikernel when it shutsdown"`
-	Rootfs        string   `long:"rootfs" usage:"Specify a path to use as root file system (can be volume or initramfs)"`
-	RunAs         string   `long:"as" usage:"Force a specific runner"`
-	Runtime       string   `long:"runtime" short:"r" usage:"Set an alternative unikernel runtime"`
-	Target        string   `long:"target" short:"t" usage:"Explicitly use the defined project target"`
-	Volumes       []string `long:"volume" short:"v" usage:"Bind a volume to the instance"`
-	WithKernelDbg bool     `long:"symbolic" usage:"Use the debuggable (symbolic) unikernel"`
+	AddHosts             []string      `long:"add-host" usage:"Add a host-to-IP mapping to the hosts file of the root file system, in the format host=ip"`
+	Architecture         string        `long:"arch" short:"m" usage:"Set the architecture"`
+	CPUs                 uint          `long:"cpus" usage:"Assign the number of vCPUs to the unikernel"`
+	Certificate          string        `long:"certificate" usage:"Path to a PEM file with the certificate chain and private key of the domain names"`
+	Cooldown             time.Duration `long:"cooldown" usage:"Idle period after which the unikernel is scaled to zero (ms/s/m/h)" default:"30s"`
+	Detach               bool          `long:"detach" short:"d" usage:"Run unikernel in background"`
+	DisableAccel         bool          `long:"disable-acceleration" short:"W" usage:"Disable acceleration of CPU (usually enables TCG)"`
+	Domain               []string      `long:"domain" usage:"Route the domain name to the unikernel through the local ingress"`
+	Env                  []string      `long:"env" short:"e" usage:"Set environment variables, int the format key[=value]"`
+	InitRd               string        `long:"initrd" usage:"Use the specified initrd (readonly)" hidden:"true"`
+	IP                   string        `long:"ip" usage:"Assign the provided IP address"`
+	KernelArgs           []string      `long:"kernel-arg" short:"a" usage:"Set additional kernel arguments"`
+	Kraftfile            string        `long:"kraftfile" short:"K" usage:"Set an alternative path of the Kraftfile"`
+	Labels               []string      `long:"label" usage:"Set labels on the instance, in the format key=value"`
+	MacAddress           string        `long:"mac" usage:"Assign the provided MAC address"`
+	Memory               string        `long:"memory" short:"M" usage:"Assign memory to the unikernel (K/Ki, M/Mi, G/Gi)" default:"64Mi"`
+	Name                 string        `long:"name" short:"n" usage:"Name of the instance"`
+	Networks             []string      `long:"network" usage:"Attach instance to the provided network, in the format <network>[:ip[/mask][:gw[:dns0[:dns1[:hostname[:domain]]]]]], e.g. kraft0:172.100.0.2"`
+	NoStart              bool          `long:"no-start" usage:"Do not start the machine"`
+	Platform             string        `noattribute:"true"`
+	Ports                []string      `long:"port" short:"p" usage:"Publish a machine's port(s) to the host" split:"false"`
+	Prefix               string        `long:"prefix" usage:"Prefix each log line with the given string"`
+	PrefixName           bool          `long:"prefix-name" usage:"Prefix each log line with the machine name"`
+	Remove               bool          `long:"rm" usage:"Automatically remove the unikernel when it shutsdown"`
+	Rootfs               string        `long:"rootfs" usage:"Specify a path to use as root file system (can be volume or initramfs)"`
+	RunAs                string        `long:"as" usage:"Force a specific runner"`
+	Runtime              string        `long:"runtime" short:"r" usage:"Set an alternative unikernel runtime"`
+	ScaleToZero          bool          `long:"scale-to-zero" usage:"Start the unikernel upon the first connection to its published ports and pause it once idle"`
+	ScaleToZeroStateless bool          `long:"scale-to-zero-stateless" usage:"Stop instead of pause the unikernel when scaling to zero"`
+	Target               string        `long:"target" short:"t" usage:"Explicitly use the defined project target"`
+	Tmpfs                []string      `long:"tmpfs" usage:"Mount an in-memory file system at the provided path in the unikernel"`
+	Volumes              []string      `long:"volume" short:"v" usage:"Bind a volume to the instance"`
+	WithKernelDbg        bool          `long:"symbolic" usage:"Use the debuggable (symbolic) unikernel"`
 
 	workdir           string
 	platform          mplatform.Platform
@@ -108,12 +122,24 @@ func NewCmd() *cobra.Command {
 			Supply a path which is dynamically serialized into an initramfs CPIO archive:
 			$ kraft run --rootfs ./path/to/rootfs
 
+			Mount an in-memory file system at /tmp in the unikernel:
+			$ kraft run --tmpfs /tmp unikraft.org/nginx:latest
+
+			Run an OCI-compatible unikernel with 2 vCPUs and a label:
+			$ kraft run --cpus 2 --label env=staging unikraft.org/nginx:latest
+
 			Mount a bi-directional path from on the host to the unikernel mapped to /dir:
 			$ kraft run -v ./path/to/dir:/dir
 
 			Supply a read-only root file system at / via initramfs CPIO archive and mount a bi-directional volume at /dir:
 			$ kraft run --rootfs ./initramfs.cpio --volume ./path/to/dir:/dir
 
+			Start an OCI-compatible unikernel upon the first connection to port 8080 and pause it after 30 seconds without connections:
+			$ kraft run --scale-to-zero --cooldown 30s -p 8080:80 unikraft.org/nginx:latest
+
+			Serve an OCI-compatible unikernel at http://app.localhost:8000 and https://app.localhost:8443 through the local ingress:
+			$ kraft run --domain app.localhost -p 8080:80 unikraft.org/nginx:latest
+
 			Customize the default content directory of the official Unikraft NGINX OCI-compatible unikernel and map port 8080 to localhost:
 			$ kraft run -v ./path/to/html:/nginx/html -p 8080:80 unikraft.org/nginx:latest
 		`),
@@ -181,6 +207,51 @@ func (opts *RunOptions) Pre(cmd *cobra.Command, _ []string) error {
 		}
 	}
 
+	if opts.ScaleToZero {
+		if len(opts.Ports) == 0 {
+			return fmt.Errorf("the --scale-to-zero flag requires at least one published port")
+		}
+
+		if opts.Detach || opts.NoStart {
+			return fmt.Errorf("the --scale-to-zero flag cannot be used with --detach or --no-start as kraft holds the published ports in the foreground")
+		}
+
+		if opts.Cooldown <= 0 {
+			return fmt.Errorf("the --cooldown flag must be positive")
+		}
+	} else if opts.ScaleToZeroStateless {
+		return fmt.Errorf("the --scale-to-zero-stateless flag requires --scale-to-zero")
+	}
+
+	// The platform is only known here if it has been set explicitly, otherwise
+	// it is checked again once it has been detected.
+	if plat, ok := mplatform.PlatformsByName()[opts.Platform]; ok {
+		if err := opts.checkScaleToZero(plat); err != nil {
+			return err
+		}
+	}
+
+	if len(opts.Domain) > 0 && opts.ScaleToZero {
+		return fmt.Errorf("the --domain flag cannot be used with --scale-to-zero")
+	}
+
+	if opts.Certificate != "" {
+		if len(opts.Domain) == 0 {
+			return fmt.Errorf("the --certificate flag requires --domain")
+		}
+
+		if _, err := tls.LoadX509KeyPair(opts.Certificate, opts.Certificate); err != nil {
+			return fmt.Errorf("could not load certificate: %w", err)
+		}
+
+		certificate, err := filepath.Abs(opts.Certificate)
+		if err != nil {
+			return err
+		}
+
+		opts.Certificate = certificate
+	}
+
 	if opts.Memory != "" {
 		qty, err := resource.ParseQuantity(opts.Memory)
 		if err != nil {
@@ -249,6 +320,10 @@ func (opts *RunOptions) Run(ctx context.Context, args []string) error {
 		return err
 	}
 
+	if err := opts.checkScaleToZero(opts.platform); err != nil {
+		return err
+	}
+
 	if len(opts.Architecture) > 0 {
 		if _, found := ukarch.ArchitecturesByName()[opts.Architecture]; !found {
 			log.G(ctx).WithFields(logrus.Fields{
@@ -274,6 +349,16 @@ func (opts *RunOptions) Run(ctx context.Context, args []string) error {
 		return err
 	}
 
+	// When scaling to zero, kraft itself listens on the published ports such
+	// that the machine only needs to run once a connection is made.
+	var bindings []activator.ActivatorOption
+	if opts.ScaleToZero {
+		bindings, err = opts.holdPorts(ctx, machine)
+		if err != nil {
+			return err
+		}
+	}
+
 	var run runner
 	var errs []error
 	runners, err := runners()
@@ -350,6 +435,14 @@ func (opts *RunOptions) Run(ctx context.Context, args []string) error {
 		machine.Spec.Resources.Requests[corev1.ResourceMemory] = quantity
 	}
 
+	if opts.CPUs > 0 {
+		machine.Spec.Resources.Requests[corev1.ResourceCPU] = *resource.NewQuantity(int64(opts.CPUs), resource.DecimalSI)
+	}
+
+	if err := opts.parseLabels(ctx, machine); err != nil {
+		return err
+	}
+
 	if err := opts.parseNetworks(ctx, machine); err != nil {
 		return err
 	}
@@ -362,20 +455,41 @@ func (opts *RunOptions) Run(ctx context.Context, args []string) error {
 		return err
 	}
 
+	if err := opts.parseTmpfs(ctx, machine); err != nil {
+		return err
+	}
+
 	if err := opts.prepareRootfs(ctx, machine); err != nil {
 		return err
 	}
 
+	if err := opts.addHosts(ctx, machine); err != nil {
+		return err
+	}
+
 	if err := opts.parseEnvs(ctx, machine); err != nil {
 		return err
 	}
 
+	// Make sure the local ingress routes the domain names of the machine,
+	// which may also have been set through its labels.  The machine is not
+	// created if its domain names cannot be served.
+	if len(machine.Labels[ingress.LabelDomain]) > 0 {
+		if err := ingress.Start(ctx); err != nil {
+			return fmt.Errorf("could not start local ingress: %w", err)
+		}
+	}
+
 	// Create the machine
 	machine, err = opts.machineController.Create(ctx, machine)
 	if err != nil {
 		return err
 	}
 
+	if opts.ScaleToZero {
+		return opts.scaleToZero(ctx, machine, bindings)
+	}
+
 	if opts.NoStart {
 		// Output the name of the instance such that it can be piped
 		fmt.Fprintf(iostreams.G(ctx).Out, "%s\n", machine.Name)
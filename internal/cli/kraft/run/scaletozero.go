@@ -0,0 +1,172 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package run
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+
+	corev1 "k8s.io/api/core/v1"
+
+	machineapi "kraftkit.sh/api/machine/v1alpha1"
+	"kraftkit.sh/internal/activator"
+	"kraftkit.sh/internal/netutil"
+	"kraftkit.sh/iostreams"
+	"kraftkit.sh/log"
+	mplatform "kraftkit.sh/machine/platform"
+)
+
+// pausablePlatforms are the platforms whose machines can be paused and later
+// resumed, which is required to scale to zero without losing their state.
+var pausablePlatforms = []mplatform.Platform{
+	mplatform.PlatformQEMU,
+	mplatform.PlatformFirecracker,
+}
+
+// checkScaleToZero returns an error if the machine is scaled to zero on the
+// provided platform without being stateless but the platform cannot pause it.
+func (opts *RunOptions) checkScaleToZero(plat mplatform.Platform) error {
+	if !opts.ScaleToZero || opts.ScaleToZeroStateless || slices.Contains(pausablePlatforms, plat) {
+		return nil
+	}
+
+	return fmt.Errorf("the %s platform cannot pause unikernels: use --scale-to-zero-stateless to stop them when scaling to zero instead", plat)
+}
+
+// holdPorts listens on the host ports published by the machine on its behalf.
+// The machine itself instead publishes each port on a free port of the
+// loopback interface to which the connections accepted by kraft are
+// forwarded.
+func (opts *RunOptions) holdPorts(ctx context.Context, machine *machineapi.Machine) ([]activator.ActivatorOption, error) {
+	var bindings []activator.ActivatorOption
+	var listeners []net.Listener
+
+	closeAll := func() {
+		for _, listener := range listeners {
+			listener.Close()
+		}
+	}
+
+	for i, port := range machine.Spec.Ports {
+		if port.Protocol != "" && !strings.EqualFold(string(port.Protocol), string(corev1.ProtocolTCP)) {
+			closeAll()
+			return nil, fmt.Errorf("cannot scale to zero with %s port %d: only tcp ports are supported", port.Protocol, port.MachinePort)
+		}
+
+		address := net.JoinHostPort(port.HostIP, strconv.Itoa(int(port.HostPort)))
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("could not listen on %s: %w", address, err)
+		}
+
+		listeners = append(listeners, listener)
+
+		backend, err := netutil.FreeLoopbackPort()
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+
+		machine.Spec.Ports[i].HostIP = "127.0.0.1"
+		machine.Spec.Ports[i].HostPort = int32(backend)
+
+		log.G(ctx).
+			WithField("listen", listener.Addr().String()).
+			WithField("backend", backend).
+			Debug("holding port")
+
+		bindings = append(bindings, activator.WithListener(
+			listener,
+			net.JoinHostPort("127.0.0.1", strconv.Itoa(backend)),
+		))
+	}
+
+	return bindings, nil
+}
+
+// scaleToZero serves the held ports of the machine in the foreground, starting
+// or resuming it upon the first connection and pausing or stopping it once it
+// has been idle for the cooldown period.  The machine is stopped, and removed
+// if requested, when interrupted.
+func (opts *RunOptions) scaleToZero(ctx context.Context, machine *machineapi.Machine, bindings []activator.ActivatorOption) error {
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	act, err := activator.New(append(bindings,
+		activator.WithCooldown(opts.Cooldown),
+		activator.WithWake(func(ctx context.Context) error {
+			started, err := opts.machineController.Start(ctx, machine)
+			if err != nil {
+				return err
+			}
+
+			machine = started
+			return nil
+		}),
+		activator.WithSleep(func(ctx context.Context) error {
+			slept, err := opts.sleep(ctx, machine)
+			if err != nil {
+				return err
+			}
+
+			machine = slept
+			return nil
+		}),
+	)...)
+	if err != nil {
+		return err
+	}
+
+	// Output the name of the instance such that it can be referenced whilst
+	// kraft holds its ports.
+	fmt.Fprintf(iostreams.G(ctx).Out, "%s\n", machine.Name)
+
+	log.G(ctx).
+		WithField("machine", machine.Name).
+		WithField("ports", strings.Join(opts.Ports, ", ")).
+		Info("waiting for connections")
+
+	serveErr := act.Serve(ctx)
+
+	// The context is cancelled at this point, but the machine must still be
+	// cleaned up.
+	ctx = context.WithoutCancel(ctx)
+
+	var errs []error
+	if serveErr != nil {
+		errs = append(errs, serveErr)
+	}
+
+	if _, err := opts.machineController.Stop(ctx, machine); err != nil {
+		errs = append(errs, fmt.Errorf("could not stop: %w", err))
+	}
+
+	if opts.Remove {
+		if _, err := opts.machineController.Delete(ctx, machine); err != nil {
+			errs = append(errs, fmt.Errorf("could not remove: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// sleep scales the machine to zero once it has been idle, by stopping it if
+// it is stateless or by pausing it otherwise.
+func (opts *RunOptions) sleep(ctx context.Context, machine *machineapi.Machine) (*machineapi.Machine, error) {
+	if opts.ScaleToZeroStateless {
+		return opts.machineController.Stop(ctx, machine)
+	}
+
+	return opts.machineController.Pause(ctx, machine)
+}
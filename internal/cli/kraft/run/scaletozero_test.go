@@ -0,0 +1,107 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package run
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	machineapi "kraftkit.sh/api/machine/v1alpha1"
+	mplatform "kraftkit.sh/machine/platform"
+)
+
+// fakeMachineService is a machine service which can only stop machines, and
+// pause them if it is pausable.
+type fakeMachineService struct {
+	machineapi.MachineService
+	pausable bool
+}
+
+func (service *fakeMachineService) Pause(_ context.Context, machine *machineapi.Machine) (*machineapi.Machine, error) {
+	if !service.pausable {
+		return machine, errors.ErrUnsupported
+	}
+
+	machine.Status.State = machineapi.MachineStatePaused
+
+	return machine, nil
+}
+
+func (service *fakeMachineService) Stop(_ context.Context, machine *machineapi.Machine) (*machineapi.Machine, error) {
+	machine.Status.State = machineapi.MachineStateExited
+
+	return machine, nil
+}
+
+func TestScaleToZeroSleep(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		platform  mplatform.Platform
+		stateless bool
+		rejected  bool
+		expected  machineapi.MachineState
+	}{
+		{
+			name:     "qemu pauses",
+			platform: mplatform.PlatformQEMU,
+			expected: machineapi.MachineStatePaused,
+		},
+		{
+			name:     "firecracker pauses",
+			platform: mplatform.PlatformFirecracker,
+			expected: machineapi.MachineStatePaused,
+		},
+		{
+			name:      "qemu stops when stateless",
+			platform:  mplatform.PlatformQEMU,
+			stateless: true,
+			expected:  machineapi.MachineStateExited,
+		},
+		{
+			name:     "unpausable platform is rejected",
+			platform: mplatform.PlatformXen,
+			rejected: true,
+		},
+		{
+			name:      "unpausable platform stops when stateless",
+			platform:  mplatform.PlatformXen,
+			stateless: true,
+			expected:  machineapi.MachineStateExited,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := &RunOptions{
+				ScaleToZero:          true,
+				ScaleToZeroStateless: tc.stateless,
+				machineController: &fakeMachineService{
+					pausable: tc.platform != mplatform.PlatformXen,
+				},
+			}
+
+			if err := opts.checkScaleToZero(tc.platform); err != nil {
+				if !tc.rejected {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				return
+			} else if tc.rejected {
+				t.Fatal("expected scaling to zero to be rejected")
+			}
+
+			machine := &machineapi.Machine{}
+			machine.Status.State = machineapi.MachineStateRunning
+
+			slept, err := opts.sleep(context.Background(), machine)
+			if err != nil {
+				t.Fatalf("could not scale to zero: %v", err)
+			}
+
+			if slept.Status.State != tc.expected {
+				t.Errorf("expected machine to be %s, got %s", tc.expected, slept.Status.State)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package run
 import (
 	"context"
 	"fmt"
+	"io/fs"
 	"net"
 	"os"
 	"path/filepath"
@@ -17,6 +18,7 @@ import (
 	volumeapi "kraftkit.sh/api/volume/v1alpha1"
 	"kraftkit.sh/config"
 	"kraftkit.sh/initrd"
+	"kraftkit.sh/internal/cli/kraft/ingress"
 	"kraftkit.sh/internal/cli/kraft/utils"
 	"kraftkit.sh/log"
 	machinename "kraftkit.sh/machine/name"
@@ -423,6 +425,40 @@ func (opts *RunOptions) prepareRootfs(ctx context.Context, machine *machineapi.M
 	return treemodel.Start()
 }
 
+// Were additional hosts specified? E.g. --add-host db=10.0.0.2.  The entries
+// are written to the hosts file of a copy of the root file system which is kept
+// in the state directory of the machine.
+func (opts *RunOptions) addHosts(ctx context.Context, machine *machineapi.Machine) error {
+	if len(opts.AddHosts) == 0 {
+		return nil
+	}
+
+	if machine.Status.InitrdPath == "" {
+		return fmt.Errorf("the --add-host flag requires a root file system, e.g. --rootfs")
+	}
+
+	if machine.ObjectMeta.UID == "" {
+		machine.ObjectMeta.UID = uuid.NewUUID()
+	}
+
+	if machine.Status.StateDir == "" {
+		machine.Status.StateDir = filepath.Join(config.G[config.KraftKit](ctx).RuntimeDir, string(machine.ObjectMeta.UID))
+	}
+
+	if err := os.MkdirAll(machine.Status.StateDir, fs.ModeSetgid|0o775); err != nil {
+		return err
+	}
+
+	output := filepath.Join(machine.Status.StateDir, "initramfs-hosts.cpio")
+	if err := initrd.AddHosts(machine.Status.InitrdPath, output, strutil.DedupeStrSlice(opts.AddHosts)); err != nil {
+		return fmt.Errorf("could not add hosts to the root file system: %w", err)
+	}
+
+	machine.Status.InitrdPath = output
+
+	return nil
+}
+
 func (opts *RunOptions) parseKraftfileEnv(_ context.Context, project app.Application, machine *machineapi.Machine) error {
 	if project.Env() == nil {
 		return nil
@@ -462,3 +498,55 @@ func (opts *RunOptions) parseEnvs(_ context.Context, machine *machineapi.Machine
 
 	return nil
 }
+
+// Were labels specified? E.g. --label key=value
+func (opts *RunOptions) parseLabels(_ context.Context, machine *machineapi.Machine) error {
+	if len(opts.Labels) == 0 && len(opts.Domain) == 0 {
+		return nil
+	}
+
+	if machine.ObjectMeta.Labels == nil {
+		machine.ObjectMeta.Labels = make(map[string]string, len(opts.Labels))
+	}
+
+	for _, label := range opts.Labels {
+		k, v, _ := strings.Cut(label, "=")
+		if len(k) == 0 {
+			return fmt.Errorf("invalid syntax for --label=%s expected --label=<key>=<value>", label)
+		}
+
+		machine.ObjectMeta.Labels[k] = v
+	}
+
+	// Domain names are routed by the local ingress, e.g. --domain app.localhost
+	if len(opts.Domain) > 0 {
+		machine.ObjectMeta.Labels[ingress.LabelDomain] = strings.Join(opts.Domain, ",")
+	}
+
+	if len(opts.Certificate) > 0 {
+		machine.ObjectMeta.Labels[ingress.LabelCertificate] = opts.Certificate
+	}
+
+	return nil
+}
+
+// Were in-memory file systems requested? E.g. --tmpfs /tmp
+func (opts *RunOptions) parseTmpfs(_ context.Context, machine *machineapi.Machine) error {
+	for _, path := range strutil.DedupeStrSlice(opts.Tmpfs) {
+		if !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("invalid syntax for --tmpfs=%s expected an absolute path", path)
+		}
+
+		machine.Spec.Volumes = append(machine.Spec.Volumes, volumeapi.Volume{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: fmt.Sprintf("%s-tmpfs%d", machine.ObjectMeta.Name, len(machine.Spec.Volumes)),
+			},
+			Spec: volumeapi.VolumeSpec{
+				Driver:      "ramfs",
+				Destination: path,
+			},
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,306 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+package df
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+
+	machineapi "kraftkit.sh/api/machine/v1alpha1"
+	volumeapi "kraftkit.sh/api/volume/v1alpha1"
+	"kraftkit.sh/cmdfactory"
+	"kraftkit.sh/internal/tableprinter"
+	"kraftkit.sh/iostreams"
+	"kraftkit.sh/log"
+	mplatform "kraftkit.sh/machine/platform"
+	"kraftkit.sh/machine/volume"
+	"kraftkit.sh/oci"
+	"kraftkit.sh/oci/handler"
+	"kraftkit.sh/unikraft"
+)
+
+type DfOptions struct {
+	Output string `long:"output" short:"o" usage:"Set output format. Options: table,yaml,json,list" default:"table"`
+}
+
+// usage is the disk space used by a type of local resource.
+type usage struct {
+	kind        string
+	total       int
+	size        int64
+	reclaimable int64
+}
+
+// Df shows the disk space used by KraftKit.
+func Df(ctx context.Context, opts *DfOptions, args ...string) error {
+	if opts == nil {
+		opts = &DfOptions{}
+	}
+
+	return opts.Run(ctx, args)
+}
+
+func NewCmd() *cobra.Command {
+	cmd, err := cmdfactory.New(&DfOptions{}, cobra.Command{
+		Short: "Show disk usage",
+		Use:   "df [FLAGS] [DIR...]",
+		Args:  cobra.ArbitraryArgs,
+		Long: heredoc.Doc(`
+			Show the disk space used by locally stored packages, machines, volumes
+			and the build directories of the projects in the provided directories,
+			or the current working directory if none are provided.
+
+			The reclaimable space of packages is occupied by blobs which are no longer
+			referenced by any package and is freed by 'kraft system prune'.  The
+			reclaimable space of machines and volumes is occupied by those which are
+			not running or not bound to a machine, respectively.
+		`),
+		Example: heredoc.Doc(`
+			# Show the disk space used by KraftKit
+			$ kraft system df
+
+			# Include the build directories of multiple projects
+			$ kraft system df ./nginx ./redis
+		`),
+		Annotations: map[string]string{
+			cmdfactory.AnnotationHelpGroup: "misc",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+func (opts *DfOptions) Run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+
+		args = []string{cwd}
+	}
+
+	var usages []usage
+
+	for _, fn := range []func(context.Context) (*usage, error){
+		packagesUsage,
+		machinesUsage,
+		volumesUsage,
+	} {
+		u, err := fn(ctx)
+		if err != nil {
+			return err
+		} else if u != nil {
+			usages = append(usages, *u)
+		}
+	}
+
+	u, err := buildDirsUsage(args)
+	if err != nil {
+		return err
+	}
+
+	usages = append(usages, *u)
+
+	cs := iostreams.G(ctx).ColorScheme()
+	table, err := tableprinter.NewTablePrinter(ctx,
+		tableprinter.WithMaxWidth(iostreams.G(ctx).TerminalWidth()),
+		tableprinter.WithOutputFormatFromString(opts.Output),
+	)
+	if err != nil {
+		return err
+	}
+
+	table.AddField("TYPE", cs.Bold)
+	table.AddField("TOTAL", cs.Bold)
+	table.AddField("SIZE", cs.Bold)
+	table.AddField("RECLAIMABLE", cs.Bold)
+	table.EndRow()
+
+	for _, u := range usages {
+		table.AddField(u.kind, nil)
+		table.AddField(strconv.Itoa(u.total), nil)
+		table.AddField(humanize.Bytes(uint64(u.size)), nil)
+		table.AddField(humanize.Bytes(uint64(u.reclaimable)), nil)
+		table.EndRow()
+	}
+
+	return table.Render(iostreams.G(ctx).Out)
+}
+
+// dirSize returns the total size of the regular files within the provided
+// directory.  A directory which does not exist has no size.
+func dirSize(path string) (int64, error) {
+	var size int64
+
+	if err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		size += info.Size()
+
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
+
+// packagesUsage returns the disk space used by locally stored packages.  No
+// usage is returned if the handler is unable to account for it.
+func packagesUsage(ctx context.Context) (*usage, error) {
+	ctx, handle, err := oci.NewHandlerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reporter, ok := handle.(handler.DiskUsageReporter)
+	if !ok {
+		log.G(ctx).Warn("package handler does not support disk usage accounting")
+		return nil, nil
+	}
+
+	du, err := reporter.DiskUsage(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not account for packages: %w", err)
+	}
+
+	return &usage{
+		kind:        "Packages",
+		total:       du.Packages,
+		size:        du.Size,
+		reclaimable: du.Reclaimable,
+	}, nil
+}
+
+// machinesUsage returns the disk space used by the state directories of
+// machines across all platforms.
+func machinesUsage(ctx context.Context) (*usage, error) {
+	controller, err := mplatform.NewMachineV1alpha1ServiceIterator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	machines, err := controller.List(ctx, &machineapi.MachineList{})
+	if err != nil {
+		return nil, fmt.Errorf("could not list machines: %w", err)
+	}
+
+	ret := usage{
+		kind:  "Machines",
+		total: len(machines.Items),
+	}
+
+	for _, machine := range machines.Items {
+		if len(machine.Status.StateDir) == 0 {
+			continue
+		}
+
+		size, err := dirSize(machine.Status.StateDir)
+		if err != nil {
+			return nil, fmt.Errorf("could not account for machine '%s': %w", machine.Name, err)
+		}
+
+		ret.size += size
+		if machine.Status.State != machineapi.MachineStateRunning {
+			ret.reclaimable += size
+		}
+	}
+
+	return &ret, nil
+}
+
+// volumesUsage returns the disk space used by the sources of volumes across
+// all drivers supported by the host.
+func volumesUsage(ctx context.Context) (*usage, error) {
+	ret := usage{
+		kind: "Volumes",
+	}
+
+	for driver, strategy := range volume.Strategies() {
+		controller, err := strategy.NewVolumeV1alpha1(ctx)
+		if err != nil {
+			log.G(ctx).
+				WithField("driver", driver).
+				Debugf("could not instantiate volume controller: %v", err)
+			continue
+		}
+
+		volumes, err := controller.List(ctx, &volumeapi.VolumeList{})
+		if err != nil {
+			return nil, fmt.Errorf("could not list %s volumes: %w", driver, err)
+		}
+
+		for _, vol := range volumes.Items {
+			ret.total++
+
+			size, err := dirSize(vol.Spec.Source)
+			if err != nil {
+				return nil, fmt.Errorf("could not account for volume '%s': %w", vol.Name, err)
+			}
+
+			ret.size += size
+			if vol.Status.State != volumeapi.VolumeStateBound {
+				ret.reclaimable += size
+			}
+		}
+	}
+
+	return &ret, nil
+}
+
+// buildDirsUsage returns the disk space used by the build directories of the
+// projects in the provided directories, all of which is reclaimable by
+// cleaning the projects.
+func buildDirsUsage(dirs []string) (*usage, error) {
+	ret := usage{
+		kind: "Build directories",
+	}
+
+	for _, dir := range dirs {
+		buildDir := filepath.Join(dir, unikraft.BuildDir)
+		if _, err := os.Stat(buildDir); err != nil {
+			continue
+		}
+
+		size, err := dirSize(buildDir)
+		if err != nil {
+			return nil, fmt.Errorf("could not account for '%s': %w", buildDir, err)
+		}
+
+		ret.total++
+		ret.size += size
+		ret.reclaimable += size
+	}
+
+	return &ret, nil
+}
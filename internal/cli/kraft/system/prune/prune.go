@@ -0,0 +1,139 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+package prune
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+
+	"kraftkit.sh/cmdfactory"
+	"kraftkit.sh/iostreams"
+	"kraftkit.sh/oci"
+	"kraftkit.sh/oci/handler"
+)
+
+type PruneOptions struct {
+	GracePeriod time.Duration `long:"grace-period" usage:"Keep unreferenced blobs written within the provided duration, which may belong to packages being saved (ms/s/m/h)" default:"1h"`
+	KeepLast    int           `long:"keep-last" usage:"Keep the N most recently pulled packages of each repository"`
+	Until       time.Duration `long:"until" usage:"Only remove packages pulled longer ago than the provided duration (e.g. 24h)"`
+}
+
+// Prune removes unused packages and unreferenced blobs.
+func Prune(ctx context.Context, opts *PruneOptions, args ...string) error {
+	if opts == nil {
+		opts = &PruneOptions{}
+	}
+
+	return opts.Run(ctx, args)
+}
+
+func NewCmd() *cobra.Command {
+	cmd, err := cmdfactory.New(&PruneOptions{}, cobra.Command{
+		Short: "Remove unused data",
+		Use:   "prune [FLAGS]",
+		Args:  cobra.NoArgs,
+		Long: heredoc.Doc(`
+			Remove locally stored blobs which are not referenced by any package, e.g.
+			those left behind by interrupted pulls or overwritten packages.
+
+			Packages are only removed if a policy is provided.  With --keep-last,
+			all but the most recently pulled packages of each repository are removed.
+			With --until, packages which have been pulled longer ago than the provided
+			duration are removed.  If both are provided, only packages selected by
+			both policies are removed.
+
+			Blobs which have been written within the grace period are kept, as they
+			may belong to a package which is being saved by another process.
+		`),
+		Example: heredoc.Doc(`
+			# Remove unreferenced blobs
+			$ kraft system prune
+
+			# Additionally remove all but the 3 most recently pulled packages of each repository
+			$ kraft system prune --keep-last 3
+
+			# Additionally remove packages which were pulled over a week ago
+			$ kraft system prune --until 168h
+
+			# Remove all unreferenced blobs, including those written just now
+			$ kraft system prune --grace-period 0
+		`),
+		Annotations: map[string]string{
+			cmdfactory.AnnotationHelpGroup: "misc",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+func (opts *PruneOptions) Pre(cmd *cobra.Command, _ []string) error {
+	if opts.KeepLast < 0 {
+		return fmt.Errorf("--keep-last must not be negative")
+	}
+
+	if opts.Until < 0 {
+		return fmt.Errorf("--until must not be negative")
+	}
+
+	if opts.GracePeriod < 0 {
+		return fmt.Errorf("--grace-period must not be negative")
+	}
+
+	return nil
+}
+
+func (opts *PruneOptions) Run(ctx context.Context, _ []string) error {
+	ctx, handle, err := oci.NewHandlerFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	pruner, ok := handle.(handler.Pruner)
+	if !ok {
+		return fmt.Errorf("package handler does not support pruning")
+	}
+
+	popts := handler.PruneOptions{
+		GracePeriod: opts.GracePeriod,
+		KeepLast:    opts.KeepLast,
+	}
+
+	// The handler uses its default if no grace period is set.
+	if opts.GracePeriod == 0 {
+		popts.GracePeriod = -1
+	}
+
+	if opts.Until > 0 {
+		popts.Until = time.Now().Add(-opts.Until)
+	}
+
+	report, err := pruner.Prune(ctx, popts)
+	if err != nil {
+		return fmt.Errorf("could not prune packages: %w", err)
+	}
+
+	out := iostreams.G(ctx).Out
+
+	for _, ref := range report.Untagged {
+		fmt.Fprintf(out, "untagged: %s\n", ref)
+	}
+
+	for _, dgst := range report.Deleted {
+		fmt.Fprintf(out, "deleted: %s\n", dgst.String())
+	}
+
+	fmt.Fprintf(out, "Total reclaimed space: %s\n", humanize.Bytes(uint64(report.Reclaimed)))
+
+	return nil
+}
@@ -0,0 +1,51 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+package system
+
+import (
+	"context"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"kraftkit.sh/internal/cli/kraft/system/df"
+	"kraftkit.sh/internal/cli/kraft/system/prune"
+
+	"kraftkit.sh/cmdfactory"
+)
+
+type SystemOptions struct{}
+
+func NewCmd() *cobra.Command {
+	cmd, err := cmdfactory.New(&SystemOptions{}, cobra.Command{
+		Short: "Manage local KraftKit data",
+		Use:   "system SUBCOMMAND",
+		Long:  "Inspect and reclaim the disk space used by KraftKit on this host.",
+		Example: heredoc.Doc(`
+			# Show the disk space used by packages, machines, volumes and build directories
+			$ kraft system df
+
+			# Remove unreferenced package blobs
+			$ kraft system prune
+		`),
+		Annotations: map[string]string{
+			cmdfactory.AnnotationHelpGroup: "misc",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	cmd.AddCommand(df.NewCmd())
+	cmd.AddCommand(prune.NewCmd())
+
+	return cmd
+}
+
+func (opts *SystemOptions) Run(_ context.Context, _ []string) error {
+	return pflag.ErrHelp
+}
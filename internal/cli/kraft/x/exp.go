@@ -13,6 +13,7 @@ import (
 	"kraftkit.sh/cmdfactory"
 
 	"kraftkit.sh/internal/cli/kraft/x/probe"
+	"kraftkit.sh/internal/cli/kraft/x/size"
 )
 
 type Exp struct{}
@@ -32,6 +33,7 @@ func NewCmd() *cobra.Command {
 	}
 
 	cmd.AddCommand(probe.NewCmd())
+	cmd.AddCommand(size.NewCmd())
 
 	return cmd
 }
@@ -0,0 +1,491 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package size
+
+import (
+	"bufio"
+	"debug/dwarf"
+	"debug/elf"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// UnknownLibrary is used for any bytes which could not be attributed to a
+// specific library.
+const UnknownLibrary = "unknown"
+
+// LibrarySize represents the number of bytes a single library contributes to
+// each class of section, following the semantics of Berkeley `size(1)`: text
+// includes all read-only allocated sections (e.g. .rodata), data includes all
+// writable allocated sections and bss all allocated sections which occupy no
+// space in the file.
+type LibrarySize struct {
+	Name string `json:"name"`
+	Text uint64 `json:"text"`
+	Data uint64 `json:"data"`
+	Bss  uint64 `json:"bss"`
+}
+
+// Total returns the sum of all sections of the library.
+func (ls LibrarySize) Total() uint64 {
+	return ls.Text + ls.Data + ls.Bss
+}
+
+// Attribution is the set of libraries and their sizes for a single build.
+type Attribution map[string]*LibrarySize
+
+// add attributes size bytes of a section of the given class to the library.
+func (attr Attribution) add(library string, class sectionClass, size uint64) {
+	if len(library) == 0 {
+		library = UnknownLibrary
+	}
+
+	ls, ok := attr[library]
+	if !ok {
+		ls = &LibrarySize{Name: library}
+		attr[library] = ls
+	}
+
+	switch class {
+	case sectionText:
+		ls.Text += size
+	case sectionData:
+		ls.Data += size
+	case sectionBss:
+		ls.Bss += size
+	}
+}
+
+// Sorted returns the libraries sorted by their total size in descending order.
+func (attr Attribution) Sorted() []LibrarySize {
+	ret := make([]LibrarySize, 0, len(attr))
+	for _, ls := range attr {
+		ret = append(ret, *ls)
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		if ret[i].Total() == ret[j].Total() {
+			return ret[i].Name < ret[j].Name
+		}
+		return ret[i].Total() > ret[j].Total()
+	})
+
+	return ret
+}
+
+type sectionClass int
+
+const (
+	sectionNone sectionClass = iota
+	sectionText
+	sectionData
+	sectionBss
+)
+
+// classifyELFSection determines the class of an ELF section from its type and
+// flags.
+func classifyELFSection(section *elf.Section) sectionClass {
+	if section.Flags&elf.SHF_ALLOC == 0 {
+		return sectionNone
+	}
+
+	switch {
+	case section.Type == elf.SHT_NOBITS:
+		return sectionBss
+	case section.Flags&elf.SHF_WRITE != 0:
+		return sectionData
+	default:
+		return sectionText
+	}
+}
+
+// classifySectionName determines the class of a section only from its name,
+// which is necessary when the section's flags are not known, e.g. in a linker
+// map.
+func classifySectionName(name string) sectionClass {
+	switch {
+	case name == "COMMON",
+		strings.HasPrefix(name, ".bss"),
+		strings.HasPrefix(name, ".tbss"),
+		strings.HasPrefix(name, ".sbss"):
+		return sectionBss
+	case strings.HasPrefix(name, ".data"),
+		strings.HasPrefix(name, ".tdata"),
+		strings.HasPrefix(name, ".sdata"),
+		strings.HasPrefix(name, ".got"),
+		strings.HasPrefix(name, ".init_array"),
+		strings.HasPrefix(name, ".fini_array"):
+		return sectionData
+	case strings.HasPrefix(name, ".debug"),
+		strings.HasPrefix(name, ".comment"),
+		strings.HasPrefix(name, ".note.GNU-stack"),
+		strings.HasPrefix(name, ".rela"),
+		strings.HasPrefix(name, ".symtab"),
+		strings.HasPrefix(name, ".strtab"):
+		return sectionNone
+	default:
+		return sectionText
+	}
+}
+
+var (
+	// buildDirLibraryRegex matches a library's build directory, e.g.
+	// `.unikraft/build/libukdebug/print.o`.
+	buildDirLibraryRegex = regexp.MustCompile(`/build/(lib[^/]+|app[^/]+)/`)
+
+	// coreLibraryRegex matches internal libraries of the Unikraft core, e.g.
+	// `unikraft/lib/ukdebug/print.c`.
+	coreLibraryRegex = regexp.MustCompile(`/lib/([^/]+)/`)
+
+	// externalLibraryRegex matches external libraries which are vendored in the
+	// project, e.g. `.unikraft/libs/musl/...`.
+	externalLibraryRegex = regexp.MustCompile(`/libs/([^/]+)/`)
+
+	// platformRegex matches platform libraries of the Unikraft core, e.g.
+	// `unikraft/plat/kvm/...`.
+	platformRegex = regexp.MustCompile(`/plat/([^/]+)/`)
+)
+
+// libraryFromPath determines the name of the Unikraft library the given source
+// or object file belongs to based on the layout of a Unikraft project.
+func libraryFromPath(path string) string {
+	path = filepath.ToSlash(path)
+
+	// A partially linked library object, e.g. `.unikraft/build/libukdebug.o`.
+	if name := strings.TrimSuffix(filepath.Base(path), ".o"); filepath.Ext(path) == ".o" &&
+		(strings.HasPrefix(name, "lib") || strings.HasPrefix(name, "app")) &&
+		filepath.Base(filepath.Dir(path)) == "build" {
+		return name
+	}
+
+	for _, match := range []struct {
+		regex  *regexp.Regexp
+		format func(string) string
+	}{
+		{buildDirLibraryRegex, func(name string) string { return name }},
+		{externalLibraryRegex, func(name string) string { return "lib" + strings.ReplaceAll(name, "-", "_") }},
+		{platformRegex, func(name string) string { return "lib" + name + "plat" }},
+		{coreLibraryRegex, func(name string) string { return "lib" + strings.ReplaceAll(name, "-", "_") }},
+	} {
+		if matches := match.regex.FindStringSubmatch(path); matches != nil {
+			return match.format(matches[1])
+		}
+	}
+
+	return UnknownLibrary
+}
+
+// AttributeBuildDir attributes sizes to libraries by inspecting the partially
+// linked library objects (e.g. `libukdebug.o`) which Unikraft's build system
+// places in the root of the build directory.
+func AttributeBuildDir(dir string) (Attribution, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading build directory: %w", err)
+	}
+
+	attr := Attribution{}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".o" {
+			continue
+		}
+
+		// Skip intermediate objects of the final image, e.g.
+		// `helloworld_qemu-x86_64.ld.o`.
+		if strings.HasSuffix(name, ".ld.o") {
+			continue
+		}
+
+		if !strings.HasPrefix(name, "lib") && !strings.HasPrefix(name, "app") {
+			continue
+		}
+
+		if err := attributeObject(attr, strings.TrimSuffix(name, ".o"), filepath.Join(dir, name)); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(attr) == 0 {
+		return nil, fmt.Errorf("no library objects found in %s: has the project been built?", dir)
+	}
+
+	return attr, nil
+}
+
+// attributeObject adds all allocated sections of the given ELF object to the
+// named library.
+func attributeObject(attr Attribution, library, path string) error {
+	f, err := elf.Open(path)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", path, err)
+	}
+
+	defer f.Close()
+
+	for _, section := range f.Sections {
+		if class := classifyELFSection(section); class != sectionNone {
+			attr.add(library, class, section.Size)
+		}
+	}
+
+	return nil
+}
+
+var (
+	// mapInputRegex matches an input section of a GNU ld link map, e.g.:
+	//
+	//   .text          0x0000000000105000      0x1a2 /path/build/libukdebug.o
+	mapInputRegex = regexp.MustCompile(`^\s*(\S+)?\s+0x([0-9a-fA-F]+)\s+0x([0-9a-fA-F]+)\s+(\S.*)$`)
+
+	// mapLongSectionRegex matches an input section whose name is too long and
+	// where the remaining fields are wrapped onto the next line.
+	mapLongSectionRegex = regexp.MustCompile(`^\s+(\.\S+)\s*$`)
+)
+
+// AttributeLinkMap attributes sizes to libraries by parsing a GNU ld link map
+// (as generated via `-Wl,-Map=...`).
+func AttributeLinkMap(r io.Reader) (Attribution, error) {
+	attr := Attribution{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	inMemoryMap := false
+	pending := ""
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if !inMemoryMap {
+			if strings.HasPrefix(line, "Linker script and memory map") {
+				inMemoryMap = true
+			}
+			continue
+		}
+
+		if matches := mapLongSectionRegex.FindStringSubmatch(line); matches != nil {
+			pending = matches[1]
+			continue
+		}
+
+		matches := mapInputRegex.FindStringSubmatch(line)
+		if matches == nil {
+			pending = ""
+			continue
+		}
+
+		section := matches[1]
+		if len(section) == 0 {
+			section = pending
+		}
+		pending = ""
+
+		// Only input sections (which are indented) carry the object file.  Output
+		// sections start at the beginning of the line and their size already
+		// includes all of the inputs.
+		if len(section) == 0 || !strings.HasPrefix(line, " ") {
+			continue
+		}
+
+		class := classifySectionName(section)
+		if class == sectionNone {
+			continue
+		}
+
+		size, err := strconv.ParseUint(matches[3], 16, 64)
+		if err != nil || size == 0 {
+			continue
+		}
+
+		object := strings.TrimSpace(matches[4])
+		if strings.HasPrefix(object, "load address") {
+			continue
+		}
+
+		// Archive members are reported as `archive.a(member.o)`.
+		if i := strings.Index(object, "("); i > 0 {
+			object = object[:i]
+		}
+
+		attr.add(libraryFromPath(object), class, size)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(attr) == 0 {
+		return nil, fmt.Errorf("no input sections found in link map")
+	}
+
+	return attr, nil
+}
+
+// AttributeELF attributes sizes to libraries from a symbolic (unstripped)
+// kernel image.  Each sized symbol is attributed to the compilation unit which
+// defines it according to the image's DWARF debug information and the
+// compilation unit's path is used to determine the library.  Any bytes in an
+// allocated section which are not covered by a symbol are reported as unknown.
+func AttributeELF(path string) (Attribution, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+
+	defer f.Close()
+
+	symbols, err := f.Symbols()
+	if err != nil {
+		return nil, fmt.Errorf("reading symbols of %s: %w", path, err)
+	}
+
+	owners, err := dwarfOwners(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading debug information of %s: %w (is this a debuggable image?)", path, err)
+	}
+
+	attr := Attribution{}
+	covered := make(map[int]uint64)
+	seen := map[uint64]bool{}
+
+	for _, sym := range symbols {
+		if sym.Size == 0 || sym.Section == elf.SHN_UNDEF || int(sym.Section) >= len(f.Sections) {
+			continue
+		}
+
+		switch elf.ST_TYPE(sym.Info) {
+		case elf.STT_FUNC, elf.STT_OBJECT, elf.STT_TLS:
+		default:
+			continue
+		}
+
+		// Aliases share the same address and should only be counted once.
+		if seen[sym.Value] {
+			continue
+		}
+		seen[sym.Value] = true
+
+		section := f.Sections[sym.Section]
+		class := classifyELFSection(section)
+		if class == sectionNone {
+			continue
+		}
+
+		attr.add(libraryFromPath(owners[sym.Value]), class, sym.Size)
+		covered[int(sym.Section)] += sym.Size
+	}
+
+	for i, section := range f.Sections {
+		class := classifyELFSection(section)
+		if class == sectionNone || section.Size <= covered[i] {
+			continue
+		}
+
+		attr.add(UnknownLibrary, class, section.Size-covered[i])
+	}
+
+	return attr, nil
+}
+
+// dwarfOwners returns a map of addresses of functions and variables to the name
+// of the compilation unit which defines them.
+func dwarfOwners(f *elf.File) (map[uint64]string, error) {
+	data, err := f.DWARF()
+	if err != nil {
+		return nil, err
+	}
+
+	owners := map[uint64]string{}
+	reader := data.Reader()
+	cu := ""
+
+	for {
+		entry, err := reader.Next()
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			break
+		}
+
+		switch entry.Tag {
+		case dwarf.TagCompileUnit:
+			cu, _ = entry.Val(dwarf.AttrName).(string)
+			if dir, ok := entry.Val(dwarf.AttrCompDir).(string); ok && !filepath.IsAbs(cu) {
+				cu = filepath.Join(dir, cu)
+			}
+
+		case dwarf.TagSubprogram:
+			if lowpc, ok := entry.Val(dwarf.AttrLowpc).(uint64); ok {
+				owners[lowpc] = cu
+			}
+
+		case dwarf.TagVariable:
+			// Only static storage is of interest, which is described by a single
+			// DW_OP_addr operation.
+			loc, ok := entry.Val(dwarf.AttrLocation).([]byte)
+			if !ok || len(loc) < 1 || loc[0] != 0x03 /* DW_OP_addr */ {
+				continue
+			}
+
+			switch len(loc) - 1 {
+			case 8:
+				owners[f.ByteOrder.Uint64(loc[1:])] = cu
+			case 4:
+				owners[uint64(f.ByteOrder.Uint32(loc[1:]))] = cu
+			}
+		}
+	}
+
+	return owners, nil
+}
+
+// Attribute determines the sizes of each library from the provided path, which
+// may be a Unikraft build directory, a project directory containing a build
+// directory, a GNU ld link map or a symbolic kernel image.
+func Attribute(path string) (Attribution, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if fi.IsDir() {
+		if sub := filepath.Join(path, ".unikraft", "build"); isDir(sub) {
+			path = sub
+		}
+
+		return AttributeBuildDir(path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(f, magic); err == nil && string(magic) == elf.ELFMAG {
+		return AttributeELF(path)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	return AttributeLinkMap(f)
+}
+
+func isDir(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && fi.IsDir()
+}
@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package size
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLibraryFromPath(t *testing.T) {
+	for path, expected := range map[string]string{
+		"/app/.unikraft/build/libukdebug.o":                "libukdebug",
+		"/app/.unikraft/build/libukdebug/print.o":          "libukdebug",
+		"/app/.unikraft/build/appelfloader/main.o":         "appelfloader",
+		"/app/.unikraft/unikraft/lib/posix-process/exit.c": "libposix_process",
+		"/app/.unikraft/unikraft/plat/kvm/x86/setup.c":     "libkvmplat",
+		"/app/.unikraft/libs/musl/src/foo.c":               "libmusl",
+		"/app/main.c":                                      UnknownLibrary,
+	} {
+		if actual := libraryFromPath(path); actual != expected {
+			t.Errorf("libraryFromPath(%q): expected %q, got %q", path, expected, actual)
+		}
+	}
+}
+
+func TestAttributeLinkMap(t *testing.T) {
+	linkmap := `Archive member included to satisfy reference by file (symbol)
+
+Linker script and memory map
+
+.text           0x0000000000105000     0x2000
+ .text          0x0000000000105000      0x1a2 /app/.unikraft/build/libukdebug.o
+                0x0000000000105000                uk_printk
+ .text.very_long_section_name_which_wraps
+                0x00000000001051a2       0x10 /app/.unikraft/build/libukdebug.o
+ *fill*         0x00000000001051b2        0xe 
+ .rodata        0x00000000001051c0       0x40 /app/.unikraft/build/libkvmplat.o
+.data           0x0000000000108000      0x100
+ .data          0x0000000000108000       0x20 /app/.unikraft/build/libkvmplat.o
+.bss            0x0000000000109000      0x200
+ .bss           0x0000000000109000      0x100 /app/.unikraft/build/libukdebug.o
+ COMMON         0x0000000000109100        0x8 /usr/lib/gcc/libgcc.a(_udivdi3.o)
+ .debug_info    0x0000000000000000     0x1000 /app/.unikraft/build/libukdebug.o
+`
+
+	attr, err := AttributeLinkMap(strings.NewReader(linkmap))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ls := attr["libukdebug"]; ls == nil || ls.Text != 0x1b2 || ls.Data != 0 || ls.Bss != 0x100 {
+		t.Errorf("unexpected size for libukdebug: %+v", ls)
+	}
+
+	if ls := attr["libkvmplat"]; ls == nil || ls.Text != 0x40 || ls.Data != 0x20 || ls.Bss != 0 {
+		t.Errorf("unexpected size for libkvmplat: %+v", ls)
+	}
+
+	if len(attr) != 3 {
+		t.Errorf("expected 3 libraries, got %d", len(attr))
+	}
+}
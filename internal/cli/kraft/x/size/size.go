@@ -0,0 +1,296 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package size
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"kraftkit.sh/cmdfactory"
+	"kraftkit.sh/internal/tableprinter"
+	"kraftkit.sh/iostreams"
+)
+
+const (
+	outputTable   = "table"
+	outputJSON    = "json"
+	outputTreemap = "treemap"
+)
+
+type Size struct {
+	Against string `long:"against" short:"a" usage:"Compare against a previous build (build directory, link map or symbolic kernel image)"`
+	Map     string `long:"map" usage:"Attribute sizes using the provided GNU ld link map"`
+	Output  string `long:"output" short:"o" usage:"Set output format. Options: table,json,treemap" default:"table"`
+}
+
+func NewCmd() *cobra.Command {
+	cmd, err := cmdfactory.New(&Size{}, cobra.Command{
+		Short: "Attribute the size of a unikernel to its libraries",
+		Use:   "size [FLAGS] [DIR|FILE]",
+		Args:  cobra.MaximumNArgs(1),
+		Long: heredoc.Doc(`
+			Attribute the size of a unikernel to its libraries.
+
+			The size of the .text, .data and .bss sections contributed by each library
+			can be determined from a project or its build directory, using the
+			partially linked library objects, from a GNU ld link map or from a
+			symbolic (debuggable) kernel image, using its DWARF information.
+
+			Read-only sections (e.g. .rodata) are accounted as .text, following the
+			semantics of size(1).
+
+			The treemap output format emits one "<bytes> <library>/<section>" line
+			per non-empty section which can be consumed by tools such as webtreemap.
+		`),
+		Example: heredoc.Doc(`
+			# Show the size of each library of the project in the cwd
+			$ kraft x size
+
+			# Show the size of each library of a debuggable kernel image
+			$ kraft x size .unikraft/build/helloworld_qemu-x86_64.dbg
+
+			# Compare the current build against a previous build
+			$ kraft x size --against previous.dbg .unikraft/build/helloworld_qemu-x86_64.dbg
+
+			# Attribute sizes using a link map and output JSON
+			$ kraft x size --map .unikraft/build/helloworld_qemu-x86_64.map -o json`),
+		Annotations: map[string]string{
+			cmdfactory.AnnotationHelpGroup: "experimental",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+func (opts *Size) Pre(cmd *cobra.Command, _ []string) error {
+	switch opts.Output {
+	case outputTable, outputJSON, outputTreemap:
+	default:
+		return fmt.Errorf("invalid output format: %s", opts.Output)
+	}
+
+	return nil
+}
+
+// librarySizeDiff represents a library's size in the current build and,
+// optionally, in the build which is compared against.
+type librarySizeDiff struct {
+	LibrarySize
+	Previous *LibrarySize `json:"previous,omitempty"`
+}
+
+func (opts *Size) Run(ctx context.Context, args []string) error {
+	var err error
+	var current, previous Attribution
+
+	path := "."
+	if len(args) > 0 {
+		path = args[0]
+	}
+
+	if len(opts.Map) > 0 {
+		f, err := os.Open(opts.Map)
+		if err != nil {
+			return fmt.Errorf("opening link map: %w", err)
+		}
+
+		defer f.Close()
+
+		current, err = AttributeLinkMap(f)
+		if err != nil {
+			return fmt.Errorf("parsing link map: %w", err)
+		}
+	} else {
+		current, err = Attribute(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(opts.Against) > 0 {
+		previous, err = Attribute(opts.Against)
+		if err != nil {
+			return fmt.Errorf("attributing %s: %w", opts.Against, err)
+		}
+	}
+
+	diffs := diff(current, previous)
+
+	switch opts.Output {
+	case outputJSON:
+		enc := json.NewEncoder(iostreams.G(ctx).Out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(diffs)
+
+	case outputTreemap:
+		return writeTreemap(iostreams.G(ctx).Out, diffs)
+	}
+
+	cs := iostreams.G(ctx).ColorScheme()
+
+	table, err := tableprinter.NewTablePrinter(ctx,
+		tableprinter.WithMaxWidth(iostreams.G(ctx).TerminalWidth()),
+		tableprinter.WithOutputFormatFromString(opts.Output),
+	)
+	if err != nil {
+		return err
+	}
+
+	table.AddField("LIBRARY", cs.Bold)
+	table.AddField("TEXT", cs.Bold)
+	table.AddField("DATA", cs.Bold)
+	table.AddField("BSS", cs.Bold)
+	table.AddField("TOTAL", cs.Bold)
+	if previous != nil {
+		table.AddField("DELTA", cs.Bold)
+	}
+	table.EndRow()
+
+	var total, totalPrevious LibrarySize
+	for _, d := range diffs {
+		total.Text += d.Text
+		total.Data += d.Data
+		total.Bss += d.Bss
+
+		table.AddField(d.Name, nil)
+		table.AddField(strconv.FormatUint(d.Text, 10), nil)
+		table.AddField(strconv.FormatUint(d.Data, 10), nil)
+		table.AddField(strconv.FormatUint(d.Bss, 10), nil)
+		table.AddField(strconv.FormatUint(d.Total(), 10), nil)
+
+		if previous != nil {
+			var prev uint64
+			if d.Previous != nil {
+				prev = d.Previous.Total()
+				totalPrevious.Text += d.Previous.Text
+				totalPrevious.Data += d.Previous.Data
+				totalPrevious.Bss += d.Previous.Bss
+			}
+
+			table.AddField(formatDelta(d.Total(), prev), deltaColor(cs, d.Total(), prev))
+		}
+
+		table.EndRow()
+	}
+
+	table.AddField("total", cs.Bold)
+	table.AddField(strconv.FormatUint(total.Text, 10), cs.Bold)
+	table.AddField(strconv.FormatUint(total.Data, 10), cs.Bold)
+	table.AddField(strconv.FormatUint(total.Bss, 10), cs.Bold)
+	table.AddField(strconv.FormatUint(total.Total(), 10), cs.Bold)
+	if previous != nil {
+		table.AddField(formatDelta(total.Total(), totalPrevious.Total()), cs.Bold)
+	}
+	table.EndRow()
+
+	return table.Render(iostreams.G(ctx).Out)
+}
+
+// diff combines the current and previous attribution, including libraries
+// which have been removed since the previous build.  The result is sorted by
+// the total size of the current build in descending order.
+func diff(current, previous Attribution) []librarySizeDiff {
+	var diffs []librarySizeDiff
+
+	for _, ls := range current.Sorted() {
+		d := librarySizeDiff{LibrarySize: ls}
+		if prev, ok := previous[ls.Name]; ok {
+			p := *prev
+			d.Previous = &p
+		} else if previous != nil {
+			d.Previous = &LibrarySize{Name: ls.Name}
+		}
+
+		diffs = append(diffs, d)
+	}
+
+	var removed []librarySizeDiff
+	for name, prev := range previous {
+		if _, ok := current[name]; ok {
+			continue
+		}
+
+		p := *prev
+		removed = append(removed, librarySizeDiff{
+			LibrarySize: LibrarySize{Name: name},
+			Previous:    &p,
+		})
+	}
+
+	sort.SliceStable(removed, func(i, j int) bool {
+		return removed[i].Previous.Total() > removed[j].Previous.Total()
+	})
+
+	return append(diffs, removed...)
+}
+
+// formatDelta returns the signed difference between the current and previous
+// size.
+func formatDelta(current, previous uint64) string {
+	switch {
+	case current > previous:
+		return "+" + strconv.FormatUint(current-previous, 10)
+	case current < previous:
+		return "-" + strconv.FormatUint(previous-current, 10)
+	default:
+		return "0"
+	}
+}
+
+func deltaColor(cs *iostreams.ColorScheme, current, previous uint64) func(string) string {
+	switch {
+	case current > previous:
+		return cs.Red
+	case current < previous:
+		return cs.Green
+	default:
+		return nil
+	}
+}
+
+// writeTreemap writes the sizes in a format which is suitable for rendering
+// as a treemap, where each line is "<bytes> <library>/<section>".  When
+// comparing builds, the size is replaced by the signed delta.
+func writeTreemap(w io.Writer, diffs []librarySizeDiff) error {
+	for _, d := range diffs {
+		for _, section := range []struct {
+			name    string
+			current uint64
+			prev    func(*LibrarySize) uint64
+		}{
+			{".text", d.Text, func(ls *LibrarySize) uint64 { return ls.Text }},
+			{".data", d.Data, func(ls *LibrarySize) uint64 { return ls.Data }},
+			{".bss", d.Bss, func(ls *LibrarySize) uint64 { return ls.Bss }},
+		} {
+			value := strconv.FormatUint(section.current, 10)
+			if d.Previous != nil {
+				prev := section.prev(d.Previous)
+				if prev == section.current {
+					continue
+				}
+				value = formatDelta(section.current, prev)
+			} else if section.current == 0 {
+				continue
+			}
+
+			if _, err := fmt.Fprintf(w, "%s %s/%s\n", value, d.Name, section.name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
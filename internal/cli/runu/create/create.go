@@ -13,24 +13,24 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
-	"strings"
 	"syscall"
 
 	"github.com/MakeNowJust/heredoc"
 	securejoin "github.com/cyphar/filepath-securejoin"
 	"github.com/spf13/cobra"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	rtspec "github.com/opencontainers/runtime-spec/specs-go"
 
 	machineapi "kraftkit.sh/api/machine/v1alpha1"
 	"kraftkit.sh/cmdfactory"
-	"kraftkit.sh/config"
-	"kraftkit.sh/exec"
 	"kraftkit.sh/internal/set"
 	libcontainer "kraftkit.sh/libmocktainer"
 	"kraftkit.sh/libmocktainer/specconv"
+	"kraftkit.sh/libmocktainer/unikraft"
 	"kraftkit.sh/log"
 	mplatform "kraftkit.sh/machine/platform"
 	"kraftkit.sh/machine/qemu"
@@ -63,6 +63,10 @@ func NewCmd() *cobra.Command {
 			The specification file includes an args parameter. The args parameter is used
 			to specify command(s) that get run when the unikernel is started. To change the
 			command(s) that get executed on start, edit the args parameter of the spec
+
+			The unikernel is run with QEMU, unless the "org.unikraft.kernel.plat"
+			annotation of the spec, which is also set on packages built by kraft,
+			selects Firecracker with the value "fc".
 		`),
 		Example: heredoc.Doc(`
 			# Create a new unikernel
@@ -109,11 +113,6 @@ const (
 	flagSdCgroup = "systemd-cgroup"
 )
 
-// TODO(antoineco): the runtime spec (config.json) doesn't provide any hint
-// about the desired target platform, so we will need to think about ways to
-// figure this out.
-const plat = mplatform.PlatformQEMU
-
 const (
 	specAnnotCRIContainerType = "io.kubernetes.cri.container-type"
 	specAnnotUnikernel        = "org.unikraft.kernel"
@@ -129,8 +128,9 @@ func (opts *CreateOptions) Run(ctx context.Context, args []string) (retErr error
 		}
 	}()
 
-	if opts.sdcg {
-		log.G(ctx).Warnf("ignoring --%s flag", flagSdCgroup)
+	bundle, err := filepath.Abs(opts.Bundle)
+	if err != nil {
+		return fmt.Errorf("getting bundle abs path: %w", err)
 	}
 
 	var pidFile string
@@ -140,48 +140,24 @@ func (opts *CreateOptions) Run(ctx context.Context, args []string) (retErr error
 		}
 	}
 
-	if err = os.Chdir(opts.Bundle); err != nil {
+	if err = os.Chdir(bundle); err != nil {
 		return fmt.Errorf("changing working dir to OCI bundle: %w", err)
 	}
 
-	spec, err := loadSpec()
+	spec, err := LoadSpec(bundle)
 	if err != nil {
 		return fmt.Errorf("loading runtime spec: %w", err)
 	}
-	spec.Linux.Namespaces = supportedNamespaces(spec.Linux.Namespaces)
 
 	cID := args[0]
 
-	if isCRISandbox(spec) {
-		// NOTE(antoineco): alternatively we could start the sandbox container
-		// using (upstream) libcontainer, providing that we are willing to import
-		// it as a dependency additionally to libmocktainer.
-		if len(spec.Process.Args) == 0 {
-			return fmt.Errorf("sandbox container has no process arg")
-		}
-		cArgPath, err := securejoin.SecureJoin(spec.Root.Path, spec.Process.Args[0])
-		if err != nil {
-			return fmt.Errorf("joining path components: %w", err)
-		}
-		if cArgPath, err = filepath.Abs(cArgPath); err != nil {
-			return err
-		}
-		spec.Process.Args[0] = cArgPath
-	} else {
-		cArgs, err := genMachineArgs(ctx, cID, opts.rootDir, spec.Root.Path)
-		if err != nil {
-			return fmt.Errorf("generating machine args: %w", err)
-		}
-		spec.Process.Args = cArgs
-
-		if spec.Annotations == nil {
-			spec.Annotations = make(map[string]string, 1)
-		}
-		spec.Annotations[specAnnotUnikernel] = ""
+	if err = PrepareSpec(ctx, cID, opts.rootDir, bundle, spec); err != nil {
+		return err
 	}
 
-	c, err := createContainer(cID, opts.rootDir, spec)
+	c, err := CreateContainer(cID, opts.rootDir, bundle, opts.sdcg, spec)
 	if err != nil {
+		_ = unikraft.RemoveQemuControlSocket(spec.Annotations[unikraft.AnnotationQemuControlSocket])
 		return fmt.Errorf("creating container environment: %w", err)
 	}
 
@@ -192,11 +168,9 @@ func (opts *CreateOptions) Run(ctx context.Context, args []string) (retErr error
 	return nil
 }
 
-const specFile = "config.json"
-
-// loadSpec loads the OCI runtime specification from the provided path.
-func loadSpec() (*rtspec.Spec, error) {
-	f, err := os.Open(specFile)
+// LoadSpec loads the OCI runtime specification of the provided bundle.
+func LoadSpec(bundle string) (*rtspec.Spec, error) {
+	f, err := os.Open(filepath.Join(bundle, oci.ConfigFilename))
 	if err != nil {
 		return nil, fmt.Errorf("opening spec file: %w", err)
 	}
@@ -210,123 +184,145 @@ func loadSpec() (*rtspec.Spec, error) {
 	return spec, nil
 }
 
-// isCRISandbox returns whether the current container is a CRI sandbox
-// (Kubernetes "pause" container).
-func isCRISandbox(spec *rtspec.Spec) bool {
-	return spec.Annotations[specAnnotCRIContainerType] == "sandbox"
-}
-
-// genMachineArgs returns the command-line arguments for starting a unikernel machine.
-func genMachineArgs(ctx context.Context, cID, rootDir, bundleRoot string) (args []string, retErr error) {
-	m, err := newMachine(cID, rootDir, bundleRoot)
-	if err != nil {
-		return nil, fmt.Errorf("creating machine: %w", err)
+// PrepareSpec prepares the provided spec of the container with the provided
+// ID, whose bundle is at the provided path, for being created by
+// libmocktainer.  The process of a CRI sandbox is run as is from the root
+// filesystem of the container, whereas the process of any other container is
+// replaced with the virtual machine monitor which runs its unikernel.
+func PrepareSpec(ctx context.Context, cID, rootDir, bundle string, spec *rtspec.Spec) error {
+	if spec.Root == nil {
+		return fmt.Errorf("root must be specified")
 	}
 
-	mSvc, err := newMachineService(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("instantiating machine service: %w", err)
-	}
-
-	// HACK: QMP socket paths are unusable with long container IDs (e.g. in Kubernetes).
-	// Use a short ephemeral directory as a workaround.
-	//
-	//   FATA[0000] failed to create shim task: OCI runtime create failed:
-	//   generating machine args: creating machine: could not start and wait for QEMU process:
-	//   qemu-system-x86_64: -qmp unix:/run/containerd/runc/default/bf6f970d280ba13c36f34f8d8765603244362620d12b405a5e91bd822ead4c8a/qemu_control.sock,server,nowait:
-	//   UNIX socket path '/run/containerd/runc/default/bf6f970d280ba13c36f34f8d8765603244362620d12b405a5e91bd822ead4c8a/qemu_control.sock' is too long
-	//   Path must be less than 108 bytes
-	//
-	origStateDir := m.Status.StateDir
-	m.Status.StateDir, err = os.MkdirTemp("", "runu-")
-	if err != nil {
-		return nil, fmt.Errorf("creating short temp state dir: %w", err)
+	if !filepath.IsAbs(spec.Root.Path) {
+		spec.Root.Path = filepath.Join(bundle, spec.Root.Path)
 	}
-	defer func() {
-		if err := os.RemoveAll(m.Status.StateDir); err != nil {
-			retErr = combineErrors(retErr, fmt.Errorf("deleting short temp state dir: %w", err))
+
+	spec.Linux.Namespaces = supportedNamespaces(spec.Linux.Namespaces)
+
+	if IsCRISandbox(spec) {
+		// NOTE(antoineco): alternatively we could start the sandbox container
+		// using (upstream) libcontainer, providing that we are willing to import
+		// it as a dependency additionally to libmocktainer.
+		if len(spec.Process.Args) == 0 {
+			return fmt.Errorf("sandbox container has no process arg")
 		}
-	}()
+		cArgPath, err := securejoin.SecureJoin(spec.Root.Path, spec.Process.Args[0])
+		if err != nil {
+			return fmt.Errorf("joining path components: %w", err)
+		}
+		if cArgPath, err = filepath.Abs(cArgPath); err != nil {
+			return err
+		}
+		spec.Process.Args[0] = cArgPath
 
-	// HACK: this starts a QEMU process without starting the guest (-S flag, "freeze CPU at startup").
-	// We only do that for the sake of cloning the machine's startup command and
-	// arguments, and set those same arguments inside the OCI container's config.
-	m, err = mSvc.Create(ctx, m)
+		return nil
+	}
+
+	if spec.Annotations == nil {
+		spec.Annotations = make(map[string]string, 1)
+	}
+
+	cArgs, err := genMachineArgs(ctx, cID, rootDir, bundle, spec)
 	if err != nil {
-		return nil, fmt.Errorf("creating machine: %w", err)
+		return fmt.Errorf("generating machine args: %w", err)
 	}
+	spec.Process.Args = cArgs
 
-	defer func() {
-		if _, err := mSvc.Stop(ctx, m); err != nil {
-			retErr = combineErrors(retErr, fmt.Errorf("stopping machine: %w", err))
-		}
-		if _, err := mSvc.Delete(ctx, m); err != nil {
-			retErr = combineErrors(retErr, fmt.Errorf("deleting machine: %w", err))
-		}
-	}()
+	spec.Annotations[specAnnotUnikernel] = ""
+
+	return nil
+}
 
-	qCfg, ok := m.Status.PlatformConfig.(qemu.QemuConfig)
+// IsCRISandbox returns whether the given container is a CRI sandbox
+// (Kubernetes "pause" container).
+func IsCRISandbox(spec *rtspec.Spec) bool {
+	return spec.Annotations[specAnnotCRIContainerType] == "sandbox"
+}
+
+// MachinePlatform returns the platform on which the unikernel is run, which is
+// selected by the org.unikraft.kernel.plat annotation of the container, as set
+// on the packages built by kraft, and is QEMU by default.
+func MachinePlatform(spec *rtspec.Spec) (mplatform.Platform, error) {
+	name := spec.Annotations[oci.AnnotationKernelPlat]
+	if name == "" {
+		return mplatform.PlatformQEMU, nil
+	}
+
+	plat, ok := mplatform.PlatformsByName()[name]
 	if !ok {
-		return nil, fmt.Errorf("machine does not embed a QEMU platform config")
+		return "", fmt.Errorf("unknown platform: %s", name)
 	}
 
-	var bin string
-	switch mArch := m.Spec.Architecture; mArch {
-	case "x86_64":
-		bin = qemu.QemuSystemX86
-	case "arm":
-		bin = qemu.QemuSystemArm
-	case "arm64":
-		bin = qemu.QemuSystemAarch64
+	switch plat {
+	case mplatform.PlatformQEMU, mplatform.PlatformFirecracker:
+		return plat, nil
 	default:
-		return nil, fmt.Errorf("unsupported machine architecture: %s", mArch)
+		return "", fmt.Errorf("unsupported platform: %s", plat)
 	}
+}
 
-	if config.G[config.KraftKit](ctx).Qemu != "" {
-		bin = config.G[config.KraftKit](ctx).Qemu
+// genMachineArgs returns the command-line arguments for starting a unikernel
+// machine.  The arguments are generated directly from the machine rather than
+// captured from a virtual machine monitor which is started for that purpose.
+func genMachineArgs(ctx context.Context, cID, rootDir, bundle string, spec *rtspec.Spec) ([]string, error) {
+	plat, err := MachinePlatform(spec)
+	if err != nil {
+		return nil, err
 	}
 
-	exe, err := exec.NewExecutable(bin, qCfg)
+	m, err := newMachine(cID, rootDir, spec.Root.Path, plat)
 	if err != nil {
-		return nil, fmt.Errorf("preparing machine executable: %w", err)
+		return nil, fmt.Errorf("creating machine: %w", err)
 	}
 
-	args = sanitizeQemuArgs(exe.Args())
+	switch plat {
+	case mplatform.PlatformFirecracker:
+		return genFirecrackerArgs(m, bundle)
 
-	// HACK: restore previously overridden stateDir
-	for i := range args {
-		args[i] = strings.Replace(args[i], m.Status.StateDir, origStateDir, 1)
-	}
+	default:
+		bin, err := qemuBin(ctx, m.Spec.Architecture)
+		if err != nil {
+			return nil, err
+		}
 
-	return append([]string{bin}, args...), nil
-}
+		// Determine the version of QEMU so as to adjust the supplied command-line
+		// arguments.
+		version, err := qemu.GetQemuVersionFromBin(ctx, bin)
+		if err != nil {
+			return nil, err
+		}
 
-// sanitizeQemuArgs filters out undesired QEMU commands line arguments, such as
-// the "-daemonize" flag, from the given list.
-func sanitizeQemuArgs(qemuArgs []string) []string {
-	xFlags := set.NewStringSet("-daemonize", "-S")
-	xFlagsVal := set.NewStringSet("-qmp", "-monitor", "-serial")
-
-	filtered := qemuArgs[:0]
-	for i := 0; i < len(qemuArgs); i++ {
-		switch {
-		case xFlags.Contains(qemuArgs[i]):
-		case xFlagsVal.Contains(qemuArgs[i]):
-			i++
-		default:
-			filtered = append(filtered, qemuArgs[i])
+		// The QMP socket is recorded in the state of the container, which removes
+		// its directory once destroyed.
+		socket, err := unikraft.NewQemuControlSocket()
+		if err != nil {
+			return nil, err
+		}
+
+		args, err := genQemuArgs(m, bin, version, socket)
+		if err != nil {
+			_ = unikraft.RemoveQemuControlSocket(socket)
+			return nil, err
 		}
+
+		spec.Annotations[unikraft.AnnotationQemuControlSocket] = socket
+
+		return args, nil
 	}
-	return filtered
 }
 
-// newMachine returns a new Machine with the given ID.
-func newMachine(mID string, rootDir, bundleRoot string) (*machineapi.Machine, error) {
+// newMachine returns a new Machine with the given ID for the given platform.
+func newMachine(mID string, rootDir, bundleRoot string, plat mplatform.Platform) (*machineapi.Machine, error) {
 	kPath, err := kernelAbsPath(bundleRoot)
 	if err != nil {
 		return nil, fmt.Errorf("getting absolute path of kernel: %w", err)
 	}
 
+	if err := assembleChunkedInitrd(bundleRoot); err != nil {
+		return nil, fmt.Errorf("assembling chunked initrd: %w", err)
+	}
+
 	irdPath, err := initrdAbsPath(bundleRoot)
 	if err != nil {
 		return nil, fmt.Errorf("getting absolute path of initrd: %w", err)
@@ -351,6 +347,12 @@ func newMachine(mID string, rootDir, bundleRoot string) (*machineapi.Machine, er
 		Spec: machineapi.MachineSpec{
 			Platform:     plat.String(),
 			Architecture: kArch,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceMemory: resource.MustParse("64Mi"),
+					corev1.ResourceCPU:    resource.MustParse("1"),
+				},
+			},
 		},
 		Status: machineapi.MachineStatus{
 			KernelPath: kPath,
@@ -383,6 +385,22 @@ func initrdAbsPath(bundleRoot string) (string, error) {
 	return p, err
 }
 
+// assembleChunkedInitrd reassembles the initrd of the bundle in place if it
+// has been packaged in chunks, which the layers of the image only contain.
+func assembleChunkedInitrd(bundleRoot string) error {
+	chunksDir, err := securejoin.SecureJoin(bundleRoot, oci.WellKnownInitrdChunksDir)
+	if err != nil {
+		return fmt.Errorf("joining path components: %w", err)
+	}
+
+	path, err := securejoin.SecureJoin(bundleRoot, initrdRelPath)
+	if err != nil {
+		return fmt.Errorf("joining path components: %w", err)
+	}
+
+	return oci.AssembleChunkedFile(chunksDir, path)
+}
+
 // fileAbsPath returns the absolute path of the given file relative to the
 // bundle root, and performs some basic sanity checks on the value.
 func fileAbsPath(bundleRoot, relPath string) (string, error) {
@@ -405,16 +423,6 @@ func fileAbsPath(bundleRoot, relPath string) (string, error) {
 	return path, nil
 }
 
-// newMachineService returns a new MachineService.
-func newMachineService(ctx context.Context) (machineapi.MachineService, error) {
-	ms, ok := mplatform.Strategies()[plat]
-	if !ok {
-		return nil, fmt.Errorf("unsupported platform driver: %s", plat)
-	}
-
-	return ms.NewMachineV1alpha1(ctx)
-}
-
 // kernelArchitecture returns the architecture of the given kernel file.
 // https://github.com/unikraft/kraftkit/blob/v0.6.4/cmd/kraft/run/runner_kernel.go#L47-L85
 func kernelArchitecture(path string) (string, error) {
@@ -453,11 +461,16 @@ func supportedNamespaces(nss []rtspec.LinuxNamespace) []rtspec.LinuxNamespace {
 	return filtered
 }
 
-// createContainer creates a new container in a stopped state for the given
-// container id inside the provided state directory (root).
-func createContainer(cID, rootDir string, spec *rtspec.Spec) (*libcontainer.Container, error) {
+// CreateContainer creates a new container in a stopped state for the given
+// container id and bundle inside the provided state directory (root).  The
+// virtual machine monitor of the container is run in the cgroup of the spec,
+// which is managed by systemd if sdcg is set.
+func CreateContainer(cID, rootDir, bundle string, sdcg bool, spec *rtspec.Spec) (*libcontainer.Container, error) {
 	config, err := specconv.CreateLibcontainerConfig(&specconv.CreateOpts{
-		Spec: spec,
+		CgroupName:       cID,
+		UseSystemdCgroup: sdcg,
+		Spec:             spec,
+		Bundle:           bundle,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("creating libcontainer configuration: %w", err)
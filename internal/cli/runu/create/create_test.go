@@ -0,0 +1,82 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package create
+
+import (
+	"testing"
+
+	rtspec "github.com/opencontainers/runtime-spec/specs-go"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	machineapi "kraftkit.sh/api/machine/v1alpha1"
+	mplatform "kraftkit.sh/machine/platform"
+	"kraftkit.sh/oci"
+)
+
+// testMachine returns a machine as created by newMachine without requiring a
+// kernel in a bundle.
+func testMachine(plat mplatform.Platform, arch string) *machineapi.Machine {
+	return &machineapi.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "my-machine",
+		},
+		Spec: machineapi.MachineSpec{
+			Platform:     plat.String(),
+			Architecture: arch,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceMemory: resource.MustParse("64Mi"),
+					corev1.ResourceCPU:    resource.MustParse("1"),
+				},
+			},
+		},
+		Status: machineapi.MachineStatus{
+			KernelPath: "/bundle/rootfs/unikraft/bin/kernel",
+			InitrdPath: "/bundle/rootfs/unikraft/bin/initrd",
+			StateDir:   "/run/runu/my-machine",
+		},
+	}
+}
+
+func TestMachinePlatform(t *testing.T) {
+	for annotation, expected := range map[string]mplatform.Platform{
+		"":            mplatform.PlatformQEMU,
+		"qemu":        mplatform.PlatformQEMU,
+		"kvm":         mplatform.PlatformQEMU,
+		"fc":          mplatform.PlatformFirecracker,
+		"firecracker": mplatform.PlatformFirecracker,
+	} {
+		spec := &rtspec.Spec{
+			Annotations: map[string]string{
+				oci.AnnotationKernelPlat: annotation,
+			},
+		}
+
+		actual, err := MachinePlatform(spec)
+		if err != nil {
+			t.Errorf("MachinePlatform(%q): unexpected error: %v", annotation, err)
+		} else if actual != expected {
+			t.Errorf("MachinePlatform(%q): expected %q, got %q", annotation, expected, actual)
+		}
+	}
+
+	for _, annotation := range []string{"xen", "unknown"} {
+		spec := &rtspec.Spec{
+			Annotations: map[string]string{
+				oci.AnnotationKernelPlat: annotation,
+			},
+		}
+
+		if _, err := MachinePlatform(spec); err == nil {
+			t.Errorf("MachinePlatform(%q): expected an error", annotation)
+		}
+	}
+
+	if actual, err := MachinePlatform(&rtspec.Spec{}); err != nil || actual != mplatform.PlatformQEMU {
+		t.Errorf("MachinePlatform without annotations: expected %q, got %q (%v)", mplatform.PlatformQEMU, actual, err)
+	}
+}
@@ -0,0 +1,74 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package create
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	fcsdk "github.com/firecracker-microvm/firecracker-go-sdk"
+	"github.com/firecracker-microvm/firecracker-go-sdk/client/models"
+
+	machineapi "kraftkit.sh/api/machine/v1alpha1"
+	"kraftkit.sh/exec"
+	"kraftkit.sh/internal/run"
+	"kraftkit.sh/machine/firecracker"
+)
+
+// firecrackerConfigFile is the name of the file in the bundle which holds the
+// microVM configuration read by Firecracker.
+const firecrackerConfigFile = "firecracker.json"
+
+// genFirecrackerConfig returns the microVM configuration for starting the
+// provided machine with Firecracker.
+func genFirecrackerConfig(m *machineapi.Machine) *firecracker.VMConfig {
+	// TODO(nderjung): This is standard "Unikraft" positional argument syntax
+	// (kernel args and application arguments separated with "--").  The resulting
+	// string should be standardized through a central function.
+	args := []string{filepath.Base(m.Status.KernelPath), "--"}
+
+	return &firecracker.VMConfig{
+		BootSource: models.BootSource{
+			KernelImagePath: fcsdk.String(m.Status.KernelPath),
+			InitrdPath:      m.Status.InitrdPath,
+			BootArgs:        run.BootArgsPrepare(args...),
+		},
+		Drives: []models.Drive{},
+		MachineConfig: models.MachineConfiguration{
+			VcpuCount:  fcsdk.Int64(m.Spec.Resources.Requests.Cpu().Value()),
+			MemSizeMib: fcsdk.Int64(m.Spec.Resources.Requests.Memory().Value() / firecracker.FirecrackerMemoryScale),
+		},
+	}
+}
+
+// genFirecrackerArgs writes the microVM configuration of the provided machine
+// to the provided directory and returns the command-line arguments for
+// starting Firecracker with it.  Firecracker is started without its API
+// socket, since the configuration is complete and the console of the
+// unikernel is its standard output.
+func genFirecrackerArgs(m *machineapi.Machine, dir string) ([]string, error) {
+	path := filepath.Join(dir, firecrackerConfigFile)
+
+	cfg, err := json.MarshalIndent(genFirecrackerConfig(m), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding Firecracker config: %w", err)
+	}
+
+	if err := os.WriteFile(path, cfg, 0o644); err != nil {
+		return nil, fmt.Errorf("writing Firecracker config: %w", err)
+	}
+
+	exe, err := exec.NewExecutable(firecracker.FirecrackerBin, firecracker.ExecConfig{
+		ConfigFile: path,
+		NoApi:      true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("preparing machine executable: %w", err)
+	}
+
+	return append([]string{firecracker.FirecrackerBin}, exe.Args()...), nil
+}
@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package create
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"kraftkit.sh/machine/firecracker"
+	mplatform "kraftkit.sh/machine/platform"
+)
+
+func TestGenFirecrackerArgs(t *testing.T) {
+	dir := t.TempDir()
+	m := testMachine(mplatform.PlatformFirecracker, "x86_64")
+
+	args, err := genFirecrackerArgs(m, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, firecrackerConfigFile)
+
+	expectedArgs := []string{
+		firecracker.FirecrackerBin,
+		"--config-file", path,
+		"--no-api",
+	}
+	if !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("expected args %q, got %q", expectedArgs, args)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actual any
+	if err := json.Unmarshal(raw, &actual); err != nil {
+		t.Fatal(err)
+	}
+
+	var expected any
+	if err := json.Unmarshal([]byte(`{
+		"boot-source": {
+			"kernel_image_path": "/bundle/rootfs/unikraft/bin/kernel",
+			"initrd_path": "/bundle/rootfs/unikraft/bin/initrd",
+			"boot_args": "kernel -- "
+		},
+		"drives": [],
+		"machine-config": {
+			"vcpu_count": 1,
+			"mem_size_mib": 64
+		}
+	}`), &expected); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected config:\n%s", raw)
+	}
+}
+
+func TestGenFirecrackerConfigWithoutInitrd(t *testing.T) {
+	m := testMachine(mplatform.PlatformFirecracker, "x86_64")
+	m.Status.InitrdPath = ""
+
+	raw, err := json.Marshal(genFirecrackerConfig(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg struct {
+		BootSource map[string]any `json:"boot-source"`
+	}
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := cfg.BootSource["initrd_path"]; ok {
+		t.Errorf("unexpected initrd in config: %s", raw)
+	}
+}
@@ -0,0 +1,125 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package create
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/Masterminds/semver/v3"
+
+	machineapi "kraftkit.sh/api/machine/v1alpha1"
+	"kraftkit.sh/config"
+	"kraftkit.sh/exec"
+	"kraftkit.sh/machine/qemu"
+)
+
+// qemuBin returns the QEMU binary for the provided architecture.
+func qemuBin(ctx context.Context, arch string) (string, error) {
+	if config.G[config.KraftKit](ctx).Qemu != "" {
+		return config.G[config.KraftKit](ctx).Qemu, nil
+	}
+
+	switch arch {
+	case "x86_64":
+		return qemu.QemuSystemX86, nil
+	case "arm":
+		return qemu.QemuSystemArm, nil
+	case "arm64":
+		return qemu.QemuSystemAarch64, nil
+	default:
+		return "", fmt.Errorf("unsupported machine architecture: %s", arch)
+	}
+}
+
+// genQemuArgs returns the command-line arguments for starting the provided
+// machine with the provided QEMU binary of the provided version.  Unlike the
+// QEMU machine service, the process is not daemonized, since it is the init
+// process of the container and the console of the unikernel is its standard
+// output.  Its QMP socket, at the provided path, is only used to pause and
+// resume the machine.
+func genQemuArgs(m *machineapi.Machine, bin string, version *semver.Version, socket string) ([]string, error) {
+	qopts := []qemu.QemuOption{
+		qemu.WithNoGraphic(true),
+		qemu.WithPidFile(filepath.Join(m.Status.StateDir, "machine.pid")),
+		qemu.WithNoReboot(true),
+		qemu.WithName(m.Name),
+		qemu.WithQMP(qemu.QemuHostCharDevUnix{
+			Path:   socket,
+			NoWait: true,
+			Server: true,
+		}),
+		qemu.WithKernel(m.Status.KernelPath),
+		qemu.WithVGA(qemu.QemuVGANone),
+		qemu.WithMemory(qemu.QemuMemory{
+			// The value returned from Memory() is in bytes
+			Size: uint64(m.Spec.Resources.Requests.Memory().Value() / qemu.QemuMemoryScale),
+			Unit: qemu.QemuMemoryUnitMB,
+		}),
+		qemu.WithSMP(qemu.QemuSMP{
+			CPUs:    uint64(m.Spec.Resources.Requests.Cpu().Value()),
+			Threads: 1,
+			Sockets: 1,
+		}),
+		qemu.WithRTC(qemu.QemuRTC{
+			Base: qemu.QemuRTCBaseUtc,
+		}),
+		qemu.WithDisplay(qemu.QemuDisplayNone{}),
+		qemu.WithParallel(qemu.QemuHostCharDevNone{}),
+	}
+
+	if len(m.Status.InitrdPath) > 0 {
+		qopts = append(qopts,
+			qemu.WithInitRd(m.Status.InitrdPath),
+		)
+	}
+
+	switch m.Spec.Architecture {
+	case "x86_64":
+		qopts = append(qopts,
+			qemu.WithDevice(qemu.QemuDevicePvpanic{}),
+			qemu.WithEnableKVM(true),
+			qemu.WithMachine(qemu.QemuMachine{
+				Type:         qemu.QemuMachineTypePC,
+				Accelerators: []qemu.QemuMachineAccelerator{qemu.QemuMachineAccelKVM},
+			}),
+			qemu.WithCPU(qemu.QemuCPU{
+				CPU: qemu.QemuCPUX86Host,
+				On:  qemu.QemuCPUFeatures{qemu.QemuCPUFeatureX2apic},
+				Off: qemu.QemuCPUFeatures{qemu.QemuCPUFeaturePmu},
+			}),
+		)
+
+		if version.LessThan(qemu.QemuVersion8_0_0) {
+			qopts = append(qopts,
+				qemu.WithDevice(qemu.QemuDeviceSga{}),
+			)
+		}
+	case "arm", "arm64":
+		qopts = append(qopts,
+			qemu.WithMachine(qemu.QemuMachine{
+				Type: qemu.QemuMachineTypeVirt,
+			}),
+			qemu.WithCPU(qemu.QemuCPU{
+				CPU: qemu.QemuCPUArmMax,
+			}),
+		)
+	default:
+		return nil, fmt.Errorf("unsupported machine architecture: %s", m.Spec.Architecture)
+	}
+
+	qcfg, err := qemu.NewQemuConfig(qopts...)
+	if err != nil {
+		return nil, fmt.Errorf("generating QEMU config: %w", err)
+	}
+
+	exe, err := exec.NewExecutable(bin, *qcfg)
+	if err != nil {
+		return nil, fmt.Errorf("preparing machine executable: %w", err)
+	}
+
+	return append([]string{bin}, exe.Args()...), nil
+}
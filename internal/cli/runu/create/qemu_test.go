@@ -0,0 +1,180 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package create
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+
+	"kraftkit.sh/libmocktainer/unikraft"
+	mplatform "kraftkit.sh/machine/platform"
+	"kraftkit.sh/machine/qemu"
+)
+
+// testSocket is the path of the QMP socket of the test machines.
+const testSocket = "/tmp/runu-qmp-1234/qemu_control.sock"
+
+// flagValues returns the values of every occurrence of the provided flag in
+// the provided command-line arguments.
+func flagValues(args []string, flag string) []string {
+	var values []string
+	for i := 0; i < len(args)-1; i++ {
+		if args[i] == flag {
+			values = append(values, args[i+1])
+		}
+	}
+
+	return values
+}
+
+func hasFlag(args []string, flag string) bool {
+	for _, arg := range args {
+		if arg == flag {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestGenQemuArgs(t *testing.T) {
+	version := semver.MustParse("8.2.0")
+
+	m := testMachine(mplatform.PlatformQEMU, "x86_64")
+
+	args, err := genQemuArgs(m, qemu.QemuSystemX86, version, testSocket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if args[0] != qemu.QemuSystemX86 {
+		t.Errorf("expected binary %q, got %q", qemu.QemuSystemX86, args[0])
+	}
+
+	for flag, expected := range map[string][]string{
+		"-kernel":  {m.Status.KernelPath},
+		"-initrd":  {m.Status.InitrdPath},
+		"-m":       {"size=64M"},
+		"-name":    {"my-machine"},
+		"-pidfile": {"/run/runu/my-machine/machine.pid"},
+		"-qmp":     {"unix:" + testSocket + ",server,nowait"},
+	} {
+		if actual := flagValues(args, flag); !reflect.DeepEqual(actual, expected) {
+			t.Errorf("expected %s %q, got %q", flag, expected, actual)
+		}
+	}
+
+	for _, flag := range []string{"-nographic", "-no-reboot", "-enable-kvm"} {
+		if !hasFlag(args, flag) {
+			t.Errorf("expected %s in %q", flag, args)
+		}
+	}
+
+	// The process is the init process of the container and hence must neither
+	// daemonize, wait for a QMP command to start, nor redirect its console.
+	for _, flag := range []string{"-daemonize", "-S", "-monitor", "-serial"} {
+		if hasFlag(args, flag) {
+			t.Errorf("unexpected %s in %q", flag, args)
+		}
+	}
+
+	// Versions of QEMU before 8.0.0 additionally require the SGA device.
+	older, err := genQemuArgs(m, qemu.QemuSystemX86, semver.MustParse("7.2.0"), testSocket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if devices, olderDevices := flagValues(args, "-device"), flagValues(older, "-device"); len(olderDevices) != len(devices)+1 {
+		t.Errorf("expected one more device for QEMU 7.2.0, got %q and %q", olderDevices, devices)
+	}
+}
+
+func TestGenQemuArgsArm64(t *testing.T) {
+	m := testMachine(mplatform.PlatformQEMU, "arm64")
+	m.Status.InitrdPath = ""
+
+	args, err := genQemuArgs(m, qemu.QemuSystemAarch64, semver.MustParse("8.2.0"), testSocket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if args[0] != qemu.QemuSystemAarch64 {
+		t.Errorf("expected binary %q, got %q", qemu.QemuSystemAarch64, args[0])
+	}
+
+	for _, flag := range []string{"-initrd", "-enable-kvm"} {
+		if hasFlag(args, flag) {
+			t.Errorf("unexpected %s in %q", flag, args)
+		}
+	}
+
+	if actual := flagValues(args, "-kernel"); !reflect.DeepEqual(actual, []string{m.Status.KernelPath}) {
+		t.Errorf("expected -kernel %q, got %q", m.Status.KernelPath, actual)
+	}
+}
+
+func TestGenQemuArgsUnsupportedArchitecture(t *testing.T) {
+	m := testMachine(mplatform.PlatformQEMU, "riscv64")
+
+	if _, err := genQemuArgs(m, "qemu-system-riscv64", semver.MustParse("8.2.0"), testSocket); err == nil {
+		t.Error("expected an error for an unsupported architecture")
+	}
+}
+
+func TestGenQemuArgsLongContainerID(t *testing.T) {
+	id := strings.Repeat("0123456789abcdef", 4)
+
+	m := testMachine(mplatform.PlatformQEMU, "x86_64")
+	m.Name = id
+	m.Status.StateDir = filepath.Join("/run/containerd/runc/k8s.io", id)
+
+	socket, err := unikraft.NewQemuControlSocket()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		if err := unikraft.RemoveQemuControlSocket(socket); err != nil {
+			t.Error(err)
+		}
+
+		if _, err := os.Stat(filepath.Dir(socket)); !os.IsNotExist(err) {
+			t.Errorf("expected the directory of the QMP socket to be removed, got %v", err)
+		}
+	})
+
+	args, err := genQemuArgs(m, qemu.QemuSystemX86, semver.MustParse("8.2.0"), socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual, expected := flagValues(args, "-qmp"), []string{"unix:" + socket + ",server,nowait"}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected -qmp %q, got %q", expected, actual)
+	}
+
+	// The path of a UNIX socket must fit into the 108 bytes of sun_path,
+	// including its terminating NUL byte, which a socket in the state directory
+	// does not.
+	if len(socket) >= 108 {
+		t.Errorf("expected the path of the QMP socket to be shorter than 108 bytes, got %q", socket)
+	}
+
+	if len(filepath.Join(m.Status.StateDir, unikraft.QemuControlSocket)) < 108 {
+		t.Fatal("expected the state directory to be too long for a UNIX socket")
+	}
+
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("could not listen on the QMP socket: %v", err)
+	}
+
+	l.Close()
+}
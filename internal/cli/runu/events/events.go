@@ -0,0 +1,237 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/opencontainers/runc/types"
+	"github.com/spf13/cobra"
+
+	"kraftkit.sh/cmdfactory"
+	libcontainer "kraftkit.sh/libmocktainer"
+	"kraftkit.sh/log"
+)
+
+const (
+	flagRoot = "root"
+)
+
+// EventsOptions implements the runc "events" command.
+type EventsOptions struct {
+	Interval time.Duration `long:"interval" usage:"set the stats collection interval" default:"5s"`
+	Stats    bool          `long:"stats" usage:"display the unikernel's stats then exit"`
+
+	rootDir string
+}
+
+func NewCmd() *cobra.Command {
+	cmd, err := cmdfactory.New(&EventsOptions{}, cobra.Command{
+		Short: "Display unikernel events and resource usage statistics",
+		Args:  cobra.ExactArgs(1),
+		Use:   "events [FLAGS] <unikernel-id>",
+		Long: heredoc.Doc(`
+			The events command displays the CPU, memory, pids and IO usage statistics
+			of a unikernel, which are those of the cgroup of its virtual machine
+			monitor, as well as OOM notifications.  By default the statistics are
+			displayed once every 5 seconds until the unikernel stops.
+		`),
+		Example: heredoc.Doc(`
+			# Display the statistics of a unikernel once
+			$ runu events --stats my-unikernel
+
+			# Display the events of a unikernel every second
+			$ runu events --interval 1s my-unikernel
+		`),
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+func (opts *EventsOptions) Pre(cmd *cobra.Command, args []string) error {
+	opts.rootDir = cmd.Flag(flagRoot).Value.String()
+	if opts.rootDir == "" {
+		return fmt.Errorf("state directory (--%s flag) is not set", flagRoot)
+	}
+
+	if opts.Interval <= 0 {
+		return fmt.Errorf("duration interval must be greater than 0")
+	}
+
+	return nil
+}
+
+func (opts *EventsOptions) Run(ctx context.Context, args []string) (retErr error) {
+	defer func() {
+		// Make sure the error is written to the configured log destination, so
+		// that the message gets propagated through the caller (e.g. containerd-shim)
+		if retErr != nil {
+			log.G(ctx).Error(retErr)
+		}
+	}()
+
+	cID := args[0]
+
+	c, err := libcontainer.Load(opts.rootDir, cID)
+	if err != nil {
+		return fmt.Errorf("loading container from saved state: %w", err)
+	}
+
+	status, err := c.Status()
+	if err != nil {
+		return fmt.Errorf("getting container status: %w", err)
+	}
+
+	if status == libcontainer.Stopped {
+		return fmt.Errorf("container with id %s is not running", cID)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+
+	if opts.Stats {
+		s, err := c.Stats()
+		if err != nil {
+			return fmt.Errorf("getting container stats: %w", err)
+		}
+
+		return enc.Encode(&types.Event{Type: "stats", ID: cID, Data: convertLibcontainerStats(s)})
+	}
+
+	// OOM kills are detected from the increase of the counter of the cgroup
+	// between two intervals.
+	ooms, err := c.OOMKillCount()
+	if err != nil {
+		log.G(ctx).Warnf("could not get OOM kill count: %v", err)
+	}
+
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if status, err := c.Status(); err != nil {
+			return fmt.Errorf("getting container status: %w", err)
+		} else if status == libcontainer.Stopped {
+			return nil
+		}
+
+		if count, err := c.OOMKillCount(); err == nil && count > ooms {
+			ooms = count
+			if err := enc.Encode(&types.Event{Type: "oom", ID: cID}); err != nil {
+				log.G(ctx).Error(err)
+			}
+		}
+
+		s, err := c.Stats()
+		if err != nil {
+			log.G(ctx).Error(err)
+			continue
+		}
+
+		if err := enc.Encode(&types.Event{Type: "stats", ID: cID, Data: convertLibcontainerStats(s)}); err != nil {
+			log.G(ctx).Error(err)
+		}
+	}
+}
+
+/*
+Copyright 2014 Docker, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+func convertLibcontainerStats(ls *libcontainer.Stats) *types.Stats {
+	cg := ls.CgroupStats
+	if cg == nil {
+		return nil
+	}
+	var s types.Stats
+	s.Pids.Current = cg.PidsStats.Current
+	s.Pids.Limit = cg.PidsStats.Limit
+
+	s.CPU.Usage.Kernel = cg.CpuStats.CpuUsage.UsageInKernelmode
+	s.CPU.Usage.User = cg.CpuStats.CpuUsage.UsageInUsermode
+	s.CPU.Usage.Total = cg.CpuStats.CpuUsage.TotalUsage
+	s.CPU.Usage.Percpu = cg.CpuStats.CpuUsage.PercpuUsage
+	s.CPU.Usage.PercpuKernel = cg.CpuStats.CpuUsage.PercpuUsageInKernelmode
+	s.CPU.Usage.PercpuUser = cg.CpuStats.CpuUsage.PercpuUsageInUsermode
+	s.CPU.Throttling.Periods = cg.CpuStats.ThrottlingData.Periods
+	s.CPU.Throttling.ThrottledPeriods = cg.CpuStats.ThrottlingData.ThrottledPeriods
+	s.CPU.Throttling.ThrottledTime = cg.CpuStats.ThrottlingData.ThrottledTime
+
+	s.CPUSet = types.CPUSet(cg.CPUSetStats)
+
+	s.Memory.Cache = cg.MemoryStats.Cache
+	s.Memory.Kernel = convertMemoryEntry(cg.MemoryStats.KernelUsage)
+	s.Memory.KernelTCP = convertMemoryEntry(cg.MemoryStats.KernelTCPUsage)
+	s.Memory.Swap = convertMemoryEntry(cg.MemoryStats.SwapUsage)
+	s.Memory.Usage = convertMemoryEntry(cg.MemoryStats.Usage)
+	s.Memory.Raw = cg.MemoryStats.Stats
+
+	s.Blkio.IoServiceBytesRecursive = convertBlkioEntry(cg.BlkioStats.IoServiceBytesRecursive)
+	s.Blkio.IoServicedRecursive = convertBlkioEntry(cg.BlkioStats.IoServicedRecursive)
+	s.Blkio.IoQueuedRecursive = convertBlkioEntry(cg.BlkioStats.IoQueuedRecursive)
+	s.Blkio.IoServiceTimeRecursive = convertBlkioEntry(cg.BlkioStats.IoServiceTimeRecursive)
+	s.Blkio.IoWaitTimeRecursive = convertBlkioEntry(cg.BlkioStats.IoWaitTimeRecursive)
+	s.Blkio.IoMergedRecursive = convertBlkioEntry(cg.BlkioStats.IoMergedRecursive)
+	s.Blkio.IoTimeRecursive = convertBlkioEntry(cg.BlkioStats.IoTimeRecursive)
+	s.Blkio.SectorsRecursive = convertBlkioEntry(cg.BlkioStats.SectorsRecursive)
+
+	s.Hugetlb = make(map[string]types.Hugetlb)
+	for k, v := range cg.HugetlbStats {
+		s.Hugetlb[k] = convertHugtlb(v)
+	}
+
+	return &s
+}
+
+func convertHugtlb(c cgroups.HugetlbStats) types.Hugetlb {
+	return types.Hugetlb{
+		Usage:   c.Usage,
+		Max:     c.MaxUsage,
+		Failcnt: c.Failcnt,
+	}
+}
+
+func convertMemoryEntry(c cgroups.MemoryData) types.MemoryEntry {
+	return types.MemoryEntry{
+		Limit:   c.Limit,
+		Usage:   c.Usage,
+		Max:     c.MaxUsage,
+		Failcnt: c.Failcnt,
+	}
+}
+
+func convertBlkioEntry(c []cgroups.BlkioStatEntry) []types.BlkioEntry {
+	var out []types.BlkioEntry
+	for _, e := range c {
+		out = append(out, types.BlkioEntry(e))
+	}
+	return out
+}
@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package features
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	"github.com/MakeNowJust/heredoc"
+	rtspec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/opencontainers/runtime-spec/specs-go/features"
+	"github.com/spf13/cobra"
+
+	"kraftkit.sh/cmdfactory"
+	"kraftkit.sh/internal/version"
+	"kraftkit.sh/libmocktainer/configs"
+	"kraftkit.sh/libmocktainer/specconv"
+	"kraftkit.sh/oci"
+)
+
+const (
+	annotationRunuVersion = "org.unikraft.runu.version"
+	annotationRunuCommit  = "org.unikraft.runu.commit"
+
+	specAnnotCRIContainerType = "io.kubernetes.cri.container-type"
+)
+
+// FeaturesOptions implements the OCI "features" command.
+type FeaturesOptions struct{}
+
+func NewCmd() *cobra.Command {
+	cmd, err := cmdfactory.New(&FeaturesOptions{}, cobra.Command{
+		Short: "Show the enabled features",
+		Args:  cobra.NoArgs,
+		Use:   "features",
+		Long: heredoc.Doc(`
+			The features command shows the features supported by runu as JSON, in the
+			format of the OCI runtime features structure.
+
+			The annotations of the spec which change how a unikernel is run are listed
+			as potentially unsafe config annotations.
+		`),
+		Example: heredoc.Doc(`
+			# Show the enabled features
+			$ runu features
+		`),
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+func (opts *FeaturesOptions) Run(_ context.Context, _ []string) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "    ")
+
+	return enc.Encode(supportedFeatures())
+}
+
+// supportedFeatures returns the features supported by runu.
+func supportedFeatures() *features.Features {
+	t := true
+	f := false
+
+	return &features.Features{
+		OCIVersionMin: "1.0.0",
+		OCIVersionMax: rtspec.Version,
+		Hooks:         configs.KnownHookNames(),
+		Linux: &features.Linux{
+			Namespaces: specconv.KnownNamespaces(),
+			Cgroup: &features.Cgroup{
+				V1:          &t,
+				V2:          &t,
+				Systemd:     &t,
+				SystemdUser: &f,
+			},
+			Apparmor: &features.Apparmor{
+				Enabled: &f,
+			},
+			Selinux: &features.Selinux{
+				Enabled: &f,
+			},
+		},
+		Annotations: map[string]string{
+			annotationRunuVersion: version.Version(),
+			annotationRunuCommit:  version.Commit(),
+		},
+		PotentiallyUnsafeConfigAnnotations: []string{
+			// Selects the virtual machine monitor of the unikernel.
+			oci.AnnotationKernelPlat,
+			// Runs the process of a CRI sandbox as is instead of a unikernel.
+			specAnnotCRIContainerType,
+		},
+	}
+}
@@ -0,0 +1,25 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package features
+
+import (
+	"reflect"
+	"testing"
+
+	"kraftkit.sh/oci"
+)
+
+func TestSupportedFeatures(t *testing.T) {
+	f := supportedFeatures()
+
+	if expected := []string{"network"}; !reflect.DeepEqual(f.Linux.Namespaces, expected) {
+		t.Errorf("expected namespaces %q, got %q", expected, f.Linux.Namespaces)
+	}
+
+	expected := []string{oci.AnnotationKernelPlat, "io.kubernetes.cri.container-type"}
+	if !reflect.DeepEqual(f.PotentiallyUnsafeConfigAnnotations, expected) {
+		t.Errorf("expected annotations %q, got %q", expected, f.PotentiallyUnsafeConfigAnnotations)
+	}
+}
@@ -0,0 +1,107 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package pause
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"kraftkit.sh/cmdfactory"
+	libcontainer "kraftkit.sh/libmocktainer"
+	"kraftkit.sh/libmocktainer/unikraft"
+	"kraftkit.sh/log"
+)
+
+const (
+	flagRoot = "root"
+)
+
+// PauseOptions implements the OCI "pause" command.
+type PauseOptions struct {
+	rootDir string
+}
+
+func NewCmd() *cobra.Command {
+	cmd, err := cmdfactory.New(&PauseOptions{}, cobra.Command{
+		Short: "Pause a unikernel",
+		Args:  cobra.ExactArgs(1),
+		Use:   "pause <unikernel-id>",
+		Long: heredoc.Doc(`
+			The pause command suspends the execution of a unikernel.
+
+			The virtual CPUs of a unikernel run with QEMU are stopped via QMP, and the
+			virtual machine monitor is frozen in its cgroup.
+		`),
+		Example: heredoc.Doc(`
+			# Pause a unikernel
+			$ runu pause my-unikernel
+		`),
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+func (opts *PauseOptions) Pre(cmd *cobra.Command, args []string) error {
+	opts.rootDir = cmd.Flag(flagRoot).Value.String()
+	if opts.rootDir == "" {
+		return fmt.Errorf("state directory (--%s flag) is not set", flagRoot)
+	}
+
+	return nil
+}
+
+func (opts *PauseOptions) Run(ctx context.Context, args []string) (retErr error) {
+	defer func() {
+		// Make sure the error is written to the configured log destination, so
+		// that the message gets propagated through the caller (e.g. containerd-shim)
+		if retErr != nil {
+			log.G(ctx).Error(retErr)
+		}
+	}()
+
+	cID := args[0]
+
+	c, err := libcontainer.Load(opts.rootDir, cID)
+	if err != nil {
+		return fmt.Errorf("loading container from saved state: %w", err)
+	}
+
+	status, err := c.Status()
+	if err != nil {
+		return fmt.Errorf("getting container status: %w", err)
+	}
+
+	switch status {
+	case libcontainer.Paused:
+		return nil
+	case libcontainer.Running, libcontainer.Created:
+	default:
+		return libcontainer.ErrNotRunning
+	}
+
+	socket := unikraft.QemuControlSocketFromLabels(c.Config().Labels)
+
+	// The machine is stopped before its monitor is frozen, since QEMU can no
+	// longer answer to QMP commands afterwards.
+	if err := unikraft.StopQemu(socket); err != nil {
+		return fmt.Errorf("pausing machine: %w", err)
+	}
+
+	if err := c.Pause(); err != nil {
+		if cerr := unikraft.ContQemu(socket); cerr != nil {
+			log.G(ctx).Warnf("could not resume machine: %v", cerr)
+		}
+
+		return fmt.Errorf("pausing container: %w", err)
+	}
+
+	return nil
+}
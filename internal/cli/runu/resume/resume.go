@@ -0,0 +1,83 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package resume
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"kraftkit.sh/cmdfactory"
+	libcontainer "kraftkit.sh/libmocktainer"
+	"kraftkit.sh/libmocktainer/unikraft"
+	"kraftkit.sh/log"
+)
+
+const (
+	flagRoot = "root"
+)
+
+// ResumeOptions implements the OCI "resume" command.
+type ResumeOptions struct {
+	rootDir string
+}
+
+func NewCmd() *cobra.Command {
+	cmd, err := cmdfactory.New(&ResumeOptions{}, cobra.Command{
+		Short: "Resume a paused unikernel",
+		Args:  cobra.ExactArgs(1),
+		Use:   "resume <unikernel-id>",
+		Long:  "The resume command resumes the execution of a unikernel which has been paused.",
+		Example: heredoc.Doc(`
+			# Resume a unikernel
+			$ runu resume my-unikernel
+		`),
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+func (opts *ResumeOptions) Pre(cmd *cobra.Command, args []string) error {
+	opts.rootDir = cmd.Flag(flagRoot).Value.String()
+	if opts.rootDir == "" {
+		return fmt.Errorf("state directory (--%s flag) is not set", flagRoot)
+	}
+
+	return nil
+}
+
+func (opts *ResumeOptions) Run(ctx context.Context, args []string) (retErr error) {
+	defer func() {
+		// Make sure the error is written to the configured log destination, so
+		// that the message gets propagated through the caller (e.g. containerd-shim)
+		if retErr != nil {
+			log.G(ctx).Error(retErr)
+		}
+	}()
+
+	cID := args[0]
+
+	c, err := libcontainer.Load(opts.rootDir, cID)
+	if err != nil {
+		return fmt.Errorf("loading container from saved state: %w", err)
+	}
+
+	if err := c.Resume(); err != nil {
+		return fmt.Errorf("resuming container: %w", err)
+	}
+
+	socket := unikraft.QemuControlSocketFromLabels(c.Config().Labels)
+
+	if err := unikraft.ContQemu(socket); err != nil {
+		return fmt.Errorf("resuming machine: %w", err)
+	}
+
+	return nil
+}
@@ -24,10 +24,15 @@ import (
 
 	"kraftkit.sh/internal/cli/runu/create"
 	"kraftkit.sh/internal/cli/runu/delete"
+	"kraftkit.sh/internal/cli/runu/events"
+	"kraftkit.sh/internal/cli/runu/features"
 	"kraftkit.sh/internal/cli/runu/kill"
+	"kraftkit.sh/internal/cli/runu/pause"
 	"kraftkit.sh/internal/cli/runu/ps"
+	"kraftkit.sh/internal/cli/runu/resume"
 	"kraftkit.sh/internal/cli/runu/start"
 	"kraftkit.sh/internal/cli/runu/state"
+	"kraftkit.sh/internal/cli/runu/update"
 )
 
 // [OCI runtime] for unikernels. Implements the runc [command-line interface].
@@ -59,6 +64,11 @@ func NewCmd() *cobra.Command {
 	cmd.AddCommand(kill.NewCmd())
 	cmd.AddCommand(delete.NewCmd())
 	cmd.AddCommand(ps.NewCmd())
+	cmd.AddCommand(pause.NewCmd())
+	cmd.AddCommand(resume.NewCmd())
+	cmd.AddCommand(update.NewCmd())
+	cmd.AddCommand(events.NewCmd())
+	cmd.AddCommand(features.NewCmd())
 
 	return cmd
 }
@@ -0,0 +1,314 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/dustin/go-humanize"
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	rtspec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/spf13/cobra"
+
+	"kraftkit.sh/cmdfactory"
+	libcontainer "kraftkit.sh/libmocktainer"
+	"kraftkit.sh/libmocktainer/configs"
+	"kraftkit.sh/log"
+)
+
+const (
+	flagRoot = "root"
+)
+
+// UpdateOptions implements the OCI "update" command.
+type UpdateOptions struct {
+	Resources         string `long:"resources" short:"r" usage:"path to the file containing the resources to update or '-' to read from the standard input"`
+	BlkioWeight       uint   `long:"blkio-weight" usage:"specifies per cgroup weight, range is from 10 to 1000"`
+	CPUPeriod         string `long:"cpu-period" usage:"CPU CFS period to be used for hardcapping (in usecs). 0 to use system default"`
+	CPUQuota          string `long:"cpu-quota" usage:"CPU CFS hardcap limit (in usecs). Allowed cpu time in a given period"`
+	CPUShare          string `long:"cpu-share" usage:"CPU shares (relative weight vs. other containers)"`
+	CPURtPeriod       string `long:"cpu-rt-period" usage:"CPU realtime period to be used for hardcapping (in usecs). 0 to use system default"`
+	CPURtRuntime      string `long:"cpu-rt-runtime" usage:"CPU realtime hardcap limit (in usecs). Allowed cpu time in a given period"`
+	CpusetCpus        string `long:"cpuset-cpus" usage:"CPU(s) to use"`
+	CpusetMems        string `long:"cpuset-mems" usage:"memory node(s) to use"`
+	Memory            string `long:"memory" usage:"memory limit of the virtual machine monitor (in bytes)"`
+	MemoryReservation string `long:"memory-reservation" usage:"memory reservation or soft_limit (in bytes)"`
+	MemorySwap        string `long:"memory-swap" usage:"total memory usage (memory + swap); set '-1' to enable unlimited swap"`
+	PidsLimit         int    `long:"pids-limit" usage:"maximum number of pids allowed in the unikernel's cgroup"`
+
+	rootDir string
+}
+
+func NewCmd() *cobra.Command {
+	cmd, err := cmdfactory.New(&UpdateOptions{}, cobra.Command{
+		Short: "Update the resource constraints of a unikernel",
+		Args:  cobra.ExactArgs(1),
+		Use:   "update [FLAGS] <unikernel-id>",
+		Long: heredoc.Doc(`
+			The update command updates the resource constraints of a unikernel.
+
+			The constraints are applied to the cgroup of the virtual machine monitor
+			of the unikernel.  The memory and the virtual CPUs of the machine itself
+			are set when it is created and remain unchanged, such that lowering the
+			memory limit below the memory of the machine causes the monitor to be
+			killed by the OOM killer when the memory is used by the unikernel.
+
+			If the resources are read from a file or the standard input, all other
+			options are ignored.  The accepted format is the "linux.resources" object
+			of the OCI runtime specification, where unchanged values can be omitted.
+		`),
+		Example: heredoc.Doc(`
+			# Limit the CPU time of a unikernel to half a CPU
+			$ runu update --cpu-quota 50000 --cpu-period 100000 my-unikernel
+
+			# Update the resources of a unikernel as containerd does
+			$ echo '{"memory": {"limit": 268435456}}' | runu update -r - my-unikernel
+		`),
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+func (opts *UpdateOptions) Pre(cmd *cobra.Command, args []string) error {
+	opts.rootDir = cmd.Flag(flagRoot).Value.String()
+	if opts.rootDir == "" {
+		return fmt.Errorf("state directory (--%s flag) is not set", flagRoot)
+	}
+
+	return nil
+}
+
+func (opts *UpdateOptions) Run(ctx context.Context, args []string) (retErr error) {
+	defer func() {
+		// Make sure the error is written to the configured log destination, so
+		// that the message gets propagated through the caller (e.g. containerd-shim)
+		if retErr != nil {
+			log.G(ctx).Error(retErr)
+		}
+	}()
+
+	cID := args[0]
+
+	c, err := libcontainer.Load(opts.rootDir, cID)
+	if err != nil {
+		return fmt.Errorf("loading container from saved state: %w", err)
+	}
+
+	var r *rtspec.LinuxResources
+
+	switch opts.Resources {
+	case "":
+		r, err = opts.resources()
+	case "-":
+		r, err = ReadResources(os.Stdin)
+	default:
+		var f *os.File
+		if f, err = os.Open(opts.Resources); err != nil {
+			return fmt.Errorf("opening resources file: %w", err)
+		}
+
+		defer f.Close()
+
+		r, err = ReadResources(f)
+	}
+	if err != nil {
+		return err
+	}
+
+	return UpdateContainer(c, r)
+}
+
+// Container is a container whose cgroup resources can be updated.
+type Container interface {
+	Config() configs.Config
+	Set(configs.Config) error
+}
+
+// UpdateContainer updates the cgroup resources of the given container with the
+// given resources.
+func UpdateContainer(c Container, r *rtspec.LinuxResources) error {
+	config := c.Config()
+	applyResources(config.Cgroups.Resources, r)
+
+	// The devices of the cgroup are never restricted, since the virtual machine
+	// monitor requires access to devices which the spec does not allow.
+	config.Cgroups.SkipDevices = true
+
+	if err := c.Set(config); err != nil {
+		return fmt.Errorf("updating container resources: %w", err)
+	}
+
+	return nil
+}
+
+func i64Ptr(i int64) *int64   { return &i }
+func u64Ptr(i uint64) *uint64 { return &i }
+func u16Ptr(i uint16) *uint16 { return &i }
+
+// emptyResources returns resources where every value which can be updated is
+// unset, such that unchanged values can be omitted.
+func emptyResources() *rtspec.LinuxResources {
+	return &rtspec.LinuxResources{
+		Memory: &rtspec.LinuxMemory{
+			Limit:       i64Ptr(0),
+			Reservation: i64Ptr(0),
+			Swap:        i64Ptr(0),
+		},
+		CPU: &rtspec.LinuxCPU{
+			Shares:          u64Ptr(0),
+			Quota:           i64Ptr(0),
+			Period:          u64Ptr(0),
+			RealtimeRuntime: i64Ptr(0),
+			RealtimePeriod:  u64Ptr(0),
+		},
+		BlockIO: &rtspec.LinuxBlockIO{
+			Weight: u16Ptr(0),
+		},
+		Pids: &rtspec.LinuxPids{},
+	}
+}
+
+// ReadResources decodes the resources to update from the given reader.
+func ReadResources(in io.Reader) (*rtspec.LinuxResources, error) {
+	r := emptyResources()
+
+	if err := json.NewDecoder(in).Decode(r); err != nil {
+		return nil, fmt.Errorf("decoding resources: %w", err)
+	}
+
+	// Objects which are explicitly null in the input are reset to their empty
+	// value.
+	empty := emptyResources()
+	if r.Memory == nil {
+		r.Memory = empty.Memory
+	}
+	if r.CPU == nil {
+		r.CPU = empty.CPU
+	}
+	if r.BlockIO == nil {
+		r.BlockIO = empty.BlockIO
+	}
+	if r.Pids == nil {
+		r.Pids = empty.Pids
+	}
+
+	return r, nil
+}
+
+// resources returns the resources to update from the command-line options.
+func (opts *UpdateOptions) resources() (*rtspec.LinuxResources, error) {
+	r := emptyResources()
+
+	if opts.BlkioWeight != 0 {
+		r.BlockIO.Weight = u16Ptr(uint16(opts.BlkioWeight))
+	}
+
+	r.CPU.Cpus = opts.CpusetCpus
+	r.CPU.Mems = opts.CpusetMems
+
+	for _, pair := range []struct {
+		opt  string
+		val  string
+		dest *uint64
+	}{
+		{"cpu-period", opts.CPUPeriod, r.CPU.Period},
+		{"cpu-rt-period", opts.CPURtPeriod, r.CPU.RealtimePeriod},
+		{"cpu-share", opts.CPUShare, r.CPU.Shares},
+	} {
+		if pair.val != "" {
+			var err error
+			if *pair.dest, err = strconv.ParseUint(pair.val, 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid value for %s: %w", pair.opt, err)
+			}
+		}
+	}
+
+	for _, pair := range []struct {
+		opt  string
+		val  string
+		dest *int64
+	}{
+		{"cpu-quota", opts.CPUQuota, r.CPU.Quota},
+		{"cpu-rt-runtime", opts.CPURtRuntime, r.CPU.RealtimeRuntime},
+	} {
+		if pair.val != "" {
+			var err error
+			if *pair.dest, err = strconv.ParseInt(pair.val, 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid value for %s: %w", pair.opt, err)
+			}
+		}
+	}
+
+	for _, pair := range []struct {
+		opt  string
+		val  string
+		dest *int64
+	}{
+		{"memory", opts.Memory, r.Memory.Limit},
+		{"memory-reservation", opts.MemoryReservation, r.Memory.Reservation},
+		{"memory-swap", opts.MemorySwap, r.Memory.Swap},
+	} {
+		if pair.val == "" {
+			continue
+		}
+
+		if pair.val == "-1" {
+			*pair.dest = -1
+			continue
+		}
+
+		v, err := humanize.ParseBytes(pair.val)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", pair.opt, err)
+		}
+
+		*pair.dest = int64(v)
+	}
+
+	r.Pids.Limit = int64(opts.PidsLimit)
+
+	return r, nil
+}
+
+// applyResources updates the cgroup resources of a container with the given
+// resources.
+func applyResources(res *configs.Resources, r *rtspec.LinuxResources) {
+	res.BlkioWeight = *r.BlockIO.Weight
+
+	// Setting CPU quota and period independently does not make much sense,
+	// but runc allows it.  If only one of them is set, the other one is left
+	// at its previous value.
+	p, q := *r.CPU.Period, *r.CPU.Quota
+	if (p == 0 && q == 0) || (p != 0 && q != 0) {
+		res.CpuPeriod = p
+		res.CpuQuota = q
+	} else if p != 0 {
+		res.CpuPeriod = p
+	} else {
+		res.CpuQuota = q
+	}
+
+	res.CpuShares = *r.CPU.Shares
+	// CpuWeight is used for cgroupv2 and should be converted
+	res.CpuWeight = cgroups.ConvertCPUSharesToCgroupV2Value(*r.CPU.Shares)
+	res.CpuRtPeriod = *r.CPU.RealtimePeriod
+	res.CpuRtRuntime = *r.CPU.RealtimeRuntime
+	res.CpusetCpus = r.CPU.Cpus
+	res.CpusetMems = r.CPU.Mems
+	res.Memory = *r.Memory.Limit
+	res.MemoryReservation = *r.Memory.Reservation
+	res.MemorySwap = *r.Memory.Swap
+	res.PidsLimit = r.Pids.Limit
+	res.Unified = r.Unified
+}
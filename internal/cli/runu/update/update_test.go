@@ -0,0 +1,82 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package update
+
+import (
+	"strings"
+	"testing"
+
+	"kraftkit.sh/libmocktainer/configs"
+)
+
+func TestReadResources(t *testing.T) {
+	r, err := ReadResources(strings.NewReader(`{"memory": {"limit": 268435456}, "cpu": null}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := &configs.Resources{
+		Memory:    134217728,
+		CpuQuota:  50000,
+		CpuPeriod: 100000,
+	}
+
+	applyResources(res, r)
+
+	if res.Memory != 268435456 {
+		t.Errorf("expected memory limit 268435456, got %d", res.Memory)
+	}
+
+	if res.CpuQuota != 0 || res.CpuPeriod != 0 {
+		t.Errorf("expected unset CPU quota and period, got %d and %d", res.CpuQuota, res.CpuPeriod)
+	}
+}
+
+func TestOptionsResources(t *testing.T) {
+	opts := &UpdateOptions{
+		CPUQuota:   "25000",
+		Memory:     "256MiB",
+		MemorySwap: "-1",
+		PidsLimit:  64,
+	}
+
+	r, err := opts.resources()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := &configs.Resources{
+		CpuQuota:  50000,
+		CpuPeriod: 100000,
+	}
+
+	applyResources(res, r)
+
+	if res.CpuQuota != 25000 || res.CpuPeriod != 100000 {
+		t.Errorf("expected CPU quota 25000 with the previous period, got %d and %d", res.CpuQuota, res.CpuPeriod)
+	}
+
+	if res.Memory != 256<<20 {
+		t.Errorf("expected memory limit %d, got %d", 256<<20, res.Memory)
+	}
+
+	if res.MemorySwap != -1 {
+		t.Errorf("expected unlimited swap, got %d", res.MemorySwap)
+	}
+
+	if res.PidsLimit != 64 {
+		t.Errorf("expected pids limit 64, got %d", res.PidsLimit)
+	}
+}
+
+func TestOptionsResourcesInvalid(t *testing.T) {
+	opts := &UpdateOptions{
+		CPUShare: "-1",
+	}
+
+	if _, err := opts.resources(); err == nil {
+		t.Error("expected an error for negative CPU shares")
+	}
+}
@@ -0,0 +1,461 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package cloudemulator
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+)
+
+const (
+	// autoscalePolicyTypeStep is the type of a policy which adjusts the size
+	// of a service group by the step whose bounds contain its metric.
+	autoscalePolicyTypeStep = "step"
+
+	// autoscaleMinTimeMs is the shortest warmup and cooldown time.
+	autoscaleMinTimeMs = 10
+
+	// autoscaleDefaultTimeMs is the warmup and cooldown time unless another
+	// one is provided.
+	autoscaleDefaultTimeMs = 1000
+)
+
+var (
+	// autoscaleMetrics are the metrics which policies can be based on.
+	autoscaleMetrics = []string{"cpu"}
+
+	// autoscaleAdjustmentTypes are the ways in which policies can adjust the
+	// size of a service group.
+	autoscaleAdjustmentTypes = []string{"change", "exact", "percent"}
+)
+
+type createAutoscaleRequest struct {
+	Reference
+
+	MinSize        *int              `json:"min_size"`
+	MaxSize        *int              `json:"max_size"`
+	WarmupTimeMs   *int              `json:"warmup_time_ms"`
+	CooldownTimeMs *int              `json:"cooldown_time_ms"`
+	Master         *Reference        `json:"master"`
+	Policies       []AutoscalePolicy `json:"policies"`
+}
+
+type autoscalePolicyRequest struct {
+	Name string `json:"name"`
+}
+
+// intOr returns the value of the provided pointer or the provided default if
+// it is nil.
+func intOr(v *int, def int) int {
+	if v == nil {
+		return def
+	}
+
+	return *v
+}
+
+// newAutoscalePolicy returns the provided policy once validated.  Policies are
+// enabled and step policies unless stated otherwise.
+func newAutoscalePolicy(policy AutoscalePolicy) (AutoscalePolicy, error) {
+	if len(policy.Name) == 0 {
+		return policy, fmt.Errorf("no policy name provided")
+	}
+
+	if len(policy.Type) == 0 {
+		policy.Type = autoscalePolicyTypeStep
+	}
+
+	if policy.Type != autoscalePolicyTypeStep {
+		return policy, fmt.Errorf("unsupported policy type %s", policy.Type)
+	}
+
+	if !slices.Contains(autoscaleMetrics, policy.Metric) {
+		return policy, fmt.Errorf("unsupported metric %q: expected one of %v", policy.Metric, autoscaleMetrics)
+	}
+
+	if len(policy.AdjustmentType) == 0 {
+		policy.AdjustmentType = autoscaleAdjustmentTypes[0]
+	}
+
+	if !slices.Contains(autoscaleAdjustmentTypes, policy.AdjustmentType) {
+		return policy, fmt.Errorf("unsupported adjustment type %q: expected one of %v", policy.AdjustmentType, autoscaleAdjustmentTypes)
+	}
+
+	if len(policy.Steps) == 0 {
+		return policy, fmt.Errorf("no steps provided")
+	}
+
+	for i, step := range policy.Steps {
+		if step.LowerBound != nil && step.UpperBound != nil && *step.LowerBound >= *step.UpperBound {
+			return policy, fmt.Errorf("step %d has an empty range", i)
+		}
+
+		// Only the first step may be unbounded below and the last unbounded
+		// above, and each step starts where the previous one ends.
+		if i > 0 {
+			prev := policy.Steps[i-1]
+			if prev.UpperBound == nil || step.LowerBound == nil || *prev.UpperBound != *step.LowerBound {
+				return policy, fmt.Errorf("steps %d and %d are not contiguous", i-1, i)
+			}
+		}
+	}
+
+	policy.status = status{}
+	policy.Enabled = true
+
+	return policy, nil
+}
+
+// newAutoscaleConfiguration returns the autoscale configuration of the
+// provided service group as requested.  The emulator records the
+// configuration without scaling the service group.
+func newAutoscaleConfiguration(group *ServiceGroup, req createAutoscaleRequest) (*AutoscaleConfiguration, error) {
+	minSize := intOr(req.MinSize, 0)
+	maxSize := intOr(req.MaxSize, limits.MaxAutoscaleSize)
+	warmup := intOr(req.WarmupTimeMs, autoscaleDefaultTimeMs)
+	cooldown := intOr(req.CooldownTimeMs, autoscaleDefaultTimeMs)
+
+	if maxSize < limits.MinAutoscaleSize || maxSize > limits.MaxAutoscaleSize {
+		return nil, fmt.Errorf("maximum size %d is out of range [%d, %d]", maxSize, limits.MinAutoscaleSize, limits.MaxAutoscaleSize)
+	}
+
+	if minSize < 0 || minSize > maxSize {
+		return nil, fmt.Errorf("minimum size %d is out of range [0, %d]", minSize, maxSize)
+	}
+
+	if warmup < autoscaleMinTimeMs || cooldown < autoscaleMinTimeMs {
+		return nil, fmt.Errorf("warmup and cooldown time must be at least %dms", autoscaleMinTimeMs)
+	}
+
+	if req.Master == nil {
+		return nil, fmt.Errorf("no master instance provided")
+	}
+
+	m := slices.IndexFunc(group.Instances, func(ref Reference) bool {
+		return req.Master.matches(ref.UUID, ref.Name)
+	})
+	if m < 0 {
+		return nil, fmt.Errorf("instance %s is not in service group %s", req.Master, group.Name)
+	}
+
+	master := group.Instances[m]
+
+	policies := make([]AutoscalePolicy, len(req.Policies))
+	for i, policy := range req.Policies {
+		policy, err := newAutoscalePolicy(policy)
+		if err != nil {
+			return nil, err
+		}
+
+		if slices.ContainsFunc(policies[:i], func(p AutoscalePolicy) bool { return p.Name == policy.Name }) {
+			return nil, fmt.Errorf("policy %s already exists", policy.Name)
+		}
+
+		policies[i] = policy
+	}
+
+	return &AutoscaleConfiguration{
+		UUID:           group.UUID,
+		Name:           group.Name,
+		Enabled:        true,
+		MinSize:        &minSize,
+		MaxSize:        &maxSize,
+		WarmupTimeMs:   &warmup,
+		CooldownTimeMs: &cooldown,
+		Master:         &master,
+		Policies:       policies,
+	}, nil
+}
+
+// viewAutoscale returns the autoscale configuration of the provided service
+// group as it is reported, which is disabled if the service group has none.
+func (group *ServiceGroup) viewAutoscale() AutoscaleConfiguration {
+	if group.autoscale == nil {
+		return AutoscaleConfiguration{
+			status: succeeded(),
+			UUID:   group.UUID,
+			Name:   group.Name,
+		}
+	}
+
+	ret := *group.autoscale
+	ret.status = succeeded()
+	ret.Policies = slices.Clone(group.autoscale.Policies)
+
+	return ret
+}
+
+func (e *Emulator) createAutoscaleConfigurations(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	reqs, err := referencedRequests[createAutoscaleRequest](r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if len(reqs) == 0 {
+		respondError(w, http.StatusBadRequest, "no autoscale configurations provided")
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	items := make([]item, len(reqs))
+	for i, req := range reqs {
+		group := e.group(req.Reference)
+		if group == nil {
+			items[i] = failureOf(req.Reference, http.StatusNotFound, errNotFound("service group", req.Reference))
+			continue
+		}
+
+		if group.autoscale != nil {
+			items[i] = failureOf(req.Reference, http.StatusConflict, fmt.Errorf("service group %s already has an autoscale configuration", group.Name))
+			continue
+		}
+
+		config, err := newAutoscaleConfiguration(group, req)
+		if err != nil {
+			items[i] = failureOf(req.Reference, http.StatusBadRequest, err)
+			continue
+		}
+
+		group.autoscale = config
+
+		items[i] = summaryOf(group.UUID, group.Name)
+	}
+
+	respond(w, start, "service_groups", items)
+}
+
+func (e *Emulator) getAutoscaleConfigurations(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	refs, err := referencedRequests[Reference](r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if len(refs) == 0 {
+		respondError(w, http.StatusBadRequest, "no service groups provided")
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	items := make([]item, len(refs))
+	for i, ref := range refs {
+		group := e.group(ref)
+		if group == nil {
+			items[i] = failureOf(ref, http.StatusNotFound, errNotFound("service group", ref))
+			continue
+		}
+
+		items[i] = group.viewAutoscale()
+	}
+
+	respond(w, start, "service_groups", items)
+}
+
+func (e *Emulator) deleteAutoscaleConfigurations(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	refs, err := referencedRequests[Reference](r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if len(refs) == 0 {
+		respondError(w, http.StatusBadRequest, "no service groups provided")
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	items := make([]item, len(refs))
+	for i, ref := range refs {
+		group := e.group(ref)
+		if group == nil {
+			items[i] = failureOf(ref, http.StatusNotFound, errNotFound("service group", ref))
+			continue
+		}
+
+		if group.autoscale == nil {
+			items[i] = failureOf(ref, http.StatusNotFound, fmt.Errorf("service group %s has no autoscale configuration", group.Name))
+			continue
+		}
+
+		group.autoscale = nil
+
+		items[i] = summaryOf(group.UUID, group.Name)
+	}
+
+	respond(w, start, "service_groups", items)
+}
+
+// autoscaleOfPath returns the autoscale configuration of the service group
+// referenced by the path of the provided request, or writes the failure.
+func (e *Emulator) autoscaleOfPath(w http.ResponseWriter, r *http.Request) (*AutoscaleConfiguration, bool) {
+	ref, _ := referenceOfPath(r)
+
+	group := e.group(ref)
+	if group == nil {
+		respondError(w, http.StatusNotFound, errNotFound("service group", ref).Error())
+		return nil, false
+	}
+
+	if group.autoscale == nil {
+		respondError(w, http.StatusNotFound, fmt.Sprintf("service group %s has no autoscale configuration", group.Name))
+		return nil, false
+	}
+
+	return group.autoscale, true
+}
+
+// policyRequests decodes the names of the policies of the provided request.
+// The name held by the path of the request, if any, takes precedence over the
+// body.
+func policyRequests(r *http.Request) ([]autoscalePolicyRequest, error) {
+	if name := r.PathValue("name"); len(name) > 0 {
+		return []autoscalePolicyRequest{{Name: name}}, nil
+	}
+
+	return decodeBody[autoscalePolicyRequest](r)
+}
+
+// policyFailure returns the entry of the data of a response of the operation
+// which failed with the provided error on the named policy.
+func policyFailure(name string, code int, err error) AutoscalePolicy {
+	return AutoscalePolicy{
+		status: failed(code, err),
+		Name:   name,
+	}
+}
+
+func (e *Emulator) createAutoscalePolicies(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	reqs, err := decodeBody[AutoscalePolicy](r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if len(reqs) == 0 {
+		respondError(w, http.StatusBadRequest, "no policies provided")
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	config, ok := e.autoscaleOfPath(w, r)
+	if !ok {
+		return
+	}
+
+	items := make([]AutoscalePolicy, len(reqs))
+	for i, req := range reqs {
+		policy, err := newAutoscalePolicy(req)
+		if err != nil {
+			items[i] = policyFailure(req.Name, http.StatusBadRequest, err)
+			continue
+		}
+
+		if slices.ContainsFunc(config.Policies, func(p AutoscalePolicy) bool { return p.Name == policy.Name }) {
+			items[i] = policyFailure(req.Name, http.StatusConflict, fmt.Errorf("policy %s already exists", policy.Name))
+			continue
+		}
+
+		config.Policies = append(config.Policies, policy)
+
+		items[i] = AutoscalePolicy{status: succeeded(), Name: policy.Name}
+	}
+
+	respond(w, start, "policies", items)
+}
+
+func (e *Emulator) getAutoscalePolicies(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	reqs, err := policyRequests(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	config, ok := e.autoscaleOfPath(w, r)
+	if !ok {
+		return
+	}
+
+	if len(reqs) == 0 {
+		for _, policy := range config.Policies {
+			reqs = append(reqs, autoscalePolicyRequest{Name: policy.Name})
+		}
+	}
+
+	items := make([]AutoscalePolicy, len(reqs))
+	for i, req := range reqs {
+		j := slices.IndexFunc(config.Policies, func(p AutoscalePolicy) bool { return p.Name == req.Name })
+		if j < 0 {
+			items[i] = policyFailure(req.Name, http.StatusNotFound, fmt.Errorf("no policy with name %s", req.Name))
+			continue
+		}
+
+		items[i] = config.Policies[j]
+		items[i].status = succeeded()
+	}
+
+	respond(w, start, "policies", items)
+}
+
+func (e *Emulator) deleteAutoscalePolicies(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	reqs, err := policyRequests(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if len(reqs) == 0 {
+		respondError(w, http.StatusBadRequest, "no policies provided")
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	config, ok := e.autoscaleOfPath(w, r)
+	if !ok {
+		return
+	}
+
+	items := make([]AutoscalePolicy, len(reqs))
+	for i, req := range reqs {
+		j := slices.IndexFunc(config.Policies, func(p AutoscalePolicy) bool { return p.Name == req.Name })
+		if j < 0 {
+			items[i] = policyFailure(req.Name, http.StatusNotFound, fmt.Errorf("no policy with name %s", req.Name))
+			continue
+		}
+
+		config.Policies = slices.Delete(config.Policies, j, j+1)
+
+		items[i] = AutoscalePolicy{status: succeeded(), Name: req.Name}
+	}
+
+	respond(w, start, "policies", items)
+}
@@ -0,0 +1,227 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package cloudemulator
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+)
+
+type createCertificateRequest struct {
+	Name  string `json:"name"`
+	CN    string `json:"cn"`
+	Chain string `json:"chain"`
+	PKey  string `json:"pkey"`
+}
+
+// certificate returns the referenced certificate or nil if it does not exist.
+func (e *Emulator) certificate(ref Reference) *Certificate {
+	for _, cert := range e.certificates {
+		if ref.matches(cert.UUID, cert.Name) {
+			return cert
+		}
+	}
+
+	return nil
+}
+
+// newCertificate returns a certificate as requested without adding it to the
+// state.  The chain and private key must form a key pair whose leaf
+// certificate is valid for the common name.
+func (e *Emulator) newCertificate(req createCertificateRequest) (*Certificate, error) {
+	if len(req.Name) == 0 {
+		req.Name = fmt.Sprintf("cert-%s", newUUID()[:8])
+	}
+
+	if e.certificate(Reference{Name: req.Name}) != nil {
+		return nil, fmt.Errorf("certificate %s already exists", req.Name)
+	}
+
+	pair, err := tls.X509KeyPair([]byte(req.Chain), []byte(req.PKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate chain or private key: %w", err)
+	}
+
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate: %w", err)
+	}
+
+	if len(req.CN) == 0 {
+		req.CN = leaf.Subject.CommonName
+	}
+
+	if err := leaf.VerifyHostname(req.CN); err != nil {
+		return nil, fmt.Errorf("certificate is not valid for %s: %w", req.CN, err)
+	}
+
+	state := CertificateStateValid
+	if now := time.Now(); now.Before(leaf.NotBefore) || now.After(leaf.NotAfter) {
+		state = CertificateStateError
+	}
+
+	return &Certificate{
+		UUID:         newUUID(),
+		Name:         req.Name,
+		State:        state,
+		CommonName:   req.CN,
+		Subject:      leaf.Subject.String(),
+		Issuer:       leaf.Issuer.String(),
+		SerialNumber: leaf.SerialNumber.Text(16),
+		NotBefore:    timestamp(leaf.NotBefore),
+		NotAfter:     timestamp(leaf.NotAfter),
+		CreatedAt:    timestamp(time.Now()),
+		Chain:        req.Chain,
+		PrivateKey:   req.PKey,
+	}, nil
+}
+
+// certificateGroups returns the service groups which serve a domain name with
+// the provided certificate.
+func (e *Emulator) certificateGroups(cert *Certificate) []Reference {
+	groups := []Reference{}
+	for _, group := range e.groups {
+		if slices.ContainsFunc(group.Domains, func(d Domain) bool {
+			return d.Certificate != nil && d.Certificate.UUID == cert.UUID
+		}) {
+			groups = append(groups, Reference{UUID: group.UUID, Name: group.Name})
+		}
+	}
+
+	return groups
+}
+
+// viewCertificate returns a copy of the provided certificate as it is
+// reported.
+func (e *Emulator) viewCertificate(cert *Certificate) Certificate {
+	ret := *cert
+	ret.status = succeeded()
+	ret.ServiceGroups = e.certificateGroups(cert)
+
+	return ret
+}
+
+func (e *Emulator) createCertificates(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	reqs, err := decodeBody[createCertificateRequest](r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if len(reqs) == 0 {
+		respondError(w, http.StatusBadRequest, "no certificates provided")
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	items := make([]item, len(reqs))
+	for i, req := range reqs {
+		cert, err := e.newCertificate(req)
+		if err != nil {
+			items[i] = failureOf(Reference{Name: req.Name}, http.StatusBadRequest, err)
+			continue
+		}
+
+		e.certificates = append(e.certificates, cert)
+
+		items[i] = summaryOf(cert.UUID, cert.Name)
+	}
+
+	respond(w, start, "certificates", items)
+}
+
+func (e *Emulator) getCertificates(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	refs, err := referencedRequests[Reference](r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(refs) == 0 {
+		for _, cert := range e.certificates {
+			refs = append(refs, Reference{UUID: cert.UUID})
+		}
+	}
+
+	items := make([]item, len(refs))
+	for i, ref := range refs {
+		cert := e.certificate(ref)
+		if cert == nil {
+			items[i] = failureOf(ref, http.StatusNotFound, errNotFound("certificate", ref))
+			continue
+		}
+
+		items[i] = e.viewCertificate(cert)
+	}
+
+	respond(w, start, "certificates", items)
+}
+
+func (e *Emulator) listCertificates(w http.ResponseWriter, _ *http.Request) {
+	start := time.Now()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	items := make([]summary, len(e.certificates))
+	for i, cert := range e.certificates {
+		items[i] = summaryOf(cert.UUID, cert.Name)
+	}
+
+	respond(w, start, "certificates", items)
+}
+
+func (e *Emulator) deleteCertificates(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	refs, err := referencedRequests[Reference](r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if len(refs) == 0 {
+		respondError(w, http.StatusBadRequest, "no certificates provided")
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	items := make([]item, len(refs))
+	for i, ref := range refs {
+		cert := e.certificate(ref)
+		if cert == nil {
+			items[i] = failureOf(ref, http.StatusNotFound, errNotFound("certificate", ref))
+			continue
+		}
+
+		if len(e.certificateGroups(cert)) > 0 {
+			items[i] = failureOf(ref, http.StatusConflict, fmt.Errorf("certificate %s is used by a service group", cert.Name))
+			continue
+		}
+
+		e.certificates = slices.DeleteFunc(e.certificates, func(c *Certificate) bool {
+			return c == cert
+		})
+
+		items[i] = summaryOf(cert.UUID, cert.Name)
+	}
+
+	respond(w, start, "certificates", items)
+}
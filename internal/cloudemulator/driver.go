@@ -0,0 +1,41 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package cloudemulator
+
+import (
+	"context"
+)
+
+// Driver runs the resources of the emulator on the host.
+type Driver interface {
+	// Images returns the images which instances can be created from.
+	Images(ctx context.Context) ([]Image, error)
+
+	// CreateInstance validates and prepares the provided instance without
+	// starting it.
+	CreateInstance(ctx context.Context, instance *Instance) error
+
+	// StartInstance starts the provided instance with its current
+	// configuration, which may have changed whilst it was stopped.
+	StartInstance(ctx context.Context, instance *Instance) error
+
+	// StopInstance stops the provided instance.
+	StopInstance(ctx context.Context, instance *Instance) error
+
+	// DeleteInstance removes the provided stopped instance.
+	DeleteInstance(ctx context.Context, instance *Instance) error
+
+	// InstanceRunning returns whether the provided instance is still running.
+	InstanceRunning(ctx context.Context, instance *Instance) (bool, error)
+
+	// InstanceLogs returns the console output of the provided instance.
+	InstanceLogs(ctx context.Context, instance *Instance) ([]byte, error)
+
+	// CreateVolume allocates the storage of the provided volume.
+	CreateVolume(ctx context.Context, volume *Volume) error
+
+	// DeleteVolume releases the storage of the provided volume.
+	DeleteVolume(ctx context.Context, volume *Volume) error
+}
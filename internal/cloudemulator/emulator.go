@@ -0,0 +1,231 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+// Package cloudemulator serves a local emulation of the REST API of Unikraft
+// Cloud.  Instances and volumes are handed to a Driver, which runs them on
+// the host, such that clients of the API, including 'kraft cloud', can be
+// used without an account or network access.
+package cloudemulator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultMetro is the name of the metro served by the emulator unless
+	// another one is provided.
+	DefaultMetro = "local"
+
+	// DomainSuffix is appended to the metro to form the domain names of the
+	// service groups of the emulator.  Subdomains of "localhost" resolve to
+	// the loopback interface without any further configuration.
+	DomainSuffix = "localhost"
+)
+
+// Emulator implements http.Handler and serves the REST API of Unikraft Cloud
+// from an in-memory state.
+type Emulator struct {
+	driver Driver
+	metro  string
+	mux    *http.ServeMux
+
+	// mu serializes all operations on the state and the driver.
+	mu           sync.Mutex
+	instances    []*Instance
+	volumes      []*Volume
+	groups       []*ServiceGroup
+	certificates []*Certificate
+}
+
+// EmulatorOption is a functional option of the emulator.
+type EmulatorOption func(*Emulator) error
+
+// WithDriver sets the driver running the instances and volumes of the
+// emulator.
+func WithDriver(driver Driver) EmulatorOption {
+	return func(e *Emulator) error {
+		e.driver = driver
+		return nil
+	}
+}
+
+// WithMetro sets the name of the metro served by the emulator.
+func WithMetro(metro string) EmulatorOption {
+	return func(e *Emulator) error {
+		e.metro = metro
+		return nil
+	}
+}
+
+// New returns an emulator configured with the provided options.
+func New(opts ...EmulatorOption) (*Emulator, error) {
+	e := &Emulator{
+		metro: DefaultMetro,
+		mux:   http.NewServeMux(),
+	}
+
+	for _, opt := range opts {
+		if err := opt(e); err != nil {
+			return nil, err
+		}
+	}
+
+	if e.driver == nil {
+		return nil, fmt.Errorf("no driver provided")
+	}
+
+	if len(e.metro) == 0 {
+		return nil, fmt.Errorf("no metro provided")
+	}
+
+	e.routes()
+
+	return e, nil
+}
+
+// Metro returns the name of the metro served by the emulator.
+func (e *Emulator) Metro() string {
+	return e.metro
+}
+
+// routes registers the endpoints of the API.
+func (e *Emulator) routes() {
+	e.mux.HandleFunc("GET /v1/instances", e.getInstances)
+	e.mux.HandleFunc("GET /v1/instances/list", e.listInstances)
+	e.mux.HandleFunc("GET /v1/instances/log", e.logInstances)
+	e.mux.HandleFunc("GET /v1/instances/{id}", e.getInstances)
+	e.mux.HandleFunc("GET /v1/instances/{id}/log", e.logInstances)
+	e.mux.HandleFunc("POST /v1/instances", e.createInstances)
+	e.mux.HandleFunc("DELETE /v1/instances", e.deleteInstances)
+	e.mux.HandleFunc("DELETE /v1/instances/{id}", e.deleteInstances)
+	e.mux.HandleFunc("PUT /v1/instances/start", e.startInstances)
+	e.mux.HandleFunc("PUT /v1/instances/{id}/start", e.startInstances)
+	e.mux.HandleFunc("PUT /v1/instances/stop", e.stopInstances)
+	e.mux.HandleFunc("PUT /v1/instances/{id}/stop", e.stopInstances)
+	e.mux.HandleFunc("GET /v1/instances/wait", e.waitInstances)
+	e.mux.HandleFunc("PUT /v1/instances/wait", e.waitInstances)
+	e.mux.HandleFunc("GET /v1/instances/{id}/wait", e.waitInstances)
+
+	e.mux.HandleFunc("GET /v1/volumes", e.getVolumes)
+	e.mux.HandleFunc("GET /v1/volumes/list", e.listVolumes)
+	e.mux.HandleFunc("GET /v1/volumes/{id}", e.getVolumes)
+	e.mux.HandleFunc("POST /v1/volumes", e.createVolumes)
+	e.mux.HandleFunc("DELETE /v1/volumes", e.deleteVolumes)
+	e.mux.HandleFunc("DELETE /v1/volumes/{id}", e.deleteVolumes)
+	e.mux.HandleFunc("PUT /v1/volumes/attach", e.attachVolumes)
+	e.mux.HandleFunc("PUT /v1/volumes/{id}/attach", e.attachVolumes)
+	e.mux.HandleFunc("PUT /v1/volumes/detach", e.detachVolumes)
+	e.mux.HandleFunc("PUT /v1/volumes/{id}/detach", e.detachVolumes)
+
+	e.mux.HandleFunc("GET /v1/services", e.getServiceGroups)
+	e.mux.HandleFunc("GET /v1/services/list", e.listServiceGroups)
+	e.mux.HandleFunc("GET /v1/services/{id}", e.getServiceGroups)
+	e.mux.HandleFunc("POST /v1/services", e.createServiceGroups)
+	e.mux.HandleFunc("DELETE /v1/services", e.deleteServiceGroups)
+	e.mux.HandleFunc("DELETE /v1/services/{id}", e.deleteServiceGroups)
+
+	e.mux.HandleFunc("GET /v1/services/autoscale", e.getAutoscaleConfigurations)
+	e.mux.HandleFunc("GET /v1/services/{id}/autoscale", e.getAutoscaleConfigurations)
+	e.mux.HandleFunc("POST /v1/services/autoscale", e.createAutoscaleConfigurations)
+	e.mux.HandleFunc("POST /v1/services/{id}/autoscale", e.createAutoscaleConfigurations)
+	e.mux.HandleFunc("DELETE /v1/services/autoscale", e.deleteAutoscaleConfigurations)
+	e.mux.HandleFunc("DELETE /v1/services/{id}/autoscale", e.deleteAutoscaleConfigurations)
+	e.mux.HandleFunc("GET /v1/services/{id}/autoscale/policies", e.getAutoscalePolicies)
+	e.mux.HandleFunc("GET /v1/services/{id}/autoscale/policies/{name}", e.getAutoscalePolicies)
+	e.mux.HandleFunc("POST /v1/services/{id}/autoscale/policies", e.createAutoscalePolicies)
+	e.mux.HandleFunc("DELETE /v1/services/{id}/autoscale/policies", e.deleteAutoscalePolicies)
+	e.mux.HandleFunc("DELETE /v1/services/{id}/autoscale/policies/{name}", e.deleteAutoscalePolicies)
+
+	e.mux.HandleFunc("GET /v1/certificates", e.getCertificates)
+	e.mux.HandleFunc("GET /v1/certificates/list", e.listCertificates)
+	e.mux.HandleFunc("GET /v1/certificates/{id}", e.getCertificates)
+	e.mux.HandleFunc("POST /v1/certificates", e.createCertificates)
+	e.mux.HandleFunc("DELETE /v1/certificates", e.deleteCertificates)
+	e.mux.HandleFunc("DELETE /v1/certificates/{id}", e.deleteCertificates)
+
+	e.mux.HandleFunc("GET /v1/images", e.getImages)
+	e.mux.HandleFunc("GET /v1/images/list", e.getImages)
+	e.mux.HandleFunc("GET /v1/images/quotas", e.imageQuotas)
+
+	e.mux.HandleFunc("GET /v1/users/quotas", e.userQuotas)
+	e.mux.HandleFunc("GET /v1/users/{id}/quotas", e.userQuotas)
+
+	e.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		respondError(w, http.StatusNotImplemented, fmt.Sprintf("%s %s is not supported by the emulator", r.Method, r.URL.Path))
+	})
+}
+
+// ServeHTTP implements http.Handler.
+func (e *Emulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mux.ServeHTTP(w, r)
+}
+
+// Close stops and deletes all instances and volumes of the emulator.
+func (e *Emulator) Close(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var errs []error
+
+	for _, instance := range e.instances {
+		if err := e.removeInstance(ctx, instance); err != nil {
+			errs = append(errs, fmt.Errorf("could not delete instance %s: %w", instance.Name, err))
+		}
+	}
+
+	for _, volume := range e.volumes {
+		if err := e.driver.DeleteVolume(ctx, volume); err != nil {
+			errs = append(errs, fmt.Errorf("could not delete volume %s: %w", volume.Name, err))
+		}
+	}
+
+	e.instances = nil
+	e.volumes = nil
+	e.groups = nil
+	e.certificates = nil
+
+	return errors.Join(errs...)
+}
+
+// fqdn returns the fully qualified domain name of the provided name in the
+// metro of the emulator.
+func (e *Emulator) fqdn(name string) string {
+	if strings.Contains(name, ".") {
+		return strings.TrimSuffix(name, ".")
+	}
+
+	return fmt.Sprintf("%s.%s.%s", name, e.metro, DomainSuffix)
+}
+
+// newUUID returns a new random identifier of an object.
+func newUUID() string {
+	return uuid.NewString()
+}
+
+// matches returns whether the provided reference identifies the object with
+// the provided UUID and name.
+func (ref Reference) matches(id, name string) bool {
+	if len(ref.UUID) > 0 {
+		return ref.UUID == id
+	}
+
+	return len(ref.Name) > 0 && ref.Name == name
+}
+
+// String implements fmt.Stringer.
+func (ref Reference) String() string {
+	if len(ref.UUID) > 0 {
+		return ref.UUID
+	}
+
+	return ref.Name
+}
@@ -0,0 +1,521 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+package cloudemulator
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDriver records the resources of the emulator in memory.
+type fakeDriver struct {
+	mu       sync.Mutex
+	running  map[string]bool
+	volumes  map[string]bool
+	instance map[string]bool
+}
+
+func newFakeDriver() *fakeDriver {
+	return &fakeDriver{
+		running:  map[string]bool{},
+		volumes:  map[string]bool{},
+		instance: map[string]bool{},
+	}
+}
+
+func (d *fakeDriver) Images(context.Context) ([]Image, error) {
+	return []Image{{
+		Digest: "unikraft.org/nginx@sha256:0123",
+		Tags:   []string{"unikraft.org/nginx:latest"},
+	}}, nil
+}
+
+func (d *fakeDriver) CreateInstance(_ context.Context, instance *Instance) error {
+	images, _ := d.Images(context.Background())
+	if !MatchesImage(images[0], instance.Image) {
+		return fmt.Errorf("unknown image %s", instance.Image)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	instance.Machine = instance.Name
+	d.instance[instance.Machine] = true
+
+	return nil
+}
+
+func (d *fakeDriver) StartInstance(_ context.Context, instance *Instance) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.running[instance.Machine] = true
+
+	return nil
+}
+
+func (d *fakeDriver) StopInstance(_ context.Context, instance *Instance) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.running[instance.Machine] = false
+
+	return nil
+}
+
+func (d *fakeDriver) DeleteInstance(_ context.Context, instance *Instance) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.instance, instance.Machine)
+
+	return nil
+}
+
+func (d *fakeDriver) InstanceRunning(_ context.Context, instance *Instance) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.running[instance.Machine], nil
+}
+
+func (d *fakeDriver) InstanceLogs(_ context.Context, instance *Instance) ([]byte, error) {
+	return []byte("hello from " + instance.Name), nil
+}
+
+func (d *fakeDriver) CreateVolume(_ context.Context, volume *Volume) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	volume.Path = "/volumes/" + volume.UUID
+	d.volumes[volume.UUID] = true
+
+	return nil
+}
+
+func (d *fakeDriver) DeleteVolume(_ context.Context, volume *Volume) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.volumes, volume.UUID)
+
+	return nil
+}
+
+type testResponse struct {
+	Status string                       `json:"status"`
+	Data   map[string][]json.RawMessage `json:"data"`
+}
+
+// call performs a request against the emulator and decodes the entries of
+// the provided kind of its response into the provided list.
+func call(t *testing.T, e *Emulator, method, path string, body any, kind string, into any) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(method, path, &buf))
+
+	var resp testResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("could not decode response %q: %v", rec.Body.String(), err)
+	}
+
+	if into != nil {
+		raw, err := json.Marshal(resp.Data[kind])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := json.Unmarshal(raw, into); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return resp.Status
+}
+
+func TestEmulatorInstanceLifecycle(t *testing.T) {
+	driver := newFakeDriver()
+
+	e, err := New(WithDriver(driver))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var created []Instance
+	if status := call(t, e, http.MethodPost, "/v1/instances", map[string]any{
+		"name":  "web",
+		"image": "unikraft.org/nginx:latest",
+		"service_group": map[string]any{
+			"services": []Service{{Port: 443, DestinationPort: 8080, Handlers: []string{"tls", "http"}}},
+		},
+		"volumes":   []map[string]any{{"size_mb": 16, "at": "/data"}},
+		"autostart": true,
+	}, "instances", &created); status != statusSuccess {
+		t.Fatalf("expected instance to be created, got %s", status)
+	}
+
+	instance := created[0]
+	if instance.State != InstanceStateRunning {
+		t.Fatalf("expected instance to be running, got %s", instance.State)
+	}
+
+	if instance.ServiceGroup == nil || len(instance.ServiceGroup.Domains) != 1 ||
+		instance.ServiceGroup.Domains[0].FQDN != "web.local.localhost" {
+		t.Fatalf("unexpected service group: %+v", instance.ServiceGroup)
+	}
+
+	if len(instance.Volumes) != 1 || instance.Volumes[0].At != "/data" {
+		t.Fatalf("unexpected volumes: %+v", instance.Volumes)
+	}
+
+	var volumes []Volume
+	call(t, e, http.MethodGet, "/v1/volumes", nil, "volumes", &volumes)
+	if len(volumes) != 1 || volumes[0].State != volumeStateMounted {
+		t.Fatalf("expected a mounted volume, got %+v", volumes)
+	}
+
+	var logs []instanceLog
+	call(t, e, http.MethodGet, "/v1/instances/web/log?offset=-3", nil, "instances", &logs)
+	if output, _ := base64.StdEncoding.DecodeString(logs[0].Output); string(output) != "web" {
+		t.Fatalf("unexpected log output: %q", output)
+	}
+
+	// The instance exits on its own.
+	driver.StopInstance(context.Background(), &Instance{Machine: "web"})
+
+	var waits []waited
+	if status := call(t, e, http.MethodGet, "/v1/instances/wait", []map[string]any{
+		{"name": "web", "state": InstanceStateStopped, "timeout_ms": 1000},
+	}, "instances", &waits); status != statusSuccess {
+		t.Fatalf("expected wait to succeed, got %s", status)
+	}
+
+	if status := call(t, e, http.MethodDelete, "/v1/instances/"+instance.UUID, nil, "instances", nil); status != statusSuccess {
+		t.Fatalf("expected instance to be deleted, got %s", status)
+	}
+
+	var groups []ServiceGroup
+	call(t, e, http.MethodGet, "/v1/services", nil, "service_groups", &groups)
+	if len(groups) != 0 {
+		t.Fatalf("expected the service group of the instance to be deleted, got %+v", groups)
+	}
+
+	if len(driver.volumes) != 0 || len(driver.instance) != 0 {
+		t.Fatalf("expected driver to be empty, got %v and %v", driver.volumes, driver.instance)
+	}
+}
+
+func TestEmulatorPartialFailure(t *testing.T) {
+	e, err := New(WithDriver(newFakeDriver()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var items []summary
+	status := call(t, e, http.MethodPost, "/v1/instances", []map[string]any{
+		{"name": "ok", "image": "unikraft.org/nginx:latest"},
+		{"name": "missing", "image": "unikraft.org/missing:latest"},
+	}, "instances", &items)
+
+	if status != statusPartialSuccess {
+		t.Fatalf("expected partial success, got %s", status)
+	}
+
+	if items[0].Status != statusSuccess || items[1].Status != statusError {
+		t.Fatalf("unexpected outcomes: %+v", items)
+	}
+
+	if status := call(t, e, http.MethodGet, "/v1/instances/missing", nil, "instances", nil); status != statusError {
+		t.Fatalf("expected lookup of missing instance to fail, got %s", status)
+	}
+}
+
+func TestEmulatorVolumeAttachment(t *testing.T) {
+	e, err := New(WithDriver(newFakeDriver()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	call(t, e, http.MethodPost, "/v1/volumes", map[string]any{"name": "data", "size_mb": 32}, "volumes", nil)
+	call(t, e, http.MethodPost, "/v1/instances", map[string]any{"name": "app", "image": "unikraft.org/nginx"}, "instances", nil)
+
+	if status := call(t, e, http.MethodPut, "/v1/volumes/data/attach", map[string]any{
+		"attach_to": map[string]string{"name": "app"},
+		"at":        "/data",
+	}, "volumes", nil); status != statusSuccess {
+		t.Fatalf("expected volume to be attached, got %s", status)
+	}
+
+	if status := call(t, e, http.MethodDelete, "/v1/volumes/data", nil, "volumes", nil); status != statusError {
+		t.Fatalf("expected deletion of attached volume to fail, got %s", status)
+	}
+
+	call(t, e, http.MethodDelete, "/v1/instances/app", nil, "instances", nil)
+
+	// Persistent volumes outlive their instances.
+	if status := call(t, e, http.MethodDelete, "/v1/volumes/data", nil, "volumes", nil); status != statusSuccess {
+		t.Fatalf("expected volume to be deleted, got %s", status)
+	}
+}
+
+// selfSignedCertificate returns the PEM-encoded chain and private key of a
+// certificate which is valid for the provided domain name.
+func selfSignedCertificate(t *testing.T, domain string) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: domain},
+		DNSNames:     []string{domain},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: raw}))
+}
+
+func TestEmulatorCertificates(t *testing.T) {
+	e, err := New(WithDriver(newFakeDriver()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chain, pkey := selfSignedCertificate(t, "app.local.localhost")
+	_, otherKey := selfSignedCertificate(t, "app.local.localhost")
+
+	if status := call(t, e, http.MethodPost, "/v1/certificates", map[string]any{
+		"name": "mismatched", "chain": chain, "pkey": otherKey,
+	}, "certificates", nil); status != statusError {
+		t.Fatalf("expected a certificate with another private key to be rejected, got %s", status)
+	}
+
+	if status := call(t, e, http.MethodPost, "/v1/certificates", map[string]any{
+		"name": "other", "cn": "other.local.localhost", "chain": chain, "pkey": pkey,
+	}, "certificates", nil); status != statusError {
+		t.Fatalf("expected a certificate for another common name to be rejected, got %s", status)
+	}
+
+	if status := call(t, e, http.MethodPost, "/v1/certificates", map[string]any{
+		"name": "app", "cn": "app.local.localhost", "chain": chain, "pkey": pkey,
+	}, "certificates", nil); status != statusSuccess {
+		t.Fatalf("expected certificate to be created, got %s", status)
+	}
+
+	var certs []Certificate
+	call(t, e, http.MethodGet, "/v1/certificates/app", nil, "certificates", &certs)
+	if len(certs) != 1 || certs[0].State != CertificateStateValid ||
+		certs[0].CommonName != "app.local.localhost" || certs[0].SerialNumber != "2a" {
+		t.Fatalf("unexpected certificate: %+v", certs)
+	}
+
+	if status := call(t, e, http.MethodPost, "/v1/services", map[string]any{
+		"name":     "app",
+		"services": []Service{{Port: 443, DestinationPort: 8080, Handlers: []string{"tls", "http"}}},
+		"domains":  []map[string]any{{"name": "app", "certificate": map[string]string{"name": "app"}}},
+	}, "service_groups", nil); status != statusSuccess {
+		t.Fatalf("expected service group to be created, got %s", status)
+	}
+
+	call(t, e, http.MethodGet, "/v1/certificates", []Reference{{Name: "app"}}, "certificates", &certs)
+	if len(certs[0].ServiceGroups) != 1 || certs[0].ServiceGroups[0].Name != "app" {
+		t.Fatalf("expected certificate to be used by the service group, got %+v", certs[0].ServiceGroups)
+	}
+
+	if status := call(t, e, http.MethodDelete, "/v1/certificates/app", nil, "certificates", nil); status != statusError {
+		t.Fatalf("expected deletion of a certificate in use to fail, got %s", status)
+	}
+
+	call(t, e, http.MethodDelete, "/v1/services/app", nil, "service_groups", nil)
+
+	if status := call(t, e, http.MethodDelete, "/v1/certificates/app", nil, "certificates", nil); status != statusSuccess {
+		t.Fatalf("expected certificate to be deleted, got %s", status)
+	}
+
+	var list []summary
+	call(t, e, http.MethodGet, "/v1/certificates/list", nil, "certificates", &list)
+	if len(list) != 0 {
+		t.Fatalf("expected no certificates, got %+v", list)
+	}
+}
+
+func TestEmulatorAutoscale(t *testing.T) {
+	e, err := New(WithDriver(newFakeDriver()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var created []Instance
+	call(t, e, http.MethodPost, "/v1/instances", map[string]any{
+		"name":  "web",
+		"image": "unikraft.org/nginx:latest",
+		"service_group": map[string]any{
+			"services": []Service{{Port: 443, DestinationPort: 8080, Handlers: []string{"tls", "http"}}},
+		},
+	}, "instances", &created)
+
+	path := "/v1/services/" + created[0].ServiceGroup.UUID + "/autoscale"
+
+	var configs []AutoscaleConfiguration
+	call(t, e, http.MethodGet, path, nil, "service_groups", &configs)
+	if len(configs) != 1 || configs[0].Enabled {
+		t.Fatalf("expected autoscale to be disabled, got %+v", configs)
+	}
+
+	for _, invalid := range []map[string]any{
+		{"master": map[string]string{"name": "web"}, "max_size": limits.MaxAutoscaleSize + 1},
+		{"master": map[string]string{"name": "web"}, "min_size": 4, "max_size": 2},
+		{"master": map[string]string{"name": "web"}, "warmup_time_ms": 1},
+		{"master": map[string]string{"name": "other"}},
+		{},
+	} {
+		if status := call(t, e, http.MethodPost, path, invalid, "service_groups", nil); status != statusError {
+			t.Errorf("expected configuration %v to be rejected, got %s", invalid, status)
+		}
+	}
+
+	if status := call(t, e, http.MethodPost, path, map[string]any{
+		"master":           map[string]string{"name": "web"},
+		"min_size":         0,
+		"max_size":         4,
+		"warmup_time_ms":   100,
+		"cooldown_time_ms": 200,
+	}, "service_groups", nil); status != statusSuccess {
+		t.Fatalf("expected configuration to be created, got %s", status)
+	}
+
+	if status := call(t, e, http.MethodPost, path+"/policies", map[string]any{
+		"name":   "gap",
+		"metric": "cpu",
+		"steps": []map[string]any{
+			{"adjustment": -1, "upper_bound": 20},
+			{"adjustment": 1, "lower_bound": 50},
+		},
+	}, "policies", nil); status != statusError {
+		t.Fatalf("expected policy with non-contiguous steps to be rejected, got %s", status)
+	}
+
+	if status := call(t, e, http.MethodPost, path+"/policies", map[string]any{
+		"name":            "cpu",
+		"metric":          "cpu",
+		"adjustment_type": "percent",
+		"steps": []map[string]any{
+			{"adjustment": -50, "upper_bound": 50},
+			{"adjustment": 100, "lower_bound": 50},
+		},
+	}, "policies", nil); status != statusSuccess {
+		t.Fatalf("expected policy to be added, got %s", status)
+	}
+
+	call(t, e, http.MethodGet, path, nil, "service_groups", &configs)
+	if config := configs[0]; !config.Enabled || *config.MaxSize != 4 || *config.CooldownTimeMs != 200 ||
+		config.Master == nil || config.Master.Name != "web" || len(config.Policies) != 1 {
+		t.Fatalf("unexpected configuration: %+v", config)
+	}
+
+	var groups []ServiceGroup
+	call(t, e, http.MethodGet, "/v1/services/"+created[0].ServiceGroup.UUID, nil, "service_groups", &groups)
+	if !groups[0].Autoscale {
+		t.Fatal("expected service group to report autoscale")
+	}
+
+	var policies []AutoscalePolicy
+	call(t, e, http.MethodGet, path+"/policies/cpu", nil, "policies", &policies)
+	if len(policies) != 1 || policies[0].AdjustmentType != "percent" || len(policies[0].Steps) != 2 || !policies[0].Enabled {
+		t.Fatalf("unexpected policy: %+v", policies)
+	}
+
+	if status := call(t, e, http.MethodDelete, path+"/policies/cpu", nil, "policies", nil); status != statusSuccess {
+		t.Fatalf("expected policy to be deleted, got %s", status)
+	}
+
+	if status := call(t, e, http.MethodDelete, path, nil, "service_groups", nil); status != statusSuccess {
+		t.Fatalf("expected configuration to be deleted, got %s", status)
+	}
+
+	call(t, e, http.MethodGet, path, nil, "service_groups", &configs)
+	if configs[0].Enabled {
+		t.Fatal("expected autoscale to be disabled once its configuration is deleted")
+	}
+
+	if status := call(t, e, http.MethodGet, path+"/policies", nil, "policies", nil); status != statusError {
+		t.Fatalf("expected policies of a service group without autoscale to fail, got %s", status)
+	}
+}
+
+func TestTransportRedirectsAPI(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+	}))
+	defer server.Close()
+
+	transport, err := NewTransport(server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := http.Client{Transport: transport}
+
+	resp, err := client.Get("https://api.fra0.kraft.cloud/v1/instances")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if len(paths) != 1 || paths[0] != "/v1/instances" {
+		t.Fatalf("expected request to be redirected, got %v", paths)
+	}
+
+	for host, expected := range map[string]bool{
+		"api.kraft.cloud":      true,
+		"api.fra0.kraft.cloud": true,
+		"kraft.cloud":          false,
+		"api.example.com":      false,
+	} {
+		if IsAPIHost(host) != expected {
+			t.Errorf("expected IsAPIHost(%q) to be %t", host, expected)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package cloudemulator
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+type imageRequest struct {
+	Digest string `json:"digest"`
+	Tag    string `json:"tag"`
+}
+
+// MatchesImage returns whether the provided image is identified by the
+// provided reference, which is either its digest or one of its tags.  A tag
+// without a version matches the "latest" version.
+func MatchesImage(image Image, ref string) bool {
+	if len(ref) == 0 {
+		return false
+	}
+
+	if image.Digest == ref || strings.HasSuffix(image.Digest, "@"+ref) {
+		return true
+	}
+
+	if !strings.Contains(ref[strings.LastIndex(ref, "/")+1:], ":") {
+		ref += ":latest"
+	}
+
+	return slices.Contains(image.Tags, ref)
+}
+
+func (e *Emulator) getImages(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	reqs, err := decodeBody[imageRequest](r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	images, err := e.driver.Images(r.Context())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, fmt.Sprintf("could not list images: %v", err))
+		return
+	}
+
+	for i := range images {
+		images[i].status = succeeded()
+	}
+
+	if len(reqs) == 0 {
+		respond(w, start, "images", images)
+		return
+	}
+
+	items := make([]item, len(reqs))
+	for i, req := range reqs {
+		ref := req.Digest
+		if len(ref) == 0 {
+			ref = req.Tag
+		}
+
+		idx := slices.IndexFunc(images, func(image Image) bool {
+			return MatchesImage(image, ref)
+		})
+		if idx < 0 {
+			items[i] = imageFailure(ref)
+			continue
+		}
+
+		items[i] = images[idx]
+	}
+
+	respond(w, start, "images", items)
+}
+
+// imageFailure returns the entry of the data of a response for an image
+// which does not exist.
+func imageFailure(ref string) item {
+	return struct {
+		status
+
+		Tag string `json:"tag"`
+	}{
+		status: failed(http.StatusNotFound, fmt.Errorf("no image with reference %s", ref)),
+		Tag:    ref,
+	}
+}
+
+func (e *Emulator) imageQuotas(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	images, err := e.driver.Images(r.Context())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, fmt.Sprintf("could not list images: %v", err))
+		return
+	}
+
+	var used int64
+	for _, image := range images {
+		used += image.SizeInBytes
+	}
+
+	respond(w, start, "quotas", []imageQuotas{{
+		status: succeeded(),
+		Used:   used,
+		Hard:   imagesHardBytes,
+	}})
+}
@@ -0,0 +1,742 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package cloudemulator
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultMemoryMB is the amount of memory of an instance which does not
+	// request any.
+	DefaultMemoryMB = 128
+
+	// DefaultVcpus is the number of virtual CPUs of an instance which does not
+	// request any.
+	DefaultVcpus = 1
+
+	// DefaultRestartPolicy is the restart policy of an instance which does not
+	// request any.  Instances of the emulator are never restarted.
+	DefaultRestartPolicy = "never"
+
+	// waitInterval is how often the state of an instance is checked whilst
+	// waiting for it.
+	waitInterval = 100 * time.Millisecond
+)
+
+type createInstanceRequest struct {
+	Name          string                      `json:"name"`
+	Image         string                      `json:"image"`
+	Args          []string                    `json:"args"`
+	Env           map[string]string           `json:"env"`
+	MemoryMB      int                         `json:"memory_mb"`
+	Vcpus         int                         `json:"vcpus"`
+	Autostart     bool                        `json:"autostart"`
+	RestartPolicy string                      `json:"restart_policy"`
+	ServiceGroup  *createInstanceServiceGroup `json:"service_group"`
+	Volumes       []createInstanceVolume      `json:"volumes"`
+}
+
+type createInstanceServiceGroup struct {
+	Reference
+
+	Services []Service `json:"services"`
+	Domains  []Domain  `json:"domains"`
+}
+
+type createInstanceVolume struct {
+	Reference
+
+	SizeMB   int    `json:"size_mb"`
+	At       string `json:"at"`
+	ReadOnly bool   `json:"readonly"`
+}
+
+type waitRequest struct {
+	Reference
+
+	State     InstanceState `json:"state"`
+	TimeoutMs int           `json:"timeout_ms"`
+}
+
+type waited struct {
+	summary
+
+	State InstanceState `json:"state"`
+}
+
+type logRequest struct {
+	Reference
+
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+type logRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+type instanceLog struct {
+	summary
+
+	Output    string   `json:"output"`
+	Available logRange `json:"available"`
+	Range     logRange `json:"range"`
+}
+
+// timestamp formats the provided time as the API does.
+func timestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// instance returns the referenced instance or nil if it does not exist.
+func (e *Emulator) instance(ref Reference) *Instance {
+	for _, instance := range e.instances {
+		if ref.matches(instance.UUID, instance.Name) {
+			return instance
+		}
+	}
+
+	return nil
+}
+
+// view returns a copy of the provided instance as it is reported.
+func (instance *Instance) view() Instance {
+	ret := *instance
+	ret.status = succeeded()
+
+	return ret
+}
+
+// refresh updates the state of the provided instance from the driver.
+func (e *Emulator) refresh(ctx context.Context, instance *Instance) error {
+	if instance.State != InstanceStateRunning {
+		return nil
+	}
+
+	running, err := e.driver.InstanceRunning(ctx, instance)
+	if err != nil {
+		return err
+	}
+
+	instance.UptimeMs = time.Since(instance.started).Milliseconds()
+
+	if !running {
+		instance.PreviousState = instance.State
+		instance.State = InstanceStateStopped
+		instance.StoppedAt = timestamp(time.Now())
+	}
+
+	return nil
+}
+
+// instanceName returns a name for an instance of the provided image which is
+// not yet taken.
+func (e *Emulator) instanceName(image string) string {
+	base := path.Base(image)
+	if i := strings.IndexAny(base, ":@"); i > 0 {
+		base = base[:i]
+	}
+
+	return fmt.Sprintf("%s-%s", base, newUUID()[:8])
+}
+
+// createInstance creates an instance as requested and returns the HTTP
+// status code of the failure, if any.
+func (e *Emulator) createInstance(ctx context.Context, req createInstanceRequest) (*Instance, int, error) {
+	if len(req.Image) == 0 {
+		return nil, http.StatusBadRequest, fmt.Errorf("no image provided")
+	}
+
+	if len(req.Name) == 0 {
+		req.Name = e.instanceName(req.Image)
+	}
+
+	if e.instance(Reference{Name: req.Name}) != nil {
+		return nil, http.StatusConflict, fmt.Errorf("instance %s already exists", req.Name)
+	}
+
+	if req.MemoryMB == 0 {
+		req.MemoryMB = DefaultMemoryMB
+	}
+
+	if req.MemoryMB < limits.MinMemoryMb || req.MemoryMB > limits.MaxMemoryMb {
+		return nil, http.StatusBadRequest, fmt.Errorf("memory must be between %d and %d MiB", limits.MinMemoryMb, limits.MaxMemoryMb)
+	}
+
+	if req.Vcpus == 0 {
+		req.Vcpus = DefaultVcpus
+	}
+
+	if req.Vcpus < limits.MinVcpus || req.Vcpus > limits.MaxVcpus {
+		return nil, http.StatusBadRequest, fmt.Errorf("vcpus must be between %d and %d", limits.MinVcpus, limits.MaxVcpus)
+	}
+
+	if len(req.RestartPolicy) == 0 {
+		req.RestartPolicy = DefaultRestartPolicy
+	}
+
+	now := time.Now()
+	instance := &Instance{
+		status:        succeeded(),
+		UUID:          newUUID(),
+		Name:          req.Name,
+		CreatedAt:     timestamp(now),
+		State:         InstanceStateStopped,
+		Image:         req.Image,
+		MemoryMB:      req.MemoryMB,
+		Vcpus:         req.Vcpus,
+		Args:          req.Args,
+		Env:           req.Env,
+		RestartPolicy: req.RestartPolicy,
+		PrivateFQDN:   fmt.Sprintf("%s.internal", req.Name),
+	}
+
+	// Resolve the referenced objects before anything is created such that
+	// failures need no rollback.
+	var group *ServiceGroup
+	var newGroup bool
+
+	if req.ServiceGroup != nil {
+		if ref := req.ServiceGroup.Reference; len(ref.UUID) > 0 || len(ref.Name) > 0 {
+			if group = e.group(ref); group == nil {
+				return nil, http.StatusNotFound, errNotFound("service group", ref)
+			}
+		} else if len(req.ServiceGroup.Services) > 0 {
+			var err error
+			group, err = e.newServiceGroup(createServiceGroupRequest{
+				Name:     instance.Name,
+				Services: req.ServiceGroup.Services,
+				Domains:  req.ServiceGroup.Domains,
+			})
+			if err != nil {
+				return nil, http.StatusBadRequest, err
+			}
+
+			newGroup = true
+		}
+	}
+
+	var attached []*Volume
+	for _, vol := range req.Volumes {
+		if len(vol.At) == 0 {
+			return nil, http.StatusBadRequest, fmt.Errorf("no mount point provided for volume %s", vol.Reference)
+		}
+
+		if len(vol.UUID) == 0 && len(vol.Name) == 0 {
+			continue
+		}
+
+		volume := e.volume(vol.Reference)
+		if volume == nil {
+			return nil, http.StatusNotFound, errNotFound("volume", vol.Reference)
+		}
+
+		if len(volume.AttachedTo) > 0 || slices.Contains(attached, volume) {
+			return nil, http.StatusConflict, fmt.Errorf("volume %s is already attached", volume.Name)
+		}
+
+		attached = append(attached, volume)
+	}
+
+	var created []*Volume
+	rollback := func() {
+		for _, volume := range created {
+			_ = e.driver.DeleteVolume(ctx, volume)
+		}
+	}
+
+	for i, vol := range req.Volumes {
+		var volume *Volume
+
+		if len(vol.UUID) > 0 || len(vol.Name) > 0 {
+			volume = e.volume(vol.Reference)
+		} else {
+			var err error
+			volume, err = e.newVolume(ctx, createVolumeRequest{
+				Name:   fmt.Sprintf("%s-%d", instance.Name, i),
+				SizeMB: vol.SizeMB,
+			})
+			if err != nil {
+				rollback()
+				return nil, http.StatusBadRequest, err
+			}
+
+			created = append(created, volume)
+		}
+
+		instance.Volumes = append(instance.Volumes, InstanceVolume{
+			UUID:     volume.UUID,
+			Name:     volume.Name,
+			SizeMB:   volume.SizeMB,
+			At:       vol.At,
+			ReadOnly: vol.ReadOnly,
+			Path:     volume.Path,
+		})
+	}
+
+	if group != nil {
+		instance.ServiceGroup = &InstanceServiceGroup{
+			UUID:    group.UUID,
+			Name:    group.Name,
+			Domains: group.Domains,
+		}
+		instance.Services = group.Services
+	}
+
+	if err := e.driver.CreateInstance(ctx, instance); err != nil {
+		rollback()
+		return nil, http.StatusInternalServerError, fmt.Errorf("could not create instance: %w", err)
+	}
+
+	if group != nil {
+		if newGroup {
+			e.groups = append(e.groups, group)
+		}
+
+		group.Instances = append(group.Instances, Reference{UUID: instance.UUID, Name: instance.Name})
+	}
+
+	e.volumes = append(e.volumes, created...)
+	for _, iv := range instance.Volumes {
+		volume := e.volume(Reference{UUID: iv.UUID})
+		volume.AttachedTo = append(volume.AttachedTo, Reference{UUID: instance.UUID, Name: instance.Name})
+	}
+
+	e.instances = append(e.instances, instance)
+
+	if req.Autostart {
+		if err := e.startInstance(ctx, instance); err != nil {
+			return instance, http.StatusInternalServerError, fmt.Errorf("could not start instance: %w", err)
+		}
+	}
+
+	return instance, 0, nil
+}
+
+// startInstance starts the provided instance unless it is already running.
+func (e *Emulator) startInstance(ctx context.Context, instance *Instance) error {
+	if err := e.refresh(ctx, instance); err != nil {
+		return err
+	}
+
+	if instance.State == InstanceStateRunning {
+		return nil
+	}
+
+	begin := time.Now()
+
+	if err := e.driver.StartInstance(ctx, instance); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	instance.PreviousState = instance.State
+	instance.State = InstanceStateRunning
+	instance.StartCount++
+	instance.StartedAt = timestamp(now)
+	instance.BootTimeUs = now.Sub(begin).Microseconds()
+	instance.UptimeMs = 0
+	instance.started = now
+
+	return nil
+}
+
+// stopInstance stops the provided instance unless it is already stopped.
+func (e *Emulator) stopInstance(ctx context.Context, instance *Instance) error {
+	if err := e.refresh(ctx, instance); err != nil {
+		return err
+	}
+
+	if instance.State != InstanceStateRunning {
+		return nil
+	}
+
+	if err := e.driver.StopInstance(ctx, instance); err != nil {
+		return err
+	}
+
+	instance.PreviousState = instance.State
+	instance.State = InstanceStateStopped
+	instance.StoppedAt = timestamp(time.Now())
+	instance.UptimeMs = time.Since(instance.started).Milliseconds()
+
+	return nil
+}
+
+// removeInstance stops and deletes the provided instance and releases the
+// objects it holds.  The instance itself remains part of the state.
+func (e *Emulator) removeInstance(ctx context.Context, instance *Instance) error {
+	if err := e.stopInstance(ctx, instance); err != nil {
+		return err
+	}
+
+	if err := e.driver.DeleteInstance(ctx, instance); err != nil {
+		return err
+	}
+
+	ref := Reference{UUID: instance.UUID}
+
+	if instance.ServiceGroup != nil {
+		if group := e.group(Reference{UUID: instance.ServiceGroup.UUID}); group != nil {
+			group.Instances = slices.DeleteFunc(group.Instances, func(r Reference) bool {
+				return r.UUID == ref.UUID
+			})
+
+			if !group.Persistent && len(group.Instances) == 0 {
+				e.groups = slices.DeleteFunc(e.groups, func(g *ServiceGroup) bool {
+					return g == group
+				})
+			}
+		}
+	}
+
+	var errs []error
+
+	for _, iv := range instance.Volumes {
+		volume := e.volume(Reference{UUID: iv.UUID})
+		if volume == nil {
+			continue
+		}
+
+		volume.AttachedTo = slices.DeleteFunc(volume.AttachedTo, func(r Reference) bool {
+			return r.UUID == ref.UUID
+		})
+
+		if volume.Persistent {
+			continue
+		}
+
+		if err := e.driver.DeleteVolume(ctx, volume); err != nil {
+			errs = append(errs, fmt.Errorf("could not delete volume %s: %w", volume.Name, err))
+			continue
+		}
+
+		e.volumes = slices.DeleteFunc(e.volumes, func(v *Volume) bool {
+			return v == volume
+		})
+	}
+
+	return errors.Join(errs...)
+}
+
+func (e *Emulator) createInstances(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	reqs, err := decodeBody[createInstanceRequest](r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if len(reqs) == 0 {
+		respondError(w, http.StatusBadRequest, "no instances provided")
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	items := make([]item, len(reqs))
+	for i, req := range reqs {
+		instance, code, err := e.createInstance(r.Context(), req)
+		if err != nil {
+			items[i] = failureOf(Reference{Name: req.Name}, code, err)
+			continue
+		}
+
+		items[i] = instance.view()
+	}
+
+	respond(w, start, "instances", items)
+}
+
+func (e *Emulator) getInstances(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	refs, err := referencedRequests[Reference](r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(refs) == 0 {
+		for _, instance := range e.instances {
+			refs = append(refs, Reference{UUID: instance.UUID})
+		}
+	}
+
+	items := make([]item, len(refs))
+	for i, ref := range refs {
+		instance := e.instance(ref)
+		if instance == nil {
+			items[i] = failureOf(ref, http.StatusNotFound, errNotFound("instance", ref))
+			continue
+		}
+
+		if err := e.refresh(r.Context(), instance); err != nil {
+			items[i] = failureOf(ref, http.StatusInternalServerError, err)
+			continue
+		}
+
+		items[i] = instance.view()
+	}
+
+	respond(w, start, "instances", items)
+}
+
+func (e *Emulator) listInstances(w http.ResponseWriter, _ *http.Request) {
+	start := time.Now()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	items := make([]summary, len(e.instances))
+	for i, instance := range e.instances {
+		items[i] = summaryOf(instance.UUID, instance.Name)
+	}
+
+	respond(w, start, "instances", items)
+}
+
+// eachInstance applies the provided operation to each instance referenced by
+// the provided request and responds with their resulting state.
+func (e *Emulator) eachInstance(w http.ResponseWriter, r *http.Request, op func(context.Context, *Instance) error) {
+	start := time.Now()
+
+	refs, err := referencedRequests[Reference](r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if len(refs) == 0 {
+		respondError(w, http.StatusBadRequest, "no instances provided")
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	items := make([]item, len(refs))
+	for i, ref := range refs {
+		instance := e.instance(ref)
+		if instance == nil {
+			items[i] = failureOf(ref, http.StatusNotFound, errNotFound("instance", ref))
+			continue
+		}
+
+		if err := op(r.Context(), instance); err != nil {
+			items[i] = failureOf(ref, http.StatusInternalServerError, err)
+			continue
+		}
+
+		items[i] = instance.view()
+	}
+
+	respond(w, start, "instances", items)
+}
+
+func (e *Emulator) startInstances(w http.ResponseWriter, r *http.Request) {
+	e.eachInstance(w, r, e.startInstance)
+}
+
+func (e *Emulator) stopInstances(w http.ResponseWriter, r *http.Request) {
+	e.eachInstance(w, r, e.stopInstance)
+}
+
+func (e *Emulator) deleteInstances(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	refs, err := referencedRequests[Reference](r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if len(refs) == 0 {
+		respondError(w, http.StatusBadRequest, "no instances provided")
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	items := make([]item, len(refs))
+	for i, ref := range refs {
+		instance := e.instance(ref)
+		if instance == nil {
+			items[i] = failureOf(ref, http.StatusNotFound, errNotFound("instance", ref))
+			continue
+		}
+
+		if err := e.removeInstance(r.Context(), instance); err != nil {
+			items[i] = failureOf(ref, http.StatusInternalServerError, err)
+			continue
+		}
+
+		e.instances = slices.DeleteFunc(e.instances, func(i *Instance) bool {
+			return i == instance
+		})
+
+		items[i] = summaryOf(instance.UUID, instance.Name)
+	}
+
+	respond(w, start, "instances", items)
+}
+
+// wait returns once the referenced instance reaches the requested state or
+// the timeout of the request expires.
+func (e *Emulator) wait(ctx context.Context, req waitRequest) item {
+	if len(req.State) == 0 {
+		req.State = InstanceStateRunning
+	}
+
+	deadline := time.Now().Add(time.Duration(req.TimeoutMs) * time.Millisecond)
+
+	for {
+		e.mu.Lock()
+		instance := e.instance(req.Reference)
+		if instance == nil {
+			e.mu.Unlock()
+			return failureOf(req.Reference, http.StatusNotFound, errNotFound("instance", req.Reference))
+		}
+
+		err := e.refresh(ctx, instance)
+		ret := waited{
+			summary: summaryOf(instance.UUID, instance.Name),
+			State:   instance.State,
+		}
+		e.mu.Unlock()
+
+		if err != nil {
+			return failureOf(req.Reference, http.StatusInternalServerError, err)
+		}
+
+		if ret.State == req.State {
+			return ret
+		}
+
+		if req.TimeoutMs >= 0 && !time.Now().Before(deadline) {
+			return failureOf(req.Reference, http.StatusRequestTimeout, fmt.Errorf("timed out waiting for instance %s to be %s", ret.Name, req.State))
+		}
+
+		select {
+		case <-ctx.Done():
+			return failureOf(req.Reference, http.StatusRequestTimeout, ctx.Err())
+		case <-time.After(waitInterval):
+		}
+	}
+}
+
+func (e *Emulator) waitInstances(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	reqs, err := referencedRequests[waitRequest](r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if len(reqs) == 0 {
+		respondError(w, http.StatusBadRequest, "no instances provided")
+		return
+	}
+
+	items := make([]item, len(reqs))
+	for i, req := range reqs {
+		items[i] = e.wait(r.Context(), req)
+	}
+
+	respond(w, start, "instances", items)
+}
+
+func (e *Emulator) logInstances(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	reqs, err := referencedRequests[logRequest](r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if len(reqs) == 0 {
+		respondError(w, http.StatusBadRequest, "no instances provided")
+		return
+	}
+
+	// The path form of the endpoint takes its range as query parameters.
+	if _, ok := referenceOfPath(r); ok {
+		query := r.URL.Query()
+
+		for key, dst := range map[string]*int{"offset": &reqs[0].Offset, "limit": &reqs[0].Limit} {
+			if value := query.Get(key); len(value) > 0 {
+				if *dst, err = strconv.Atoi(value); err != nil {
+					respondError(w, http.StatusBadRequest, fmt.Sprintf("invalid %s: %v", key, err))
+					return
+				}
+			}
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	items := make([]item, len(reqs))
+	for i, req := range reqs {
+		instance := e.instance(req.Reference)
+		if instance == nil {
+			items[i] = failureOf(req.Reference, http.StatusNotFound, errNotFound("instance", req.Reference))
+			continue
+		}
+
+		output, err := e.driver.InstanceLogs(r.Context(), instance)
+		if err != nil {
+			items[i] = failureOf(req.Reference, http.StatusInternalServerError, err)
+			continue
+		}
+
+		items[i] = logOf(instance, output, req.Offset, req.Limit)
+	}
+
+	respond(w, start, "instances", items)
+}
+
+// logOf returns the provided console output of the instance limited to the
+// requested range.  A negative offset is relative to the end of the output
+// and a limit of zero extends the range to its end.
+func logOf(instance *Instance, output []byte, offset, limit int) instanceLog {
+	begin := offset
+	if begin < 0 {
+		begin += len(output)
+	}
+
+	begin = max(0, min(begin, len(output)))
+
+	end := len(output)
+	if limit > 0 {
+		end = min(begin+limit, end)
+	}
+
+	return instanceLog{
+		summary:   summaryOf(instance.UUID, instance.Name),
+		Output:    base64.StdEncoding.EncodeToString(output[begin:end]),
+		Available: logRange{Start: 0, End: len(output)},
+		Range:     logRange{Start: begin, End: end},
+	}
+}
@@ -0,0 +1,98 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package cloudemulator
+
+import (
+	"net/http"
+	"time"
+)
+
+// UserUUID is the identifier of the single user of the emulator.
+const UserUUID = "00000000-0000-0000-0000-000000000000"
+
+var (
+	// limits bound the size of single resources of the emulator.
+	limits = QuotaLimits{
+		MinMemoryMb:      16,
+		MaxMemoryMb:      4096,
+		MinVcpus:         1,
+		MaxVcpus:         8,
+		MinVolumeMb:      1,
+		MaxVolumeMb:      16384,
+		MinAutoscaleSize: 1,
+		MaxAutoscaleSize: 16,
+	}
+
+	// hard are the amounts of resources reported as available.  The emulator
+	// does not enforce them.
+	hard = QuotaCounts{
+		Instances:     64,
+		LiveInstances: 16,
+		LiveVcpus:     16,
+		LiveMemoryMb:  8192,
+		ServiceGroups: 64,
+		Services:      64,
+		Volumes:       64,
+		TotalVolumeMb: 65536,
+	}
+
+	// imagesHardBytes is the amount of image storage reported as available.
+	imagesHardBytes int64 = 16 << 30
+)
+
+type imageQuotas struct {
+	status
+
+	Used int64 `json:"used"`
+	Hard int64 `json:"hard"`
+}
+
+// used returns the amounts of resources currently held.
+func (e *Emulator) used() QuotaCounts {
+	var used QuotaCounts
+
+	used.Instances = len(e.instances)
+	for _, instance := range e.instances {
+		if instance.State != InstanceStateRunning {
+			continue
+		}
+
+		used.LiveInstances++
+		used.LiveVcpus += instance.Vcpus
+		used.LiveMemoryMb += instance.MemoryMB
+	}
+
+	used.ServiceGroups = len(e.groups)
+	for _, group := range e.groups {
+		used.Services += len(group.Services)
+	}
+
+	used.Volumes = len(e.volumes)
+	for _, volume := range e.volumes {
+		used.TotalVolumeMb += volume.SizeMB
+	}
+
+	return used
+}
+
+func (e *Emulator) userQuotas(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.refreshAll(r.Context()); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respond(w, start, "quotas", []Quotas{{
+		status: succeeded(),
+		UUID:   UserUUID,
+		Used:   e.used(),
+		Hard:   hard,
+		Limits: limits,
+	}})
+}
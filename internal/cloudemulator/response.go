@@ -0,0 +1,231 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package cloudemulator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	statusSuccess        = "success"
+	statusPartialSuccess = "partial_success"
+	statusError          = "error"
+)
+
+// item is an entry of the data of a response.
+type item interface {
+	outcome() string
+}
+
+func (s status) outcome() string {
+	return s.Status
+}
+
+// succeeded returns a successful status.
+func succeeded() status {
+	return status{Status: statusSuccess}
+}
+
+// failed returns the status of an operation which failed with the provided
+// error.
+func failed(code int, err error) status {
+	return status{Status: statusError, Message: err.Error(), Error: code}
+}
+
+// summary is an entry of the data of a response which only identifies its
+// object.
+type summary struct {
+	status
+
+	UUID string `json:"uuid,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// summaryOf returns the entry of the data of a response of a successful
+// operation on the provided object.
+func summaryOf(id, name string) summary {
+	return summary{
+		status: succeeded(),
+		UUID:   id,
+		Name:   name,
+	}
+}
+
+// failureOf returns the entry of the data of a response of the operation
+// which failed with the provided error on the referenced object.
+func failureOf(ref Reference, code int, err error) summary {
+	return summary{
+		status: failed(code, err),
+		UUID:   ref.UUID,
+		Name:   ref.Name,
+	}
+}
+
+// errNotFound returns the error of an object of the provided kind which does
+// not exist.
+func errNotFound(kind string, ref Reference) error {
+	return fmt.Errorf("no %s with identifier %s", kind, ref)
+}
+
+// responseError is an error of a response which is not associated with any
+// entry of its data.
+type responseError struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+// response is the envelope of all responses of the API.
+type response struct {
+	Status   string           `json:"status"`
+	Message  string           `json:"message,omitempty"`
+	Data     map[string][]any `json:"data,omitempty"`
+	Errors   []responseError  `json:"errors,omitempty"`
+	OpTimeUs int64            `json:"op_time_us"`
+}
+
+// respond writes the provided entries as the data of the provided kind.  The
+// status of the response is derived from the outcome of the entries.
+func respond[T item](w http.ResponseWriter, start time.Time, kind string, items []T) {
+	var succeeded, failed int
+	data := make([]any, len(items))
+
+	for i, it := range items {
+		if it.outcome() == statusSuccess {
+			succeeded++
+		} else {
+			failed++
+		}
+
+		data[i] = it
+	}
+
+	resp := response{
+		Status:   statusSuccess,
+		Data:     map[string][]any{kind: data},
+		OpTimeUs: time.Since(start).Microseconds(),
+	}
+
+	code := http.StatusOK
+
+	switch {
+	case failed > 0 && succeeded > 0:
+		resp.Status = statusPartialSuccess
+		resp.Message = fmt.Sprintf("%d of %d operations failed", failed, len(items))
+	case failed > 0:
+		resp.Status = statusError
+		resp.Message = "all operations failed"
+		code = http.StatusBadRequest
+	}
+
+	writeJSON(w, code, resp)
+}
+
+// respondError writes a response which failed as a whole.
+func respondError(w http.ResponseWriter, code int, message string) {
+	writeJSON(w, code, response{
+		Status:  statusError,
+		Message: message,
+		Errors: []responseError{
+			{Status: code, Message: message},
+		},
+	})
+}
+
+// writeJSON writes the provided value as the JSON body of a response.
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// decodeBody decodes the body of the provided request into a list of the
+// provided type.  The body may hold a single object or an array of objects.
+// An empty body results in an empty list.
+func decodeBody[T any](r *http.Request) ([]T, error) {
+	raw, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read request: %w", err)
+	}
+
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	if raw[0] == '[' {
+		var list []T
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return nil, fmt.Errorf("could not decode request: %w", err)
+		}
+
+		return list, nil
+	}
+
+	var one T
+	if err := json.Unmarshal(raw, &one); err != nil {
+		return nil, fmt.Errorf("could not decode request: %w", err)
+	}
+
+	return []T{one}, nil
+}
+
+// referenceOfPath returns the reference held by the path of the provided
+// request, if any.
+func referenceOfPath(r *http.Request) (Reference, bool) {
+	id := r.PathValue("id")
+	if len(id) == 0 {
+		return Reference{}, false
+	}
+
+	if _, err := uuid.Parse(id); err == nil {
+		return Reference{UUID: id}, true
+	}
+
+	return Reference{Name: id}, true
+}
+
+// referenced is a request which embeds the reference of its object.
+type referenced interface {
+	reference() *Reference
+}
+
+func (ref *Reference) reference() *Reference {
+	return ref
+}
+
+// referencedRequests decodes the body of the provided request into a list of
+// the provided type.  The reference held by the path of the request, if any,
+// takes precedence over the body and results in a single request.
+func referencedRequests[T any, PT interface {
+	*T
+	referenced
+}](r *http.Request,
+) ([]T, error) {
+	reqs, err := decodeBody[T](r)
+	if err != nil {
+		return nil, err
+	}
+
+	ref, ok := referenceOfPath(r)
+	if !ok {
+		return reqs, nil
+	}
+
+	if len(reqs) == 0 {
+		reqs = make([]T, 1)
+	}
+
+	reqs = reqs[:1]
+	*PT(&reqs[0]).reference() = ref
+
+	return reqs, nil
+}
@@ -0,0 +1,239 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package cloudemulator
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+)
+
+type createServiceGroupRequest struct {
+	Name      string    `json:"name"`
+	Services  []Service `json:"services"`
+	Domains   []Domain  `json:"domains"`
+	SoftLimit int       `json:"soft_limit"`
+	HardLimit int       `json:"hard_limit"`
+}
+
+// group returns the referenced service group or nil if it does not exist.
+func (e *Emulator) group(ref Reference) *ServiceGroup {
+	for _, group := range e.groups {
+		if ref.matches(group.UUID, group.Name) {
+			return group
+		}
+	}
+
+	return nil
+}
+
+// fqdnTaken returns whether the provided domain name is already served by a
+// service group.
+func (e *Emulator) fqdnTaken(fqdn string) bool {
+	for _, group := range e.groups {
+		for _, domain := range group.Domains {
+			if domain.FQDN == fqdn {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// newServiceGroup returns a service group as requested without adding it to
+// the state.  A service group without domain names is served on one derived
+// from its name.
+func (e *Emulator) newServiceGroup(req createServiceGroupRequest) (*ServiceGroup, error) {
+	if len(req.Name) == 0 {
+		req.Name = fmt.Sprintf("sg-%s", newUUID()[:8])
+	}
+
+	if e.group(Reference{Name: req.Name}) != nil {
+		return nil, fmt.Errorf("service group %s already exists", req.Name)
+	}
+
+	services := make([]Service, len(req.Services))
+	for i, service := range req.Services {
+		if service.Port <= 0 || service.Port > 65535 {
+			return nil, fmt.Errorf("invalid port %d", service.Port)
+		}
+
+		if service.DestinationPort == 0 {
+			service.DestinationPort = service.Port
+		}
+
+		services[i] = service
+	}
+
+	if len(req.Domains) == 0 {
+		req.Domains = []Domain{{Name: req.Name}}
+	}
+
+	domains := make([]Domain, len(req.Domains))
+	for i, domain := range req.Domains {
+		if len(domain.Name) == 0 {
+			return nil, fmt.Errorf("no domain name provided")
+		}
+
+		fqdn := e.fqdn(domain.Name)
+		if e.fqdnTaken(fqdn) || slices.ContainsFunc(domains[:i], func(d Domain) bool { return d.FQDN == fqdn }) {
+			return nil, fmt.Errorf("domain %s is already in use", fqdn)
+		}
+
+		var cert *Reference
+		if domain.Certificate != nil {
+			c := e.certificate(*domain.Certificate)
+			if c == nil {
+				return nil, errNotFound("certificate", *domain.Certificate)
+			}
+
+			cert = &Reference{UUID: c.UUID, Name: c.Name}
+		}
+
+		domains[i] = Domain{FQDN: fqdn, Certificate: cert}
+	}
+
+	return &ServiceGroup{
+		UUID:      newUUID(),
+		Name:      req.Name,
+		CreatedAt: timestamp(time.Now()),
+		SoftLimit: req.SoftLimit,
+		HardLimit: req.HardLimit,
+		Services:  services,
+		Domains:   domains,
+		Instances: []Reference{},
+	}, nil
+}
+
+// view returns a copy of the provided service group as it is reported.
+func (group *ServiceGroup) view() ServiceGroup {
+	ret := *group
+	ret.status = succeeded()
+	ret.Autoscale = group.autoscale != nil
+
+	return ret
+}
+
+func (e *Emulator) createServiceGroups(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	reqs, err := decodeBody[createServiceGroupRequest](r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if len(reqs) == 0 {
+		respondError(w, http.StatusBadRequest, "no service groups provided")
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	items := make([]item, len(reqs))
+	for i, req := range reqs {
+		group, err := e.newServiceGroup(req)
+		if err != nil {
+			items[i] = failureOf(Reference{Name: req.Name}, http.StatusBadRequest, err)
+			continue
+		}
+
+		group.Persistent = true
+		e.groups = append(e.groups, group)
+
+		items[i] = group.view()
+	}
+
+	respond(w, start, "service_groups", items)
+}
+
+func (e *Emulator) getServiceGroups(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	refs, err := referencedRequests[Reference](r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(refs) == 0 {
+		for _, group := range e.groups {
+			refs = append(refs, Reference{UUID: group.UUID})
+		}
+	}
+
+	items := make([]item, len(refs))
+	for i, ref := range refs {
+		group := e.group(ref)
+		if group == nil {
+			items[i] = failureOf(ref, http.StatusNotFound, errNotFound("service group", ref))
+			continue
+		}
+
+		items[i] = group.view()
+	}
+
+	respond(w, start, "service_groups", items)
+}
+
+func (e *Emulator) listServiceGroups(w http.ResponseWriter, _ *http.Request) {
+	start := time.Now()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	items := make([]summary, len(e.groups))
+	for i, group := range e.groups {
+		items[i] = summaryOf(group.UUID, group.Name)
+	}
+
+	respond(w, start, "service_groups", items)
+}
+
+func (e *Emulator) deleteServiceGroups(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	refs, err := referencedRequests[Reference](r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if len(refs) == 0 {
+		respondError(w, http.StatusBadRequest, "no service groups provided")
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	items := make([]item, len(refs))
+	for i, ref := range refs {
+		group := e.group(ref)
+		if group == nil {
+			items[i] = failureOf(ref, http.StatusNotFound, errNotFound("service group", ref))
+			continue
+		}
+
+		if len(group.Instances) > 0 {
+			items[i] = failureOf(ref, http.StatusConflict, fmt.Errorf("service group %s has instances", group.Name))
+			continue
+		}
+
+		e.groups = slices.DeleteFunc(e.groups, func(g *ServiceGroup) bool {
+			return g == group
+		})
+
+		items[i] = summaryOf(group.UUID, group.Name)
+	}
+
+	respond(w, start, "service_groups", items)
+}
@@ -0,0 +1,68 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package cloudemulator
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// APIDomain is the domain under which the API of Unikraft Cloud is served.
+const APIDomain = "kraft.cloud"
+
+// Transport implements http.RoundTripper and redirects requests made to the
+// API of any metro of Unikraft Cloud to an emulator.  All other requests are
+// passed to the base transport unchanged.
+type Transport struct {
+	target *url.URL
+	base   http.RoundTripper
+}
+
+// NewTransport returns a transport which redirects requests made to the API
+// to the emulator at the provided URL.
+func NewTransport(target string, base http.RoundTripper) (*Transport, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse emulator address: %w", err)
+	}
+
+	if len(u.Scheme) == 0 || len(u.Host) == 0 {
+		return nil, fmt.Errorf("emulator address %q is not an absolute URL", target)
+	}
+
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &Transport{
+		target: u,
+		base:   base,
+	}, nil
+}
+
+// IsAPIHost returns whether the provided host name is that of the API of a
+// metro of Unikraft Cloud.
+func IsAPIHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	return host == "api."+APIDomain ||
+		(strings.HasPrefix(host, "api.") && strings.HasSuffix(host, "."+APIDomain))
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !IsAPIHost(req.URL.Hostname()) {
+		return t.base.RoundTrip(req)
+	}
+
+	out := req.Clone(req.Context())
+	out.URL.Scheme = t.target.Scheme
+	out.URL.Host = t.target.Host
+	out.Host = ""
+
+	return t.base.RoundTrip(out)
+}
@@ -0,0 +1,252 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package cloudemulator
+
+import (
+	"time"
+)
+
+// InstanceState is the state of an instance as reported by the API.
+type InstanceState string
+
+const (
+	InstanceStateStopped  = InstanceState("stopped")
+	InstanceStateStarting = InstanceState("starting")
+	InstanceStateRunning  = InstanceState("running")
+	InstanceStateStopping = InstanceState("stopping")
+)
+
+// status is the outcome of the operation on a single item of a response.
+type status struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+	Error   int    `json:"error,omitempty"`
+}
+
+// Reference identifies an object of the API by its UUID or its name.
+type Reference struct {
+	UUID string `json:"uuid,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// Domain is a domain name of a service group.
+type Domain struct {
+	Name        string     `json:"name,omitempty"`
+	FQDN        string     `json:"fqdn,omitempty"`
+	Certificate *Reference `json:"certificate,omitempty"`
+}
+
+// Service is a port published by a service group.
+type Service struct {
+	Port            int      `json:"port"`
+	DestinationPort int      `json:"destination_port,omitempty"`
+	Handlers        []string `json:"handlers,omitempty"`
+}
+
+// ServiceGroup publishes the services of its instances on its domain names.
+type ServiceGroup struct {
+	status
+
+	UUID       string      `json:"uuid"`
+	Name       string      `json:"name"`
+	CreatedAt  string      `json:"created_at,omitempty"`
+	Persistent bool        `json:"persistent"`
+	Autoscale  bool        `json:"autoscale"`
+	SoftLimit  int         `json:"soft_limit,omitempty"`
+	HardLimit  int         `json:"hard_limit,omitempty"`
+	Services   []Service   `json:"services"`
+	Domains    []Domain    `json:"domains"`
+	Instances  []Reference `json:"instances"`
+
+	// autoscale is the autoscale configuration of the service group, if any.
+	autoscale *AutoscaleConfiguration
+}
+
+// InstanceServiceGroup is the service group of an instance.
+type InstanceServiceGroup struct {
+	UUID    string   `json:"uuid"`
+	Name    string   `json:"name"`
+	Domains []Domain `json:"domains,omitempty"`
+}
+
+// InstanceVolume is a volume mounted by an instance.
+type InstanceVolume struct {
+	UUID     string `json:"uuid,omitempty"`
+	Name     string `json:"name,omitempty"`
+	SizeMB   int    `json:"size_mb,omitempty"`
+	At       string `json:"at"`
+	ReadOnly bool   `json:"readonly"`
+
+	// Path is the location of the storage of the volume on the host.
+	Path string `json:"-"`
+}
+
+// Instance is a unikernel instance of the emulator.
+type Instance struct {
+	status
+
+	UUID          string                `json:"uuid"`
+	Name          string                `json:"name"`
+	CreatedAt     string                `json:"created_at"`
+	State         InstanceState         `json:"state"`
+	PreviousState InstanceState         `json:"previous_state,omitempty"`
+	Image         string                `json:"image"`
+	MemoryMB      int                   `json:"memory_mb"`
+	Vcpus         int                   `json:"vcpus"`
+	Args          []string              `json:"args"`
+	Env           map[string]string     `json:"env"`
+	StartCount    int                   `json:"start_count"`
+	RestartCount  int                   `json:"restart_count"`
+	RestartPolicy string                `json:"restart_policy"`
+	StartedAt     string                `json:"started_at,omitempty"`
+	StoppedAt     string                `json:"stopped_at,omitempty"`
+	UptimeMs      int64                 `json:"uptime_ms"`
+	BootTimeUs    int64                 `json:"boot_time_us"`
+	PrivateFQDN   string                `json:"private_fqdn"`
+	PrivateIP     string                `json:"private_ip"`
+	ServiceGroup  *InstanceServiceGroup `json:"service_group,omitempty"`
+	Volumes       []InstanceVolume      `json:"volumes"`
+
+	// Machine is the name of the machine running the instance, as set by the
+	// driver.
+	Machine string `json:"-"`
+
+	// Services are the ports published by the service group of the instance.
+	Services []Service `json:"-"`
+
+	started time.Time
+}
+
+// Volume is a persistent volume of the emulator.
+type Volume struct {
+	status
+
+	UUID       string      `json:"uuid"`
+	Name       string      `json:"name"`
+	SizeMB     int         `json:"size_mb"`
+	State      string      `json:"state"`
+	CreatedAt  string      `json:"created_at"`
+	Persistent bool        `json:"persistent"`
+	AttachedTo []Reference `json:"attached_to"`
+	MountedBy  []Reference `json:"mounted_by"`
+
+	// Path is the location of the storage of the volume on the host, as set
+	// by the driver.
+	Path string `json:"-"`
+}
+
+// Image is an image from which instances can be created.
+type Image struct {
+	status
+
+	Digest      string            `json:"digest"`
+	Tags        []string          `json:"tags"`
+	Initrd      bool              `json:"initrd"`
+	SizeInBytes int64             `json:"size_in_bytes"`
+	Args        []string          `json:"args"`
+	KernelArgs  []string          `json:"kernel_args"`
+	Env         map[string]string `json:"env,omitempty"`
+}
+
+// Quotas are the limits and usage of the user of the emulator.
+type Quotas struct {
+	status
+
+	UUID   string      `json:"uuid"`
+	Used   QuotaCounts `json:"used"`
+	Hard   QuotaCounts `json:"hard"`
+	Limits QuotaLimits `json:"limits"`
+}
+
+// QuotaCounts are the amounts of resources of a user.
+type QuotaCounts struct {
+	Instances     int `json:"instances"`
+	LiveInstances int `json:"live_instances"`
+	LiveVcpus     int `json:"live_vcpus"`
+	LiveMemoryMb  int `json:"live_memory_mb"`
+	ServiceGroups int `json:"service_groups"`
+	Services      int `json:"services"`
+	Volumes       int `json:"volumes"`
+	TotalVolumeMb int `json:"total_volume_mb"`
+}
+
+// QuotaLimits are the bounds of the size of single resources.
+type QuotaLimits struct {
+	MinMemoryMb      int `json:"min_memory_mb"`
+	MaxMemoryMb      int `json:"max_memory_mb"`
+	MinVcpus         int `json:"min_vcpus"`
+	MaxVcpus         int `json:"max_vcpus"`
+	MinVolumeMb      int `json:"min_volume_mb"`
+	MaxVolumeMb      int `json:"max_volume_mb"`
+	MinAutoscaleSize int `json:"min_autoscale_size"`
+	MaxAutoscaleSize int `json:"max_autoscale_size"`
+}
+
+// CertificateState is the state of a certificate as reported by the API.
+type CertificateState string
+
+const (
+	CertificateStatePending = CertificateState("pending")
+	CertificateStateValid   = CertificateState("valid")
+	CertificateStateError   = CertificateState("error")
+)
+
+// Certificate is a TLS certificate with which the domain names of service
+// groups are served.
+type Certificate struct {
+	status
+
+	UUID          string           `json:"uuid"`
+	Name          string           `json:"name"`
+	State         CertificateState `json:"state"`
+	CommonName    string           `json:"common_name"`
+	Subject       string           `json:"subject"`
+	Issuer        string           `json:"issuer"`
+	SerialNumber  string           `json:"serial_number"`
+	NotBefore     string           `json:"not_before"`
+	NotAfter      string           `json:"not_after"`
+	CreatedAt     string           `json:"created_at"`
+	ServiceGroups []Reference      `json:"service_groups"`
+
+	// Chain and PrivateKey are the PEM-encoded certificate chain and private
+	// key of the certificate.
+	Chain      string `json:"-"`
+	PrivateKey string `json:"-"`
+}
+
+// AutoscaleStep is a step of an autoscale policy, which applies its
+// adjustment whilst the metric of the policy is within its bounds.
+type AutoscaleStep struct {
+	Adjustment int  `json:"adjustment"`
+	LowerBound *int `json:"lower_bound,omitempty"`
+	UpperBound *int `json:"upper_bound,omitempty"`
+}
+
+// AutoscalePolicy is a policy of an autoscale configuration.
+type AutoscalePolicy struct {
+	status
+
+	Name           string          `json:"name"`
+	Type           string          `json:"type"`
+	Enabled        bool            `json:"enabled"`
+	Metric         string          `json:"metric,omitempty"`
+	AdjustmentType string          `json:"adjustment_type,omitempty"`
+	Steps          []AutoscaleStep `json:"steps,omitempty"`
+}
+
+// AutoscaleConfiguration is the autoscale configuration of a service group.
+type AutoscaleConfiguration struct {
+	status
+
+	UUID           string            `json:"uuid"`
+	Name           string            `json:"name"`
+	Enabled        bool              `json:"enabled"`
+	MinSize        *int              `json:"min_size,omitempty"`
+	MaxSize        *int              `json:"max_size,omitempty"`
+	WarmupTimeMs   *int              `json:"warmup_time_ms,omitempty"`
+	CooldownTimeMs *int              `json:"cooldown_time_ms,omitempty"`
+	Master         *Reference        `json:"master,omitempty"`
+	Policies       []AutoscalePolicy `json:"policies,omitempty"`
+}
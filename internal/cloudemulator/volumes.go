@@ -0,0 +1,382 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package cloudemulator
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+)
+
+const (
+	volumeStateAvailable = "available"
+	volumeStateIdle      = "idle"
+	volumeStateMounted   = "mounted"
+)
+
+type createVolumeRequest struct {
+	Name   string `json:"name"`
+	SizeMB int    `json:"size_mb"`
+}
+
+type attachVolumeRequest struct {
+	Reference
+
+	AttachTo Reference `json:"attach_to"`
+	At       string    `json:"at"`
+	ReadOnly bool      `json:"readonly"`
+}
+
+type detachVolumeRequest struct {
+	Reference
+
+	From Reference `json:"from"`
+}
+
+// volume returns the referenced volume or nil if it does not exist.
+func (e *Emulator) volume(ref Reference) *Volume {
+	for _, volume := range e.volumes {
+		if ref.matches(volume.UUID, volume.Name) {
+			return volume
+		}
+	}
+
+	return nil
+}
+
+// viewVolume returns a copy of the provided volume as it is reported.
+func (e *Emulator) viewVolume(volume *Volume) Volume {
+	ret := *volume
+	ret.status = succeeded()
+	ret.State = volumeStateAvailable
+	ret.MountedBy = nil
+
+	for _, ref := range volume.AttachedTo {
+		ret.State = volumeStateIdle
+
+		if instance := e.instance(ref); instance != nil && instance.State == InstanceStateRunning {
+			ret.State = volumeStateMounted
+			ret.MountedBy = append(ret.MountedBy, ref)
+		}
+	}
+
+	return ret
+}
+
+// newVolume allocates a volume as requested without adding it to the state.
+func (e *Emulator) newVolume(ctx context.Context, req createVolumeRequest) (*Volume, error) {
+	if len(req.Name) == 0 {
+		req.Name = fmt.Sprintf("vol-%s", newUUID()[:8])
+	}
+
+	if e.volume(Reference{Name: req.Name}) != nil {
+		return nil, fmt.Errorf("volume %s already exists", req.Name)
+	}
+
+	if req.SizeMB < limits.MinVolumeMb || req.SizeMB > limits.MaxVolumeMb {
+		return nil, fmt.Errorf("size must be between %d and %d MiB", limits.MinVolumeMb, limits.MaxVolumeMb)
+	}
+
+	volume := &Volume{
+		UUID:      newUUID(),
+		Name:      req.Name,
+		SizeMB:    req.SizeMB,
+		CreatedAt: timestamp(time.Now()),
+	}
+
+	if err := e.driver.CreateVolume(ctx, volume); err != nil {
+		return nil, fmt.Errorf("could not create volume: %w", err)
+	}
+
+	return volume, nil
+}
+
+func (e *Emulator) createVolumes(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	reqs, err := decodeBody[createVolumeRequest](r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if len(reqs) == 0 {
+		respondError(w, http.StatusBadRequest, "no volumes provided")
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	items := make([]item, len(reqs))
+	for i, req := range reqs {
+		volume, err := e.newVolume(r.Context(), req)
+		if err != nil {
+			items[i] = failureOf(Reference{Name: req.Name}, http.StatusBadRequest, err)
+			continue
+		}
+
+		volume.Persistent = true
+		e.volumes = append(e.volumes, volume)
+
+		items[i] = e.viewVolume(volume)
+	}
+
+	respond(w, start, "volumes", items)
+}
+
+func (e *Emulator) getVolumes(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	refs, err := referencedRequests[Reference](r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// The state of volumes follows the one of the instances mounting them.
+	if err := e.refreshAll(r.Context()); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if len(refs) == 0 {
+		for _, volume := range e.volumes {
+			refs = append(refs, Reference{UUID: volume.UUID})
+		}
+	}
+
+	items := make([]item, len(refs))
+	for i, ref := range refs {
+		volume := e.volume(ref)
+		if volume == nil {
+			items[i] = failureOf(ref, http.StatusNotFound, errNotFound("volume", ref))
+			continue
+		}
+
+		items[i] = e.viewVolume(volume)
+	}
+
+	respond(w, start, "volumes", items)
+}
+
+func (e *Emulator) listVolumes(w http.ResponseWriter, _ *http.Request) {
+	start := time.Now()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	items := make([]summary, len(e.volumes))
+	for i, volume := range e.volumes {
+		items[i] = summaryOf(volume.UUID, volume.Name)
+	}
+
+	respond(w, start, "volumes", items)
+}
+
+func (e *Emulator) deleteVolumes(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	refs, err := referencedRequests[Reference](r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if len(refs) == 0 {
+		respondError(w, http.StatusBadRequest, "no volumes provided")
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	items := make([]item, len(refs))
+	for i, ref := range refs {
+		volume := e.volume(ref)
+		if volume == nil {
+			items[i] = failureOf(ref, http.StatusNotFound, errNotFound("volume", ref))
+			continue
+		}
+
+		if len(volume.AttachedTo) > 0 {
+			items[i] = failureOf(ref, http.StatusConflict, fmt.Errorf("volume %s is attached to %s", volume.Name, volume.AttachedTo[0]))
+			continue
+		}
+
+		if err := e.driver.DeleteVolume(r.Context(), volume); err != nil {
+			items[i] = failureOf(ref, http.StatusInternalServerError, err)
+			continue
+		}
+
+		e.volumes = slices.DeleteFunc(e.volumes, func(v *Volume) bool {
+			return v == volume
+		})
+
+		items[i] = summaryOf(volume.UUID, volume.Name)
+	}
+
+	respond(w, start, "volumes", items)
+}
+
+// attachVolume attaches the requested volume to the stopped instance.
+func (e *Emulator) attachVolume(req attachVolumeRequest) (*Volume, int, error) {
+	volume := e.volume(req.Reference)
+	if volume == nil {
+		return nil, http.StatusNotFound, errNotFound("volume", req.Reference)
+	}
+
+	instance := e.instance(req.AttachTo)
+	if instance == nil {
+		return nil, http.StatusNotFound, errNotFound("instance", req.AttachTo)
+	}
+
+	if len(volume.AttachedTo) > 0 {
+		return nil, http.StatusConflict, fmt.Errorf("volume %s is already attached", volume.Name)
+	}
+
+	if instance.State != InstanceStateStopped {
+		return nil, http.StatusConflict, fmt.Errorf("instance %s must be stopped", instance.Name)
+	}
+
+	if len(req.At) == 0 {
+		return nil, http.StatusBadRequest, fmt.Errorf("no mount point provided")
+	}
+
+	instance.Volumes = append(instance.Volumes, InstanceVolume{
+		UUID:     volume.UUID,
+		Name:     volume.Name,
+		SizeMB:   volume.SizeMB,
+		At:       req.At,
+		ReadOnly: req.ReadOnly,
+		Path:     volume.Path,
+	})
+
+	volume.AttachedTo = append(volume.AttachedTo, Reference{UUID: instance.UUID, Name: instance.Name})
+
+	return volume, 0, nil
+}
+
+func (e *Emulator) attachVolumes(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	reqs, err := referencedRequests[attachVolumeRequest](r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if len(reqs) == 0 {
+		respondError(w, http.StatusBadRequest, "no volumes provided")
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	items := make([]item, len(reqs))
+	for i, req := range reqs {
+		if err := e.refreshAll(r.Context()); err != nil {
+			items[i] = failureOf(req.Reference, http.StatusInternalServerError, err)
+			continue
+		}
+
+		volume, code, err := e.attachVolume(req)
+		if err != nil {
+			items[i] = failureOf(req.Reference, code, err)
+			continue
+		}
+
+		items[i] = e.viewVolume(volume)
+	}
+
+	respond(w, start, "volumes", items)
+}
+
+// detachVolume detaches the requested volume from its stopped instance.
+func (e *Emulator) detachVolume(req detachVolumeRequest) (*Volume, int, error) {
+	volume := e.volume(req.Reference)
+	if volume == nil {
+		return nil, http.StatusNotFound, errNotFound("volume", req.Reference)
+	}
+
+	if len(volume.AttachedTo) == 0 {
+		return nil, http.StatusConflict, fmt.Errorf("volume %s is not attached", volume.Name)
+	}
+
+	from := volume.AttachedTo[0]
+	if len(req.From.UUID) > 0 || len(req.From.Name) > 0 {
+		if !req.From.matches(from.UUID, from.Name) {
+			return nil, http.StatusConflict, fmt.Errorf("volume %s is not attached to %s", volume.Name, req.From)
+		}
+	}
+
+	instance := e.instance(from)
+	if instance != nil {
+		if instance.State != InstanceStateStopped {
+			return nil, http.StatusConflict, fmt.Errorf("instance %s must be stopped", instance.Name)
+		}
+
+		instance.Volumes = slices.DeleteFunc(instance.Volumes, func(iv InstanceVolume) bool {
+			return iv.UUID == volume.UUID
+		})
+	}
+
+	volume.AttachedTo = nil
+
+	return volume, 0, nil
+}
+
+func (e *Emulator) detachVolumes(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	reqs, err := referencedRequests[detachVolumeRequest](r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if len(reqs) == 0 {
+		respondError(w, http.StatusBadRequest, "no volumes provided")
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	items := make([]item, len(reqs))
+	for i, req := range reqs {
+		if err := e.refreshAll(r.Context()); err != nil {
+			items[i] = failureOf(req.Reference, http.StatusInternalServerError, err)
+			continue
+		}
+
+		volume, code, err := e.detachVolume(req)
+		if err != nil {
+			items[i] = failureOf(req.Reference, code, err)
+			continue
+		}
+
+		items[i] = e.viewVolume(volume)
+	}
+
+	respond(w, start, "volumes", items)
+}
+
+// refreshAll updates the state of all instances from the driver.
+func (e *Emulator) refreshAll(ctx context.Context) error {
+	for _, instance := range e.instances {
+		if err := e.refresh(ctx, instance); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
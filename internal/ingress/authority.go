@@ -0,0 +1,213 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package ingress
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// AuthorityCertificateFile is the name of the file holding the certificate
+	// of the local authority, which can be added to the trust store of the
+	// host.
+	AuthorityCertificateFile = "ca.pem"
+
+	// AuthorityKeyFile is the name of the file holding the private key of the
+	// local authority.
+	AuthorityKeyFile = "ca-key.pem"
+)
+
+const (
+	authorityValidity = 10 * 365 * 24 * time.Hour
+	leafValidity      = 90 * 24 * time.Hour
+)
+
+// Authority is a local certificate authority which issues the certificates of
+// domain names which lack a user-supplied one.
+type Authority struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+
+	mu     sync.Mutex
+	leaves map[string]*tls.Certificate
+}
+
+// LoadOrCreateAuthority loads the local certificate authority stored in the
+// provided directory, creating it first if it does not exist.
+func LoadOrCreateAuthority(dir string) (*Authority, error) {
+	certPath := filepath.Join(dir, AuthorityCertificateFile)
+	keyPath := filepath.Join(dir, AuthorityKeyFile)
+
+	pair, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if errors.Is(err, os.ErrNotExist) {
+		if err := createAuthority(certPath, keyPath); err != nil {
+			return nil, err
+		}
+
+		pair, err = tls.LoadX509KeyPair(certPath, keyPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not load certificate authority: %w", err)
+	}
+
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("could not parse certificate authority: %w", err)
+	}
+
+	key, ok := pair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key of certificate authority")
+	}
+
+	return &Authority{
+		cert:   cert,
+		key:    key,
+		leaves: map[string]*tls.Certificate{},
+	}, nil
+}
+
+// createAuthority generates a self-signed certificate authority and saves it
+// to the provided paths.
+func createAuthority(certPath, keyPath string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("could not generate private key: %w", err)
+	}
+
+	serial, err := serialNumber()
+	if err != nil {
+		return err
+	}
+
+	template := x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			Organization: []string{"KraftKit"},
+			CommonName:   "KraftKit Local Ingress CA",
+		},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(authorityValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return fmt.Errorf("could not create certificate authority: %w", err)
+	}
+
+	rawKey, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("could not marshal private key: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(certPath), 0o755); err != nil {
+		return fmt.Errorf("could not make certificate authority directory: %w", err)
+	}
+
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: rawKey}), 0o600); err != nil {
+		return fmt.Errorf("could not save private key: %w", err)
+	}
+
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644); err != nil {
+		return fmt.Errorf("could not save certificate authority: %w", err)
+	}
+
+	return nil
+}
+
+// CertPool returns a pool holding the certificate of the authority.
+func (authority *Authority) CertPool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(authority.cert)
+	return pool
+}
+
+// Certificate returns a certificate of the provided host name issued by the
+// authority.  Certificates are cached and reissued once they near expiry.
+func (authority *Authority) Certificate(host string) (*tls.Certificate, error) {
+	authority.mu.Lock()
+	defer authority.mu.Unlock()
+
+	if leaf, ok := authority.leaves[host]; ok && time.Until(leaf.Leaf.NotAfter) > leafValidity/2 {
+		return leaf, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("could not generate private key: %w", err)
+	}
+
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	template := x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			Organization: []string{"KraftKit"},
+			CommonName:   host,
+		},
+		NotBefore:   time.Now().Add(-time.Hour),
+		NotAfter:    time.Now().Add(leafValidity),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	} else {
+		template.DNSNames = []string{host}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, authority.cert, &key.PublicKey, authority.key)
+	if err != nil {
+		return nil, fmt.Errorf("could not issue certificate of %s: %w", host, err)
+	}
+
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	cert := &tls.Certificate{
+		Certificate: [][]byte{der, authority.cert.Raw},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}
+
+	authority.leaves[host] = cert
+
+	return cert, nil
+}
+
+// serialNumber returns a random certificate serial number.
+func serialNumber() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("could not generate serial number: %w", err)
+	}
+
+	return serial, nil
+}
@@ -0,0 +1,227 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+// Package ingress routes connections made to domain names on the host to the
+// machines serving them, mirroring the ingress of a cloud deployment locally.
+// HTTP requests are routed by their Host header, whilst TLS connections are
+// terminated and routed by their server name indication (SNI).
+package ingress
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"time"
+
+	"kraftkit.sh/internal/netutil"
+	"kraftkit.sh/log"
+)
+
+const (
+	// DialTimeout is the time after which connecting to a machine is given up.
+	DialTimeout = 5 * time.Second
+
+	// HandshakeTimeout is the time after which a TLS handshake is given up.
+	HandshakeTimeout = 10 * time.Second
+)
+
+// Ingress routes connections to the machines serving their domain names.
+type Ingress struct {
+	routes    Routes
+	authority *Authority
+
+	mu    sync.Mutex
+	certs map[string]*tls.Certificate
+}
+
+// New returns an ingress of the provided routes.  Domain names without a
+// certificate are served with one issued by the provided authority.
+func New(routes Routes, authority *Authority) *Ingress {
+	return &Ingress{
+		routes:    routes,
+		authority: authority,
+		certs:     map[string]*tls.Certificate{},
+	}
+}
+
+// lookup returns the route serving the provided host name.
+func (in *Ingress) lookup(ctx context.Context, host string) (Route, error) {
+	routes, err := in.routes(ctx)
+	if err != nil {
+		return Route{}, fmt.Errorf("could not determine routes: %w", err)
+	}
+
+	route, ok := Lookup(routes, host)
+	if !ok {
+		return Route{}, fmt.Errorf("no route to %s", host)
+	}
+
+	return route, nil
+}
+
+// ServeHTTP implements http.Handler and forwards the request to the machine
+// serving its host.
+func (in *Ingress) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route, err := in.lookup(r.Context(), r.Host)
+	if err != nil {
+		log.G(r.Context()).Debug(err)
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	target := &url.URL{Scheme: "http", Host: route.Backend}
+
+	proxy := httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			pr.SetXForwarded()
+			pr.Out.Host = pr.In.Host
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			log.G(r.Context()).
+				WithField("machine", route.Machine).
+				Warnf("could not forward request: %v", err)
+			http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+		},
+	}
+
+	proxy.ServeHTTP(w, r)
+}
+
+// ServeHTTPListener serves HTTP requests accepted by the provided listener
+// until the context is cancelled.
+func (in *Ingress) ServeHTTPListener(ctx context.Context, listener net.Listener) error {
+	server := http.Server{
+		Handler:           in,
+		ReadHeaderTimeout: HandshakeTimeout,
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
+	}
+
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// ServeTLSListener terminates the TLS connections accepted by the provided
+// listener and forwards their content to the machine serving their server
+// name until the context is cancelled.
+func (in *Ingress) ServeTLSListener(ctx context.Context, listener net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: in.getCertificate,
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+
+			return err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			in.handleTLS(ctx, tls.Server(conn, config))
+		}()
+	}
+}
+
+// handleTLS completes the handshake of the provided connection and forwards
+// it to the machine serving its server name.
+func (in *Ingress) handleTLS(ctx context.Context, conn *tls.Conn) {
+	defer conn.Close()
+
+	hctx, cancel := context.WithTimeout(ctx, HandshakeTimeout)
+	defer cancel()
+
+	if err := conn.HandshakeContext(hctx); err != nil {
+		log.G(ctx).Debugf("could not complete handshake with %s: %v", conn.RemoteAddr(), err)
+		return
+	}
+
+	route, err := in.lookup(ctx, conn.ConnectionState().ServerName)
+	if err != nil {
+		log.G(ctx).Debug(err)
+		return
+	}
+
+	dialer := net.Dialer{Timeout: DialTimeout}
+	backend, err := dialer.DialContext(ctx, "tcp", route.Backend)
+	if err != nil {
+		log.G(ctx).
+			WithField("machine", route.Machine).
+			Warnf("could not connect to %s: %v", route.Backend, err)
+		return
+	}
+
+	defer backend.Close()
+
+	netutil.Splice(conn, backend)
+}
+
+// getCertificate returns the certificate of the server name requested by the
+// client, which is either the one supplied for its route or one issued by the
+// authority.
+func (in *Ingress) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	host := Normalize(hello.ServerName)
+	if len(host) == 0 {
+		return nil, fmt.Errorf("client did not indicate a server name")
+	}
+
+	route, err := in.lookup(hello.Context(), host)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(route.Certificate) == 0 {
+		if in.authority == nil {
+			return nil, fmt.Errorf("no certificate for %s", host)
+		}
+
+		return in.authority.Certificate(host)
+	}
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	if cert, ok := in.certs[route.Certificate]; ok {
+		return cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(route.Certificate, route.Certificate)
+	if err != nil {
+		return nil, fmt.Errorf("could not load certificate of %s: %w", host, err)
+	}
+
+	in.certs[route.Certificate] = &cert
+
+	return &cert, nil
+}
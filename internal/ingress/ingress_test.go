@@ -0,0 +1,194 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+package ingress
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	routes := []Route{
+		{Domain: "*.localhost", Machine: "catchall"},
+		{Domain: "*.app.localhost", Machine: "wildcard"},
+		{Domain: "app.localhost", Machine: "exact"},
+	}
+
+	tests := []struct {
+		host    string
+		machine string
+	}{
+		{host: "app.localhost", machine: "exact"},
+		{host: "APP.localhost.", machine: "exact"},
+		{host: "app.localhost:8443", machine: "exact"},
+		{host: "api.app.localhost", machine: "wildcard"},
+		{host: "other.localhost", machine: "catchall"},
+		{host: "localhost", machine: ""},
+		{host: "example.com", machine: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			route, ok := Lookup(routes, tt.host)
+			if ok != (tt.machine != "") {
+				t.Fatalf("expected found to be %t, got %t", tt.machine != "", ok)
+			}
+
+			if route.Machine != tt.machine {
+				t.Fatalf("expected machine %q, got %q", tt.machine, route.Machine)
+			}
+		})
+	}
+}
+
+// backend serves an HTTP server which responds with its name and the
+// requested host.
+func backend(t *testing.T, name string) string {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", name, r.Host)
+	}))
+	t.Cleanup(server.Close)
+
+	return strings.TrimPrefix(server.URL, "http://")
+}
+
+func TestIngressRoutesByHost(t *testing.T) {
+	routes := []Route{
+		{Domain: "a.localhost", Machine: "a", Backend: backend(t, "a")},
+		{Domain: "b.localhost", Machine: "b", Backend: backend(t, "b")},
+	}
+
+	in := New(func(context.Context) ([]Route, error) { return routes, nil }, nil)
+
+	for _, host := range []string{"a.localhost", "b.localhost"} {
+		req := httptest.NewRequest(http.MethodGet, "http://"+host+"/", nil)
+		rec := httptest.NewRecorder()
+
+		in.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+
+		if expected := host[:1] + " " + host; rec.Body.String() != expected {
+			t.Fatalf("expected %q, got %q", expected, rec.Body.String())
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "http://c.localhost/", nil)
+	rec := httptest.NewRecorder()
+
+	in.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestIngressTerminatesTLS(t *testing.T) {
+	authority, err := LoadOrCreateAuthority(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	routes := []Route{
+		{Domain: "app.localhost", Machine: "app", Backend: backend(t, "app")},
+	}
+
+	in := New(func(context.Context) ([]Route, error) { return routes, nil }, authority)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error)
+	go func() { served <- in.ServeTLSListener(ctx, listener) }()
+
+	conn, err := tls.Dial("tcp", listener.Addr().String(), &tls.Config{
+		ServerName: "app.localhost",
+		RootCAs:    authority.CertPool(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := io.WriteString(conn, "GET / HTTP/1.1\r\nHost: app.localhost\r\nConnection: close\r\n\r\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn.Close()
+
+	if string(body) != "app app.localhost" {
+		t.Fatalf("unexpected response: %q", body)
+	}
+
+	// Unknown server names are refused during the handshake.
+	if _, err := tls.Dial("tcp", listener.Addr().String(), &tls.Config{
+		ServerName: "other.localhost",
+		RootCAs:    authority.CertPool(),
+	}); err == nil {
+		t.Fatal("expected handshake with unknown server name to fail")
+	}
+
+	cancel()
+
+	if err := <-served; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadOrCreateAuthorityPersists(t *testing.T) {
+	dir := t.TempDir()
+
+	first, err := LoadOrCreateAuthority(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := LoadOrCreateAuthority(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !first.cert.Equal(second.cert) {
+		t.Fatal("expected the authority to be reused")
+	}
+
+	cert, err := second.Certificate("app.localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cert.Leaf.Verify(x509.VerifyOptions{
+		DNSName: "app.localhost",
+		Roots:   first.CertPool(),
+	}); err != nil {
+		t.Fatalf("expected certificate to be issued by the authority: %v", err)
+	}
+}
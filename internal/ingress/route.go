@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package ingress
+
+import (
+	"context"
+	"net"
+	"strings"
+)
+
+// Route directs the connections to a domain name to a machine.
+type Route struct {
+	// Domain is the domain name, or wildcard domain name, of the route.
+	Domain string
+
+	// Machine is the name of the machine the route leads to.
+	Machine string
+
+	// Backend is the address of the machine, in the format host:port.
+	Backend string
+
+	// Certificate is the path to the PEM file with the certificate chain and
+	// private key of the domain name, if any.
+	Certificate string
+}
+
+// Routes returns the currently available routes.
+type Routes func(ctx context.Context) ([]Route, error)
+
+// Matches returns whether the route serves the provided host name.
+func (route Route) Matches(host string) bool {
+	if suffix, ok := strings.CutPrefix(route.Domain, "*"); ok {
+		return strings.HasSuffix(host, suffix) && len(host) > len(suffix)
+	}
+
+	return host == route.Domain
+}
+
+// Lookup returns the route which serves the provided host name, which may
+// include a port.  Exact domain names are preferred over wildcards and longer
+// wildcards over shorter ones.
+func Lookup(routes []Route, host string) (Route, bool) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	host = Normalize(host)
+
+	var found Route
+	ok := false
+
+	for _, route := range routes {
+		if !route.Matches(host) {
+			continue
+		}
+
+		if route.Domain == host {
+			return route, true
+		}
+
+		if !ok || len(route.Domain) > len(found.Domain) {
+			found = route
+			ok = true
+		}
+	}
+
+	return found, ok
+}
+
+// Normalize returns the canonical form of the provided domain name.
+func Normalize(domain string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+}
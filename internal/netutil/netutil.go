@@ -0,0 +1,60 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+// Package netutil provides helpers for proxying connections to local
+// unikernels.
+package netutil
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+// closeWriter is a connection whose writing side can be shut down whilst its
+// reading side remains open, e.g. a *net.TCPConn or a *tls.Conn.
+type closeWriter interface {
+	CloseWrite() error
+}
+
+// Splice copies data between the two provided connections in both directions
+// until both streams have ended.  The end of each stream is propagated to the
+// other connection whilst the opposite direction drains.  Neither connection
+// is closed once both streams have ended.
+func Splice(a, b net.Conn) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	pipe := func(dst, src net.Conn) {
+		defer wg.Done()
+
+		_, _ = io.Copy(dst, src)
+
+		if cw, ok := dst.(closeWriter); ok {
+			_ = cw.CloseWrite()
+		} else {
+			dst.Close()
+		}
+	}
+
+	go pipe(a, b)
+	go pipe(b, a)
+
+	wg.Wait()
+}
+
+// FreeLoopbackPort returns a port of the loopback interface which is not in
+// use at the time of calling.
+func FreeLoopbackPort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, fmt.Errorf("could not allocate loopback port: %w", err)
+	}
+
+	defer listener.Close()
+
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package netutil_test
+
+import (
+	"io"
+	"net"
+	"strconv"
+	"testing"
+
+	"kraftkit.sh/internal/netutil"
+)
+
+func TestSpliceHalfClose(t *testing.T) {
+	backend, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer backend.Close()
+
+	// The backend only replies once the client has finished sending.
+	go func() {
+		conn, err := backend.Accept()
+		if err != nil {
+			return
+		}
+
+		defer conn.Close()
+
+		b, _ := io.ReadAll(conn)
+		_, _ = conn.Write(append([]byte("echo: "), b...))
+	}()
+
+	front, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer front.Close()
+
+	go func() {
+		conn, err := front.Accept()
+		if err != nil {
+			return
+		}
+
+		defer conn.Close()
+
+		upstream, err := net.Dial("tcp", backend.Addr().String())
+		if err != nil {
+			return
+		}
+
+		defer upstream.Close()
+
+		netutil.Splice(conn, upstream)
+	}()
+
+	client, err := net.Dial("tcp", front.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer client.Close()
+
+	if _, err := client.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.(*net.TCPConn).CloseWrite(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := io.ReadAll(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "echo: hello"; string(b) != expected {
+		t.Errorf("expected %q, got %q", expected, b)
+	}
+}
+
+func TestFreeLoopbackPort(t *testing.T) {
+	port, err := netutil.FreeLoopbackPort()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		t.Fatalf("expected port %d to be free: %v", port, err)
+	}
+
+	l.Close()
+}
@@ -0,0 +1,227 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+// Package manager implements the manager of the containerd shim of runu, which
+// starts the shim process serving a task and cleans up after it when it is
+// gone.
+package manager
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/containerd/containerd/mount"
+	"github.com/containerd/containerd/namespaces"
+	"github.com/containerd/containerd/runtime/v2/shim"
+	"golang.org/x/sys/unix"
+
+	"kraftkit.sh/internal/cli/runu/create"
+	"kraftkit.sh/internal/shim/task"
+	libcontainer "kraftkit.sh/libmocktainer"
+	"kraftkit.sh/log"
+)
+
+// groupAnnotations are the annotations of a spec which group the containers
+// served by the same shim process, in order of precedence.  The containers of
+// a CRI sandbox, including the sandbox container itself, are served by the
+// shim of the sandbox.
+var groupAnnotations = []string{
+	"io.containerd.runu.v2.group",
+	"io.kubernetes.cri.sandbox-id",
+}
+
+// NewShimManager returns a new manager of the shim with the provided name.
+func NewShimManager(name string) shim.Manager {
+	return &manager{
+		name: name,
+	}
+}
+
+type manager struct {
+	name string
+}
+
+func (m manager) Name() string {
+	return m.name
+}
+
+// newCommand returns the command which starts the shim process serving the
+// task with the provided ID.
+func newCommand(ctx context.Context, id, containerdAddress string, debug bool) (*exec.Cmd, error) {
+	ns, err := namespaces.NamespaceRequired(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{
+		"-namespace", ns,
+		"-id", id,
+		"-address", containerdAddress,
+	}
+	if debug {
+		args = append(args, "-debug")
+	}
+
+	cmd := exec.Command(self, args...)
+	cmd.Dir = cwd
+	cmd.Env = append(os.Environ(), "GOMAXPROCS=4")
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid: true,
+	}
+
+	return cmd, nil
+}
+
+// Start starts the shim process serving the task with the provided ID, whose
+// bundle is the current working directory, or returns the address of the shim
+// process of its group if it is already running.
+func (manager) Start(ctx context.Context, id string, opts shim.StartOpts) (_ string, retErr error) {
+	cmd, err := newCommand(ctx, id, opts.Address, opts.Debug)
+	if err != nil {
+		return "", err
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	spec, err := create.LoadSpec(cwd)
+	if err != nil {
+		return "", fmt.Errorf("loading runtime spec: %w", err)
+	}
+
+	grouping := id
+	for _, annotation := range groupAnnotations {
+		if groupID, ok := spec.Annotations[annotation]; ok {
+			grouping = groupID
+			break
+		}
+	}
+
+	address, err := shim.SocketAddress(ctx, opts.Address, grouping)
+	if err != nil {
+		return "", err
+	}
+
+	socket, err := shim.NewSocket(address)
+	if err != nil {
+		// The socket already exists if the shim process of the group of the task
+		// is running.
+		if !shim.SocketEaddrinuse(err) {
+			return "", fmt.Errorf("creating shim socket: %w", err)
+		}
+		if shim.CanConnect(address) {
+			if err := shim.WriteAddress("address", address); err != nil {
+				return "", fmt.Errorf("writing existing socket for shim: %w", err)
+			}
+			return address, nil
+		}
+		if err := shim.RemoveSocket(address); err != nil {
+			return "", fmt.Errorf("removing pre-existing socket: %w", err)
+		}
+		if socket, err = shim.NewSocket(address); err != nil {
+			return "", fmt.Errorf("creating shim socket: %w", err)
+		}
+	}
+
+	defer func() {
+		if retErr != nil {
+			socket.Close()
+			_ = shim.RemoveSocket(address)
+		}
+	}()
+
+	if err := shim.WriteAddress("address", address); err != nil {
+		return "", err
+	}
+
+	f, err := socket.File()
+	if err != nil {
+		return "", err
+	}
+
+	cmd.ExtraFiles = append(cmd.ExtraFiles, f)
+
+	if err := cmd.Start(); err != nil {
+		f.Close()
+		return "", err
+	}
+
+	defer func() {
+		if retErr != nil {
+			_ = cmd.Process.Kill()
+		}
+	}()
+
+	// make sure to wait after start
+	go func() { _ = cmd.Wait() }()
+
+	if err := shim.AdjustOOMScore(cmd.Process.Pid); err != nil {
+		return "", fmt.Errorf("adjusting OOM score for shim: %w", err)
+	}
+
+	return address, nil
+}
+
+// Stop destroys the container of the task with the provided ID after its shim
+// process is gone, killing its machine if it is still running.
+func (manager) Stop(ctx context.Context, id string) (shim.StopStatus, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return shim.StopStatus{}, err
+	}
+
+	bundle := filepath.Join(filepath.Dir(cwd), id)
+
+	ns, err := namespaces.NamespaceRequired(ctx)
+	if err != nil {
+		return shim.StopStatus{}, err
+	}
+
+	opts, err := task.ReadOptions(bundle)
+	if err != nil {
+		return shim.StopStatus{}, err
+	}
+
+	var pid int
+
+	if c, err := libcontainer.Load(task.RootDir(ns, opts), id); err != nil {
+		log.G(ctx).WithError(err).Warn("failed to load container")
+	} else {
+		if state, err := c.State(); err == nil {
+			pid = state.InitProcessPid
+		}
+
+		if err := task.KillContainer(c); err != nil {
+			log.G(ctx).WithError(err).Warn("failed to remove container")
+		}
+	}
+
+	if err := mount.UnmountRecursive(filepath.Join(bundle, "rootfs"), 0); err != nil {
+		log.G(ctx).WithError(err).Warn("failed to cleanup rootfs mount")
+	}
+
+	return shim.StopStatus{
+		ExitedAt:   time.Now(),
+		ExitStatus: 128 + int(unix.SIGKILL),
+		Pid:        pid,
+	}, nil
+}
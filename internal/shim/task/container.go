@@ -0,0 +1,659 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package task
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	v2 "github.com/containerd/cgroups/v3/cgroup2/stats"
+	taskAPI "github.com/containerd/containerd/api/runtime/task/v2"
+	"github.com/containerd/containerd/api/types/task"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/mount"
+	"github.com/containerd/containerd/namespaces"
+	"github.com/containerd/containerd/pkg/stdio"
+	"github.com/containerd/containerd/runtime/v2/runc/options"
+	"github.com/containerd/typeurl/v2"
+	"golang.org/x/sys/unix"
+
+	"kraftkit.sh/internal/cli/runu/create"
+	"kraftkit.sh/internal/cli/runu/update"
+	libcontainer "kraftkit.sh/libmocktainer"
+	"kraftkit.sh/libmocktainer/configs"
+	"kraftkit.sh/libmocktainer/unikraft"
+	"kraftkit.sh/log"
+	mplatform "kraftkit.sh/machine/platform"
+	qmpapi "kraftkit.sh/machine/qemu/qmp/v7alpha2"
+)
+
+const (
+	// monitorTimeout is the time to wait for QEMU to expose its QMP socket once
+	// a task is started.
+	monitorTimeout = 10 * time.Second
+
+	// monitorInterval is the interval at which the QMP socket of QEMU is looked
+	// for until it is exposed.
+	monitorInterval = 50 * time.Millisecond
+)
+
+// initContainer is the libmocktainer container of a task, which holds its init
+// process.
+type initContainer interface {
+	Status() (libcontainer.Status, error)
+	Stats() (*libcontainer.Stats, error)
+	Config() configs.Config
+	Set(configs.Config) error
+	Exec() error
+	Signal(os.Signal) error
+	Pause() error
+	Resume() error
+	Destroy() error
+}
+
+// initProcess is the init process of a task.
+type initProcess interface {
+	Wait() (*os.ProcessState, error)
+}
+
+// container is a task of the shim, which is a libmocktainer container whose
+// init process is either the virtual machine monitor of a unikernel or, for a
+// CRI sandbox, the sandbox process itself.
+type container struct {
+	mu sync.Mutex
+
+	id     string
+	bundle string
+	rootfs string
+	stdio  stdio.Stdio
+
+	// socket is the path of the QMP socket of the machine, as recorded in the
+	// state of the container.
+	socket string
+
+	// sandbox is set if the container is a CRI sandbox, whose process is run as
+	// is rather than in a machine.
+	sandbox bool
+
+	// qemu is set if the machine of the container is run by QEMU, which means
+	// that it can be controlled through QMP.
+	qemu bool
+
+	c       initContainer
+	process initProcess
+	pid     int
+	io      []io.Closer
+
+	// monitor is the QMP client of the machine, which is kept open from the
+	// start of the task until its exit.  It is nil if the machine is not run by
+	// QEMU or if QEMU could not be reached.
+	monitor  *unikraft.QemuMonitor
+	panicked bool
+
+	exited     chan struct{}
+	exitStatus int
+	exitedAt   time.Time
+}
+
+// newContainer creates the container of the task described by the provided
+// request, whose init process waits to be started.
+func newContainer(ctx context.Context, r *taskAPI.CreateTaskRequest) (_ *container, retErr error) {
+	ns, err := namespaces.NamespaceRequired(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &options.Options{}
+	if r.Options.GetValue() != nil {
+		v, err := typeurl.UnmarshalAny(r.Options)
+		if err != nil {
+			return nil, fmt.Errorf("decoding runtime options: %w", err)
+		}
+
+		if o, ok := v.(*options.Options); ok {
+			opts = o
+		}
+	}
+
+	if err := writeOptions(r.Bundle, opts); err != nil {
+		return nil, err
+	}
+
+	root := RootDir(ns, opts)
+
+	c := &container{
+		id:     r.ID,
+		bundle: r.Bundle,
+		stdio: stdio.Stdio{
+			Stdin:    r.Stdin,
+			Stdout:   r.Stdout,
+			Stderr:   r.Stderr,
+			Terminal: r.Terminal,
+		},
+		exited: make(chan struct{}),
+	}
+
+	if len(r.Rootfs) > 0 {
+		c.rootfs = filepath.Join(r.Bundle, "rootfs")
+		if err := os.Mkdir(c.rootfs, 0o711); err != nil && !os.IsExist(err) {
+			return nil, fmt.Errorf("creating rootfs: %w", err)
+		}
+
+		mounts := make([]mount.Mount, 0, len(r.Rootfs))
+		for _, m := range r.Rootfs {
+			mounts = append(mounts, mount.Mount{
+				Type:    m.Type,
+				Source:  m.Source,
+				Options: m.Options,
+			})
+		}
+
+		if err := mount.All(mounts, c.rootfs); err != nil {
+			return nil, fmt.Errorf("mounting rootfs: %w", err)
+		}
+
+		defer func() {
+			if retErr != nil {
+				c.unmountRootfs(ctx)
+			}
+		}()
+	}
+
+	spec, err := create.LoadSpec(r.Bundle)
+	if err != nil {
+		return nil, fmt.Errorf("loading runtime spec: %w", err)
+	}
+
+	c.sandbox = create.IsCRISandbox(spec)
+	if !c.sandbox {
+		plat, err := create.MachinePlatform(spec)
+		if err != nil {
+			return nil, err
+		}
+
+		c.qemu = plat == mplatform.PlatformQEMU
+	}
+
+	if err := create.PrepareSpec(ctx, r.ID, root, r.Bundle, spec); err != nil {
+		return nil, err
+	}
+
+	c.socket = spec.Annotations[unikraft.AnnotationQemuControlSocket]
+
+	// The directory of the QMP socket is removed along with the container once
+	// it has been created.
+	defer func() {
+		if retErr != nil && c.c == nil {
+			_ = unikraft.RemoveQemuControlSocket(c.socket)
+		}
+	}()
+
+	if c.stdio.Terminal {
+		// The console of a unikernel is its serial port, which is streamed as is
+		// rather than through a pseudoterminal.
+		log.G(ctx).WithField("id", r.ID).Debug("ignoring terminal, streaming serial console")
+	}
+
+	p := &libcontainer.Process{
+		Args:     spec.Process.Args,
+		Env:      spec.Process.Env,
+		LogLevel: strconv.Itoa(int(log.G(ctx).Level)),
+	}
+
+	if err := c.openIO(p); err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if retErr != nil {
+			c.closeIO()
+		}
+	}()
+
+	lc, err := create.CreateContainer(r.ID, root, r.Bundle, opts.SystemdCgroup, spec)
+	if err != nil {
+		return nil, fmt.Errorf("creating container environment: %w", err)
+	}
+
+	c.c = lc
+
+	if err := lc.Start(p); err != nil {
+		if derr := lc.Destroy(); derr != nil {
+			log.G(ctx).Warnf("could not destroy container: %v", derr)
+		}
+
+		return nil, fmt.Errorf("starting container init process: %w", err)
+	}
+
+	c.process = p
+	c.pid, _ = p.Pid()
+
+	return c, nil
+}
+
+// openIO opens the standard streams of the task and sets them as the ones of
+// the provided process.  The serial console of the machine is the standard
+// output of its virtual machine monitor, and is thus streamed to the standard
+// output of the task.
+func (c *container) openIO(p *libcontainer.Process) error {
+	stdin, err := openStdio(c.stdio.Stdin, os.O_RDONLY|syscall.O_NONBLOCK)
+	if err != nil {
+		return fmt.Errorf("opening stdin: %w", err)
+	}
+	if stdin != nil {
+		c.io = append(c.io, stdin)
+		p.Stdin = stdin
+	}
+
+	stdout, err := openStdio(c.stdio.Stdout, os.O_WRONLY)
+	if err != nil {
+		c.closeIO()
+		return fmt.Errorf("opening stdout: %w", err)
+	}
+	if stdout != nil {
+		c.io = append(c.io, stdout)
+		p.Stdout = stdout
+	}
+
+	if c.stdio.Stderr == c.stdio.Stdout {
+		if stdout != nil {
+			p.Stderr = stdout
+		}
+		return nil
+	}
+
+	stderr, err := openStdio(c.stdio.Stderr, os.O_WRONLY)
+	if err != nil {
+		c.closeIO()
+		return fmt.Errorf("opening stderr: %w", err)
+	}
+	if stderr != nil {
+		c.io = append(c.io, stderr)
+		p.Stderr = stderr
+	}
+
+	return nil
+}
+
+// openStdio opens the provided standard stream of a task, which is either a
+// FIFO or a file:// URI.  The streams are opened as files such that they are
+// inherited by the virtual machine monitor rather than copied.
+func openStdio(path string, flag int) (*os.File, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	u, err := url.Parse(path)
+	if err != nil || u.Scheme == "" {
+		return os.OpenFile(path, flag, 0)
+	}
+
+	switch u.Scheme {
+	case "file":
+		if flag&os.O_WRONLY == 0 {
+			return nil, fmt.Errorf("%w: reading from %s", errdefs.ErrNotImplemented, path)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(u.Path), 0o755); err != nil {
+			return nil, err
+		}
+
+		return os.OpenFile(u.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+
+	default:
+		return nil, fmt.Errorf("%w: unsupported stdio scheme: %s", errdefs.ErrNotImplemented, u.Scheme)
+	}
+}
+
+// closeIO closes the standard streams of the task.
+func (c *container) closeIO() {
+	for _, f := range c.io {
+		_ = f.Close()
+	}
+
+	c.io = nil
+}
+
+// closeStdin closes the standard input of the task.
+func (c *container) closeStdin() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stdio.Stdin == "" || len(c.io) == 0 {
+		return
+	}
+
+	_ = c.io[0].Close()
+}
+
+// unmountRootfs unmounts the root filesystem of the task, if it was mounted by
+// the shim.
+func (c *container) unmountRootfs(ctx context.Context) {
+	if c.rootfs == "" {
+		return
+	}
+
+	if err := mount.UnmountRecursive(c.rootfs, 0); err != nil {
+		log.G(ctx).Warnf("could not unmount rootfs: %v", err)
+	}
+}
+
+// wait waits for the init process of the task to exit and records its exit
+// status.
+func (c *container) wait(ctx context.Context) {
+	state, err := c.process.Wait()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch {
+	case err != nil && state == nil:
+		log.G(ctx).WithField("id", c.id).Warnf("could not wait for init process: %v", err)
+		c.exitStatus = 255
+	case state.Sys().(syscall.WaitStatus).Signaled():
+		c.exitStatus = 128 + int(state.Sys().(syscall.WaitStatus).Signal())
+	default:
+		c.exitStatus = state.ExitCode()
+	}
+
+	// QEMU exits successfully when the guest panics, which is only known from
+	// the event it emits beforehand.
+	if c.panicked && c.exitStatus == 0 {
+		c.exitStatus = 1
+	}
+
+	c.exitedAt = time.Now()
+
+	if c.monitor != nil {
+		_ = c.monitor.Close()
+		c.monitor = nil
+	}
+
+	c.closeIO()
+	close(c.exited)
+}
+
+// start starts the init process of the task and, if its machine is run by
+// QEMU, connects to the QMP socket of the machine.
+func (c *container) start(ctx context.Context) error {
+	if err := c.c.Exec(); err != nil {
+		return fmt.Errorf("starting container: %w", err)
+	}
+
+	if !c.qemu {
+		return nil
+	}
+
+	monitor, err := c.connectMonitor(ctx)
+	if err != nil {
+		log.G(ctx).WithField("id", c.id).Warnf("could not connect to machine: %v", err)
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	select {
+	case <-c.exited:
+		_ = monitor.Close()
+		return nil
+	default:
+	}
+
+	c.monitor = monitor
+
+	go c.watch(ctx, monitor)
+
+	return nil
+}
+
+// connectMonitor waits for QEMU to expose its QMP socket and connects to it.
+func (c *container) connectMonitor(ctx context.Context) (*unikraft.QemuMonitor, error) {
+	ctx, cancel := context.WithTimeout(ctx, monitorTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(monitorInterval)
+	defer ticker.Stop()
+
+	for {
+		monitor, err := unikraft.NewQemuMonitor(c.socket)
+		if err != nil || monitor != nil {
+			return monitor, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for QMP socket: %w", ctx.Err())
+		case <-c.exited:
+			return nil, libcontainer.ErrNotRunning
+		case <-ticker.C:
+		}
+	}
+}
+
+// watch handles the events emitted by the machine of the task until the
+// connection to it is closed.
+func (c *container) watch(ctx context.Context, monitor *unikraft.QemuMonitor) {
+	for event := range monitor.Events() {
+		log.G(ctx).
+			WithField("id", c.id).
+			Debugf("received machine event: %s", event.Event)
+
+		if event.Event == qmpapi.EVENT_GUEST_PANICKED {
+			c.mu.Lock()
+			c.panicked = true
+			c.mu.Unlock()
+		}
+	}
+}
+
+// getMonitor returns the QMP client of the machine of the task, if any.
+func (c *container) getMonitor() *unikraft.QemuMonitor {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.monitor
+}
+
+// status returns the status of the task.  The status of a running container
+// is refined with the run state of its machine, which can be stopped without
+// the container being paused, for instance on an I/O error.
+func (c *container) status() (task.Status, error) {
+	st, err := c.c.Status()
+	if err != nil {
+		return task.Status_UNKNOWN, err
+	}
+
+	switch st {
+	case libcontainer.Created:
+		return task.Status_CREATED, nil
+	case libcontainer.Paused:
+		return task.Status_PAUSED, nil
+	case libcontainer.Stopped:
+		return task.Status_STOPPED, nil
+	case libcontainer.Running:
+	default:
+		return task.Status_UNKNOWN, nil
+	}
+
+	// The monitor cannot answer whilst the container is frozen, which is why it
+	// is only queried once the container is known to be running.
+	if monitor := c.getMonitor(); monitor != nil {
+		info, err := monitor.Status()
+		if err == nil && !info.Running && info.Status != qmpapi.RUN_STATE_SHUTDOWN {
+			return task.Status_PAUSED, nil
+		}
+	}
+
+	return task.Status_RUNNING, nil
+}
+
+// pause stops the machine of the task and freezes its container.
+func (c *container) pause(ctx context.Context) error {
+	monitor := c.getMonitor()
+
+	// The machine is stopped before its monitor is frozen, since QEMU can no
+	// longer answer to QMP commands afterwards.
+	if monitor != nil {
+		if err := monitor.Stop(); err != nil {
+			return fmt.Errorf("pausing machine: %w", err)
+		}
+	}
+
+	if err := c.c.Pause(); err != nil {
+		if monitor != nil {
+			if cerr := monitor.Cont(); cerr != nil {
+				log.G(ctx).Warnf("could not resume machine: %v", cerr)
+			}
+		}
+
+		return fmt.Errorf("pausing container: %w", err)
+	}
+
+	return nil
+}
+
+// resume thaws the container of the task and resumes its machine.
+func (c *container) resume() error {
+	if err := c.c.Resume(); err != nil {
+		return fmt.Errorf("resuming container: %w", err)
+	}
+
+	if monitor := c.getMonitor(); monitor != nil {
+		if err := monitor.Cont(); err != nil {
+			return fmt.Errorf("resuming machine: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// kill sends the provided signal to the init process of the task.
+func (c *container) kill(sig uint32) error {
+	if err := c.c.Signal(unix.Signal(sig)); err != nil {
+		if errors.Is(err, libcontainer.ErrNotRunning) {
+			return fmt.Errorf("%w: %w", errdefs.ErrNotFound, err)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// update updates the resources of the task from the provided JSON-encoded
+// Linux resources.
+func (c *container) update(resources []byte) error {
+	r, err := update.ReadResources(bytes.NewReader(resources))
+	if err != nil {
+		return err
+	}
+
+	return update.UpdateContainer(c.c, r)
+}
+
+// stats returns the metrics of the task, which are those of the cgroup of its
+// virtual machine monitor since the usage within the unikernel cannot be
+// queried.  The memory of a machine is reported as its limit when its cgroup
+// is not limited, since the machine cannot use more memory than it was given.
+func (c *container) stats() (*v2.Metrics, error) {
+	st, err := c.c.Stats()
+	if err != nil {
+		return nil, fmt.Errorf("getting container stats: %w", err)
+	}
+
+	m := metrics(st.CgroupStats)
+
+	if status, err := c.c.Status(); err != nil || status != libcontainer.Running {
+		return m, nil
+	}
+
+	if monitor := c.getMonitor(); monitor != nil && !hasMemoryLimit(m.Memory.UsageLimit) {
+		if size, err := monitor.MemorySize(); err == nil {
+			m.Memory.UsageLimit = size
+		}
+	}
+
+	return m, nil
+}
+
+// destroy destroys the container of the task, killing its init process first
+// unless it has exited.
+func (c *container) destroy(ctx context.Context, force bool) error {
+	st, err := c.c.Status()
+	if err != nil {
+		return fmt.Errorf("getting container status: %w", err)
+	}
+
+	switch st {
+	case libcontainer.Stopped:
+	case libcontainer.Created:
+		if err := killContainer(c.c); err != nil {
+			return err
+		}
+	default:
+		if !force {
+			return fmt.Errorf("%w: container is not stopped: %s", errdefs.ErrFailedPrecondition, st)
+		}
+
+		if err := killContainer(c.c); err != nil {
+			return err
+		}
+	}
+
+	if err := destroyContainer(c.c); err != nil {
+		return err
+	}
+
+	c.unmountRootfs(ctx)
+
+	return nil
+}
+
+// destroyContainer destroys the given container once its init process has
+// exited.
+func destroyContainer(c initContainer) error {
+	if err := c.Destroy(); err != nil {
+		return fmt.Errorf("destroying container: %w", err)
+	}
+
+	return nil
+}
+
+// killContainer sends a SIGKILL to the init process of the given container and
+// waits for it to exit.
+func killContainer(c initContainer) error {
+	_ = c.Signal(unix.SIGKILL)
+	for i := 0; i < 100; i++ {
+		time.Sleep(100 * time.Millisecond)
+		if err := c.Signal(unix.Signal(0)); err != nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("container init still running")
+}
+
+// KillContainer kills the init process of the given container, if it is still
+// running, and destroys the container.
+func KillContainer(c *libcontainer.Container) error {
+	if st, err := c.Status(); err == nil && st != libcontainer.Stopped {
+		if err := killContainer(c); err != nil {
+			return err
+		}
+	}
+
+	return destroyContainer(c)
+}
@@ -0,0 +1,124 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package task
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/pkg/stdio"
+	"golang.org/x/sys/unix"
+
+	libcontainer "kraftkit.sh/libmocktainer"
+)
+
+func TestOpenStdioFIFO(t *testing.T) {
+	fifo := filepath.Join(t.TempDir(), "stdout")
+	if err := unix.Mkfifo(fifo, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// The reading end is opened first, as containerd does, such that opening
+	// the writing end does not block.
+	r, err := openStdio(fifo, os.O_RDONLY|syscall.O_NONBLOCK)
+	if err != nil {
+		t.Fatalf("opening FIFO for reading: %v", err)
+	}
+
+	defer r.Close()
+
+	w, err := openStdio(fifo, os.O_WRONLY)
+	if err != nil {
+		t.Fatalf("opening FIFO for writing: %v", err)
+	}
+
+	if _, err := w.Write([]byte("console")); err != nil {
+		t.Fatal(err)
+	}
+
+	w.Close()
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(b) != "console" {
+		t.Errorf("expected %q to be read from FIFO, got %q", "console", b)
+	}
+}
+
+func TestOpenStdioFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "task.log")
+
+	// The file and its parent directories are created and appended to.
+	for _, line := range []string{"first\n", "second\n"} {
+		f, err := openStdio("file://"+path, os.O_WRONLY)
+		if err != nil {
+			t.Fatalf("opening file: %v", err)
+		}
+
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+
+		f.Close()
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "first\nsecond\n"; string(b) != expected {
+		t.Errorf("expected file to contain %q, got %q", expected, b)
+	}
+
+	if _, err := openStdio("file://"+path, os.O_RDONLY); !errdefs.IsNotImplemented(err) {
+		t.Errorf("expected reading from a file to be unsupported, got %v", err)
+	}
+
+	if _, err := openStdio("binary:///usr/bin/logger", os.O_WRONLY); !errdefs.IsNotImplemented(err) {
+		t.Errorf("expected binary logging to be unsupported, got %v", err)
+	}
+
+	if f, err := openStdio("", os.O_WRONLY); f != nil || err != nil {
+		t.Errorf("expected no stream to be opened for an empty path, got %v, %v", f, err)
+	}
+}
+
+func TestOpenIOSharedOutput(t *testing.T) {
+	path := "file://" + filepath.Join(t.TempDir(), "task.log")
+
+	c := &container{
+		stdio: stdio.Stdio{
+			Stdout: path,
+			Stderr: path,
+		},
+	}
+
+	p := &libcontainer.Process{}
+	if err := c.openIO(p); err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.closeIO()
+
+	if p.Stdin != nil {
+		t.Error("expected no stdin to be opened")
+	}
+
+	if p.Stdout == nil || p.Stdout != p.Stderr {
+		t.Error("expected stdout and stderr to share the same file")
+	}
+
+	if len(c.io) != 1 {
+		t.Errorf("expected a single stream to be opened, got %d", len(c.io))
+	}
+}
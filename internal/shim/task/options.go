@@ -0,0 +1,67 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package task
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/containerd/containerd/runtime/v2/runc/options"
+)
+
+// DefaultRoot is the directory in which the state of the containers of each
+// containerd namespace is stored, unless the runtime options of a task set
+// another one.
+const DefaultRoot = "/run/containerd/runu"
+
+// optionsFile is the name of the file in the bundle of a task which holds its
+// runtime options, such that they can be read again when the shim is gone.
+const optionsFile = "options.json"
+
+// RootDir returns the directory in which the state of the containers of the
+// provided containerd namespace is stored given the provided runtime options.
+func RootDir(ns string, opts *options.Options) string {
+	root := DefaultRoot
+	if opts != nil && opts.Root != "" {
+		root = opts.Root
+	}
+
+	return filepath.Join(root, ns)
+}
+
+// ReadOptions reads the runtime options from the provided bundle.  It returns
+// nil if the task was created without runtime options.
+func ReadOptions(bundle string) (*options.Options, error) {
+	b, err := os.ReadFile(filepath.Join(bundle, optionsFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("reading runtime options: %w", err)
+	}
+
+	opts := &options.Options{}
+	if err := json.Unmarshal(b, opts); err != nil {
+		return nil, fmt.Errorf("decoding runtime options: %w", err)
+	}
+
+	return opts, nil
+}
+
+// writeOptions writes the provided runtime options to the provided bundle.
+func writeOptions(bundle string, opts *options.Options) error {
+	b, err := json.Marshal(opts)
+	if err != nil {
+		return fmt.Errorf("encoding runtime options: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(bundle, optionsFile), b, 0o600); err != nil {
+		return fmt.Errorf("writing runtime options: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,473 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+// Package task implements the task service of the containerd shim of runu,
+// which runs the unikernel of each task in a libmocktainer container from
+// within the shim itself.  Unlike with runu behind the runc shim, the shim
+// outlives the operations on a task, which allows it to keep a connection to
+// the QMP socket of each machine for the lifetime of the machine.
+//
+// The metrics of a task are those of the cgroup of its virtual machine
+// monitor rather than of the unikernel: the CPU time includes the time spent
+// emulating devices, and the memory usage is the memory of the guest which
+// the monitor has touched in addition to its own.  Unikernels have no guest
+// agent nor balloon device which could report their usage, such that the only
+// metric taken from the machine through QMP is its memory size, which is
+// reported as the memory limit of a task whose cgroup is not limited.
+package task
+
+import (
+	"context"
+	"os"
+	"sync"
+
+	eventstypes "github.com/containerd/containerd/api/events"
+	taskAPI "github.com/containerd/containerd/api/runtime/task/v2"
+	"github.com/containerd/containerd/api/types/task"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/namespaces"
+	"github.com/containerd/containerd/pkg/shutdown"
+	"github.com/containerd/containerd/protobuf"
+	ptypes "github.com/containerd/containerd/protobuf/types"
+	"github.com/containerd/containerd/runtime"
+	"github.com/containerd/containerd/runtime/v2/shim"
+	"github.com/containerd/ttrpc"
+
+	"kraftkit.sh/log"
+)
+
+var (
+	_     = (taskAPI.TaskService)(&service{})
+	empty = &ptypes.Empty{}
+)
+
+// NewTaskService returns a new task service which publishes the events of its
+// tasks with the provided publisher.
+func NewTaskService(ctx context.Context, publisher shim.Publisher, sd shutdown.Service) (taskAPI.TaskService, error) {
+	s := &service{
+		context:    ctx,
+		events:     make(chan interface{}, 128),
+		containers: make(map[string]*container),
+		shutdown:   sd,
+
+		newContainer: newContainer,
+	}
+
+	go s.forward(ctx, publisher)
+
+	sd.RegisterCallback(func(context.Context) error {
+		close(s.events)
+		return nil
+	})
+
+	if address, err := shim.ReadAddress("address"); err == nil {
+		sd.RegisterCallback(func(context.Context) error {
+			return shim.RemoveSocket(address)
+		})
+	}
+
+	return s, nil
+}
+
+// service is the task service of the shim, which serves the tasks of a single
+// container or, with CRI, of all the containers of a sandbox.
+type service struct {
+	mu sync.Mutex
+
+	context    context.Context
+	events     chan interface{}
+	containers map[string]*container
+
+	shutdown shutdown.Service
+
+	// newContainer creates the container of a task from the request to create
+	// the task.
+	newContainer func(context.Context, *taskAPI.CreateTaskRequest) (*container, error)
+}
+
+func (s *service) RegisterTTRPC(server *ttrpc.Server) error {
+	taskAPI.RegisterTaskService(server, s)
+	return nil
+}
+
+// Create a new container whose init process waits to be started.
+func (s *service) Create(ctx context.Context, r *taskAPI.CreateTaskRequest) (*taskAPI.CreateTaskResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.containers[r.ID]; ok {
+		return nil, errdefs.ToGRPCf(errdefs.ErrAlreadyExists, "container %s", r.ID)
+	}
+
+	c, err := s.newContainer(ctx, r)
+	if err != nil {
+		log.G(ctx).Error(err)
+		return nil, errdefs.ToGRPC(err)
+	}
+
+	s.containers[r.ID] = c
+
+	s.send(&eventstypes.TaskCreate{
+		ContainerID: r.ID,
+		Bundle:      r.Bundle,
+		Rootfs:      r.Rootfs,
+		IO: &eventstypes.TaskIO{
+			Stdin:    r.Stdin,
+			Stdout:   r.Stdout,
+			Stderr:   r.Stderr,
+			Terminal: r.Terminal,
+		},
+		Checkpoint: r.Checkpoint,
+		Pid:        uint32(c.pid),
+	})
+
+	go func() {
+		c.wait(s.context)
+
+		s.send(&eventstypes.TaskExit{
+			ContainerID: c.id,
+			ID:          c.id,
+			Pid:         uint32(c.pid),
+			ExitStatus:  uint32(c.exitStatus),
+			ExitedAt:    protobuf.ToTimestamp(c.exitedAt),
+		})
+	}()
+
+	return &taskAPI.CreateTaskResponse{
+		Pid: uint32(c.pid),
+	}, nil
+}
+
+// Start the init process of a container.
+func (s *service) Start(ctx context.Context, r *taskAPI.StartRequest) (*taskAPI.StartResponse, error) {
+	if r.ExecID != "" {
+		return nil, errdefs.ToGRPCf(errdefs.ErrNotImplemented, "exec is not supported by unikernels")
+	}
+
+	c, err := s.getContainer(r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.start(ctx); err != nil {
+		log.G(ctx).Error(err)
+		return nil, errdefs.ToGRPC(err)
+	}
+
+	s.send(&eventstypes.TaskStart{
+		ContainerID: c.id,
+		Pid:         uint32(c.pid),
+	})
+
+	return &taskAPI.StartResponse{
+		Pid: uint32(c.pid),
+	}, nil
+}
+
+// Delete the container once its init process has exited.
+func (s *service) Delete(ctx context.Context, r *taskAPI.DeleteRequest) (*taskAPI.DeleteResponse, error) {
+	if r.ExecID != "" {
+		return nil, errdefs.ToGRPCf(errdefs.ErrNotFound, "exec %s", r.ExecID)
+	}
+
+	c, err := s.getContainer(r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.destroy(ctx, false); err != nil {
+		return nil, errdefs.ToGRPC(err)
+	}
+
+	<-c.exited
+
+	s.mu.Lock()
+	delete(s.containers, r.ID)
+	s.mu.Unlock()
+
+	s.send(&eventstypes.TaskDelete{
+		ContainerID: c.id,
+		Pid:         uint32(c.pid),
+		ExitStatus:  uint32(c.exitStatus),
+		ExitedAt:    protobuf.ToTimestamp(c.exitedAt),
+	})
+
+	return &taskAPI.DeleteResponse{
+		Pid:        uint32(c.pid),
+		ExitStatus: uint32(c.exitStatus),
+		ExitedAt:   protobuf.ToTimestamp(c.exitedAt),
+	}, nil
+}
+
+// Exec is not supported, since unikernels run a single application.
+func (s *service) Exec(ctx context.Context, r *taskAPI.ExecProcessRequest) (*ptypes.Empty, error) {
+	return nil, errdefs.ToGRPCf(errdefs.ErrNotImplemented, "exec is not supported by unikernels")
+}
+
+// ResizePty does nothing, since the serial console of a machine is not
+// attached to a pseudoterminal.
+func (s *service) ResizePty(ctx context.Context, r *taskAPI.ResizePtyRequest) (*ptypes.Empty, error) {
+	if _, err := s.getContainer(r.ID); err != nil {
+		return nil, err
+	}
+
+	return empty, nil
+}
+
+// State returns runtime state information for the init process of a container.
+func (s *service) State(ctx context.Context, r *taskAPI.StateRequest) (*taskAPI.StateResponse, error) {
+	if r.ExecID != "" {
+		return nil, errdefs.ToGRPCf(errdefs.ErrNotFound, "exec %s", r.ExecID)
+	}
+
+	c, err := s.getContainer(r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &taskAPI.StateResponse{
+		ID:       c.id,
+		Bundle:   c.bundle,
+		Pid:      uint32(c.pid),
+		Stdin:    c.stdio.Stdin,
+		Stdout:   c.stdio.Stdout,
+		Stderr:   c.stdio.Stderr,
+		Terminal: c.stdio.Terminal,
+	}
+
+	select {
+	case <-c.exited:
+		res.Status = task.Status_STOPPED
+		res.ExitStatus = uint32(c.exitStatus)
+		res.ExitedAt = protobuf.ToTimestamp(c.exitedAt)
+		return res, nil
+	default:
+	}
+
+	if res.Status, err = c.status(); err != nil {
+		return nil, errdefs.ToGRPC(err)
+	}
+
+	return res, nil
+}
+
+// Pause the machine and the container.
+func (s *service) Pause(ctx context.Context, r *taskAPI.PauseRequest) (*ptypes.Empty, error) {
+	c, err := s.getContainer(r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.pause(ctx); err != nil {
+		return nil, errdefs.ToGRPC(err)
+	}
+
+	s.send(&eventstypes.TaskPaused{
+		ContainerID: c.id,
+	})
+
+	return empty, nil
+}
+
+// Resume the container and the machine.
+func (s *service) Resume(ctx context.Context, r *taskAPI.ResumeRequest) (*ptypes.Empty, error) {
+	c, err := s.getContainer(r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.resume(); err != nil {
+		return nil, errdefs.ToGRPC(err)
+	}
+
+	s.send(&eventstypes.TaskResumed{
+		ContainerID: c.id,
+	})
+
+	return empty, nil
+}
+
+// Kill the init process of a container with the provided signal.
+func (s *service) Kill(ctx context.Context, r *taskAPI.KillRequest) (*ptypes.Empty, error) {
+	if r.ExecID != "" {
+		return nil, errdefs.ToGRPCf(errdefs.ErrNotFound, "exec %s", r.ExecID)
+	}
+
+	c, err := s.getContainer(r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.kill(r.Signal); err != nil {
+		return nil, errdefs.ToGRPC(err)
+	}
+
+	return empty, nil
+}
+
+// Pids returns the init process of a container, which is its only process.
+func (s *service) Pids(ctx context.Context, r *taskAPI.PidsRequest) (*taskAPI.PidsResponse, error) {
+	c, err := s.getContainer(r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &taskAPI.PidsResponse{
+		Processes: []*task.ProcessInfo{{
+			Pid: uint32(c.pid),
+		}},
+	}, nil
+}
+
+// CloseIO closes the standard input of the init process of a container.
+func (s *service) CloseIO(ctx context.Context, r *taskAPI.CloseIORequest) (*ptypes.Empty, error) {
+	c, err := s.getContainer(r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Stdin {
+		c.closeStdin()
+	}
+
+	return empty, nil
+}
+
+// Checkpoint is not supported.
+func (s *service) Checkpoint(ctx context.Context, r *taskAPI.CheckpointTaskRequest) (*ptypes.Empty, error) {
+	return nil, errdefs.ToGRPCf(errdefs.ErrNotImplemented, "checkpoint is not supported by runu")
+}
+
+// Update the resources of a container.
+func (s *service) Update(ctx context.Context, r *taskAPI.UpdateTaskRequest) (*ptypes.Empty, error) {
+	c, err := s.getContainer(r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.update(r.Resources.GetValue()); err != nil {
+		return nil, errdefs.ToGRPC(err)
+	}
+
+	return empty, nil
+}
+
+// Wait for the init process of a container to exit.
+func (s *service) Wait(ctx context.Context, r *taskAPI.WaitRequest) (*taskAPI.WaitResponse, error) {
+	if r.ExecID != "" {
+		return nil, errdefs.ToGRPCf(errdefs.ErrNotFound, "exec %s", r.ExecID)
+	}
+
+	c, err := s.getContainer(r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-c.exited:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	return &taskAPI.WaitResponse{
+		ExitStatus: uint32(c.exitStatus),
+		ExitedAt:   protobuf.ToTimestamp(c.exitedAt),
+	}, nil
+}
+
+// Connect returns shim information such as the shim's pid.
+func (s *service) Connect(ctx context.Context, r *taskAPI.ConnectRequest) (*taskAPI.ConnectResponse, error) {
+	var pid int
+	if c, err := s.getContainer(r.ID); err == nil {
+		pid = c.pid
+	}
+
+	return &taskAPI.ConnectResponse{
+		ShimPid: uint32(os.Getpid()),
+		TaskPid: uint32(pid),
+	}, nil
+}
+
+// Shutdown the shim once it no longer serves any container.
+func (s *service) Shutdown(ctx context.Context, r *taskAPI.ShutdownRequest) (*ptypes.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// return out if the shim is still servicing containers
+	if len(s.containers) > 0 {
+		return empty, nil
+	}
+
+	s.shutdown.Shutdown()
+
+	return empty, nil
+}
+
+// Stats returns the metrics of a container.
+func (s *service) Stats(ctx context.Context, r *taskAPI.StatsRequest) (*taskAPI.StatsResponse, error) {
+	c, err := s.getContainer(r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := c.stats()
+	if err != nil {
+		return nil, errdefs.ToGRPC(err)
+	}
+
+	data, err := protobuf.MarshalAnyToProto(m)
+	if err != nil {
+		return nil, err
+	}
+
+	return &taskAPI.StatsResponse{
+		Stats: data,
+	}, nil
+}
+
+func (s *service) send(evt interface{}) {
+	s.events <- evt
+}
+
+func (s *service) forward(ctx context.Context, publisher shim.Publisher) {
+	ns, _ := namespaces.Namespace(ctx)
+	ctx = namespaces.WithNamespace(context.Background(), ns)
+	for e := range s.events {
+		if err := publisher.Publish(ctx, topic(e), e); err != nil {
+			log.G(ctx).WithError(err).Error("post event")
+		}
+	}
+	publisher.Close()
+}
+
+func (s *service) getContainer(id string) (*container, error) {
+	s.mu.Lock()
+	c := s.containers[id]
+	s.mu.Unlock()
+	if c == nil {
+		return nil, errdefs.ToGRPCf(errdefs.ErrNotFound, "container not created")
+	}
+	return c, nil
+}
+
+// topic returns the topic of the provided event.
+func topic(e interface{}) string {
+	switch e.(type) {
+	case *eventstypes.TaskCreate:
+		return runtime.TaskCreateEventTopic
+	case *eventstypes.TaskStart:
+		return runtime.TaskStartEventTopic
+	case *eventstypes.TaskExit:
+		return runtime.TaskExitEventTopic
+	case *eventstypes.TaskDelete:
+		return runtime.TaskDeleteEventTopic
+	case *eventstypes.TaskPaused:
+		return runtime.TaskPausedEventTopic
+	case *eventstypes.TaskResumed:
+		return runtime.TaskResumedEventTopic
+	default:
+		return runtime.TaskUnknownTopic
+	}
+}
@@ -0,0 +1,492 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package task
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	eventstypes "github.com/containerd/containerd/api/events"
+	taskAPI "github.com/containerd/containerd/api/runtime/task/v2"
+	"github.com/containerd/containerd/api/types/task"
+	"github.com/containerd/containerd/errdefs"
+
+	libcontainer "kraftkit.sh/libmocktainer"
+	"kraftkit.sh/libmocktainer/configs"
+)
+
+// fakeInitEnv is the environment variable which holds the exit code of a fake
+// init process.
+const fakeInitEnv = "RUNU_SHIM_FAKE_INIT"
+
+// fakeInitReady is written by a fake init process once it handles SIGTERM.
+const fakeInitReady = '!'
+
+// TestFakeInitHelper is not a test by itself but the init process of a fake
+// container.  Like the init process of a libmocktainer container, which waits
+// on its exec FIFO, it waits for its standard input to be closed before it is
+// started, after which it exits with the provided exit code once terminated.
+func TestFakeInitHelper(t *testing.T) {
+	code := os.Getenv(fakeInitEnv)
+	if code == "" {
+		t.Skip("only run as a helper process")
+	}
+
+	exitCode, err := strconv.Atoi(code)
+	if err != nil {
+		os.Exit(255)
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM)
+
+	// Report that the process is ready to be terminated.
+	if _, err := os.Stdout.Write([]byte{fakeInitReady}); err != nil {
+		os.Exit(255)
+	}
+
+	_, _ = io.Copy(io.Discard, os.Stdin)
+
+	<-sigs
+	os.Exit(exitCode)
+}
+
+// fakeContainer is a libmocktainer container whose init process is a helper
+// process of the test binary rather than a virtual machine monitor.
+type fakeContainer struct {
+	mu        sync.Mutex
+	cmd       *exec.Cmd
+	start     io.WriteCloser
+	status    libcontainer.Status
+	exited    bool
+	destroyed bool
+}
+
+// newFakeContainer creates a fake container whose init process exits with the
+// provided exit code once terminated, and waits to be started.
+func newFakeContainer(t *testing.T, exitCode int) (*fakeContainer, error) {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestFakeInitHelper$")
+	cmd.Env = append(os.Environ(), fakeInitEnv+"="+strconv.Itoa(exitCode))
+
+	start, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	ready, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 1)
+	if _, err := io.ReadFull(ready, b); err != nil || b[0] != fakeInitReady {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return nil, fmt.Errorf("fake init process is not ready: %v", err)
+	}
+
+	f := &fakeContainer{
+		cmd:    cmd,
+		start:  start,
+		status: libcontainer.Created,
+	}
+
+	t.Cleanup(func() {
+		_ = f.cmd.Process.Kill()
+	})
+
+	return f, nil
+}
+
+func (f *fakeContainer) Status() (libcontainer.Status, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.exited || f.destroyed {
+		return libcontainer.Stopped, nil
+	}
+
+	return f.status, nil
+}
+
+func (f *fakeContainer) Stats() (*libcontainer.Stats, error) {
+	return nil, errdefs.ErrNotImplemented
+}
+
+func (f *fakeContainer) Config() configs.Config {
+	return configs.Config{}
+}
+
+func (f *fakeContainer) Set(configs.Config) error {
+	return errdefs.ErrNotImplemented
+}
+
+func (f *fakeContainer) Exec() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.status != libcontainer.Created || f.exited {
+		return libcontainer.ErrNotRunning
+	}
+
+	f.status = libcontainer.Running
+
+	return f.start.Close()
+}
+
+func (f *fakeContainer) Signal(sig os.Signal) error {
+	if err := f.cmd.Process.Signal(sig); err != nil {
+		return libcontainer.ErrNotRunning
+	}
+
+	return nil
+}
+
+func (f *fakeContainer) Pause() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.status = libcontainer.Paused
+
+	return nil
+}
+
+func (f *fakeContainer) Resume() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.status = libcontainer.Running
+
+	return nil
+}
+
+func (f *fakeContainer) Destroy() error {
+	if err := f.cmd.Process.Signal(syscall.Signal(0)); err == nil {
+		return errors.New("container init still running")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.destroyed = true
+
+	return nil
+}
+
+// Wait implements initProcess.
+func (f *fakeContainer) Wait() (*os.ProcessState, error) {
+	err := f.cmd.Wait()
+
+	f.mu.Lock()
+	f.exited = true
+	f.mu.Unlock()
+
+	return f.cmd.ProcessState, err
+}
+
+// isDestroyed returns whether the fake container has been destroyed.
+func (f *fakeContainer) isDestroyed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.destroyed
+}
+
+// serveFakeQMP serves a QMP socket at the provided path which reports that the
+// guest has panicked as soon as the capabilities have been negotiated, and
+// reports that the machine is running to any other command.
+func serveFakeQMP(t *testing.T, path string) {
+	t.Helper()
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		l.Close()
+	})
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		defer conn.Close()
+
+		_, _ = conn.Write([]byte(`{"QMP": {"version": {"qemu": {"major": 8, "minor": 2, "micro": 0}, "package": ""}, "capabilities": []}}` + "\n"))
+
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			if strings.Contains(line, "qmp_capabilities") {
+				_, _ = conn.Write([]byte(`{"return": {}}` + "\n" +
+					`{"event": "GUEST_PANICKED", "data": {"action": "pause"}, "timestamp": {"seconds": 1, "microseconds": 2}}` + "\n"))
+				continue
+			}
+
+			_, _ = conn.Write([]byte(`{"return": {"running": true, "singlestep": false, "status": "running"}}` + "\n"))
+		}
+	}()
+}
+
+// newTestService returns a task service whose tasks are fake containers which
+// exit with the provided exit code once terminated.  The machine of each task
+// is controlled through the provided QMP socket, if set.
+func newTestService(t *testing.T, exitCode int, socket string) (*service, map[string]*fakeContainer) {
+	fakes := map[string]*fakeContainer{}
+
+	s := &service{
+		context:    context.Background(),
+		events:     make(chan interface{}, 128),
+		containers: make(map[string]*container),
+		newContainer: func(_ context.Context, r *taskAPI.CreateTaskRequest) (*container, error) {
+			f, err := newFakeContainer(t, exitCode)
+			if err != nil {
+				return nil, err
+			}
+
+			fakes[r.ID] = f
+
+			return &container{
+				id:      r.ID,
+				bundle:  r.Bundle,
+				socket:  socket,
+				qemu:    socket != "",
+				c:       f,
+				process: f,
+				pid:     f.cmd.Process.Pid,
+				exited:  make(chan struct{}),
+			}, nil
+		},
+	}
+
+	return s, fakes
+}
+
+// expectStatus fails the test if the task with the provided ID does not have
+// the provided status.
+func expectStatus(t *testing.T, s *service, id string, expected task.Status) *taskAPI.StateResponse {
+	t.Helper()
+
+	res, err := s.State(context.Background(), &taskAPI.StateRequest{ID: id})
+	if err != nil {
+		t.Fatalf("getting state: %v", err)
+	}
+
+	if res.Status != expected {
+		t.Errorf("expected status %s, got %s", expected, res.Status)
+	}
+
+	return res
+}
+
+// expectEvents fails the test if the provided service has not sent events of
+// the provided types, in order.
+func expectEvents(t *testing.T, s *service, expected ...string) []interface{} {
+	t.Helper()
+
+	var events []interface{}
+	for _, topic := range expected {
+		select {
+		case evt := <-s.events:
+			if got := fmt.Sprintf("%T", evt); got != topic {
+				t.Errorf("expected event %s, got %s", topic, got)
+			}
+
+			events = append(events, evt)
+		case <-time.After(10 * time.Second):
+			t.Fatalf("expected event %s, got none", topic)
+		}
+	}
+
+	return events
+}
+
+func TestServiceLifecycle(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		exitCode   int
+		signal     syscall.Signal
+		qemu       bool
+		exitStatus uint32
+	}{
+		{
+			name:       "exit",
+			exitCode:   3,
+			signal:     syscall.SIGTERM,
+			exitStatus: 3,
+		},
+		{
+			name:       "killed",
+			signal:     syscall.SIGKILL,
+			exitStatus: 128 + uint32(syscall.SIGKILL),
+		},
+		{
+			// QEMU exits successfully when the guest panics.
+			name:       "guest panic",
+			signal:     syscall.SIGTERM,
+			qemu:       true,
+			exitStatus: 1,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			socket := ""
+			if test.qemu {
+				socket = filepath.Join(t.TempDir(), "qmp.sock")
+				serveFakeQMP(t, socket)
+			}
+
+			s, fakes := newTestService(t, test.exitCode, socket)
+
+			if _, err := s.Create(ctx, &taskAPI.CreateTaskRequest{ID: "task", Bundle: "/bundle"}); err != nil {
+				t.Fatalf("creating task: %v", err)
+			}
+
+			if _, err := s.Create(ctx, &taskAPI.CreateTaskRequest{ID: "task"}); !errdefs.IsAlreadyExists(errdefs.FromGRPC(err)) {
+				t.Errorf("expected creating the task twice to fail, got %v", err)
+			}
+
+			expectStatus(t, s, "task", task.Status_CREATED)
+
+			if _, err := s.Start(ctx, &taskAPI.StartRequest{ID: "task"}); err != nil {
+				t.Fatalf("starting task: %v", err)
+			}
+
+			expectStatus(t, s, "task", task.Status_RUNNING)
+
+			if test.qemu {
+				c, err := s.getContainer("task")
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				// The task only exits once its machine has reported the panic.
+				for deadline := time.Now().Add(10 * time.Second); ; {
+					c.mu.Lock()
+					panicked := c.panicked
+					c.mu.Unlock()
+
+					if panicked {
+						break
+					} else if time.Now().After(deadline) {
+						t.Fatal("expected the panic of the guest to be reported")
+					}
+
+					time.Sleep(10 * time.Millisecond)
+				}
+			}
+
+			// A running task cannot be deleted.
+			if _, err := s.Delete(ctx, &taskAPI.DeleteRequest{ID: "task"}); !errdefs.IsFailedPrecondition(errdefs.FromGRPC(err)) {
+				t.Errorf("expected deleting a running task to fail, got %v", err)
+			}
+
+			if _, err := s.Kill(ctx, &taskAPI.KillRequest{ID: "task", Signal: uint32(test.signal)}); err != nil {
+				t.Fatalf("killing task: %v", err)
+			}
+
+			res, err := s.Wait(ctx, &taskAPI.WaitRequest{ID: "task"})
+			if err != nil {
+				t.Fatalf("waiting for task: %v", err)
+			}
+
+			if res.ExitStatus != test.exitStatus {
+				t.Errorf("expected exit status %d, got %d", test.exitStatus, res.ExitStatus)
+			}
+
+			if state := expectStatus(t, s, "task", task.Status_STOPPED); state.ExitStatus != test.exitStatus {
+				t.Errorf("expected exit status %d in state, got %d", test.exitStatus, state.ExitStatus)
+			}
+
+			deleted, err := s.Delete(ctx, &taskAPI.DeleteRequest{ID: "task"})
+			if err != nil {
+				t.Fatalf("deleting task: %v", err)
+			}
+
+			if deleted.ExitStatus != test.exitStatus {
+				t.Errorf("expected exit status %d on deletion, got %d", test.exitStatus, deleted.ExitStatus)
+			}
+
+			if !fakes["task"].isDestroyed() {
+				t.Error("expected the container to be destroyed")
+			}
+
+			if _, err := s.State(ctx, &taskAPI.StateRequest{ID: "task"}); !errdefs.IsNotFound(errdefs.FromGRPC(err)) {
+				t.Errorf("expected the deleted task not to be found, got %v", err)
+			}
+
+			events := expectEvents(t, s,
+				"*events.TaskCreate",
+				"*events.TaskStart",
+				"*events.TaskExit",
+				"*events.TaskDelete",
+			)
+
+			if exit, ok := events[2].(*eventstypes.TaskExit); ok && exit.ExitStatus != test.exitStatus {
+				t.Errorf("expected exit status %d in exit event, got %d", test.exitStatus, exit.ExitStatus)
+			}
+		})
+	}
+}
+
+func TestServiceDeleteCreated(t *testing.T) {
+	ctx := context.Background()
+
+	s, fakes := newTestService(t, 0, "")
+
+	if _, err := s.Create(ctx, &taskAPI.CreateTaskRequest{ID: "task"}); err != nil {
+		t.Fatalf("creating task: %v", err)
+	}
+
+	// The init process of a task which has never been started is killed.
+	res, err := s.Delete(ctx, &taskAPI.DeleteRequest{ID: "task"})
+	if err != nil {
+		t.Fatalf("deleting task: %v", err)
+	}
+
+	if expected := 128 + uint32(syscall.SIGKILL); res.ExitStatus != expected {
+		t.Errorf("expected exit status %d, got %d", expected, res.ExitStatus)
+	}
+
+	if !fakes["task"].isDestroyed() {
+		t.Error("expected the container to be destroyed")
+	}
+
+	if _, err := s.Start(ctx, &taskAPI.StartRequest{ID: "task"}); !errdefs.IsNotFound(errdefs.FromGRPC(err)) {
+		t.Errorf("expected the deleted task not to be found, got %v", err)
+	}
+
+	expectEvents(t, s,
+		"*events.TaskCreate",
+		"*events.TaskExit",
+		"*events.TaskDelete",
+	)
+}
@@ -0,0 +1,69 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package task
+
+import (
+	"math"
+
+	v2 "github.com/containerd/cgroups/v3/cgroup2/stats"
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+)
+
+// metrics returns the metrics of a task from the statistics of the cgroup of
+// its container.  The metrics are reported in the cgroup v2 format whichever
+// version of cgroups is used by the host, since a task has a single process.
+func metrics(s *cgroups.Stats) *v2.Metrics {
+	m := &v2.Metrics{
+		Pids: &v2.PidsStat{
+			Current: s.PidsStats.Current,
+			Limit:   s.PidsStats.Limit,
+		},
+		CPU: &v2.CPUStat{
+			UsageUsec:     s.CpuStats.CpuUsage.TotalUsage / 1000,
+			UserUsec:      s.CpuStats.CpuUsage.UsageInUsermode / 1000,
+			SystemUsec:    s.CpuStats.CpuUsage.UsageInKernelmode / 1000,
+			NrPeriods:     s.CpuStats.ThrottlingData.Periods,
+			NrThrottled:   s.CpuStats.ThrottlingData.ThrottledPeriods,
+			ThrottledUsec: s.CpuStats.ThrottlingData.ThrottledTime / 1000,
+		},
+		Memory: &v2.MemoryStat{
+			Usage:        s.MemoryStats.Usage.Usage,
+			UsageLimit:   s.MemoryStats.Usage.Limit,
+			MaxUsage:     s.MemoryStats.Usage.MaxUsage,
+			SwapUsage:    s.MemoryStats.SwapUsage.Usage,
+			SwapLimit:    s.MemoryStats.SwapUsage.Limit,
+			SwapMaxUsage: s.MemoryStats.SwapUsage.MaxUsage,
+		},
+	}
+
+	// The names of the statistics differ between cgroup v1 and v2.
+	for _, name := range []string{"anon", "rss", "total_rss"} {
+		if v, ok := s.MemoryStats.Stats[name]; ok {
+			m.Memory.Anon = v
+			break
+		}
+	}
+	for _, name := range []string{"file", "cache", "total_cache"} {
+		if v, ok := s.MemoryStats.Stats[name]; ok {
+			m.Memory.File = v
+			break
+		}
+	}
+	for _, name := range []string{"inactive_file", "total_inactive_file"} {
+		if v, ok := s.MemoryStats.Stats[name]; ok {
+			m.Memory.InactiveFile = v
+			break
+		}
+	}
+
+	return m
+}
+
+// hasMemoryLimit returns whether the provided memory limit of a cgroup is set.
+// The limit of an unlimited cgroup is either unknown or the maximum value of
+// its page counter, which is slightly lower than math.MaxInt64 with cgroup v1.
+func hasMemoryLimit(limit uint64) bool {
+	return limit != 0 && limit < math.MaxInt64/2
+}
@@ -0,0 +1,23 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2014 Docker, Inc.
+// Copyright 2023 Unikraft GmbH and The KraftKit Authors
+
+package configs
+
+import (
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// The cgroup configuration is shared with upstream libcontainer, so that the
+// cgroup of the container can be handled by its cgroup managers.
+type (
+	Cgroup       = configs.Cgroup
+	Resources    = configs.Resources
+	FreezerState = configs.FreezerState
+)
+
+const (
+	Undefined = configs.Undefined
+	Frozen    = configs.Frozen
+	Thawed    = configs.Thawed
+)
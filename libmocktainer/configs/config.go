@@ -35,6 +35,9 @@ type Config struct {
 	// Routes can be specified to create entries in the route table as the container is started
 	Routes []*Route `json:"routes"`
 
+	// Cgroups specifies specific cgroup settings for the various subsystems
+	Cgroups *Cgroup `json:"cgroups"`
+
 	// AppArmorProfile specifies the profile to apply to the process running in the container and is
 	// change at the time the process is execed
 	AppArmorProfile string `json:"apparmor_profile,omitempty"`
@@ -92,6 +95,19 @@ const (
 	Poststop HookName = "poststop"
 )
 
+// KnownHookNames returns the known hook names.
+// Used by `runu features`.
+func KnownHookNames() []string {
+	return []string{
+		string(Prestart), // deprecated
+		string(CreateRuntime),
+		string(CreateContainer),
+		string(StartContainer),
+		string(Poststart),
+		string(Poststop),
+	}
+}
+
 func (hooks HookList) RunHooks(state *specs.State) error {
 	for i, h := range hooks {
 		if err := h.Run(state); err != nil {
@@ -18,6 +18,8 @@ const (
 	Created Status = iota
 	// Running is the status that denotes the container exists and is running.
 	Running
+	// Paused is the status that denotes the container exists, but all its processes are paused.
+	Paused
 	// Stopped is the status that denotes the container does not have a created or running process.
 	Stopped
 )
@@ -28,6 +30,8 @@ func (s Status) String() string {
 		return "created"
 	case Running:
 		return "running"
+	case Paused:
+		return "paused"
 	case Stopped:
 		return "stopped"
 	default:
@@ -22,6 +22,7 @@ import (
 	"github.com/vishvananda/netlink/nl"
 	"golang.org/x/sys/unix"
 
+	"github.com/opencontainers/runc/libcontainer/cgroups"
 	"github.com/opencontainers/runc/libcontainer/system"
 	"github.com/opencontainers/runc/libcontainer/utils"
 
@@ -35,6 +36,7 @@ type Container struct {
 	id                   string
 	root                 string
 	config               *configs.Config
+	cgroupManager        cgroups.Manager
 	initProcess          parentProcess
 	initProcessStartTime uint64
 	m                    sync.Mutex
@@ -49,6 +51,14 @@ type State struct {
 
 	// Platform specific fields below here
 
+	// Paths to all the container's cgroups, as returned by (*cgroups.Manager).GetPaths
+	//
+	// For cgroup v1, a key is cgroup subsystem name, and the value is the path
+	// to the cgroup for this subsystem.
+	//
+	// For cgroup v2 unified hierarchy, a key is "", and the value is the unified path.
+	CgroupPaths map[string]string `json:"cgroup_paths"`
+
 	// NamespacePaths are filepaths to the container's namespaces. Key is the namespace type
 	// with the value as the path.
 	NamespacePaths map[configs.NamespaceType]string `json:"namespace_paths"`
@@ -78,6 +88,49 @@ func (c *Container) State() (*State, error) {
 	return c.currentState()
 }
 
+// Stats returns statistics for the container.
+func (c *Container) Stats() (*Stats, error) {
+	var (
+		err   error
+		stats = &Stats{}
+	)
+	if stats.CgroupStats, err = c.cgroupManager.GetStats(); err != nil {
+		return stats, fmt.Errorf("unable to get container cgroup stats: %w", err)
+	}
+	return stats, nil
+}
+
+// OOMKillCount returns the number of processes of the container which have
+// been killed by the OOM killer.
+func (c *Container) OOMKillCount() (uint64, error) {
+	return c.cgroupManager.OOMKillCount()
+}
+
+// Set resources of container as configured. Can be used to change resources
+// when containers are running.
+func (c *Container) Set(config configs.Config) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	status, err := c.currentStatus()
+	if err != nil {
+		return err
+	}
+	if status == Stopped {
+		return ErrNotRunning
+	}
+	if err := c.cgroupManager.Set(config.Cgroups.Resources); err != nil {
+		// Set configs back
+		if err2 := c.cgroupManager.Set(c.config.Cgroups.Resources); err2 != nil {
+			logrus.Warnf("Setting back cgroup configs failed due to error: %v, your state.json and actual configs might be inconsistent.", err2)
+		}
+		return err
+	}
+	// After config setting succeed, update config and states
+	c.config = &config
+	_, err = c.updateState(nil)
+	return err
+}
+
 // Start starts a process inside the container. Returns error if process fails
 // to start. You can track process lifecycle with passed Process structure.
 func (c *Container) Start(process *Process) error {
@@ -225,7 +278,7 @@ func (c *Container) Signal(s os.Signal) error {
 	}
 	// To avoid a PID reuse attack, don't kill non-running container.
 	switch status {
-	case Running, Created:
+	case Running, Created, Paused:
 	default:
 		return ErrNotRunning
 	}
@@ -234,6 +287,12 @@ func (c *Container) Signal(s os.Signal) error {
 	if err != nil {
 		return fmt.Errorf("unable to signal init: %w", err)
 	}
+	// For cgroup v1, killing a process in a frozen cgroup
+	// does nothing until it's thawed. Only thaw the cgroup
+	// for SIGKILL.
+	if s, ok := s.(unix.Signal); ok && s == unix.SIGKILL {
+		_ = c.cgroupManager.Freeze(configs.Thawed)
+	}
 	return nil
 }
 
@@ -349,6 +408,7 @@ func (c *Container) newInitProcess(p *Process, cmd *exec.Cmd, messageSockPair, l
 		cmd:             cmd,
 		messageSockPair: messageSockPair,
 		logFilePair:     logFilePair,
+		manager:         c.cgroupManager,
 		config:          c.newInitConfig(p),
 		container:       c,
 		process:         p,
@@ -390,6 +450,49 @@ func (c *Container) Destroy() error {
 	return c.state.destroy()
 }
 
+// Pause pauses the container, if its state is RUNNING or CREATED, changing
+// its state to PAUSED. If the state is already PAUSED, does nothing.
+func (c *Container) Pause() error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	status, err := c.currentStatus()
+	if err != nil {
+		return err
+	}
+	switch status {
+	case Running, Created:
+		if err := c.cgroupManager.Freeze(configs.Frozen); err != nil {
+			return err
+		}
+		return c.state.transition(&pausedState{
+			c: c,
+		})
+	}
+	return ErrNotRunning
+}
+
+// Resume resumes the execution of any user processes in the
+// container before setting the container state to RUNNING.
+// This is only performed if the current state is PAUSED.
+// If the Container state is RUNNING, does nothing.
+func (c *Container) Resume() error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	status, err := c.currentStatus()
+	if err != nil {
+		return err
+	}
+	if status != Paused {
+		return ErrNotPaused
+	}
+	if err := c.cgroupManager.Freeze(configs.Thawed); err != nil {
+		return err
+	}
+	return c.state.transition(&runningState{
+		c: c,
+	})
+}
+
 func (c *Container) updateState(process parentProcess) (*State, error) {
 	if process != nil {
 		c.initProcess = process
@@ -443,6 +546,13 @@ func (c *Container) currentStatus() (Status, error) {
 // out of process we need to verify the container's status based on runtime
 // information and not rely on our in process info.
 func (c *Container) refreshState() error {
+	paused, err := c.isPaused()
+	if err != nil {
+		return err
+	}
+	if paused {
+		return c.state.transition(&pausedState{c: c})
+	}
 	t := c.runType()
 	switch t {
 	case Created:
@@ -473,6 +583,14 @@ func (c *Container) runType() Status {
 	return Running
 }
 
+func (c *Container) isPaused() (bool, error) {
+	state, err := c.cgroupManager.GetFreezerState()
+	if err != nil {
+		return false, err
+	}
+	return state == configs.Frozen, nil
+}
+
 func (c *Container) currentState() (*State, error) {
 	var (
 		startTime uint64
@@ -491,6 +609,7 @@ func (c *Container) currentState() (*State, error) {
 			InitProcessStartTime: startTime,
 			Created:              c.created,
 		},
+		CgroupPaths:    c.cgroupManager.GetPaths(),
 		NamespacePaths: make(map[configs.NamespaceType]string),
 	}
 	if pid > 0 {
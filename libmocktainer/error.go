@@ -12,4 +12,6 @@ var (
 	ErrNotExist   = errors.New("container does not exist")
 	ErrRunning    = errors.New("container still running")
 	ErrNotRunning = errors.New("container not running")
+	ErrPaused     = errors.New("container paused")
+	ErrNotPaused  = errors.New("container not paused")
 )
@@ -7,10 +7,13 @@ package libmocktainer
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 
 	securejoin "github.com/cyphar/filepath-securejoin"
+	"golang.org/x/sys/unix"
 
+	"github.com/opencontainers/runc/libcontainer/cgroups/manager"
 	"github.com/opencontainers/runc/libcontainer/utils"
 
 	"kraftkit.sh/libmocktainer/configs"
@@ -55,14 +58,47 @@ func Create(root, id string, config *configs.Config) (*Container, error) {
 		return nil, err
 	}
 
+	cm, err := manager.New(config.Cgroups)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check that cgroup does not exist or empty (no processes).
+	// Note for cgroup v1 this check is not thorough, as there are multiple
+	// separate hierarchies, while both Exists() and GetAllPids() only use
+	// one for "devices" controller (assuming others are the same, which is
+	// probably true in almost all scenarios). Checking all the hierarchies
+	// would be too expensive.
+	if cm.Exists() {
+		pids, err := cm.GetAllPids()
+		// Reading PIDs can race with cgroups removal, so ignore ENOENT and ENODEV.
+		if err != nil && !errors.Is(err, os.ErrNotExist) && !errors.Is(err, unix.ENODEV) {
+			return nil, fmt.Errorf("unable to get cgroup PIDs: %w", err)
+		}
+		if len(pids) != 0 {
+			return nil, fmt.Errorf("container's cgroup is not empty: %d process(es) found", len(pids))
+		}
+	}
+
+	// Check that cgroup is not frozen. Do not use Exists() here
+	// since in cgroup v1 it only checks "devices" controller.
+	st, err := cm.GetFreezerState()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get cgroup freezer state: %w", err)
+	}
+	if st == configs.Frozen {
+		return nil, errors.New("container's cgroup unexpectedly frozen")
+	}
+
 	// Parent directory is already created above, so Mkdir is enough.
 	if err := os.Mkdir(containerRoot, 0o711); err != nil {
 		return nil, err
 	}
 	c := &Container{
-		id:     id,
-		root:   containerRoot,
-		config: config,
+		id:            id,
+		root:          containerRoot,
+		config:        config,
+		cgroupManager: cm,
 	}
 	c.state = &stoppedState{c: c}
 	return c, nil
@@ -87,6 +123,10 @@ func Load(root, id string) (*Container, error) {
 	if err != nil {
 		return nil, err
 	}
+	cm, err := manager.NewWithPaths(state.Config.Cgroups, state.CgroupPaths)
+	if err != nil {
+		return nil, err
+	}
 	r := &nonChildProcess{
 		processPid:       state.InitProcessPid,
 		processStartTime: state.InitProcessStartTime,
@@ -96,6 +136,7 @@ func Load(root, id string) (*Container, error) {
 		initProcessStartTime: state.InitProcessStartTime,
 		id:                   id,
 		config:               &state.Config,
+		cgroupManager:        cm,
 		root:                 containerRoot,
 		created:              state.Created,
 	}
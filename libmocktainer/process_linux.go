@@ -15,6 +15,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/opencontainers/runc/libcontainer/cgroups"
 	"github.com/opencontainers/runc/libcontainer/logs"
 	"github.com/opencontainers/runc/libcontainer/system"
 	"github.com/opencontainers/runc/libcontainer/utils"
@@ -56,6 +57,7 @@ type initProcess struct {
 	messageSockPair filePair
 	logFilePair     filePair
 	config          *initConfig
+	manager         cgroups.Manager
 	container       *Container
 	fds             []string
 	process         *Process
@@ -132,9 +134,17 @@ func (p *initProcess) start() (retErr error) {
 			if err := ignoreTerminateErrors(p.terminate()); err != nil {
 				logrus.WithError(err).Warn("unable to terminate initProcess")
 			}
+
+			_ = p.manager.Destroy()
 		}
 	}()
 
+	// Do this before syncing with child so that no children can escape the
+	// cgroup.
+	if err := p.manager.Apply(p.pid()); err != nil {
+		return fmt.Errorf("unable to apply cgroup configuration: %w", err)
+	}
+
 	if _, err := io.Copy(p.messageSockPair.parent, p.bootstrapData); err != nil {
 		return fmt.Errorf("can't copy bootstrap data to pipe: %w", err)
 	}
@@ -178,6 +188,10 @@ func (p *initProcess) start() (retErr error) {
 		case procReady:
 			seenProcReady = true
 
+			if err := p.manager.Set(p.config.Config.Cgroups.Resources); err != nil {
+				return fmt.Errorf("error setting cgroup config for ready process: %w", err)
+			}
+
 			// generate a timestamp indicating when the container was started
 			p.container.created = time.Now().UTC()
 			p.container.state = &createdState{
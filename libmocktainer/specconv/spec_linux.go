@@ -15,6 +15,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/opencontainers/runc/libcontainer/specconv"
 	"github.com/opencontainers/runtime-spec/specs-go"
 
 	"kraftkit.sh/libmocktainer/configs"
@@ -46,16 +47,25 @@ func KnownNamespaces() []string {
 }
 
 type CreateOpts struct {
-	Spec *specs.Spec
+	CgroupName       string
+	UseSystemdCgroup bool
+	Spec             *specs.Spec
+	// Bundle is the path of the bundle of the container, which defaults to the
+	// current working directory.
+	Bundle string
 }
 
 // CreateLibcontainerConfig creates a new libcontainer configuration from a
 // given specification and a cgroup name
 func CreateLibcontainerConfig(opts *CreateOpts) (*configs.Config, error) {
-	// runc's cwd will always be the bundle path
-	rcwd, err := os.Getwd()
-	if err != nil {
-		return nil, err
+	// runc's cwd will always be the bundle path, whereas a shim which manages
+	// several containers provides it
+	rcwd := opts.Bundle
+	if rcwd == "" {
+		var err error
+		if rcwd, err = os.Getwd(); err != nil {
+			return nil, err
+		}
 	}
 	cwd, err := filepath.Abs(rcwd)
 	if err != nil {
@@ -101,11 +111,38 @@ func CreateLibcontainerConfig(opts *CreateOpts) (*configs.Config, error) {
 		}
 	}
 
+	c, err := CreateCgroupConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	config.Cgroups = c
+
 	createHooks(spec, config)
 	config.Version = specs.Version
 	return config, nil
 }
 
+// CreateCgroupConfig creates the configuration of the cgroup of the container
+// from the given specification, using the conversion of upstream libcontainer.
+func CreateCgroupConfig(opts *CreateOpts) (*configs.Cgroup, error) {
+	c, err := specconv.CreateCgroupConfig(&specconv.CreateOpts{
+		CgroupName:       opts.CgroupName,
+		UseSystemdCgroup: opts.UseSystemdCgroup,
+		Spec:             opts.Spec,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// The device rules of the specification are meant for the processes of a
+	// container, whereas the process of a unikernel is a virtual machine
+	// monitor which requires access to devices such as /dev/kvm and the tap
+	// devices of its network interfaces.
+	c.Resources.SkipDevices = true
+
+	return c, nil
+}
+
 func createHooks(rspec *specs.Spec, config *configs.Config) {
 	config.Hooks = configs.Hooks{}
 	if rspec.Hooks != nil {
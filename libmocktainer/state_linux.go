@@ -12,6 +12,7 @@ import (
 	"golang.org/x/sys/unix"
 
 	"kraftkit.sh/libmocktainer/configs"
+	"kraftkit.sh/libmocktainer/unikraft"
 )
 
 func newStateTransitionError(from, to containerState) error {
@@ -39,10 +40,19 @@ type containerState interface {
 }
 
 func destroy(c *Container) error {
-	var err error
+	err := c.cgroupManager.Destroy()
 	if rerr := os.RemoveAll(c.root); err == nil {
 		err = rerr
 	}
+
+	// -- BEGIN Unikraft
+
+	if rerr := unikraft.RemoveQemuControlSocket(unikraft.QemuControlSocketFromLabels(c.config.Labels)); err == nil {
+		err = rerr
+	}
+
+	// -- END Unikraft
+
 	c.initProcess = nil
 	if herr := runPoststopHooks(c); err == nil {
 		err = herr
@@ -111,6 +121,9 @@ func (r *runningState) transition(s containerState) error {
 		}
 		r.c.state = s
 		return nil
+	case *pausedState:
+		r.c.state = s
+		return nil
 	case *runningState:
 		return nil
 	}
@@ -124,6 +137,38 @@ func (r *runningState) destroy() error {
 	return destroy(r.c)
 }
 
+// pausedState represents a container that is currently pause.  It cannot be destroyed in a
+// paused state and must transition back to running first.
+type pausedState struct {
+	c *Container
+}
+
+func (p *pausedState) status() Status {
+	return Paused
+}
+
+func (p *pausedState) transition(s containerState) error {
+	switch s.(type) {
+	case *runningState, *stoppedState:
+		p.c.state = s
+		return nil
+	case *pausedState:
+		return nil
+	}
+	return newStateTransitionError(p, s)
+}
+
+func (p *pausedState) destroy() error {
+	t := p.c.runType()
+	if t != Running && t != Created {
+		if err := p.c.cgroupManager.Freeze(configs.Thawed); err != nil {
+			return err
+		}
+		return destroy(p.c)
+	}
+	return ErrPaused
+}
+
 type createdState struct {
 	c *Container
 }
@@ -134,7 +179,7 @@ func (i *createdState) status() Status {
 
 func (i *createdState) transition(s containerState) error {
 	switch s.(type) {
-	case *runningState, *stoppedState:
+	case *runningState, *pausedState, *stoppedState:
 		i.c.state = s
 		return nil
 	case *createdState:
@@ -0,0 +1,12 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2014 Docker, Inc.
+// Copyright 2023 Unikraft GmbH and The KraftKit Authors
+
+package libmocktainer
+
+import "github.com/opencontainers/runc/libcontainer/cgroups"
+
+// Stats holds the resource usage statistics of a container.
+type Stats struct {
+	CgroupStats *cgroups.Stats
+}
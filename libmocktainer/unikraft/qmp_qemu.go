@@ -0,0 +1,204 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+package unikraft
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"kraftkit.sh/machine/qemu/qmp"
+	qmpapi "kraftkit.sh/machine/qemu/qmp/v7alpha2"
+)
+
+// QemuControlSocket is the name of the QMP socket which QEMU exposes.
+const QemuControlSocket = "qemu_control.sock"
+
+// AnnotationQemuControlSocket is the annotation of a container which records
+// the path of the QMP socket of its QEMU machine.  The socket is kept in a
+// directory of its own rather than in the state directory of the container,
+// since the path of a UNIX socket is limited to 108 bytes whereas the path of
+// the state directory already contains the 64-character ID of the container.
+const AnnotationQemuControlSocket = "org.unikraft.runu.qmp-socket"
+
+// qemuControlDirPrefix is the prefix of the name of the directory of the QMP
+// socket of a container.
+const qemuControlDirPrefix = "runu-qmp-"
+
+// qemuEventsBuffer is the number of QMP events which are buffered by a
+// QemuMonitor until they are received.
+const qemuEventsBuffer = 16
+
+// QemuMonitor is a client of the QMP socket of a QEMU machine which can be
+// kept open for the lifetime of the machine.  The events emitted by the
+// machine are delivered separately from the responses to commands.
+type QemuMonitor struct {
+	conn   *qmp.EventConn[qmpapi.EventType]
+	client *qmpapi.QEMUMachineProtocolClient
+}
+
+// NewQemuControlSocket creates a directory of its own for the QMP socket of a
+// container and returns the path of the socket.
+func NewQemuControlSocket() (string, error) {
+	dir, err := os.MkdirTemp("", qemuControlDirPrefix)
+	if err != nil {
+		return "", fmt.Errorf("creating QMP socket directory: %w", err)
+	}
+
+	return filepath.Join(dir, QemuControlSocket), nil
+}
+
+// QemuControlSocketFromLabels returns the path of the QMP socket which is
+// recorded in the given labels of a container, in the format key=value, or an
+// empty string if there is none.
+func QemuControlSocketFromLabels(labels []string) string {
+	for _, label := range labels {
+		if k, v, _ := strings.Cut(label, "="); k == AnnotationQemuControlSocket {
+			return v
+		}
+	}
+
+	return ""
+}
+
+// RemoveQemuControlSocket removes the directory of the given QMP socket, as
+// created by NewQemuControlSocket.  It does nothing if the path is empty.
+func RemoveQemuControlSocket(socket string) error {
+	if socket == "" {
+		return nil
+	}
+
+	dir := filepath.Dir(socket)
+	if filepath.Base(socket) != QemuControlSocket || !strings.HasPrefix(filepath.Base(dir), qemuControlDirPrefix) {
+		return fmt.Errorf("not a QMP socket directory: %s", dir)
+	}
+
+	return os.RemoveAll(dir)
+}
+
+// NewQemuMonitor connects to the given QMP socket and negotiates its
+// capabilities.  It returns nil if the path is empty or the socket does not
+// exist, for instance because the machine is run with another virtual machine
+// monitor.
+func NewQemuMonitor(socket string) (*QemuMonitor, error) {
+	if socket == "" {
+		return nil, nil
+	}
+
+	if _, err := os.Stat(socket); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	nconn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to QMP socket: %w", err)
+	}
+
+	conn := qmp.NewEventConn(nconn, qmpapi.EventTypes(), qemuEventsBuffer)
+	client := qmpapi.NewQEMUMachineProtocolClient(conn)
+
+	greeting, err := client.Greeting()
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("reading QMP greeting: %w", err)
+	}
+
+	if _, err = client.Capabilities(qmpapi.CapabilitiesRequest{
+		Arguments: qmpapi.CapabilitiesRequestArguments{
+			Enable: greeting.Qmp.Capabilities,
+		},
+	}); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("negotiating QMP capabilities: %w", err)
+	}
+
+	return &QemuMonitor{
+		conn:   conn,
+		client: client,
+	}, nil
+}
+
+// Stop stops the virtual CPUs of the machine.
+func (m *QemuMonitor) Stop() error {
+	if _, err := m.client.Stop(qmpapi.StopRequest{}); err != nil {
+		return fmt.Errorf("stopping machine: %w", err)
+	}
+
+	return nil
+}
+
+// Cont resumes the virtual CPUs of the machine.
+func (m *QemuMonitor) Cont() error {
+	if _, err := m.client.Cont(qmpapi.ContRequest{}); err != nil {
+		return fmt.Errorf("resuming machine: %w", err)
+	}
+
+	return nil
+}
+
+// Status returns the run status of the machine.
+func (m *QemuMonitor) Status() (*qmpapi.StatusInfo, error) {
+	res, err := m.client.QueryStatus(qmpapi.QueryStatusRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("querying machine status: %w", err)
+	}
+
+	return &res.Return, nil
+}
+
+// MemorySize returns the amount of memory of the machine in bytes, including
+// the memory which is hotplugged.
+func (m *QemuMonitor) MemorySize() (uint64, error) {
+	res, err := m.client.QueryMemorySizeSummary(qmpapi.QueryMemorySizeSummaryRequest{})
+	if err != nil {
+		return 0, fmt.Errorf("querying machine memory size: %w", err)
+	}
+
+	return res.Return.BaseMemory + res.Return.PluggedMemory, nil
+}
+
+// Events returns the channel through which the events emitted by the machine
+// are delivered.  The channel is closed when the connection to the machine is.
+func (m *QemuMonitor) Events() <-chan *qmp.QMPEvent[qmpapi.EventType] {
+	return m.conn.Events()
+}
+
+// Close closes the connection to the QMP socket of the machine.
+func (m *QemuMonitor) Close() error {
+	return m.client.Close()
+}
+
+// StopQemu stops the virtual CPUs of the QEMU machine which exposes the given
+// QMP socket.  It does nothing if the path is empty or the socket does not
+// exist, for instance because the machine is run with another virtual machine
+// monitor.
+func StopQemu(socket string) error {
+	m, err := NewQemuMonitor(socket)
+	if err != nil || m == nil {
+		return err
+	}
+
+	defer m.Close()
+
+	return m.Stop()
+}
+
+// ContQemu resumes the virtual CPUs of the QEMU machine which exposes the given
+// QMP socket.  It does nothing if the path is empty or the socket does not
+// exist.
+func ContQemu(socket string) error {
+	m, err := NewQemuMonitor(socket)
+	if err != nil || m == nil {
+		return err
+	}
+
+	defer m.Close()
+
+	return m.Cont()
+}
@@ -4,6 +4,8 @@
 // You may not use this file except in compliance with the License.
 package firecracker
 
+import "github.com/firecracker-microvm/firecracker-go-sdk/client/models"
+
 // FirecrackerConfig is a subset of the Firecracker's Go SDK structure of the
 // same format.  We use this subset because these are the only attribute
 // necessary and additionally, gob cannot register some of the embedded types.
@@ -16,3 +18,14 @@ type FirecrackerConfig struct {
 	// un/marshalling of the resources (and all structures).
 	Memory string `json:"memory,omitempty"`
 }
+
+// VMConfig is the microVM configuration which Firecracker reads from the file
+// provided via --config-file, which allows starting a microVM without first
+// configuring it through the API socket.
+type VMConfig struct {
+	BootSource        models.BootSource           `json:"boot-source"`
+	Drives            []models.Drive              `json:"drives"`
+	MachineConfig     models.MachineConfiguration `json:"machine-config"`
+	NetworkInterfaces []models.NetworkInterface   `json:"network-interfaces,omitempty"`
+	Logger            *models.Logger              `json:"logger,omitempty"`
+}
@@ -123,6 +123,17 @@ func (service *machineV1alpha1Service) Create(ctx context.Context, machine *mach
 				"",
 				"",
 			).String())
+
+		case "ramfs":
+			fstab = append(fstab, vfscore.NewFstabEntry(
+				"none",
+				vol.Spec.Destination,
+				vol.Spec.Driver,
+				"",
+				"",
+				"mkmp",
+			).String())
+
 		default:
 			return machine, fmt.Errorf("unsupported Firecracker volume driver: %v", vol.Spec.Driver)
 		}
@@ -424,6 +435,20 @@ func (service *machineV1alpha1Service) Start(ctx context.Context, machine *machi
 	}
 
 	client := firecracker.NewClient(fccfg.SocketPath, logrus.NewEntry(log.G(ctx)), false)
+
+	// A paused machine has already booted and is only resumed.
+	if machine.Status.State == machinev1alpha1.MachineStatePaused {
+		if _, err := client.PatchVM(ctx, &models.VM{
+			State: firecracker.String(models.VMStateResumed),
+		}); err != nil {
+			return machine, fmt.Errorf("could not resume firecracker instance: %w", err)
+		}
+
+		machine.Status.State = machinev1alpha1.MachineStateRunning
+
+		return machine, nil
+	}
+
 	action := models.InstanceActionInfoActionTypeInstanceStart
 	info := models.InstanceActionInfo{
 		ActionType: &action,
@@ -441,7 +466,22 @@ func (service *machineV1alpha1Service) Start(ctx context.Context, machine *machi
 
 // Pause implements kraftkit.sh/api/machine/v1alpha1.MachineService
 func (service *machineV1alpha1Service) Pause(ctx context.Context, machine *machinev1alpha1.Machine) (*machinev1alpha1.Machine, error) {
-	panic("not implemented: kraftkit.sh/machine/firecracker.machineV1alpha1Service.Pause")
+	fccfg, err := getFirecrackerConfigFromPlatformConfig(machine.Status.PlatformConfig)
+	if err != nil {
+		return machine, err
+	}
+
+	client := firecracker.NewClient(fccfg.SocketPath, logrus.NewEntry(log.G(ctx)), false)
+
+	if _, err := client.PatchVM(ctx, &models.VM{
+		State: firecracker.String(models.VMStatePaused),
+	}); err != nil {
+		return machine, fmt.Errorf("could not pause firecracker instance: %w", err)
+	}
+
+	machine.Status.State = machinev1alpha1.MachineStatePaused
+
+	return machine, nil
 }
 
 // Logs implements kraftkit.sh/api/machine/v1alpha1.MachineService
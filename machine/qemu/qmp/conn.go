@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+package qmp
+
+import (
+	"bufio"
+	"errors"
+	"io"
+
+	"kraftkit.sh/utils"
+)
+
+// EventConn is a connection to a QMP service which separates the events that
+// the service emits asynchronously from the responses to commands.  Reading
+// from the connection only returns responses, such that a client which reads
+// exactly one line per command can be kept open for the lifetime of the
+// machine, whilst the events are delivered through a channel.
+type EventConn[T utils.ComparableStringer] struct {
+	io.ReadWriteCloser
+
+	types     []T
+	events    chan *QMPEvent[T]
+	responses chan []byte
+	buf       []byte
+	err       error
+}
+
+// NewEventConn wraps the provided connection to a QMP service and starts
+// receiving from it.  Events of the provided types are buffered in a channel
+// of the provided size, and events which do not fit are dropped.
+func NewEventConn[T utils.ComparableStringer](conn io.ReadWriteCloser, types []T, size int) *EventConn[T] {
+	c := &EventConn[T]{
+		ReadWriteCloser: conn,
+		types:           types,
+		events:          make(chan *QMPEvent[T], size),
+		responses:       make(chan []byte),
+	}
+
+	go c.recv()
+
+	return c
+}
+
+// Events returns the channel through which the events emitted by the QMP
+// service are delivered.  The channel is closed when the connection is.
+func (c *EventConn[T]) Events() <-chan *QMPEvent[T] {
+	return c.events
+}
+
+// Read implements io.Reader and only returns the lines received from the QMP
+// service which are not events.
+func (c *EventConn[T]) Read(p []byte) (int, error) {
+	if len(c.buf) == 0 {
+		line, ok := <-c.responses
+		if !ok {
+			return 0, c.err
+		}
+
+		c.buf = line
+	}
+
+	n := copy(p, c.buf)
+	c.buf = c.buf[n:]
+
+	return n, nil
+}
+
+// recv reads lines from the QMP service until the connection is closed and
+// dispatches them to either the events or the responses channel.
+func (c *EventConn[T]) recv() {
+	defer close(c.events)
+	defer close(c.responses)
+
+	r := bufio.NewReader(c.ReadWriteCloser)
+
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			c.err = err
+			return
+		}
+
+		event, err := parseEvent(line, c.types)
+		if errors.Is(err, ErrAcceptedNonEvent) {
+			c.responses <- line
+			continue
+		} else if err != nil {
+			continue
+		}
+
+		select {
+		case c.events <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+package qmp
+
+import (
+	"bufio"
+	"net"
+	"testing"
+)
+
+type testEventType string
+
+func (t testEventType) String() string {
+	return string(t)
+}
+
+func TestEventConn(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+
+	conn := NewEventConn(client, []testEventType{"STOP", "RESUME"}, 2)
+	defer conn.Close()
+
+	go func() {
+		_, _ = server.Write([]byte(
+			`{"event": "STOP", "timestamp": {"seconds": 1, "microseconds": 2}}` + "\n" +
+				`{"event": "UNKNOWN"}` + "\n" +
+				`{"return": {}}` + "\n" +
+				`{"event": "RESUME"}` + "\n" +
+				`{"return": {"running": true}}` + "\n",
+		))
+	}()
+
+	recv := bufio.NewReader(conn)
+
+	for _, expected := range []string{
+		`{"return": {}}` + "\n",
+		`{"return": {"running": true}}` + "\n",
+	} {
+		line, err := recv.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading response: %v", err)
+		}
+
+		if line != expected {
+			t.Errorf("expected response %q, got %q", expected, line)
+		}
+	}
+
+	for _, expected := range []testEventType{"STOP", "RESUME"} {
+		event := <-conn.Events()
+		if event.Event != expected {
+			t.Errorf("expected event %q, got %q", expected, event.Event)
+		}
+	}
+}
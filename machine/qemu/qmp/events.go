@@ -78,6 +78,12 @@ func (em *QMPEventMonitor[T]) Accept() (*QMPEvent[T], error) {
 		return nil, err
 	}
 
+	return parseEvent(data, em.types)
+}
+
+// parseEvent parses the provided line received from the QMP service as an
+// event of one of the provided types.
+func parseEvent[T utils.ComparableStringer](data []byte, types []T) (*QMPEvent[T], error) {
 	// Use a generic to serialize as a map (which is the very least we can expect)
 	// from the Unmarshal result.  We can then peak at it before applying a type.
 	var raw map[string]any
@@ -92,7 +98,7 @@ func (em *QMPEventMonitor[T]) Accept() (*QMPEvent[T], error) {
 
 	var t T
 	found := false
-	for _, needle := range em.types {
+	for _, needle := range types {
 		if needle.String() == typ {
 			t = needle
 			found = true
@@ -16,6 +16,23 @@ type QueryKvmResponse struct {
 	Return KvmInfo `json:"return"`
 }
 
+type QueryMemorySizeSummaryRequest struct {
+	Execute string `json:"execute" default:"query-memory-size-summary"`
+}
+
+// Actual memory information in bytes.
+type MemoryInfo struct {
+	// size of "base" memory specified with command line option -m.
+	BaseMemory uint64 `json:"base-memory"`
+	// size of memory that can be hot-unplugged.  This field is omitted if
+	// target doesn't support memory hotplug.
+	PluggedMemory uint64 `json:"plugged-memory"`
+}
+
+type QueryMemorySizeSummaryResponse struct {
+	Return MemoryInfo `json:"return"`
+}
+
 type SystemResetRequest struct {
 	Execute string `json:"execute" default:"system_reset"`
 }
@@ -390,6 +390,40 @@ func (c *QEMUMachineProtocolClient) QueryStatus(req QueryStatusRequest) (*QueryS
 	return &res, nil
 }
 
+func (c *QEMUMachineProtocolClient) QueryMemorySizeSummary(req QueryMemorySizeSummaryRequest) (*QueryMemorySizeSummaryResponse, error) {
+	var b []byte
+	var err error
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if err := c.setRpcRequestSetDefaults(&req); err != nil {
+		return nil, err
+	}
+
+	b, err = json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.send.Write(append(b, '\x0a')); err != nil {
+		return nil, err
+	}
+	if err := c.send.Flush(); err != nil {
+		return nil, err
+	}
+
+	var res QueryMemorySizeSummaryResponse
+	b, err = c.recv.ReadBytes('\n')
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &res); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
 func (c *QEMUMachineProtocolClient) SetLink(req SetLinkRequest) (*any, error) {
 	var b []byte
 	var err error
@@ -309,6 +309,13 @@ func (service *machineV1alpha1Service) Create(ctx context.Context, machine *mach
 				mac = startMac.String()
 			}
 
+			// QEMU's user networking only binds forwarded ports to IPv4 addresses,
+			// otherwise all interfaces are used.
+			hostIP := port.HostIP
+			if ip := net.ParseIP(hostIP); ip == nil || ip.To4() == nil {
+				hostIP = ""
+			}
+
 			hostnetid := fmt.Sprintf("hostnet%d", hostnetCounter)
 			hostnetCounter++
 			qopts = append(qopts,
@@ -318,7 +325,7 @@ func (service *machineV1alpha1Service) Create(ctx context.Context, machine *mach
 				}),
 				WithNetDevice(QemuNetDevUser{
 					Id:      hostnetid,
-					Hostfwd: fmt.Sprintf("%s::%d-:%d", port.Protocol, port.HostPort, port.MachinePort),
+					Hostfwd: fmt.Sprintf("%s:%s:%d-:%d", port.Protocol, hostIP, port.HostPort, port.MachinePort),
 				}),
 			)
 		}
@@ -364,6 +371,17 @@ func (service *machineV1alpha1Service) Create(ctx context.Context, machine *mach
 				"",
 				"",
 			).String())
+
+		case "ramfs":
+			fstab = append(fstab, vfscore.NewFstabEntry(
+				"none",
+				vol.Spec.Destination,
+				vol.Spec.Driver,
+				"",
+				"",
+				"mkmp",
+			).String())
+
 		default:
 			return machine, fmt.Errorf("unsupported QEMU volume driver: %v", vol.Spec.Driver)
 		}
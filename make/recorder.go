@@ -0,0 +1,243 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package make
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DiagnosticSeverity represents the severity of a compiler diagnostic.
+type DiagnosticSeverity string
+
+const (
+	DiagnosticSeverityWarning = DiagnosticSeverity("warning")
+	DiagnosticSeverityError   = DiagnosticSeverity("error")
+)
+
+// Diagnostic is a single warning or error emitted by the compiler or linker
+// during the invocation of make.
+type Diagnostic struct {
+	Severity DiagnosticSeverity `json:"severity"`
+	File     string             `json:"file,omitempty"`
+	Line     int                `json:"line,omitempty"`
+	Column   int                `json:"column,omitempty"`
+	Message  string             `json:"message"`
+}
+
+// LibraryTiming represents the time spent compiling the objects of a single
+// Unikraft library.  The duration is measured from the first to the last
+// compilation step which was reported for the library.
+type LibraryTiming struct {
+	Name     string        `json:"name"`
+	Objects  int           `json:"objects"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+}
+
+var (
+	// Matches the quiet output of Unikraft's build system, e.g.:
+	//
+	//   CC      libukdebug: /path/to/build/libukdebug/print.o
+	//   LD      helloworld_qemu-x86_64.dbg
+	stepRegex = regexp.MustCompile(`^\s+([A-Z][A-Z0-9+\-]*)\s+(?:([^\s:]+):\s+)?(\S+)\s*$`)
+
+	// Matches GCC/Clang-style diagnostics, e.g.:
+	//
+	//   /path/to/file.c:12:5: warning: unused variable 'x' [-Wunused-variable]
+	diagnosticRegex = regexp.MustCompile(`^(.+?):(\d+):(?:(\d+):)?\s+(?:fatal\s+)?(warning|error):\s+(.*)$`)
+
+	// Matches linker diagnostics which do not carry a line number, e.g.:
+	//
+	//   ld: undefined reference to `foo'
+	linkerErrorRegex = regexp.MustCompile(`^(?:\S*ld(?:\.\w+)?|collect2):\s+(?:error:\s+)?(.*)$`)
+)
+
+// compileSteps are the step names emitted by Unikraft's build system which
+// represent the compilation of an object of a library.
+var compileSteps = map[string]bool{
+	"AS":  true,
+	"CC":  true,
+	"CXX": true,
+	"GOC": true,
+	"RS":  true,
+}
+
+// linkSteps are the step names emitted by Unikraft's build system which
+// represent the final linking of the image.
+var linkSteps = map[string]bool{
+	"LD":        true,
+	"OBJCOPY":   true,
+	"STRIP":     true,
+	"SCSTRIP":   true,
+	"MKBOOTIMG": true,
+}
+
+// Recorder is an io.Writer which parses the output of an invocation of make
+// against Unikraft's build system.  It records per-library compilation timings,
+// the time at which the final link began and all compiler diagnostics.  Pass
+// the same Recorder to both the standard output and standard error of the
+// process via the exec.WithStdoutCallback and exec.WithStderrCallback options.
+type Recorder struct {
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	libraries map[string]*LibraryTiming
+	diags     []Diagnostic
+	linkStart time.Time
+	now       func() time.Time
+}
+
+// NewRecorder instantiates a new make output recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{
+		libraries: map[string]*LibraryTiming{},
+		now:       time.Now,
+	}
+}
+
+// Write implements io.Writer.  The output is buffered until a full line is
+// available since the underlying process may emit partial lines.
+func (r *Recorder) Write(b []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.buf.Write(b)
+
+	for {
+		line, err := r.buf.ReadString('\n')
+		if err != nil {
+			// Put back the incomplete line so it can be completed by a subsequent
+			// write.
+			r.buf.Reset()
+			r.buf.WriteString(line)
+			break
+		}
+
+		r.parseLine(strings.TrimRight(line, "\r\n"))
+	}
+
+	return len(b), nil
+}
+
+// Flush processes any remaining buffered output which was not terminated by
+// a newline character.
+func (r *Recorder) Flush() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.buf.Len() > 0 {
+		r.parseLine(strings.TrimRight(r.buf.String(), "\r\n"))
+		r.buf.Reset()
+	}
+}
+
+func (r *Recorder) parseLine(line string) {
+	if len(line) == 0 {
+		return
+	}
+
+	for _, prefix := range IgnoredMakePrefixes {
+		if strings.HasPrefix(line, prefix) {
+			return
+		}
+	}
+
+	if matches := diagnosticRegex.FindStringSubmatch(line); matches != nil {
+		diag := Diagnostic{
+			Severity: DiagnosticSeverity(matches[4]),
+			File:     matches[1],
+			Message:  matches[5],
+		}
+		diag.Line, _ = strconv.Atoi(matches[2])
+		if len(matches[3]) > 0 {
+			diag.Column, _ = strconv.Atoi(matches[3])
+		}
+
+		r.diags = append(r.diags, diag)
+		return
+	}
+
+	if matches := linkerErrorRegex.FindStringSubmatch(line); matches != nil {
+		r.diags = append(r.diags, Diagnostic{
+			Severity: DiagnosticSeverityError,
+			Message:  matches[1],
+		})
+		return
+	}
+
+	matches := stepRegex.FindStringSubmatch(line)
+	if matches == nil {
+		return
+	}
+
+	step, library, object := matches[1], matches[2], matches[3]
+	now := r.now()
+
+	switch {
+	case compileSteps[step] && len(library) > 0:
+		timing, ok := r.libraries[library]
+		if !ok {
+			timing = &LibraryTiming{
+				Name:  library,
+				Start: now,
+			}
+			r.libraries[library] = timing
+		}
+
+		timing.Objects++
+		timing.Duration = now.Sub(timing.Start)
+
+	case linkSteps[step] && len(library) == 0 && !strings.HasPrefix(object, "lib"):
+		// Library-level partial links are reported with the library's name,
+		// whilst the final image is named after the application.
+		if r.linkStart.IsZero() {
+			r.linkStart = now
+		}
+	}
+}
+
+// Libraries returns the recorded compilation timings of all libraries sorted
+// by the time at which their compilation began.
+func (r *Recorder) Libraries() []LibraryTiming {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	libraries := make([]LibraryTiming, 0, len(r.libraries))
+	for _, timing := range r.libraries {
+		libraries = append(libraries, *timing)
+	}
+
+	sort.SliceStable(libraries, func(i, j int) bool {
+		if libraries[i].Start.Equal(libraries[j].Start) {
+			return libraries[i].Name < libraries[j].Name
+		}
+		return libraries[i].Start.Before(libraries[j].Start)
+	})
+
+	return libraries
+}
+
+// Diagnostics returns all recorded compiler and linker diagnostics in the
+// order in which they were emitted.
+func (r *Recorder) Diagnostics() []Diagnostic {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Diagnostic{}, r.diags...)
+}
+
+// LinkStart returns the time at which the final link of the image began.  If
+// no link step was observed, the zero time is returned.
+func (r *Recorder) LinkStart() time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.linkStart
+}
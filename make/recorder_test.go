@@ -0,0 +1,61 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package make
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecorder(t *testing.T) {
+	clock := time.Unix(0, 0)
+	r := NewRecorder()
+	r.now = func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}
+
+	// Deliberately split writes across line boundaries.
+	for _, chunk := range []string{
+		"make[1]: Entering directory '/uk'\n  CC      libukdebug: /b/libukdebug/print.o\n  CC      libuk",
+		"debug: /b/libukdebug/outf.o\n",
+		"/app/main.c:12:5: warning: unused variable 'x' [-Wunused-variable]\n",
+		"  CC      apphello: /b/apphello/main.o\n",
+		"  LD      libukdebug.ld.o\n",
+		"  LD      hello_qemu-x86_64.dbg\n",
+		"/usr/bin/ld: main.o: undefined reference to `foo'",
+	} {
+		if _, err := r.Write([]byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r.Flush()
+
+	libs := r.Libraries()
+	if len(libs) != 2 {
+		t.Fatalf("expected 2 libraries, got %d: %v", len(libs), libs)
+	}
+	if libs[0].Name != "libukdebug" || libs[0].Objects != 2 || libs[0].Duration != time.Second {
+		t.Errorf("unexpected timing for libukdebug: %+v", libs[0])
+	}
+	if libs[1].Name != "apphello" || libs[1].Objects != 1 {
+		t.Errorf("unexpected timing for apphello: %+v", libs[1])
+	}
+
+	if want := time.Unix(5, 0); !r.LinkStart().Equal(want) {
+		t.Errorf("expected link to start at %v, got %v", want, r.LinkStart())
+	}
+
+	diags := r.Diagnostics()
+	if len(diags) != 2 {
+		t.Fatalf("expected 2 diagnostics, got %d: %v", len(diags), diags)
+	}
+	if d := diags[0]; d.Severity != DiagnosticSeverityWarning || d.File != "/app/main.c" || d.Line != 12 || d.Column != 5 {
+		t.Errorf("unexpected warning: %+v", d)
+	}
+	if d := diags[1]; d.Severity != DiagnosticSeverityError || d.File != "" {
+		t.Errorf("unexpected linker error: %+v", d)
+	}
+}
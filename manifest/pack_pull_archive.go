@@ -8,6 +8,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net/http"
@@ -177,7 +178,7 @@ func pullArchive(ctx context.Context, manifest *Manifest, opts ...pack.PullOptio
 					return fmt.Errorf("could not perform checksum: %v", err)
 				}
 
-				if checksum != string(h.Sum(nil)) {
+				if checksum != hex.EncodeToString(h.Sum(nil)) {
 					return fmt.Errorf("checksum of package does not match")
 				}
 
@@ -0,0 +1,421 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package manifest
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+
+	"kraftkit.sh/archive"
+	"kraftkit.sh/log"
+	"kraftkit.sh/unikraft"
+)
+
+// IndexServerIndexFile is the path at which an IndexServer serves its
+// manifest index.
+const IndexServerIndexFile = "index.yaml"
+
+// IndexServer publishes the Unikraft components found in local directories and
+// Git repositories as a manifest index which is served over HTTP together with
+// the manifest of each component and an archive of each of its channels and
+// versions.
+type IndexServer struct {
+	index     []byte
+	manifests map[string][]byte
+	archives  map[string]string
+}
+
+// NewIndexServer generates the manifest index of the components at the
+// provided paths, each of which is either a component or a directory of
+// components, as they are read by the DirectoryProvider.  Components which are
+// Git repositories have a channel for each of their branches and a version for
+// each of their tags, as they are read by the GitProvider, otherwise they
+// have a single default channel.  Archives are written to the provided
+// working directory and referenced relative to the provided base URL.
+func NewIndexServer(ctx context.Context, baseURL, workdir string, paths []string) (*IndexServer, error) {
+	server := IndexServer{
+		manifests: map[string][]byte{},
+		archives:  map[string]string{},
+	}
+
+	index := ManifestIndex{
+		LastUpdated: time.Now(),
+	}
+
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	for _, p := range paths {
+		dirs, err := componentDirs(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, dir := range dirs {
+			manifest, err := server.publish(ctx, baseURL, workdir, dir)
+			if err != nil {
+				return nil, fmt.Errorf("could not publish '%s': %w", dir, err)
+			}
+
+			filename := manifest.Name + ".yaml"
+			if manifest.Type != unikraft.ComponentTypeCore {
+				filename = manifest.Type.Plural() + "/" + filename
+			}
+
+			if _, ok := server.manifests[filename]; ok {
+				return nil, fmt.Errorf("component '%s/%s' is provided more than once", manifest.Type, manifest.Name)
+			}
+
+			raw, err := yaml.Marshal(manifest)
+			if err != nil {
+				return nil, fmt.Errorf("could not marshal manifest: %w", err)
+			}
+
+			server.manifests[filename] = raw
+
+			index.Manifests = append(index.Manifests, &Manifest{
+				Name:     manifest.Name,
+				Type:     manifest.Type,
+				Manifest: "./" + filename,
+			})
+
+			log.G(ctx).WithFields(logrus.Fields{
+				"name":     manifest.Name,
+				"type":     manifest.Type,
+				"channels": len(manifest.Channels),
+				"versions": len(manifest.Versions),
+			}).Info("publishing")
+		}
+	}
+
+	if len(index.Manifests) == 0 {
+		return nil, fmt.Errorf("no components found")
+	}
+
+	var err error
+	server.index, err = yaml.Marshal(index)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal manifest index: %w", err)
+	}
+
+	return &server, nil
+}
+
+// componentDirs returns the provided path if it is a component or otherwise
+// each of its subdirectories which is a component.
+func componentDirs(ctx context.Context, p string) ([]string, error) {
+	if _, err := NewDirectoryProvider(ctx, p); err == nil {
+		return []string{p}, nil
+	}
+
+	entries, err := os.ReadDir(p)
+	if err != nil {
+		return nil, fmt.Errorf("could not read directory: %w", err)
+	}
+
+	var dirs []string
+
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		dir := filepath.Join(p, entry.Name())
+		if _, err := NewDirectoryProvider(ctx, dir); err != nil {
+			log.G(ctx).
+				WithField("dir", dir).
+				Debugf("skipping: %v", err)
+			continue
+		}
+
+		dirs = append(dirs, dir)
+	}
+
+	return dirs, nil
+}
+
+// publish archives the channels and versions of the component in the provided
+// directory and returns its manifest.
+func (server *IndexServer) publish(ctx context.Context, baseURL, workdir, dir string) (*Manifest, error) {
+	provider, err := NewDirectoryProvider(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	manifests, err := provider.Manifests()
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := manifests[0]
+	manifest.Provider = nil
+	manifest.Origin = ""
+	if len(manifest.Name) == 0 {
+		manifest.Name = filepath.Base(dir)
+	}
+
+	prefix := path.Join(manifest.Type.Plural(), manifest.Name)
+
+	repo, err := git.PlainOpen(dir)
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		resource := path.Join(prefix, manifest.Channels[0].Name+".tar.gz")
+
+		manifest.Channels[0].Sha256, err = server.archive(resource, workdir, func(tw *tar.Writer) error {
+			return archive.TarDirWriter(ctx, dir, manifest.Name, tw,
+				archive.WithStripTimes(true),
+				archive.WithFilter(func(path string, fi os.FileInfo) bool {
+					return fi.Name() != ".git"
+				}),
+			)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		manifest.Channels[0].Resource = baseURL + "/" + resource
+
+		return manifest, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not open git repository: %w", err)
+	}
+
+	iter, err := repo.References()
+	if err != nil {
+		return nil, fmt.Errorf("could not list git references: %w", err)
+	}
+
+	gp := GitProvider{
+		repo: dir,
+		ctx:  ctx,
+	}
+
+	if err := iter.ForEach(func(ref *plumbing.Reference) error {
+		gp.refs = append(gp.refs, ref)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("could not list git references: %w", err)
+	}
+
+	manifest.Channels = gp.probeChannels()
+	manifest.Versions = gp.probeVersions()
+
+	for i, channel := range manifest.Channels {
+		resource := path.Join(prefix, channel.Name+".tar.gz")
+
+		channel.Sha256, err = server.archiveRevision(repo, manifest.Name, resource, workdir, "refs/heads/"+channel.Name)
+		if err != nil {
+			return nil, fmt.Errorf("could not archive channel '%s': %w", channel.Name, err)
+		}
+
+		channel.Resource = baseURL + "/" + resource
+		manifest.Channels[i] = channel
+	}
+
+	for i, version := range manifest.Versions {
+		tag := version.Version
+		if version.Type == ManifestVersionGitSha {
+			tag = "RELEASE-" + version.Unikraft
+		}
+
+		resource := path.Join(prefix, version.Version+".tar.gz")
+
+		version.Sha256, err = server.archiveRevision(repo, manifest.Name, resource, workdir, "refs/tags/"+tag)
+		if err != nil {
+			return nil, fmt.Errorf("could not archive version '%s': %w", version.Version, err)
+		}
+
+		version.Resource = baseURL + "/" + resource
+		manifest.Versions[i] = version
+	}
+
+	return manifest, nil
+}
+
+// archiveRevision archives the tree of the provided revision of the provided
+// Git repository beneath the provided prefix.
+func (server *IndexServer) archiveRevision(repo *git.Repository, prefix, resource, workdir, revision string) (string, error) {
+	hash, err := repo.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		return "", fmt.Errorf("could not resolve revision: %w", err)
+	}
+
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return "", fmt.Errorf("could not get commit: %w", err)
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return "", fmt.Errorf("could not get tree: %w", err)
+	}
+
+	return server.archive(resource, workdir, func(tw *tar.Writer) error {
+		modTime := commit.Committer.When
+		dirs := map[string]bool{}
+
+		// Parent directories are written before their contents such that they are
+		// created with the correct permissions when unarchived.
+		var mkdir func(dir string) error
+		mkdir = func(dir string) error {
+			if dir == "." || dirs[dir] {
+				return nil
+			}
+
+			if err := mkdir(path.Dir(dir)); err != nil {
+				return err
+			}
+
+			dirs[dir] = true
+
+			return tw.WriteHeader(&tar.Header{
+				Typeflag: tar.TypeDir,
+				Name:     dir + "/",
+				Mode:     0o755,
+				ModTime:  modTime,
+			})
+		}
+
+		if err := mkdir(prefix); err != nil {
+			return err
+		}
+
+		return tree.Files().ForEach(func(f *object.File) error {
+			if err := mkdir(path.Join(prefix, path.Dir(f.Name))); err != nil {
+				return err
+			}
+
+			reader, err := f.Reader()
+			if err != nil {
+				return err
+			}
+
+			defer reader.Close()
+
+			header := tar.Header{
+				Name:    path.Join(prefix, f.Name),
+				ModTime: modTime,
+			}
+
+			switch f.Mode {
+			case filemode.Symlink:
+				target, err := io.ReadAll(reader)
+				if err != nil {
+					return err
+				}
+
+				header.Typeflag = tar.TypeSymlink
+				header.Linkname = string(target)
+				header.Mode = 0o777
+
+				return tw.WriteHeader(&header)
+
+			case filemode.Executable:
+				header.Mode = 0o755
+
+			default:
+				header.Mode = 0o644
+			}
+
+			header.Typeflag = tar.TypeReg
+			header.Size = f.Size
+
+			if err := tw.WriteHeader(&header); err != nil {
+				return err
+			}
+
+			_, err = io.Copy(tw, reader)
+			return err
+		})
+	})
+}
+
+// archive writes the gzip-compressed tarball which is populated by the
+// provided function to the working directory, registers it to be served at
+// the provided resource path and returns its SHA-256 checksum.
+func (server *IndexServer) archive(resource, workdir string, populate func(*tar.Writer) error) (string, error) {
+	out := filepath.Join(workdir, filepath.FromSlash(resource))
+	if err := os.MkdirAll(filepath.Dir(out), 0o755); err != nil {
+		return "", fmt.Errorf("could not make parent directory: %w", err)
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		return "", fmt.Errorf("could not create archive: %w", err)
+	}
+
+	defer f.Close()
+
+	h := sha256.New()
+	gw := gzip.NewWriter(io.MultiWriter(f, h))
+	tw := tar.NewWriter(gw)
+
+	if err := populate(tw); err != nil {
+		return "", fmt.Errorf("could not populate archive: %w", err)
+	}
+
+	if err := tw.Close(); err != nil {
+		return "", err
+	}
+
+	if err := gw.Close(); err != nil {
+		return "", err
+	}
+
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	server.archives[resource] = out
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ServeHTTP implements http.Handler.
+func (server *IndexServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	p := strings.TrimPrefix(path.Clean(r.URL.Path), "/")
+
+	if p == IndexServerIndexFile {
+		w.Header().Set("Content-Type", "application/yaml")
+		http.ServeContent(w, r, p, time.Time{}, bytes.NewReader(server.index))
+		return
+	}
+
+	if raw, ok := server.manifests[p]; ok {
+		w.Header().Set("Content-Type", "application/yaml")
+		http.ServeContent(w, r, p, time.Time{}, bytes.NewReader(raw))
+		return
+	}
+
+	if out, ok := server.archives[p]; ok {
+		http.ServeFile(w, r, out)
+		return
+	}
+
+	http.NotFound(w, r)
+}
@@ -0,0 +1,199 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package manifest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"kraftkit.sh/pack"
+	"kraftkit.sh/unikraft"
+)
+
+// commit writes the provided file to the worktree of the repository and
+// commits it.
+func commit(t *testing.T, repo *git.Repository, dir, file, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := wt.Add(file); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := wt.Commit("Update "+file, &git.CommitOptions{
+		Author: &object.Signature{Name: "KraftKit", Email: "kraftkit@unikraft.io", When: time.Now()},
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// pull pulls the provided version of the component with the provided name
+// from the manifest index at the provided URL and returns the directory it has
+// been placed in.
+func pull(t *testing.T, index, cache, name, version string) (string, error) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	manifests, err := FindManifestsFromSource(ctx, index,
+		WithCacheDir(cache),
+		WithUpdate(true),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, manifest := range manifests {
+		if manifest.Name != name {
+			continue
+		}
+
+		p, err := NewPackageFromManifestWithVersion(manifest, version)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		workdir := t.TempDir()
+
+		if err := p.Pull(ctx,
+			pack.WithPullWorkdir(workdir),
+			pack.WithPullChecksum(true),
+		); err != nil {
+			return "", err
+		}
+
+		return unikraft.PlaceComponent(workdir, manifest.Type, manifest.Name)
+	}
+
+	t.Fatalf("component '%s' is not listed in the index", name)
+
+	return "", nil
+}
+
+func expectFile(t *testing.T, dir, file, expected string) {
+	t.Helper()
+
+	b, err := os.ReadFile(filepath.Join(dir, file))
+	if err != nil {
+		t.Errorf("expected '%s' to be pulled: %v", file, err)
+		return
+	}
+
+	if string(b) != expected {
+		t.Errorf("expected '%s' to contain %q, got %q", file, expected, b)
+	}
+}
+
+func TestIndexServer(t *testing.T) {
+	ctx := context.Background()
+	libs := t.TempDir()
+
+	// A component which is a plain directory.
+	plain := filepath.Join(libs, "lib-plain")
+	if err := os.MkdirAll(plain, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(plain, unikraft.Config_uk), []byte("config LIBPLAIN\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(plain, "plain.c"), []byte("plain"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A component which is a Git repository with a tagged and a later revision.
+	tracked := filepath.Join(libs, "lib-tracked")
+	repo, err := git.PlainInit(tracked, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commit(t, repo, tracked, unikraft.Config_uk, "config LIBTRACKED\n")
+	commit(t, repo, tracked, "tracked.c", "v0.1.0")
+
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CreateTag("v0.1.0", head.Hash(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	commit(t, repo, tracked, "tracked.c", "master")
+
+	// Uncommitted changes are not published.
+	if err := os.WriteFile(filepath.Join(tracked, "scratch.c"), []byte("scratch"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var server *IndexServer
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	workdir := t.TempDir()
+
+	server, err = NewIndexServer(ctx, ts.URL, workdir, []string{libs})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	index := ts.URL + "/" + IndexServerIndexFile
+
+	dir, err := pull(t, index, t.TempDir(), "plain", "default")
+	if err != nil {
+		t.Fatalf("could not pull plain component: %v", err)
+	}
+
+	expectFile(t, dir, "plain.c", "plain")
+
+	dir, err = pull(t, index, t.TempDir(), "tracked", "v0.1.0")
+	if err != nil {
+		t.Fatalf("could not pull tagged version: %v", err)
+	}
+
+	expectFile(t, dir, "tracked.c", "v0.1.0")
+
+	dir, err = pull(t, index, t.TempDir(), "tracked", "master")
+	if err != nil {
+		t.Fatalf("could not pull branch: %v", err)
+	}
+
+	expectFile(t, dir, "tracked.c", "master")
+
+	if _, err := os.Stat(filepath.Join(dir, "scratch.c")); err == nil {
+		t.Error("expected uncommitted file not to be published")
+	}
+
+	// An archive which does not match the checksum of the manifest is rejected.
+	archive := filepath.Join(workdir, "libs", "plain", "default.tar.gz")
+	if err := os.WriteFile(archive, []byte("tampered"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := pull(t, index, t.TempDir(), "plain", "default"); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("expected pulling a tampered archive to fail its checksum, got %v", err)
+	}
+}
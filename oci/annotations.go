@@ -14,6 +14,7 @@ const (
 	AnnotationKernelPath           = "org.unikraft.kernel.image"
 	AnnotationKernelVersion        = "org.unikraft.kernel.version"
 	AnnotationKernelInitrdPath     = "org.unikraft.kernel.initrd"
+	AnnotationKernelInitrdChunk    = "org.unikraft.kernel.initrd.chunk"
 	AnnotationKernelKConfig        = "org.unikraft.kernel.kconfig."
 	AnnotationKernelArch           = "org.unikraft.kernel.arch"
 	AnnotationKernelPlat           = "org.unikraft.kernel.plat"
@@ -0,0 +1,190 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package oci
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"kraftkit.sh/log"
+	"kraftkit.sh/packmanager"
+)
+
+// addBuildLayers adds the optional layers which contain the library objects,
+// the intermediate objects and the source files of the unikernel, such that a
+// consumer of the package is able to relink or debug it without the original
+// source tree.
+func (ocipack *ociPackage) addBuildLayers(ctx context.Context, popts *packmanager.PackOptions) (err error) {
+	var layers []*Layer
+
+	// The temporary tarballs are otherwise removed once the blobs are saved.
+	defer func() {
+		if err != nil {
+			for _, layer := range layers {
+				os.Remove(layer.tmp)
+			}
+		}
+	}()
+
+	if popts.PackKernelLibraryObjects() || popts.PackKernelLibraryIntermediateObjects() {
+		if len(ocipack.Kernel()) == 0 {
+			return fmt.Errorf("cannot include object files without a kernel build directory")
+		}
+	}
+
+	// The objects are located alongside the kernel in the build directory, where
+	// each library has been linked into an object at the root of the directory,
+	// e.g. libnolibc.o, and its intermediate objects are in the sub-directory of
+	// the library, e.g. libnolibc/errno.o.
+	buildDir := filepath.Dir(ocipack.Kernel())
+
+	if popts.PackKernelLibraryObjects() {
+		log.G(ctx).
+			WithField("src", buildDir).
+			WithField("dest", WellKnownKernelLibDir).
+			Debug("including kernel library objects")
+
+		layer, err := NewLayerFromDirs(ctx,
+			MediaTypeKernelLibraryObjects,
+			map[string]string{WellKnownKernelLibDir: buildDir},
+			func(path string, fi os.FileInfo) bool {
+				return !fi.IsDir() && filepath.Ext(path) == ".o"
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("could not create library objects layer: %w", err)
+		}
+
+		layers = append(layers, layer)
+	}
+
+	if popts.PackKernelLibraryIntermediateObjects() {
+		log.G(ctx).
+			WithField("src", buildDir).
+			WithField("dest", WellKnownKernelObjDir).
+			Debug("including kernel library intermediate objects")
+
+		layer, err := NewLayerFromDirs(ctx,
+			MediaTypeKernelLibraryIntermediateObjects,
+			map[string]string{WellKnownKernelObjDir: buildDir},
+			func(path string, fi os.FileInfo) bool {
+				if fi.IsDir() {
+					return true
+				}
+
+				return filepath.Dir(path) != buildDir && filepath.Ext(path) == ".o"
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("could not create intermediate objects layer: %w", err)
+		}
+
+		layers = append(layers, layer)
+	}
+
+	if popts.PackKernelSourceFiles() {
+		if len(popts.KernelSourceDirs()) == 0 {
+			return fmt.Errorf("cannot include kernel source files without any source directory")
+		}
+
+		dirs := sourceDestinations(WellKnownKernelSourceDir, popts.KernelSourceDirs())
+
+		log.G(ctx).
+			WithField("src", popts.KernelSourceDirs()).
+			WithField("dest", WellKnownKernelSourceDir).
+			Debug("including kernel source files")
+
+		layer, err := NewLayerFromDirs(ctx,
+			MediaTypeKernelSource,
+			dirs,
+			func(path string, fi os.FileInfo) bool {
+				return !fi.IsDir() || fi.Name() != ".git"
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("could not create kernel source layer: %w", err)
+		}
+
+		layers = append(layers, layer)
+	}
+
+	if popts.PackAppSourceFiles() {
+		if len(popts.AppSourceDir()) == 0 {
+			return fmt.Errorf("cannot include application source files without a source directory")
+		}
+
+		log.G(ctx).
+			WithField("src", popts.AppSourceDir()).
+			WithField("dest", WellKnownAppSourceDir).
+			Debug("including application source files")
+
+		// The hidden .unikraft directory contains the build directory as well as
+		// the sources of the kernel, which are packaged separately.
+		layer, err := NewLayerFromDirs(ctx,
+			MediaTypeAppSource,
+			map[string]string{WellKnownAppSourceDir: popts.AppSourceDir()},
+			func(path string, fi os.FileInfo) bool {
+				return !fi.IsDir() || (fi.Name() != ".git" && fi.Name() != ".unikraft")
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("could not create application source layer: %w", err)
+		}
+
+		layers = append(layers, layer)
+	}
+
+	for _, layer := range layers {
+		if _, err := ocipack.manifest.AddLayer(ctx, layer); err != nil {
+			return fmt.Errorf("could not add layer to manifest: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// sourceDestinations places each of the provided source directories beneath
+// the destination at its path relative to the deepest directory containing
+// all of them, e.g. /app/.unikraft/unikraft and /app/.unikraft/libs/musl are
+// placed at <dst>/unikraft and <dst>/libs/musl.  Unlike their base names,
+// these paths are unique even if two components share the same name.
+func sourceDestinations(dst string, srcs []string) map[string]string {
+	dirs := make(map[string]string, len(srcs))
+	if len(srcs) == 0 {
+		return dirs
+	}
+
+	cleaned := make([]string, len(srcs))
+	for i, src := range srcs {
+		if abs, err := filepath.Abs(src); err == nil {
+			src = abs
+		}
+
+		cleaned[i] = filepath.Clean(src)
+	}
+
+	// The common parent is determined from the parent of each directory such
+	// that a single directory is placed at its base name.
+	common := filepath.Dir(cleaned[0])
+	for _, src := range cleaned[1:] {
+		for common != filepath.Dir(common) && !strings.HasPrefix(src, common+string(filepath.Separator)) {
+			common = filepath.Dir(common)
+		}
+	}
+
+	for _, src := range cleaned {
+		rel, err := filepath.Rel(common, src)
+		if err != nil {
+			rel = filepath.Base(src)
+		}
+
+		dirs[filepath.Join(dst, rel)] = src
+	}
+
+	return dirs
+}
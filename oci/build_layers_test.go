@@ -0,0 +1,132 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package oci
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestSourceDestinations(t *testing.T) {
+	tests := []struct {
+		name     string
+		srcs     []string
+		expected map[string]string
+	}{
+		{
+			name: "single directory",
+			srcs: []string{"/app/.unikraft/unikraft"},
+			expected: map[string]string{
+				"/unikraft/src/unikraft": "/app/.unikraft/unikraft",
+			},
+		},
+		{
+			name: "core and libraries",
+			srcs: []string{"/app/.unikraft/unikraft", "/app/.unikraft/libs/musl"},
+			expected: map[string]string{
+				"/unikraft/src/unikraft":  "/app/.unikraft/unikraft",
+				"/unikraft/src/libs/musl": "/app/.unikraft/libs/musl",
+			},
+		},
+		{
+			name: "duplicate base names",
+			srcs: []string{"/app/.unikraft/libs/lwip", "/home/user/sources/lwip"},
+			expected: map[string]string{
+				"/unikraft/src/app/.unikraft/libs/lwip": "/app/.unikraft/libs/lwip",
+				"/unikraft/src/home/user/sources/lwip":  "/home/user/sources/lwip",
+			},
+		},
+		{
+			name:     "no directories",
+			expected: map[string]string{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := sourceDestinations(WellKnownKernelSourceDir, tc.srcs)
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestNewLayerFromDirs(t *testing.T) {
+	dir := t.TempDir()
+
+	for path, body := range map[string]string{
+		"a/lwip/tcp.c":     "tcp",
+		"a/lwip/.git/HEAD": "ref: refs/heads/main",
+		"b/lwip/udp.c":     "udp",
+	} {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	layer, err := NewLayerFromDirs(context.Background(),
+		MediaTypeKernelSource,
+		sourceDestinations(WellKnownKernelSourceDir, []string{
+			filepath.Join(dir, "a", "lwip"),
+			filepath.Join(dir, "b", "lwip"),
+		}),
+		func(path string, fi os.FileInfo) bool {
+			return !fi.IsDir() || fi.Name() != ".git"
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.Remove(layer.tmp)
+
+	f, err := os.Open(layer.tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	var files []string
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+
+		if hdr.Typeflag == tar.TypeReg {
+			files = append(files, hdr.Name)
+		}
+	}
+
+	sort.Strings(files)
+
+	// Both components named lwip are retained and the filtered directory is
+	// excluded.
+	expected := []string{
+		"unikraft/src/a/lwip/tcp.c",
+		"unikraft/src/b/lwip/udp.c",
+	}
+
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected files %v, got %v", expected, files)
+	}
+}
@@ -0,0 +1,257 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+// Package buildcache provides a content-addressed cache of the objects which
+// are produced when building the components of a Unikraft unikernel.  Each
+// entry is stored as an OCI artifact through an OCI handler, such that it can
+// be kept locally or shared with other hosts via a remote registry.
+package buildcache
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"kraftkit.sh/log"
+	"kraftkit.sh/oci"
+	"kraftkit.sh/oci/handler"
+)
+
+// AnnotationComponent is set on each entry of the cache and contains the name
+// of the component whose objects are stored in the entry.
+const AnnotationComponent = "org.unikraft.buildcache.component"
+
+// BuildCache stores and retrieves the build objects of components keyed by
+// the digest of the inputs which produced them.
+type BuildCache struct {
+	handle handler.Handler
+	repo   name.Repository
+	push   bool
+}
+
+// NewBuildCache instantiates a new build cache whose entries are tagged within
+// the provided repository, e.g. `ghcr.io/acme/buildcache`.  The entries are
+// stored using the OCI handler determined by the KraftKit configuration.  The
+// returned context must be used in subsequent calls.
+func NewBuildCache(ctx context.Context, repo string, opts ...BuildCacheOption) (context.Context, *BuildCache, error) {
+	var err error
+
+	cache := BuildCache{}
+
+	cache.repo, err = name.NewRepository(repo,
+		name.WithDefaultRegistry(oci.DefaultRegistry),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not parse build cache repository: %w", err)
+	}
+
+	for _, opt := range opts {
+		if err := opt(&cache); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	ctx, cache.handle, err = oci.NewHandlerFromContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ctx, &cache, nil
+}
+
+// reference returns the canonical reference of the entry with the given key.
+func (cache *BuildCache) reference(key digest.Digest) string {
+	return cache.repo.Tag(key.Encoded()).Name()
+}
+
+// Restore retrieves the entry with the provided key, first from the local
+// store and otherwise from the remote repository, and places its contents
+// in the build directory.  The placeholders of the host paths which were
+// substituted when the entry was saved are replaced by the provided host
+// paths.  The modification times of all restored files are set to the current
+// time such that the build system considers them to be up to date.  The
+// returned boolean indicates whether the entry was found.
+func (cache *BuildCache) Restore(ctx context.Context, key digest.Digest, plat *ocispec.Platform, buildDir string, hosts HostPaths) (bool, error) {
+	fullref := cache.reference(key)
+
+	index, err := cache.handle.ResolveIndex(ctx, fullref)
+	if err != nil {
+		if err := cache.handle.PullDigest(ctx, ocispec.MediaTypeImageIndex, fullref, "", plat, nil); err != nil {
+			log.G(ctx).
+				WithField("ref", fullref).
+				Debugf("build cache miss: %v", err)
+			return false, nil
+		}
+
+		index, err = cache.handle.ResolveIndex(ctx, fullref)
+		if err != nil {
+			return false, fmt.Errorf("could not resolve build cache entry: %w", err)
+		}
+	}
+
+	var manifest *ocispec.Descriptor
+	for i, desc := range index.Manifests {
+		if desc.Platform == nil {
+			continue
+		}
+
+		if desc.Platform.OS == plat.OS && desc.Platform.Architecture == plat.Architecture {
+			manifest = &index.Manifests[i]
+			break
+		}
+	}
+	if manifest == nil {
+		return false, nil
+	}
+
+	if err := os.MkdirAll(buildDir, 0o755); err != nil {
+		return false, fmt.Errorf("could not create build directory: %w", err)
+	}
+
+	// Unpack the entry into a staging directory first such that a partially
+	// restored entry cannot be mistaken for a complete build by the build
+	// system.
+	staging, err := os.MkdirTemp(buildDir, ".buildcache-*")
+	if err != nil {
+		return false, fmt.Errorf("could not create staging directory: %w", err)
+	}
+
+	defer os.RemoveAll(staging)
+
+	if _, err := cache.handle.UnpackImage(ctx, fullref, manifest.Digest, staging); err != nil {
+		return false, fmt.Errorf("could not unpack build cache entry: %w", err)
+	}
+
+	if err := relocate(staging, hosts.replacer(true)); err != nil {
+		return false, fmt.Errorf("could not relocate build objects: %w", err)
+	}
+
+	entries, err := os.ReadDir(staging)
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+
+	for _, entry := range entries {
+		src := filepath.Join(staging, entry.Name())
+		dst := filepath.Join(buildDir, entry.Name())
+
+		if err := filepath.Walk(src, func(path string, _ os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			return os.Chtimes(path, now, now)
+		}); err != nil {
+			return false, fmt.Errorf("could not update modification times: %w", err)
+		}
+
+		if err := os.RemoveAll(dst); err != nil {
+			return false, fmt.Errorf("could not remove stale build objects: %w", err)
+		}
+
+		if err := os.Rename(src, dst); err != nil {
+			return false, fmt.Errorf("could not restore build objects: %w", err)
+		}
+	}
+
+	log.G(ctx).
+		WithField("ref", fullref).
+		WithField("component", index.Annotations[AnnotationComponent]).
+		Debug("restored from build cache")
+
+	return true, nil
+}
+
+// Save stores the provided paths, which are relative to the build directory,
+// as the entry with the provided key.  The provided host paths are substituted
+// by placeholders in the stored entry such that it can be restored on a host
+// whose sources are located elsewhere.  The entry is pushed to the remote
+// repository if the cache was instantiated with WithPush.
+func (cache *BuildCache) Save(ctx context.Context, key digest.Digest, plat *ocispec.Platform, component, buildDir string, paths []string, hosts HostPaths) error {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	// The entry is staged such that the files of the build directory, which
+	// are still used by the build system, are not rewritten.
+	staging, err := os.MkdirTemp(buildDir, ".buildcache-*")
+	if err != nil {
+		return fmt.Errorf("could not create staging directory: %w", err)
+	}
+
+	defer os.RemoveAll(staging)
+
+	r := hosts.replacer(false)
+	for _, path := range paths {
+		if err := stage(filepath.Join(buildDir, path), filepath.Join(staging, path), r); err != nil {
+			return fmt.Errorf("could not stage %s: %w", path, err)
+		}
+	}
+
+	fullref := cache.reference(key)
+
+	manifest, err := oci.NewManifest(ctx, cache.handle)
+	if err != nil {
+		return fmt.Errorf("could not instantiate new manifest structure: %w", err)
+	}
+
+	for _, path := range paths {
+		layer, err := oci.NewLayerFromFile(ctx,
+			ocispec.MediaTypeImageLayer,
+			filepath.Join(staging, path),
+			path,
+		)
+		if err != nil {
+			return fmt.Errorf("could not create layer from %s: %w", path, err)
+		}
+
+		if _, err := manifest.AddLayer(ctx, layer); err != nil {
+			return fmt.Errorf("could not add layer to manifest: %w", err)
+		}
+	}
+
+	manifest.SetOS(ctx, plat.OS)
+	manifest.SetArchitecture(ctx, plat.Architecture)
+	manifest.SetAnnotation(ctx, AnnotationComponent, component)
+
+	index, err := oci.NewIndex(ctx, cache.handle)
+	if err != nil {
+		return fmt.Errorf("could not instantiate new index structure: %w", err)
+	}
+
+	index.SetAnnotation(ctx, AnnotationComponent, component)
+
+	if err := index.AddManifest(ctx, manifest); err != nil {
+		return fmt.Errorf("could not add manifest to index: %w", err)
+	}
+
+	desc, err := index.Save(ctx, fullref, nil)
+	if err != nil {
+		return fmt.Errorf("could not save build cache entry: %w", err)
+	}
+
+	log.G(ctx).
+		WithField("ref", fullref).
+		WithField("component", component).
+		Debug("saved to build cache")
+
+	if !cache.push {
+		return nil
+	}
+
+	if err := cache.handle.PushDescriptor(ctx, fullref, &desc); err != nil {
+		return fmt.Errorf("could not push build cache entry: %w", err)
+	}
+
+	return nil
+}
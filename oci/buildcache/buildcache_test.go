@@ -0,0 +1,146 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package buildcache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"kraftkit.sh/oci/handler"
+)
+
+func TestSaveRestore(t *testing.T) {
+	ctx := context.Background()
+
+	handle, err := handler.NewDirectoryHandler(t.TempDir(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := name.NewRepository("localhost/buildcache")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache := &BuildCache{
+		handle: handle,
+		repo:   repo,
+	}
+
+	plat := &ocispec.Platform{OS: "qemu", Architecture: "x86_64"}
+	key := Inputs{Toolchain: "gcc", Platform: "qemu", Architecture: "x86_64"}.Key("libfoo", "1.0")
+
+	// The sources and build directory of the saving host are located in
+	// different directories than the ones of the restoring host.
+	srcA := filepath.Join(t.TempDir(), "a", "unikraft")
+	buildA := filepath.Join(t.TempDir(), "a", "build")
+	srcB := filepath.Join(t.TempDir(), "b", "src", "unikraft")
+	buildB := filepath.Join(t.TempDir(), "b", "build")
+
+	cmd := func(src, build string) string {
+		return "cmd_" + build + "/libfoo/bar.o := gcc -I" + src + "/include -c " + src + "/lib/bar.c -o " + build + "/libfoo/bar.o\n" +
+			"deps_" + build + "/libfoo/bar.o := " + src + "/include/uk/config.h\n"
+	}
+
+	object := []byte("\x7fELF" + srcA + "\x00")
+
+	if err := os.MkdirAll(filepath.Join(buildA, "libfoo"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(buildA, "libfoo", "bar.o"), object, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(buildA, "libfoo", ".bar.o.cmd"), []byte(cmd(srcA, buildA)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cache.Save(ctx, key, plat, "libfoo", buildA, []string{"libfoo"}, HostPaths{
+		"O":       buildA,
+		"UK_BASE": srcA,
+	}); err != nil {
+		t.Fatalf("could not save entry: %v", err)
+	}
+
+	// Saving must not alter the files which are still used by the build system.
+	b, err := os.ReadFile(filepath.Join(buildA, "libfoo", ".bar.o.cmd"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(b) != cmd(srcA, buildA) {
+		t.Errorf("expected saved build directory to be unchanged, got:\n%s", b)
+	}
+
+	hit, err := cache.Restore(ctx, key, plat, buildB, HostPaths{
+		"O":       buildB,
+		"UK_BASE": srcB,
+	})
+	if err != nil {
+		t.Fatalf("could not restore entry: %v", err)
+	}
+
+	if !hit {
+		t.Fatal("expected build cache hit")
+	}
+
+	b, err = os.ReadFile(filepath.Join(buildB, "libfoo", ".bar.o.cmd"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(b) != cmd(srcB, buildB) {
+		t.Errorf("expected restored command file to refer to the restoring host:\nexpected:\n%s\ngot:\n%s", cmd(srcB, buildB), b)
+	}
+
+	if strings.Contains(string(b), "@@KRAFTKIT_") {
+		t.Errorf("expected all placeholders to be replaced, got:\n%s", b)
+	}
+
+	// Objects are restored verbatim.
+	b, err = os.ReadFile(filepath.Join(buildB, "libfoo", "bar.o"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(b) != string(object) {
+		t.Errorf("expected object to be restored verbatim")
+	}
+
+	other := Inputs{Toolchain: "clang", Platform: "qemu", Architecture: "x86_64"}.Key("libfoo", "1.0")
+	if hit, err := cache.Restore(ctx, other, plat, t.TempDir(), nil); err != nil || hit {
+		t.Errorf("expected build cache miss for unknown key, got hit=%t err=%v", hit, err)
+	}
+}
+
+func TestHostPathsReplacer(t *testing.T) {
+	hosts := HostPaths{
+		"UK_APP": "/home/a/app",
+		"O":      "/home/a/app/.unikraft/build",
+		"EMPTY":  "",
+	}
+
+	in := "/home/a/app/main.c /home/a/app/.unikraft/build/app/main.o"
+
+	saved := hosts.replacer(false).Replace(in)
+	if expected := "@@KRAFTKIT_UK_APP@@/main.c @@KRAFTKIT_O@@/app/main.o"; saved != expected {
+		t.Errorf("expected the longest host path to be replaced first:\nexpected: %s\ngot:      %s", expected, saved)
+	}
+
+	restored := HostPaths{
+		"UK_APP": "/runner/app",
+		"O":      "/tmp/build",
+	}.replacer(true).Replace(saved)
+	if expected := "/runner/app/main.c /tmp/build/app/main.o"; restored != expected {
+		t.Errorf("expected placeholders to be replaced:\nexpected: %s\ngot:      %s", expected, restored)
+	}
+}
@@ -0,0 +1,89 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package buildcache
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/opencontainers/go-digest"
+
+	"kraftkit.sh/kconfig"
+)
+
+// hostKConfigKeys are KConfig options which are written into the resolved
+// `.config` file but which only reflect the location of the sources on the
+// host performing the build.  They do not influence the resulting objects and
+// are therefore excluded from the key such that separate hosts (e.g. CI
+// runners) can share the same cache.
+var hostKConfigKeys = map[string]bool{
+	"CONFIG_UK_BASE":    true,
+	"CONFIG_UK_APP":     true,
+	"CONFIG_UK_DEFNAME": true,
+}
+
+// Inputs represent the inputs of a build which are shared by all components
+// of a target.
+type Inputs struct {
+	// KConfig is the fully resolved set of KConfig options of the target.
+	KConfig kconfig.KeyValueMap
+
+	// Toolchain identifies the compiler which is used to perform the build,
+	// e.g. the output of `gcc --version`.
+	Toolchain string
+
+	// Platform is the name of the platform of the target.
+	Platform string
+
+	// Architecture is the name of the architecture of the target.
+	Architecture string
+
+	// Components are the source and version of every resolved component of the
+	// target, in the format source@version, by component name.  They are part
+	// of the key of each component since a component is built against the
+	// headers of the core and of its dependencies.
+	Components map[string]string
+}
+
+// Key returns the content-addressed key of the objects of the provided
+// component which have been built with these inputs.
+func (inputs Inputs) Key(component, version string) digest.Digest {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "component=%s\n", component)
+	fmt.Fprintf(&b, "version=%s\n", version)
+	fmt.Fprintf(&b, "toolchain=%s\n", inputs.Toolchain)
+	fmt.Fprintf(&b, "platform=%s\n", inputs.Platform)
+	fmt.Fprintf(&b, "architecture=%s\n", inputs.Architecture)
+
+	names := make([]string, 0, len(inputs.Components))
+	for name := range inputs.Components {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(&b, "dependency=%s=%s\n", name, inputs.Components[name])
+	}
+
+	keys := make([]string, 0, len(inputs.KConfig))
+	for k := range inputs.KConfig {
+		if hostKConfigKeys[k] {
+			continue
+		}
+
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s=%s\n", k, inputs.KConfig[k].Value)
+	}
+
+	return digest.FromString(b.String())
+}
@@ -0,0 +1,65 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package buildcache
+
+import (
+	"testing"
+
+	"kraftkit.sh/kconfig"
+)
+
+func TestInputsKey(t *testing.T) {
+	inputs := func(values ...string) Inputs {
+		kvm := kconfig.KeyValueMap{}
+		for i := 0; i < len(values); i += 2 {
+			kvm.Set(values[i], values[i+1])
+		}
+
+		return Inputs{
+			KConfig:      kvm,
+			Toolchain:    "gcc (GCC) 12.2.0",
+			Platform:     "qemu",
+			Architecture: "x86_64",
+			Components: map[string]string{
+				"unikraft": "https://github.com/unikraft/unikraft.git@stable",
+				"lwip":     "https://github.com/unikraft/lib-lwip.git@stable",
+			},
+		}
+	}
+
+	base := inputs("CONFIG_LIBUKDEBUG", "y", "CONFIG_UK_BASE", "/home/a/unikraft").Key("unikraft", "stable")
+
+	if got := inputs("CONFIG_UK_BASE", "/runner/unikraft", "CONFIG_LIBUKDEBUG", "y").Key("unikraft", "stable"); got != base {
+		t.Errorf("expected host paths to be excluded from key: %s != %s", got, base)
+	}
+
+	if got := inputs("CONFIG_LIBUKDEBUG", "n").Key("unikraft", "stable"); got == base {
+		t.Errorf("expected KConfig change to alter key")
+	}
+
+	if got := inputs("CONFIG_LIBUKDEBUG", "y").Key("unikraft", "v0.17.0"); got == base {
+		t.Errorf("expected version change to alter key")
+	}
+
+	if got := inputs("CONFIG_LIBUKDEBUG", "y").Key("lwip", "stable"); got == base {
+		t.Errorf("expected component change to alter key")
+	}
+
+	lib := inputs("CONFIG_LIBUKDEBUG", "y").Key("lwip", "stable")
+
+	core := inputs("CONFIG_LIBUKDEBUG", "y")
+	core.Components["unikraft"] = "https://github.com/unikraft/unikraft.git@v0.17.0"
+
+	if got := core.Key("lwip", "stable"); got == lib {
+		t.Errorf("expected core version change to alter key of library")
+	}
+
+	dep := inputs("CONFIG_LIBUKDEBUG", "y")
+	dep.Components["musl"] = "https://github.com/unikraft/lib-musl.git@stable"
+
+	if got := dep.Key("lwip", "stable"); got == lib {
+		t.Errorf("expected additional dependency to alter key of library")
+	}
+}
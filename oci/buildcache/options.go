@@ -0,0 +1,18 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package buildcache
+
+// BuildCacheOption is an option which can be provided when instantiating a
+// new build cache.
+type BuildCacheOption func(*BuildCache) error
+
+// WithPush pushes every newly saved entry to the remote repository of the
+// build cache.
+func WithPush(push bool) BuildCacheOption {
+	return func(cache *BuildCache) error {
+		cache.push = push
+		return nil
+	}
+}
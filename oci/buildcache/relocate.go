@@ -0,0 +1,167 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package buildcache
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// HostPaths maps a stable name, e.g. `UK_BASE`, to the absolute path of the
+// corresponding directory on the host performing the build.  Unikraft's build
+// system records absolute paths in the dependency (`.d`) and command (`.cmd`)
+// files of each object.  Before an entry is saved, these paths are replaced by
+// a placeholder of their name and, when the entry is restored, the
+// placeholders are replaced by the paths of the restoring host.  Without this,
+// the build system would consider all restored objects to be out of date when
+// the sources are located elsewhere, e.g. on a different CI runner.
+type HostPaths map[string]string
+
+// relocatable returns whether the file at the provided path records the
+// absolute paths of the host which performed the build.
+func relocatable(path string) bool {
+	switch filepath.Ext(path) {
+	case ".cmd", ".d":
+		return true
+	}
+
+	return false
+}
+
+// placeholder returns the string which replaces the host path with the
+// provided name.
+func placeholder(name string) string {
+	return fmt.Sprintf("@@KRAFTKIT_%s@@", name)
+}
+
+// replacer returns the replacer which substitutes the host paths with their
+// placeholders or, if restore is set, the placeholders with the host paths.
+// Longer paths are replaced first such that e.g. the build directory is not
+// replaced as part of the application directory which contains it.
+func (hosts HostPaths) replacer(restore bool) *strings.Replacer {
+	names := make([]string, 0, len(hosts))
+	for name, path := range hosts {
+		if len(path) == 0 || path == string(filepath.Separator) {
+			continue
+		}
+
+		names = append(names, name)
+	}
+
+	sort.SliceStable(names, func(i, j int) bool {
+		if len(hosts[names[i]]) != len(hosts[names[j]]) {
+			return len(hosts[names[i]]) > len(hosts[names[j]])
+		}
+
+		return names[i] < names[j]
+	})
+
+	var oldnew []string
+	for _, name := range names {
+		path := filepath.Clean(hosts[name])
+		if restore {
+			oldnew = append(oldnew, placeholder(name), path)
+		} else {
+			oldnew = append(oldnew, path, placeholder(name))
+		}
+	}
+
+	return strings.NewReplacer(oldnew...)
+}
+
+// relocate rewrites the relocatable files within the provided directory in
+// place using the provided replacer.
+func relocate(dir string, r *strings.Replacer) error {
+	return filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !fi.Mode().IsRegular() || !relocatable(path) {
+			return nil
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		return os.WriteFile(path, []byte(r.Replace(string(b))), fi.Mode().Perm())
+	})
+}
+
+// stage mirrors the entry at src to dst whilst substituting the host paths
+// of the relocatable files with the provided replacer.  All other files are
+// hard linked where possible such that the objects are not copied.
+func stage(src, dst string, r *strings.Replacer) error {
+	return filepath.Walk(src, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case fi.IsDir():
+			return os.MkdirAll(target, fi.Mode().Perm())
+
+		case fi.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+
+			return os.Symlink(link, target)
+
+		case !fi.Mode().IsRegular():
+			return nil
+
+		case relocatable(path):
+			b, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+
+			return os.WriteFile(target, []byte(r.Replace(string(b))), fi.Mode().Perm())
+		}
+
+		if err := os.Link(path, target); err == nil {
+			return nil
+		}
+
+		return copyFile(path, target, fi.Mode().Perm())
+	})
+}
+
+// copyFile copies the regular file at src to dst.
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
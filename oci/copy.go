@@ -0,0 +1,395 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package oci
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/containerd/errdefs"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/content"
+
+	"kraftkit.sh/internal/version"
+	"kraftkit.sh/log"
+	"kraftkit.sh/oci/handler"
+	ociutils "kraftkit.sh/oci/utils"
+)
+
+// Location is the source or the destination of a copy.  It is a package which
+// is stored by the handler or, if no handler is set, a package in a remote
+// registry.
+type Location struct {
+	Handle handler.Handler
+	Ref    string
+}
+
+// String implements fmt.Stringer.
+func (loc Location) String() string {
+	if loc.Handle == nil {
+		return loc.Ref
+	}
+
+	return fmt.Sprintf("%s (local)", loc.Ref)
+}
+
+// Copy copies the package at the source location, including all of its
+// targets, to the destination location without unpacking it.  Only the blobs
+// which are missing at the destination are transferred, and packages are
+// copied between registries without being stored locally.
+func Copy(ctx context.Context, src, dst Location) error {
+	srcRef, err := parseRef(src.Ref)
+	if err != nil {
+		return fmt.Errorf("could not parse source reference: %w", err)
+	}
+
+	dstRef, err := parseRef(dst.Ref)
+	if err != nil {
+		return fmt.Errorf("could not parse destination reference: %w", err)
+	}
+
+	log.G(ctx).
+		WithField("src", src.String()).
+		WithField("dst", dst.String()).
+		Debug("copying")
+
+	switch {
+	case src.Handle == nil && dst.Handle == nil:
+		return copyRemote(ctx, srcRef, dstRef)
+
+	case src.Handle != nil && dst.Handle != nil:
+		return copyLocal(ctx, src.Handle, srcRef, dst.Handle, dstRef)
+
+	case src.Handle != nil:
+		return copyPush(ctx, src.Handle, srcRef, dstRef)
+
+	default:
+		return copyPull(ctx, srcRef, dst.Handle, dstRef)
+	}
+}
+
+// remoteOptions returns the options used to communicate with the registry of
+// the provided reference based on the authentication and registry details
+// which are set in the KraftKit configuration.
+func remoteOptions(ctx context.Context, ref name.Reference) ([]remote.Option, error) {
+	ropts, err := ociutils.RegistriesFromContext(ctx).RemoteOptions(ref.Context().RegistryStr())
+	if err != nil {
+		return nil, err
+	}
+
+	return append(ropts,
+		remote.WithContext(ctx),
+		remote.WithUserAgent(version.UserAgent()),
+	), nil
+}
+
+// copyRemote copies a package between two registries, or two repositories of
+// the same registry, as well as the artifacts which refer to it.  Blobs which
+// already exist at the destination are skipped.
+func copyRemote(ctx context.Context, srcRef, dstRef name.Reference) error {
+	srcOpts, err := remoteOptions(ctx, srcRef)
+	if err != nil {
+		return err
+	}
+
+	dstOpts, err := remoteOptions(ctx, dstRef)
+	if err != nil {
+		return err
+	}
+
+	desc, err := remote.Get(srcRef, srcOpts...)
+	if err != nil {
+		return fmt.Errorf("could not get %s: %w", srcRef.Name(), err)
+	}
+
+	subjects := []string{desc.Digest.String()}
+
+	switch {
+	case desc.MediaType.IsIndex():
+		index, err := desc.ImageIndex()
+		if err != nil {
+			return err
+		}
+
+		manifest, err := index.IndexManifest()
+		if err != nil {
+			return err
+		}
+
+		for _, m := range manifest.Manifests {
+			subjects = append(subjects, m.Digest.String())
+		}
+
+		if err := remote.WriteIndex(dstRef, index, dstOpts...); err != nil {
+			return fmt.Errorf("could not write %s: %w", dstRef.Name(), err)
+		}
+
+	case desc.MediaType.IsImage():
+		image, err := desc.Image()
+		if err != nil {
+			return err
+		}
+
+		if err := remote.Write(dstRef, image, dstOpts...); err != nil {
+			return fmt.Errorf("could not write %s: %w", dstRef.Name(), err)
+		}
+
+	default:
+		return fmt.Errorf("unsupported media type: %s", desc.MediaType)
+	}
+
+	// Copy signatures, SBOMs and other artifacts of the package.  Registries
+	// which do not support the referrers API are skipped.
+	for _, subject := range subjects {
+		referrers, err := remote.Referrers(srcRef.Context().Digest(subject), srcOpts...)
+		if err != nil {
+			log.G(ctx).
+				WithField("subject", subject).
+				Debugf("could not list referrers: %v", err)
+			continue
+		}
+
+		manifest, err := referrers.IndexManifest()
+		if err != nil {
+			return err
+		}
+
+		for _, referrer := range manifest.Manifests {
+			image, err := remote.Image(srcRef.Context().Digest(referrer.Digest.String()), srcOpts...)
+			if err != nil {
+				return fmt.Errorf("could not get referrer '%s': %w", referrer.Digest.String(), err)
+			}
+
+			if err := remote.Write(dstRef.Context().Digest(referrer.Digest.String()), image, dstOpts...); err != nil {
+				return fmt.Errorf("could not write referrer '%s': %w", referrer.Digest.String(), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// copyBlob copies the blob of the descriptor between handlers unless it
+// already exists at the destination.
+func copyBlob(ctx context.Context, from, to handler.Handler, fullref string, desc ocispec.Descriptor) error {
+	if info, _ := to.DigestInfo(ctx, desc.Digest); info != nil {
+		return nil
+	}
+
+	reader, err := from.ReadDigest(ctx, desc.Digest)
+	if err != nil {
+		return fmt.Errorf("could not read blob '%s': %w", desc.Digest.String(), err)
+	}
+
+	defer reader.Close()
+
+	if err := to.SaveDescriptor(ctx, fullref, desc, reader, nil); err != nil && !errors.Is(err, errdefs.ErrAlreadyExists) {
+		return fmt.Errorf("could not save blob '%s': %w", desc.Digest.String(), err)
+	}
+
+	return nil
+}
+
+// copyManifest copies the manifest of the descriptor as well as its config,
+// its layers and the artifacts which refer to it between handlers.
+func copyManifest(ctx context.Context, from, to handler.Handler, srcRef name.Reference, dst string, desc ocispec.Descriptor) error {
+	reader, err := from.ReadDigest(ctx, desc.Digest)
+	if err != nil {
+		return fmt.Errorf("could not read manifest '%s': %w", desc.Digest.String(), err)
+	}
+
+	raw, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		return fmt.Errorf("could not read manifest '%s': %w", desc.Digest.String(), err)
+	}
+
+	manifest := ocispec.Manifest{}
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return fmt.Errorf("could not unmarshal manifest '%s': %w", desc.Digest.String(), err)
+	}
+
+	// The manifest is saved before its blobs, as is done by Manifest.Save, such
+	// that containerd's garbage collector does not remove them in the meantime.
+	if info, _ := to.DigestInfo(ctx, desc.Digest); info == nil {
+		if err := to.SaveDescriptor(ctx, dst, ocispec.Descriptor{
+			MediaType:    ocispec.MediaTypeImageManifest,
+			ArtifactType: manifest.ArtifactType,
+			Digest:       desc.Digest,
+			Size:         int64(len(raw)),
+			Platform:     desc.Platform,
+			Annotations:  desc.Annotations,
+		}, bytes.NewReader(raw), nil); err != nil && !errors.Is(err, errdefs.ErrAlreadyExists) {
+			return fmt.Errorf("could not save manifest '%s': %w", desc.Digest.String(), err)
+		}
+	}
+
+	for _, blob := range append([]ocispec.Descriptor{manifest.Config}, manifest.Layers...) {
+		if err := copyBlob(ctx, from, to, dst, blob); err != nil {
+			return err
+		}
+	}
+
+	return copyReferrers(ctx, from, to, srcRef, dst, desc.Digest)
+}
+
+// copyReferrers copies the artifacts which refer to the subject between
+// handlers.
+func copyReferrers(ctx context.Context, from, to handler.Handler, srcRef name.Reference, dst string, subject digest.Digest) error {
+	referrers, err := from.ResolveReferrers(ctx, srcRef.Name(), subject, "")
+	if err != nil {
+		return fmt.Errorf("could not resolve referrers: %w", err)
+	}
+
+	dstRef, err := parseRef(dst)
+	if err != nil {
+		return err
+	}
+
+	for _, referrer := range referrers {
+		if err := copyManifest(ctx, from, to, srcRef, fmt.Sprintf("%s@%s", dstRef.Context().Name(), referrer.Digest.String()), referrer); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// copyLocal copies a package between handlers.
+func copyLocal(ctx context.Context, from handler.Handler, srcRef name.Reference, to handler.Handler, dstRef name.Reference) error {
+	spec, err := from.ResolveIndex(ctx, srcRef.Name())
+	if err != nil {
+		return fmt.Errorf("could not resolve %s: %w", srcRef.Name(), err)
+	}
+
+	for _, desc := range spec.Manifests {
+		if err := copyManifest(ctx, from, to, srcRef, dstRef.Name(), desc); err != nil {
+			return err
+		}
+	}
+
+	// The index is copied as-is if it retains its name such that its digest,
+	// and therefore any signature of it, remains valid.
+	if srcRef.Name() != dstRef.Name() {
+		index, err := NewIndexFromSpec(ctx, to, spec)
+		if err != nil {
+			return err
+		}
+
+		_, err = tagIndex(ctx, to, index, dstRef.Name())
+		return err
+	}
+
+	raw, err := json.Marshal(spec)
+	if err != nil {
+		return fmt.Errorf("could not marshal index: %w", err)
+	}
+
+	desc := content.NewDescriptorFromBytes(ocispec.MediaTypeImageIndex, raw)
+	desc.Annotations = spec.Annotations
+
+	if err := to.DeleteIndex(ctx, dstRef.Name(), false); err != nil {
+		return fmt.Errorf("could not remove existing index: %w", err)
+	}
+
+	if err := to.SaveDescriptor(ctx, dstRef.Name(), desc, bytes.NewReader(raw), nil); err != nil && !errors.Is(err, errdefs.ErrAlreadyExists) {
+		return fmt.Errorf("could not save index: %w", err)
+	}
+
+	return copyReferrers(ctx, from, to, srcRef, dstRef.Name(), desc.Digest)
+}
+
+// copyPush pushes a package from a handler to a registry.  A package which is
+// pushed under a different name is tagged locally for the duration of the push.
+func copyPush(ctx context.Context, handle handler.Handler, srcRef, dstRef name.Reference) error {
+	if srcRef.Name() != dstRef.Name() {
+		if _, err := handle.ResolveIndex(ctx, dstRef.Name()); err != nil {
+			defer func() {
+				if err := handle.DeleteIndex(ctx, dstRef.Name(), false); err != nil {
+					log.G(ctx).Debugf("could not remove temporary tag: %v", err)
+				}
+			}()
+		}
+
+		if _, err := Tag(ctx, handle, srcRef.Name(), dstRef.Name()); err != nil {
+			return err
+		}
+	}
+
+	index, err := NewIndexFromRef(ctx, handle, dstRef.Name())
+	if err != nil {
+		return err
+	}
+
+	desc, err := index.Descriptor()
+	if err != nil {
+		return err
+	}
+
+	if err := handle.PushDescriptor(ctx, dstRef.Name(), desc); err != nil {
+		return fmt.Errorf("could not push %s: %w", dstRef.Name(), err)
+	}
+
+	if err := pushReferrers(ctx, handle, dstRef.Name(), dstRef.Context().Name(), desc.Digest); err != nil {
+		return err
+	}
+
+	for _, manifest := range index.manifests {
+		if err := pushReferrers(ctx, handle, dstRef.Name(), dstRef.Context().Name(), manifest.desc.Digest); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// copyPull pulls all targets of a package from a registry into a handler.  A
+// package which is pulled under a different name is only retained under the
+// new name.
+func copyPull(ctx context.Context, srcRef name.Reference, handle handler.Handler, dstRef name.Reference) error {
+	ropts, err := remoteOptions(ctx, srcRef)
+	if err != nil {
+		return err
+	}
+
+	head, err := remote.Head(srcRef, ropts...)
+	if err != nil {
+		return fmt.Errorf("could not resolve %s: %w", srcRef.Name(), err)
+	}
+
+	if srcRef.Name() != dstRef.Name() {
+		if _, err := handle.ResolveIndex(ctx, srcRef.Name()); err != nil {
+			defer func() {
+				if err := handle.DeleteIndex(ctx, srcRef.Name(), false); err != nil {
+					log.G(ctx).Debugf("could not remove temporary tag: %v", err)
+				}
+			}()
+		}
+	}
+
+	if err := handle.PullDigest(ctx,
+		ocispec.MediaTypeImageIndex,
+		srcRef.Name(),
+		digest.Digest(head.Digest.String()),
+		&ocispec.Platform{},
+		nil,
+	); err != nil {
+		return fmt.Errorf("could not pull %s: %w", srcRef.Name(), err)
+	}
+
+	if srcRef.Name() == dstRef.Name() {
+		return nil
+	}
+
+	_, err = Tag(ctx, handle, srcRef.Name(), dstRef.Name())
+	return err
+}
@@ -0,0 +1,256 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package oci
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"kraftkit.sh/oci/handler"
+	"kraftkit.sh/packmanager"
+)
+
+// saveTestReferrer saves an artifact in the handler which refers to the
+// subject, e.g. a signature.
+func saveTestReferrer(t *testing.T, handle handler.Handler, repo string, subject ocispec.Descriptor) ocispec.Descriptor {
+	t.Helper()
+
+	ctx := context.Background()
+
+	// The config is an image configuration as the handler expects when the
+	// artifact is pushed.
+	config, err := json.Marshal(ocispec.Image{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	configDesc := ocispec.Descriptor{
+		MediaType: "application/vnd.unikraft.test.config+json",
+		Digest:    digest.FromBytes(config),
+		Size:      int64(len(config)),
+	}
+
+	raw, err := json.Marshal(ocispec.Manifest{
+		Versioned:    specs.Versioned{SchemaVersion: 2},
+		MediaType:    ocispec.MediaTypeImageManifest,
+		ArtifactType: configDesc.MediaType,
+		Config:       configDesc,
+		Layers:       []ocispec.Descriptor{},
+		Subject:      &subject,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	desc := ocispec.Descriptor{
+		MediaType:    ocispec.MediaTypeImageManifest,
+		ArtifactType: configDesc.MediaType,
+		Digest:       digest.FromBytes(raw),
+		Size:         int64(len(raw)),
+	}
+
+	fullref := repo + "@" + desc.Digest.String()
+
+	if err := handle.SaveDescriptor(ctx, fullref, configDesc, bytes.NewReader(config), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := handle.SaveDescriptor(ctx, fullref, desc, bytes.NewReader(raw), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	return desc
+}
+
+func TestCopyLocal(t *testing.T) {
+	ctx := context.Background()
+
+	from, err := handler.NewDirectoryHandler(t.TempDir(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	to, err := handler.NewDirectoryHandler(t.TempDir(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ref := "unikraft.org/helloworld:latest"
+	indexDesc := newTestPackage(t, from, ref, "x86_64", "x86_64 kernel")
+	signature := saveTestReferrer(t, from, "unikraft.org/helloworld", *indexDesc)
+
+	if err := Copy(ctx, Location{Handle: from, Ref: ref}, Location{Handle: to, Ref: ref}); err != nil {
+		t.Fatalf("could not copy: %v", err)
+	}
+
+	// A package which retains its name is copied as-is.
+	spec, err := to.ResolveIndex(ctx, ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := json.Marshal(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dgst := digest.FromBytes(raw); dgst != indexDesc.Digest {
+		t.Errorf("expected copied index to retain its digest %s, got %s", indexDesc.Digest, dgst)
+	}
+
+	manifest, err := to.ResolveManifest(ctx, ref, spec.Manifests[0].Digest)
+	if err != nil {
+		t.Fatalf("could not resolve copied manifest: %v", err)
+	}
+
+	for _, blob := range append([]ocispec.Descriptor{manifest.Config}, manifest.Layers...) {
+		if info, err := to.DigestInfo(ctx, blob.Digest); err != nil || info == nil {
+			t.Errorf("expected blob %s to be copied: %v", blob.Digest, err)
+		}
+	}
+
+	referrers, err := to.ResolveReferrers(ctx, ref, indexDesc.Digest, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(referrers) != 1 || referrers[0].Digest != signature.Digest {
+		t.Errorf("expected the signature %s to be copied, got %+v", signature.Digest, referrers)
+	}
+
+	// A package which is copied under a new name lists the same manifests.
+	renamed := "unikraft.org/hello:v1"
+	if err := Copy(ctx, Location{Handle: from, Ref: ref}, Location{Handle: to, Ref: renamed}); err != nil {
+		t.Fatalf("could not copy under a new name: %v", err)
+	}
+
+	expected := manifestDigests(t, from, ref)
+	if got := manifestDigests(t, to, renamed); !equalDigests(expected, got) {
+		t.Errorf("expected renamed copy to list the manifests %v, got %v", expected, got)
+	}
+}
+
+// newTestRegistry pushes a two-target package from a handler to an in-process
+// registry and returns the reference of the pushed package.
+func newTestRegistry(t *testing.T) string {
+	t.Helper()
+
+	ctx := context.Background()
+
+	server := httptest.NewServer(registry.New(registry.WithReferrersSupport(true)))
+	t.Cleanup(server.Close)
+
+	handle, err := handler.NewDirectoryHandler(t.TempDir(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	amd64 := host + "/unikraft.org/helloworld:x86_64"
+	arm64 := host + "/unikraft.org/helloworld:arm64"
+	fullref := host + "/unikraft.org/helloworld:latest"
+
+	newTestPackage(t, handle, amd64, "x86_64", "x86_64 kernel")
+	newTestPackage(t, handle, arm64, "arm64", "arm64 kernel")
+
+	desc, err := CreateIndex(ctx, handle, fullref, []string{amd64, arm64}, packmanager.StrategyMerge)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saveTestReferrer(t, handle, host+"/unikraft.org/helloworld", *desc)
+
+	if err := Copy(ctx, Location{Handle: handle, Ref: fullref}, Location{Ref: fullref}); err != nil {
+		t.Fatalf("could not push: %v", err)
+	}
+
+	return fullref
+}
+
+func TestCopyRemote(t *testing.T) {
+	ctx := context.Background()
+
+	src := newTestRegistry(t)
+	dst := strings.Replace(src, "unikraft.org/helloworld", "mirror/helloworld", 1)
+
+	if err := Copy(ctx, Location{Ref: src}, Location{Ref: dst}); err != nil {
+		t.Fatalf("could not copy between registries: %v", err)
+	}
+
+	srcRef, err := name.ParseReference(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dstRef, err := name.ParseReference(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srcDesc, err := remote.Head(srcRef)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dstDesc, err := remote.Head(dstRef)
+	if err != nil {
+		t.Fatalf("expected package to be copied: %v", err)
+	}
+
+	if srcDesc.Digest != dstDesc.Digest {
+		t.Errorf("expected copied index to retain its digest %s, got %s", srcDesc.Digest, dstDesc.Digest)
+	}
+
+	referrers, err := remote.Referrers(dstRef.Context().Digest(dstDesc.Digest.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err := referrers.IndexManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(manifest.Manifests) != 1 {
+		t.Errorf("expected the referrer to be copied, got %+v", manifest.Manifests)
+	}
+}
+
+func TestCopyPull(t *testing.T) {
+	ctx := context.Background()
+
+	src := newTestRegistry(t)
+
+	handle, err := handler.NewDirectoryHandler(t.TempDir(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := "unikraft.org/helloworld:pulled"
+
+	if err := Copy(ctx, Location{Ref: src}, Location{Handle: handle, Ref: dst}); err != nil {
+		t.Fatalf("could not pull: %v", err)
+	}
+
+	if got := manifestDigests(t, handle, dst); len(got) != 2 {
+		t.Errorf("expected all targets to be pulled, got %v", got)
+	}
+
+	// The package is only retained under its new name.
+	if _, err := handle.ResolveIndex(ctx, src); err == nil {
+		t.Errorf("expected the source reference %s not to be retained", src)
+	}
+}
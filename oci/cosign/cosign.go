@@ -0,0 +1,89 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+// Package cosign signs and verifies OCI images using signatures which are
+// compatible with Sigstore's cosign.  Signatures are stored as OCI referrers
+// of the signed image, i.e. as manifests whose subject is the signed index or
+// manifest, and contain cosign's "simple signing" payload as their only layer.
+package cosign
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/opencontainers/go-digest"
+)
+
+const (
+	// ArtifactTypeSignature is the artifact type of manifests which contain
+	// signatures.
+	ArtifactTypeSignature = "application/vnd.dev.cosign.artifact.sig.v1+json"
+
+	// MediaTypeSimpleSigning is the media type of the layer which contains the
+	// signed payload.
+	MediaTypeSimpleSigning = "application/vnd.dev.cosign.simplesigning.v1+json"
+
+	// AnnotationSignature contains the base64-encoded signature of the payload.
+	AnnotationSignature = "dev.cosignproject.cosign/signature"
+
+	// AnnotationCertificate contains the PEM-encoded certificate of the signer
+	// for signatures which have been created without a key pair.
+	AnnotationCertificate = "dev.sigstore.cosign/certificate"
+
+	// AnnotationChain contains the PEM-encoded chain of intermediate
+	// certificates of the signer's certificate.
+	AnnotationChain = "dev.sigstore.cosign/chain"
+
+	// payloadType is the type of cosign's simple signing payload.
+	payloadType = "cosign container image signature"
+)
+
+// Payload is cosign's "simple signing" payload which is signed in order to
+// attest to the digest of an image.
+type Payload struct {
+	Critical struct {
+		Identity struct {
+			DockerReference string `json:"docker-reference"`
+		} `json:"identity"`
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+	Optional map[string]interface{} `json:"optional"`
+}
+
+// NewPayload returns the serialized payload which attests to the image with the
+// provided digest within the provided repository, e.g. `ghcr.io/acme/app`.
+func NewPayload(repository string, dgst digest.Digest, optional map[string]interface{}) ([]byte, error) {
+	payload := Payload{
+		Optional: optional,
+	}
+
+	payload.Critical.Identity.DockerReference = repository
+	payload.Critical.Image.DockerManifestDigest = dgst.String()
+	payload.Critical.Type = payloadType
+
+	return json.Marshal(payload)
+}
+
+// ParsePayload parses the provided payload and checks that it attests to the
+// image with the provided digest.
+func ParsePayload(raw []byte, dgst digest.Digest) (*Payload, error) {
+	payload := Payload{}
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil, fmt.Errorf("could not unmarshal payload: %w", err)
+	}
+
+	if payload.Critical.Type != payloadType {
+		return nil, fmt.Errorf("unsupported payload type: %s", payload.Critical.Type)
+	}
+
+	if payload.Critical.Image.DockerManifestDigest != dgst.String() {
+		return nil, fmt.Errorf("payload attests to %s and not %s", payload.Critical.Image.DockerManifestDigest, dgst.String())
+	}
+
+	return &payload, nil
+}
@@ -0,0 +1,318 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package cosign
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"kraftkit.sh/oci/handler"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	password := []byte("secret")
+	privPEM, pubPEM, err := GenerateKeyPair(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pubPath := filepath.Join(dir, "cosign.pub")
+	if err := os.WriteFile(pubPath, pubPEM, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadPrivateKey(privPEM, []byte("wrong")); err == nil {
+		t.Fatal("expected decrypting the private key with the wrong password to fail")
+	}
+
+	signer, err := LoadPrivateKey(privPEM, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handle, err := handler.NewDirectoryHandler(filepath.Join(dir, "oci"), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subjectRaw := []byte(`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.index.v1+json","manifests":[]}`)
+	subject := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Digest:    digest.FromBytes(subjectRaw),
+		Size:      int64(len(subjectRaw)),
+	}
+
+	repository := "unikraft.org/helloworld"
+
+	desc, err := Sign(ctx, handle, repository, subject, signer, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	referrers, err := handle.ResolveReferrers(ctx, repository, subject.Digest, ArtifactTypeSignature)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(referrers) != 1 || referrers[0].Digest != desc.Digest {
+		t.Fatalf("expected signature %s to be a referrer of the subject, got %v", desc.Digest, referrers)
+	}
+
+	manifest, err := handle.ResolveManifest(ctx, repository, desc.Digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(manifest.Layers) != 1 {
+		t.Fatalf("expected 1 layer, got %d", len(manifest.Layers))
+	}
+
+	layer := manifest.Layers[0]
+	payload, err := os.ReadFile(filepath.Join(dir, "oci", handler.DirectoryHandlerDigestsDir, layer.Digest.Algorithm().String(), layer.Digest.Encoded()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := Requirement{Keys: []string{pubPath}}
+	if err := req.Verify(subject.Digest, payload, layer.Annotations); err != nil {
+		t.Fatalf("expected signature to verify: %v", err)
+	}
+
+	if err := req.Verify(digest.FromString("other"), payload, layer.Annotations); err == nil {
+		t.Fatal("expected signature of another digest to be rejected")
+	}
+
+	_, otherPEM, err := GenerateKeyPair(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherPath := filepath.Join(dir, "other.pub")
+	if err := os.WriteFile(otherPath, otherPEM, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	req = Requirement{Keys: []string{otherPath}}
+	if err := req.Verify(subject.Digest, payload, layer.Annotations); err == nil {
+		t.Fatal("expected signature by an untrusted key to be rejected")
+	}
+}
+
+func TestPolicyRequirement(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "policy.yaml")
+
+	if err := os.WriteFile(path, []byte(`
+default:
+  reject: true
+repositories:
+  index.unikraft.io/*:
+    keys:
+      - unikraft.pub
+  index.unikraft.io/unikraft.org/*:
+    keys:
+      - /etc/kraftkit/unikraft.pub
+  ghcr.io/acme/app:
+    keys: []
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	policy, err := LoadPolicy(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		repository string
+		reject     bool
+		keys       []string
+	}{
+		{repository: "index.unikraft.io/unikraft.org/nginx", keys: []string{"/etc/kraftkit/unikraft.pub"}},
+		{repository: "index.unikraft.io/acme/nginx", keys: []string{filepath.Join(dir, "unikraft.pub")}},
+		{repository: "ghcr.io/acme/app"},
+		{repository: "docker.io/library/nginx", reject: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.repository, func(t *testing.T) {
+			req := policy.Requirement(tc.repository)
+			if req.Reject != tc.reject {
+				t.Fatalf("expected reject to be %v", tc.reject)
+			}
+
+			if len(req.Keys) != len(tc.keys) {
+				t.Fatalf("expected keys %v, got %v", tc.keys, req.Keys)
+			}
+
+			for i := range tc.keys {
+				if req.Keys[i] != tc.keys[i] {
+					t.Fatalf("expected keys %v, got %v", tc.keys, req.Keys)
+				}
+			}
+		})
+	}
+
+	var nilPolicy *Policy
+	if nilPolicy.Requirement("docker.io/library/nginx").Verifies() {
+		t.Fatal("expected the empty policy to accept all images")
+	}
+}
+
+func TestPolicyRejectsKeyless(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte(`
+repositories:
+  ghcr.io/acme/app:
+    keyless:
+      - issuer: https://token.actions.githubusercontent.com
+        subject: https://github.com/acme/app/.*
+        roots: fulcio.pem
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only key pairs are supported, such that a policy which lists keyless
+	// identities must not load rather than accept images unverified.
+	if _, err := LoadPolicy(path); err == nil {
+		t.Fatal("expected keyless policy to be rejected")
+	}
+}
+
+func TestSignPulledAndVerify(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	server := httptest.NewServer(registry.New(registry.WithReferrersSupport(true)))
+	defer server.Close()
+
+	fullref := strings.TrimPrefix(server.URL, "http://") + "/unikraft.org/helloworld:latest"
+
+	ref, err := name.ParseReference(fullref)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Push a package for two platforms of which only one is pulled, such that
+	// the local index differs from the one served by the registry.
+	index := mutate.IndexMediaType(empty.Index, types.OCIImageIndex)
+	for _, plat := range []string{"qemu", "fc"} {
+		image, err := mutate.ConfigFile(
+			mutate.ConfigMediaType(mutate.MediaType(empty.Image, types.OCIManifestSchema1), types.OCIConfigJSON),
+			&v1.ConfigFile{OS: plat, Architecture: "x86_64"},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		image, err = mutate.AppendLayers(image, static.NewLayer([]byte(plat+" kernel"), types.OCILayer))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		index = mutate.AppendManifests(index, mutate.IndexAddendum{
+			Add: image,
+			Descriptor: v1.Descriptor{
+				Platform: &v1.Platform{OS: plat, Architecture: "x86_64"},
+			},
+		})
+	}
+
+	if err := remote.WriteIndex(ref, index); err != nil {
+		t.Fatal(err)
+	}
+
+	remoteDigest, err := index.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handle, err := handler.NewDirectoryHandler(filepath.Join(dir, "oci"), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := handle.PullDigest(ctx,
+		ocispec.MediaTypeImageIndex,
+		fullref,
+		digest.Digest(remoteDigest.String()),
+		&ocispec.Platform{OS: "qemu", Architecture: "x86_64"},
+		nil,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	subject, err := ResolveSubject(ctx, handle, ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if subject.Digest.String() != remoteDigest.String() {
+		t.Fatalf("expected subject to be the index of the registry %s, got %s", remoteDigest, subject.Digest)
+	}
+
+	password := []byte("secret")
+	privPEM, pubPEM, err := GenerateKeyPair(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pubPath := filepath.Join(dir, "cosign.pub")
+	if err := os.WriteFile(pubPath, pubPEM, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := LoadPrivateKey(privPEM, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	desc, err := Sign(ctx, handle, ref.Context().Name(), subject, signer, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := handle.PushDescriptor(ctx, ref.Context().Name()+"@"+desc.Digest.String(), desc); err != nil {
+		t.Fatalf("could not push signature: %v", err)
+	}
+
+	policy := &Policy{Default: Requirement{Keys: []string{pubPath}}}
+
+	verified, err := policy.Verify(ctx, handle, ref)
+	if err != nil {
+		t.Fatalf("expected pulled and signed package to verify: %v", err)
+	}
+
+	if verified.String() != remoteDigest.String() {
+		t.Fatalf("expected verified digest %s, got %s", remoteDigest, verified)
+	}
+
+	// Updating the tag in the registry invalidates the pulled index.
+	if err := remote.WriteIndex(ref, mutate.IndexMediaType(empty.Index, types.OCIImageIndex)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ResolveSubject(ctx, handle, ref); err == nil {
+		t.Fatal("expected resolving an outdated pulled index to fail")
+	}
+}
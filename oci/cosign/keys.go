@@ -0,0 +1,168 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package cosign
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+
+	"github.com/secure-systems-lab/go-securesystemslib/encrypted"
+)
+
+const (
+	// PrivateKeyPEMType is the PEM block type of encrypted private keys.
+	PrivateKeyPEMType = "ENCRYPTED SIGSTORE PRIVATE KEY"
+
+	// legacyPrivateKeyPEMType is the PEM block type of encrypted private keys
+	// which have been generated by older versions of cosign.
+	legacyPrivateKeyPEMType = "ENCRYPTED COSIGN PRIVATE KEY"
+
+	// PublicKeyPEMType is the PEM block type of public keys.
+	PublicKeyPEMType = "PUBLIC KEY"
+)
+
+// GenerateKeyPair generates a new ECDSA P-256 key pair and returns the
+// PEM-encoded private key, encrypted with the provided password, and public
+// key in the same format as `cosign generate-key-pair`.
+func GenerateKeyPair(password []byte) ([]byte, []byte, error) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not generate private key: %w", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not marshal private key: %w", err)
+	}
+
+	enc, err := encrypted.Encrypt(der, password)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not encrypt private key: %w", err)
+	}
+
+	pub, err := x509.MarshalPKIXPublicKey(priv.Public())
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not marshal public key: %w", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: PrivateKeyPEMType, Bytes: enc}),
+		pem.EncodeToMemory(&pem.Block{Type: PublicKeyPEMType, Bytes: pub}),
+		nil
+}
+
+// LoadPrivateKey decrypts the PEM-encoded private key with the provided
+// password.
+func LoadPrivateKey(raw, password []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("could not decode private key")
+	}
+
+	if block.Type != PrivateKeyPEMType && block.Type != legacyPrivateKeyPEMType {
+		return nil, fmt.Errorf("unsupported private key type: %s", block.Type)
+	}
+
+	der, err := encrypted.Decrypt(block.Bytes, password)
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt private key: %w", err)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse private key: %w", err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key: %T", key)
+	}
+
+	return signer, nil
+}
+
+// LoadPrivateKeyFromFile decrypts the PEM-encoded private key at the provided
+// path with the provided password.
+func LoadPrivateKeyFromFile(path string, password []byte) (crypto.Signer, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read private key: %w", err)
+	}
+
+	return LoadPrivateKey(raw, password)
+}
+
+// LoadPublicKey parses the PEM-encoded public key.
+func LoadPublicKey(raw []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("could not decode public key")
+	}
+
+	if block.Type != PublicKeyPEMType {
+		return nil, fmt.Errorf("unsupported public key type: %s", block.Type)
+	}
+
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// LoadPublicKeyFromFile parses the PEM-encoded public key at the provided
+// path.
+func LoadPublicKeyFromFile(path string) (crypto.PublicKey, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read public key: %w", err)
+	}
+
+	return LoadPublicKey(raw)
+}
+
+// signPayload signs the payload with the provided key.  ECDSA and RSA keys sign
+// the SHA-256 digest of the payload whilst Ed25519 keys sign the payload
+// itself, as is done by cosign.
+func signPayload(signer crypto.Signer, payload []byte) ([]byte, error) {
+	if _, ok := signer.Public().(ed25519.PublicKey); ok {
+		return signer.Sign(rand.Reader, payload, crypto.Hash(0))
+	}
+
+	sum := sha256.Sum256(payload)
+
+	return signer.Sign(rand.Reader, sum[:], crypto.SHA256)
+}
+
+// verifyPayload checks the signature of the payload with the provided key.
+func verifyPayload(pub crypto.PublicKey, payload, signature []byte) error {
+	sum := sha256.Sum256(payload)
+
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, sum[:], signature) {
+			return fmt.Errorf("invalid signature")
+		}
+
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], signature); err != nil {
+			return fmt.Errorf("invalid signature: %w", err)
+		}
+
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, payload, signature) {
+			return fmt.Errorf("invalid signature")
+		}
+
+	default:
+		return fmt.Errorf("unsupported public key: %T", pub)
+	}
+
+	return nil
+}
@@ -0,0 +1,174 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package cosign
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/opencontainers/go-digest"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrRejected is returned when the policy rejects an image outright.
+var ErrRejected = errors.New("rejected by signature policy")
+
+// Policy determines which signatures images must carry in order to be
+// accepted.  A policy is read from a YAML file, e.g.:
+//
+//	default:
+//	  reject: false
+//	repositories:
+//	  index.unikraft.io/unikraft.org/*:
+//	    keys:
+//	      - unikraft.pub
+//	  ghcr.io/acme/app:
+//	    keys:
+//	      - /etc/kraftkit/acme.pub
+//
+// Repository patterns ending in `/*` match all repositories beneath the
+// prefix, other patterns are matched with path.Match.  The most specific
+// matching pattern applies and the default requirement applies to images
+// which match no pattern.  Relative paths are resolved against the directory
+// containing the policy file.
+//
+// Only signatures created with key pairs are verified.  Keyless signatures,
+// whose short-lived certificates are issued by e.g. Sigstore's Fulcio, are not
+// supported, and policies with fields other than the above are rejected when
+// they are loaded rather than being enforced partially.
+type Policy struct {
+	Default      Requirement            `yaml:"default"`
+	Repositories map[string]Requirement `yaml:"repositories"`
+}
+
+// Requirement is the set of signers of which at least one must have signed an
+// image.  An image is accepted without verification if no keys are provided.
+type Requirement struct {
+	Reject bool     `yaml:"reject"`
+	Keys   []string `yaml:"keys"`
+}
+
+// LoadPolicy reads the policy at the provided path.  A nil policy, which
+// accepts every image, is returned if no path is provided.
+func LoadPolicy(path string) (*Policy, error) {
+	if len(path) == 0 {
+		return nil, nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read signature policy: %w", err)
+	}
+
+	policy := Policy{}
+	decoder := yaml.NewDecoder(bytes.NewReader(raw))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&policy); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("could not parse signature policy: %w", err)
+	}
+
+	dir := filepath.Dir(path)
+	policy.Default.resolve(dir)
+	for pattern, req := range policy.Repositories {
+		req.resolve(dir)
+		policy.Repositories[pattern] = req
+	}
+
+	return &policy, nil
+}
+
+// resolve makes all relative paths of the requirement relative to dir.
+func (req *Requirement) resolve(dir string) {
+	abs := func(p string) string {
+		if len(p) == 0 || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+
+	for i := range req.Keys {
+		req.Keys[i] = abs(req.Keys[i])
+	}
+}
+
+// Requirement returns the requirement which applies to the provided
+// repository, e.g. `index.unikraft.io/unikraft.org/nginx`.
+func (policy *Policy) Requirement(repository string) Requirement {
+	if policy == nil {
+		return Requirement{}
+	}
+
+	var match string
+	var found bool
+
+	for pattern := range policy.Repositories {
+		var ok bool
+		if prefix, wildcard := strings.CutSuffix(pattern, "/*"); wildcard {
+			ok = strings.HasPrefix(repository, prefix+"/")
+		} else {
+			ok, _ = path.Match(pattern, repository)
+		}
+
+		if ok && (!found || len(pattern) > len(match)) {
+			match = pattern
+			found = true
+		}
+	}
+
+	if !found {
+		return policy.Default
+	}
+
+	return policy.Repositories[match]
+}
+
+// Verifies returns whether the requirement demands images to be signed.
+func (req Requirement) Verifies() bool {
+	return len(req.Keys) > 0
+}
+
+// Verify checks that the payload attests to the provided digest and that it
+// has been signed by one of the signers of the requirement.  The signature is
+// read from the annotations of the payload's layer.
+func (req Requirement) Verify(dgst digest.Digest, payload []byte, annotations map[string]string) error {
+	if _, err := ParsePayload(payload, dgst); err != nil {
+		return err
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(annotations[AnnotationSignature])
+	if err != nil {
+		return fmt.Errorf("could not decode signature: %w", err)
+	}
+
+	var errs []error
+
+	for _, path := range req.Keys {
+		pub, err := LoadPublicKeyFromFile(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if err := verifyPayload(pub, payload, signature); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+
+		return nil
+	}
+
+	if len(errs) == 0 {
+		return fmt.Errorf("not signed by any trusted signer")
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,121 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package cosign
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"kraftkit.sh/oci/handler"
+)
+
+// Sign signs the subject, an index or manifest within the provided repository,
+// e.g. `ghcr.io/acme/app`, with the provided key.  The signature is stored via
+// the handler as a referrer of the subject and the descriptor of the signature
+// manifest is returned.
+func Sign(ctx context.Context, handle handler.Handler, repository string, subject ocispec.Descriptor, signer crypto.Signer, optional map[string]interface{}) (*ocispec.Descriptor, error) {
+	payload, err := NewPayload(repository, subject.Digest, optional)
+	if err != nil {
+		return nil, fmt.Errorf("could not create payload: %w", err)
+	}
+
+	signature, err := signPayload(signer, payload)
+	if err != nil {
+		return nil, fmt.Errorf("could not sign payload: %w", err)
+	}
+
+	layer := ocispec.Descriptor{
+		MediaType: MediaTypeSimpleSigning,
+		Digest:    digest.FromBytes(payload),
+		Size:      int64(len(payload)),
+		Annotations: map[string]string{
+			AnnotationSignature: base64.StdEncoding.EncodeToString(signature),
+		},
+	}
+
+	// The configuration lists the payload as its only layer such that the
+	// signature is a valid image and can be handled like any other.  Its media
+	// type is the artifact type, as registries which predate the artifactType
+	// field of manifests report the media type of the configuration instead.
+	configRaw, err := json.Marshal(ocispec.Image{
+		RootFS: ocispec.RootFS{
+			Type:    "layers",
+			DiffIDs: []digest.Digest{layer.Digest},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal config: %w", err)
+	}
+
+	manifestRaw, err := json.Marshal(ocispec.Manifest{
+		Versioned: specs.Versioned{
+			SchemaVersion: 2,
+		},
+		MediaType:    ocispec.MediaTypeImageManifest,
+		ArtifactType: ArtifactTypeSignature,
+		Config: ocispec.Descriptor{
+			MediaType: ArtifactTypeSignature,
+			Digest:    digest.FromBytes(configRaw),
+			Size:      int64(len(configRaw)),
+		},
+		Layers: []ocispec.Descriptor{layer},
+		Subject: &ocispec.Descriptor{
+			MediaType: subject.MediaType,
+			Digest:    subject.Digest,
+			Size:      subject.Size,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal manifest: %w", err)
+	}
+
+	return store(ctx, handle, repository, manifestRaw, configRaw, payload)
+}
+
+// store saves the payload, configuration and manifest of a signature via the
+// handler and returns the descriptor of the manifest.
+func store(ctx context.Context, handle handler.Handler, repository string, manifestRaw, configRaw, payload []byte) (*ocispec.Descriptor, error) {
+	manifest := ocispec.Manifest{}
+	if err := json.Unmarshal(manifestRaw, &manifest); err != nil {
+		return nil, fmt.Errorf("could not unmarshal signature manifest: %w", err)
+	}
+
+	desc := ocispec.Descriptor{
+		MediaType:    ocispec.MediaTypeImageManifest,
+		ArtifactType: manifest.ArtifactType,
+		Digest:       digest.FromBytes(manifestRaw),
+		Size:         int64(len(manifestRaw)),
+	}
+
+	fullref := fmt.Sprintf("%s@%s", repository, desc.Digest.String())
+
+	for _, layer := range manifest.Layers {
+		if layer.MediaType != MediaTypeSimpleSigning || layer.Digest != digest.FromBytes(payload) {
+			continue
+		}
+
+		if err := handle.SaveDescriptor(ctx, fullref, layer, bytes.NewReader(payload), nil); err != nil {
+			return nil, fmt.Errorf("could not save signature payload: %w", err)
+		}
+	}
+
+	if err := handle.SaveDescriptor(ctx, fullref, manifest.Config, bytes.NewReader(configRaw), nil); err != nil {
+		return nil, fmt.Errorf("could not save signature config: %w", err)
+	}
+
+	if err := handle.SaveDescriptor(ctx, fullref, desc, bytes.NewReader(manifestRaw), nil); err != nil {
+		return nil, fmt.Errorf("could not save signature manifest: %w", err)
+	}
+
+	return &desc, nil
+}
@@ -0,0 +1,251 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package cosign
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"kraftkit.sh/config"
+	"kraftkit.sh/internal/version"
+	"kraftkit.sh/log"
+	"kraftkit.sh/oci/handler"
+	ociutils "kraftkit.sh/oci/utils"
+)
+
+// remoteOptions returns the options used to communicate with the registry of
+// the provided reference based on the authentication and registry details
+// which are set in the KraftKit configuration.
+func remoteOptions(ctx context.Context, ref name.Reference) ([]remote.Option, error) {
+	ropts, err := ociutils.RegistriesFromContext(ctx).RemoteOptions(ref.Context().RegistryStr())
+	if err != nil {
+		return nil, err
+	}
+
+	return append(ropts,
+		remote.WithContext(ctx),
+		remote.WithUserAgent(version.UserAgent()),
+	), nil
+}
+
+// VerifyFromContext verifies the image at the provided reference against the
+// signature policy which is set in the KraftKit configuration.  See
+// Policy.Verify.
+func VerifyFromContext(ctx context.Context, handle handler.Handler, ref name.Reference) (digest.Digest, error) {
+	policy, err := LoadPolicy(config.G[config.KraftKit](ctx).SignaturePolicy)
+	if err != nil {
+		return "", err
+	}
+
+	return policy.Verify(ctx, handle, ref)
+}
+
+// Verify checks that the remote image at the provided reference has been
+// signed as demanded by the policy.  On success, the digest of the verified
+// image is returned such that it can be pulled without the risk of the
+// reference having been updated in the meantime, and the signature is stored
+// via the handler, if one is provided.  An empty digest is returned if the
+// policy does not demand the image to be signed.
+func (policy *Policy) Verify(ctx context.Context, handle handler.Handler, ref name.Reference) (digest.Digest, error) {
+	req := policy.Requirement(ref.Context().Name())
+	if req.Reject {
+		return "", fmt.Errorf("%s: %w", ref.Name(), ErrRejected)
+	}
+
+	if !req.Verifies() {
+		return "", nil
+	}
+
+	ropts, err := remoteOptions(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+
+	head, err := remote.Head(ref, ropts...)
+	if err != nil {
+		return "", fmt.Errorf("could not resolve %s: %w", ref.Name(), err)
+	}
+
+	subject := digest.Digest(head.Digest.String())
+
+	referrers, err := remote.Referrers(
+		ref.Context().Digest(subject.String()),
+		append(ropts, remote.WithFilter("artifactType", ArtifactTypeSignature))...,
+	)
+	if err != nil {
+		return "", fmt.Errorf("could not retrieve signatures of %s: %w", ref.Name(), err)
+	}
+
+	index, err := referrers.IndexManifest()
+	if err != nil {
+		return "", fmt.Errorf("could not retrieve signatures of %s: %w", ref.Name(), err)
+	}
+
+	var errs []error
+
+	for _, desc := range index.Manifests {
+		if desc.ArtifactType != ArtifactTypeSignature {
+			continue
+		}
+
+		if err := verifySignature(ctx, handle, ref, subject, req, desc.Digest.String(), ropts); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", desc.Digest.String(), err))
+			continue
+		}
+
+		log.G(ctx).
+			WithField("ref", ref.Name()).
+			WithField("digest", subject.String()).
+			WithField("signature", desc.Digest.String()).
+			Debug("verified signature")
+
+		return subject, nil
+	}
+
+	if len(errs) == 0 {
+		return "", fmt.Errorf("%s is not signed", ref.Name())
+	}
+
+	return "", fmt.Errorf("no valid signature for %s: %w", ref.Name(), errors.Join(errs...))
+}
+
+// verifySignature retrieves the signature manifest with the provided digest
+// and checks its payload against the requirement.
+func verifySignature(ctx context.Context, handle handler.Handler, ref name.Reference, subject digest.Digest, req Requirement, dgst string, ropts []remote.Option) error {
+	img, err := remote.Image(ref.Context().Digest(dgst), ropts...)
+	if err != nil {
+		return fmt.Errorf("could not retrieve signature: %w", err)
+	}
+
+	manifest, err := img.Manifest()
+	if err != nil {
+		return fmt.Errorf("could not retrieve signature manifest: %w", err)
+	}
+
+	var errs []error
+
+	for _, desc := range manifest.Layers {
+		if string(desc.MediaType) != MediaTypeSimpleSigning {
+			continue
+		}
+
+		layer, err := img.LayerByDigest(desc.Digest)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		rc, err := layer.Compressed()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		payload, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not read payload: %w", err))
+			continue
+		}
+
+		if digest.FromBytes(payload).String() != desc.Digest.String() {
+			errs = append(errs, fmt.Errorf("payload does not match digest %s", desc.Digest.String()))
+			continue
+		}
+
+		if err := req.Verify(subject, payload, desc.Annotations); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if handle != nil {
+			if err := storeRemote(ctx, handle, ref, img, payload); err != nil {
+				log.G(ctx).Warnf("could not store signature: %v", err)
+			}
+		}
+
+		return nil
+	}
+
+	if len(errs) == 0 {
+		return fmt.Errorf("no signed payload")
+	}
+
+	return errors.Join(errs...)
+}
+
+// storeRemote stores the verified remote signature via the handler such that
+// it is retained alongside the image.
+func storeRemote(ctx context.Context, handle handler.Handler, ref name.Reference, img v1.Image, payload []byte) error {
+	manifestRaw, err := img.RawManifest()
+	if err != nil {
+		return err
+	}
+
+	configRaw, err := img.RawConfigFile()
+	if err != nil {
+		return err
+	}
+
+	_, err = store(ctx, handle, ref.Context().Name(), manifestRaw, configRaw, payload)
+	return err
+}
+
+// ResolveSubject returns the descriptor of the image which is referenced by
+// ref.  Indexes which have been packaged locally are preferred over the remote
+// image such that they can be signed before they are pushed.  Indexes which
+// have been pulled are resolved from the registry, as only the index served by
+// the registry is verified, and must not have been updated since.
+func ResolveSubject(ctx context.Context, handle handler.Handler, ref name.Reference) (ocispec.Descriptor, error) {
+	var pulled string
+
+	if _, ok := ref.(name.Tag); ok && handle != nil {
+		if index, err := handle.ResolveIndex(ctx, ref.Name()); err == nil {
+			pulled = index.Annotations[handler.AnnotationPulledIndex]
+
+			if len(pulled) == 0 {
+				raw, err := json.Marshal(index)
+				if err != nil {
+					return ocispec.Descriptor{}, fmt.Errorf("could not marshal index: %w", err)
+				}
+
+				return ocispec.Descriptor{
+					MediaType: ocispec.MediaTypeImageIndex,
+					Digest:    digest.FromBytes(raw),
+					Size:      int64(len(raw)),
+				}, nil
+			}
+		}
+	}
+
+	ropts, err := remoteOptions(ctx, ref)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
+	head, err := remote.Head(ref, ropts...)
+	if err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("could not resolve %s: %w", ref.Name(), err)
+	}
+
+	if len(pulled) > 0 && head.Digest.String() != pulled {
+		return ocispec.Descriptor{}, fmt.Errorf("%s has been updated since it was pulled: pull it again before signing", ref.Name())
+	}
+
+	return ocispec.Descriptor{
+		MediaType: string(head.MediaType),
+		Digest:    digest.Digest(head.Digest.String()),
+		Size:      head.Size,
+	}, nil
+}
@@ -0,0 +1,56 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package oci
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"kraftkit.sh/config"
+	"kraftkit.sh/log"
+	"kraftkit.sh/oci/handler"
+)
+
+// NewHandlerFromContext uses the KraftKit configuration embedded within the
+// provided context to instantiate the OCI handler which should be used to
+// store OCI artifacts.  A containerd handler is used if an address to the
+// containerd daemon is set, otherwise a directory handler is instantiated
+// within the runtime directory.  The returned context must be used in
+// subsequent calls to the handler.
+func NewHandlerFromContext(ctx context.Context) (context.Context, handler.Handler, error) {
+	if contAddr := config.G[config.KraftKit](ctx).ContainerdAddr; len(contAddr) > 0 {
+		namespace := DefaultNamespace
+		if n := os.Getenv("CONTAINERD_NAMESPACE"); n != "" {
+			namespace = n
+		}
+
+		log.G(ctx).
+			WithField("addr", contAddr).
+			WithField("namespace", namespace).
+			Debug("using containerd handler")
+
+		return handler.NewContainerdHandler(ctx, contAddr, namespace, config.G[config.KraftKit](ctx).Auth, config.G[config.KraftKit](ctx).Registries)
+	}
+
+	if err := os.MkdirAll(config.G[config.KraftKit](ctx).RuntimeDir, fs.ModeSetgid|0o775); err != nil {
+		return nil, nil, fmt.Errorf("could not create local oci cache directory: %w", err)
+	}
+
+	ociDir := filepath.Join(config.G[config.KraftKit](ctx).RuntimeDir, "oci")
+
+	log.G(ctx).
+		WithField("path", ociDir).
+		Trace("directory handler")
+
+	handle, err := handler.NewDirectoryHandler(ociDir, config.G[config.KraftKit](ctx).Auth, config.G[config.KraftKit](ctx).Registries)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ctx, handle, nil
+}
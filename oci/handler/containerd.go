@@ -11,6 +11,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"strings"
 	"time"
 
@@ -20,9 +21,9 @@ import (
 	"github.com/containerd/containerd/labels"
 	"github.com/containerd/containerd/namespaces"
 	"github.com/containerd/containerd/remotes"
+	"github.com/containerd/containerd/remotes/docker"
 	"github.com/containerd/errdefs"
 	clog "github.com/containerd/log"
-	"github.com/containerd/nerdctl/pkg/imgutil/dockerconfigresolver"
 	"github.com/containerd/platforms"
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/opencontainers/go-digest"
@@ -31,6 +32,7 @@ import (
 	"kraftkit.sh/archive"
 	"kraftkit.sh/config"
 	"kraftkit.sh/log"
+	ociutils "kraftkit.sh/oci/utils"
 )
 
 const (
@@ -38,17 +40,18 @@ const (
 	ContainerdGCManifestPrefix = "containerd.io/gc.ref.content.m"
 	KraftKitLabelPrefix        = "kraftkit.sh/oci."
 	KraftKitLabelMediaType     = KraftKitLabelPrefix + "mediaType"
+	KraftKitLabelSubject       = KraftKitLabelPrefix + "subject"
 )
 
 type ContainerdHandler struct {
-	client    *containerd.Client
-	namespace string
-	auths     map[string]config.AuthConfig
+	client     *containerd.Client
+	namespace  string
+	registries ociutils.Registries
 }
 
 // NewContainerdHandler creates a Resolver-compatible interface given the
 // containerd address and namespace.
-func NewContainerdHandler(ctx context.Context, address, namespace string, auths map[string]config.AuthConfig, opts ...containerd.ClientOpt) (context.Context, *ContainerdHandler, error) {
+func NewContainerdHandler(ctx context.Context, address, namespace string, auths map[string]config.AuthConfig, registries map[string]config.RegistryConfig, opts ...containerd.ClientOpt) (context.Context, *ContainerdHandler, error) {
 	client, err := containerd.New(address, opts...)
 	if err != nil {
 		return nil, nil, err
@@ -67,7 +70,10 @@ func NewContainerdHandler(ctx context.Context, address, namespace string, auths
 	return ctx, &ContainerdHandler{
 		client:    client,
 		namespace: namespace,
-		auths:     auths,
+		registries: ociutils.Registries{
+			Auths:   auths,
+			Configs: registries,
+		},
 	}, nil
 }
 
@@ -81,6 +87,80 @@ func NewContainerdWithClient(ctx context.Context, client *containerd.Client) (co
 	return ctx, &ContainerdHandler{client: client}, nil
 }
 
+// hosts returns the hosts which serve the registry at the provided domain in
+// the order in which they are tried: each of its mirrors, which are only
+// pulled and resolved from, followed by the registry itself.
+func (handle *ContainerdHandler) hosts(domain string) ([]docker.RegistryHost, error) {
+	mirrors, err := handle.registries.Mirrors(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	var hosts []docker.RegistryHost
+
+	for _, mirror := range mirrors {
+		host, err := handle.registryHost(mirror.Registry.RegistryStr(), mirror.Prefix,
+			docker.HostCapabilityPull|docker.HostCapabilityResolve,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		hosts = append(hosts, host)
+	}
+
+	host, err := handle.registryHost(domain, "",
+		docker.HostCapabilityPull|docker.HostCapabilityResolve|docker.HostCapabilityPush,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(hosts, host), nil
+}
+
+// registryHost returns the host of the registry at the provided domain whose
+// repositories are served beneath the provided path prefix.
+func (handle *ContainerdHandler) registryHost(domain, prefix string, capabilities docker.HostCapabilities) (docker.RegistryHost, error) {
+	transport, err := handle.registries.Transport(domain)
+	if err != nil {
+		return docker.RegistryHost{}, err
+	}
+
+	client := &http.Client{Transport: transport}
+
+	scheme := "https"
+	if handle.registries.Config(domain).PlainHTTP {
+		scheme = "http"
+	} else if local, err := docker.MatchLocalhost(domain); err == nil && local {
+		scheme = "http"
+	}
+
+	path := "/v2"
+	if len(prefix) > 0 {
+		path = "/v2/" + prefix
+	}
+
+	return docker.RegistryHost{
+		Client: client,
+		Authorizer: docker.NewDockerAuthorizer(
+			docker.WithAuthClient(client),
+			docker.WithAuthCreds(func(host string) (string, string, error) {
+				auth, ok := handle.registries.Auths[host]
+				if !ok {
+					return "", "", nil
+				}
+
+				return auth.User, auth.Token, nil
+			}),
+		),
+		Host:         domain,
+		Scheme:       scheme,
+		Path:         path,
+		Capabilities: capabilities,
+	}, nil
+}
+
 // lease creates a lease which can be closed to enable asynchronous
 // communication with containerd
 func (handle *ContainerdHandler) lease(ctx context.Context) (context.Context, func(context.Context) error, error) {
@@ -114,6 +194,29 @@ func (handle *ContainerdHandler) DigestInfo(ctx context.Context, dgst digest.Dig
 	return &info, nil
 }
 
+// ReadDigest implements DigestReader.
+func (handle *ContainerdHandler) ReadDigest(ctx context.Context, dgst digest.Digest) (io.ReadCloser, error) {
+	ra, err := handle.client.ContentStore().ReaderAt(
+		namespaces.WithNamespace(ctx, handle.namespace),
+		ocispec.Descriptor{Digest: dgst},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &readerAtCloser{
+		Reader: io.NewSectionReader(ra, 0, ra.Size()),
+		Closer: ra,
+	}, nil
+}
+
+// readerAtCloser closes the underlying content.ReaderAt once the sequential
+// reader has been consumed.
+type readerAtCloser struct {
+	io.Reader
+	io.Closer
+}
+
 // PullDigest implements DigestPuller.
 func (handle *ContainerdHandler) PullDigest(ctx context.Context, mediaType, fullref string, dgst digest.Digest, plat *ocispec.Platform, onProgress func(float64)) error {
 	progress := make(chan struct{})
@@ -124,22 +227,9 @@ func (handle *ContainerdHandler) PullDigest(ctx context.Context, mediaType, full
 		close(progress)
 	}()
 
-	resolver, err := dockerconfigresolver.New(
-		ctx,
-		strings.Split(fullref, "/")[0],
-		dockerconfigresolver.WithSkipVerifyCerts(true),
-		dockerconfigresolver.WithAuthCreds(func(domain string) (string, string, error) {
-			auth, ok := handle.auths[domain]
-			if !ok {
-				return "", "", nil
-			}
-
-			return auth.User, auth.Token, nil
-		}),
-	)
-	if err != nil {
-		return err
-	}
+	resolver := docker.NewResolver(docker.ResolverOptions{
+		Hosts: handle.hosts,
+	})
 
 	ctx, done, err := handle.lease(ctx)
 	if err != nil {
@@ -350,6 +440,12 @@ func (handle *ContainerdHandler) SaveDescriptor(ctx context.Context, fullref str
 
 		labels[fmt.Sprintf("%s.%d", ContainerdGCLayerPrefix, len(manifest.Layers))] = manifest.Config.Digest.String()
 
+		// Record the subject of the manifest such that it can be found as one of
+		// its referrers.
+		if manifest.Subject != nil {
+			labels[KraftKitLabelSubject] = manifest.Subject.Digest.String()
+		}
+
 		updatedFields := make([]string, 0)
 
 		for k, v := range labels {
@@ -435,6 +531,71 @@ func (handle *ContainerdHandler) DeleteManifest(ctx context.Context, fullref str
 	return cs.Delete(ctx, dgst)
 }
 
+// ResolveReferrers implements ReferrersResolver.
+func (handle *ContainerdHandler) ResolveReferrers(ctx context.Context, _ string, subject digest.Digest, artifactType string) (referrers []ocispec.Descriptor, err error) {
+	ctx, done, err := handle.lease(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		err = errors.Join(err, done(ctx))
+	}()
+
+	cs := handle.client.ContentStore()
+
+	if err := cs.Walk(ctx, func(info content.Info) error {
+		if info.Labels[KraftKitLabelSubject] != subject.String() {
+			return nil // Do not return an error, simply "continue"
+		}
+
+		readerAt, err := cs.ReaderAt(ctx, ocispec.Descriptor{
+			Digest: info.Digest,
+		})
+		if err != nil {
+			return err
+		}
+
+		defer readerAt.Close()
+
+		blob, err := readBlob(readerAt)
+		if err != nil {
+			return nil // Do not return an error, simply "continue"
+		}
+
+		manifest := ocispec.Manifest{}
+		if err := json.Unmarshal(blob, &manifest); err != nil {
+			return nil // Do not return an error, simply "continue"
+		}
+
+		desc := ocispec.Descriptor{
+			MediaType:    ocispec.MediaTypeImageManifest,
+			ArtifactType: manifest.ArtifactType,
+			Digest:       info.Digest,
+			Size:         info.Size,
+			Annotations:  manifest.Annotations,
+		}
+
+		// As per the OCI distribution specification, the artifact type of a
+		// manifest without one is the media type of its config.
+		if len(desc.ArtifactType) == 0 {
+			desc.ArtifactType = manifest.Config.MediaType
+		}
+
+		if len(artifactType) > 0 && desc.ArtifactType != artifactType {
+			return nil
+		}
+
+		referrers = append(referrers, desc)
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return referrers, nil
+}
+
 // ResolveIndex implements IndexResolver.
 func (handle *ContainerdHandler) ResolveIndex(ctx context.Context, fullref string) (*ocispec.Index, error) {
 	ctx, done, err := handle.lease(ctx)
@@ -734,22 +895,9 @@ outer:
 
 // PushDescriptor implements DescriptorPusher.
 func (handle *ContainerdHandler) PushDescriptor(ctx context.Context, ref string, target *ocispec.Descriptor) error {
-	resolver, err := dockerconfigresolver.New(
-		ctx,
-		strings.Split(ref, "/")[0],
-		dockerconfigresolver.WithSkipVerifyCerts(true),
-		dockerconfigresolver.WithAuthCreds(func(domain string) (string, string, error) {
-			auth, ok := handle.auths[domain]
-			if !ok {
-				return "", "", nil
-			}
-
-			return auth.User, auth.Token, nil
-		}),
-	)
-	if err != nil {
-		return err
-	}
+	resolver := docker.NewResolver(docker.ResolverOptions{
+		Hosts: handle.hosts,
+	})
 
 	return handle.client.Push(
 		namespaces.WithNamespace(ctx, handle.namespace),
@@ -786,17 +934,30 @@ func (handle *ContainerdHandler) UnpackImage(ctx context.Context, ref string, dg
 		platforms.Only(*manifest.Config.Platform),
 	)
 
-	if err = i.Unpack(ctx, containerd.DefaultSnapshotter); err != nil {
-		return nil, err
+	// Layers with Unikraft-specific media types, e.g. those which contain the
+	// library objects or the source files of the unikernel, cannot be applied by
+	// the snapshotter and are only extracted to the destination below.
+	snapshottable := true
+	for _, layer := range manifest.Layers {
+		if !images.IsLayerType(layer.MediaType) {
+			snapshottable = false
+			break
+		}
 	}
 
-	isUnpacked, err := i.IsUnpacked(ctx, containerd.DefaultSnapshotter)
-	if err != nil {
-		return nil, err
-	}
+	if snapshottable {
+		if err = i.Unpack(ctx, containerd.DefaultSnapshotter); err != nil {
+			return nil, err
+		}
 
-	if !isUnpacked {
-		return nil, fmt.Errorf("empty image")
+		isUnpacked, err := i.IsUnpacked(ctx, containerd.DefaultSnapshotter)
+		if err != nil {
+			return nil, err
+		}
+
+		if !isUnpacked {
+			return nil, fmt.Errorf("empty image")
+		}
 	}
 
 	// TODO(nderjung): This is where we could used media-types to extract the
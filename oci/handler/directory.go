@@ -6,13 +6,12 @@ package handler
 
 import (
 	"archive/tar"
+	"bytes"
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/fs"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -21,42 +20,49 @@ import (
 
 	"golang.org/x/sync/errgroup"
 	"kraftkit.sh/config"
-	"kraftkit.sh/internal/lockedfile"
 	"kraftkit.sh/internal/set"
 	"kraftkit.sh/internal/version"
 	"kraftkit.sh/log"
 	"kraftkit.sh/oci/cache"
-	"kraftkit.sh/oci/simpleauth"
 	ociutils "kraftkit.sh/oci/utils"
 
 	"github.com/containerd/containerd/content"
-	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
-	"github.com/google/go-containerregistry/pkg/v1/types"
 	"github.com/opencontainers/go-digest"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
 const (
-	DirectoryHandlerDigestsDir = "digests"
-	DirectoryHandlerIndexesDir = "indexes"
+	DirectoryHandlerDigestsDir   = "digests"
+	DirectoryHandlerIndexesDir   = "indexes"
+	DirectoryHandlerReferrersDir = "referrers"
 )
 
+// AnnotationPulledIndex is set on indexes which have been pulled from a
+// registry and contains the digest of the index which was served by the
+// registry.  The local index only retains the manifests of the requested
+// platforms and therefore has a different digest, whereas signatures refer to
+// the index of the registry.
+const AnnotationPulledIndex = "org.unikraft.handler.pulled-index"
+
 type DirectoryHandler struct {
-	path  string
-	auths map[string]config.AuthConfig
+	path       string
+	registries ociutils.Registries
 }
 
-func NewDirectoryHandler(path string, auths map[string]config.AuthConfig) (*DirectoryHandler, error) {
+func NewDirectoryHandler(path string, auths map[string]config.AuthConfig, registries map[string]config.RegistryConfig) (*DirectoryHandler, error) {
 	if err := os.MkdirAll(path, 0o775); err != nil {
 		return nil, fmt.Errorf("could not create local oci cache directory: %w", err)
 	}
 
 	return &DirectoryHandler{
-		path:  path,
-		auths: auths,
+		path: path,
+		registries: ociutils.Registries{
+			Auths:   auths,
+			Configs: registries,
+		},
 	}, nil
 }
 
@@ -90,16 +96,38 @@ func (handle *DirectoryHandler) DigestInfo(ctx context.Context, needle digest.Di
 	}, nil
 }
 
+// ReadDigest implements DigestReader.
+func (handle *DirectoryHandler) ReadDigest(ctx context.Context, dgst digest.Digest) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(
+		handle.path,
+		DirectoryHandlerDigestsDir,
+		dgst.Algorithm().String(),
+		dgst.Encoded(),
+	))
+}
+
 // PullDigest implements DigestPuller.
 func (handle *DirectoryHandler) PullDigest(ctx context.Context, mediaType, fullref string, dgst digest.Digest, plat *ocispec.Platform, onProgress func(float64)) error {
-	ref, err := name.ParseReference(fullref)
+	unlock, err := handle.lockStore(false)
 	if err != nil {
 		return err
 	}
 
-	authConfig := &authn.AuthConfig{}
+	defer unlock()
+
+	return handle.pullDigest(ctx, mediaType, fullref, dgst, plat, onProgress)
+}
+
+// pullDigest pulls the provided digest whilst the store lock is held.
+func (handle *DirectoryHandler) pullDigest(ctx context.Context, mediaType, fullref string, dgst digest.Digest, plat *ocispec.Platform, onProgress func(float64)) error {
+	ref, err := handle.registries.ParseReference(fullref)
+	if err != nil {
+		return err
+	}
 
-	ropts := []remote.Option{
+	// Content is pulled from the first of the mirrors of the registry which
+	// serves it, falling back to the registry itself.
+	sources, err := handle.registries.Sources(ref,
 		remote.WithContext(ctx),
 		remote.WithUserAgent(version.UserAgent()),
 		remote.WithPlatform(v1.Platform{
@@ -107,32 +135,16 @@ func (handle *DirectoryHandler) PullDigest(ctx context.Context, mediaType, fullr
 			OS:           plat.OS,
 			OSFeatures:   plat.OSFeatures,
 		}),
-	}
-
-	// Annoyingly convert between regtypes and authn.
-	if auth, ok := handle.auths[ref.Context().RegistryStr()]; ok {
-		authConfig.Username = auth.User
-		authConfig.Password = auth.Token
-
-		ropts = append(ropts,
-			remote.WithAuth(&simpleauth.SimpleAuthenticator{
-				Auth: authConfig,
-			}),
-		)
-
-		if !auth.VerifySSL {
-			transport := remote.DefaultTransport.(*http.Transport).Clone()
-			transport.TLSClientConfig = &tls.Config{
-				InsecureSkipVerify: true,
-			}
-
-			ropts = append(ropts, remote.WithTransport(transport))
-		}
+	)
+	if err != nil {
+		return err
 	}
 
 	switch mediaType {
 	case ocispec.MediaTypeImageIndex:
-		indexV1, err := cache.RemoteIndex(ref, ropts...)
+		indexV1, err := ociutils.Fetch(ctx, sources, func(src ociutils.Source) (v1.ImageIndex, error) {
+			return cache.RemoteIndex(src.Ref, src.Options...)
+		})
 		if err != nil {
 			return fmt.Errorf("could not retrieve remote index: %w", err)
 		}
@@ -152,38 +164,27 @@ func (handle *DirectoryHandler) PullDigest(ctx context.Context, mediaType, fullr
 				strings.ReplaceAll(fullref, ":", string(filepath.Separator)),
 			)
 
-			if indexFi, err := os.Stat(indexTagPath); err == nil {
-				if indexFi.Mode()&fs.ModeSymlink == 0 {
-					oldIndexDigestPath, err := filepath.EvalSymlinks(indexTagPath)
-					if err != nil {
-						return err
-					}
+			// Hold the lock of the reference until the new index has been tagged
+			// such that concurrent pulls of the same reference do not drop each
+			// other's manifests.
+			unlock, err := handle.lockRef(fullref)
+			if err != nil {
+				return err
+			}
 
-					if err := os.RemoveAll(indexTagPath); err != nil {
-						return fmt.Errorf("could not remove index symbolic link: %w", err)
-					}
+			defer unlock()
 
-					// Check if a local index already exists, if it does we will append the
-					// requested manifest to it.
-					if _, err := os.Stat(oldIndexDigestPath); err == nil {
-						localIndexRaw, err := lockedfile.Read(oldIndexDigestPath)
-						if err != nil {
-							return fmt.Errorf("could not read existing index: %w", err)
-						}
-
-						localIndex := ocispec.Index{}
-						if err = json.Unmarshal(localIndexRaw, &localIndex); err != nil {
-							return fmt.Errorf("could not unmarshal raw index: %w", err)
-						}
-
-						// Save the existing local manifests
-						localManifests = localIndex.Manifests
-
-						if err := os.RemoveAll(oldIndexDigestPath); err != nil {
-							return fmt.Errorf("could not remove existing index: %w", err)
-						}
-					}
+			// Check if a local index already exists, if it does we will append the
+			// requested manifest to it.  The existing index is not removed as it may
+			// be in use by other references, and is otherwise reclaimed by pruning.
+			if localIndexRaw, err := os.ReadFile(indexTagPath); err == nil {
+				localIndex := ocispec.Index{}
+				if err = json.Unmarshal(localIndexRaw, &localIndex); err != nil {
+					return fmt.Errorf("could not unmarshal raw index: %w", err)
 				}
+
+				// Save the existing local manifests
+				localManifests = localIndex.Manifests
 			}
 		}
 
@@ -240,11 +241,18 @@ func (handle *DirectoryHandler) PullDigest(ctx context.Context, mediaType, fullr
 		for i := range manifestsToPull {
 			eg.Go(func(i int) func() error {
 				return func() error {
-					if err := handle.PullDigest(egCtx,
+					// Each manifest is pulled for its own platform, as the selector may
+					// match several manifests or, if empty, none at all.
+					manifestPlat := plat
+					if manifestsToPull[i].Platform != nil {
+						manifestPlat = manifestsToPull[i].Platform
+					}
+
+					if err := handle.pullDigest(egCtx,
 						ocispec.MediaTypeImageManifest,
 						fullref,
 						manifestsToPull[i].Digest,
-						plat,
+						manifestPlat,
 						onProgress,
 					); err != nil {
 						return fmt.Errorf("could not pull manifest: %w", err)
@@ -294,17 +302,8 @@ func (handle *DirectoryHandler) PullDigest(ctx context.Context, mediaType, fullr
 
 			// If this checksum does not exist in the existing list, we can safely add
 			// it to the new index.
+			// The replaced manifest is reclaimed by pruning.
 			if _, ok := newManifestPlatChecksums[checksum]; ok {
-				oldManifestPath := filepath.Join(
-					handle.path,
-					DirectoryHandlerDigestsDir,
-					localManifest.Digest.Algorithm().String(),
-					localManifest.Digest.Encoded(),
-				)
-				if err := os.Remove(oldManifestPath); err != nil {
-					return fmt.Errorf("could not remove old manifest '%s': %w", oldManifestPath, err)
-				}
-
 				continue
 			}
 
@@ -313,66 +312,45 @@ func (handle *DirectoryHandler) PullDigest(ctx context.Context, mediaType, fullr
 
 		index.Manifests = newManifests
 
-		indexRaw, err = json.Marshal(&index)
-		if err != nil {
-			return fmt.Errorf("could not marshal raw index: %w", err)
+		if index.Annotations == nil {
+			index.Annotations = map[string]string{}
 		}
 
-		newIndexDigest := digest.FromBytes(indexRaw)
-		newIndexDigestPath := filepath.Join(
-			handle.path,
-			DirectoryHandlerDigestsDir,
-			newIndexDigest.Algorithm().String(),
-			newIndexDigest.Encoded(),
-		)
+		index.Annotations[AnnotationPulledIndex] = indexDgst.String()
 
-		if err = os.MkdirAll(filepath.Dir(newIndexDigestPath), 0o775); err != nil {
-			return fmt.Errorf("could not make manifest parent directories: %w", err)
-		}
-
-		indexWriter, err := lockedfile.Edit(newIndexDigestPath)
+		indexRaw, err = json.Marshal(&index)
 		if err != nil {
-			return fmt.Errorf("could not get manifest file descriptor: %w", err)
+			return fmt.Errorf("could not marshal raw index: %w", err)
 		}
 
-		defer indexWriter.Close()
+		newIndexDigest := digest.FromBytes(indexRaw)
 
-		if _, err = indexWriter.Write(indexRaw); err != nil {
-			return fmt.Errorf("could not write manifest: %w", err)
+		if err := handle.writeBlob(newIndexDigest, bytes.NewReader(indexRaw)); err != nil {
+			return fmt.Errorf("could not write index: %w", err)
 		}
 
 		if len(indexTagPath) > 0 {
-			// Create the parent directory if it does not exist
-			if err := os.MkdirAll(filepath.Dir(indexTagPath), 0o774); err != nil {
-				return fmt.Errorf("could not make parent directory: %w", err)
-			}
-
-			if err := os.Symlink(newIndexDigestPath, indexTagPath); err != nil {
-				return err
+			if err := handle.symlink(handle.blobPath(newIndexDigest), indexTagPath); err != nil {
+				return fmt.Errorf("could not tag index: %w", err)
 			}
 		}
 
 	case ocispec.MediaTypeImageManifest:
-		v1Index, err := cache.RemoteIndex(ref, ropts...)
-		if err != nil {
-			return fmt.Errorf("could not retrieve remote manifest: %w", err)
-		}
-
 		// Only pull the manifest if does not exist locally.
 		manifest, err := handle.ResolveManifest(ctx, fullref, dgst)
 		if err != nil {
-			manifestPath := filepath.Join(
-				handle.path,
-				DirectoryHandlerDigestsDir,
-				dgst.Algorithm().String(),
-				dgst.Encoded(),
-			)
-
 			hash, err := v1.NewHash(dgst.String())
 			if err != nil {
 				return fmt.Errorf("could not calculate image digest: %w", err)
 			}
-			image, err := v1Index.Image(hash)
+			image, err := ociutils.Fetch(ctx, sources, func(src ociutils.Source) (v1.Image, error) {
+				v1Index, err := cache.RemoteIndex(src.Ref, src.Options...)
+				if err != nil {
+					return nil, fmt.Errorf("could not retrieve remote manifest: %w", err)
+				}
+
+				return v1Index.Image(hash)
+			})
 			if err != nil {
 				return fmt.Errorf("could not retrieve image: %w", err)
 			}
@@ -390,24 +368,7 @@ func (handle *DirectoryHandler) PullDigest(ctx context.Context, mediaType, fullr
 				return fmt.Errorf("could not unmarshal raw manifest: %w", err)
 			}
 
-			if err = os.MkdirAll(filepath.Dir(manifestPath), 0o775); err != nil {
-				return fmt.Errorf("could not make manifest parent directories: %w", err)
-			}
-
-			manifestWriter, err := os.Create(manifestPath)
-			if err != nil {
-				return fmt.Errorf("could not get manifest file descriptor: %w", err)
-			}
-
-			defer manifestWriter.Close()
-
-			if _, err = manifestWriter.Write(manifestRaw); err != nil {
-				if err2 := manifestWriter.Close(); err2 != nil {
-					return fmt.Errorf("%w: could not close manifest writer: %w", err, err2)
-				}
-				if err2 := os.RemoveAll(manifestPath); err2 != nil {
-					return fmt.Errorf("%w: could not remove manifest: %w", err, err2)
-				}
+			if err := handle.writeBlob(dgst, bytes.NewReader(manifestRaw)); err != nil {
 				return fmt.Errorf("could not write manifest: %w", err)
 			}
 		}
@@ -431,12 +392,14 @@ func (handle *DirectoryHandler) PullDigest(ctx context.Context, mediaType, fullr
 				WithField("digest", manifest.Config.Digest.String()).
 				Debugf("pulling config")
 
-			image, err := remote.Image(ref, ropts...)
-			if err != nil {
-				return fmt.Errorf("could not retrieve remote manifest: %w", err)
-			}
+			configRaw, err := ociutils.Fetch(ctx, sources, func(src ociutils.Source) ([]byte, error) {
+				image, err := remote.Image(src.Ref, src.Options...)
+				if err != nil {
+					return nil, fmt.Errorf("could not retrieve remote manifest: %w", err)
+				}
 
-			configRaw, err := image.RawConfigFile()
+				return image.RawConfigFile()
+			})
 			if err != nil {
 				return fmt.Errorf("could not get raw config: %w", err)
 			}
@@ -446,24 +409,7 @@ func (handle *DirectoryHandler) PullDigest(ctx context.Context, mediaType, fullr
 				return fmt.Errorf("could not unmarshal raw config: %w", err)
 			}
 
-			if err = os.MkdirAll(filepath.Dir(configPath), 0o775); err != nil {
-				return fmt.Errorf("could not create config parent directories: %w", err)
-			}
-
-			configWriter, err := os.Create(configPath)
-			if err != nil {
-				return fmt.Errorf("could not get config file descriptor: %w", err)
-			}
-
-			defer configWriter.Close()
-
-			if _, err = configWriter.Write(configRaw); err != nil {
-				if err2 := configWriter.Close(); err2 != nil {
-					return fmt.Errorf("%w: could not close raw config writer: %w", err, err2)
-				}
-				if err2 := os.RemoveAll(configPath); err2 != nil {
-					return fmt.Errorf("%w: could not remove raw config: %w", err, err2)
-				}
+			if err := handle.writeBlob(manifest.Config.Digest, bytes.NewReader(configRaw)); err != nil {
 				return fmt.Errorf("could not write raw config: %w", err)
 			}
 		}
@@ -475,95 +421,57 @@ func (handle *DirectoryHandler) PullDigest(ctx context.Context, mediaType, fullr
 			totalSize += layer.Size
 		}
 
+		var layerProgress func(float64)
+		if onProgress != nil {
+			layerProgress = func(size float64) {
+				onProgress(size / float64(totalSize))
+			}
+		}
+
 		for _, layer := range manifest.Layers {
-			if err := handle.PullDigest(ctx,
+			if err := handle.pullDigest(ctx,
 				ocispec.MediaTypeImageLayer,
 				fullref,
 				layer.Digest,
 				plat,
-				func(size float64) {
-					onProgress(size / float64(totalSize))
-				},
+				layerProgress,
 			); err != nil {
 				return fmt.Errorf("could not pull layer from digest: %w", err)
 			}
 		}
 
 	case ocispec.MediaTypeImageLayer, ocispec.MediaTypeImageLayerGzip:
-		log.G(ctx).
-			WithField("digest", dgst.String()).
-			Debugf("pulling layer")
-
-		fullref = fmt.Sprintf("%s/%s@%s",
-			ref.Context().RegistryStr(),
-			ref.Context().RepositoryStr(),
-			dgst.String(),
-		)
-
-		layerV1Dgst, err := name.NewDigest(fullref)
-		if err != nil {
-			return fmt.Errorf("could not generate full digest: %w", err)
-		}
-
-		layer, err := remote.Layer(layerV1Dgst, ropts...)
-		if err != nil {
-			return fmt.Errorf("could not get remote layer: %w", err)
-		}
-
-		mediaType, err := layer.MediaType()
+		// Deduplicate concurrent pulls of the same layer: only the first holder of
+		// the lock pulls it, after which it exists for all others.
+		unlock, err := handle.lockDigest(dgst)
 		if err != nil {
-			return fmt.Errorf("could not get media type of layer: %w", err)
+			return err
 		}
 
-		var reader io.ReadCloser
+		defer unlock()
 
-		switch mediaType {
-		case ocispec.MediaTypeImageLayer, types.DockerUncompressedLayer:
-			reader, err = layer.Uncompressed()
-		case ocispec.MediaTypeImageLayerGzip, types.DockerLayer:
-			reader, err = layer.Compressed()
-		default:
-			return fmt.Errorf("unsupported layer mediatype '%s'", mediaType)
-		}
-		if err != nil {
-			return fmt.Errorf("could not open layer reader: %w", err)
-		}
+		if _, err := os.Stat(handle.blobPath(dgst)); err == nil {
+			log.G(ctx).
+				WithField("digest", dgst.String()).
+				Debugf("layer already exists")
 
-		var progresReader io.Reader
-		if onProgress != nil {
-			progresReader = &progressWriter{
-				Reader:     reader,
-				onProgress: onProgress,
+			if onProgress != nil {
+				onProgress(1)
 			}
-		} else {
-			progresReader = reader
-		}
-
-		layerPath := filepath.Join(
-			handle.path,
-			DirectoryHandlerDigestsDir,
-			dgst.Algorithm().String(),
-			dgst.Encoded(),
-		)
 
-		if err = os.MkdirAll(filepath.Dir((layerPath)), 0o775); err != nil {
-			return fmt.Errorf("could not make directory: %w", err)
+			return nil
 		}
 
-		blob, err := os.OpenFile(layerPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o664)
-		if err != nil {
-			return fmt.Errorf("could not create layer: %w", err)
-		}
-		defer blob.Close()
+		log.G(ctx).
+			WithField("digest", dgst.String()).
+			Debugf("pulling layer")
 
-		if _, err := io.Copy(blob, progresReader); err != nil {
-			if err2 := blob.Close(); err2 != nil {
-				return fmt.Errorf("%w: could not close blob: %w", err, err2)
-			}
-			if err2 := os.RemoveAll(layerPath); err2 != nil {
-				return fmt.Errorf("%w: could not remove blob: %w", err, err2)
-			}
-			return fmt.Errorf("could not write layer: %w", err)
+		// Layers are downloaded directly such that an interrupted download is
+		// resumed from where it stopped rather than started over.
+		if _, err := ociutils.Fetch(ctx, sources, func(src ociutils.Source) (struct{}, error) {
+			return struct{}{}, handle.fetchBlob(ctx, src.Ref.Context(), dgst, onProgress)
+		}); err != nil {
+			return fmt.Errorf("could not pull layer: %w", err)
 		}
 
 	default:
@@ -575,23 +483,20 @@ func (handle *DirectoryHandler) PullDigest(ctx context.Context, mediaType, fullr
 
 // SaveDescriptor implements DescriptorSaver.
 func (handle *DirectoryHandler) SaveDescriptor(ctx context.Context, ref string, desc ocispec.Descriptor, reader io.Reader, onProgress func(float64)) error {
-	blobPath := filepath.Join(
-		handle.path,
-		DirectoryHandlerDigestsDir,
-		desc.Digest.Algorithm().String(),
-		desc.Digest.Encoded(),
-	)
-
-	// Create the parent directory if it does not exist
-	if err := os.MkdirAll(filepath.Dir(blobPath), 0o774); err != nil {
-		return fmt.Errorf("could not make parent directory: %w", err)
+	unlock, err := handle.lockStore(false)
+	if err != nil {
+		return err
 	}
 
-	blob, err := os.OpenFile(blobPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o664)
-	if err != nil {
-		return fmt.Errorf("could not create blob: %w", err)
+	defer unlock()
+
+	blobPath := handle.blobPath(desc.Digest)
+
+	// Retain a copy of manifests such that they can be inspected for a subject.
+	var cache bytes.Buffer
+	if desc.MediaType == ocispec.MediaTypeImageManifest {
+		reader = io.TeeReader(reader, &cache)
 	}
-	defer blob.Close()
 
 	var progresReader io.Reader
 	if onProgress != nil {
@@ -609,13 +514,7 @@ func (handle *DirectoryHandler) SaveDescriptor(ctx context.Context, ref string,
 		WithField("digest", desc.Digest.String()).
 		Trace("saving")
 
-	if _, err := io.Copy(blob, progresReader); err != nil {
-		if err2 := blob.Close(); err2 != nil {
-			return fmt.Errorf("%w: could not close blob: %w", err, err2)
-		}
-		if err2 := os.RemoveAll(blobPath); err2 != nil {
-			return fmt.Errorf("%w: could not remove blob: %w", err, err2)
-		}
+	if err := handle.writeBlob(desc.Digest, progresReader); err != nil {
 		return err
 	}
 
@@ -629,61 +528,122 @@ func (handle *DirectoryHandler) SaveDescriptor(ctx context.Context, ref string,
 				strings.ReplaceAll(ref, ":", string(filepath.Separator)),
 			)
 
-			if _, err := os.Stat(indexTagPath); err == nil {
-				if err := os.RemoveAll(indexTagPath); err != nil {
-					return fmt.Errorf("could not create symbolic link to new index: %w", err)
-				}
+			unlock, err := handle.lockRef(ref)
+			if err != nil {
+				return err
 			}
 
-			// Create the parent directory if it does not exist
-			if err := os.MkdirAll(filepath.Dir(indexTagPath), 0o774); err != nil {
-				return fmt.Errorf("could not make parent directory: %w", err)
-			}
+			defer unlock()
 
-			if err := os.Symlink(blobPath, indexTagPath); err != nil {
+			if err := handle.symlink(blobPath, indexTagPath); err != nil {
 				return fmt.Errorf("creating symbolic link to new index: %w", err)
 			}
 		}
+
+	// Create a symbolic link from the subject of the manifest, if set, such that
+	// the manifest can be found as one of its referrers.
+	case ocispec.MediaTypeImageManifest:
+		manifest := ocispec.Manifest{}
+		if err := json.Unmarshal(cache.Bytes(), &manifest); err != nil {
+			return fmt.Errorf("could not unmarshal manifest: %w", err)
+		}
+
+		if manifest.Subject == nil {
+			break
+		}
+
+		referrerPath := filepath.Join(
+			handle.path,
+			DirectoryHandlerReferrersDir,
+			manifest.Subject.Digest.Algorithm().String(),
+			manifest.Subject.Digest.Encoded(),
+			desc.Digest.Encoded(),
+		)
+
+		if err := handle.symlink(blobPath, referrerPath); err != nil {
+			return fmt.Errorf("creating symbolic link to referrer: %w", err)
+		}
 	}
 
 	return nil
 }
 
-// PushDescriptor implements DescriptorPusher.
-func (handle *DirectoryHandler) PushDescriptor(ctx context.Context, fullref string, desc *ocispec.Descriptor) error {
-	ref, err := name.ParseReference(fullref)
-	if err != nil {
-		return err
-	}
+// ResolveReferrers implements ReferrersResolver.
+func (handle *DirectoryHandler) ResolveReferrers(ctx context.Context, fullref string, subject digest.Digest, artifactType string) ([]ocispec.Descriptor, error) {
+	referrersDir := filepath.Join(
+		handle.path,
+		DirectoryHandlerReferrersDir,
+		subject.Algorithm().String(),
+		subject.Encoded(),
+	)
 
-	ropts := []remote.Option{
-		remote.WithContext(ctx),
-		remote.WithUserAgent(version.UserAgent()),
+	entries, err := os.ReadDir(referrersDir)
+	if err != nil && os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not read referrers: %w", err)
 	}
 
-	authConfig := &authn.AuthConfig{}
+	var referrers []ocispec.Descriptor
 
-	// Annoyingly convert between regtypes and authn.
-	if auth, ok := handle.auths[ref.Context().RegistryStr()]; ok {
-		authConfig.Username = auth.User
-		authConfig.Password = auth.Token
+	for _, entry := range entries {
+		dgst := digest.NewDigestFromEncoded(subject.Algorithm(), entry.Name())
 
-		ropts = append(ropts,
-			remote.WithAuth(&simpleauth.SimpleAuthenticator{
-				Auth: authConfig,
-			}),
-		)
+		// Skip referrers whose manifest has since been removed.
+		raw, err := os.ReadFile(filepath.Join(referrersDir, entry.Name()))
+		if err != nil {
+			log.G(ctx).
+				WithField("digest", dgst.String()).
+				Debugf("skipping referrer: %v", err)
+			continue
+		}
 
-		if !auth.VerifySSL {
-			transport := remote.DefaultTransport.(*http.Transport).Clone()
-			transport.TLSClientConfig = &tls.Config{
-				InsecureSkipVerify: true,
-			}
+		manifest := ocispec.Manifest{}
+		if err := json.Unmarshal(raw, &manifest); err != nil {
+			return nil, fmt.Errorf("could not unmarshal referrer '%s': %w", dgst.String(), err)
+		}
+
+		desc := ocispec.Descriptor{
+			MediaType:    ocispec.MediaTypeImageManifest,
+			ArtifactType: manifest.ArtifactType,
+			Digest:       dgst,
+			Size:         int64(len(raw)),
+			Annotations:  manifest.Annotations,
+		}
+
+		// As per the OCI distribution specification, the artifact type of a
+		// manifest without one is the media type of its config.
+		if len(desc.ArtifactType) == 0 {
+			desc.ArtifactType = manifest.Config.MediaType
+		}
 
-			ropts = append(ropts, remote.WithTransport(transport))
+		if len(artifactType) > 0 && desc.ArtifactType != artifactType {
+			continue
 		}
+
+		referrers = append(referrers, desc)
 	}
 
+	return referrers, nil
+}
+
+// PushDescriptor implements DescriptorPusher.
+func (handle *DirectoryHandler) PushDescriptor(ctx context.Context, fullref string, desc *ocispec.Descriptor) error {
+	ref, err := handle.registries.ParseReference(fullref)
+	if err != nil {
+		return err
+	}
+
+	ropts, err := handle.registries.RemoteOptions(ref.Context().RegistryStr())
+	if err != nil {
+		return err
+	}
+
+	ropts = append(ropts,
+		remote.WithContext(ctx),
+		remote.WithUserAgent(version.UserAgent()),
+	)
+
 	log.G(ctx).
 		WithField("ref", ref.Name()).
 		WithField("mediaType", desc.MediaType).
@@ -819,7 +779,28 @@ func (handle *DirectoryHandler) ListManifests(ctx context.Context) (map[string]*
 	return manifests, nil
 }
 
+// DeleteManifest implements ManifestDeleter.
 func (handle *DirectoryHandler) DeleteManifest(ctx context.Context, fullref string, dgst digest.Digest) error {
+	unlock, err := handle.lockStore(false)
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	unlockRef, err := handle.lockRef(fullref)
+	if err != nil {
+		return err
+	}
+
+	defer unlockRef()
+
+	return handle.deleteManifest(ctx, fullref, dgst)
+}
+
+// deleteManifest deletes the manifest whilst the store and reference locks are
+// held.
+func (handle *DirectoryHandler) deleteManifest(ctx context.Context, fullref string, dgst digest.Digest) error {
 	manifestPath := filepath.Join(
 		handle.path,
 		DirectoryHandlerDigestsDir,
@@ -944,21 +925,15 @@ func (handle *DirectoryHandler) DeleteManifest(ctx context.Context, fullref stri
 			return fmt.Errorf("could not marshal new index: %w", err)
 		}
 
-		indexFile, err := os.OpenFile(indexPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o664)
-		if err != nil {
-			return fmt.Errorf("could not open index file: %w", err)
+		// Save the updated index as a new blob rather than modifying the existing
+		// one in-place, such that it remains addressable by its digest.
+		indexDigest := digest.FromBytes(indexJson)
+		if err := handle.writeBlob(indexDigest, bytes.NewReader(indexJson)); err != nil {
+			return fmt.Errorf("could not write index file: %w", err)
 		}
 
-		defer indexFile.Close()
-
-		if _, err := indexFile.Write(indexJson); err != nil {
-			if err2 := indexFile.Close(); err2 != nil {
-				return fmt.Errorf("%w: could not close index writer: %w", err, err2)
-			}
-			if err2 := os.RemoveAll(indexPath); err2 != nil {
-				return fmt.Errorf("%w: could not remove index: %w", err, err2)
-			}
-			return fmt.Errorf("could not write index file: %w", err)
+		if err := handle.symlink(handle.blobPath(indexDigest), indexPath); err != nil {
+			return fmt.Errorf("could not tag index: %w", err)
 		}
 	}
 
@@ -1084,7 +1059,22 @@ func (handle *DirectoryHandler) ListIndexes(ctx context.Context) (map[string]*oc
 	return indexes, nil
 }
 
+// DeleteIndex implements IndexDeleter.
 func (handle *DirectoryHandler) DeleteIndex(ctx context.Context, fullref string, deps bool) error {
+	unlock, err := handle.lockStore(false)
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	unlockRef, err := handle.lockRef(fullref)
+	if err != nil {
+		return err
+	}
+
+	defer unlockRef()
+
 	indexPath := filepath.Join(
 		handle.path,
 		DirectoryHandlerIndexesDir,
@@ -1117,7 +1107,7 @@ func (handle *DirectoryHandler) DeleteIndex(ctx context.Context, fullref string,
 		}
 
 		for _, manifest := range index.Manifests {
-			if err := handle.DeleteManifest(ctx, fullref, manifest.Digest); err != nil && !os.IsNotExist(err) {
+			if err := handle.deleteManifest(ctx, fullref, manifest.Digest); err != nil && !os.IsNotExist(err) {
 				return fmt.Errorf("could not delete manifest from '%s': %w", fullref, err)
 			}
 		}
@@ -0,0 +1,241 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package handler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/opencontainers/go-digest"
+
+	"kraftkit.sh/internal/version"
+	"kraftkit.sh/log"
+)
+
+// partialPath returns the path at which the provided blob is downloaded before
+// it is committed.  The path is stable such that an interrupted download is
+// resumed by the next pull of the same blob.
+func (handle *DirectoryHandler) partialPath(dgst digest.Digest) string {
+	return filepath.Join(
+		handle.path,
+		DirectoryHandlerIngestDir,
+		dgst.Algorithm().String()+"-"+dgst.Encoded()+".partial",
+	)
+}
+
+// fetchBlob downloads the blob with the provided digest from the provided
+// repository and commits it to the store.  If a previous download of the blob
+// was interrupted, only its remainder is requested using an HTTP range
+// request.  The lock of the digest must be held.
+func (handle *DirectoryHandler) fetchBlob(ctx context.Context, repo name.Repository, dgst digest.Digest, onProgress func(float64)) error {
+	domain := repo.RegistryStr()
+
+	rt, err := handle.registries.Transport(domain)
+	if err != nil {
+		return err
+	}
+
+	tr, err := transport.NewWithContext(ctx,
+		repo.Registry,
+		handle.registries.Authenticator(domain),
+		transport.NewUserAgent(rt, version.UserAgent()),
+		[]string{repo.Scope(transport.PullScope)},
+	)
+	if err != nil {
+		return fmt.Errorf("could not connect to registry: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(handle.path, DirectoryHandlerIngestDir), 0o775); err != nil {
+		return fmt.Errorf("could not make ingest directory: %w", err)
+	}
+
+	partialPath := handle.partialPath(dgst)
+
+	partial, err := os.OpenFile(partialPath, os.O_RDWR|os.O_CREATE, 0o664)
+	if err != nil {
+		return fmt.Errorf("could not open partial blob: %w", err)
+	}
+
+	defer partial.Close()
+
+	offset, err := partial.Seek(0, io.SeekEnd)
+	if err != nil {
+		return fmt.Errorf("could not seek partial blob: %w", err)
+	}
+
+	u := url.URL{
+		Scheme: repo.Registry.Scheme(),
+		Host:   repo.RegistryStr(),
+		Path:   fmt.Sprintf("/v2/%s/blobs/%s", repo.RepositoryStr(), dgst.String()),
+	}
+
+	client := &http.Client{Transport: tr}
+
+	// Determine the size of the blob such that a closed range of its remainder
+	// can be requested, as not all registries support open ranges.
+	size, err := blobSize(ctx, client, u.String())
+	if err != nil {
+		return err
+	}
+
+	if offset > size {
+		offset = 0
+	}
+
+	if offset < size {
+		if err := downloadRange(ctx, client, u.String(), partial, offset, size, dgst, onProgress); err != nil {
+			return err
+		}
+	}
+
+	if err := partial.Sync(); err != nil {
+		return fmt.Errorf("could not sync blob: %w", err)
+	}
+
+	if _, err := partial.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("could not seek partial blob: %w", err)
+	}
+
+	actual, err := dgst.Algorithm().FromReader(partial)
+	if err != nil {
+		return fmt.Errorf("could not digest blob: %w", err)
+	}
+
+	if actual != dgst {
+		os.Remove(partialPath)
+		return fmt.Errorf("downloaded blob has digest '%s' but expected '%s'", actual, dgst)
+	}
+
+	if err := partial.Close(); err != nil {
+		return fmt.Errorf("could not close blob: %w", err)
+	}
+
+	blobPath := handle.blobPath(dgst)
+	if err := os.MkdirAll(filepath.Dir(blobPath), 0o775); err != nil {
+		return fmt.Errorf("could not make parent directory: %w", err)
+	}
+
+	if err := os.Rename(partialPath, blobPath); err != nil {
+		return fmt.Errorf("could not commit blob: %w", err)
+	}
+
+	if onProgress != nil {
+		onProgress(1)
+	}
+
+	return nil
+}
+
+// rangeProgressReader wraps an io.Reader of the remainder of a blob and
+// reports the progress of the download of the entire blob.
+type rangeProgressReader struct {
+	io.Reader
+	done       int64
+	total      int64
+	onProgress func(float64)
+}
+
+// Read implements io.Reader.
+func (r *rangeProgressReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.done += int64(n)
+	r.onProgress(float64(r.done) / float64(r.total))
+	return n, err
+}
+
+// blobSize returns the size of the blob at the provided URL.
+func blobSize(ctx context.Context, client *http.Client, u string) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, u, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("could not request blob: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if err := transport.CheckError(resp, http.StatusOK); err != nil {
+		return 0, err
+	}
+
+	if resp.ContentLength < 0 {
+		return 0, fmt.Errorf("registry did not report size of blob")
+	}
+
+	return resp.ContentLength, nil
+}
+
+// downloadRange downloads the blob at the provided URL from the provided
+// offset into the partial blob, which holds everything before the offset.
+func downloadRange(ctx context.Context, client *http.Client, u string, partial *os.File, offset, size int64, dgst digest.Digest, onProgress func(float64)) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+
+	if offset > 0 {
+		log.G(ctx).
+			WithField("digest", dgst.String()).
+			WithField("offset", offset).
+			Debug("resuming interrupted download")
+
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, size-1))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not request blob: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		// The remainder of the blob is appended to what was downloaded before.
+
+	case http.StatusOK:
+		// The registry does not support range requests, so start over.
+		if err := partial.Truncate(0); err != nil {
+			return fmt.Errorf("could not truncate partial blob: %w", err)
+		}
+
+		if _, err := partial.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("could not seek partial blob: %w", err)
+		}
+
+		offset = 0
+
+	default:
+		return transport.CheckError(resp, http.StatusOK, http.StatusPartialContent)
+	}
+
+	var reader io.Reader = resp.Body
+	if onProgress != nil && size > 0 {
+		reader = &rangeProgressReader{
+			Reader:     resp.Body,
+			done:       offset,
+			total:      size,
+			onProgress: onProgress,
+		}
+	}
+
+	// The partial blob is retained on failure such that the next pull resumes
+	// from where this one stopped.
+	if _, err := io.Copy(partial, reader); err != nil {
+		return fmt.Errorf("could not download blob: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,759 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"kraftkit.sh/log"
+)
+
+// DefaultPruneGracePeriod is the grace period of pruning if none is set.
+const DefaultPruneGracePeriod = time.Hour
+
+// directoryManifestSizeLimit is the size above which a blob is not considered
+// to be an index or a manifest.
+const directoryManifestSizeLimit = 4 << 20
+
+// DiskUsage summarises the space occupied by the packages of a handler.
+type DiskUsage struct {
+	// Packages is the number of tagged packages.
+	Packages int
+
+	// Blobs is the number of stored blobs, i.e. indexes, manifests, configs and
+	// layers.
+	Blobs int
+
+	// Size is the total size in bytes of all stored blobs.
+	Size int64
+
+	// Reclaimable is the size in bytes of the blobs which are not referenced by
+	// any tagged package and which would be removed by pruning with the default
+	// grace period.
+	Reclaimable int64
+}
+
+// PruneOptions is the policy which selects the packages to remove when
+// pruning.  The policies are combined such that a package is only removed if
+// all set policies select it.  With no policy set, no package is removed and
+// only unreferenced blobs are.
+type PruneOptions struct {
+	// KeepLast retains the provided number of most recently pulled packages of
+	// each repository.  Disabled if zero.
+	KeepLast int
+
+	// Until selects packages which have been pulled before the provided time.
+	// Disabled if zero.
+	Until time.Time
+
+	// GracePeriod retains the blobs which are not referenced by any package but
+	// which have been written within the provided duration, as well as the
+	// blobs they reference.  Packages are saved one blob at a time, e.g. by a
+	// concurrent `kraft pkg`, such that their blobs are only referenced once
+	// the package is complete.  If zero, DefaultPruneGracePeriod is used and, if
+	// negative, all unreferenced blobs are removed.
+	GracePeriod time.Duration
+}
+
+// PruneReport details what has been removed when pruning.
+type PruneReport struct {
+	// Untagged is the list of references of packages which have been removed.
+	Untagged []string
+
+	// Deleted is the list of digests of blobs which have been removed.
+	Deleted []digest.Digest
+
+	// Reclaimed is the size in bytes of the removed blobs.
+	Reclaimed int64
+}
+
+// directoryTag is a tagged index stored by the directory handler.
+type directoryTag struct {
+	ref      string
+	path     string
+	index    *ocispec.Index
+	digest   digest.Digest
+	pulledAt time.Time
+}
+
+// repository returns the reference of the tag without its tag.
+func (tag directoryTag) repository() string {
+	return tag.ref[:strings.LastIndex(tag.ref, ":")]
+}
+
+// blobPath returns the path of the blob with the provided digest.
+func (handle *DirectoryHandler) blobPath(dgst digest.Digest) string {
+	return filepath.Join(
+		handle.path,
+		DirectoryHandlerDigestsDir,
+		dgst.Algorithm().String(),
+		dgst.Encoded(),
+	)
+}
+
+// digestFromPath returns the digest of the blob stored at the provided path.
+func digestFromPath(path string) digest.Digest {
+	return digest.NewDigestFromEncoded(
+		digest.Algorithm(filepath.Base(filepath.Dir(path))),
+		filepath.Base(path),
+	)
+}
+
+// tags returns all tagged indexes as well as the paths of tags which do not
+// point to a valid index, e.g. following an interrupted pull.
+func (handle *DirectoryHandler) tags(ctx context.Context) ([]directoryTag, []string, error) {
+	indexesDir := filepath.Join(handle.path, DirectoryHandlerIndexesDir)
+
+	var tags []directoryTag
+	var invalid []string
+
+	if err := filepath.WalkDir(indexesDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		split := strings.Split(strings.TrimPrefix(path, indexesDir+string(filepath.Separator)), string(filepath.Separator))
+		tag := directoryTag{
+			ref:  fmt.Sprintf("%s:%s", strings.Join(split[:len(split)-1], "/"), split[len(split)-1]),
+			path: path,
+		}
+
+		target := path
+		if d.Type()&fs.ModeSymlink != 0 {
+			if target, err = filepath.EvalSymlinks(path); err != nil {
+				log.G(ctx).
+					WithField("ref", tag.ref).
+					Debug("tag does not point to an index")
+				invalid = append(invalid, path)
+				return nil
+			}
+
+			tag.digest = digestFromPath(target)
+		}
+
+		raw, err := os.ReadFile(target)
+		if err != nil {
+			return fmt.Errorf("could not read index of '%s': %w", tag.ref, err)
+		}
+
+		tag.index = &ocispec.Index{}
+		if err := json.Unmarshal(raw, tag.index); err != nil {
+			log.G(ctx).
+				WithField("ref", tag.ref).
+				Debug("tag does not point to a valid index")
+			invalid = append(invalid, path)
+			return nil
+		}
+
+		tag.pulledAt = handle.pulledAt(tag.index)
+		if tag.pulledAt.IsZero() {
+			if fi, err := os.Stat(target); err == nil {
+				tag.pulledAt = fi.ModTime()
+			}
+		}
+
+		tags = append(tags, tag)
+
+		return nil
+	}); err != nil {
+		return nil, nil, fmt.Errorf("could not walk indexes directory: %w", err)
+	}
+
+	return tags, invalid, nil
+}
+
+// pulledAt returns the most recent time at which any of the manifests of the
+// provided index was pulled.  Consistent with the package's PulledAt, a
+// manifest is considered pulled at the time its earliest layer was written.
+func (handle *DirectoryHandler) pulledAt(index *ocispec.Index) time.Time {
+	var latest time.Time
+
+	for _, desc := range index.Manifests {
+		raw, err := os.ReadFile(handle.blobPath(desc.Digest))
+		if err != nil {
+			continue
+		}
+
+		manifest := ocispec.Manifest{}
+		if err := json.Unmarshal(raw, &manifest); err != nil {
+			continue
+		}
+
+		var earliest time.Time
+		for _, layer := range manifest.Layers {
+			fi, err := os.Stat(handle.blobPath(layer.Digest))
+			if err != nil {
+				continue
+			}
+
+			if earliest.IsZero() || fi.ModTime().Before(earliest) {
+				earliest = fi.ModTime()
+			}
+		}
+
+		if earliest.After(latest) {
+			latest = earliest
+		}
+	}
+
+	return latest
+}
+
+// expired returns the tags which are selected for removal by the policy.
+func (opts PruneOptions) expired(tags []directoryTag) []directoryTag {
+	if opts.KeepLast <= 0 && opts.Until.IsZero() {
+		return nil
+	}
+
+	repositories := map[string][]directoryTag{}
+	for _, tag := range tags {
+		repositories[tag.repository()] = append(repositories[tag.repository()], tag)
+	}
+
+	var expired []directoryTag
+
+	for _, repository := range repositories {
+		sort.SliceStable(repository, func(i, j int) bool {
+			return repository[i].pulledAt.After(repository[j].pulledAt)
+		})
+
+		for i, tag := range repository {
+			if opts.KeepLast > 0 && i < opts.KeepLast {
+				continue
+			}
+
+			if !opts.Until.IsZero() && !tag.pulledAt.Before(opts.Until) {
+				continue
+			}
+
+			expired = append(expired, tag)
+		}
+	}
+
+	sort.Slice(expired, func(i, j int) bool {
+		return expired[i].ref < expired[j].ref
+	})
+
+	return expired
+}
+
+// mark returns the set of blobs which are reachable from the provided tags,
+// either directly or as (transitive) referrers of a reachable blob.
+func (handle *DirectoryHandler) mark(ctx context.Context, tags []directoryTag) (map[digest.Digest]struct{}, error) {
+	marked := map[digest.Digest]struct{}{}
+
+	for _, tag := range tags {
+		if tag.digest != "" {
+			marked[tag.digest] = struct{}{}
+		}
+
+		if err := handle.markIndex(ctx, marked, tag.index); err != nil {
+			return nil, fmt.Errorf("could not mark '%s': %w", tag.ref, err)
+		}
+	}
+
+	if err := handle.markReferrers(ctx, marked); err != nil {
+		return nil, err
+	}
+
+	return marked, nil
+}
+
+// markReferrers marks the (transitive) referrers of the marked blobs.
+func (handle *DirectoryHandler) markReferrers(ctx context.Context, marked map[digest.Digest]struct{}) error {
+	// Referrers are not listed by their subject and are instead discovered via
+	// the referrers directory.  Since referrers may themselves be referred to,
+	// e.g. the signature of an SBOM, repeat until no new blobs are reached.
+	for {
+		subjects, err := handle.referrerSubjects()
+		if err != nil {
+			return err
+		}
+
+		reached := false
+
+		for subject, referrers := range subjects {
+			if _, ok := marked[subject]; !ok {
+				continue
+			}
+
+			for _, referrer := range referrers {
+				if _, ok := marked[referrer]; ok {
+					continue
+				}
+
+				reached = true
+				if err := handle.markManifest(ctx, marked, referrer); err != nil {
+					return fmt.Errorf("could not mark referrer '%s': %w", referrer.String(), err)
+				}
+			}
+		}
+
+		if !reached {
+			return nil
+		}
+	}
+}
+
+// retained returns the set of blobs which are not swept when the provided
+// tags are kept and the other provided tags are removed.  These are the blobs
+// which are reachable from the kept tags as well as those which have been
+// written within the grace period, unless only the removed tags reach them,
+// and the blobs which these reach in turn.
+func (handle *DirectoryHandler) retained(ctx context.Context, kept, removed []directoryTag, grace time.Duration) (map[digest.Digest]struct{}, error) {
+	marked, err := handle.mark(ctx, kept)
+	if err != nil {
+		return nil, err
+	}
+
+	untagged, err := handle.mark(ctx, removed)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-grace)
+	recent := map[digest.Digest]int64{}
+
+	if err := handle.blobs(func(_ string, dgst digest.Digest, info fs.FileInfo) error {
+		if _, ok := marked[dgst]; ok {
+			return nil
+		}
+
+		if _, ok := untagged[dgst]; ok {
+			return nil
+		}
+
+		if info.ModTime().After(cutoff) {
+			recent[dgst] = info.Size()
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if len(recent) == 0 {
+		return marked, nil
+	}
+
+	for dgst, size := range recent {
+		log.G(ctx).
+			WithField("digest", dgst.String()).
+			Trace("retaining recently written blob")
+
+		if err := handle.markBlob(ctx, marked, dgst, size); err != nil {
+			return nil, fmt.Errorf("could not mark '%s': %w", dgst.String(), err)
+		}
+	}
+
+	if err := handle.markReferrers(ctx, marked); err != nil {
+		return nil, err
+	}
+
+	return marked, nil
+}
+
+// markBlob marks the blob with the provided digest as well as, if it is an
+// index or a manifest, the blobs which it references.
+func (handle *DirectoryHandler) markBlob(ctx context.Context, marked map[digest.Digest]struct{}, dgst digest.Digest, size int64) error {
+	marked[dgst] = struct{}{}
+
+	// Avoid reading layers.
+	if size > directoryManifestSizeLimit {
+		return nil
+	}
+
+	raw, err := os.ReadFile(handle.blobPath(dgst))
+	if err != nil && errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// Blobs are stored without their media type, which is therefore determined
+	// from their content.
+	var blob struct {
+		MediaType string               `json:"mediaType"`
+		Config    *ocispec.Descriptor  `json:"config"`
+		Manifests []ocispec.Descriptor `json:"manifests"`
+	}
+
+	if err := json.Unmarshal(raw, &blob); err != nil {
+		return nil
+	}
+
+	switch {
+	case blob.MediaType == ocispec.MediaTypeImageIndex || blob.MediaType == string(types.DockerManifestList) || len(blob.Manifests) > 0:
+		return handle.markIndex(ctx, marked, &ocispec.Index{Manifests: blob.Manifests})
+
+	case blob.Config != nil:
+		return handle.markManifest(ctx, marked, dgst)
+	}
+
+	return nil
+}
+
+// markIndex marks the manifests, and recursively their blobs, of the provided
+// index.  Manifests which have not been pulled are skipped.
+func (handle *DirectoryHandler) markIndex(ctx context.Context, marked map[digest.Digest]struct{}, index *ocispec.Index) error {
+	for _, desc := range index.Manifests {
+		if _, ok := marked[desc.Digest]; ok {
+			continue
+		}
+
+		if desc.MediaType != ocispec.MediaTypeImageIndex && desc.MediaType != string(types.DockerManifestList) {
+			if err := handle.markManifest(ctx, marked, desc.Digest); err != nil {
+				return err
+			}
+			continue
+		}
+
+		raw, err := os.ReadFile(handle.blobPath(desc.Digest))
+		if err != nil && errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		marked[desc.Digest] = struct{}{}
+
+		nested := ocispec.Index{}
+		if err := json.Unmarshal(raw, &nested); err != nil {
+			return fmt.Errorf("could not unmarshal index '%s': %w", desc.Digest.String(), err)
+		}
+
+		if err := handle.markIndex(ctx, marked, &nested); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// markManifest marks the manifest with the provided digest as well as its
+// config and layers.
+func (handle *DirectoryHandler) markManifest(ctx context.Context, marked map[digest.Digest]struct{}, dgst digest.Digest) error {
+	raw, err := os.ReadFile(handle.blobPath(dgst))
+	if err != nil && errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	marked[dgst] = struct{}{}
+
+	manifest := ocispec.Manifest{}
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		// An incomplete manifest does not reference anything.
+		log.G(ctx).
+			WithField("digest", dgst.String()).
+			Debug("could not unmarshal manifest")
+		return nil
+	}
+
+	marked[manifest.Config.Digest] = struct{}{}
+
+	for _, layer := range manifest.Layers {
+		marked[layer.Digest] = struct{}{}
+	}
+
+	return nil
+}
+
+// referrerSubjects returns the digests of the referrers of each subject.
+func (handle *DirectoryHandler) referrerSubjects() (map[digest.Digest][]digest.Digest, error) {
+	referrersDir := filepath.Join(handle.path, DirectoryHandlerReferrersDir)
+	subjects := map[digest.Digest][]digest.Digest{}
+
+	if err := filepath.WalkDir(referrersDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		target, err := os.Readlink(path)
+		if err != nil {
+			return nil
+		}
+
+		subject := digestFromPath(filepath.Dir(path))
+		subjects[subject] = append(subjects[subject], digestFromPath(target))
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("could not walk referrers directory: %w", err)
+	}
+
+	return subjects, nil
+}
+
+// blobs calls the provided function with the path, digest and file info of
+// each stored blob.
+func (handle *DirectoryHandler) blobs(fn func(string, digest.Digest, fs.FileInfo) error) error {
+	digestsDir := filepath.Join(handle.path, DirectoryHandlerDigestsDir)
+
+	if err := filepath.WalkDir(digestsDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		return fn(path, digestFromPath(path), info)
+	}); err != nil {
+		return fmt.Errorf("could not walk digests directory: %w", err)
+	}
+
+	return nil
+}
+
+// removeEmptyDirs removes all empty directories within the provided root
+// directory, excluding the root directory itself.
+func removeEmptyDirs(root string) error {
+	var dirs []string
+
+	if err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		if d.IsDir() && path != root {
+			dirs = append(dirs, path)
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	// Remove the most deeply nested directories first.
+	for i := len(dirs) - 1; i >= 0; i-- {
+		entries, err := os.ReadDir(dirs[i])
+		if err != nil {
+			return err
+		}
+
+		if len(entries) > 0 {
+			continue
+		}
+
+		if err := os.Remove(dirs[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DiskUsage implements DiskUsageReporter.
+func (handle *DirectoryHandler) DiskUsage(ctx context.Context) (*DiskUsage, error) {
+	unlock, err := handle.lockStore(false)
+	if err != nil {
+		return nil, err
+	}
+
+	defer unlock()
+
+	tags, _, err := handle.tags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	marked, err := handle.retained(ctx, tags, nil, DefaultPruneGracePeriod)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := DiskUsage{
+		Packages: len(tags),
+	}
+
+	if err := handle.blobs(func(_ string, dgst digest.Digest, info fs.FileInfo) error {
+		usage.Blobs++
+		usage.Size += info.Size()
+
+		if _, ok := marked[dgst]; !ok {
+			usage.Reclaimable += info.Size()
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return &usage, nil
+}
+
+// Prune implements Pruner.  Packages are removed following the provided
+// policy, after which the blobs which are not reachable from the remaining
+// packages, e.g. those left behind by interrupted pulls or overwritten
+// packages, are removed unless they have been written within the grace
+// period.
+func (handle *DirectoryHandler) Prune(ctx context.Context, opts PruneOptions) (*PruneReport, error) {
+	// Exclusively lock the store such that blobs which are being written, and
+	// therefore not yet referenced, are not swept.
+	unlock, err := handle.lockStore(true)
+	if err != nil {
+		return nil, err
+	}
+
+	defer unlock()
+
+	tags, invalid, err := handle.tags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := PruneReport{}
+	indexesDir := filepath.Join(handle.path, DirectoryHandlerIndexesDir)
+
+	for _, path := range invalid {
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("could not remove invalid tag: %w", err)
+		}
+
+		log.G(ctx).
+			WithField("path", path).
+			Debug("removed invalid tag")
+	}
+
+	expired := map[string]struct{}{}
+	for _, tag := range opts.expired(tags) {
+		log.G(ctx).
+			WithField("ref", tag.ref).
+			WithField("pulled", tag.pulledAt).
+			Debug("untagging")
+
+		if err := os.Remove(tag.path); err != nil {
+			return nil, fmt.Errorf("could not remove '%s': %w", tag.ref, err)
+		}
+
+		expired[tag.ref] = struct{}{}
+		report.Untagged = append(report.Untagged, tag.ref)
+	}
+
+	var kept, removed []directoryTag
+	for _, tag := range tags {
+		if _, ok := expired[tag.ref]; ok {
+			removed = append(removed, tag)
+		} else {
+			kept = append(kept, tag)
+		}
+	}
+
+	grace := opts.GracePeriod
+	if grace == 0 {
+		grace = DefaultPruneGracePeriod
+	}
+
+	marked, err := handle.retained(ctx, kept, removed, grace)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := handle.blobs(func(path string, dgst digest.Digest, info fs.FileInfo) error {
+		if _, ok := marked[dgst]; ok {
+			return nil
+		}
+
+		log.G(ctx).
+			WithField("digest", dgst.String()).
+			Trace("deleting unreferenced blob")
+
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("could not delete blob '%s': %w", dgst.String(), err)
+		}
+
+		report.Deleted = append(report.Deleted, dgst)
+		report.Reclaimed += info.Size()
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	// Remove the referrers of deleted subjects.
+	subjects, err := handle.referrerSubjects()
+	if err != nil {
+		return nil, err
+	}
+
+	for subject := range subjects {
+		if _, ok := marked[subject]; ok {
+			continue
+		}
+
+		if err := os.RemoveAll(filepath.Join(
+			handle.path,
+			DirectoryHandlerReferrersDir,
+			subject.Algorithm().String(),
+			subject.Encoded(),
+		)); err != nil {
+			return nil, fmt.Errorf("could not remove referrers of '%s': %w", subject.String(), err)
+		}
+	}
+
+	// With the store exclusively locked, no blob is being written such that
+	// left-over temporary files of interrupted writes as well as the locks of
+	// digests can be removed.
+	for _, dir := range []string{
+		filepath.Join(handle.path, DirectoryHandlerIngestDir),
+		filepath.Join(handle.path, DirectoryHandlerLocksDir, DirectoryHandlerDigestsDir),
+	} {
+		if err := os.RemoveAll(dir); err != nil {
+			return nil, fmt.Errorf("could not remove temporary files: %w", err)
+		}
+	}
+
+	for _, dir := range []string{
+		indexesDir,
+		filepath.Join(handle.path, DirectoryHandlerReferrersDir),
+	} {
+		if err := removeEmptyDirs(dir); err != nil {
+			return nil, fmt.Errorf("could not remove empty directories: %w", err)
+		}
+	}
+
+	return &report, nil
+}
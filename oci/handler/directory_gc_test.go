@@ -0,0 +1,308 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// saveBlob marshals v and stores it in the handler under the provided
+// reference.
+func saveBlob(t *testing.T, handle *DirectoryHandler, fullref, mediaType string, v interface{}) ocispec.Descriptor {
+	t.Helper()
+
+	raw, ok := v.([]byte)
+	if !ok {
+		var err error
+		if raw, err = json.Marshal(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	desc := ocispec.Descriptor{
+		MediaType: mediaType,
+		Digest:    digest.FromBytes(raw),
+		Size:      int64(len(raw)),
+	}
+
+	if err := handle.SaveDescriptor(context.Background(), fullref, desc, bytes.NewReader(raw), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	return desc
+}
+
+// savePackage stores a single-target package with the provided layers which
+// have been pulled at the provided time and returns the descriptor of its
+// index.
+func savePackage(t *testing.T, handle *DirectoryHandler, fullref string, pulledAt time.Time, layers ...[]byte) ocispec.Descriptor {
+	t.Helper()
+
+	manifest := ocispec.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageManifest,
+	}
+
+	for _, layer := range layers {
+		desc := saveBlob(t, handle, fullref, ocispec.MediaTypeImageLayer, layer)
+		if err := os.Chtimes(handle.blobPath(desc.Digest), pulledAt, pulledAt); err != nil {
+			t.Fatal(err)
+		}
+
+		manifest.Layers = append(manifest.Layers, desc)
+	}
+
+	manifest.Config = saveBlob(t, handle, fullref, ocispec.MediaTypeImageConfig, ocispec.Image{
+		Platform: ocispec.Platform{Architecture: "x86_64", OS: "qemu"},
+	})
+
+	return saveBlob(t, handle, fullref, ocispec.MediaTypeImageIndex, ocispec.Index{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: []ocispec.Descriptor{
+			saveBlob(t, handle, fullref, ocispec.MediaTypeImageManifest, manifest),
+		},
+	})
+}
+
+func exists(handle *DirectoryHandler, dgst digest.Digest) bool {
+	_, err := os.Stat(handle.blobPath(dgst))
+	return err == nil
+}
+
+func TestPrune(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	handle, err := NewDirectoryHandler(t.TempDir(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shared := []byte("shared")
+	old := []byte("old kernel")
+	recent := []byte("recent kernel")
+	other := []byte("other kernel")
+
+	savePackage(t, handle, "unikraft.org/helloworld:0.1", now.Add(-48*time.Hour), shared, old)
+	recentIndex := savePackage(t, handle, "unikraft.org/helloworld:0.2", now.Add(-time.Hour), shared, recent)
+	savePackage(t, handle, "unikraft.org/nginx:latest", now.Add(-48*time.Hour), other)
+
+	// Attach an artifact to the recent package.
+	artifact := saveBlob(t, handle, "", "application/spdx+json", []byte(`{}`))
+	referrer := saveBlob(t, handle, "", ocispec.MediaTypeImageManifest, ocispec.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    ocispec.DescriptorEmptyJSON,
+		Layers:    []ocispec.Descriptor{artifact},
+		Subject:   &recentIndex,
+	})
+
+	// Leave behind a blob of an interrupted pull.
+	orphan := saveBlob(t, handle, "", ocispec.MediaTypeImageLayer, []byte("interrupted"))
+	if err := os.Chtimes(handle.blobPath(orphan.Digest), now.Add(-48*time.Hour), now.Add(-48*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	// A blob of a package which is still being saved is retained.
+	inflight := saveBlob(t, handle, "", ocispec.MediaTypeImageLayer, []byte("in-flight"))
+
+	usage, err := handle.DiskUsage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if usage.Packages != 3 {
+		t.Errorf("expected 3 packages, got %d", usage.Packages)
+	}
+
+	if usage.Reclaimable != orphan.Size {
+		t.Errorf("expected %d reclaimable bytes, got %d", orphan.Size, usage.Reclaimable)
+	}
+
+	report, err := handle.Prune(ctx, PruneOptions{
+		KeepLast: 1,
+		Until:    now.Add(-24 * time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Untagged) != 1 || report.Untagged[0] != "unikraft.org/helloworld:0.1" {
+		t.Fatalf("expected only unikraft.org/helloworld:0.1 to be removed, got %v", report.Untagged)
+	}
+
+	for _, layer := range [][]byte{shared, recent, other} {
+		if !exists(handle, digest.FromBytes(layer)) {
+			t.Errorf("expected layer %q to be retained", layer)
+		}
+	}
+
+	for _, dgst := range []digest.Digest{referrer.Digest, artifact.Digest} {
+		if !exists(handle, dgst) {
+			t.Errorf("expected referrer blob %s to be retained", dgst)
+		}
+	}
+
+	if !exists(handle, inflight.Digest) {
+		t.Error("expected recently written blob to be retained")
+	}
+
+	for _, dgst := range []digest.Digest{digest.FromBytes(old), orphan.Digest} {
+		if exists(handle, dgst) {
+			t.Errorf("expected blob %s to be deleted", dgst)
+		}
+	}
+
+	if _, err := handle.ResolveIndex(ctx, "unikraft.org/helloworld:0.2"); err != nil {
+		t.Errorf("expected unikraft.org/helloworld:0.2 to be retained: %v", err)
+	}
+
+	usage, err = handle.DiskUsage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if usage.Packages != 2 || usage.Reclaimable != 0 {
+		t.Errorf("expected 2 packages and nothing reclaimable, got %+v", usage)
+	}
+}
+
+func TestPruneGracePeriod(t *testing.T) {
+	ctx := context.Background()
+	fullref := "unikraft.org/helloworld:latest"
+
+	handle, err := NewDirectoryHandler(t.TempDir(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A layer which is left behind long ago is reused, rather than written
+	// anew, by a package which is being saved.
+	layer := saveBlob(t, handle, fullref, ocispec.MediaTypeImageLayer, []byte("kernel"))
+	old := time.Now().Add(-2 * DefaultPruneGracePeriod)
+	if err := os.Chtimes(handle.blobPath(layer.Digest), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	config := saveBlob(t, handle, fullref, ocispec.MediaTypeImageConfig, ocispec.Image{
+		Platform: ocispec.Platform{Architecture: "x86_64", OS: "qemu"},
+	})
+
+	manifest := saveBlob(t, handle, fullref, ocispec.MediaTypeImageManifest, ocispec.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    config,
+		Layers:    []ocispec.Descriptor{layer},
+	})
+
+	report, err := handle.Prune(ctx, PruneOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Deleted) > 0 {
+		t.Fatalf("expected the blobs of the package being saved to be retained, got %v", report.Deleted)
+	}
+
+	usage, err := handle.DiskUsage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if usage.Reclaimable != 0 {
+		t.Errorf("expected nothing reclaimable, got %d", usage.Reclaimable)
+	}
+
+	// Without a grace period, all unreferenced blobs are removed.
+	report, err = handle.Prune(ctx, PruneOptions{GracePeriod: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Deleted) != 3 {
+		t.Errorf("expected 3 unreferenced blobs to be deleted, got %v", report.Deleted)
+	}
+
+	for _, dgst := range []digest.Digest{layer.Digest, config.Digest, manifest.Digest} {
+		if exists(handle, dgst) {
+			t.Errorf("expected blob %s to be deleted", dgst)
+		}
+	}
+}
+
+func TestPruneDuringSave(t *testing.T) {
+	ctx := context.Background()
+
+	handle, err := NewDirectoryHandler(t.TempDir(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	pruneErr := make(chan error, 1)
+
+	// Continuously prune whilst packages are being saved one blob at a time.
+	go func() {
+		defer close(pruneErr)
+
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			if _, err := handle.Prune(ctx, PruneOptions{}); err != nil {
+				pruneErr <- err
+				return
+			}
+		}
+	}()
+
+	var refs []string
+	for i := 0; i < 25; i++ {
+		fullref := fmt.Sprintf("unikraft.org/helloworld:%d", i)
+		savePackage(t, handle, fullref, time.Now(), []byte(fmt.Sprintf("kernel %d", i)))
+		refs = append(refs, fullref)
+	}
+
+	close(done)
+	if err := <-pruneErr; err != nil {
+		t.Fatalf("could not prune: %v", err)
+	}
+
+	for _, fullref := range refs {
+		index, err := handle.ResolveIndex(ctx, fullref)
+		if err != nil {
+			t.Errorf("expected %s to be resolvable: %v", fullref, err)
+			continue
+		}
+
+		for _, desc := range index.Manifests {
+			manifest, err := handle.ResolveManifest(ctx, fullref, desc.Digest)
+			if err != nil {
+				t.Errorf("expected manifest of %s to be retained: %v", fullref, err)
+				continue
+			}
+
+			for _, blob := range append([]ocispec.Descriptor{manifest.Config}, manifest.Layers...) {
+				if !exists(handle, blob.Digest) {
+					t.Errorf("expected blob %s of %s to be retained", blob.Digest, fullref)
+				}
+			}
+		}
+	}
+}
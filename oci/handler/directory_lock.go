@@ -0,0 +1,159 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package handler
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/opencontainers/go-digest"
+
+	"kraftkit.sh/internal/filelock"
+)
+
+const (
+	DirectoryHandlerIngestDir = "ingest"
+	DirectoryHandlerLocksDir  = "locks"
+)
+
+// directoryStoreLock is the lock which is held, shared, whilst the store is
+// written to and, exclusively, whilst it is pruned, such that blobs which are
+// written but not yet referenced are never swept.
+const directoryStoreLock = "store.lock"
+
+// linkCounter disambiguates temporary symbolic links created concurrently by
+// the same process.
+var linkCounter atomic.Uint64
+
+// lock acquires the advisory file lock at the provided path relative to the
+// locks directory and returns the function which releases it.  The lock is
+// held across processes as well as across goroutines of the same process.  On
+// platforms which do not support file locking, no lock is held.
+func (handle *DirectoryHandler) lock(exclusive bool, elem ...string) (func(), error) {
+	path := filepath.Join(append([]string{handle.path, DirectoryHandlerLocksDir}, elem...)...)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o775); err != nil {
+		return nil, fmt.Errorf("could not make lock directory: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o664)
+	if err != nil {
+		return nil, fmt.Errorf("could not open lock: %w", err)
+	}
+
+	if exclusive {
+		err = filelock.Lock(f)
+	} else {
+		err = filelock.RLock(f)
+	}
+	if err != nil && filelock.IsNotSupported(err) {
+		return func() { f.Close() }, nil
+	} else if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("could not acquire lock '%s': %w", path, err)
+	}
+
+	return func() {
+		_ = filelock.Unlock(f)
+		f.Close()
+	}, nil
+}
+
+// lockStore acquires the store-wide lock.
+func (handle *DirectoryHandler) lockStore(exclusive bool) (func(), error) {
+	return handle.lock(exclusive, directoryStoreLock)
+}
+
+// lockRef exclusively acquires the lock of the provided reference, which
+// guards reading and replacing the index it is tagged with.
+func (handle *DirectoryHandler) lockRef(ref string) (func(), error) {
+	return handle.lock(true, "refs", digest.FromString(ref).Encoded()+".lock")
+}
+
+// lockDigest exclusively acquires the lock of the provided digest, which is
+// held whilst the blob is being pulled such that concurrent pulls of the same
+// blob are performed only once.
+func (handle *DirectoryHandler) lockDigest(dgst digest.Digest) (func(), error) {
+	return handle.lock(true, DirectoryHandlerDigestsDir, dgst.Algorithm().String(), dgst.Encoded()+".lock")
+}
+
+// writeBlob writes the content of the provided reader to the blob with the
+// provided digest.  The content is first written to a temporary file which is
+// then renamed, such that the blob is never observed partially written, e.g.
+// by a concurrent reader or following an interrupted pull.
+func (handle *DirectoryHandler) writeBlob(dgst digest.Digest, reader io.Reader) error {
+	ingestDir := filepath.Join(handle.path, DirectoryHandlerIngestDir)
+	if err := os.MkdirAll(ingestDir, 0o775); err != nil {
+		return fmt.Errorf("could not make ingest directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(ingestDir, dgst.Encoded()+"-*")
+	if err != nil {
+		return fmt.Errorf("could not create blob: %w", err)
+	}
+
+	if _, err := io.Copy(tmp, reader); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("could not write blob: %w", err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("could not sync blob: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("could not close blob: %w", err)
+	}
+
+	if err := os.Chmod(tmp.Name(), 0o664); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("could not set blob permissions: %w", err)
+	}
+
+	blobPath := handle.blobPath(dgst)
+	if err := os.MkdirAll(filepath.Dir(blobPath), 0o775); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("could not make parent directory: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), blobPath); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("could not commit blob: %w", err)
+	}
+
+	return nil
+}
+
+// symlink creates the symbolic link at path which points to target, replacing
+// any existing file at path atomically.
+func (handle *DirectoryHandler) symlink(target, path string) error {
+	ingestDir := filepath.Join(handle.path, DirectoryHandlerIngestDir)
+	if err := os.MkdirAll(ingestDir, 0o775); err != nil {
+		return fmt.Errorf("could not make ingest directory: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o775); err != nil {
+		return fmt.Errorf("could not make parent directory: %w", err)
+	}
+
+	tmp := filepath.Join(ingestDir, fmt.Sprintf("link-%d-%d", os.Getpid(), linkCounter.Add(1)))
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return nil
+}
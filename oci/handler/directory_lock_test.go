@@ -0,0 +1,320 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// stressEnv selects the operation performed by TestStressHelper when the test
+// binary is re-executed as one of several concurrent processes.
+const stressEnv = "KRAFTKIT_TEST_DIRECTORY_HANDLER_STRESS"
+
+// stress runs the provided operation of TestStressHelper in n concurrent
+// processes against the same store and fails if any of them fails.
+func stress(t *testing.T, n int, env ...string) {
+	t.Helper()
+
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	outs := make([][]byte, n)
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			cmd := exec.Command(os.Args[0], "-test.run=^TestStressHelper$", "-test.v")
+			cmd.Env = append(append(os.Environ(), env...), "STRESS_ID="+strconv.Itoa(i))
+			outs[i], errs[i] = cmd.CombinedOutput()
+		}(i)
+	}
+
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("process %d failed: %v\n%s", i, err, outs[i])
+		}
+	}
+}
+
+// TestStressHelper is not a test by itself but performs the operation of a
+// single process of a stress test.
+func TestStressHelper(t *testing.T) {
+	op := os.Getenv(stressEnv)
+	if op == "" {
+		t.Skip("only run as a helper process")
+	}
+
+	ctx := context.Background()
+
+	handle, err := NewDirectoryHandler(os.Getenv("STRESS_ROOT"), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fullref := os.Getenv("STRESS_REF")
+
+	switch op {
+	case "pull":
+		// Alternate between the provided references such that the same layer is
+		// pulled concurrently as part of distinct packages.
+		refs := strings.Split(fullref, ",")
+		id, err := strconv.Atoi(os.Getenv("STRESS_ID"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := handle.PullDigest(ctx,
+			ocispec.MediaTypeImageIndex,
+			refs[id%len(refs)],
+			digest.Digest(os.Getenv("STRESS_DIGEST")),
+			&ocispec.Platform{OS: "qemu", Architecture: "x86_64"},
+			nil,
+		); err != nil {
+			t.Fatal(err)
+		}
+
+	case "save":
+		iterations, err := strconv.Atoi(os.Getenv("STRESS_ITERATIONS"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i := 0; i < iterations; i++ {
+			// All processes save the same layer but distinct indexes.
+			savePackage(t, handle, fullref, time.Now(),
+				[]byte("shared layer"),
+				[]byte(fmt.Sprintf("layer %s/%d", os.Getenv("STRESS_ID"), i)),
+			)
+
+			if _, err := handle.ResolveIndex(ctx, fullref); err != nil {
+				t.Fatalf("could not resolve index: %v", err)
+			}
+		}
+
+	default:
+		t.Fatalf("unknown operation: %s", op)
+	}
+}
+
+func TestStressConcurrentPull(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping stress test in short mode")
+	}
+
+	// Slow down layer downloads to ensure the processes overlap and count how
+	// often the layer is fetched.
+	var fetches atomic.Int32
+	layerContent := make([]byte, 4<<20)
+	if _, err := rand.Read(layerContent); err != nil {
+		t.Fatal(err)
+	}
+
+	layerDigest := digest.FromBytes(layerContent)
+	reg := registry.New()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/blobs/"+layerDigest.String()) {
+			fetches.Add(1)
+			time.Sleep(200 * time.Millisecond)
+		}
+
+		reg.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	repository := strings.TrimPrefix(server.URL, "http://") + "/unikraft/stress"
+	refs := []string{repository + ":latest", repository + ":v1"}
+
+	image, err := mutate.ConfigFile(
+		mutate.ConfigMediaType(mutate.MediaType(empty.Image, types.OCIManifestSchema1), types.OCIConfigJSON),
+		&v1.ConfigFile{OS: "qemu", Architecture: "x86_64"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	image, err = mutate.AppendLayers(image, static.NewLayer(layerContent, types.OCILayer))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	index := mutate.AppendManifests(mutate.IndexMediaType(empty.Index, types.OCIImageIndex), mutate.IndexAddendum{
+		Add: image,
+		Descriptor: v1.Descriptor{
+			Platform: &v1.Platform{OS: "qemu", Architecture: "x86_64"},
+		},
+	})
+
+	for _, fullref := range refs {
+		ref, err := name.ParseReference(fullref)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := remote.WriteIndex(ref, index); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	indexDigest, err := index.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The registry has been written to, only count the pulls.
+	fetches.Store(0)
+
+	root := t.TempDir()
+	stress(t, 8,
+		stressEnv+"=pull",
+		"STRESS_ROOT="+root,
+		"STRESS_REF="+strings.Join(refs, ","),
+		"STRESS_DIGEST="+indexDigest.String(),
+	)
+
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("expected layer to be fetched once, got %d", n)
+	}
+
+	handle, err := NewDirectoryHandler(root, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, fullref := range refs {
+		pulled, err := handle.ResolveIndex(context.Background(), fullref)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(pulled.Manifests) != 1 {
+			t.Errorf("expected index of %s with 1 manifest, got %d", fullref, len(pulled.Manifests))
+		}
+	}
+
+	raw, err := os.ReadFile(handle.blobPath(layerDigest))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(raw, layerContent) {
+		t.Error("expected pulled layer to be intact")
+	}
+}
+
+func TestStressConcurrentSave(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping stress test in short mode")
+	}
+
+	ctx := context.Background()
+	root := t.TempDir()
+	fullref := "unikraft.org/stress:latest"
+
+	handle, err := NewDirectoryHandler(root, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	savePackage(t, handle, fullref, time.Now(), []byte("shared layer"))
+
+	// Continuously read the tagged index whilst it is being replaced, which must
+	// never be observed missing or partially written.
+	done := make(chan struct{})
+	readErr := make(chan error, 1)
+
+	go func() {
+		defer close(readErr)
+
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			if _, err := handle.ResolveIndex(ctx, fullref); err != nil {
+				readErr <- err
+				return
+			}
+		}
+	}()
+
+	stress(t, 8,
+		stressEnv+"=save",
+		"STRESS_ROOT="+root,
+		"STRESS_REF="+fullref,
+		"STRESS_ITERATIONS=25",
+	)
+
+	close(done)
+	if err := <-readErr; err != nil {
+		t.Errorf("could not read index during concurrent saves: %v", err)
+	}
+
+	index, err := handle.ResolveIndex(ctx, fullref)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := json.Marshal(ocispec.Index{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: index.Manifests,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The tag must point to an intact blob.
+	if _, err := os.Stat(handle.blobPath(digest.FromBytes(raw))); err != nil {
+		t.Errorf("expected tag to point to an intact index: %v", err)
+	}
+
+	for _, manifest := range index.Manifests {
+		if _, err := handle.ResolveManifest(ctx, fullref, manifest.Digest); err != nil {
+			t.Errorf("expected manifest of tagged index to exist: %v", err)
+		}
+	}
+
+	// No temporary files must remain.
+	entries, err := os.ReadDir(root + "/" + DirectoryHandlerIngestDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) > 0 {
+		t.Errorf("expected no temporary files to remain, got %d", len(entries))
+	}
+}
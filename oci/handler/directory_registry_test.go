@@ -0,0 +1,261 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"kraftkit.sh/config"
+)
+
+// pushPackage pushes a single-target package with the provided layer to the
+// provided reference and returns the digest of its index.
+func pushPackage(t *testing.T, fullref string, layer []byte, opts ...remote.Option) digest.Digest {
+	t.Helper()
+
+	image, err := mutate.ConfigFile(
+		mutate.ConfigMediaType(mutate.MediaType(empty.Image, types.OCIManifestSchema1), types.OCIConfigJSON),
+		&v1.ConfigFile{OS: "qemu", Architecture: "x86_64"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	image, err = mutate.AppendLayers(image, static.NewLayer(layer, types.OCILayer))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	index := mutate.AppendManifests(mutate.IndexMediaType(empty.Index, types.OCIImageIndex), mutate.IndexAddendum{
+		Add: image,
+		Descriptor: v1.Descriptor{
+			Platform: &v1.Platform{OS: "qemu", Architecture: "x86_64"},
+		},
+	})
+
+	ref, err := name.ParseReference(fullref)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := remote.WriteIndex(ref, index, opts...); err != nil {
+		t.Fatal(err)
+	}
+
+	dgst, err := index.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return digest.Digest(dgst.String())
+}
+
+// pull pulls the package at the provided reference into a new directory
+// handler which is configured with the provided registries.
+func pull(t *testing.T, registries map[string]config.RegistryConfig, fullref string, dgst digest.Digest) (*DirectoryHandler, error) {
+	t.Helper()
+
+	handle, err := NewDirectoryHandler(t.TempDir(), nil, registries)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return handle, handle.PullDigest(context.Background(),
+		ocispec.MediaTypeImageIndex,
+		fullref,
+		dgst,
+		&ocispec.Platform{OS: "qemu", Architecture: "x86_64"},
+		nil,
+	)
+}
+
+func TestPullFromMirror(t *testing.T) {
+	// The first mirror does not serve the package, the second serves it beneath
+	// a prefix and the registry itself cannot be reached.
+	empty := httptest.NewServer(registry.New())
+	defer empty.Close()
+
+	mirror := httptest.NewServer(registry.New())
+	defer mirror.Close()
+
+	emptyHost := strings.TrimPrefix(empty.URL, "http://")
+	mirrorHost := strings.TrimPrefix(mirror.URL, "http://")
+	layer := []byte("mirrored kernel")
+
+	dgst := pushPackage(t, mirrorHost+"/proxy/unikraft.invalid/helloworld:latest", layer)
+
+	fullref := "unikraft.invalid/helloworld:latest"
+
+	if _, err := pull(t, nil, fullref, dgst); err == nil {
+		t.Fatal("expected pull without mirrors to fail")
+	}
+
+	handle, err := pull(t, map[string]config.RegistryConfig{
+		"unikraft.invalid": {
+			Mirrors: []string{
+				emptyHost,
+				mirrorHost + "/proxy/unikraft.invalid",
+			},
+		},
+	}, fullref, dgst)
+	if err != nil {
+		t.Fatalf("could not pull through mirror: %v", err)
+	}
+
+	// The package is stored under its original name.
+	index, err := handle.ResolveIndex(context.Background(), fullref)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(index.Manifests) != 1 {
+		t.Fatalf("expected index with 1 manifest, got %d", len(index.Manifests))
+	}
+
+	if !exists(handle, digest.FromBytes(layer)) {
+		t.Error("expected layer to be pulled from mirror")
+	}
+}
+
+func TestPullFromTLSRegistry(t *testing.T) {
+	server := httptest.NewTLSServer(registry.New())
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: server.Certificate().Raw,
+	}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		config config.RegistryConfig
+		fails  bool
+	}{
+		{
+			name:  "untrusted",
+			fails: true,
+		},
+		{
+			name:   "ca-file",
+			config: config.RegistryConfig{CAFile: caFile},
+		},
+		{
+			name:   "insecure",
+			config: config.RegistryConfig{Insecure: true},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// Use a distinct repository per case as remote indexes are cached.
+			fullref := host + "/unikraft/" + tc.name + ":latest"
+			dgst := pushPackage(t, fullref, []byte(tc.name), remote.WithTransport(server.Client().Transport))
+
+			_, err := pull(t, map[string]config.RegistryConfig{host: tc.config}, fullref, dgst)
+			if tc.fails && err == nil {
+				t.Error("expected pull to fail")
+			} else if !tc.fails && err != nil {
+				t.Errorf("could not pull: %v", err)
+			}
+		})
+	}
+}
+
+func TestPullResumesInterruptedDownload(t *testing.T) {
+	layer := bytes.Repeat([]byte("unikernel"), 64<<10)
+	layerDigest := digest.FromBytes(layer)
+
+	var interrupted atomic.Bool
+	var ranges []string
+	var mu sync.Mutex
+
+	reg := registry.New()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || !strings.HasSuffix(r.URL.Path, "/blobs/"+layerDigest.String()) {
+			reg.ServeHTTP(w, r)
+			return
+		}
+
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		mu.Unlock()
+
+		// Abort the first download of the layer halfway through.
+		if interrupted.CompareAndSwap(false, true) {
+			w.Header().Set("Content-Length", strconv.Itoa(len(layer)))
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(layer[:len(layer)/2])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+
+		reg.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	fullref := strings.TrimPrefix(server.URL, "http://") + "/unikraft/resume:latest"
+	dgst := pushPackage(t, fullref, layer)
+
+	handle, err := NewDirectoryHandler(t.TempDir(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pull := func() error {
+		return handle.PullDigest(context.Background(),
+			ocispec.MediaTypeImageIndex,
+			fullref,
+			dgst,
+			&ocispec.Platform{OS: "qemu", Architecture: "x86_64"},
+			nil,
+		)
+	}
+
+	if err := pull(); err == nil {
+		t.Fatal("expected interrupted pull to fail")
+	}
+
+	if err := pull(); err != nil {
+		t.Fatalf("could not resume pull: %v", err)
+	}
+
+	if len(ranges) != 2 || ranges[0] != "" || !strings.HasPrefix(ranges[1], "bytes=") || ranges[1] == "bytes=0-" {
+		t.Fatalf("expected second download to request a range, got %q", ranges)
+	}
+
+	blob, err := os.ReadFile(handle.blobPath(layerDigest))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(blob, layer) {
+		t.Error("expected resumed layer to be intact")
+	}
+}
@@ -17,6 +17,12 @@ type DigestResolver interface {
 	DigestInfo(context.Context, digest.Digest) (*content.Info, error)
 }
 
+type DigestReader interface {
+	// ReadDigest returns a reader of the locally stored blob with the provided
+	// digest.  The reader must be closed by the caller.
+	ReadDigest(context.Context, digest.Digest) (io.ReadCloser, error)
+}
+
 type DigestPuller interface {
 	// PullDigest retrieves the provided mediaType, full canonically referencable
 	// image and its digest for the given platform and returns the progress of
@@ -65,12 +71,33 @@ type IndexDeleter interface {
 	DeleteIndex(context.Context, string, bool) error
 }
 
+type ReferrersResolver interface {
+	// ResolveReferrers returns the descriptors of all locally stored manifests
+	// whose subject is the provided digest.  The results can optionally be
+	// filtered by the artifact type of the referring manifest.
+	ResolveReferrers(ctx context.Context, fullref string, subject digest.Digest, artifactType string) ([]ocispec.Descriptor, error)
+}
+
 type ImageUnpacker interface {
 	UnpackImage(context.Context, string, digest.Digest, string) (*ocispec.Image, error)
 }
 
+// DiskUsageReporter is implemented by handlers which are able to account for
+// the space occupied by the packages they store.
+type DiskUsageReporter interface {
+	DiskUsage(context.Context) (*DiskUsage, error)
+}
+
+// Pruner is implemented by handlers which are able to remove the packages
+// selected by the provided policy and the blobs which are no longer referenced
+// by any of the remaining packages.
+type Pruner interface {
+	Prune(context.Context, PruneOptions) (*PruneReport, error)
+}
+
 type Handler interface {
 	DigestResolver
+	DigestReader
 	DigestPuller
 	DescriptorSaver
 	DescriptorPusher
@@ -80,5 +107,6 @@ type Handler interface {
 	IndexResolver
 	IndexLister
 	IndexDeleter
+	ReferrersResolver
 	ImageUnpacker
 }
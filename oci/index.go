@@ -73,6 +73,12 @@ func NewIndexFromSpec(ctx context.Context, handle handler.Handler, spec *ocispec
 			return nil, fmt.Errorf("could not instantiate manifest from structure: %w", err)
 		}
 
+		// The descriptor within the index is authoritative, e.g. it contains the
+		// actual size of the manifest, such that the manifest can be added to
+		// other indexes as-is.
+		desc := desc
+		manifest.desc = &desc
+
 		index.manifests = append(index.manifests, manifest)
 	}
 
@@ -5,11 +5,20 @@
 package oci
 
 import (
+	"archive/tar"
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
+	"github.com/opencontainers/go-digest"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"kraftkit.sh/archive"
+	"kraftkit.sh/internal/chunker"
 )
 
 type Layer struct {
@@ -18,7 +27,8 @@ type Layer struct {
 	blob *Blob
 }
 
-// NewLayerFromFile creates a new layer from a given blob
+// NewLayerFromFile creates a new layer from a given blob.  If the source is a
+// directory, its contents are recursively placed at the destination.
 func NewLayerFromFile(ctx context.Context, mediaType, src, dst string, opts ...LayerOption) (*Layer, error) {
 	if mediaType == "" {
 		mediaType = ocispec.MediaTypeImageLayer
@@ -38,7 +48,14 @@ func NewLayerFromFile(ctx context.Context, mediaType, src, dst string, opts ...L
 			return nil, err
 		}
 
-		if err := archive.TarFileTo(ctx,
+		if fi, err := os.Stat(src); err == nil && fi.IsDir() {
+			if err := archive.TarDir(ctx,
+				src, dst, tmp.Name(),
+				archive.WithStripTimes(true),
+			); err != nil {
+				return nil, err
+			}
+		} else if err := archive.TarFileTo(ctx,
 			src, dst, tmp.Name(),
 			archive.WithStripTimes(true),
 			archive.WithGzip(mediaType == MediaTypeImageKernelGzip),
@@ -77,3 +94,219 @@ func NewLayerFromFile(ctx context.Context, mediaType, src, dst string, opts ...L
 
 	return &layer, nil
 }
+
+// NewLayerFromDirs creates a new layer which is an uncompressed tarball of the
+// provided directories, indexed by their destination within the layer.  Only
+// the files which are accepted by the optional filter are included.
+func NewLayerFromDirs(ctx context.Context, mediaType string, dirs map[string]string, filter archive.FilterFunc, opts ...LayerOption) (*Layer, error) {
+	dsts := make([]string, 0, len(dirs))
+	for dst := range dirs {
+		dsts = append(dsts, dst)
+	}
+
+	// Sort the destinations such that the resulting layer is reproducible.
+	sort.Strings(dsts)
+
+	tmp, err := os.CreateTemp("", "kraftkit-ociblob*")
+	if err != nil {
+		return nil, err
+	}
+
+	tw := tar.NewWriter(tmp)
+
+	for _, dst := range dsts {
+		if err := archive.TarDirWriter(ctx,
+			dirs[dst], dst, tw,
+			archive.WithStripTimes(true),
+			archive.WithFilter(filter),
+		); err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+
+	layer := Layer{
+		dst: strings.Join(dsts, ","),
+		tmp: tmp.Name(),
+	}
+
+	layer.blob, err = NewBlobFromFile(ctx, mediaType, tmp.Name(),
+		WithBlobRemoveAfterSave(true),
+	)
+	if err != nil {
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+
+	for _, opt := range opts {
+		if err := opt(&layer); err != nil {
+			return nil, err
+		}
+	}
+
+	return &layer, nil
+}
+
+// ChunkIndexName is the name of the file within the destination directory of
+// a chunked file which lists the names of its chunks in order, one per line.
+const ChunkIndexName = "index"
+
+// NewLayersFromChunkedFile splits the provided file into content-defined
+// chunks and creates a layer for each of them, in order, which places the
+// chunk at a path within the destination directory named after its digest.
+// Chunks which are unchanged between versions of the file therefore result in
+// identical layers.  Each layer is annotated with the path of its chunk.  A
+// last layer places the index of the chunks within the destination directory
+// such that the file can be reassembled with AssembleChunkedFile from the
+// directory alone.
+func NewLayersFromChunkedFile(ctx context.Context, src, dst string, opts ...LayerOption) ([]*Layer, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	c, err := chunker.New(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var layers []*Layer
+	var index strings.Builder
+
+	removeAll := func() {
+		for _, layer := range layers {
+			os.Remove(layer.tmp)
+		}
+	}
+
+	for {
+		chunk, err := c.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			removeAll()
+			return nil, fmt.Errorf("could not chunk '%s': %w", src, err)
+		}
+
+		name := digest.FromBytes(chunk).Encoded()
+		path := filepath.Join(dst, name)
+
+		layer, err := newLayerFromBytes(ctx, chunk, path,
+			append(opts, WithLayerAnnotation(AnnotationKernelInitrdChunk, path))...,
+		)
+		if err != nil {
+			removeAll()
+			return nil, err
+		}
+
+		layers = append(layers, layer)
+
+		fmt.Fprintln(&index, name)
+	}
+
+	layer, err := newLayerFromBytes(ctx, []byte(index.String()), filepath.Join(dst, ChunkIndexName), opts...)
+	if err != nil {
+		removeAll()
+		return nil, err
+	}
+
+	return append(layers, layer), nil
+}
+
+// newLayerFromBytes creates a layer which places the provided content at the
+// destination path.
+func newLayerFromBytes(ctx context.Context, b []byte, dst string, opts ...LayerOption) (*Layer, error) {
+	tmp, err := os.CreateTemp("", "kraftkit-chunk*")
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+
+	return NewLayerFromFile(ctx,
+		ocispec.MediaTypeImageLayer,
+		tmp.Name(),
+		dst,
+		opts...,
+	)
+}
+
+// AssembleChunkedFile concatenates the chunks within the provided directory,
+// in the order of its index, into the file at the destination path, as they
+// are placed by the layers of NewLayersFromChunkedFile.  The directory is
+// removed afterwards.  Nothing is done if the directory does not exist.
+func AssembleChunkedFile(dir, dst string) error {
+	index, err := os.ReadFile(filepath.Join(dir, ChunkIndexName))
+	if errors.Is(err, os.ErrNotExist) {
+		if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return fmt.Errorf("missing index of chunks in '%s'", dir)
+	} else if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	for _, name := range strings.Fields(string(index)) {
+		if name != filepath.Base(name) || name == ChunkIndexName {
+			return fmt.Errorf("invalid chunk name '%s'", name)
+		}
+
+		if err := appendFile(f, filepath.Join(dir, name)); err != nil {
+			return fmt.Errorf("could not append chunk: %w", err)
+		}
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.RemoveAll(dir)
+}
+
+// appendFile appends the content of the file at the provided path to w.
+func appendFile(w io.Writer, path string) error {
+	chunk, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer chunk.Close()
+
+	_, err = io.Copy(w, chunk)
+	return err
+}
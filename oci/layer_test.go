@@ -0,0 +1,229 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package oci
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"kraftkit.sh/cpio"
+	"kraftkit.sh/oci/handler"
+)
+
+// newTestInitrd writes a CPIO archive with a single file of the provided size
+// of pseudo-random content and returns its path.
+func newTestInitrd(t *testing.T, size int) string {
+	t.Helper()
+
+	content := make([]byte, size)
+	rand.New(rand.NewSource(1)).Read(content)
+
+	path := filepath.Join(t.TempDir(), "initrd.cpio")
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	w := cpio.NewWriter(f)
+
+	if err := w.WriteHeader(&cpio.Header{
+		Name: "./data",
+		Mode: cpio.TypeReg | 0o644,
+		Size: int64(size),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.Write(content); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestChunkedInitrdRoundTrip(t *testing.T) {
+	ctx := context.Background()
+
+	server := httptest.NewServer(registry.New())
+	t.Cleanup(server.Close)
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	fullref := host + "/unikraft.org/chunked:latest"
+
+	src, err := handler.NewDirectoryHandler(t.TempDir(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The initrd exceeds the maximum size of a chunk such that it is split into
+	// several.
+	initrdPath := newTestInitrd(t, 24<<20)
+
+	kernelPath := filepath.Join(t.TempDir(), "kernel")
+	if err := os.WriteFile(kernelPath, []byte("kernel"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	kernel, err := NewLayerFromFile(ctx, ocispec.MediaTypeImageLayer, kernelPath, WellKnownKernelPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chunks, err := NewLayersFromChunkedFile(ctx, initrdPath, WellKnownInitrdChunksDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every chunk is followed by the index of the chunks.
+	if len(chunks) < 3 {
+		t.Fatalf("expected the initrd to be split into several chunks, got %d layers", len(chunks))
+	}
+
+	manifest, err := NewManifest(ctx, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifest.SetOS(ctx, "qemu")
+	manifest.SetArchitecture(ctx, "x86_64")
+
+	for _, layer := range append([]*Layer{kernel}, chunks...) {
+		if _, err := manifest.AddLayer(ctx, layer); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	index, err := NewIndex(ctx, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := index.AddManifest(ctx, manifest); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := index.Save(ctx, fullref, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Push the package to the registry and pull it into another store.
+	if err := Copy(ctx, Location{Handle: src, Ref: fullref}, Location{Ref: fullref}); err != nil {
+		t.Fatalf("could not push: %v", err)
+	}
+
+	dst, err := handler.NewDirectoryHandler(t.TempDir(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Copy(ctx, Location{Ref: fullref}, Location{Handle: dst, Ref: fullref}); err != nil {
+		t.Fatalf("could not pull: %v", err)
+	}
+
+	digests := manifestDigests(t, dst, fullref)
+	if len(digests) != 1 {
+		t.Fatalf("expected a single manifest to be pulled, got %v", digests)
+	}
+
+	pulled, err := NewPackageFromOCIManifestDigest(ctx, dst, fullref, nil, digest.Digest(digests[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := pulled.Unpack(ctx, dir); err != nil {
+		t.Fatalf("could not unpack: %v", err)
+	}
+
+	expected, err := os.ReadFile(initrdPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := os.ReadFile(filepath.Join(dir, WellKnownInitrdPath))
+	if err != nil {
+		t.Fatalf("expected the initrd to be reassembled: %v", err)
+	}
+
+	if !bytes.Equal(actual, expected) {
+		t.Errorf("expected the reassembled initrd to be identical to the packaged initrd (%d bytes), got %d bytes", len(expected), len(actual))
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, WellKnownInitrdChunksDir)); !os.IsNotExist(err) {
+		t.Errorf("expected the chunks to be removed, got %v", err)
+	}
+}
+
+func TestAssembleChunkedFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "initrd.d")
+	dst := filepath.Join(t.TempDir(), "initrd")
+
+	// Nothing is assembled without chunks.
+	if err := AssembleChunkedFile(dir, dst); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Fatalf("expected no file to be assembled, got %v", err)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range map[string]string{
+		"aa":           "first ",
+		"bb":           "second ",
+		ChunkIndexName: "bb\naa\nbb\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := AssembleChunkedFile(dir, dst); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Repeated chunks are only stored once but appended as often as listed.
+	if expected := "second first second "; string(b) != expected {
+		t.Errorf("expected %q, got %q", expected, b)
+	}
+
+	// The chunks of an index which escapes the directory are rejected.
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, ChunkIndexName), []byte("../initrd\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := AssembleChunkedFile(dir, dst); err == nil {
+		t.Error("expected an error for a chunk outside of the directory")
+	}
+}
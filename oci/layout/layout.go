@@ -0,0 +1,578 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+// Package layout exports packages from and imports packages into a local
+// handler as OCI image-layout tarballs, which allows packages to be moved
+// between hosts without access to a registry.
+package layout
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/containerd/containerd/images"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"kraftkit.sh/log"
+	"kraftkit.sh/oci/handler"
+)
+
+const (
+	// BlobsDir is the directory within the layout which contains the blobs.
+	BlobsDir = "blobs"
+
+	// IndexFile is the entrypoint of the layout which lists its images.
+	IndexFile = "index.json"
+)
+
+// writer accumulates the blobs of the images which are written to a layout.
+type writer struct {
+	handle handler.Handler
+
+	// blobs lists the descriptors of all blobs in the order in which they are
+	// written.
+	blobs []ocispec.Descriptor
+
+	// raw contains the contents of blobs which are not read from the handler.
+	raw map[digest.Digest][]byte
+
+	// seen tracks blobs which have already been added.
+	seen map[digest.Digest]bool
+
+	// index is the top-level index of the layout.
+	index ocispec.Index
+}
+
+// Save exports the indexes of the provided canonical references, including
+// all of their manifests, configs, layers and locally stored referrers such as
+// signatures and SBOMs, as an OCI image-layout tarball to w.
+func Save(ctx context.Context, handle handler.Handler, w io.Writer, refs ...string) error {
+	if len(refs) == 0 {
+		return fmt.Errorf("no references provided")
+	}
+
+	lw := &writer{
+		handle: handle,
+		raw:    make(map[digest.Digest][]byte),
+		seen:   make(map[digest.Digest]bool),
+		index: ocispec.Index{
+			Versioned: specs.Versioned{
+				SchemaVersion: 2,
+			},
+			MediaType: ocispec.MediaTypeImageIndex,
+		},
+	}
+
+	for _, fullref := range refs {
+		ref, err := name.ParseReference(fullref)
+		if err != nil {
+			return fmt.Errorf("could not parse reference '%s': %w", fullref, err)
+		}
+
+		index, err := handle.ResolveIndex(ctx, ref.Name())
+		if err != nil {
+			return fmt.Errorf("could not resolve index of '%s': %w", ref.Name(), err)
+		}
+
+		// Locally stored indexes are identified by the digest of their JSON
+		// representation.
+		indexRaw, err := json.Marshal(index)
+		if err != nil {
+			return fmt.Errorf("could not marshal index: %w", err)
+		}
+
+		desc := ocispec.Descriptor{
+			MediaType: ocispec.MediaTypeImageIndex,
+			Digest:    digest.FromBytes(indexRaw),
+			Size:      int64(len(indexRaw)),
+			Annotations: map[string]string{
+				ocispec.AnnotationRefName:  ref.Identifier(),
+				images.AnnotationImageName: ref.Name(),
+			},
+		}
+
+		log.G(ctx).
+			WithField("ref", ref.Name()).
+			WithField("digest", desc.Digest.String()).
+			Debug("saving index")
+
+		for _, manifest := range index.Manifests {
+			if err := lw.addManifest(ctx, ref.Context().Name(), manifest); err != nil {
+				return err
+			}
+		}
+
+		lw.addBlob(desc, indexRaw)
+		lw.index.Manifests = append(lw.index.Manifests, desc)
+
+		if err := lw.addReferrers(ctx, ref.Context().Name(), desc.Digest); err != nil {
+			return err
+		}
+	}
+
+	return lw.write(ctx, w)
+}
+
+// addBlob adds the blob of the descriptor to the layout, whose contents are
+// read from the handler unless they are provided.
+func (lw *writer) addBlob(desc ocispec.Descriptor, raw []byte) {
+	if lw.seen[desc.Digest] {
+		return
+	}
+
+	lw.seen[desc.Digest] = true
+	lw.blobs = append(lw.blobs, desc)
+
+	if raw != nil {
+		lw.raw[desc.Digest] = raw
+	}
+}
+
+// addManifest adds the manifest with the provided descriptor as well as its
+// config, its layers and its referrers to the layout.
+func (lw *writer) addManifest(ctx context.Context, repository string, desc ocispec.Descriptor) error {
+	if lw.seen[desc.Digest] {
+		return nil
+	}
+
+	reader, err := lw.handle.ReadDigest(ctx, desc.Digest)
+	if err != nil {
+		return fmt.Errorf("could not read manifest '%s': %w", desc.Digest.String(), err)
+	}
+
+	defer reader.Close()
+
+	raw, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("could not read manifest '%s': %w", desc.Digest.String(), err)
+	}
+
+	manifest := ocispec.Manifest{}
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return fmt.Errorf("could not unmarshal manifest '%s': %w", desc.Digest.String(), err)
+	}
+
+	lw.addBlob(manifest.Config, nil)
+
+	for _, layer := range manifest.Layers {
+		lw.addBlob(layer, nil)
+	}
+
+	lw.addBlob(ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    desc.Digest,
+		Size:      int64(len(raw)),
+	}, raw)
+
+	return lw.addReferrers(ctx, repository, desc.Digest)
+}
+
+// addReferrers adds all locally stored artifacts which refer to the subject to
+// the layout.  They are listed in its index such that they are found when the
+// layout is loaded.
+func (lw *writer) addReferrers(ctx context.Context, repository string, subject digest.Digest) error {
+	referrers, err := lw.handle.ResolveReferrers(ctx, repository, subject, "")
+	if err != nil {
+		return fmt.Errorf("could not resolve referrers of '%s': %w", subject.String(), err)
+	}
+
+	for _, referrer := range referrers {
+		if lw.seen[referrer.Digest] {
+			continue
+		}
+
+		if err := lw.addManifest(ctx, repository, referrer); err != nil {
+			return err
+		}
+
+		lw.index.Manifests = append(lw.index.Manifests, ocispec.Descriptor{
+			MediaType:    ocispec.MediaTypeImageManifest,
+			ArtifactType: referrer.ArtifactType,
+			Digest:       referrer.Digest,
+			Size:         referrer.Size,
+			Annotations: map[string]string{
+				images.AnnotationImageName: fmt.Sprintf("%s@%s", repository, referrer.Digest.String()),
+			},
+		})
+	}
+
+	return nil
+}
+
+// write serializes the layout as a tarball to w.
+func (lw *writer) write(ctx context.Context, w io.Writer) error {
+	tw := tar.NewWriter(w)
+
+	layoutRaw, err := json.Marshal(ocispec.ImageLayout{
+		Version: ocispec.ImageLayoutVersion,
+	})
+	if err != nil {
+		return err
+	}
+
+	indexRaw, err := json.Marshal(lw.index)
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range []string{BlobsDir, BlobsDir + "/" + digest.SHA256.String()} {
+		if err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeDir,
+			Name:     dir + "/",
+			Mode:     0o755,
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := writeFile(tw, ocispec.ImageLayoutFile, bytes.NewReader(layoutRaw), int64(len(layoutRaw))); err != nil {
+		return err
+	}
+
+	if err := writeFile(tw, IndexFile, bytes.NewReader(indexRaw), int64(len(indexRaw))); err != nil {
+		return err
+	}
+
+	for _, desc := range lw.blobs {
+		path := blobPath(desc.Digest)
+
+		log.G(ctx).
+			WithField("digest", desc.Digest.String()).
+			WithField("mediaType", desc.MediaType).
+			Trace("saving blob")
+
+		if raw, ok := lw.raw[desc.Digest]; ok {
+			if err := writeFile(tw, path, bytes.NewReader(raw), int64(len(raw))); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		reader, err := lw.handle.ReadDigest(ctx, desc.Digest)
+		if err != nil {
+			return fmt.Errorf("could not read blob '%s': %w", desc.Digest.String(), err)
+		}
+
+		err = writeFile(tw, path, reader, desc.Size)
+		reader.Close()
+		if err != nil {
+			return fmt.Errorf("could not save blob '%s': %w", desc.Digest.String(), err)
+		}
+	}
+
+	return tw.Close()
+}
+
+// writeFile writes a regular file of the provided size to the tarball.
+func writeFile(tw *tar.Writer, path string, reader io.Reader, size int64) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     path,
+		Mode:     0o644,
+		Size:     size,
+	}); err != nil {
+		return err
+	}
+
+	_, err := io.Copy(tw, reader)
+	return err
+}
+
+// blobPath returns the path of the blob with the provided digest within the
+// layout.
+func blobPath(dgst digest.Digest) string {
+	return strings.Join([]string{BlobsDir, dgst.Algorithm().String(), dgst.Encoded()}, "/")
+}
+
+// Load imports all images of the OCI image-layout tarball into the handler and
+// returns their canonical references.  Images are named after the
+// "io.containerd.image.name" annotation, or the
+// "org.opencontainers.image.ref.name" annotation if it is a canonical
+// reference, of their entry in the index of the layout.
+func Load(ctx context.Context, handle handler.Handler, r io.Reader) ([]string, error) {
+	dir, err := os.MkdirTemp("", "kraftkit-layout-*")
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.RemoveAll(dir)
+
+	if err := extract(r, dir); err != nil {
+		return nil, fmt.Errorf("could not extract layout: %w", err)
+	}
+
+	layoutRaw, err := os.ReadFile(filepath.Join(dir, ocispec.ImageLayoutFile))
+	if err != nil {
+		return nil, fmt.Errorf("not an OCI image layout: %w", err)
+	}
+
+	layout := ocispec.ImageLayout{}
+	if err := json.Unmarshal(layoutRaw, &layout); err != nil {
+		return nil, fmt.Errorf("could not unmarshal %s: %w", ocispec.ImageLayoutFile, err)
+	}
+
+	if layout.Version != ocispec.ImageLayoutVersion {
+		return nil, fmt.Errorf("unsupported image layout version: %s", layout.Version)
+	}
+
+	indexRaw, err := os.ReadFile(filepath.Join(dir, IndexFile))
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", IndexFile, err)
+	}
+
+	index := ocispec.Index{}
+	if err := json.Unmarshal(indexRaw, &index); err != nil {
+		return nil, fmt.Errorf("could not unmarshal %s: %w", IndexFile, err)
+	}
+
+	lr := &reader{
+		dir:    dir,
+		handle: handle,
+		saved:  make(map[digest.Digest]bool),
+	}
+
+	var refs []string
+
+	for _, desc := range index.Manifests {
+		fullref := desc.Annotations[images.AnnotationImageName]
+		if len(fullref) == 0 {
+			fullref = desc.Annotations[ocispec.AnnotationRefName]
+		}
+
+		ref, err := name.ParseReference(fullref, name.StrictValidation)
+		if err != nil {
+			return nil, fmt.Errorf("could not determine name of '%s': %w", desc.Digest.String(), err)
+		}
+
+		log.G(ctx).
+			WithField("ref", ref.Name()).
+			WithField("digest", desc.Digest.String()).
+			Debug("loading")
+
+		switch desc.MediaType {
+		case ocispec.MediaTypeImageIndex:
+			if err := lr.saveIndex(ctx, ref.Name(), desc); err != nil {
+				return nil, err
+			}
+
+		case ocispec.MediaTypeImageManifest:
+			if err := lr.saveManifest(ctx, ref.Name(), desc); err != nil {
+				return nil, err
+			}
+
+			// Referrers are named by their digest and are not listed.
+			if _, ok := ref.(name.Digest); ok {
+				continue
+			}
+
+		default:
+			return nil, fmt.Errorf("unsupported media type '%s' of '%s'", desc.MediaType, desc.Digest.String())
+		}
+
+		refs = append(refs, ref.Name())
+	}
+
+	return refs, nil
+}
+
+// reader saves the blobs of an extracted layout to a handler.
+type reader struct {
+	dir    string
+	handle handler.Handler
+	saved  map[digest.Digest]bool
+}
+
+// readBlob returns the verified contents of the blob with the provided digest.
+func (lr *reader) readBlob(desc ocispec.Descriptor) ([]byte, error) {
+	if err := desc.Digest.Validate(); err != nil {
+		return nil, err
+	}
+
+	raw, err := os.ReadFile(filepath.Join(lr.dir, filepath.FromSlash(blobPath(desc.Digest))))
+	if err != nil {
+		return nil, fmt.Errorf("could not read blob '%s': %w", desc.Digest.String(), err)
+	}
+
+	if dgst := desc.Digest.Algorithm().FromBytes(raw); dgst != desc.Digest {
+		return nil, fmt.Errorf("digest mismatch: expected '%s' but got '%s'", desc.Digest.String(), dgst.String())
+	}
+
+	return raw, nil
+}
+
+// saveBlob saves the blob of the descriptor, verifying its digest as it is
+// streamed to the handler.
+func (lr *reader) saveBlob(ctx context.Context, fullref string, desc ocispec.Descriptor) error {
+	if lr.saved[desc.Digest] {
+		return nil
+	}
+
+	if err := desc.Digest.Validate(); err != nil {
+		return err
+	}
+
+	f, err := os.Open(filepath.Join(lr.dir, filepath.FromSlash(blobPath(desc.Digest))))
+	if err != nil {
+		return fmt.Errorf("could not open blob '%s': %w", desc.Digest.String(), err)
+	}
+
+	defer f.Close()
+
+	verifier := desc.Digest.Verifier()
+
+	if err := lr.handle.SaveDescriptor(ctx, fullref, desc, io.TeeReader(f, verifier), nil); err != nil {
+		return fmt.Errorf("could not save blob '%s': %w", desc.Digest.String(), err)
+	}
+
+	if !verifier.Verified() {
+		return fmt.Errorf("digest mismatch of blob '%s'", desc.Digest.String())
+	}
+
+	lr.saved[desc.Digest] = true
+
+	return nil
+}
+
+// saveManifest saves the config and layers of the manifest before the manifest
+// itself.
+func (lr *reader) saveManifest(ctx context.Context, fullref string, desc ocispec.Descriptor) error {
+	if lr.saved[desc.Digest] {
+		return nil
+	}
+
+	raw, err := lr.readBlob(desc)
+	if err != nil {
+		return err
+	}
+
+	manifest := ocispec.Manifest{}
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return fmt.Errorf("could not unmarshal manifest '%s': %w", desc.Digest.String(), err)
+	}
+
+	if err := lr.saveBlob(ctx, fullref, manifest.Config); err != nil {
+		return err
+	}
+
+	for _, layer := range manifest.Layers {
+		if err := lr.saveBlob(ctx, fullref, layer); err != nil {
+			return err
+		}
+	}
+
+	if err := lr.handle.SaveDescriptor(ctx, fullref, ocispec.Descriptor{
+		MediaType:    ocispec.MediaTypeImageManifest,
+		ArtifactType: manifest.ArtifactType,
+		Digest:       desc.Digest,
+		Size:         int64(len(raw)),
+		Platform:     desc.Platform,
+		Annotations:  desc.Annotations,
+	}, bytes.NewReader(raw), nil); err != nil {
+		return fmt.Errorf("could not save manifest '%s': %w", desc.Digest.String(), err)
+	}
+
+	lr.saved[desc.Digest] = true
+
+	return nil
+}
+
+// saveIndex saves the manifests of the index before the index itself, which
+// replaces any existing index of the same name.  The existing index is not
+// removed beforehand such that its reference remains resolvable should the
+// new index fail to be saved.
+func (lr *reader) saveIndex(ctx context.Context, fullref string, desc ocispec.Descriptor) error {
+	raw, err := lr.readBlob(desc)
+	if err != nil {
+		return err
+	}
+
+	index := ocispec.Index{}
+	if err := json.Unmarshal(raw, &index); err != nil {
+		return fmt.Errorf("could not unmarshal index '%s': %w", desc.Digest.String(), err)
+	}
+
+	for _, manifest := range index.Manifests {
+		if manifest.MediaType != ocispec.MediaTypeImageManifest {
+			return fmt.Errorf("unsupported media type '%s' in index '%s'", manifest.MediaType, desc.Digest.String())
+		}
+
+		if err := lr.saveManifest(ctx, fullref, manifest); err != nil {
+			return err
+		}
+	}
+
+	if err := lr.handle.SaveDescriptor(ctx, fullref, ocispec.Descriptor{
+		MediaType:   ocispec.MediaTypeImageIndex,
+		Digest:      desc.Digest,
+		Size:        int64(len(raw)),
+		Annotations: index.Annotations,
+	}, bytes.NewReader(raw), nil); err != nil {
+		return fmt.Errorf("could not save index '%s': %w", desc.Digest.String(), err)
+	}
+
+	lr.saved[desc.Digest] = true
+
+	return nil
+}
+
+// extract writes the regular files of the tarball to the directory.  Only the
+// files which are part of an OCI image layout are considered.
+func extract(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		path := strings.TrimPrefix(filepath.ToSlash(filepath.Clean(hdr.Name)), "./")
+
+		switch {
+		case path == ocispec.ImageLayoutFile, path == IndexFile:
+		case strings.HasPrefix(path, BlobsDir+"/") && strings.Count(path, "/") == 2 && !strings.Contains(path, ".."):
+		default:
+			continue
+		}
+
+		dst := filepath.Join(dir, filepath.FromSlash(path))
+
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return err
+		}
+
+		f, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+
+		if _, err := io.Copy(f, tr); err != nil {
+			f.Close()
+			return err
+		}
+
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+}
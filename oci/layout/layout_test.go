@@ -0,0 +1,303 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package layout
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"kraftkit.sh/oci/handler"
+)
+
+// save marshals v and stores it in the handler under the provided reference.
+func save(t *testing.T, handle handler.Handler, fullref, mediaType string, v interface{}) ocispec.Descriptor {
+	t.Helper()
+
+	raw, ok := v.([]byte)
+	if !ok {
+		var err error
+		if raw, err = json.Marshal(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	desc := ocispec.Descriptor{
+		MediaType: mediaType,
+		Digest:    digest.FromBytes(raw),
+		Size:      int64(len(raw)),
+	}
+
+	if err := handle.SaveDescriptor(context.Background(), fullref, desc, bytes.NewReader(raw), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	return desc
+}
+
+// image stores a single-layer image of the provided architecture and returns
+// the descriptor of its manifest.
+func image(t *testing.T, handle handler.Handler, fullref, arch string, layer []byte) ocispec.Descriptor {
+	t.Helper()
+
+	layerDesc := save(t, handle, fullref, ocispec.MediaTypeImageLayer, layer)
+
+	config := ocispec.Image{
+		Platform: ocispec.Platform{
+			Architecture: arch,
+			OS:           "qemu",
+		},
+		RootFS: ocispec.RootFS{
+			Type:    "layers",
+			DiffIDs: []digest.Digest{layerDesc.Digest},
+		},
+	}
+
+	configDesc := save(t, handle, fullref, ocispec.MediaTypeImageConfig, config)
+
+	manifestDesc := save(t, handle, fullref, ocispec.MediaTypeImageManifest, ocispec.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    configDesc,
+		Layers:    []ocispec.Descriptor{layerDesc},
+	})
+
+	manifestDesc.Platform = &config.Platform
+
+	return manifestDesc
+}
+
+func TestSaveLoad(t *testing.T) {
+	ctx := context.Background()
+	fullref := "unikraft.org/helloworld:latest"
+
+	src, err := handler.NewDirectoryHandler(filepath.Join(t.TempDir(), "src"), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	x86 := image(t, src, fullref, "x86_64", []byte("x86_64 kernel"))
+	arm := image(t, src, fullref, "arm64", []byte("arm64 kernel"))
+
+	index := ocispec.Index{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: []ocispec.Descriptor{x86, arm},
+	}
+
+	indexDesc := save(t, src, fullref, ocispec.MediaTypeImageIndex, index)
+
+	// Attach an artifact, e.g. an SBOM, to the index.
+	sbom := save(t, src, "", "application/spdx+json", []byte(`{"spdxVersion":"SPDX-2.3"}`))
+	sbomConfig := save(t, src, "", ocispec.MediaTypeImageConfig, ocispec.Image{
+		RootFS: ocispec.RootFS{Type: "layers", DiffIDs: []digest.Digest{sbom.Digest}},
+	})
+	referrer := save(t, src, "", ocispec.MediaTypeImageManifest, ocispec.Manifest{
+		Versioned:    specs.Versioned{SchemaVersion: 2},
+		MediaType:    ocispec.MediaTypeImageManifest,
+		ArtifactType: sbom.MediaType,
+		Config:       sbomConfig,
+		Layers:       []ocispec.Descriptor{sbom},
+		Subject:      &indexDesc,
+	})
+
+	var tarball bytes.Buffer
+	if err := Save(ctx, src, &tarball, fullref); err != nil {
+		t.Fatal(err)
+	}
+
+	// The tarball must be a valid OCI image layout.
+	files := map[string]bool{}
+	tr := tar.NewReader(bytes.NewReader(tarball.Bytes()))
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+
+		files[hdr.Name] = true
+	}
+
+	for _, file := range []string{ocispec.ImageLayoutFile, IndexFile, blobPath(indexDesc.Digest), blobPath(referrer.Digest)} {
+		if !files[file] {
+			t.Errorf("expected layout to contain %s", file)
+		}
+	}
+
+	dst, err := handler.NewDirectoryHandler(filepath.Join(t.TempDir(), "dst"), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refs, err := Load(ctx, dst, bytes.NewReader(tarball.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(refs) != 1 || refs[0] != fullref {
+		t.Fatalf("expected to load %s, got %v", fullref, refs)
+	}
+
+	loaded, err := dst.ResolveIndex(ctx, fullref)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(loaded.Manifests) != 2 {
+		t.Fatalf("expected index with 2 manifests, got %d", len(loaded.Manifests))
+	}
+
+	for _, desc := range loaded.Manifests {
+		image, err := dst.UnpackImage(ctx, fullref, desc.Digest, t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if image.Architecture != desc.Platform.Architecture {
+			t.Errorf("expected %s image, got %s", desc.Platform.Architecture, image.Architecture)
+		}
+	}
+
+	referrers, err := dst.ResolveReferrers(ctx, fullref, indexDesc.Digest, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(referrers) != 1 || referrers[0].Digest != referrer.Digest {
+		t.Fatalf("expected referrer %s to be loaded, got %v", referrer.Digest, referrers)
+	}
+
+	// A round-trip through the loaded handler must produce the same layout.
+	var again bytes.Buffer
+	if err := Save(ctx, dst, &again, fullref); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(tarball.Bytes(), again.Bytes()) {
+		t.Error("expected saving the loaded package to produce an identical tarball")
+	}
+}
+
+func TestLoadCorrupt(t *testing.T) {
+	ctx := context.Background()
+	fullref := "unikraft.org/helloworld:latest"
+
+	src, err := handler.NewDirectoryHandler(filepath.Join(t.TempDir(), "src"), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	layer := []byte("x86_64 kernel")
+	manifest := image(t, src, fullref, "x86_64", layer)
+	save(t, src, fullref, ocispec.MediaTypeImageIndex, ocispec.Index{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: []ocispec.Descriptor{manifest},
+	})
+
+	var tarball bytes.Buffer
+	if err := Save(ctx, src, &tarball, fullref); err != nil {
+		t.Fatal(err)
+	}
+
+	// Tamper with the layer whilst retaining its size.
+	corrupt := bytes.Replace(tarball.Bytes(), layer, []byte("x86_64 kerneL"), 1)
+
+	dst, err := handler.NewDirectoryHandler(filepath.Join(t.TempDir(), "dst"), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Load(ctx, dst, bytes.NewReader(corrupt)); err == nil {
+		t.Fatal("expected loading a corrupted layout to fail")
+	}
+}
+
+// failingIndexHandler fails to save any index.
+type failingIndexHandler struct {
+	handler.Handler
+}
+
+func (handle failingIndexHandler) SaveDescriptor(ctx context.Context, fullref string, desc ocispec.Descriptor, reader io.Reader, onProgress func(float64)) error {
+	if desc.MediaType == ocispec.MediaTypeImageIndex {
+		return errors.New("disk full")
+	}
+
+	return handle.Handler.SaveDescriptor(ctx, fullref, desc, reader, onProgress)
+}
+
+func TestLoadFailureRetainsIndex(t *testing.T) {
+	ctx := context.Background()
+	fullref := "unikraft.org/helloworld:latest"
+
+	tarball := func(layer string) *bytes.Buffer {
+		src, err := handler.NewDirectoryHandler(t.TempDir(), nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		save(t, src, fullref, ocispec.MediaTypeImageIndex, ocispec.Index{
+			Versioned: specs.Versioned{SchemaVersion: 2},
+			MediaType: ocispec.MediaTypeImageIndex,
+			Manifests: []ocispec.Descriptor{image(t, src, fullref, "x86_64", []byte(layer))},
+		})
+
+		var buf bytes.Buffer
+		if err := Save(ctx, src, &buf, fullref); err != nil {
+			t.Fatal(err)
+		}
+
+		return &buf
+	}
+
+	dst, err := handler.NewDirectoryHandler(t.TempDir(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Load(ctx, dst, tarball("v1 kernel")); err != nil {
+		t.Fatal(err)
+	}
+
+	existing, err := dst.ResolveIndex(ctx, fullref)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Load(ctx, failingIndexHandler{dst}, tarball("v2 kernel")); err == nil {
+		t.Fatal("expected loading to fail when the index cannot be saved")
+	}
+
+	index, err := dst.ResolveIndex(ctx, fullref)
+	if err != nil {
+		t.Fatalf("expected existing index to remain resolvable: %v", err)
+	}
+
+	if index.Manifests[0].Digest != existing.Manifests[0].Digest {
+		t.Errorf("expected existing index to be retained, got manifest %s", index.Manifests[0].Digest)
+	}
+
+	// A successful load replaces the existing index.
+	if _, err := Load(ctx, dst, tarball("v2 kernel")); err != nil {
+		t.Fatal(err)
+	}
+
+	if index, err = dst.ResolveIndex(ctx, fullref); err != nil {
+		t.Fatal(err)
+	} else if index.Manifests[0].Digest == existing.Manifests[0].Digest {
+		t.Error("expected existing index to be replaced")
+	}
+}
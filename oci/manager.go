@@ -6,17 +6,13 @@ package oci
 
 import (
 	"context"
-	"crypto/tls"
 	"errors"
 	"fmt"
-	"net/http"
 	"slices"
 	"strings"
 	"sync"
 
 	"github.com/gobwas/glob"
-	"github.com/google/go-containerregistry/pkg/authn"
-	"github.com/google/go-containerregistry/pkg/crane"
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
@@ -29,7 +25,6 @@ import (
 	"kraftkit.sh/log"
 	"kraftkit.sh/oci/cache"
 	"kraftkit.sh/oci/handler"
-	"kraftkit.sh/oci/simpleauth"
 	ociutils "kraftkit.sh/oci/utils"
 	"kraftkit.sh/pack"
 	"kraftkit.sh/packmanager"
@@ -99,44 +94,17 @@ func (manager *ociManager) update(ctx context.Context, auths map[string]config.A
 	}
 
 	packs := make(map[string]pack.Package)
+	registries := ociutils.Registries{
+		Auths:   auths,
+		Configs: config.G[config.KraftKit](ctx).Registries,
+	}
 
 	for _, domain := range manager.registries {
 		log.G(ctx).
 			WithField("registry", domain).
 			Trace("querying")
 
-		nopts := []name.Option{}
-		authConfig := &authn.AuthConfig{}
-		transport := http.DefaultTransport.(*http.Transport).Clone()
-
-		// Annoyingly convert between regtypes and authn.
-		if auth, ok := auths[domain]; ok {
-			authConfig.Username = auth.User
-			authConfig.Password = auth.Token
-
-			if !auth.VerifySSL {
-				transport.TLSClientConfig = &tls.Config{
-					InsecureSkipVerify: true,
-				}
-				nopts = append(nopts, name.Insecure)
-			}
-		}
-
-		regName, err := name.NewRegistry(domain, nopts...)
-		if err != nil {
-			log.G(ctx).
-				WithField("registry", domain).
-				Debugf("could not parse registry: %v", err)
-			continue
-		}
-
-		catalog, err := remote.Catalog(ctx, regName,
-			remote.WithContext(ctx),
-			remote.WithAuth(&simpleauth.SimpleAuthenticator{
-				Auth: authConfig,
-			}),
-			remote.WithTransport(transport),
-		)
+		mirror, catalog, ropts, err := manager.catalog(ctx, registries, domain)
 		if err != nil {
 			log.G(ctx).
 				WithField("registry", domain).
@@ -163,12 +131,10 @@ func (manager *ociManager) update(ctx context.Context, auths map[string]config.A
 					return
 				}
 
-				index, err := cache.RemoteIndex(ref,
-					remote.WithContext(ctx),
-					remote.WithAuth(&simpleauth.SimpleAuthenticator{
-						Auth: authConfig,
-					}),
-					remote.WithTransport(transport),
+				// Retrieve the index from the registry which served the catalog.
+				index, err := cache.RemoteIndex(
+					mirror.Repository(ref.Context().RepositoryStr()).Tag(ref.Identifier()),
+					ropts...,
 				)
 				if err != nil {
 					log.G(ctx).
@@ -217,6 +183,57 @@ func (manager *ociManager) update(ctx context.Context, auths map[string]config.A
 	return packs, nil
 }
 
+// catalog returns the repositories of the registry at the provided domain as
+// listed by the first of its mirrors, or otherwise the registry itself, which
+// serves its catalog, together with the options used to access it.
+// Repositories of a mirror are relative to its prefix.
+func (manager *ociManager) catalog(ctx context.Context, registries ociutils.Registries, domain string) (ociutils.Mirror, []string, []remote.Option, error) {
+	mirrors, err := registries.Mirrors(domain)
+	if err != nil {
+		return ociutils.Mirror{}, nil, nil, err
+	}
+
+	regName, err := name.NewRegistry(domain, registries.NameOptions(domain)...)
+	if err != nil {
+		return ociutils.Mirror{}, nil, nil, fmt.Errorf("could not parse registry: %w", err)
+	}
+
+	// The registry itself is its own mirror without a prefix.
+	mirrors = append(mirrors, ociutils.Mirror{Registry: regName})
+
+	for _, mirror := range mirrors {
+		ropts, err := registries.RemoteOptions(mirror.Registry.RegistryStr())
+		if err != nil {
+			return ociutils.Mirror{}, nil, nil, err
+		}
+
+		ropts = append(ropts, remote.WithContext(ctx))
+
+		catalog, err := remote.Catalog(ctx, mirror.Registry, ropts...)
+		if err != nil {
+			log.G(ctx).
+				WithField("registry", mirror.Registry.RegistryStr()).
+				Debugf("could not query catalog: %v", err)
+			continue
+		}
+
+		if len(mirror.Prefix) == 0 {
+			return mirror, catalog, ropts, nil
+		}
+
+		var repos []string
+		for _, repo := range catalog {
+			if rest, ok := strings.CutPrefix(repo, mirror.Prefix+"/"); ok {
+				repos = append(repos, rest)
+			}
+		}
+
+		return mirror, repos, ropts, nil
+	}
+
+	return ociutils.Mirror{}, nil, nil, fmt.Errorf("no catalog served by registry '%s' or its mirrors", domain)
+}
+
 // Pack implements packmanager.PackageManager
 func (manager *ociManager) Pack(ctx context.Context, entity component.Component, opts ...packmanager.PackOption) ([]pack.Package, error) {
 	targ, ok := entity.(target.Target)
@@ -424,39 +441,25 @@ func (manager *ociManager) Catalog(ctx context.Context, qopts ...packmanager.Que
 
 	// If a direct reference can be made, attempt to generate a package from it.
 	if query.Remote() && refErr == nil && !unsetRegistry {
-		authConfig := &authn.AuthConfig{}
-
-		ropts := []remote.Option{
-			remote.WithContext(ctx),
+		registries := ociutils.Registries{
+			Auths:   auths,
+			Configs: config.G[config.KraftKit](ctx).Registries,
 		}
 
-		// Annoyingly convert between regtypes and authn.
-		if auth, ok := auths[ref.Context().RegistryStr()]; ok {
-			authConfig.Username = auth.User
-			authConfig.Password = auth.Token
-
-			if !auth.VerifySSL {
-				rt := http.DefaultTransport.(*http.Transport).Clone()
-				rt.TLSClientConfig = &tls.Config{
-					InsecureSkipVerify: true,
-				}
-				ropts = append(ropts,
-					remote.WithTransport(rt),
-				)
-			}
-
-			ropts = append(ropts,
-				remote.WithAuth(&simpleauth.SimpleAuthenticator{
-					Auth: authConfig,
-				}),
-			)
+		sources, err := registries.Sources(ref, remote.WithContext(ctx))
+		if err != nil {
+			log.G(ctx).
+				Debugf("could not determine sources: %v", err)
+			goto resolveLocalIndex
 		}
 
 		log.G(ctx).
 			WithField("ref", ref.Name()).
 			Trace("getting remote index")
 
-		v1ImageIndex, err := cache.RemoteIndex(ref, ropts...)
+		v1ImageIndex, err := ociutils.Fetch(ctx, sources, func(src ociutils.Source) (v1.ImageIndex, error) {
+			return cache.RemoteIndex(src.Ref, src.Options...)
+		})
 		if err != nil {
 			log.G(ctx).
 				Debugf("could not get index: %v", err)
@@ -768,12 +771,19 @@ func (manager *ociManager) IsCompatible(ctx context.Context, source string, qopt
 			WithField("source", source).
 			Tracef("checking if source is registry")
 
-		regName, err := name.NewRegistry(source)
+		registries := ociutils.RegistriesFromContext(ctx)
+
+		regName, err := name.NewRegistry(source, registries.NameOptions(source)...)
+		if err != nil {
+			return false
+		}
+
+		rt, err := registries.Transport(source)
 		if err != nil {
 			return false
 		}
 
-		if _, err := transport.Ping(ctx, regName, http.DefaultTransport.(*http.Transport).Clone()); err == nil {
+		if _, err := transport.Ping(ctx, regName, rt); err == nil {
 			return true
 		}
 
@@ -794,50 +804,22 @@ func (manager *ociManager) IsCompatible(ctx context.Context, source string, qopt
 			return false
 		}
 
-		source = fmt.Sprintf("%s:%s", ref.Context().String(), ref.Identifier())
-
-		// log.G(ctx).WithField("source", source).Debug("checking if source is registry")
-		opts := []crane.Option{
-			crane.WithContext(ctx),
-			crane.WithUserAgent(version.UserAgent()),
-			crane.WithPlatform(&v1.Platform{
+		sources, err := ociutils.RegistriesFromContext(ctx).Sources(ref,
+			remote.WithContext(ctx),
+			remote.WithUserAgent(version.UserAgent()),
+			remote.WithPlatform(v1.Platform{
 				OS:           query.Platform(),
 				OSFeatures:   query.KConfig(),
 				Architecture: query.Architecture(),
 			}),
+		)
+		if err != nil {
+			return false
 		}
 
-		if auth, ok := config.G[config.KraftKit](ctx).Auth[ref.Context().Registry.RegistryStr()]; ok {
-			// We split up the options for authenticating and the option for
-			// "verifying ssl" such that a user can simply disable secure connection
-			// to a registry if desired.
-
-			if auth.User != "" && auth.Token != "" {
-				log.G(ctx).
-					WithField("registry", source).
-					Debug("authenticating")
-
-				opts = append(opts,
-					crane.WithAuth(authn.FromConfig(authn.AuthConfig{
-						Username: auth.User,
-						Password: auth.Token,
-					})),
-				)
-			}
-
-			if !auth.VerifySSL {
-				rt := http.DefaultTransport.(*http.Transport).Clone()
-				rt.TLSClientConfig = &tls.Config{
-					InsecureSkipVerify: true,
-				}
-				opts = append(opts,
-					crane.Insecure,
-					crane.WithTransport(rt),
-				)
-			}
-		}
-
-		desc, err := crane.Head(source, opts...)
+		desc, err := ociutils.Fetch(ctx, sources, func(src ociutils.Source) (*v1.Descriptor, error) {
+			return remote.Head(src.Ref, src.Options...)
+		})
 		if err == nil && desc != nil {
 			return true
 		}
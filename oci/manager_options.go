@@ -40,7 +40,7 @@ func WithDetectHandler() OCIManagerOption {
 				Trace("using containerd handler")
 
 			manager.handle = func(ctx context.Context) (context.Context, handler.Handler, error) {
-				return handler.NewContainerdHandler(ctx, contAddr, namespace, manager.auths)
+				return handler.NewContainerdHandler(ctx, contAddr, namespace, manager.auths, config.G[config.KraftKit](ctx).Registries)
 			}
 
 			return nil
@@ -54,7 +54,7 @@ func WithDetectHandler() OCIManagerOption {
 			Trace("using directory handler")
 
 		manager.handle = func(ctx context.Context) (context.Context, handler.Handler, error) {
-			handle, err := handler.NewDirectoryHandler(ociDir, manager.auths)
+			handle, err := handler.NewDirectoryHandler(ociDir, manager.auths, config.G[config.KraftKit](ctx).Registries)
 			if err != nil {
 				return nil, nil, err
 			}
@@ -83,7 +83,7 @@ func WithContainerd(ctx context.Context, addr, namespace string) OCIManagerOptio
 			Trace("using containerd handler")
 
 		manager.handle = func(ctx context.Context) (context.Context, handler.Handler, error) {
-			return handler.NewContainerdHandler(ctx, addr, namespace, manager.auths)
+			return handler.NewContainerdHandler(ctx, addr, namespace, manager.auths, config.G[config.KraftKit](ctx).Registries)
 		}
 
 		return nil
@@ -99,7 +99,7 @@ func WithDirectory(ctx context.Context, path string) OCIManagerOption {
 			Trace("using directory handler")
 
 		manager.handle = func(ctx context.Context) (context.Context, handler.Handler, error) {
-			handle, err := handler.NewDirectoryHandler(path, manager.auths)
+			handle, err := handler.NewDirectoryHandler(path, manager.auths, config.G[config.KraftKit](ctx).Registries)
 			if err != nil {
 				return nil, nil, err
 			}
@@ -10,6 +10,14 @@ const (
 	MediaTypeInitrdCpio  = "application/vnd.unikraft.initrd.v1"
 	MediaTypeConfig      = "application/vnd.unikraft.config.v1"
 
+	// Optional layers which are uncompressed tarballs of the build artifacts and
+	// sources of the unikernel such that it can be relinked or debugged without
+	// the original source tree.
+	MediaTypeKernelLibraryObjects             = "application/vnd.unikraft.lib.objects.v1.tar"
+	MediaTypeKernelLibraryIntermediateObjects = "application/vnd.unikraft.lib.intermediate-objects.v1.tar"
+	MediaTypeKernelSource                     = "application/vnd.unikraft.kernel.source.v1.tar"
+	MediaTypeAppSource                        = "application/vnd.unikraft.app.source.v1.tar"
+
 	MediaTypeLayerGzip       = MediaTypeLayer + "+gzip"
 	MediaTypeImageKernelGzip = MediaTypeImageKernel + "+gzip"
 	MediaTypeInitrdCpioGzip  = MediaTypeInitrdCpio + "+gzip"
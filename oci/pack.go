@@ -7,13 +7,10 @@ package oci
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/fs"
 	golog "log"
-	"net/http"
 	"os"
 	"path/filepath"
 	"sort"
@@ -22,7 +19,6 @@ import (
 	"time"
 
 	"github.com/dustin/go-humanize"
-	"github.com/google/go-containerregistry/pkg/authn"
 	gcrlogs "github.com/google/go-containerregistry/pkg/logs"
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
@@ -40,11 +36,12 @@ import (
 	"kraftkit.sh/kconfig"
 	"kraftkit.sh/log"
 	"kraftkit.sh/oci/cache"
+	"kraftkit.sh/oci/cosign"
 	"kraftkit.sh/oci/handler"
-	"kraftkit.sh/oci/simpleauth"
 	ociutils "kraftkit.sh/oci/utils"
 	"kraftkit.sh/pack"
 	"kraftkit.sh/packmanager"
+	"kraftkit.sh/sbom"
 	"kraftkit.sh/unikraft"
 	"kraftkit.sh/unikraft/arch"
 	"kraftkit.sh/unikraft/plat"
@@ -126,31 +123,7 @@ func NewPackageFromTarget(ctx context.Context, targ target.Target, opts ...packm
 		return nil, fmt.Errorf("could not parse image reference: %w", err)
 	}
 
-	if contAddr := config.G[config.KraftKit](ctx).ContainerdAddr; len(contAddr) > 0 {
-		namespace := DefaultNamespace
-		if n := os.Getenv("CONTAINERD_NAMESPACE"); n != "" {
-			namespace = n
-		}
-
-		log.G(ctx).
-			WithField("addr", contAddr).
-			WithField("namespace", namespace).
-			Debug("packaging via containerd")
-
-		ctx, ocipack.handle, err = handler.NewContainerdHandler(ctx, contAddr, namespace, config.G[config.KraftKit](ctx).Auth)
-	} else {
-		if gerr := os.MkdirAll(config.G[config.KraftKit](ctx).RuntimeDir, fs.ModeSetgid|0o775); gerr != nil {
-			return nil, fmt.Errorf("could not create local oci cache directory: %w", gerr)
-		}
-
-		ociDir := filepath.Join(config.G[config.KraftKit](ctx).RuntimeDir, "oci")
-
-		log.G(ctx).
-			WithField("path", ociDir).
-			Trace("directory handler")
-
-		ocipack.handle, err = handler.NewDirectoryHandler(ociDir, config.G[config.KraftKit](ctx).Auth)
-	}
+	ctx, ocipack.handle, err = NewHandlerFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -211,7 +184,28 @@ func NewPackageFromTarget(ctx context.Context, targ target.Target, opts ...packm
 		}
 	}
 
-	if popts.Initrd() != "" {
+	if popts.Initrd() != "" && popts.InitrdChunks() {
+		log.G(ctx).
+			WithField("src", popts.Initrd()).
+			WithField("dest", WellKnownInitrdChunksDir).
+			Debug("including chunked initrd")
+
+		layers, err := NewLayersFromChunkedFile(ctx,
+			popts.Initrd(),
+			WellKnownInitrdChunksDir,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("could build layers from chunked file: %w", err)
+		}
+
+		for _, layer := range layers {
+			defer os.Remove(layer.tmp)
+
+			if _, err := ocipack.manifest.AddLayer(ctx, layer); err != nil {
+				return nil, err
+			}
+		}
+	} else if popts.Initrd() != "" {
 		log.G(ctx).
 			WithField("src", popts.Initrd()).
 			WithField("dest", WellKnownInitrdPath).
@@ -233,23 +227,9 @@ func NewPackageFromTarget(ctx context.Context, targ target.Target, opts ...packm
 		}
 	}
 
-	// TODO(nderjung): See below.
-
-	// if popts.PackKernelLibraryObjects() {
-	// 	log.G(ctx).Debug("including kernel library objects")
-	// }
-
-	// if popts.PackKernelLibraryIntermediateObjects() {
-	// 	log.G(ctx).Debug("including kernel library intermediate objects")
-	// }
-
-	// if popts.PackKernelSourceFiles() {
-	// 	log.G(ctx).Debug("including kernel source files")
-	// }
-
-	// if popts.PackAppSourceFiles() {
-	// 	log.G(ctx).Debug("including application source files")
-	// }
+	if err := ocipack.addBuildLayers(ctx, popts); err != nil {
+		return nil, err
+	}
 
 	if ocipack.original != nil {
 		ocipack.manifest.config = ocipack.original.manifest.config
@@ -279,6 +259,13 @@ func NewPackageFromTarget(ctx context.Context, targ target.Target, opts ...packm
 
 	ocipack.manifest.SetOS(ctx, ocipack.Platform().Name())
 	ocipack.manifest.SetArchitecture(ctx, ocipack.Architecture().Name())
+
+	// Record the platform and architecture of the kernel such that runtimes,
+	// such as runu, can select the virtual machine monitor to run it with.
+	ocipack.manifest.SetAnnotation(ctx, AnnotationKernelPlat, ocipack.Platform().Name())
+	ocipack.manifest.SetAnnotation(ctx, AnnotationKernelArch, ocipack.Architecture().Name())
+	ocipack.manifest.SetLabel(ctx, AnnotationKernelPlat, ocipack.Platform().Name())
+	ocipack.manifest.SetLabel(ctx, AnnotationKernelArch, ocipack.Architecture().Name())
 	ocipack.manifest.SetEnv(ctx, popts.Env())
 	for _, env := range ocipack.manifest.config.Config.Env {
 		k, v, _ := strings.Cut(env, "=")
@@ -412,6 +399,22 @@ func NewPackageFromTarget(ctx context.Context, targ target.Target, opts ...packm
 		return nil, fmt.Errorf("could not save index: %w", err)
 	}
 
+	if raw, mediaType := popts.SBOM(); len(raw) > 0 {
+		log.G(ctx).
+			WithField("mediaType", mediaType).
+			Debug("attaching sbom")
+
+		if _, err := saveReferrer(ctx,
+			ocipack.handle,
+			ocipack.ref.Context().Name(),
+			*ocipack.manifest.desc,
+			mediaType,
+			raw,
+		); err != nil {
+			return nil, fmt.Errorf("could not attach sbom: %w", err)
+		}
+	}
+
 	return &ocipack, nil
 }
 
@@ -442,28 +445,20 @@ func newIndexAndManifestFromRemoteDigest(ctx context.Context, handle handler.Han
 	}
 
 	var retManifest *Manifest
-	authConfig := &authn.AuthConfig{}
-	transport := http.DefaultTransport.(*http.Transport).Clone()
 
-	// Annoyingly convert between regtypes and authn.
-	if auth, ok := auths[ref.Context().RegistryStr()]; ok {
-		authConfig.Username = auth.User
-		authConfig.Password = auth.Token
+	registries := ociutils.Registries{
+		Auths:   auths,
+		Configs: config.G[config.KraftKit](ctx).Registries,
+	}
 
-		if !auth.VerifySSL {
-			transport.TLSClientConfig = &tls.Config{
-				InsecureSkipVerify: true,
-			}
-		}
+	sources, err := registries.Sources(ref, remote.WithContext(ctx))
+	if err != nil {
+		return nil, nil, err
 	}
 
-	v1ImageIndex, err := cache.RemoteIndex(ref,
-		remote.WithContext(ctx),
-		remote.WithAuth(&simpleauth.SimpleAuthenticator{
-			Auth: authConfig,
-		}),
-		remote.WithTransport(transport),
-	)
+	v1ImageIndex, err := ociutils.Fetch(ctx, sources, func(src ociutils.Source) (v1.ImageIndex, error) {
+		return cache.RemoteIndex(src.Ref, src.Options...)
+	})
 	if err != nil {
 		return nil, nil, fmt.Errorf("could not get index from registry: %v", err)
 	}
@@ -519,19 +514,21 @@ func newIndexAndManifestFromRemoteDigest(ctx context.Context, handle handler.Han
 					manifest.config.Architecture = descriptor.Platform.Architecture
 					manifest.config.Platform = *descriptor.Platform
 				} else {
-					manifest.v1Image, err = cache.RemoteImage(
-						ref,
+					sources, err := registries.Sources(ref,
 						remote.WithPlatform(v1.Platform{
 							Architecture: descriptor.Platform.Architecture,
 							OS:           descriptor.Platform.OS,
 							OSFeatures:   descriptor.Platform.OSFeatures,
 						}),
 						remote.WithContext(egCtx),
-						remote.WithAuth(&simpleauth.SimpleAuthenticator{
-							Auth: authConfig,
-						}),
-						remote.WithTransport(transport),
 					)
+					if err != nil {
+						return err
+					}
+
+					manifest.v1Image, err = ociutils.Fetch(egCtx, sources, func(src ociutils.Source) (v1.Image, error) {
+						return cache.RemoteImage(src.Ref, src.Options...)
+					})
 					if err != nil {
 						return fmt.Errorf("getting image: %w", err)
 					}
@@ -738,6 +735,19 @@ func (ocipack *ociPackage) Columns() []tableprinter.Column {
 
 // Push implements pack.Package
 func (ocipack *ociPackage) Push(ctx context.Context, opts ...pack.PushOption) error {
+	// Unikraft Cloud materialises the packages which are pushed to its registry
+	// itself and does not reassemble initrds which have been packaged in chunks.
+	if strings.HasPrefix(ocipack.ref.Context().RegistryStr(), "index.unikraft.io") {
+		chunked, err := ocipack.hasChunkedInitrd(ctx)
+		if err != nil {
+			return err
+		}
+
+		if chunked {
+			return fmt.Errorf("cannot push %s to Unikraft Cloud: its initrd is packaged in chunks, repackage it without --chunk-initrd", ocipack.imageRef())
+		}
+	}
+
 	// In the circumstance where the original package is available, we use
 	// google/go-containerregistry to re-tag (which is achieved via `pusher.Push`
 	// which ultimately checks if the manifest, its layers, config and ultimately
@@ -751,30 +761,14 @@ func (ocipack *ociPackage) Push(ctx context.Context, opts ...pack.PushOption) er
 		log.G(ctx).
 			Debug("re-tagging original package such that remote references are maintained")
 
-		authConfig := &authn.AuthConfig{}
-		transport := http.DefaultTransport.(*http.Transport).Clone()
-
-		// Annoyingly convert between regtypes and authn.
-		if auth, ok := config.G[config.KraftKit](ctx).Auth[ocipack.ref.Context().RegistryStr()]; ok {
-			authConfig.Username = auth.User
-			authConfig.Password = auth.Token
-
-			if !auth.VerifySSL {
-				transport.TLSClientConfig = &tls.Config{
-					InsecureSkipVerify: true,
-				}
-			}
+		ropts, err := ociutils.RegistriesFromContext(ctx).RemoteOptions(ocipack.ref.Context().RegistryStr())
+		if err != nil {
+			return err
 		}
 
 		gcrlogs.Progress = golog.New(log.G(ctx).WriterLevel(logrus.TraceLevel), "", 0)
 
-		pusher, err := remote.NewPusher(
-			remote.WithContext(ctx),
-			remote.WithAuth(&simpleauth.SimpleAuthenticator{
-				Auth: authConfig,
-			}),
-			remote.WithTransport(transport),
-		)
+		pusher, err := remote.NewPusher(append(ropts, remote.WithContext(ctx))...)
 		if err != nil {
 			return err
 		}
@@ -798,9 +792,57 @@ func (ocipack *ociPackage) Push(ctx context.Context, opts ...pack.PushOption) er
 		return err
 	}
 
+	// Push any signatures, SBOMs and other artifacts which refer to the index
+	// or its manifests such that they accompany them in the remote registry.
+	if err := ocipack.pushReferrers(ctx, desc.Digest); err != nil {
+		return err
+	}
+
+	for _, manifest := range ocipack.index.manifests {
+		if manifest.desc == nil {
+			continue
+		}
+
+		if err := ocipack.pushReferrers(ctx, manifest.desc.Digest); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// SBOM returns the most recent software bill of materials which has been
+// attached to the package's manifest, as well as its media type.  No document
+// is returned if the package does not have an SBOM.
+func (ocipack *ociPackage) SBOM(ctx context.Context) ([]byte, string, error) {
+	return readReferrer(ctx,
+		ocipack.handle,
+		ocipack.imageRef(),
+		ocipack.manifest.desc.Digest,
+		sbom.MediaTypes()...,
+	)
+}
+
+// hasChunkedInitrd returns whether the initrd of the package has been packaged
+// in chunks.
+func (ocipack *ociPackage) hasChunkedInitrd(ctx context.Context) (bool, error) {
+	manifest, err := ocipack.handle.ResolveManifest(ctx,
+		ocipack.imageRef(),
+		ocipack.manifest.desc.Digest,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	for _, layer := range manifest.Layers {
+		if _, ok := layer.Annotations[AnnotationKernelInitrdChunk]; ok {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
 // Unpack implements pack.Package
 func (ocipack *ociPackage) Unpack(ctx context.Context, dir string) error {
 	image, err := ocipack.handle.UnpackImage(ctx,
@@ -818,6 +860,14 @@ func (ocipack *ociPackage) Unpack(ctx context.Context, dir string) error {
 	// Set the command
 	ocipack.command = image.Config.Cmd
 
+	// Reassemble the initrd if it has been packaged in chunks
+	if err := AssembleChunkedFile(
+		filepath.Join(dir, WellKnownInitrdChunksDir),
+		filepath.Join(dir, WellKnownInitrdPath),
+	); err != nil {
+		return fmt.Errorf("could not assemble initrd: %w", err)
+	}
+
 	// Set the initrd if available
 	initrdPath := filepath.Join(dir, WellKnownInitrdPath)
 	if f, err := os.Stat(initrdPath); err == nil && f.Size() > 0 {
@@ -843,6 +893,10 @@ func (ocipack *ociPackage) Pull(ctx context.Context, opts ...pack.PullOption) er
 		return err
 	}
 
+	if err := ocipack.verify(ctx); err != nil {
+		return err
+	}
+
 	// Pull the index but set the platform such that the relevant manifests can
 	// be retrieved as well.
 	if err := ocipack.handle.PullDigest(
@@ -895,6 +949,52 @@ func (ocipack *ociPackage) Pull(ctx context.Context, opts ...pack.PullOption) er
 	return nil
 }
 
+// verify checks the remote index of the package against the signature policy
+// which is set in the KraftKit configuration.  Since the index may have been
+// updated since the package was resolved, the manifest of the package must
+// also be part of the index whose signature has been verified.
+func (ocipack *ociPackage) verify(ctx context.Context) error {
+	dgst, err := cosign.VerifyFromContext(ctx, ocipack.handle, ocipack.ref)
+	if err != nil {
+		return fmt.Errorf("could not verify signature: %w", err)
+	} else if len(dgst) == 0 {
+		return nil
+	}
+
+	auths := ocipack.auths
+	if auths == nil {
+		auths = config.G[config.KraftKit](ctx).Auth
+	}
+
+	sources, err := ociutils.Registries{
+		Auths:   auths,
+		Configs: config.G[config.KraftKit](ctx).Registries,
+	}.Sources(ocipack.ref.Context().Digest(dgst.String()), remote.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	v1ImageIndex, err := ociutils.Fetch(ctx, sources, func(src ociutils.Source) (v1.ImageIndex, error) {
+		return cache.RemoteIndex(src.Ref, src.Options...)
+	})
+	if err != nil {
+		return fmt.Errorf("could not get verified index from registry: %w", err)
+	}
+
+	v1ImageIndexManifest, err := v1ImageIndex.IndexManifest()
+	if err != nil {
+		return fmt.Errorf("could not access verified index manifest: %w", err)
+	}
+
+	for _, manifest := range v1ImageIndexManifest.Manifests {
+		if manifest.Digest.String() == ocipack.manifest.desc.Digest.String() {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("manifest '%s' is not part of the verified index '%s'", ocipack.manifest.desc.Digest.String(), dgst.String())
+}
+
 // PulledAt implements pack.Package
 func (ocipack *ociPackage) PulledAt(ctx context.Context) (bool, time.Time, error) {
 	if len(ocipack.manifest.manifest.Layers) == 0 {
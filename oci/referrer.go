@@ -0,0 +1,168 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package oci
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"kraftkit.sh/oci/handler"
+)
+
+// saveReferrer stores the blob as the only layer of an artifact manifest whose
+// subject is the provided descriptor, e.g. an SBOM of a manifest.  The artifact
+// type is the media type of the blob.
+func saveReferrer(ctx context.Context, handle handler.Handler, repository string, subject ocispec.Descriptor, artifactType string, blob []byte) (*ocispec.Descriptor, error) {
+	layer := ocispec.Descriptor{
+		MediaType: artifactType,
+		Digest:    digest.FromBytes(blob),
+		Size:      int64(len(blob)),
+	}
+
+	// The configuration lists the blob as its only layer such that the artifact
+	// is a valid image and can be handled like any other.
+	configRaw, err := json.Marshal(ocispec.Image{
+		RootFS: ocispec.RootFS{
+			Type:    "layers",
+			DiffIDs: []digest.Digest{layer.Digest},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal config: %w", err)
+	}
+
+	config := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageConfig,
+		Digest:    digest.FromBytes(configRaw),
+		Size:      int64(len(configRaw)),
+	}
+
+	manifestRaw, err := json.Marshal(ocispec.Manifest{
+		Versioned: specs.Versioned{
+			SchemaVersion: 2,
+		},
+		MediaType:    ocispec.MediaTypeImageManifest,
+		ArtifactType: artifactType,
+		Config:       config,
+		Layers:       []ocispec.Descriptor{layer},
+		Subject: &ocispec.Descriptor{
+			MediaType: subject.MediaType,
+			Digest:    subject.Digest,
+			Size:      subject.Size,
+		},
+		Annotations: map[string]string{
+			ocispec.AnnotationCreated: time.Now().UTC().Format(time.RFC3339),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal manifest: %w", err)
+	}
+
+	desc := ocispec.Descriptor{
+		MediaType:    ocispec.MediaTypeImageManifest,
+		ArtifactType: artifactType,
+		Digest:       digest.FromBytes(manifestRaw),
+		Size:         int64(len(manifestRaw)),
+	}
+
+	fullref := fmt.Sprintf("%s@%s", repository, desc.Digest.String())
+
+	if err := handle.SaveDescriptor(ctx, fullref, layer, bytes.NewReader(blob), nil); err != nil {
+		return nil, fmt.Errorf("could not save artifact: %w", err)
+	}
+
+	if err := handle.SaveDescriptor(ctx, fullref, config, bytes.NewReader(configRaw), nil); err != nil {
+		return nil, fmt.Errorf("could not save artifact config: %w", err)
+	}
+
+	if err := handle.SaveDescriptor(ctx, fullref, desc, bytes.NewReader(manifestRaw), nil); err != nil {
+		return nil, fmt.Errorf("could not save artifact manifest: %w", err)
+	}
+
+	return &desc, nil
+}
+
+// readReferrer returns the blob of the most recent locally stored artifact of
+// one of the provided artifact types which refers to the subject, as well as
+// its artifact type.
+func readReferrer(ctx context.Context, handle handler.Handler, fullref string, subject digest.Digest, artifactTypes ...string) ([]byte, string, error) {
+	var latest *ocispec.Manifest
+	var created string
+
+	for _, artifactType := range artifactTypes {
+		referrers, err := handle.ResolveReferrers(ctx, fullref, subject, artifactType)
+		if err != nil {
+			return nil, "", err
+		}
+
+		for _, referrer := range referrers {
+			manifest, err := handle.ResolveManifest(ctx, fullref, referrer.Digest)
+			if err != nil {
+				return nil, "", err
+			}
+
+			if len(manifest.Layers) == 0 {
+				continue
+			}
+
+			// RFC 3339 timestamps in UTC are ordered lexicographically.
+			if latest == nil || manifest.Annotations[ocispec.AnnotationCreated] > created {
+				latest = manifest
+				created = manifest.Annotations[ocispec.AnnotationCreated]
+			}
+		}
+	}
+
+	if latest == nil {
+		return nil, "", nil
+	}
+
+	reader, err := handle.ReadDigest(ctx, latest.Layers[0].Digest)
+	if err != nil {
+		return nil, "", fmt.Errorf("could not read artifact: %w", err)
+	}
+
+	defer reader.Close()
+
+	blob, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, "", fmt.Errorf("could not read artifact: %w", err)
+	}
+
+	return blob, latest.ArtifactType, nil
+}
+
+// pushReferrers pushes all locally stored artifacts which refer to the subject.
+func (ocipack *ociPackage) pushReferrers(ctx context.Context, subject digest.Digest) error {
+	return pushReferrers(ctx, ocipack.handle, ocipack.imageRef(), ocipack.ref.Context().Name(), subject)
+}
+
+// pushReferrers pushes all artifacts which are stored by the handler and refer
+// to the subject to the provided repository.
+func pushReferrers(ctx context.Context, handle handler.Handler, fullref, repository string, subject digest.Digest) error {
+	referrers, err := handle.ResolveReferrers(ctx, fullref, subject, "")
+	if err != nil {
+		return fmt.Errorf("could not resolve referrers: %w", err)
+	}
+
+	for _, referrer := range referrers {
+		referrer := referrer
+		referrerRef := fmt.Sprintf("%s@%s", repository, referrer.Digest.String())
+
+		if err := handle.PushDescriptor(ctx, referrerRef, &referrer); err != nil {
+			return fmt.Errorf("could not push referrer '%s': %w", referrer.Digest.String(), err)
+		}
+	}
+
+	return nil
+}
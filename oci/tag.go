@@ -0,0 +1,196 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package oci
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"kraftkit.sh/log"
+	"kraftkit.sh/oci/handler"
+	ociutils "kraftkit.sh/oci/utils"
+	"kraftkit.sh/packmanager"
+)
+
+// parseRef parses the provided reference using the default registry and tag.
+func parseRef(ref string) (name.Reference, error) {
+	return name.ParseReference(ref,
+		name.WithDefaultRegistry(DefaultRegistry),
+		name.WithDefaultTag(DefaultTag),
+	)
+}
+
+// Tag creates the index dst in the handler which lists the same manifests as
+// the existing index src.  Neither the manifests nor their layers are copied.
+func Tag(ctx context.Context, handle handler.Handler, src, dst string) (*ocispec.Descriptor, error) {
+	srcRef, err := parseRef(src)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse source reference: %w", err)
+	}
+
+	dstRef, err := parseRef(dst)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse destination reference: %w", err)
+	}
+
+	index, err := NewIndexFromRef(ctx, handle, srcRef.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	if srcRef.Name() == dstRef.Name() {
+		return index.Descriptor()
+	}
+
+	log.G(ctx).
+		WithField("src", srcRef.Name()).
+		WithField("dst", dstRef.Name()).
+		Debug("tagging")
+
+	return tagIndex(ctx, handle, index, dstRef.Name())
+}
+
+// tagIndex saves a new index under the provided reference which lists the
+// same manifests as the provided index.
+func tagIndex(ctx context.Context, handle handler.Handler, index *Index, fullref string) (*ocispec.Descriptor, error) {
+	tagged, err := NewIndex(ctx, handle)
+	if err != nil {
+		return nil, err
+	}
+
+	// Retain any additional annotations.  Those which identify the index are
+	// set when the index is saved.
+	for k, v := range index.index.Annotations {
+		tagged.SetAnnotation(ctx, k, v)
+	}
+
+	for _, manifest := range index.manifests {
+		if err := tagged.AddManifest(ctx, manifest); err != nil {
+			return nil, err
+		}
+	}
+
+	desc, err := tagged.Save(ctx, fullref, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not save index: %w", err)
+	}
+
+	return &desc, nil
+}
+
+// CreateIndex creates the index dst in the handler which combines the
+// manifests of the existing indexes of the provided references, e.g. to
+// assemble a multi-architecture package from packages which have been built
+// separately.  No two manifests may target the same platform.  The strategy
+// determines whether the manifests of an existing index dst are retained
+// (merge), discarded (overwrite) or whether to fail (abort).
+func CreateIndex(ctx context.Context, handle handler.Handler, dst string, from []string, strategy packmanager.MergeStrategy) (*ocispec.Descriptor, error) {
+	if len(from) == 0 {
+		return nil, fmt.Errorf("no source packages provided")
+	}
+
+	dstRef, err := parseRef(dst)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse destination reference: %w", err)
+	}
+
+	index, err := NewIndex(ctx, handle)
+	if err != nil {
+		return nil, err
+	}
+
+	// Track the source of each platform to detect conflicts.
+	sources := map[string]string{}
+	checksums := map[*Manifest]string{}
+
+	checksum := func(manifest *Manifest) (string, error) {
+		return ociutils.PlatformChecksum(dstRef.String(), &ocispec.Platform{
+			Architecture: manifest.config.Architecture,
+			OS:           manifest.config.OS,
+			OSVersion:    manifest.config.OSVersion,
+			OSFeatures:   manifest.config.OSFeatures,
+		})
+	}
+
+	if existing, err := NewIndexFromRef(ctx, handle, dstRef.Name()); err == nil {
+		switch strategy {
+		case packmanager.StrategyAbort:
+			return nil, fmt.Errorf("package '%s' already exists and merge strategy set to exit on conflict", dstRef.Name())
+
+		case packmanager.StrategyMerge:
+			for _, manifest := range existing.manifests {
+				sum, err := checksum(manifest)
+				if err != nil {
+					return nil, err
+				}
+
+				checksums[manifest] = sum
+				index.manifests = append(index.manifests, manifest)
+			}
+
+		case packmanager.StrategyOverwrite:
+
+		default:
+			return nil, fmt.Errorf("unsupported merge strategy: %s", strategy)
+		}
+	}
+
+	for _, src := range from {
+		srcRef, err := parseRef(src)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse source reference: %w", err)
+		}
+
+		srcIndex, err := NewIndexFromRef(ctx, handle, srcRef.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, manifest := range srcIndex.manifests {
+			sum, err := checksum(manifest)
+			if err != nil {
+				return nil, err
+			}
+
+			if other, ok := sources[sum]; ok {
+				return nil, fmt.Errorf("both '%s' and '%s' provide a %s/%s target", other, srcRef.Name(), manifest.config.OS, manifest.config.Architecture)
+			}
+
+			sources[sum] = srcRef.Name()
+
+			// Manifests of the sources replace those of the existing index which
+			// target the same platform.
+			manifests := index.manifests[:0]
+			for _, m := range index.manifests {
+				if checksums[m] != sum {
+					manifests = append(manifests, m)
+				}
+			}
+
+			index.manifests = manifests
+
+			log.G(ctx).
+				WithField("src", srcRef.Name()).
+				WithField("digest", manifest.desc.Digest.String()).
+				WithField("platform", fmt.Sprintf("%s/%s", manifest.config.OS, manifest.config.Architecture)).
+				Debug("adding manifest")
+
+			checksums[manifest] = sum
+			if err := index.AddManifest(ctx, manifest); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	desc, err := index.Save(ctx, dstRef.Name(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not save index: %w", err)
+	}
+
+	return &desc, nil
+}
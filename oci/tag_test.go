@@ -0,0 +1,214 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package oci
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"kraftkit.sh/oci/handler"
+	"kraftkit.sh/packmanager"
+)
+
+// newTestPackage saves a package in the handler which consists of a single
+// QEMU target of the provided architecture whose layer contains the kernel.
+func newTestPackage(t *testing.T, handle handler.Handler, fullref, arch, kernel string) *ocispec.Descriptor {
+	t.Helper()
+
+	ctx := context.Background()
+
+	src := filepath.Join(t.TempDir(), "kernel")
+	if err := os.WriteFile(src, []byte(kernel), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	layer, err := NewLayerFromFile(ctx, ocispec.MediaTypeImageLayer, src, WellKnownKernelPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err := NewManifest(ctx, handle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifest.SetOS(ctx, "qemu")
+	manifest.SetArchitecture(ctx, arch)
+
+	if _, err := manifest.AddLayer(ctx, layer); err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := NewIndex(ctx, handle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := index.AddManifest(ctx, manifest); err != nil {
+		t.Fatal(err)
+	}
+
+	desc, err := index.Save(ctx, fullref, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &desc
+}
+
+// manifestDigests returns the sorted digests of the manifests of the index
+// with the provided reference.
+func manifestDigests(t *testing.T, handle handler.Handler, fullref string) []string {
+	t.Helper()
+
+	spec, err := handle.ResolveIndex(context.Background(), fullref)
+	if err != nil {
+		t.Fatalf("could not resolve %s: %v", fullref, err)
+	}
+
+	var digests []string
+	for _, manifest := range spec.Manifests {
+		digests = append(digests, manifest.Digest.String())
+	}
+
+	sort.Strings(digests)
+
+	return digests
+}
+
+func equalDigests(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestTag(t *testing.T) {
+	ctx := context.Background()
+
+	handle, err := handler.NewDirectoryHandler(t.TempDir(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := "unikraft.org/helloworld:latest"
+	dst := "unikraft.org/helloworld:v1"
+
+	newTestPackage(t, handle, src, "x86_64", "x86_64 kernel")
+
+	if _, err := Tag(ctx, handle, src, dst); err != nil {
+		t.Fatalf("could not tag: %v", err)
+	}
+
+	expected := manifestDigests(t, handle, src)
+	if got := manifestDigests(t, handle, dst); !equalDigests(expected, got) {
+		t.Errorf("expected tag to list the manifests %v, got %v", expected, got)
+	}
+
+	// Tagging a package with its own name leaves it as-is.
+	before, err := handle.ResolveIndex(ctx, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Tag(ctx, handle, src, src); err != nil {
+		t.Fatalf("could not tag package with its own name: %v", err)
+	}
+
+	after, err := handle.ResolveIndex(ctx, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(before.Manifests) != len(after.Manifests) || before.Annotations[ocispec.AnnotationRefName] != after.Annotations[ocispec.AnnotationRefName] {
+		t.Errorf("expected package to be unchanged, got %+v", after)
+	}
+
+	if _, err := Tag(ctx, handle, "unikraft.org/unknown:latest", dst); err == nil {
+		t.Error("expected tagging an unknown package to fail")
+	}
+}
+
+func TestCreateIndex(t *testing.T) {
+	ctx := context.Background()
+
+	handle, err := handler.NewDirectoryHandler(t.TempDir(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	amd64 := "unikraft.org/helloworld:x86_64"
+	arm64 := "unikraft.org/helloworld:arm64"
+	rebuilt := "unikraft.org/helloworld:x86_64-rebuilt"
+	dst := "unikraft.org/helloworld:latest"
+
+	newTestPackage(t, handle, amd64, "x86_64", "x86_64 kernel")
+	newTestPackage(t, handle, arm64, "arm64", "arm64 kernel")
+	newTestPackage(t, handle, rebuilt, "x86_64", "rebuilt x86_64 kernel")
+
+	if _, err := CreateIndex(ctx, handle, dst, nil, packmanager.StrategyMerge); err == nil {
+		t.Error("expected creating an index without sources to fail")
+	}
+
+	if _, err := CreateIndex(ctx, handle, "unikraft.org/helloworld:conflict", []string{amd64, rebuilt}, packmanager.StrategyMerge); err == nil {
+		t.Error("expected sources which target the same platform to conflict")
+	}
+
+	if _, err := handle.ResolveIndex(ctx, "unikraft.org/helloworld:conflict"); err == nil {
+		t.Error("expected no index to be saved on conflict")
+	}
+
+	if _, err := CreateIndex(ctx, handle, dst, []string{amd64, arm64}, packmanager.StrategyMerge); err != nil {
+		t.Fatalf("could not create index: %v", err)
+	}
+
+	expected := append(manifestDigests(t, handle, amd64), manifestDigests(t, handle, arm64)...)
+	sort.Strings(expected)
+	if got := manifestDigests(t, handle, dst); !equalDigests(expected, got) {
+		t.Fatalf("expected index to combine the manifests %v, got %v", expected, got)
+	}
+
+	if _, err := CreateIndex(ctx, handle, dst, []string{rebuilt}, packmanager.StrategyAbort); err == nil {
+		t.Error("expected the abort strategy to fail for an existing index")
+	}
+
+	if got := manifestDigests(t, handle, dst); !equalDigests(expected, got) {
+		t.Errorf("expected existing index to be unchanged on abort, got %v", got)
+	}
+
+	// Merging retains the targets of the existing index, except for those which
+	// are replaced by a source of the same platform.
+	if _, err := CreateIndex(ctx, handle, dst, []string{rebuilt}, packmanager.StrategyMerge); err != nil {
+		t.Fatalf("could not merge index: %v", err)
+	}
+
+	expected = append(manifestDigests(t, handle, rebuilt), manifestDigests(t, handle, arm64)...)
+	sort.Strings(expected)
+	if got := manifestDigests(t, handle, dst); !equalDigests(expected, got) {
+		t.Errorf("expected merged index to list the manifests %v, got %v", expected, got)
+	}
+
+	// Overwriting discards the targets of the existing index.
+	if _, err := CreateIndex(ctx, handle, dst, []string{amd64}, packmanager.StrategyOverwrite); err != nil {
+		t.Fatalf("could not overwrite index: %v", err)
+	}
+
+	expected = manifestDigests(t, handle, amd64)
+	if got := manifestDigests(t, handle, dst); !equalDigests(expected, got) {
+		t.Errorf("expected overwritten index to list the manifests %v, got %v", expected, got)
+	}
+}
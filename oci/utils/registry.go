@@ -0,0 +1,253 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"kraftkit.sh/config"
+	"kraftkit.sh/log"
+	"kraftkit.sh/oci/simpleauth"
+)
+
+// Registries determines how remote registries are accessed based on the
+// authentication and registry configuration of KraftKit.
+type Registries struct {
+	Auths   map[string]config.AuthConfig
+	Configs map[string]config.RegistryConfig
+}
+
+// RegistriesFromContext returns the registries as they are set in the KraftKit
+// configuration of the provided context.
+func RegistriesFromContext(ctx context.Context) Registries {
+	return Registries{
+		Auths:   config.G[config.KraftKit](ctx).Auth,
+		Configs: config.G[config.KraftKit](ctx).Registries,
+	}
+}
+
+// Config returns the configuration of the registry at the provided domain.  A
+// registry whose authentication disables SSL verification is insecure.
+func (r Registries) Config(domain string) config.RegistryConfig {
+	cfg := r.Configs[domain]
+
+	if auth, ok := r.Auths[domain]; ok && !auth.VerifySSL {
+		cfg.Insecure = true
+	}
+
+	return cfg
+}
+
+// NameOptions returns the options used to parse references to the registry at
+// the provided domain, such that plain HTTP is permitted for insecure and
+// plain HTTP registries.
+func (r Registries) NameOptions(domain string) []name.Option {
+	if cfg := r.Config(domain); cfg.Insecure || cfg.PlainHTTP {
+		return []name.Option{name.Insecure}
+	}
+
+	return nil
+}
+
+// ParseReference parses the provided reference with the options of its
+// registry.
+func (r Registries) ParseReference(s string, opts ...name.Option) (name.Reference, error) {
+	ref, err := name.ParseReference(s, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if nopts := r.NameOptions(ref.Context().RegistryStr()); len(nopts) > 0 {
+		return name.ParseReference(s, append(opts, nopts...)...)
+	}
+
+	return ref, nil
+}
+
+// Transport returns the HTTP transport used to connect to the registry at the
+// provided domain, which trusts its CA bundle or, if the registry is insecure,
+// does not verify its certificate at all.
+func (r Registries) Transport(domain string) (*http.Transport, error) {
+	cfg := r.Config(domain)
+	transport := remote.DefaultTransport.(*http.Transport).Clone()
+
+	if cfg.Insecure {
+		transport.TLSClientConfig = &tls.Config{
+			InsecureSkipVerify: true,
+		}
+	} else if len(cfg.CAFile) > 0 {
+		bundle, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read CA bundle of registry '%s': %w", domain, err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(bundle) {
+			return nil, fmt.Errorf("no certificates found in CA bundle '%s' of registry '%s'", cfg.CAFile, domain)
+		}
+
+		transport.TLSClientConfig = &tls.Config{
+			RootCAs: pool,
+		}
+	}
+
+	return transport, nil
+}
+
+// Authenticator returns the authenticator used for the registry at the
+// provided domain.
+func (r Registries) Authenticator(domain string) authn.Authenticator {
+	auth, ok := r.Auths[domain]
+	if !ok {
+		return authn.Anonymous
+	}
+
+	// Annoyingly convert between regtypes and authn.
+	return &simpleauth.SimpleAuthenticator{
+		Auth: &authn.AuthConfig{
+			Username: auth.User,
+			Password: auth.Token,
+		},
+	}
+}
+
+// RemoteOptions returns the transport and authentication options used to
+// communicate with the registry at the provided domain.
+func (r Registries) RemoteOptions(domain string) ([]remote.Option, error) {
+	transport, err := r.Transport(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	return []remote.Option{
+		remote.WithTransport(transport),
+		remote.WithAuth(r.Authenticator(domain)),
+	}, nil
+}
+
+// Mirror is a registry which serves the repositories of another registry,
+// optionally beneath a path prefix.
+type Mirror struct {
+	Registry name.Registry
+	Prefix   string
+}
+
+// Repository returns the repository of the mirror which serves the provided
+// repository of the mirrored registry.
+func (m Mirror) Repository(repo string) name.Repository {
+	if len(m.Prefix) == 0 {
+		return m.Registry.Repo(repo)
+	}
+
+	return m.Registry.Repo(m.Prefix, repo)
+}
+
+// Mirrors returns the mirrors of the registry at the provided domain in the
+// order in which they are preferred.
+func (r Registries) Mirrors(domain string) ([]Mirror, error) {
+	var mirrors []Mirror
+
+	for _, entry := range r.Config(domain).Mirrors {
+		host, prefix, _ := strings.Cut(strings.Trim(entry, "/"), "/")
+
+		reg, err := name.NewRegistry(host, r.NameOptions(host)...)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse mirror '%s' of registry '%s': %w", entry, domain, err)
+		}
+
+		mirrors = append(mirrors, Mirror{
+			Registry: reg,
+			Prefix:   prefix,
+		})
+	}
+
+	return mirrors, nil
+}
+
+// Source is a reference from which content is pulled together with the
+// options used to access its registry.
+type Source struct {
+	Ref     name.Reference
+	Options []remote.Option
+}
+
+// Sources returns the sources from which the provided reference is pulled in
+// the order in which they are preferred: each of the mirrors of its registry
+// followed by the registry itself.  The provided options are prepended to
+// those of each source.
+func (r Registries) Sources(ref name.Reference, opts ...remote.Option) ([]Source, error) {
+	mirrors, err := r.Mirrors(ref.Context().RegistryStr())
+	if err != nil {
+		return nil, err
+	}
+
+	repos := make([]name.Repository, 0, len(mirrors)+1)
+	for _, mirror := range mirrors {
+		repos = append(repos, mirror.Repository(ref.Context().RepositoryStr()))
+	}
+
+	origin, err := r.ParseReference(ref.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	repos = append(repos, origin.Context())
+
+	sources := make([]Source, 0, len(repos))
+	for _, repo := range repos {
+		ropts, err := r.RemoteOptions(repo.RegistryStr())
+		if err != nil {
+			return nil, err
+		}
+
+		var sref name.Reference
+		if _, ok := ref.(name.Digest); ok {
+			sref = repo.Digest(ref.Identifier())
+		} else {
+			sref = repo.Tag(ref.Identifier())
+		}
+
+		sources = append(sources, Source{
+			Ref:     sref,
+			Options: append(append([]remote.Option{}, opts...), ropts...),
+		})
+	}
+
+	return sources, nil
+}
+
+// Fetch calls fn with each of the provided sources in order and returns the
+// result of the first which succeeds or, if none does, the error of the last.
+func Fetch[T any](ctx context.Context, sources []Source, fn func(Source) (T, error)) (T, error) {
+	var ret T
+	err := fmt.Errorf("no sources to fetch from")
+
+	for _, src := range sources {
+		if ret, err = fn(src); err == nil {
+			return ret, nil
+		}
+
+		log.G(ctx).
+			WithField("ref", src.Ref.Name()).
+			Debugf("could not fetch from source: %v", err)
+	}
+
+	return ret, err
+}
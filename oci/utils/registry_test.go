@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+package utils
+
+import (
+	"testing"
+
+	"kraftkit.sh/config"
+)
+
+func TestParseReferencePlainHTTP(t *testing.T) {
+	registries := Registries{
+		Configs: map[string]config.RegistryConfig{
+			"registry.internal:5000": {PlainHTTP: true},
+		},
+	}
+
+	for fullref, scheme := range map[string]string{
+		"registry.internal:5000/helloworld:latest": "http",
+		"registry.example.com/helloworld:latest":   "https",
+	} {
+		ref, err := registries.ParseReference(fullref)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := ref.Context().Scheme(); got != scheme {
+			t.Errorf("expected scheme of %s to be %s, got %s", fullref, scheme, got)
+		}
+	}
+}
+
+func TestSources(t *testing.T) {
+	registries := Registries{
+		Configs: map[string]config.RegistryConfig{
+			"unikraft.org": {
+				Mirrors: []string{
+					"mirror.internal",
+					"harbor.internal/proxy/unikraft.org/",
+				},
+			},
+			"harbor.internal": {PlainHTTP: true},
+		},
+	}
+
+	for fullref, expected := range map[string][]string{
+		"unikraft.org/nginx:latest": {
+			"mirror.internal/nginx:latest",
+			"harbor.internal/proxy/unikraft.org/nginx:latest",
+			"unikraft.org/nginx:latest",
+		},
+		"unikraft.org/nginx@sha256:0000000000000000000000000000000000000000000000000000000000000000": {
+			"mirror.internal/nginx@sha256:0000000000000000000000000000000000000000000000000000000000000000",
+			"harbor.internal/proxy/unikraft.org/nginx@sha256:0000000000000000000000000000000000000000000000000000000000000000",
+			"unikraft.org/nginx@sha256:0000000000000000000000000000000000000000000000000000000000000000",
+		},
+		"registry.example.com/nginx:latest": {
+			"registry.example.com/nginx:latest",
+		},
+	} {
+		ref, err := registries.ParseReference(fullref)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sources, err := registries.Sources(ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(sources) != len(expected) {
+			t.Fatalf("expected %d sources of %s, got %d", len(expected), fullref, len(sources))
+		}
+
+		for i, src := range sources {
+			if src.Ref.Name() != expected[i] {
+				t.Errorf("expected source %d of %s to be %s, got %s", i, fullref, expected[i], src.Ref.Name())
+			}
+		}
+
+		if len(sources) == 3 && sources[1].Ref.Context().Scheme() != "http" {
+			t.Errorf("expected plain HTTP mirror of %s to use http", fullref)
+		}
+	}
+}
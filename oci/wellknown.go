@@ -8,7 +8,10 @@ const (
 	WellKnownKernelPath      = "/unikraft/bin/kernel"
 	WellKnownKernelDbgPath   = "/unikraft/bin/kernel.dbg"
 	WellKnownInitrdPath      = "/unikraft/bin/initrd"
+	WellKnownInitrdChunksDir = "/unikraft/bin/initrd.d"
 	WellKnownConfigPath      = "/unikraft/bin/config"
+	WellKnownKernelLibDir    = "/unikraft/lib"
+	WellKnownKernelObjDir    = "/unikraft/obj"
 	WellKnownKernelSourceDir = "/unikraft/src"
 	WellKnownAppSourceDir    = "/unikraft/app"
 )
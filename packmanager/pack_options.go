@@ -7,20 +7,25 @@ package packmanager
 // PackOptions contains the list of options which can be set when packaging a
 // component.
 type PackOptions struct {
+	appSourceDir                     string
 	appSourceFiles                   bool
 	args                             []string
 	env                              []string
 	initrd                           string
+	initrdChunks                     bool
 	kconfig                          bool
 	kernelDbg                        bool
 	kernelLibraryIntermediateObjects bool
 	kernelLibraryObjects             bool
+	kernelSourceDirs                 []string
 	kernelSourceFiles                bool
 	kernelVersion                    string
 	labels                           map[string]string
 	name                             string
 	output                           string
 	mergeStrategy                    MergeStrategy
+	sbom                             []byte
+	sbomMediaType                    string
 }
 
 // NewPackOptions returns an instantiated *NewPackOptions with default
@@ -37,6 +42,11 @@ func (popts *PackOptions) PackAppSourceFiles() bool {
 	return popts.appSourceFiles
 }
 
+// AppSourceDir returns the directory of the application source files.
+func (popts *PackOptions) AppSourceDir() string {
+	return popts.appSourceDir
+}
+
 // Args returns the arguments to pass to the kernel.
 func (popts *PackOptions) Args() []string {
 	return popts.args
@@ -52,6 +62,12 @@ func (popts *PackOptions) Initrd() string {
 	return popts.initrd
 }
 
+// InitrdChunks returns whether the initrd is packaged as multiple
+// content-defined chunks.
+func (popts *PackOptions) InitrdChunks() bool {
+	return popts.initrdChunks
+}
+
 // PackKConfig returns whether the .config file should be packaged.
 func (popts *PackOptions) PackKConfig() bool {
 	return popts.kconfig
@@ -78,6 +94,12 @@ func (popts *PackOptions) PackKernelSourceFiles() bool {
 	return popts.kernelSourceFiles
 }
 
+// KernelSourceDirs returns the directories of the Unikraft core and the
+// libraries which make up the kernel.
+func (popts *PackOptions) KernelSourceDirs() []string {
+	return popts.kernelSourceDirs
+}
+
 // KernelVersion returns the version of the kernel
 func (popts *PackOptions) KernelVersion() string {
 	return popts.kernelVersion
@@ -103,6 +125,12 @@ func (popts *PackOptions) MergeStrategy() MergeStrategy {
 	return popts.mergeStrategy
 }
 
+// SBOM returns the software bill of materials which should be attached to the
+// package and its media type.
+func (popts *PackOptions) SBOM() ([]byte, string) {
+	return popts.sbom, popts.sbomMediaType
+}
+
 // PackOption is an option function which is used to modify PackOptions.
 type PackOption func(*PackOptions)
 
@@ -113,6 +141,14 @@ func PackAppSourceFiles(pack bool) PackOption {
 	}
 }
 
+// PackAppSourceDir sets the directory whose contents are included when
+// packaging application source files.
+func PackAppSourceDir(dir string) PackOption {
+	return func(popts *PackOptions) {
+		popts.appSourceDir = dir
+	}
+}
+
 // PackArgs sets the arguments to be passed to the application.
 func PackArgs(args ...string) PackOption {
 	return func(popts *PackOptions) {
@@ -134,6 +170,15 @@ func PackInitrd(initrd string) PackOption {
 	}
 }
 
+// PackInitrdChunks marks that the initrd is split into content-defined chunks
+// which are each packaged as a separate layer, such that chunks which have not
+// changed between versions of a package are reused rather than pulled again.
+func PackInitrdChunks(chunks bool) PackOption {
+	return func(popts *PackOptions) {
+		popts.initrdChunks = chunks
+	}
+}
+
 // PackKernelDbg includes the debug kernel in the package.
 func PackKernelDbg(dbg bool) PackOption {
 	return func(popts *PackOptions) {
@@ -145,14 +190,14 @@ func PackKernelDbg(dbg bool) PackOption {
 // object files, e.g. libnolibc/errno.o
 func PackKernelLibraryIntermediateObjects(pack bool) PackOption {
 	return func(popts *PackOptions) {
-		popts.kernelSourceFiles = pack
+		popts.kernelLibraryIntermediateObjects = pack
 	}
 }
 
 // PackKernelLibraryObjects marks to include library object files, e.g. nolibc.o
 func PackKernelLibraryObjects(pack bool) PackOption {
 	return func(popts *PackOptions) {
-		popts.kernelSourceFiles = pack
+		popts.kernelLibraryObjects = pack
 	}
 }
 
@@ -165,6 +210,14 @@ func PackKernelSourceFiles(pack bool) PackOption {
 	}
 }
 
+// PackKernelSourceDirs sets the directories of the Unikraft core and the
+// libraries whose source files are included when packaging kernel source files.
+func PackKernelSourceDirs(dirs ...string) PackOption {
+	return func(popts *PackOptions) {
+		popts.kernelSourceDirs = dirs
+	}
+}
+
 // PackWithKernelVersion sets the version of the Unikraft core.
 func PackWithKernelVersion(version string) PackOption {
 	return func(popts *PackOptions) {
@@ -207,3 +260,12 @@ func PackLabels(labels map[string]string) PackOption {
 		popts.labels = labels
 	}
 }
+
+// PackSBOM attaches the software bill of materials of the provided media type
+// to the package.
+func PackSBOM(mediaType string, sbom []byte) PackOption {
+	return func(popts *PackOptions) {
+		popts.sbom = sbom
+		popts.sbomMediaType = mediaType
+	}
+}
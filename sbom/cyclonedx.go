@@ -0,0 +1,154 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"kraftkit.sh/internal/version"
+)
+
+type cdxDocument struct {
+	BOMFormat    string          `json:"bomFormat"`
+	SpecVersion  string          `json:"specVersion"`
+	SerialNumber string          `json:"serialNumber"`
+	Version      int             `json:"version"`
+	Metadata     cdxMetadata     `json:"metadata"`
+	Components   []cdxComponent  `json:"components,omitempty"`
+	Dependencies []cdxDependency `json:"dependencies,omitempty"`
+}
+
+type cdxMetadata struct {
+	Timestamp string       `json:"timestamp"`
+	Tools     cdxTools     `json:"tools"`
+	Component cdxComponent `json:"component"`
+}
+
+type cdxTools struct {
+	Components []cdxComponent `json:"components"`
+}
+
+type cdxComponent struct {
+	Type               string        `json:"type"`
+	BOMRef             string        `json:"bom-ref,omitempty"`
+	Name               string        `json:"name"`
+	Version            string        `json:"version,omitempty"`
+	Hashes             []cdxHash     `json:"hashes,omitempty"`
+	Licenses           []cdxLicense  `json:"licenses,omitempty"`
+	ExternalReferences []cdxExtRef   `json:"externalReferences,omitempty"`
+	Properties         []cdxProperty `json:"properties,omitempty"`
+}
+
+type cdxHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type cdxLicense struct {
+	Expression string `json:"expression"`
+}
+
+type cdxExtRef struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cdxDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+// cyclonedx encodes the document as CycloneDX 1.5 JSON.
+func (doc *Document) cyclonedx() ([]byte, error) {
+	sum := doc.checksum()
+
+	// Derive a version 4 UUID from the checksum of the document.
+	sum[6] = (sum[6] & 0x0f) | 0x40
+	sum[8] = (sum[8] & 0x3f) | 0x80
+
+	out := cdxDocument{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16]),
+		Version:      1,
+		Metadata: cdxMetadata{
+			Timestamp: doc.Created.UTC().Format(time.RFC3339),
+			Tools: cdxTools{
+				Components: []cdxComponent{{
+					Type:    "application",
+					Name:    "kraftkit",
+					Version: version.Version(),
+				}},
+			},
+		},
+	}
+
+	dependency := cdxDependency{
+		Ref: "component-0",
+	}
+
+	for i, component := range doc.Components {
+		c := cdxComponent{
+			Type:    "library",
+			BOMRef:  fmt.Sprintf("component-%d", i),
+			Name:    component.Name,
+			Version: component.Version,
+		}
+
+		switch component.Type {
+		case ComponentTypeApplication:
+			c.Type = "application"
+		case ComponentTypeCore:
+			c.Type = "operating-system"
+		}
+
+		if len(component.License) > 0 {
+			c.Licenses = []cdxLicense{{Expression: component.License}}
+		}
+
+		if len(component.Source) > 0 {
+			c.ExternalReferences = []cdxExtRef{{Type: "distribution", URL: component.Source}}
+		}
+
+		if len(component.Revision) > 0 {
+			c.Properties = []cdxProperty{{Name: "kraftkit:git:revision", Value: component.Revision}}
+		}
+
+		if i == 0 {
+			out.Metadata.Component = c
+			continue
+		}
+
+		out.Components = append(out.Components, c)
+		dependency.DependsOn = append(dependency.DependsOn, c.BOMRef)
+	}
+
+	for i, file := range doc.Files {
+		out.Components = append(out.Components, cdxComponent{
+			Type:   "file",
+			BOMRef: fmt.Sprintf("file-%d", i),
+			Name:   file.Path,
+			Hashes: []cdxHash{{
+				Alg:     "SHA-256",
+				Content: file.SHA256,
+			}},
+			Properties: []cdxProperty{{
+				Name:  "kraftkit:initrd:size",
+				Value: fmt.Sprintf("%d", file.Size),
+			}},
+		})
+	}
+
+	out.Dependencies = []cdxDependency{dependency}
+
+	return json.MarshalIndent(out, "", "  ")
+}
@@ -0,0 +1,229 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+// Package sbom generates software bills of materials (SBOMs) of unikernels
+// which list the Unikraft core, the libraries and the application that the
+// unikernel has been built from, as well as the files of its initramfs.
+// Documents can be encoded in the SPDX or CycloneDX JSON formats.
+package sbom
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+
+	"kraftkit.sh/cpio"
+	"kraftkit.sh/log"
+)
+
+// Format is the encoding of an SBOM document.
+type Format string
+
+const (
+	FormatSPDX      = Format("spdx")
+	FormatCycloneDX = Format("cyclonedx")
+)
+
+const (
+	// MediaTypeSPDX is the media type of SPDX JSON documents.
+	MediaTypeSPDX = "application/spdx+json"
+
+	// MediaTypeCycloneDX is the media type of CycloneDX JSON documents.
+	MediaTypeCycloneDX = "application/vnd.cyclonedx+json"
+)
+
+// Formats returns the list of supported formats.
+func Formats() []Format {
+	return []Format{
+		FormatSPDX,
+		FormatCycloneDX,
+	}
+}
+
+// String implements fmt.Stringer.
+func (format Format) String() string {
+	return string(format)
+}
+
+// MediaType returns the media type of documents in the format.
+func (format Format) MediaType() string {
+	switch format {
+	case FormatSPDX:
+		return MediaTypeSPDX
+	case FormatCycloneDX:
+		return MediaTypeCycloneDX
+	default:
+		return ""
+	}
+}
+
+// MediaTypes returns the media types of all supported formats.
+func MediaTypes() []string {
+	return []string{
+		MediaTypeSPDX,
+		MediaTypeCycloneDX,
+	}
+}
+
+// ComponentType is the role of a component within the unikernel.
+type ComponentType string
+
+const (
+	ComponentTypeApplication = ComponentType("application")
+	ComponentTypeCore        = ComponentType("core")
+	ComponentTypeLibrary     = ComponentType("library")
+)
+
+// Component is a piece of software which is part of the unikernel.
+type Component struct {
+	Type     ComponentType
+	Name     string
+	Version  string
+	License  string
+	Source   string
+	Revision string
+}
+
+// File is a file which is part of the initramfs of the unikernel.
+type File struct {
+	Path   string
+	Size   int64
+	SHA256 string
+}
+
+// Document is a format-agnostic SBOM of a unikernel.  The first component is
+// the application which the document describes.
+type Document struct {
+	Created    time.Time
+	Components []Component
+	Files      []File
+}
+
+// Revision returns the commit which is checked out in the provided directory
+// if it is the root of a Git repository.  Parent directories are not searched
+// as components which are not repositories themselves, e.g. libraries beneath
+// the `.unikraft/libs` directory of an application, would otherwise report the
+// revision of the repository containing them.
+func Revision(dir string) string {
+	if len(dir) == 0 {
+		return ""
+	}
+
+	repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{
+		DetectDotGit: false,
+	})
+	if err != nil {
+		return ""
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return ""
+	}
+
+	return head.Hash().String()
+}
+
+// AddInitrd lists the regular files of the CPIO archive at the provided path,
+// which may be gzip-compressed, in the document.
+func (doc *Document) AddInitrd(ctx context.Context, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not open initramfs: %w", err)
+	}
+
+	defer f.Close()
+
+	var reader io.Reader = f
+
+	if gr, err := gzip.NewReader(f); err == nil {
+		defer gr.Close()
+		reader = gr
+	} else if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	archive := cpio.NewReader(reader)
+
+	for {
+		header, _, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("could not read initramfs: %w", err)
+		}
+
+		if !header.Mode.IsRegular() {
+			continue
+		}
+
+		h := sha256.New()
+		size, err := io.Copy(h, archive)
+		if err != nil {
+			return fmt.Errorf("could not read %s: %w", header.Name, err)
+		}
+
+		name := "/" + strings.TrimPrefix(filepath.Clean("/"+header.Name), "/")
+
+		log.G(ctx).
+			WithField("file", name).
+			Trace("sbom")
+
+		doc.Files = append(doc.Files, File{
+			Path:   name,
+			Size:   size,
+			SHA256: hex.EncodeToString(h.Sum(nil)),
+		})
+	}
+
+	sort.Slice(doc.Files, func(i, j int) bool {
+		return doc.Files[i].Path < doc.Files[j].Path
+	})
+
+	return nil
+}
+
+// Encode serializes the document in the provided format.
+func (doc *Document) Encode(format Format) ([]byte, error) {
+	if len(doc.Components) == 0 {
+		return nil, fmt.Errorf("document does not describe any component")
+	}
+
+	switch format {
+	case FormatSPDX:
+		return doc.spdx()
+	case FormatCycloneDX:
+		return doc.cyclonedx()
+	default:
+		return nil, fmt.Errorf("unsupported SBOM format: %s", format)
+	}
+}
+
+// checksum returns a digest of the contents of the document which is used to
+// derive its unique identifiers.
+func (doc *Document) checksum() []byte {
+	h := sha256.New()
+
+	fmt.Fprintf(h, "%s\n", doc.Created.Format(time.RFC3339))
+	for _, c := range doc.Components {
+		fmt.Fprintf(h, "%s %s %s %s\n", c.Type, c.Name, c.Version, c.Revision)
+	}
+	for _, f := range doc.Files {
+		fmt.Fprintf(h, "%s %s\n", f.Path, f.SHA256)
+	}
+
+	return h.Sum(nil)
+}
@@ -0,0 +1,158 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package sbom
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"kraftkit.sh/cpio"
+)
+
+func TestEncode(t *testing.T) {
+	ctx := context.Background()
+	initrd := filepath.Join(t.TempDir(), "initramfs.cpio")
+
+	f, err := os.Create(initrd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := cpio.NewWriter(f)
+	for _, entry := range []struct {
+		name string
+		mode cpio.FileMode
+		body string
+	}{
+		{name: "./etc", mode: cpio.TypeDir | 0o755},
+		{name: "./etc/nginx.conf", mode: cpio.TypeReg | 0o644, body: "worker_processes 1;\n"},
+	} {
+		if err := w.WriteHeader(&cpio.Header{
+			Name: entry.name,
+			Mode: entry.mode,
+			Size: int64(len(entry.body)),
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := w.Write([]byte(entry.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f.Close()
+
+	doc := Document{
+		Created: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Components: []Component{
+			{Type: ComponentTypeApplication, Name: "nginx"},
+			{Type: ComponentTypeCore, Name: "unikraft", Version: "0.16.1", License: "BSD-3-Clause"},
+			{Type: ComponentTypeLibrary, Name: "musl", Version: "stable", License: "MIT", Revision: "0123456789abcdef"},
+		},
+	}
+
+	if err := doc.AddInitrd(ctx, initrd); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(doc.Files) != 1 || doc.Files[0].Path != "/etc/nginx.conf" {
+		t.Fatalf("expected initrd to contain /etc/nginx.conf, got %v", doc.Files)
+	}
+
+	raw, err := doc.Encode(FormatSPDX)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var spdx spdxDocument
+	if err := json.Unmarshal(raw, &spdx); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(spdx.Packages) != 3 || len(spdx.Files) != 1 {
+		t.Fatalf("expected 3 packages and 1 file, got %d and %d", len(spdx.Packages), len(spdx.Files))
+	}
+
+	if spdx.Packages[2].LicenseDeclared != "MIT" || spdx.Packages[0].LicenseDeclared != spdxNoAssertion {
+		t.Fatalf("unexpected licenses: %v", spdx.Packages)
+	}
+
+	raw, err = doc.Encode(FormatCycloneDX)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cdx cdxDocument
+	if err := json.Unmarshal(raw, &cdx); err != nil {
+		t.Fatal(err)
+	}
+
+	if cdx.Metadata.Component.Name != "nginx" || len(cdx.Components) != 3 {
+		t.Fatalf("unexpected components: %v", cdx.Components)
+	}
+
+	if len(cdx.Dependencies) != 1 || len(cdx.Dependencies[0].DependsOn) != 2 {
+		t.Fatalf("unexpected dependencies: %v", cdx.Dependencies)
+	}
+}
+
+func TestRevision(t *testing.T) {
+	root := t.TempDir()
+
+	repo, err := git.PlainInit(root, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "Kraftfile"), []byte("spec: v0.6\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := wt.Add("Kraftfile"); err != nil {
+		t.Fatal(err)
+	}
+
+	commit, err := wt.Commit("Initial commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "KraftKit", Email: "kraftkit@unikraft.io", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := Revision(root); got != commit.String() {
+		t.Errorf("expected revision %s of the repository, got %q", commit, got)
+	}
+
+	// A component which is not a repository itself does not report the
+	// revision of the repository containing it.
+	nested := filepath.Join(root, ".unikraft", "libs", "lwip")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := Revision(nested); got != "" {
+		t.Errorf("expected no revision for a nested non-repository, got %q", got)
+	}
+
+	if got := Revision(t.TempDir()); got != "" {
+		t.Errorf("expected no revision outside of a repository, got %q", got)
+	}
+}
@@ -0,0 +1,166 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package sbom
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"kraftkit.sh/internal/version"
+)
+
+// spdxNoAssertion indicates that no information is available for a field.
+const spdxNoAssertion = "NOASSERTION"
+
+// spdxInvalidID matches characters which are not permitted in SPDX
+// identifiers.
+var spdxInvalidID = regexp.MustCompile(`[^a-zA-Z0-9.-]+`)
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Files             []spdxFile         `json:"files,omitempty"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	Name                  string `json:"name"`
+	SPDXID                string `json:"SPDXID"`
+	VersionInfo           string `json:"versionInfo,omitempty"`
+	DownloadLocation      string `json:"downloadLocation"`
+	FilesAnalyzed         bool   `json:"filesAnalyzed"`
+	LicenseConcluded      string `json:"licenseConcluded"`
+	LicenseDeclared       string `json:"licenseDeclared"`
+	CopyrightText         string `json:"copyrightText"`
+	SourceInfo            string `json:"sourceInfo,omitempty"`
+	PrimaryPackagePurpose string `json:"primaryPackagePurpose"`
+}
+
+type spdxFile struct {
+	FileName         string         `json:"fileName"`
+	SPDXID           string         `json:"SPDXID"`
+	Checksums        []spdxChecksum `json:"checksums"`
+	LicenseConcluded string         `json:"licenseConcluded"`
+	CopyrightText    string         `json:"copyrightText"`
+}
+
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// spdxOrNoAssertion returns the value or NOASSERTION if it is empty.
+func spdxOrNoAssertion(value string) string {
+	if len(value) == 0 {
+		return spdxNoAssertion
+	}
+
+	return value
+}
+
+// spdx encodes the document as SPDX 2.3 JSON.
+func (doc *Document) spdx() ([]byte, error) {
+	application := doc.Components[0]
+
+	out := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              application.Name,
+		DocumentNamespace: fmt.Sprintf("https://kraftkit.sh/spdx/%s-%s", spdxInvalidID.ReplaceAllString(application.Name, "-"), hex.EncodeToString(doc.checksum())),
+		CreationInfo: spdxCreationInfo{
+			Created:  doc.Created.UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: kraftkit-" + version.Version()},
+		},
+	}
+
+	var applicationID string
+
+	for i, component := range doc.Components {
+		id := fmt.Sprintf("SPDXRef-Package-%s-%d", spdxInvalidID.ReplaceAllString(component.Name, "-"), i)
+
+		purpose := "LIBRARY"
+		switch component.Type {
+		case ComponentTypeApplication:
+			purpose = "APPLICATION"
+		case ComponentTypeCore:
+			purpose = "OPERATING-SYSTEM"
+		}
+
+		pkg := spdxPackage{
+			Name:                  component.Name,
+			SPDXID:                id,
+			VersionInfo:           component.Version,
+			DownloadLocation:      spdxOrNoAssertion(component.Source),
+			LicenseConcluded:      spdxNoAssertion,
+			LicenseDeclared:       spdxOrNoAssertion(component.License),
+			CopyrightText:         spdxNoAssertion,
+			PrimaryPackagePurpose: purpose,
+		}
+
+		if len(component.Revision) > 0 {
+			pkg.SourceInfo = "built from git revision " + component.Revision
+		}
+
+		out.Packages = append(out.Packages, pkg)
+
+		if i == 0 {
+			applicationID = id
+			out.Relationships = append(out.Relationships, spdxRelationship{
+				SPDXElementID:      out.SPDXID,
+				RelationshipType:   "DESCRIBES",
+				RelatedSPDXElement: id,
+			})
+		} else {
+			out.Relationships = append(out.Relationships, spdxRelationship{
+				SPDXElementID:      applicationID,
+				RelationshipType:   "DEPENDS_ON",
+				RelatedSPDXElement: id,
+			})
+		}
+	}
+
+	for i, file := range doc.Files {
+		id := fmt.Sprintf("SPDXRef-File-%d", i)
+
+		out.Files = append(out.Files, spdxFile{
+			FileName: "." + file.Path,
+			SPDXID:   id,
+			Checksums: []spdxChecksum{{
+				Algorithm:     "SHA256",
+				ChecksumValue: file.SHA256,
+			}},
+			LicenseConcluded: spdxNoAssertion,
+			CopyrightText:    spdxNoAssertion,
+		})
+
+		out.Relationships = append(out.Relationships, spdxRelationship{
+			SPDXElementID:      applicationID,
+			RelationshipType:   "CONTAINS",
+			RelatedSPDXElement: id,
+		})
+	}
+
+	return json.MarshalIndent(out, "", "  ")
+}
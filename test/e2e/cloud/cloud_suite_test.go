@@ -6,6 +6,7 @@
 package cli_test
 
 import (
+	"os"
 	"testing"
 
 	. "github.com/onsi/ginkgo/v2" //nolint:stylecheck
@@ -16,3 +17,21 @@ func TestCloud(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "Cloud Suite")
 }
+
+// emulated returns whether the suite runs against a local emulator of
+// Unikraft Cloud started with 'kraft cloud emulator', in which case no token
+// or metro is required.
+func emulated() bool {
+	for _, env := range []string{
+		"UNIKRAFTCLOUD_EMULATOR",
+		"KRAFTCLOUD_EMULATOR",
+		"KC_EMULATOR",
+		"UKC_EMULATOR",
+	} {
+		if os.Getenv(env) != "" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -39,7 +39,7 @@ var _ = Describe("kraft cloud img ls", func() {
 			token = os.Getenv("UKC_TOKEN")
 		}
 
-		if token == "" {
+		if token == "" && !emulated() {
 			Skip("UNIKRAFTCLOUD_TOKEN is not set")
 		}
 
@@ -57,7 +57,7 @@ var _ = Describe("kraft cloud img ls", func() {
 			metro = os.Getenv("UKC_METRO")
 		}
 
-		if metro == "" {
+		if metro == "" && !emulated() {
 			Skip("UNIKRAFTCLOUD_METRO is not set")
 		}
 
@@ -20,8 +20,6 @@ import (
 	"kraftkit.sh/tui"
 )
 
-var tprog *tea.Program
-
 type ParaProgress struct {
 	processes     []*Process
 	quitting      bool
@@ -143,7 +141,12 @@ func (pd *ParaProgress) Start() error {
 		pd.width, pd.maxConcurrent, _ = term.GetSize(int(os.Stdout.Fd()))
 	}
 
-	tprog = tea.NewProgram(pd, teaOpts...)
+	// Each process reports its status to the program of the ParaProgress it
+	// belongs to such that multiple instances can be run concurrently.
+	tprog := tea.NewProgram(pd, teaOpts...)
+	for _, process := range pd.processes {
+		process.tprog = tprog
+	}
 
 	// Restore the old output for the IOStreams which is manipulated per process.
 	defer func() {
@@ -79,6 +79,7 @@ type Process struct {
 	norender    bool
 	ctx         context.Context
 	timeout     time.Duration
+	tprog       *tea.Program
 
 	Name      string
 	NameWidth int
@@ -137,8 +138,8 @@ func (p *Process) Start() tea.Cmd {
 			p.Status = StatusFailed
 		}
 
-		if tprog != nil {
-			tprog.Send(StatusMsg{
+		if p.tprog != nil {
+			p.tprog.Send(StatusMsg{
 				ID:     p.id,
 				status: p.Status,
 				err:    err,
@@ -154,11 +155,11 @@ func (p *Process) Start() tea.Cmd {
 // onProgress is called to dynamically inject ProgressMsg into the bubbletea
 // runtime
 func (p Process) onProgress(progress float64) {
-	if tprog == nil || progress < 0 {
+	if p.tprog == nil || progress < 0 {
 		return
 	}
 
-	tprog.Send(ProgressMsg{
+	p.tprog.Send(ProgressMsg{
 		ID:       p.id,
 		progress: progress,
 	})
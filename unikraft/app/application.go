@@ -745,6 +745,26 @@ func (app *application) Unset(ctx context.Context, tc target.Target, mopts ...ma
 	return nil
 }
 
+// TargetMakeOptions returns the make options which are specific to the
+// provided target and must be passed to every invocation of the build system
+// for it, including when the prepare and fetch steps are invoked separately
+// from Build.
+func TargetMakeOptions(tc target.Target) []make.MakeOption {
+	// This is a special exception used for KraftCloud-centric platform targets.
+	// This includes using the ability to rename the kernal image to represent
+	// this as a platform (see [0] for additional details) and setting specific
+	// KConfig options.
+	//
+	// [0]: https://github.com/unikraft/unikraft/pull/1169
+	if tc.Platform().Name() == "kraftcloud" {
+		return []make.MakeOption{
+			make.WithVar("UK_IMAGE_NAME_OVERWRITE", fmt.Sprintf("%s_kraftcloud-%s", tc.Name(), tc.Architecture().Name())),
+		}
+	}
+
+	return nil
+}
+
 // Build offers an invocation of the Unikraft build system with the contextual
 // information of the applications
 func (app *application) Build(ctx context.Context, tc target.Target, opts ...BuildOption) error {
@@ -762,19 +782,9 @@ func (app *application) Build(ctx context.Context, tc target.Target, opts ...Bui
 		return fmt.Errorf("cannot build without Unikraft core component source")
 	}
 
-	mopts := []make.MakeOption{
+	mopts := append([]make.MakeOption{
 		make.WithProgressFunc(bopts.onProgress),
-	}
-
-	// This is a special exception used for KraftCloud-centric platform targets.
-	// This includes using the ability to rename the kernal image to represent
-	// this as a platform (see [0] for additional details) and setting specific
-	// KConfig options.
-	//
-	// [0]: https://github.com/unikraft/unikraft/pull/1169
-	if tc.Platform().Name() == "kraftcloud" {
-		mopts = append(mopts, make.WithVar("UK_IMAGE_NAME_OVERWRITE", fmt.Sprintf("%s_kraftcloud-%s", tc.Name(), tc.Architecture().Name())))
-	}
+	}, TargetMakeOptions(tc)...)
 
 	bopts.mopts = append(bopts.mopts, mopts...)
 
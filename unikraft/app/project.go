@@ -102,7 +102,11 @@ func NewProjectFromOptions(ctx context.Context, opts ...ProjectOption) (Applicat
 	}
 
 	if n, ok := iface["outdir"]; ok {
-		name = n.(string)
+		outdir = n.(string)
+	}
+
+	if popts.outdir != "" {
+		outdir = popts.outdir
 	}
 
 	popts.kraftfile.config = iface
@@ -165,7 +169,7 @@ func NewProjectFromOptions(ctx context.Context, opts ...ProjectOption) (Applicat
 		WithName(projectName),
 		WithWorkingDir(popts.workdir),
 		WithFilename(app.filename),
-		WithOutDir(app.outDir),
+		WithOutDir(uk.BUILD_DIR),
 		WithUnikraft(app.unikraft),
 		WithRuntime(app.runtime),
 		WithRootfs(app.rootfs),
@@ -47,6 +47,7 @@ type Kraftfile struct {
 type ProjectOptions struct {
 	name              string
 	workdir           string
+	outdir            string
 	kraftfile         *Kraftfile
 	kconfig           kconfig.KeyValueMap
 	skipValidation    bool
@@ -210,6 +211,15 @@ func WithProjectWorkdir(workdir string) ProjectOption {
 	}
 }
 
+// WithProjectOutDir overrides the output directory of the project, including
+// any which has been set in the Kraftfile.
+func WithProjectOutDir(outdir string) ProjectOption {
+	return func(popts *ProjectOptions) error {
+		popts.outdir = outdir
+		return nil
+	}
+}
+
 // WithProjectConfig defines a key=value set of variables used for kraft file
 // interpolation as well as with Unikraft's build system
 func WithProjectConfig(config []string) ProjectOption {
@@ -0,0 +1,60 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package app
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"kraftkit.sh/unikraft"
+)
+
+func TestNewProjectFromOptionsOutDir(t *testing.T) {
+	workdir := t.TempDir()
+	absolute := filepath.Join(t.TempDir(), "out")
+
+	for _, tc := range []struct {
+		name      string
+		kraftfile string
+		expected  string
+	}{
+		{
+			name:      "default",
+			kraftfile: "",
+			expected:  filepath.Join(workdir, unikraft.BuildDir),
+		},
+		{
+			name:      "relative outdir",
+			kraftfile: "outdir: build/out\n",
+			expected:  filepath.Join(workdir, "build", "out"),
+		},
+		{
+			name:      "absolute outdir",
+			kraftfile: "outdir: " + absolute + "\n",
+			expected:  absolute,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			kraftfile := "spec: v0.6\nname: helloworld\nunikraft: stable\ntargets:\n  - qemu/x86_64\n" + tc.kraftfile
+
+			project, err := NewProjectFromOptions(context.Background(),
+				WithProjectWorkdir(workdir),
+				WithProjectKraftfileFromBytes([]byte(kraftfile)),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if project.OutDir() != tc.expected {
+				t.Errorf("expected output directory %s, got %s", tc.expected, project.OutDir())
+			}
+
+			if project.Name() != "helloworld" {
+				t.Errorf("expected the outdir not to change the name of the project, got %s", project.Name())
+			}
+		})
+	}
+}
@@ -0,0 +1,282 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+// Package buildenv provides environments in which Unikraft's build system can
+// be invoked other than the host, such that builds are hermetic and
+// reproducible regardless of the toolchain which is installed on the host.
+package buildenv
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/cio"
+	"github.com/containerd/containerd/defaults"
+	"github.com/containerd/containerd/namespaces"
+	ctrdoci "github.com/containerd/containerd/oci"
+	"github.com/containerd/nerdctl/pkg/imgutil/dockerconfigresolver"
+	"github.com/containerd/platforms"
+	"github.com/google/go-containerregistry/pkg/name"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+
+	"kraftkit.sh/config"
+	"kraftkit.sh/exec"
+	"kraftkit.sh/internal/version"
+	"kraftkit.sh/log"
+)
+
+// DefaultImageRepository is the repository of the toolchain image which is
+// used when no image is explicitly requested.  It is built from
+// `buildenvs/base.Dockerfile` and tagged with each release of KraftKit.
+const DefaultImageRepository = "index.unikraft.io/kraftkit.sh/base"
+
+// releaseVersionRegex matches the version of a stable release of KraftKit.
+var releaseVersionRegex = regexp.MustCompile(`^v?\d+\.\d+\.\d+$`)
+
+// DefaultImage returns the toolchain image which is used when no image is
+// explicitly requested.  The image is pinned to the release of KraftKit such
+// that the same release always builds with the same toolchain.  Development
+// builds, which have no corresponding image, use the latest image instead.
+func DefaultImage() string {
+	return defaultImage(version.Version())
+}
+
+func defaultImage(v string) string {
+	if !releaseVersionRegex.MatchString(v) {
+		return DefaultImageRepository + ":latest"
+	}
+
+	return DefaultImageRepository + ":v" + strings.TrimPrefix(v, "v")
+}
+
+// DefaultNamespace is the containerd namespace in which build containers are
+// created unless otherwise specified via the CONTAINERD_NAMESPACE environment
+// variable.
+const DefaultNamespace = "default"
+
+// Container is an exec.Executor which runs each process within a fresh
+// container of a toolchain image via containerd.  The provided paths are
+// bind-mounted at the same location inside of the container such that
+// absolute paths passed to the process remain valid and any artifacts written
+// to them are available on the host once the process exits.
+type Container struct {
+	client    *containerd.Client
+	image     containerd.Image
+	namespace string
+	mounts    []string
+}
+
+var _ exec.Executor = (*Container)(nil)
+
+// NewContainer connects to the containerd daemon, pulls the provided image if
+// it is not already present and returns an executor which bind-mounts the
+// provided paths.
+func NewContainer(ctx context.Context, image string, mounts ...string) (*Container, error) {
+	if len(image) == 0 {
+		image = DefaultImage()
+	}
+
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse build environment image: %w", err)
+	}
+
+	addr := config.G[config.KraftKit](ctx).ContainerdAddr
+	if len(addr) == 0 {
+		addr = defaults.DefaultAddress
+	}
+
+	container := Container{
+		namespace: DefaultNamespace,
+		mounts:    mountPoints(mounts),
+	}
+
+	if n := os.Getenv("CONTAINERD_NAMESPACE"); n != "" {
+		container.namespace = n
+	}
+
+	container.client, err = containerd.New(addr)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to containerd at %s: %w", addr, err)
+	}
+
+	ctx = namespaces.WithNamespace(ctx, container.namespace)
+
+	container.image, err = container.client.GetImage(ctx, ref.Name())
+	if err != nil {
+		log.G(ctx).
+			WithField("image", ref.Name()).
+			Info("pulling build environment")
+
+		auths := config.G[config.KraftKit](ctx).Auth
+
+		resolver, err := dockerconfigresolver.New(
+			ctx,
+			ref.Context().RegistryStr(),
+			dockerconfigresolver.WithAuthCreds(func(domain string) (string, string, error) {
+				auth, ok := auths[domain]
+				if !ok {
+					return "", "", nil
+				}
+
+				return auth.User, auth.Token, nil
+			}),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		container.image, err = container.client.Pull(ctx,
+			ref.Name(),
+			containerd.WithPullUnpack,
+			containerd.WithPlatform(platforms.DefaultString()),
+			containerd.WithResolver(resolver),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("could not pull build environment %s: %w", ref.Name(), err)
+		}
+	}
+
+	return &container, nil
+}
+
+// mountPoints returns the minimal set of absolute paths which covers all
+// provided paths, omitting those which are nested within another.
+func mountPoints(paths []string) []string {
+	var abs []string
+	for _, path := range paths {
+		if len(path) == 0 {
+			continue
+		}
+
+		if p, err := filepath.Abs(path); err == nil {
+			abs = append(abs, filepath.Clean(p))
+		}
+	}
+
+	sort.Strings(abs)
+
+	var ret []string
+next:
+	for _, path := range abs {
+		for _, parent := range ret {
+			if path == parent || strings.HasPrefix(path, parent+string(filepath.Separator)) {
+				continue next
+			}
+		}
+
+		ret = append(ret, path)
+	}
+
+	return ret
+}
+
+// String returns the canonical reference of the toolchain image, including
+// its digest, which uniquely identifies the build environment.
+func (c *Container) String() string {
+	return fmt.Sprintf("%s@%s", c.image.Name(), c.image.Target().Digest)
+}
+
+// Execute implements exec.Executor.
+func (c *Container) Execute(ctx context.Context, bin string, args, env []string, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	ctx = namespaces.WithNamespace(ctx, c.namespace)
+	id := fmt.Sprintf("kraftkit-build-%d", time.Now().UnixNano())
+
+	var mounts []specs.Mount
+	for _, path := range c.mounts {
+		mounts = append(mounts, specs.Mount{
+			Type:        "bind",
+			Source:      path,
+			Destination: path,
+			Options:     []string{"rbind", "rw"},
+		})
+	}
+
+	// Retain the working directory only if it is available within the
+	// container.
+	cwd := "/"
+	if wd, err := os.Getwd(); err == nil {
+		for _, path := range c.mounts {
+			if wd == path || strings.HasPrefix(wd, path+string(filepath.Separator)) {
+				cwd = wd
+				break
+			}
+		}
+	}
+
+	container, err := c.client.NewContainer(ctx, id,
+		containerd.WithImage(c.image),
+		containerd.WithNewSnapshot(id, c.image),
+		containerd.WithNewSpec(
+			ctrdoci.WithImageConfig(c.image),
+			ctrdoci.WithProcessArgs(append([]string{bin}, args...)...),
+			ctrdoci.WithProcessCwd(cwd),
+			ctrdoci.WithMounts(mounts),
+			ctrdoci.WithUIDGID(uint32(os.Getuid()), uint32(os.Getgid())),
+			ctrdoci.WithEnv(append([]string{"HOME=/tmp"}, env...)),
+		),
+	)
+	if err != nil {
+		return -1, fmt.Errorf("could not create build container: %w", err)
+	}
+
+	defer func() {
+		if err := container.Delete(context.WithoutCancel(ctx), containerd.WithSnapshotCleanup); err != nil {
+			log.G(ctx).
+				WithField("id", id).
+				Debugf("could not remove build container: %v", err)
+		}
+	}()
+
+	task, err := container.NewTask(ctx, cio.NewCreator(cio.WithStreams(stdin, stdout, stderr)))
+	if err != nil {
+		return -1, fmt.Errorf("could not create build task: %w", err)
+	}
+
+	defer func() {
+		if _, err := task.Delete(context.WithoutCancel(ctx), containerd.WithProcessKill); err != nil {
+			log.G(ctx).
+				WithField("id", id).
+				Debugf("could not remove build task: %v", err)
+		}
+	}()
+
+	exitC, err := task.Wait(ctx)
+	if err != nil {
+		return -1, err
+	}
+
+	if err := task.Start(ctx); err != nil {
+		return -1, fmt.Errorf("could not start build task: %w", err)
+	}
+
+	select {
+	case status := <-exitC:
+		code, _, err := status.Result()
+		return int(code), err
+
+	case <-ctx.Done():
+		if err := task.Kill(context.WithoutCancel(ctx), syscall.SIGKILL); err != nil {
+			return -1, fmt.Errorf("could not kill build task: %w", err)
+		}
+
+		<-exitC
+		return -1, ctx.Err()
+	}
+}
+
+// Close the connection to the containerd daemon.
+func (c *Container) Close() error {
+	return c.client.Close()
+}
@@ -0,0 +1,45 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2024, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package buildenv
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMountPoints(t *testing.T) {
+	got := mountPoints([]string{
+		"/home/user/app/.unikraft/build",
+		"/home/user/app",
+		"",
+		"/home/user/app-lib",
+		"/home/user/.local/share/kraftkit/sources/unikraft",
+		"/home/user/app/",
+	})
+
+	expected := []string{
+		"/home/user/.local/share/kraftkit/sources/unikraft",
+		"/home/user/app",
+		"/home/user/app-lib",
+	}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestDefaultImage(t *testing.T) {
+	for v, expected := range map[string]string{
+		"0.9.1":               DefaultImageRepository + ":v0.9.1",
+		"v0.9.1":              DefaultImageRepository + ":v0.9.1",
+		"0.9.1-next":          DefaultImageRepository + ":latest",
+		"0.9.1-8-gabcdef0":    DefaultImageRepository + ":latest",
+		"No version provided": DefaultImageRepository + ":latest",
+	} {
+		if got := defaultImage(v); got != expected {
+			t.Errorf("expected %s for version %q, got %s", expected, v, got)
+		}
+	}
+}